@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+	"github.com/vinodismyname/mcpxcel/internal/registry"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/version"
+)
+
+// checkOptions carries the flag values --check validates alongside the
+// environment.
+type checkOptions struct {
+	stalePolicy string
+	saveDelay   string
+	backupDir   string
+	backupKeep  string
+	statusFile  string
+}
+
+// loadLimits returns the runtime limits with MCPXCEL_* overrides applied.
+func loadLimits() (runtime.Limits, error) {
+	return runtime.NewLimits(10, 4).ApplyEnv()
+}
+
+// parseSaveDelay parses --save-delay; empty means save on every call.
+func parseSaveDelay(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --save-delay %q: use a non-negative duration such as 500ms", s)
+	}
+	return d, nil
+}
+
+// parseBackupKeep parses --backup-keep; empty selects the default.
+func parseBackupKeep(s string) (int, error) {
+	if s == "" {
+		return workbooks.DefaultBackupKeep, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --backup-keep %q: use a positive integer", s)
+	}
+	return n, nil
+}
+
+// resolveBackupDir makes --backup-dir absolute; empty stays empty.
+func resolveBackupDir(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid --backup-dir %q: %v", s, err)
+	}
+	return abs, nil
+}
+
+// checkReport prints one line per finding and counts the problems.
+type checkReport struct {
+	w        io.Writer
+	problems int
+	warnings int
+}
+
+func (r *checkReport) section(name string) { fmt.Fprintf(r.w, "\n%s\n", name) }
+
+func (r *checkReport) ok(format string, args ...any) {
+	fmt.Fprintf(r.w, "  ok     "+format+"\n", args...)
+}
+
+func (r *checkReport) warn(format string, args ...any) {
+	r.warnings++
+	fmt.Fprintf(r.w, "  WARN   "+format+"\n", args...)
+}
+
+func (r *checkReport) fail(format string, args ...any) {
+	r.problems++
+	fmt.Fprintf(r.w, "  ERROR  "+format+"\n", args...)
+}
+
+// runCheck initializes the same components as a normal start (allow-list,
+// limits, write filter, stale policy, batched saves, backups, audit log, and
+// session persistence) without serving, writes a report to w, and reports
+// whether the configuration is usable. Directories the server would create
+// are created, as on a normal start.
+func runCheck(w io.Writer, opts checkOptions) bool {
+	r := &checkReport{w: w}
+	fmt.Fprintf(w, "mcpxcel %s configuration check\n", version.Version())
+
+	r.section("allow-list")
+	secMgr, err := security.NewManagerFromEnv()
+	switch {
+	case err != nil:
+		r.fail("%v (check MCPXCEL_ALLOWED_DIRS_RO/MCPXCEL_ALLOWED_DIRS_RW/MCPXCEL_DENIED_DIRS)", err)
+	case secMgr.ValidateConfig() != nil:
+		r.fail("no allowed directories configured; set MCPXCEL_ALLOWED_DIRS_RO or MCPXCEL_ALLOWED_DIRS_RW")
+	default:
+		writable := make(map[string]bool)
+		for _, d := range secMgr.WritableDirectories() {
+			writable[d] = true
+		}
+		for _, d := range secMgr.AllowedDirectories() {
+			r.ok("%-10s %s", accessMode(writable[d]), d)
+		}
+		writable = make(map[string]bool)
+		for _, p := range secMgr.WritablePatterns() {
+			writable[p] = true
+		}
+		for _, p := range secMgr.AllowedPatterns() {
+			r.ok("%-10s %s (pattern)", accessMode(writable[p]), p)
+		}
+		for _, d := range secMgr.DeniedDirectories() {
+			r.ok("%-10s %s", "denied", d)
+		}
+	}
+
+	r.section("limits")
+	limits, err := loadLimits()
+	if err != nil {
+		r.fail("%v", err)
+	} else {
+		for _, l := range []struct {
+			name  string
+			value any
+		}{
+			{"max_concurrent_requests", limits.MaxConcurrentRequests},
+			{"max_open_workbooks", limits.MaxOpenWorkbooks},
+			{"max_payload_bytes", limits.MaxPayloadBytes},
+			{"max_cells_per_op", limits.MaxCellsPerOp},
+			{"preview_row_limit", limits.PreviewRowLimit},
+			{"max_rows_per_edit", limits.MaxRowsPerEdit},
+			{"max_export_cells", limits.MaxExportCells},
+			{"max_crosstab_cells", limits.MaxCrosstabCells},
+			{"max_text_bytes", limits.TextBudgetBytes()},
+			{"max_file_bytes", limits.MaxFileBytes},
+			{"operation_timeout", limits.OperationTimeout},
+			{"acquire_request_timeout", limits.AcquireRequestTimeout},
+			{"cursor_ttl", limits.CursorTTL},
+		} {
+			r.ok("%-24s %v", l.name, l.value)
+		}
+	}
+
+	r.section("writes")
+	writes := registry.NewWriteToolFilterFromEnv().WritesEnabled()
+	switch {
+	case !writes:
+		r.ok("write tools hidden (set MCPXCEL_ENABLE_WRITES=true to expose them)")
+	case secMgr != nil && len(secMgr.WritableDirectories()) == 0 && len(secMgr.WritablePatterns()) == 0:
+		r.warn("write tools exposed, but no allow-list entry is writable; add MCPXCEL_ALLOWED_DIRS_RW")
+	default:
+		r.ok("write tools exposed")
+	}
+	if policy, err := workbooks.ParseStalePolicy(opts.stalePolicy); err != nil {
+		r.fail("%v", err)
+	} else {
+		r.ok("stale policy %s", policy)
+	}
+	if d, err := parseSaveDelay(opts.saveDelay); err != nil {
+		r.fail("%v", err)
+	} else if d > 0 {
+		r.ok("batched saves after %s", d)
+	} else {
+		r.ok("save on every call")
+	}
+
+	r.section("backups")
+	keep, err := parseBackupKeep(opts.backupKeep)
+	if err != nil {
+		r.fail("%v", err)
+	}
+	dir, err := resolveBackupDir(opts.backupDir)
+	switch {
+	case err != nil:
+		r.fail("%v", err)
+	case dir == "":
+		r.ok("on request only (backup=true), in %s beside each workbook", workbooks.LocalBackupDir)
+	default:
+		if err := probeDir(dir); err != nil {
+			r.fail("backup dir %s: %v", dir, err)
+			break
+		}
+		r.ok("before every save into %s, keeping %d per workbook", dir, keep)
+		if secMgr != nil {
+			if _, err := secMgr.ValidateWritePath(filepath.Join(dir, "probe.xlsx")); errors.Is(err, security.ErrNotAllowed) {
+				r.warn("backup dir is outside the allow-list; restore_backup cannot read its backups")
+			}
+		}
+	}
+
+	r.section("audit log")
+	auditLog, err := audit.NewFromEnv()
+	switch {
+	case err != nil:
+		r.fail("%v", err)
+	case auditLog == nil:
+		r.ok("off (set MCPXCEL_AUDIT_LOG to record writes)")
+	default:
+		r.ok("%s (strict=%t)", auditLog.Path(), auditLog.Strict())
+		_ = auditLog.Close()
+	}
+
+	r.section("sessions")
+	sessions, err := insights.NewSessionStoreFromEnv(20)
+	switch {
+	case err != nil:
+		r.fail("%v", err)
+	case sessions.Dir() == "":
+		r.ok("in memory only (set MCPXCEL_SESSION_DIR to persist)")
+	default:
+		if err := probeDir(sessions.Dir()); err != nil {
+			r.fail("session dir %s: %v", sessions.Dir(), err)
+		} else {
+			r.ok("persisted in %s", sessions.Dir())
+		}
+	}
+
+	r.section("transport")
+	if os.Getenv("MCPXCEL_HTTP_TOKEN") != "" {
+		r.ok("HTTP bearer token set")
+	} else {
+		r.ok("HTTP bearer token not set (--http would serve without authentication)")
+	}
+	if opts.statusFile != "" {
+		r.ok("status file %s", opts.statusFile)
+	}
+
+	fmt.Fprintln(w)
+	if r.problems > 0 {
+		fmt.Fprintf(w, "FAIL: %d problem(s), %d warning(s)\n", r.problems, r.warnings)
+		return false
+	}
+	fmt.Fprintf(w, "OK: %d warning(s)\n", r.warnings)
+	return true
+}
+
+func accessMode(writable bool) string {
+	if writable {
+		return "read-write"
+	}
+	return "read-only"
+}
+
+// probeDir creates dir if needed and confirms a file can be written in it.
+func probeDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".mcpxcel-check-*")
+	if err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCheck(t *testing.T) {
+	root := t.TempDir()
+	// A regular file cannot hold directories, which makes paths below it
+	// unwritable even for root.
+	blocker := filepath.Join(root, "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts checkOptions
+		ok   bool
+		want []string
+	}{
+		{
+			name: "valid",
+			env:  map[string]string{"MCPXCEL_ALLOWED_DIRS_RW": root, "MCPXCEL_AUDIT_LOG": filepath.Join(root, "audit.jsonl")},
+			opts: checkOptions{backupDir: filepath.Join(root, "backups"), saveDelay: "500ms"},
+			ok:   true,
+			want: []string{
+				"  ok     read-write " + root + "\n",
+				"  ok     batched saves after 500ms\n",
+				"  ok     before every save into " + filepath.Join(root, "backups") + ", keeping 10 per workbook\n",
+				"  ok     " + filepath.Join(root, "audit.jsonl") + " (strict=true)\n",
+				"\nOK: 0 warning(s)\n",
+			},
+		},
+		{
+			name: "bad allow-list root",
+			env:  map[string]string{"MCPXCEL_ALLOWED_DIRS_RO": filepath.Join(root, "missing")},
+			want: []string{
+				"allow-list\n  ERROR  security: eval symlinks for " + `"` + filepath.Join(root, "missing") + `"`,
+				"\nFAIL: 1 problem(s), 0 warning(s)\n",
+			},
+		},
+		{
+			name: "no allow-list",
+			want: []string{
+				"  ERROR  no allowed directories configured",
+				"\nFAIL: 1 problem(s), 0 warning(s)\n",
+			},
+		},
+		{
+			name: "unwritable backup dir",
+			env:  map[string]string{"MCPXCEL_ALLOWED_DIRS_RW": root},
+			opts: checkOptions{backupDir: filepath.Join(blocker, "backups")},
+			want: []string{
+				"  ERROR  backup dir " + filepath.Join(blocker, "backups") + ": ",
+				"\nFAIL: 1 problem(s), 0 warning(s)\n",
+			},
+		},
+		{
+			name: "unwritable audit log",
+			env:  map[string]string{"MCPXCEL_ALLOWED_DIRS_RW": root, "MCPXCEL_AUDIT_LOG": filepath.Join(blocker, "audit.jsonl")},
+			want: []string{
+				"audit log\n  ERROR  audit: open",
+				"\nFAIL: 1 problem(s), 0 warning(s)\n",
+			},
+		},
+		{
+			name: "writes without a writable root",
+			env:  map[string]string{"MCPXCEL_ALLOWED_DIRS_RO": root, "MCPXCEL_ENABLE_WRITES": "true"},
+			ok:   true,
+			want: []string{
+				"  WARN   write tools exposed, but no allow-list entry is writable",
+				"\nOK: 1 warning(s)\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{
+				"MCPXCEL_ALLOWED_DIRS", "MCPXCEL_ALLOWED_DIRS_RO", "MCPXCEL_ALLOWED_DIRS_RW", "MCPXCEL_DENIED_DIRS",
+				"MCPXCEL_ENABLE_WRITES", "MCPXCEL_AUDIT_LOG", "MCPXCEL_AUDIT_STRICT", "MCPXCEL_SESSION_DIR", "MCPXCEL_HTTP_TOKEN",
+			} {
+				t.Setenv(name, tt.env[name])
+			}
+			var out bytes.Buffer
+			require.Equal(t, tt.ok, runCheck(&out, tt.opts), out.String())
+			for _, want := range tt.want {
+				require.Contains(t, out.String(), want)
+			}
+		})
+	}
+}
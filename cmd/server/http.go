@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+
+	"github.com/vinodismyname/mcpxcel/internal/registry"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+// httpEndpoint is the path the streamable HTTP transport is served on.
+const httpEndpoint = "/mcp"
+
+// healthEndpoint serves liveness/readiness probes.
+const healthEndpoint = "/healthz"
+
+// serveHTTP runs the streamable HTTP/SSE transport on addr until a
+// termination signal arrives or the listener fails. Shutdown mirrors
+// serveStdio: the lifecycle moves to draining, in-flight calls get up to
+// shutdownTimeout to finish, then open connections are closed. A non-empty
+// token is required as a bearer token on every MCP request; health is served
+// unauthenticated at /healthz for probes.
+func serveHTTP(srv *server.MCPServer, addr, token string, health http.Handler, ctrl *runtime.Controller, reg *registry.Registry, logger zerolog.Logger, shutdownTimeout time.Duration) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(httpEndpoint, bearerAuth(token, trackHTTPSessions(server.NewStreamableHTTPServer(srv), logger, reg)))
+	mux.Handle(healthEndpoint, health)
+	httpSrv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Info().Str("addr", ln.Addr().String()).Str("endpoint", httpEndpoint).Bool("auth", token != "").Msg("serving streamable HTTP")
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigCh)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- httpSrv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		ctrl.Lifecycle().Transition(runtime.StateDraining)
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-sigCh:
+		logger.Info().Str("signal", sig.String()).Msg("shutdown requested")
+		ctrl.Lifecycle().Transition(runtime.StateDraining)
+	}
+
+	awaitInFlight(ctrl, logger, shutdownTimeout)
+	// SSE streams stay open until closed, so bound the graceful phase and
+	// then drop whatever is left.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := httpSrv.Shutdown(ctx); err != nil {
+		_ = httpSrv.Close()
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// metricsEndpoint serves Prometheus metrics on the --metrics-addr listener.
+const metricsEndpoint = "/metrics"
+
+// serveAux serves handler at endpoint on its own listener, for health checks
+// next to stdio or for metrics; the returned func stops it.
+func serveAux(addr, endpoint string, handler http.Handler, logger zerolog.Logger) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(endpoint, handler)
+	httpSrv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn().Err(err).Str("endpoint", endpoint).Msg("auxiliary listener stopped")
+		}
+	}()
+	logger.Info().Str("addr", ln.Addr().String()).Str("endpoint", endpoint).Msg("serving auxiliary endpoint")
+	return func() { _ = httpSrv.Close() }, nil
+}
+
+// bearerAuth rejects requests without "Authorization: Bearer <token>"; an
+// empty token disables the check.
+func bearerAuth(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(strings.TrimSpace(r.Header.Get("Authorization")))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcpxcel"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trackHTTPSessions feeds HTTP session starts and ends into the same
+// telemetry and cleanup as stdio sessions: a session starts when an initialize
+// response assigns an Mcp-Session-Id and ends when the client deletes it.
+func trackHTTPSessions(next http.Handler, logger zerolog.Logger, reg *registry.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent := r.Header.Get(server.HeaderKeySessionID)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		switch {
+		case r.Method == http.MethodPost && sent == "":
+			if id := w.Header().Get(server.HeaderKeySessionID); id != "" && rec.status < 300 {
+				sessionOpened(logger, id, "http")
+			}
+		case r.Method == http.MethodDelete && sent != "" && rec.status < 300:
+			sessionClosed(logger, reg, sent, "http")
+		}
+	})
+}
+
+// statusRecorder captures the response status while keeping the writer
+// flushable for SSE streams.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vinodismyname/mcpxcel/internal/registry"
+)
+
+func TestBearerAuth(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"missing token", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"wrong scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"correct token", "secret", "Bearer secret", http.StatusNoContent},
+		{"auth disabled", "", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, httpEndpoint, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			bearerAuth(tt.token, next).ServeHTTP(rec, req)
+			require.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusUnauthorized {
+				require.Equal(t, `Bearer realm="mcpxcel"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// syncBuffer collects log output written by handler goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTrackHTTPSessions(t *testing.T) {
+	logs := &syncBuffer{}
+	logger := zerolog.New(logs)
+	srv := server.NewMCPServer("test", "0.0.0")
+	handler := bearerAuth("secret", trackHTTPSessions(server.NewStreamableHTTPServer(srv), logger, registry.New()))
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	do := func(method, token, session, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+httpEndpoint, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if session != "" {
+			req.Header.Set(server.HeaderKeySessionID, session)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+	count := func(msg string) int { return strings.Count(logs.String(), `"message":"`+msg+`"`) }
+	// The tracker logs after the response is written, so wait for the line.
+	logged := func(msg string, n int) {
+		t.Helper()
+		require.Eventually(t, func() bool { return count(msg) == n }, time.Second, 5*time.Millisecond, "%s", logs.String())
+	}
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+
+	// Rejected requests never reach the session tracking.
+	resp := do(http.MethodPost, "wrong", "", initialize)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Zero(t, count("session registered"))
+
+	resp = do(http.MethodPost, "secret", "", initialize)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	id := resp.Header.Get(server.HeaderKeySessionID)
+	require.NotEmpty(t, id)
+	logged("session registered", 1)
+	require.Contains(t, logs.String(), `"session_id":"`+id+`","transport":"http","message":"session registered"`)
+
+	// Later requests in the session do not open another one.
+	resp = do(http.MethodPost, "secret", id, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 1, count("session registered"))
+
+	resp = do(http.MethodDelete, "", id, "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Zero(t, count("session unregistered"))
+
+	resp = do(http.MethodDelete, "secret", id, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	logged("session unregistered", 1)
+	require.Contains(t, logs.String(), `"session_id":"`+id+`","transport":"http","message":"session unregistered"`)
+}
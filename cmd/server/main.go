@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -12,9 +16,12 @@ import (
 	"github.com/rs/zerolog"
 	zlog "github.com/rs/zerolog/log"
 
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/insights"
 	"github.com/vinodismyname/mcpxcel/internal/registry"
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/telemetry"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
 	"github.com/vinodismyname/mcpxcel/pkg/version"
 )
@@ -24,13 +31,50 @@ func main() {
 
 	var (
 		useStdio        bool
+		httpAddr        string
+		healthAddr      string
+		metricsAddr     string
 		shutdownTimeout time.Duration
+		healthcheck     bool
+		statusFile      string
+		stalePolicy     string
+		saveDelay       string
+		backupDir       string
+		backupKeep      string
+		check           bool
 	)
 
 	flag.BoolVar(&useStdio, "stdio", false, "Run server over stdio transport")
+	flag.StringVar(&httpAddr, "http", "", "Serve streamable HTTP/SSE on this address (e.g. :8080) at /mcp; set MCPXCEL_HTTP_TOKEN to require a bearer token")
 	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 5*time.Second, "Graceful shutdown timeout")
+	flag.StringVar(&healthAddr, "health-addr", os.Getenv("MCPXCEL_HEALTH_ADDR"), "Serve /healthz on a separate listener (e.g. 127.0.0.1:8081), also with --stdio; --http serves /healthz on its own address too (env MCPXCEL_HEALTH_ADDR)")
+	flag.StringVar(&metricsAddr, "metrics-addr", os.Getenv("MCPXCEL_METRICS_ADDR"), "Serve Prometheus metrics (per-tool call counts and latency by error code) at /metrics on this address (env MCPXCEL_METRICS_ADDR)")
+	flag.BoolVar(&healthcheck, "healthcheck", false, "Probe a running server's status file and exit 0 when ready, 1 otherwise")
+	flag.StringVar(&statusFile, "status-file", defaultStatusFile(), "Path of the lifecycle status file written by the server and read by --healthcheck (env MCPXCEL_STATUS_FILE)")
+	flag.StringVar(&stalePolicy, "stale-policy", os.Getenv("MCPXCEL_STALE_POLICY"), "Reaction when an open workbook changes on disk: reopen (default) or error (env MCPXCEL_STALE_POLICY)")
+	flag.StringVar(&saveDelay, "save-delay", os.Getenv("MCPXCEL_SAVE_DELAY"), "Batch workbook saves: write tools defer the save until writes pause for this long (e.g. 500ms); 0 or empty saves on every call (env MCPXCEL_SAVE_DELAY)")
+	flag.StringVar(&backupDir, "backup-dir", os.Getenv("MCPXCEL_BACKUP_DIR"), "Copy each workbook into this directory before every save; place it inside an allowed directory so restore_backup can read it (env MCPXCEL_BACKUP_DIR)")
+	flag.StringVar(&backupKeep, "backup-keep", os.Getenv("MCPXCEL_BACKUP_KEEP"), "Backups kept per workbook, oldest pruned first (default 10; env MCPXCEL_BACKUP_KEEP)")
+	flag.BoolVar(&check, "check", false, "Validate the environment configuration (allow-list, limits, writes, backup/audit/session directories), print a report, and exit 0 when usable, 1 otherwise")
 	flag.Parse()
 
+	if check {
+		opts := checkOptions{stalePolicy: stalePolicy, saveDelay: saveDelay, backupDir: backupDir, backupKeep: backupKeep, statusFile: statusFile}
+		if !runCheck(os.Stdout, opts) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if healthcheck {
+		if err := runtime.CheckStatusFile(statusFile); err != nil {
+			fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stdout, "ready")
+		os.Exit(0)
+	}
+
 	logger := zlog.With().Str("service", "mcpxcel-server").Logger()
 	ctx := logger.WithContext(context.Background())
 
@@ -38,19 +82,41 @@ func main() {
 	secMgr, err := security.NewManagerFromEnv()
 	if err != nil {
 		logger.Error().Err(err).Msg("security: failed to initialize manager from env")
-		fmt.Fprintln(os.Stderr, "invalid security configuration; set MCPXCEL_ALLOWED_DIRS")
+		fmt.Fprintln(os.Stderr, "invalid security configuration; check MCPXCEL_ALLOWED_DIRS_RO/MCPXCEL_ALLOWED_DIRS_RW/MCPXCEL_DENIED_DIRS")
 		os.Exit(1)
 	}
 	if err := secMgr.ValidateConfig(); err != nil {
 		logger.Error().Err(err).Msg("security: invalid allow-list configuration")
-		fmt.Fprintln(os.Stderr, "no allowed directories configured; set MCPXCEL_ALLOWED_DIRS")
+		fmt.Fprintln(os.Stderr, "no allowed directories configured; set MCPXCEL_ALLOWED_DIRS_RO or MCPXCEL_ALLOWED_DIRS_RW")
 		os.Exit(1)
 	}
-	logger.Info().Strs("allowed_dirs", secMgr.AllowedDirectories()).Msg("security allow-list configured")
+	logger.Info().Strs("allowed_dirs", secMgr.AllowedDirectories()).Strs("writable_dirs", secMgr.WritableDirectories()).
+		Strs("allowed_patterns", secMgr.AllowedPatterns()).Strs("writable_patterns", secMgr.WritablePatterns()).
+		Strs("denied_dirs", secMgr.DeniedDirectories()).
+		Msg("security allow-list configured")
 
-	limits := runtime.NewLimits(10, 4)
+	limits, err := loadLimits()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	secMgr.SetMaxFileBytes(limits.MaxFileBytes)
 	runtimeController := runtime.NewController(limits)
 	runtimeMW := runtime.NewMiddleware(runtimeController)
+	// One log line per tool call with duration and the stats handlers record
+	runtimeMW.SetCallObserver(telemetry.NewHooks(logger).LogToolCall)
+
+	// Lifecycle: log each transition and mirror it to the status file for --healthcheck.
+	lifecycle := runtimeController.Lifecycle()
+	lifecycle.OnTransition(func(from, to runtime.State) {
+		logger.Info().Str("from", from.String()).Str("to", to.String()).Msg("lifecycle transition")
+		if statusFile == "" {
+			return
+		}
+		if err := runtime.WriteStatusFile(statusFile, to, time.Now()); err != nil {
+			logger.Warn().Err(err).Str("status_file", statusFile).Msg("failed to write status file")
+		}
+	})
 
 	toolRegistry := registry.New()
 
@@ -58,17 +124,100 @@ func main() {
 	wbMgr := workbooks.NewManager(0, 0, runtimeController, time.Now)
 	// Enforce filesystem allow-list validation on open.
 	wbMgr.SetPathValidator(secMgr)
+	policy, err := workbooks.ParseStalePolicy(stalePolicy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	wbMgr.SetStalePolicy(policy)
+	wbMgr.SetReopenHook(func(path string, reopens int64) {
+		logger.Info().Str("path", path).Int64("reopens", reopens).Msg("workbook changed on disk; reopened")
+	})
+	delay, err := parseSaveDelay(saveDelay)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	wbMgr.SetSaveDelay(delay)
+	if delay > 0 {
+		logger.Info().Dur("save_delay", delay).Msg("batched workbook saves enabled")
+	}
+	keep, err := parseBackupKeep(backupKeep)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if backupDir, err = resolveBackupDir(backupDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if backupDir != "" {
+		logger.Info().Str("backup_dir", backupDir).Int("backup_keep", keep).Msg("write-ahead workbook backups enabled")
+	}
+	wbMgr.SetBackup(backupDir, keep)
+	wbMgr.SetFlushErrorHook(func(path string, err error) {
+		logger.Error().Err(err).Str("path", path).Msg("failed to save batched workbook changes; they remain pending")
+	})
+
+	// Audit log of write operations (MCPXCEL_AUDIT_LOG); disabled when unset.
+	auditLog, err := audit.NewFromEnv()
+	if err != nil {
+		logger.Error().Err(err).Msg("audit: failed to open log")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if auditLog != nil {
+		defer func() { _ = auditLog.Close() }()
+		auditLog.SetErrorHandler(func(err error) {
+			logger.Error().Err(err).Bool("strict", auditLog.Strict()).Msg("audit: failed to record write")
+		})
+		toolRegistry.SetAuditLogger(auditLog)
+		logger.Info().Str("audit_log", auditLog.Path()).Bool("strict", auditLog.Strict()).Msg("write audit log enabled")
+	}
+
+	// sequential_insights sessions, persisted under MCPXCEL_SESSION_DIR when set.
+	sessions, err := insights.NewSessionStoreFromEnv(20)
+	if err != nil {
+		logger.Error().Err(err).Msg("sessions: invalid configuration")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if dir := sessions.Dir(); dir != "" {
+		sessions.SetErrorHandler(func(err error) {
+			logger.Warn().Err(err).Msg("sessions: persistence failed")
+		})
+		logger.Info().Str("session_dir", dir).Msg("session persistence enabled")
+	}
+	toolRegistry.SetSessionStore(sessions)
 
 	writeFilter := registry.NewWriteToolFilterFromEnv()
+	toolRegistry.SetWriteFilter(writeFilter)
+	toolRegistry.SetAllowList(secMgr)
 
+	transport := "stdio"
+	if httpAddr != "" {
+		transport = "http"
+	}
+	toolRegistry.SetTransports(transport)
+	var (
+		promMetrics *telemetry.PromMetrics
+		metrics     telemetry.Metrics // stays a nil interface when disabled
+	)
+	if metricsAddr != "" {
+		promMetrics = telemetry.NewPromMetrics()
+		metrics = promMetrics
+	}
+	hooks := buildHooks(logger, toolRegistry, transport, metrics)
 	srv := server.NewMCPServer(
 		"MCP Excel Analysis Server",
 		version.Version(),
 		server.WithToolCapabilities(true),
 		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
 		server.WithRecovery(),
-		server.WithHooks(buildHooks(logger)),
+		server.WithHooks(hooks),
 		server.WithToolHandlerMiddleware(runtimeMW.ToolMiddleware),
+		server.WithToolHandlerMiddleware(sessionMiddleware),
 		server.WithToolFilter(func(ctx context.Context, tools []mcp.Tool) []mcp.Tool { return writeFilter.FilterTools(ctx, tools) }),
 	)
 
@@ -76,43 +225,191 @@ func main() {
 	registry.RegisterFoundationTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
 	// Register insights planning tool (planning-only by default)
 	registry.RegisterInsightsTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register duplicate-record detection (find_duplicates)
+	registry.RegisterDuplicateTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register distribution binning (histogram)
+	registry.RegisterHistogramTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register two-dimensional pivots (crosstab)
+	registry.RegisterCrosstabTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	registry.RegisterDiffTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	registry.RegisterMergeTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register key-based joins between ranges (column_lookup)
+	registry.RegisterLookupTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register cell formatting reads (read_styles)
+	registry.RegisterStyleTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register text normalization (clean_range); hidden unless writes are enabled
+	registry.RegisterCleanTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	registry.RegisterNameTools(srv, toolRegistry, wbMgr)
+	registry.RegisterTableTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	registry.RegisterCommentTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register structural edit tools (rows and sheets); hidden unless writes are enabled
+	registry.RegisterStructureTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register formula recalculation; hidden unless writes are enabled
+	registry.RegisterRecalcTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register CSV export; hidden unless writes are enabled
+	registry.RegisterExportTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register change tracking (what_changed) backed by per-session fingerprints
+	registry.RegisterChangeTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register explicit workbook lifecycle tools (open/close/list handles)
+	registry.RegisterWorkbookTools(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	// Register server_status (lifecycle state, uptime, load)
+	registry.RegisterStatusTools(srv, toolRegistry, runtimeController, wbMgr)
+	// Register workflow prompts (profile, change drivers, concentration, retention)
+	registry.RegisterPrompts(srv, runtimeController.LimitsSnapshot())
+	// Publish allow-listed workbooks as xlsx:// resources, rescanned on each resources/list
+	resources := registry.RegisterResources(srv, toolRegistry, runtimeController.LimitsSnapshot(), wbMgr)
+	hooks.AddBeforeListResources(func(ctx context.Context, id any, req *mcp.ListResourcesRequest) {
+		resources.Refresh(ctx)
+	})
 
 	toolContextSize := toolRegistry.ModelContextSize("gpt-4o")
 
 	logger.Info().
 		Ctx(ctx).
 		Str("version", version.Version()).
+		Str("commit", version.Commit()).
+		Str("build_date", version.Date()).
 		Int("max_concurrent_requests", limits.MaxConcurrentRequests).
 		Int("max_open_workbooks", limits.MaxOpenWorkbooks).
+		Int64("max_file_bytes", limits.MaxFileBytes).
+		Dur("cursor_ttl", limits.CursorTTL).
 		Int("model_context_size", toolContextSize).
 		Bool("stdio", useStdio).
 		Msg("server bootstrap configured")
 
-	if useStdio {
-		if err := server.ServeStdio(srv); err != nil {
-			// Use stderr for transport errors so clients don't misinterpret output
-			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+	health := telemetry.NewHealthHandler(version.Version(), runtimeController, wbMgr, secMgr)
+	var serve func() error
+	switch {
+	case useStdio && httpAddr != "":
+		fmt.Fprintln(os.Stderr, "choose one transport: --stdio or --http")
+		os.Exit(2)
+	case useStdio:
+		serve = func() error { return serveStdio(srv, runtimeController, logger, shutdownTimeout) }
+	case httpAddr != "":
+		token := os.Getenv("MCPXCEL_HTTP_TOKEN")
+		serve = func() error {
+			return serveHTTP(srv, httpAddr, token, health, runtimeController, toolRegistry, logger, shutdownTimeout)
+		}
+	default:
+		// If no transport flags provided, print usage and exit non-zero
+		fmt.Fprintln(os.Stderr, "no transport selected; use --stdio to run over stdio or --http <addr> to serve HTTP")
+		os.Exit(2)
+	}
+
+	if healthAddr != "" {
+		stopHealth, err := serveAux(healthAddr, healthEndpoint, health, logger)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "health listener: %v\n", err)
 			os.Exit(1)
 		}
-		return
+		defer stopHealth()
+	}
+	if promMetrics != nil {
+		stopMetrics, err := serveAux(metricsAddr, metricsEndpoint, promMetrics.Handler(), logger)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "metrics listener: %v\n", err)
+			os.Exit(1)
+		}
+		defer stopMetrics()
+	}
+
+	lifecycle.Transition(runtime.StateReady)
+	err = serve()
+	// Release workbook handles before reporting stopped.
+	closeCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	if cerr := wbMgr.Close(closeCtx); cerr != nil {
+		logger.Warn().Err(cerr).Msg("workbook manager close did not finish cleanly")
+	}
+	cancel()
+	lifecycle.Transition(runtime.StateStopped)
+	if err != nil {
+		// Use stderr for transport errors so clients don't misinterpret output
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serveStdio runs the stdio transport until stdin closes or a termination
+// signal arrives. On a signal the lifecycle moves to draining so new tool calls
+// are rejected with SHUTTING_DOWN, in-flight calls get up to shutdownTimeout to
+// finish, and then the transport is stopped.
+func serveStdio(srv *server.MCPServer, ctrl *runtime.Controller, logger zerolog.Logger, shutdownTimeout time.Duration) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigCh)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.NewStdioServer(srv).Listen(ctx, os.Stdin, os.Stdout) }()
+
+	select {
+	case err := <-errCh:
+		ctrl.Lifecycle().Transition(runtime.StateDraining)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	case sig := <-sigCh:
+		logger.Info().Str("signal", sig.String()).Msg("shutdown requested")
+		ctrl.Lifecycle().Transition(runtime.StateDraining)
 	}
 
-	// If no transport flags provided, print usage and exit non-zero
-	fmt.Fprintln(os.Stderr, "no transport selected; use --stdio to run over stdio")
-	os.Exit(2)
+	awaitInFlight(ctrl, logger, shutdownTimeout)
+	cancel()
+	if err := <-errCh; err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
 }
 
-// buildHooks constructs mcp-go server hooks for basic telemetry.
-func buildHooks(logger zerolog.Logger) *server.Hooks {
+// awaitInFlight waits for in-flight tool calls to finish, bounded by timeout.
+func awaitInFlight(ctrl *runtime.Controller, logger zerolog.Logger, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for ctrl.InFlight() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if n := ctrl.InFlight(); n > 0 {
+		logger.Warn().Int64("in_flight", n).Msg("shutdown timeout reached with calls still running")
+	}
+}
+
+// defaultStatusFile returns MCPXCEL_STATUS_FILE or a file in the OS temp dir.
+func defaultStatusFile() string {
+	if v := os.Getenv("MCPXCEL_STATUS_FILE"); v != "" {
+		return v
+	}
+	return filepath.Join(os.TempDir(), "mcpxcel.status")
+}
+
+// sessionMiddleware stores the MCP client session ID in the call context so
+// handlers can attribute audited writes without depending on the transport.
+func sessionMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if cs := server.ClientSessionFromContext(ctx); cs != nil {
+			ctx = audit.WithSession(ctx, cs.SessionID())
+		}
+		return next(ctx, req)
+	}
+}
+
+// buildHooks constructs mcp-go server hooks for basic telemetry and per-session
+// cleanup. A nil metrics skips tool call metrics.
+func buildHooks(logger zerolog.Logger, reg *registry.Registry, transport string, metrics telemetry.Metrics) *server.Hooks {
 	hooks := &server.Hooks{}
 
-	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
-		logger.Info().Str("session_id", session.SessionID()).Msg("session registered")
-	})
+	// Streamable HTTP registers sessions only for GET streams, which come and
+	// go within one client session; serveHTTP tracks those sessions itself.
+	if transport != "http" {
+		hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+			sessionOpened(logger, session.SessionID(), transport)
+		})
 
-	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
-		logger.Info().Str("session_id", session.SessionID()).Msg("session unregistered")
-	})
+		hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+			sessionClosed(logger, reg, session.SessionID(), transport)
+		})
+	}
 
 	hooks.AddAfterListTools(func(ctx context.Context, id any, req *mcp.ListToolsRequest, res *mcp.ListToolsResult) {
 		// Keep it light: tool count only
@@ -123,13 +420,29 @@ func buildHooks(logger zerolog.Logger) *server.Hooks {
 		logger.Info().Str("uri", req.Params.URI).Msg("resource read served")
 	})
 
-	hooks.AddAfterCallTool(func(ctx context.Context, id any, req *mcp.CallToolRequest, res *mcp.CallToolResult) {
-		logger.Info().Str("tool", req.Params.Name).Msg("tool call served")
-	})
-
 	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
 		logger.Error().Str("method", string(method)).Err(err).Msg("request error")
 	})
 
+	if metrics != nil {
+		known := func(tool string) bool {
+			_, ok := reg.Get(tool)
+			return ok
+		}
+		telemetry.NewToolCallObserver(metrics, known).Attach(hooks)
+	}
+
 	return hooks
 }
+
+// sessionOpened records a new client session.
+func sessionOpened(logger zerolog.Logger, id, transport string) {
+	logger.Info().Str("session_id", id).Str("transport", transport).Msg("session registered")
+}
+
+// sessionClosed drops per-session state and records the session's end.
+func sessionClosed(logger zerolog.Logger, reg *registry.Registry, id, transport string) {
+	// Drop what_changed fingerprints recorded for this session
+	reg.Changes().ForgetSession(id)
+	logger.Info().Str("session_id", id).Str("transport", transport).Msg("session unregistered")
+}
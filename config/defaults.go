@@ -12,13 +12,39 @@ const (
 	DefaultMaxOpenWorkbooks      = 4
 
 	// Payload and row limits
-	DefaultMaxPayloadBytes = 128 * 1024 // 128KB
-	DefaultMaxCellsPerOp   = 10_000
-	DefaultPreviewRowLimit = 10 // First 10 rows by default
+	DefaultMaxPayloadBytes  = 128 * 1024 // 128KB
+	DefaultMaxCellsPerOp    = 10_000
+	DefaultPreviewRowLimit  = 10        // First 10 rows by default
+	DefaultMaxRowsPerEdit   = 1000      // insert_rows/delete_rows count cap
+	DefaultMaxExportCells   = 1_000_000 // export_range_csv cap (files bypass the payload limit)
+	DefaultMaxCrosstabCells = 2_500     // crosstab matrix cap (row keys × column keys)
+	// Share of DefaultMaxPayloadBytes that a tool's text summary may use
+	DefaultTextBudgetPercent = 50
+
+	// DefaultMaxFileBytes caps the on-disk size of workbooks accepted for open.
+	DefaultMaxFileBytes int64 = 100 << 20 // 100MB
+
+	// Pagination cursors older than this are rejected with CURSOR_EXPIRED
+	DefaultCursorTTL = 30 * time.Minute
+
+	// Markdown encoding: cells longer than this many characters are truncated
+	DefaultMarkdownCellWidth = 60
+
+	// read_comments: comment texts longer than this many runes are truncated
+	DefaultCommentTextRunes = 500
 
 	// Workbook lifecycle
 	DefaultWorkbookIdleTTL       = 5 * time.Minute
 	DefaultWorkbookCleanupPeriod = 30 * time.Second
+	// How long Open waits for a free slot before evicting the least-recently-used idle workbook
+	DefaultWorkbookAcquireWait = 250 * time.Millisecond
+	// CSV files are parsed into memory; larger files are rejected
+	DefaultMaxCSVBytes = 64 << 20 // 64MB
+
+	// Change tracking (what_changed): client sessions remembered, and
+	// workbooks remembered per session
+	DefaultMaxTrackedSessions            = 256
+	DefaultMaxTrackedWorkbooksPerSession = 16
 )
 
 const (
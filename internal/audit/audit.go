@@ -0,0 +1,160 @@
+// Package audit records workbook write operations as append-only JSON lines so
+// operators can tell which cells changed, when, and from which session.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAuditFailed indicates a strict-mode logger could not persist a record;
+// the write it describes must not be committed.
+var ErrAuditFailed = errors.New("audit: failed to record write")
+
+// Record describes one write operation.
+type Record struct {
+	Time        time.Time `json:"ts"`
+	SessionID   string    `json:"session_id,omitempty"`
+	Tool        string    `json:"tool"`
+	Path        string    `json:"path"`
+	Sheet       string    `json:"sheet,omitempty"`
+	Range       string    `json:"range,omitempty"`
+	Cells       int       `json:"cells"`
+	ContentHash string    `json:"content_hash,omitempty"`
+	Source      string    `json:"source,omitempty"`
+}
+
+// Logger appends Records to a JSONL file. A nil *Logger discards records, so
+// callers need not check whether auditing is configured.
+type Logger struct {
+	mu      sync.Mutex
+	f       *os.File
+	strict  bool
+	now     func() time.Time
+	onError func(error)
+}
+
+// Open opens (creating if needed) the audit file at path for appending. In
+// strict mode Log returns an error wrapping ErrAuditFailed when a record
+// cannot be written; otherwise failures go to the error handler only.
+func Open(path string, strict bool) (*Logger, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %q: %w", path, err)
+	}
+	return &Logger{f: f, strict: strict, now: time.Now}, nil
+}
+
+// NewFromEnv opens the file named by MCPXCEL_AUDIT_LOG, or returns nil when it
+// is unset. Strict mode is on unless MCPXCEL_AUDIT_STRICT is false/0/no.
+func NewFromEnv() (*Logger, error) {
+	path := strings.TrimSpace(os.Getenv("MCPXCEL_AUDIT_LOG"))
+	if path == "" {
+		return nil, nil
+	}
+	strict := true
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("MCPXCEL_AUDIT_STRICT"))) {
+	case "false", "0", "no":
+		strict = false
+	}
+	return Open(path, strict)
+}
+
+// SetErrorHandler installs a callback for records that could not be written.
+func (l *Logger) SetErrorHandler(fn func(error)) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onError = fn
+}
+
+// Strict reports whether write failures are returned to callers.
+func (l *Logger) Strict() bool {
+	return l != nil && l.strict
+}
+
+// Path returns the audit file path, or "" for a nil Logger.
+func (l *Logger) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.f.Name()
+}
+
+// Log stamps rec with the current time when unset and appends it as one line.
+func (l *Logger) Log(rec Record) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rec.Time.IsZero() {
+		rec.Time = l.now().UTC()
+	}
+	line, err := json.Marshal(rec)
+	if err == nil {
+		// A single write keeps each line intact under O_APPEND.
+		_, err = l.f.Write(append(line, '\n'))
+	}
+	if err == nil {
+		return nil
+	}
+	if l.onError != nil {
+		l.onError(err)
+	}
+	if l.strict {
+		return fmt.Errorf("%w: %v", ErrAuditFailed, err)
+	}
+	return nil
+}
+
+// Close closes the audit file.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// HashValues returns a SHA-256 digest of a 2D block of values. Each cell is
+// length-prefixed and rows are delimited so different shapes never collide.
+func HashValues(rows [][]string) string {
+	h := sha256.New()
+	var n [8]byte
+	for _, row := range rows {
+		binary.BigEndian.PutUint64(n[:], uint64(len(row)))
+		h.Write(n[:])
+		for _, v := range row {
+			binary.BigEndian.PutUint64(n[:], uint64(len(v)))
+			h.Write(n[:])
+			h.Write([]byte(v))
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+type sessionKey struct{}
+
+// WithSession returns ctx carrying the MCP client session ID.
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+// SessionFromContext returns the session ID stored by WithSession, or "".
+func SessionFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
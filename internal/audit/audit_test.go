@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path, true)
+	require.NoError(t, err)
+	require.NoError(t, l.Log(Record{Tool: "write_range", Path: "/data/a.xlsx", Sheet: "S", Range: "A1:B2", Cells: 4, ContentHash: HashValues([][]string{{"1", "2"}, {"3", "4"}})}))
+	require.NoError(t, l.Log(Record{Tool: "add_sheet", Path: "/data/a.xlsx", Sheet: "New"}))
+	require.NoError(t, l.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var recs []Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Record
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &r))
+		recs = append(recs, r)
+	}
+	require.Len(t, recs, 2)
+	require.Equal(t, "write_range", recs[0].Tool)
+	require.Equal(t, 4, recs[0].Cells)
+	require.False(t, recs[0].Time.IsZero())
+	require.Contains(t, recs[0].ContentHash, "sha256:")
+	require.Equal(t, "New", recs[1].Sheet)
+}
+
+func TestLoggerStrictMode(t *testing.T) {
+	dir := t.TempDir()
+	strict, err := Open(filepath.Join(dir, "strict.jsonl"), true)
+	require.NoError(t, err)
+	require.NoError(t, strict.Close())
+	require.ErrorIs(t, strict.Log(Record{Tool: "write_range"}), ErrAuditFailed)
+
+	lenient, err := Open(filepath.Join(dir, "lenient.jsonl"), false)
+	require.NoError(t, err)
+	var reported error
+	lenient.SetErrorHandler(func(err error) { reported = err })
+	require.NoError(t, lenient.Close())
+	require.NoError(t, lenient.Log(Record{Tool: "write_range"}))
+	require.Error(t, reported)
+
+	var disabled *Logger
+	require.NoError(t, disabled.Log(Record{Tool: "write_range"}))
+}
+
+func TestHashValuesDistinguishesShapes(t *testing.T) {
+	a := HashValues([][]string{{"ab", "c"}})
+	require.Equal(t, a, HashValues([][]string{{"ab", "c"}}))
+	require.NotEqual(t, a, HashValues([][]string{{"a", "bc"}}))
+	require.NotEqual(t, a, HashValues([][]string{{"ab"}, {"c"}}))
+}
+
+func TestSessionContext(t *testing.T) {
+	require.Equal(t, "", SessionFromContext(context.Background()))
+	ctx := WithSession(context.Background(), "s-1")
+	require.Equal(t, "s-1", SessionFromContext(ctx))
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("MCPXCEL_AUDIT_LOG", "")
+	l, err := NewFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	t.Setenv("MCPXCEL_AUDIT_LOG", filepath.Join(t.TempDir(), "audit.jsonl"))
+	t.Setenv("MCPXCEL_AUDIT_STRICT", "false")
+	l, err = NewFromEnv()
+	require.NoError(t, err)
+	defer l.Close()
+	require.False(t, l.Strict())
+
+	t.Setenv("MCPXCEL_AUDIT_LOG", filepath.Join(t.TempDir(), "missing", "audit.jsonl"))
+	_, err = NewFromEnv()
+	require.True(t, err != nil && !errors.Is(err, ErrAuditFailed))
+}
@@ -0,0 +1,17 @@
+package insights
+
+import "context"
+
+// cancelCheckRows is how many rows a streaming scan processes between
+// context checks, keeping the check cheap on large sheets.
+const cancelCheckRows = 1000
+
+// scanCanceled returns ctx.Err() on every cancelCheckRows-th row so long scans
+// stop promptly, and release the workbook lock, once the call is cancelled or
+// times out.
+func scanCanceled(ctx context.Context, row int) error {
+	if row%cancelCheckRows != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
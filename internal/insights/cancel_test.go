@@ -0,0 +1,69 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+// cancelOnFirstCheck cancels itself the first time a scan polls Err, so the
+// cancellation deterministically lands mid-scan rather than before it.
+type cancelOnFirstCheck struct {
+	context.Context
+	cancel context.CancelFunc
+	checks int
+}
+
+func (c *cancelOnFirstCheck) Err() error {
+	c.checks++
+	if c.checks == 1 {
+		c.cancel()
+		return nil
+	}
+	return c.Context.Err()
+}
+
+func TestConcentrationMetrics_CancelledMidScan(t *testing.T) {
+	const rows = 20000
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	require.NoError(t, err)
+	require.NoError(t, sw.SetRow("A1", []any{"Group", "Amount"}))
+	for r := 2; r <= rows+1; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, sw.SetRow(cell, []any{fmt.Sprintf("G%d", r%50), r}))
+	}
+	require.NoError(t, sw.Flush())
+	path := filepath.Join(t.TempDir(), "large.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxCellsPerOp = 2 * rows
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	c := &Concentrator{Limits: limits, Mgr: mgr}
+	// Warm the cache so the cancellation is observed by the scan, not the open.
+	_, _, err = mgr.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := &cancelOnFirstCheck{Context: base, cancel: cancel}
+	start := time.Now()
+	_, err = c.ConcentrationMetrics(ctx, ConcentrationMetricsInput{Path: path, Sheet: "Sheet1", Range: fmt.Sprintf("A1:B%d", rows+1), DimIndex: 1, MeasureIndex: 2})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, 2, ctx.checks, "scan should stop at the next check after cancellation")
+
+	// The read lock was released: the same handle serves a fresh call.
+	out, err := c.ConcentrationMetrics(context.Background(), ConcentrationMetricsInput{Path: path, Sheet: "Sheet1", Range: fmt.Sprintf("A1:B%d", rows+1), DimIndex: 1, MeasureIndex: 2})
+	require.NoError(t, err)
+	require.False(t, out.Meta.Truncated)
+}
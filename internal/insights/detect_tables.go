@@ -2,6 +2,8 @@ package insights
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"sort"
 	"strconv"
@@ -9,23 +11,30 @@ import (
 
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 	"github.com/xuri/excelize/v2"
 )
 
 // DetectTablesInput controls multi-table detection within a sheet.
 type DetectTablesInput struct {
 	Path             string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
-	Sheet            string `json:"sheet" validate:"required" jsonschema_description:"Sheet name to scan"`
+	Sheet            string `json:"sheet" validate:"required_without_all=Cursor AllSheets" jsonschema_description:"Sheet name to scan"`
+	AllSheets        bool   `json:"all_sheets,omitempty" jsonschema_description:"Scan every sheet and rank candidates across the workbook; each sheet covers an equal share of the cell limit, and unreadable sheets are skipped with a warning"`
 	MaxTables        int    `json:"max_tables,omitempty" validate:"omitempty,min=1,max=10" jsonschema_description:"Max number of table candidates to return (Top-K)"`
-	MaxScanRows      int    `json:"max_scan_rows,omitempty" jsonschema_description:"Max number of rows to scan (bounded)"`
-	MaxScanCols      int    `json:"max_scan_cols,omitempty" jsonschema_description:"Max number of columns to scan (bounded)"`
+	MaxScanRows      int    `json:"max_scan_rows,omitempty" jsonschema_description:"Max rows to scan in this call, counted from start_row (default: through the last row); a cursor is returned when rows remain"`
+	MaxScanCols      int    `json:"max_scan_cols,omitempty" jsonschema_description:"Max number of columns to scan, counted from start_col (bounded)"`
+	StartRow         int    `json:"start_row,omitempty" validate:"omitempty,min=1" jsonschema_description:"1-based sheet row to start scanning at (default 1); must lie within the used range"`
+	StartCol         int    `json:"start_col,omitempty" validate:"omitempty,min=1" jsonschema_description:"1-based sheet column to start scanning at (default 1); must lie within the used range"`
+	GapTolerance     *int   `json:"gap_tolerance,omitempty" validate:"omitempty,min=0,max=3" jsonschema_description:"Empty cells (within a row or column) bridged when growing a table, so spacer columns and blank separator rows stay inside one table (default 1; 0 = strict adjacency)"`
 	HeaderRow        int    `json:"header_row,omitempty" jsonschema_description:"Optional 1-based header row hint; defaults to first non-empty row of each block"`
 	HeaderSampleRows int    `json:"header_sample_rows,omitempty" validate:"omitempty,min=1,max=5" jsonschema_description:"Include top-N rows of each candidate for header sampling (default 2, max 5)"`
 	HeaderSampleCols int    `json:"header_sample_cols,omitempty" validate:"omitempty,min=1,max=32" jsonschema_description:"Include leftmost N columns of header sample (default 12, max 32)"`
+	Cursor           string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor from a previous call; scans the next window of rows with the same sheet and bounds"`
 }
 
 // TableCandidate describes a detected rectangular region that likely forms a table.
 type TableCandidate struct {
+	Sheet            string     `json:"sheet,omitempty" jsonschema_description:"Sheet of the candidate (all_sheets mode)"`
 	Range            string     `json:"range"`
 	Header           []string   `json:"header,omitempty"`
 	Confidence       float64    `json:"confidence"`
@@ -33,6 +42,8 @@ type TableCandidate struct {
 	Cols             int        `json:"cols"`
 	HeaderSample     [][]string `json:"header_sample,omitempty"`
 	HeaderSampleCols int        `json:"header_sample_cols_effective,omitempty"`
+	Open             bool       `json:"open,omitempty" jsonschema_description:"Reaches the last scanned rows (within gap_tolerance) while rows remain; the table may continue in the next window"`
+	GapBridged       bool       `json:"gap_bridged,omitempty" jsonschema_description:"Blocks were joined across empty cells; confidence is discounted slightly"`
 }
 
 // DetectTablesOutput carries ranked candidates with basic scan metadata.
@@ -41,20 +52,61 @@ type DetectTablesOutput struct {
 	Sheet      string           `json:"sheet"`
 	Candidates []TableCandidate `json:"candidates"`
 	Meta       struct {
-		ScannedRows int  `json:"scanned_rows"`
-		ScannedCols int  `json:"scanned_cols"`
-		Truncated   bool `json:"truncated"`
+		ScannedRows      int    `json:"scanned_rows"`
+		ScannedCols      int    `json:"scanned_cols"`
+		Truncated        bool   `json:"truncated"`
+		StartRow         int    `json:"start_row" jsonschema_description:"First sheet row covered by this scan"`
+		EndRow           int    `json:"end_row" jsonschema_description:"Last sheet row covered by this scan"`
+		StartCol         int    `json:"start_col" jsonschema_description:"First sheet column covered by this scan"`
+		EndCol           int    `json:"end_col" jsonschema_description:"Last sheet column covered by this scan"`
+		Bands            int    `json:"bands" jsonschema_description:"Row bands processed; each band holds at most the per-operation cell limit"`
+		ColumnsTruncated bool   `json:"columns_truncated" jsonschema_description:"Used columns beyond scanned_cols were not scanned"`
+		NextStartRow     int    `json:"next_start_row,omitempty" jsonschema_description:"First row of the next window when rows remain"`
+		NextCursor       string `json:"next_cursor,omitempty"`
+		// all_sheets mode
+		Sheets        []SheetScan `json:"sheets,omitempty" jsonschema_description:"Per-sheet scan coverage (all_sheets mode)"`
+		ScanTruncated bool        `json:"scan_truncated,omitempty" jsonschema_description:"Some sheet was not scanned to its last row or column within its share of the cell limit"`
+		Warnings      []string    `json:"warnings,omitempty"`
+		// Text payload size and the bounds it was built under.
+		BytesReturned int         `json:"bytes_returned,omitempty"`
+		Limits        *PageLimits `json:"limits,omitempty"`
 	} `json:"meta"`
 }
 
+// PageLimits echoes the effective bounds of an insight tool's response so
+// clients can size later calls.
+type PageLimits struct {
+	Unit            string `json:"unit" jsonschema_description:"Unit of page_size: rows"`
+	PageSize        int    `json:"page_size" jsonschema_description:"Effective rows scanned or sampled per call"`
+	MaxPayloadBytes int    `json:"max_payload_bytes" jsonschema_description:"Global cap on a response's text payload"`
+}
+
+// SheetScan describes the rows and columns one sheet's scan covered.
+type SheetScan struct {
+	Sheet            string `json:"sheet"`
+	StartRow         int    `json:"start_row"`
+	EndRow           int    `json:"end_row"`
+	StartCol         int    `json:"start_col"`
+	EndCol           int    `json:"end_col"`
+	ScannedRows      int    `json:"scanned_rows"`
+	ScannedCols      int    `json:"scanned_cols"`
+	Bands            int    `json:"bands"`
+	ColumnsTruncated bool   `json:"columns_truncated,omitempty"`
+	NextStartRow     int    `json:"next_start_row,omitempty"`
+	Candidates       int    `json:"candidates"`
+}
+
 // Detector owns dependencies and effective limits for detection.
 type Detector struct {
 	Limits runtime.Limits
 	Mgr    *workbooks.Manager
 }
 
-// DetectTables scans a sheet for multiple rectangular table regions using
-// streaming reads and simple heuristics for header detection and block growth.
+// DetectTables scans a sheet, or every sheet with all_sheets, for multiple
+// rectangular table regions using streaming reads and simple heuristics for
+// header detection and block growth. In all_sheets mode each sheet's coverage
+// is bounded by an equal share of the cell budget, sheets that cannot be read
+// are skipped with a warning, and candidates are ranked across the workbook.
 func (d *Detector) DetectTables(ctx context.Context, in DetectTablesInput) (DetectTablesOutput, error) {
 	var out DetectTablesOutput
 	out.Sheet = strings.TrimSpace(in.Sheet)
@@ -69,171 +121,88 @@ func (d *Detector) DetectTables(ctx context.Context, in DetectTablesInput) (Dete
 	if maxTables <= 0 || maxTables > 10 {
 		maxTables = 5
 	}
-
-	// Establish scan bounds using used range and global limits.
-	type grid struct {
-		rows int
-		cols int
-		data [][]bool
-		vals [][]string // optional values for header preview/type hints
+	budget := d.Limits.MaxCellsPerOp
+	if budget <= 0 {
+		budget = 10000
 	}
 
-	var g grid
-
+	var cands []TableCandidate
 	err = d.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
-		// Resolve sheet used range to cap scanning to active cells
-		usedRows, usedCols := 0, 0
-		if dim, derr := f.GetSheetDimension(out.Sheet); derr == nil && dim != "" {
-			parts := strings.Split(dim, ":")
-			if len(parts) == 2 {
-				x1, y1, e1 := excelize.CellNameToCoordinates(parts[0])
-				x2, y2, e2 := excelize.CellNameToCoordinates(parts[1])
-				if e1 == nil && e2 == nil && x2 >= x1 && y2 >= y1 {
-					usedCols = x2
-					usedRows = y2
-				}
+		if !in.AllSheets {
+			scan, sc, serr := d.scanSheet(ctx, f, out.Sheet, in, budget, in.MaxScanRows, 0, false)
+			if serr != nil {
+				return serr
 			}
+			cands = sc
+			out.Meta.ScannedRows, out.Meta.ScannedCols = scan.ScannedRows, scan.ScannedCols
+			out.Meta.StartRow, out.Meta.EndRow, out.Meta.Bands = scan.StartRow, scan.EndRow, scan.Bands
+			out.Meta.StartCol, out.Meta.EndCol = scan.StartCol, scan.EndCol
+			out.Meta.ColumnsTruncated, out.Meta.NextStartRow = scan.ColumnsTruncated, scan.NextStartRow
+			return nil
 		}
-		// Fallback for unknown dimensions
-		if usedCols <= 0 {
-			usedCols = 256
-		}
-		if usedRows <= 0 {
-			usedRows = 200
-		}
-
-		scanRows := in.MaxScanRows
-		if scanRows <= 0 || scanRows > usedRows {
-			scanRows = usedRows
-		}
-		scanCols := in.MaxScanCols
-		if scanCols <= 0 || scanCols > usedCols {
-			// limit columns to a practical bound
-			if usedCols > 256 {
-				scanCols = 256
-			} else {
-				scanCols = usedCols
+		sheets := f.GetSheetList()
+		share := budget / max(1, len(sheets))
+		for _, sh := range sheets {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
-		}
-		// Respect global cell budget (≤ MaxCellsPerOp)
-		budget := d.Limits.MaxCellsPerOp
-		if budget <= 0 {
-			budget = 10000
-		}
-		for scanRows*scanCols > budget {
-			if scanRows > scanCols {
-				scanRows--
-			} else {
-				scanCols--
-			}
-		}
-
-		g.rows, g.cols = scanRows, scanCols
-		g.data = make([][]bool, scanRows)
-		g.vals = make([][]string, scanRows)
-		for i := 0; i < scanRows; i++ {
-			g.data[i] = make([]bool, scanCols)
-			g.vals[i] = make([]string, scanCols)
-		}
-
-		r, rerr := f.Rows(out.Sheet)
-		if rerr != nil {
-			return rerr
-		}
-		defer r.Close()
-
-		rowIdx := 0
-		for r.Next() {
-			rowIdx++
-			if rowIdx > scanRows {
-				break
-			}
-			rowVals, cerr := r.Columns()
-			if cerr != nil {
-				return cerr
-			}
-			// Fill presence up to scanCols
-			for c := 0; c < scanCols && c < len(rowVals); c++ {
-				v := strings.TrimSpace(rowVals[c])
-				if v != "" {
-					g.data[rowIdx-1][c] = true
-					g.vals[rowIdx-1][c] = v
+			// Each sheet covers at most its share of the budget.
+			scan, sc, serr := d.scanSheet(ctx, f, sh, in, budget, in.MaxScanRows, share, true)
+			if serr != nil {
+				if errors.Is(serr, context.Canceled) || errors.Is(serr, context.DeadlineExceeded) {
+					return serr
 				}
+				out.Meta.Warnings = append(out.Meta.Warnings, fmt.Sprintf("sheet %q skipped: %v", sh, serr))
+				continue
 			}
+			scan.Candidates = len(sc)
+			out.Meta.Sheets = append(out.Meta.Sheets, scan)
+			out.Meta.ScanTruncated = out.Meta.ScanTruncated || scan.NextStartRow > 0 || scan.ColumnsTruncated
+			cands = append(cands, sc...)
 		}
-		return r.Error()
+		return nil
 	})
 	if err != nil {
 		return out, err
 	}
 
-	out.Meta.ScannedRows = g.rows
-	out.Meta.ScannedCols = g.cols
-
-	// Find connected components of non-empty cells (4-directional adjacency).
-	type rect struct{ r1, c1, r2, c2 int }
-	visited := make([][]bool, g.rows)
-	for i := 0; i < g.rows; i++ {
-		visited[i] = make([]bool, g.cols)
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Confidence > cands[j].Confidence })
+	if len(cands) > maxTables {
+		out.Meta.Truncated = true
+		out.Candidates = cands[:maxTables]
+	} else {
+		out.Candidates = cands
 	}
+	return out, nil
+}
 
-	comps := make([]rect, 0, 8)
-	var queue [][2]int
-	enqueue := func(r, c int) { queue = append(queue, [2]int{r, c}) }
-	dequeue := func() (int, int) { p := queue[0]; queue = queue[1:]; return p[0], p[1] }
-
-	for r := 0; r < g.rows; r++ {
-		for c := 0; c < g.cols; c++ {
-			if !g.data[r][c] || visited[r][c] {
-				continue
-			}
-			// BFS for this component
-			visited[r][c] = true
-			queue = queue[:0]
-			enqueue(r, c)
-			rr1, cc1, rr2, cc2 := r, c, r, c
-			for len(queue) > 0 {
-				cr, cc := dequeue()
-				// Update bounds
-				if cr < rr1 {
-					rr1 = cr
-				}
-				if cr > rr2 {
-					rr2 = cr
-				}
-				if cc < cc1 {
-					cc1 = cc
-				}
-				if cc > cc2 {
-					cc2 = cc
-				}
-				// 4-neighbors
-				if cr > 0 && g.data[cr-1][cc] && !visited[cr-1][cc] {
-					visited[cr-1][cc] = true
-					enqueue(cr-1, cc)
-				}
-				if cr+1 < g.rows && g.data[cr+1][cc] && !visited[cr+1][cc] {
-					visited[cr+1][cc] = true
-					enqueue(cr+1, cc)
-				}
-				if cc > 0 && g.data[cr][cc-1] && !visited[cr][cc-1] {
-					visited[cr][cc-1] = true
-					enqueue(cr, cc-1)
-				}
-				if cc+1 < g.cols && g.data[cr][cc+1] && !visited[cr][cc+1] {
-					visited[cr][cc+1] = true
-					enqueue(cr, cc+1)
-				}
-			}
-			// Reject tiny blobs (< 2x2)
-			if (rr2-rr1+1) >= 2 && (cc2-cc1+1) >= 2 {
-				comps = append(comps, rect{r1: rr1, c1: cc1, r2: rr2, c2: cc2})
-			}
-		}
+// scanSheet detects table blocks on one sheet of an open workbook. Rows are
+// processed in bands sized to budget cells, with components that cross a
+// band boundary carried into the next band, so the whole used range is
+// covered while only one band is held in memory. Merged cells with a value
+// count as filled across their whole area, and gaps of up to gap_tolerance
+// empty cells are bridged. A second pass reads just the header and sample
+// rows of the detected blocks. The scan window starts at start_row and
+// start_col, which must fall within the used range, and candidate ranges are
+// reported in sheet coordinates. maxRows bounds the rows scanned (0 = all);
+// when cover is positive the rows are further bounded to about cover cells.
+// Candidates carry the sheet name when tag is set.
+func (d *Detector) scanSheet(ctx context.Context, f *excelize.File, sheet string, in DetectTablesInput, budget, maxRows, cover int, tag bool) (SheetScan, []TableCandidate, error) {
+	scan := SheetScan{Sheet: sheet}
+	gapTol := 1
+	if in.GapTolerance != nil {
+		gapTol = *in.GapTolerance
 	}
-
-	// Build candidates with header heuristic and confidence ranking
-	cands := make([]TableCandidate, 0, len(comps))
+	startRow := in.StartRow
+	if startRow <= 0 {
+		startRow = 1
+	}
+	startCol := in.StartCol
+	if startCol <= 0 {
+		startCol = 1
+	}
+	scan.StartRow, scan.StartCol = startRow, startCol
+	off := startCol - 1 // window column c is sheet column off+c+1
 	// Bound header sample rows
 	hsr := in.HeaderSampleRows
 	if hsr <= 0 || hsr > 5 {
@@ -244,23 +213,216 @@ func (d *Detector) DetectTables(ctx context.Context, in DetectTablesInput) (Dete
 	if hsc <= 0 || hsc > 32 {
 		hsc = 12
 	}
+
+	var comps []tableRect
+	rowVals := map[int][]string{} // header and sample rows, keyed by sheet row
+
+	// Resolve sheet used range to cap scanning to active columns
+	usedCols, usedRows, usedRange := 0, 0, ""
+	if dim, derr := f.GetSheetDimension(sheet); derr == nil && dim != "" {
+		parts := strings.Split(dim, ":")
+		if len(parts) == 2 {
+			x1, y1, e1 := excelize.CellNameToCoordinates(parts[0])
+			x2, y2, e2 := excelize.CellNameToCoordinates(parts[1])
+			if e1 == nil && e2 == nil && x2 >= x1 && y2 >= y1 {
+				usedCols, usedRows, usedRange = x2, y2, dim
+			}
+		}
+	}
+	// Fallback for unknown dimensions
+	knownCols := usedCols > 0
+	if knownCols && (startRow > usedRows || startCol > usedCols) {
+		return scan, nil, windowOutside(startRow, startCol, usedRange)
+	}
+	if knownCols {
+		usedCols -= off // columns from start_col to the last used column
+	} else {
+		usedCols = 256
+	}
+	scanCols := in.MaxScanCols
+	if scanCols <= 0 || scanCols > usedCols {
+		// limit columns to a practical bound
+		scanCols = minInt(usedCols, 256)
+	}
+	// Each band holds at most budget cells
+	if scanCols > budget {
+		scanCols = budget
+	}
+	scan.ColumnsTruncated = knownCols && usedCols > scanCols
+	scan.EndCol = off + scanCols
+	if cover > 0 {
+		if rows := max(1, cover/scanCols); maxRows <= 0 || rows < maxRows {
+			maxRows = rows
+		}
+	}
+	endRow := math.MaxInt
+	if maxRows > 0 {
+		endRow = startRow + maxRows - 1
+	}
+
+	// Merged areas carry their value only in the top-left cell; treat the
+	// rest of the area as filled so merged titles and headers connect.
+	var merged []tableRect
+	mcs, merr := f.GetMergeCells(sheet)
+	if merr != nil {
+		return scan, nil, merr
+	}
+	for _, mc := range mcs {
+		if strings.TrimSpace(mc.GetCellValue()) == "" {
+			continue
+		}
+		x1, y1, e1 := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		x2, y2, e2 := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if e1 == nil && e2 == nil {
+			merged = append(merged, tableRect{r1: y1, c1: x1 - 1, r2: y2, c2: x2 - 1})
+		}
+	}
+
+	bandRows := budget / scanCols
+	band := make([][]bool, bandRows)
+	for i := range band {
+		band[i] = make([]bool, scanCols)
+	}
+	lab := newComponentLabeler(scanCols, gapTol)
+	bandStart, bandLen := startRow, 0
+	flush := func() {
+		for i := 0; i < bandLen; i++ {
+			lab.addRow(bandStart+i, band[i])
+		}
+		bandStart += bandLen
+		bandLen = 0
+		scan.Bands++
+	}
+
+	r, rerr := f.Rows(sheet)
+	if rerr != nil {
+		return scan, nil, rerr
+	}
+	defer r.Close()
+
+	rowIdx, widest := 0, 0
+	lastRow := startRow - 1
+	for r.Next() {
+		rowIdx++
+		if err := scanCanceled(ctx, rowIdx); err != nil {
+			return scan, nil, err
+		}
+		if rowIdx < startRow {
+			continue
+		}
+		if rowIdx > endRow {
+			scan.NextStartRow = rowIdx
+			break
+		}
+		vals, cerr := r.Columns()
+		if cerr != nil {
+			return scan, nil, cerr
+		}
+		widest = max(widest, len(vals))
+		// Fill presence for the window's columns
+		row := band[bandLen]
+		for c := range row {
+			row[c] = off+c < len(vals) && strings.TrimSpace(vals[off+c]) != ""
+		}
+		for _, m := range merged {
+			if rowIdx >= m.r1 && rowIdx <= m.r2 {
+				for c := max(m.c1-off, 0); c <= m.c2-off && c < scanCols; c++ {
+					row[c] = true
+				}
+			}
+		}
+		bandLen++
+		lastRow = rowIdx
+		if bandLen == bandRows {
+			flush()
+		}
+	}
+	if err := r.Error(); err != nil {
+		return scan, nil, err
+	}
+	if bandLen > 0 {
+		flush()
+	}
+	// Without a stored dimension, check the window against the rows read.
+	if !knownCols && (startRow > 1 || startCol > 1) && (lastRow < startRow || widest < startCol) {
+		return scan, nil, windowOutside(startRow, startCol, "")
+	}
+	comps = lab.finish()
+	scan.EndRow = lastRow
+	scan.ScannedRows = scan.EndRow - scan.StartRow + 1
+	scan.ScannedCols = scanCols
+	if len(comps) == 0 {
+		return scan, nil, nil
+	}
+
+	// Second pass: read only the header and sample rows of each block.
+	lastNeeded := 0
+	for _, rc := range comps {
+		for rr := rc.r1; rr < rc.r1+hsr && rr <= rc.r2; rr++ {
+			rowVals[rr] = nil
+		}
+		if in.HeaderRow >= rc.r1 && in.HeaderRow <= rc.r2 {
+			rowVals[in.HeaderRow] = nil
+		}
+	}
+	for rr := range rowVals {
+		lastNeeded = max(lastNeeded, rr)
+	}
+	r2, rerr := f.Rows(sheet)
+	if rerr != nil {
+		return scan, nil, rerr
+	}
+	defer r2.Close()
+	rowIdx = 0
+	for r2.Next() {
+		rowIdx++
+		if err := scanCanceled(ctx, rowIdx); err != nil {
+			return scan, nil, err
+		}
+		if rowIdx > lastNeeded {
+			break
+		}
+		if _, ok := rowVals[rowIdx]; !ok {
+			continue
+		}
+		vals, cerr := r2.Columns()
+		if cerr != nil {
+			return scan, nil, cerr
+		}
+		row := make([]string, scanCols)
+		for c := 0; c < scanCols && off+c < len(vals); c++ {
+			row[c] = strings.TrimSpace(vals[off+c])
+		}
+		rowVals[rowIdx] = row
+	}
+	if err := r2.Error(); err != nil {
+		return scan, nil, err
+	}
+
+	cell := func(row, col int) string {
+		if vals := rowVals[row]; col < len(vals) {
+			return vals[col]
+		}
+		return ""
+	}
+
+	// Build candidates with header heuristic and confidence ranking
+	cands := make([]TableCandidate, 0, len(comps))
 	for _, rc := range comps {
 		// Header row: use rc.r1 or explicit hint if within bounds
 		hdrRow := rc.r1
-		if in.HeaderRow > 0 {
-			if in.HeaderRow-1 >= rc.r1 && in.HeaderRow-1 <= rc.r2 {
-				hdrRow = in.HeaderRow - 1
-			}
+		if in.HeaderRow >= rc.r1 && in.HeaderRow <= rc.r2 {
+			hdrRow = in.HeaderRow
 		}
 		// Extract header values
 		header := make([]string, 0, rc.c2-rc.c1+1)
 		for c := rc.c1; c <= rc.c2; c++ {
-			header = append(header, g.vals[hdrRow][c])
+			header = append(header, cell(hdrRow, c))
 		}
 		hconf := headerConfidence(header)
 		// Size confidence: prefer moderate-to-large coherent regions without dominating
 		area := float64((rc.r2 - rc.r1 + 1) * (rc.c2 - rc.c1 + 1))
-		maxArea := float64(g.rows * g.cols)
+		maxArea := float64(scan.ScannedRows * scanCols)
 		sconf := 0.0
 		if area > 1 && maxArea > 1 {
 			sconf = math.Log2(area) / math.Log2(maxArea)
@@ -272,9 +434,12 @@ func (d *Detector) DetectTables(ctx context.Context, in DetectTablesInput) (Dete
 			}
 		}
 		conf := 0.6*hconf + 0.4*sconf
-		// Coordinates are 1-based; rc indices are 0-based rows/cols
-		tl, _ := excelize.CoordinatesToCellName(rc.c1+1, rc.r1+1)
-		br, _ := excelize.CoordinatesToCellName(rc.c2+1, rc.r2+1)
+		if rc.bridged {
+			conf *= 0.95
+		}
+		// Rows are 1-based sheet rows; rc columns are 0-based within the window
+		tl, _ := excelize.CoordinatesToCellName(off+rc.c1+1, rc.r1)
+		br, _ := excelize.CoordinatesToCellName(off+rc.c2+1, rc.r2)
 		// Build header sample from the top-left of the candidate block
 		sampleRows := hsr
 		if sampleRows > (rc.r2 - rc.r1 + 1) {
@@ -288,12 +453,11 @@ func (d *Detector) DetectTables(ctx context.Context, in DetectTablesInput) (Dete
 		}
 		sample := make([][]string, 0, sampleRows)
 		for rr := 0; rr < sampleRows; rr++ {
-			rowVals := make([]string, 0, effCols)
-			rIdx := rc.r1 + rr
+			vals := make([]string, 0, effCols)
 			for cc := rc.c1; cc < rc.c1+effCols; cc++ {
-				rowVals = append(rowVals, g.vals[rIdx][cc])
+				vals = append(vals, cell(rc.r1+rr, cc))
 			}
-			sample = append(sample, trimTrailingEmpties(rowVals))
+			sample = append(sample, trimTrailingEmpties(vals))
 		}
 
 		cands = append(cands, TableCandidate{
@@ -304,17 +468,175 @@ func (d *Detector) DetectTables(ctx context.Context, in DetectTablesInput) (Dete
 			Cols:             rc.c2 - rc.c1 + 1,
 			HeaderSample:     sample,
 			HeaderSampleCols: effCols,
+			Open:             scan.NextStartRow > 0 && rc.r2 >= scan.EndRow-gapTol,
+			GapBridged:       rc.bridged,
 		})
 	}
+	if tag {
+		for i := range cands {
+			cands[i].Sheet = sheet
+		}
+	}
+	return scan, cands, nil
+}
 
-	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Confidence > cands[j].Confidence })
-	if len(cands) > maxTables {
-		out.Meta.Truncated = true
-		out.Candidates = cands[:maxTables]
-	} else {
-		out.Candidates = cands
+// windowOutside reports a scan window that starts beyond the used range,
+// naming the range when the sheet records one.
+func windowOutside(startRow, startCol int, usedRange string) error {
+	start, _ := excelize.CoordinatesToCellName(startCol, startRow)
+	if usedRange == "" {
+		return mcperr.Errorf(mcperr.Validation, "scan window starting at %s lies outside the used range; lower start_row/start_col", start)
 	}
-	return out, nil
+	return mcperr.Errorf(mcperr.Validation, "scan window starting at %s lies outside the used range %s; lower start_row/start_col", start, usedRange)
+}
+
+// tableRect is a component's bounding box in 1-based sheet rows and 0-based
+// columns; bridged records that empty cells were bridged to build it.
+type tableRect struct {
+	r1, c1, r2, c2 int
+	bridged        bool
+}
+
+// componentLabeler finds connected components of non-empty cells one row at
+// a time. Cells connect to their left/right and up/down neighbours, reaching
+// across up to gap empty cells. It keeps only the latest filled cell of each
+// column and the bounding boxes of components that can still grow; a
+// component is complete once more than gap rows pass without extending it.
+type componentLabeler struct {
+	gap      int
+	above    []int // label of the latest filled cell in each column; 0 = none
+	aboveRow []int // sheet row of that cell
+	parent   map[int]int
+	open     map[int]*tableRect // keyed by root label
+	next     int
+	done     []tableRect
+}
+
+func newComponentLabeler(cols, gap int) *componentLabeler {
+	return &componentLabeler{gap: gap, above: make([]int, cols), aboveRow: make([]int, cols), parent: map[int]int{}, open: map[int]*tableRect{}}
+}
+
+func (l *componentLabeler) find(x int) int {
+	for l.parent[x] != x {
+		l.parent[x] = l.parent[l.parent[x]]
+		x = l.parent[x]
+	}
+	return x
+}
+
+// union merges the components of a and b and returns the surviving root.
+func (l *componentLabeler) union(a, b int) int {
+	ra, rb := l.find(a), l.find(b)
+	if ra == rb {
+		return ra
+	}
+	if rb < ra {
+		ra, rb = rb, ra
+	}
+	l.parent[rb] = ra
+	box, other := l.open[ra], l.open[rb]
+	box.r1, box.c1 = minInt(box.r1, other.r1), minInt(box.c1, other.c1)
+	box.r2, box.c2 = max(box.r2, other.r2), max(box.c2, other.c2)
+	box.bridged = box.bridged || other.bridged
+	delete(l.open, rb)
+	return ra
+}
+
+// addRow labels the filled cells of sheet row row. Cells separated by at most
+// gap empty cells form one segment; each segment joins the components whose
+// latest cell in one of its columns lies at most gap rows above. Components
+// the row leaves more than gap rows behind are closed.
+func (l *componentLabeler) addRow(row int, filled []bool) {
+	for c := 0; c < len(filled); {
+		if !filled[c] {
+			c++
+			continue
+		}
+		start, end, bridged := c, c, false
+		for c++; c < len(filled); c++ {
+			if !filled[c] {
+				continue
+			}
+			if c-end-1 > l.gap {
+				break
+			}
+			bridged = bridged || c-end > 1
+			end = c
+		}
+		label := 0
+		for k := start; k <= end; k++ {
+			a := l.above[k]
+			if !filled[k] || a == 0 || row-l.aboveRow[k]-1 > l.gap {
+				continue
+			}
+			bridged = bridged || row-l.aboveRow[k] > 1
+			if label == 0 {
+				label = l.find(a)
+			} else {
+				label = l.union(label, a)
+			}
+		}
+		if label == 0 {
+			l.next++
+			label = l.next
+			l.parent[label] = label
+			l.open[label] = &tableRect{r1: row, c1: start, r2: row, c2: end}
+		}
+		box := l.open[label]
+		box.r2 = row
+		box.c1, box.c2 = minInt(box.c1, start), max(box.c2, end)
+		box.bridged = box.bridged || bridged
+		for k := start; k <= end; k++ {
+			if filled[k] {
+				l.above[k], l.aboveRow[k] = label, row
+			}
+		}
+	}
+	for root, box := range l.open {
+		if row-box.r2 > l.gap {
+			l.close(*box)
+			delete(l.open, root)
+		}
+	}
+	// Resolve remembered labels to open roots and drop everything else, so
+	// state stays proportional to the row width.
+	for c, lb := range l.above {
+		if lb == 0 {
+			continue
+		}
+		root := l.find(lb)
+		if _, ok := l.open[root]; !ok {
+			root = 0
+		}
+		l.above[c] = root
+	}
+	l.parent = make(map[int]int, len(l.open))
+	for root := range l.open {
+		l.parent[root] = root
+	}
+}
+
+func (l *componentLabeler) close(rc tableRect) {
+	// Reject tiny blobs (< 2x2)
+	if rc.r2-rc.r1+1 >= 2 && rc.c2-rc.c1+1 >= 2 {
+		l.done = append(l.done, rc)
+	}
+}
+
+// finish closes the components still open and returns all blocks in sheet
+// order.
+func (l *componentLabeler) finish() []tableRect {
+	for root, box := range l.open {
+		l.close(*box)
+		delete(l.open, root)
+	}
+	sort.Slice(l.done, func(i, j int) bool {
+		if l.done[i].r1 != l.done[j].r1 {
+			return l.done[i].r1 < l.done[j].r1
+		}
+		return l.done[i].c1 < l.done[j].c1
+	})
+	return l.done
 }
 
 func headerConfidence(hdr []string) float64 {
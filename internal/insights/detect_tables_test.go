@@ -3,6 +3,7 @@ package insights
 import (
 	"context"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -59,3 +60,198 @@ func TestDetectTables_FindsMultipleCandidates(t *testing.T) {
 	require.True(t, found1, "expected A1:C4 candidate")
 	require.True(t, found2, "expected E6:G8 candidate")
 }
+
+func TestDetectTables_BandsAndWindows(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxCellsPerOp = 12 // 3 columns -> 4-row bands
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	d := &Detector{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	// A U-shaped block whose arms only join in row 6, two bands down.
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Left", "", "Right"}))
+	for r := 2; r <= 5; r++ {
+		require.NoError(t, f.SetSheetRow(sh, "A"+strconv.Itoa(r), &[]string{"x", "", "y"}))
+	}
+	require.NoError(t, f.SetSheetRow(sh, "A6", &[]string{"1", "2", "3"}))
+	// A second table below the first window.
+	require.NoError(t, f.SetSheetRow(sh, "A12", &[]string{"Prod", "Qty"}))
+	require.NoError(t, f.SetSheetRow(sh, "A13", &[]string{"X", "5"}))
+	require.NoError(t, f.SetSheetRow(sh, "A14", &[]string{"Y", "7"}))
+	path := filepath.Join(t.TempDir(), "bands.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	out, err := d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: sh, MaxScanCols: 3})
+	require.NoError(t, err)
+	require.Equal(t, 1, out.Meta.StartRow)
+	require.Equal(t, 14, out.Meta.EndRow)
+	require.Equal(t, 4, out.Meta.Bands)
+	require.Zero(t, out.Meta.NextStartRow)
+	ranges := map[string]bool{}
+	for _, c := range out.Candidates {
+		ranges[c.Range] = true
+		require.False(t, c.Open)
+	}
+	require.Equal(t, map[string]bool{"A1:C6": true, "A12:B14": true}, ranges)
+
+	// A window that stops inside the second table reports where to resume.
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: sh, MaxScanCols: 3, MaxScanRows: 13})
+	require.NoError(t, err)
+	require.Equal(t, 13, out.Meta.EndRow)
+	require.Equal(t, 14, out.Meta.NextStartRow)
+	for _, c := range out.Candidates {
+		require.Equal(t, c.Range == "A12:B13", c.Open, c.Range)
+	}
+
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: sh, MaxScanCols: 3, StartRow: 12})
+	require.NoError(t, err)
+	require.Len(t, out.Candidates, 1)
+	require.Equal(t, "A12:B14", out.Candidates[0].Range)
+	require.Equal(t, []string{"Prod", "Qty"}, out.Candidates[0].Header)
+
+	// Without a stored dimension the window is checked against the rows read.
+	_, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: sh, StartRow: 15})
+	require.ErrorContains(t, err, "VALIDATION: scan window starting at A15 lies outside the used range;")
+}
+
+func TestDetectTables_ScanWindow(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxCellsPerOp = 6 // 3 window columns -> 2-row bands
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	d := &Detector{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	// Wide noise at the top would use up the budget of a scan from A1.
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Title", "x", "x", "x", "x", "x", "x"}))
+	require.NoError(t, f.SetSheetRow(sh, "E20", &[]string{"Prod", "Qty", "When"}))
+	require.NoError(t, f.SetSheetRow(sh, "E21", &[]string{"X", "5", "2024-01-01"}))
+	require.NoError(t, f.SetSheetRow(sh, "E22", &[]string{"Y", "7", "2024-01-02"}))
+	// Data left of the window must not leak into it.
+	require.NoError(t, f.SetSheetRow(sh, "A21", &[]string{"left", "", "", "edge"}))
+	require.NoError(t, f.SetSheetDimension(sh, "A1:G22"))
+	path := filepath.Join(t.TempDir(), "window.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	out, err := d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: sh, StartRow: 19, StartCol: 5})
+	require.NoError(t, err)
+	require.Len(t, out.Candidates, 1)
+	require.Equal(t, "E20:G22", out.Candidates[0].Range)
+	require.Equal(t, []string{"Prod", "Qty", "When"}, out.Candidates[0].Header)
+	require.Equal(t, [][]string{{"Prod", "Qty", "When"}, {"X", "5", "2024-01-01"}}, out.Candidates[0].HeaderSample)
+	require.Equal(t, 19, out.Meta.StartRow)
+	require.Equal(t, 22, out.Meta.EndRow)
+	require.Equal(t, 5, out.Meta.StartCol)
+	require.Equal(t, 7, out.Meta.EndCol)
+	require.Equal(t, 3, out.Meta.ScannedCols)
+	require.Equal(t, 2, out.Meta.Bands)
+	require.False(t, out.Meta.ColumnsTruncated)
+
+	// max_scan_cols counts from start_col.
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: sh, StartRow: 19, StartCol: 5, MaxScanCols: 2})
+	require.NoError(t, err)
+	require.Equal(t, 6, out.Meta.EndCol)
+	require.True(t, out.Meta.ColumnsTruncated)
+	require.Equal(t, "E20:F22", out.Candidates[0].Range)
+
+	for _, in := range []DetectTablesInput{
+		{Path: path, Sheet: sh, StartCol: 8},
+		{Path: path, Sheet: sh, StartRow: 23},
+	} {
+		_, err = d.DetectTables(context.Background(), in)
+		require.ErrorContains(t, err, "VALIDATION: scan window starting at")
+		require.ErrorContains(t, err, "outside the used range A1:G22")
+	}
+}
+
+func TestDetectTables_MergedHeaderAndSpacerColumn(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	d := &Detector{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	_, err := f.NewSheet("Plain")
+	require.NoError(t, err)
+	for _, sh := range []string{"Sheet1", "Plain"} {
+		// Column C is an intentionally blank spacer.
+		require.NoError(t, f.SetSheetRow(sh, "A2", &[]string{"Region", "Units", "", "Revenue", "Margin"}))
+		require.NoError(t, f.SetSheetRow(sh, "A3", &[]string{"East", "10", "", "100", "0.2"}))
+		require.NoError(t, f.SetSheetRow(sh, "A4", &[]string{"West", "20", "", "200", "0.3"}))
+		require.NoError(t, f.SetSheetRow(sh, "A5", &[]string{"North", "30", "", "300", "0.1"}))
+	}
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "Quarterly report"))
+	require.NoError(t, f.MergeCell("Sheet1", "A1", "E1"))
+	path := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	out, err := d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: "Sheet1", MaxScanCols: 5})
+	require.NoError(t, err)
+	require.Len(t, out.Candidates, 1)
+	require.Equal(t, "A1:E5", out.Candidates[0].Range)
+	require.True(t, out.Candidates[0].GapBridged)
+
+	// Strict adjacency: the merged title alone still holds the table together.
+	strict := 0
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: "Sheet1", MaxScanCols: 5, GapTolerance: &strict})
+	require.NoError(t, err)
+	require.Len(t, out.Candidates, 1)
+	require.Equal(t, "A1:E5", out.Candidates[0].Range)
+	require.False(t, out.Candidates[0].GapBridged)
+
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: "Plain", MaxScanCols: 5})
+	require.NoError(t, err)
+	require.Len(t, out.Candidates, 1)
+	require.Equal(t, "A2:E5", out.Candidates[0].Range)
+
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, Sheet: "Plain", MaxScanCols: 5, GapTolerance: &strict})
+	require.NoError(t, err)
+	require.Len(t, out.Candidates, 2)
+}
+
+func TestDetectTables_AllSheets(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	d := &Detector{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Name", "Value"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]string{"A", "1"}))
+	_, err := f.NewSheet("Orders")
+	require.NoError(t, err)
+	require.NoError(t, f.SetSheetRow("Orders", "B3", &[]string{"Order", "Customer", "Total"}))
+	require.NoError(t, f.SetSheetRow("Orders", "B4", &[]string{"1001", "Ann", "10"}))
+	require.NoError(t, f.SetSheetRow("Orders", "B5", &[]string{"1002", "Bob", "20"}))
+	_, err = f.NewSheet("Empty")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "workbook.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	out, err := d.DetectTables(context.Background(), DetectTablesInput{Path: path, AllSheets: true})
+	require.NoError(t, err)
+	require.Len(t, out.Meta.Sheets, 3)
+	require.Empty(t, out.Meta.Warnings)
+	require.False(t, out.Meta.ScanTruncated)
+	require.Len(t, out.Candidates, 2)
+	got := map[string]string{}
+	for _, c := range out.Candidates {
+		got[c.Sheet] = c.Range
+	}
+	require.Equal(t, map[string]string{"Sheet1": "A1:B2", "Orders": "B3:D5"}, got)
+	for _, sc := range out.Meta.Sheets {
+		if sc.Sheet == "Empty" {
+			require.Zero(t, sc.Candidates)
+			require.Zero(t, sc.ScannedRows)
+		}
+	}
+
+	// A tiny budget leaves part of each sheet unscanned.
+	d.Limits.MaxCellsPerOp = 6
+	out, err = d.DetectTables(context.Background(), DetectTablesInput{Path: path, AllSheets: true, MaxScanCols: 4})
+	require.NoError(t, err)
+	require.True(t, out.Meta.ScanTruncated)
+}
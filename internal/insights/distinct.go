@@ -0,0 +1,101 @@
+package insights
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+)
+
+// distinctExactLimit is how many IDs a distinctCounter tracks exactly before
+// switching to a sketch.
+const distinctExactLimit = 5000
+
+// distinctSketchK is the number of minimum hash values kept by the sketch;
+// the estimate's relative standard error is about 1/sqrt(k-2), roughly 3%.
+const distinctSketchK = 1024
+
+// distinctCounter counts distinct strings exactly up to distinctExactLimit and
+// approximately (k-minimum-values sketch) beyond it, so memory stays bounded.
+type distinctCounter struct {
+	exact  map[string]struct{}
+	hashes map[uint64]struct{}
+	kmv    hashMaxHeap
+}
+
+func newDistinctCounter() *distinctCounter {
+	return &distinctCounter{exact: map[string]struct{}{}}
+}
+
+func (d *distinctCounter) add(s string) {
+	if d.exact != nil {
+		d.exact[s] = struct{}{}
+		if len(d.exact) <= distinctExactLimit {
+			return
+		}
+		d.hashes = make(map[uint64]struct{}, distinctSketchK)
+		for v := range d.exact {
+			d.addHash(hashString(v))
+		}
+		d.exact = nil
+		return
+	}
+	d.addHash(hashString(s))
+}
+
+func (d *distinctCounter) addHash(h uint64) {
+	if _, ok := d.hashes[h]; ok {
+		return
+	}
+	if len(d.kmv) < distinctSketchK {
+		heap.Push(&d.kmv, h)
+		d.hashes[h] = struct{}{}
+		return
+	}
+	if h >= d.kmv[0] {
+		return
+	}
+	delete(d.hashes, d.kmv[0])
+	d.kmv[0] = h
+	heap.Fix(&d.kmv, 0)
+	d.hashes[h] = struct{}{}
+}
+
+// count returns the number of distinct values seen and whether it is an estimate.
+func (d *distinctCounter) count() (int, bool) {
+	if d.exact != nil {
+		return len(d.exact), false
+	}
+	if len(d.kmv) < distinctSketchK {
+		return len(d.kmv), false
+	}
+	frac := float64(d.kmv[0]) / math.MaxUint64
+	return int(math.Round(float64(distinctSketchK-1) / frac)), true
+}
+
+// hashString hashes s with FNV-64a and a 64-bit finalizer so short, similar
+// IDs still spread uniformly across the range the sketch relies on.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// hashMaxHeap keeps the largest retained hash at the root.
+type hashMaxHeap []uint64
+
+func (h hashMaxHeap) Len() int           { return len(h) }
+func (h hashMaxHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h hashMaxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hashMaxHeap) Push(x any)        { *h = append(*h, x.(uint64)) }
+func (h *hashMaxHeap) Pop() any {
+	old := *h
+	v := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return v
+}
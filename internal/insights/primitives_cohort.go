@@ -0,0 +1,234 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/xuri/excelize/v2"
+)
+
+// CohortAnalysisInput builds a retention matrix from per-row activity.
+type CohortAnalysisInput struct {
+	Path          string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical Excel file path (allowed directories enforced)"`
+	Sheet         string `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
+	Range         string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
+	IDIndex       int    `json:"id_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the entity id (customer, user, account)"`
+	CohortIndex   int    `json:"cohort_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the cohort date (e.g. signup or first order)"`
+	ActivityIndex int    `json:"activity_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the activity date"`
+	Granularity   string `json:"granularity,omitempty" validate:"omitempty,oneof=day week month quarter year" jsonschema_description:"Bucket for cohorts and offsets: day, week, month (default), quarter, or year"`
+	MaxCohorts    int    `json:"max_cohorts,omitempty" validate:"omitempty,min=1,max=36" jsonschema_description:"Most recent cohorts to return (default 12)"`
+	MaxOffsets    int    `json:"max_offsets,omitempty" validate:"omitempty,min=1,max=36" jsonschema_description:"Period offsets after the cohort period to report, including offset 0 (default 12)"`
+	MaxCells      int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+}
+
+// CohortRow is one cohort's size and distinct active ids per period offset.
+type CohortRow struct {
+	Cohort      string    `json:"cohort"`
+	Size        int       `json:"size" jsonschema_description:"Distinct ids in the cohort"`
+	Counts      []int     `json:"counts" jsonschema_description:"Distinct ids active at each offset (index 0 = cohort period)"`
+	Retention   []float64 `json:"retention_pct" jsonschema_description:"Counts as a percentage of size"`
+	Approximate bool      `json:"approximate,omitempty" jsonschema_description:"Some counts are estimates because the cohort exceeded the exact distinct-id limit"`
+}
+
+// CohortAnalysisOutput is the cohort × offset retention matrix.
+type CohortAnalysisOutput struct {
+	Path        string      `json:"path"`
+	Sheet       string      `json:"sheet"`
+	Range       string      `json:"range"`
+	Granularity string      `json:"granularity"`
+	Offsets     int         `json:"offsets"`
+	Cohorts     []CohortRow `json:"cohorts"`
+	Meta        struct {
+		ProcessedRows    int  `json:"processed_rows"`
+		ProcessedCells   int  `json:"processed_cells"`
+		MaxCells         int  `json:"max_cells"`
+		Truncated        bool `json:"truncated"`
+		SkippedRows      int  `json:"skipped_rows" jsonschema_description:"Rows with a blank id or an unparseable date"`
+		NegativeOffsets  int  `json:"negative_offsets" jsonschema_description:"Rows whose activity precedes the cohort period; ignored"`
+		CohortsTotal     int  `json:"cohorts_total"`
+		CohortsTruncated bool `json:"cohorts_truncated"`
+		OffsetsTruncated bool `json:"offsets_truncated"`
+		Approximate      bool `json:"approximate"`
+	} `json:"meta"`
+}
+
+// Cohorter executes cohort retention analysis using streaming reads.
+type Cohorter struct {
+	Limits runtime.Limits
+	Mgr    *workbooks.Manager
+}
+
+type cohortAcc struct {
+	index   int
+	label   string
+	members *distinctCounter
+	offsets []*distinctCounter
+}
+
+// CohortAnalysis streams the range once, buckets cohort and activity dates,
+// and counts distinct ids per cohort and period offset.
+func (c *Cohorter) CohortAnalysis(ctx context.Context, in CohortAnalysisInput) (CohortAnalysisOutput, error) {
+	var out CohortAnalysisOutput
+	out.Sheet = strings.TrimSpace(in.Sheet)
+	out.Granularity = strings.ToLower(strings.TrimSpace(in.Granularity))
+	if out.Granularity == "" {
+		out.Granularity = "month"
+	}
+	maxCohorts := in.MaxCohorts
+	if maxCohorts <= 0 || maxCohorts > 36 {
+		maxCohorts = 12
+	}
+	maxOffsets := in.MaxOffsets
+	if maxOffsets <= 0 || maxOffsets > 36 {
+		maxOffsets = 12
+	}
+
+	id, canonical, err := c.Mgr.GetOrOpenByPath(ctx, in.Path)
+	if err != nil {
+		return out, err
+	}
+	out.Path = canonical
+
+	maxCells := in.MaxCells
+	if maxCells <= 0 || maxCells > c.Limits.MaxCellsPerOp {
+		maxCells = c.Limits.MaxCellsPerOp
+	}
+	out.Meta.MaxCells = maxCells
+
+	cohorts := map[int]*cohortAcc{}
+	highest := -1 // largest offset seen
+	err = c.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, out.Sheet, in.Range)
+		if rerr != nil {
+			return rerr
+		}
+		out.Range = normalized
+		colCount := x2 - x1 + 1
+		for _, idx := range []int{in.IDIndex, in.CohortIndex, in.ActivityIndex} {
+			if idx < 1 || idx > colCount {
+				return mcperr.Errorf(mcperr.Validation, "invalid id_index, cohort_index, or activity_index; range has %d columns", colCount)
+			}
+		}
+		idAbs := x1 + in.IDIndex - 2
+		cohAbs := x1 + in.CohortIndex - 2
+		actAbs := x1 + in.ActivityIndex - 2
+
+		r, rerr := f.Rows(out.Sheet)
+		if rerr != nil {
+			return rerr
+		}
+		defer r.Close()
+
+		cellsProcessed := 0
+		rowIdx := 0
+		for r.Next() {
+			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
+			if rowIdx <= y1 { // skip header row
+				continue
+			}
+			if rowIdx > y2 {
+				break
+			}
+			vals, cerr := r.Columns()
+			if cerr != nil {
+				return cerr
+			}
+			cellsProcessed += minInt(len(vals), colCount)
+			if cellsProcessed > maxCells {
+				out.Meta.Truncated = true
+				break
+			}
+			cell := func(abs int) string {
+				if abs < len(vals) {
+					return strings.TrimSpace(vals[abs])
+				}
+				return ""
+			}
+			idVal := cell(idAbs)
+			cohT, okC := parsePeriodTime(cell(cohAbs))
+			actT, okA := parsePeriodTime(cell(actAbs))
+			if idVal == "" || !okC || !okA {
+				out.Meta.SkippedRows++
+				continue
+			}
+			out.Meta.ProcessedRows++
+			ci := bucketIndex(cohT, out.Granularity)
+			acc, ok := cohorts[ci]
+			if !ok {
+				acc = &cohortAcc{index: ci, label: bucketLabel(cohT, out.Granularity), members: newDistinctCounter()}
+				cohorts[ci] = acc
+			}
+			acc.members.add(idVal)
+			off := bucketIndex(actT, out.Granularity) - ci
+			switch {
+			case off < 0:
+				out.Meta.NegativeOffsets++
+				continue
+			case off >= maxOffsets:
+				out.Meta.OffsetsTruncated = true
+				continue
+			}
+			for len(acc.offsets) <= off {
+				acc.offsets = append(acc.offsets, nil)
+			}
+			if acc.offsets[off] == nil {
+				acc.offsets[off] = newDistinctCounter()
+			}
+			acc.offsets[off].add(idVal)
+			if off > highest {
+				highest = off
+			}
+		}
+		out.Meta.ProcessedCells = cellsProcessed
+		return r.Error()
+	})
+	if err != nil {
+		return out, err
+	}
+	if len(cohorts) == 0 {
+		return out, fmt.Errorf("no rows with an id and parseable cohort and activity dates")
+	}
+
+	order := make([]*cohortAcc, 0, len(cohorts))
+	for _, acc := range cohorts {
+		order = append(order, acc)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].index < order[j].index })
+	out.Meta.CohortsTotal = len(order)
+	if len(order) > maxCohorts {
+		order = order[len(order)-maxCohorts:]
+		out.Meta.CohortsTruncated = true
+	}
+
+	out.Offsets = highest + 1
+	out.Cohorts = make([]CohortRow, 0, len(order))
+	for _, acc := range order {
+		row := CohortRow{Cohort: acc.label, Counts: make([]int, out.Offsets), Retention: make([]float64, out.Offsets)}
+		var approx bool
+		row.Size, approx = acc.members.count()
+		row.Approximate = row.Approximate || approx
+		for off, dc := range acc.offsets {
+			if dc == nil {
+				continue
+			}
+			row.Counts[off], approx = dc.count()
+			row.Approximate = row.Approximate || approx
+			if row.Size > 0 {
+				// Estimates can overshoot the cohort size slightly.
+				row.Retention[off] = round2(math.Min(100, float64(row.Counts[off])/float64(row.Size)*100))
+			}
+		}
+		out.Meta.Approximate = out.Meta.Approximate || row.Approximate
+		out.Cohorts = append(out.Cohorts, row)
+	}
+	return out, nil
+}
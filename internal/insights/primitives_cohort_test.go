@@ -0,0 +1,98 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createCohortWorkbook(t *testing.T) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Orders"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Customer", "Signup", "Order Date"}))
+	rows := [][]string{
+		// January cohort: u1, u2, u3, u4
+		{"u1", "2024-01-03", "2024-01-03"},
+		{"u1", "2024-01-03", "2024-01-20"}, // repeat in same month counts once
+		{"u1", "2024-01-03", "2024-02-11"},
+		{"u1", "2024-01-03", "2024-03-02"},
+		{"u2", "2024-01-15", "2024-01-15"},
+		{"u2", "2024-01-15", "2024-03-09"},
+		{"u3", "2024-01-20", "2024-01-21"},
+		{"u4", "2024-01-31", "2024-02-01"},
+		// February cohort: u5, u6
+		{"u5", "2024-02-02", "2024-02-02"},
+		{"u5", "2024-02-02", "2024-03-15"},
+		{"u6", "2024-02-10", "2024-02-10"},
+		{"u6", "2024-02-10", "2024-01-01"}, // before cohort: ignored
+		{"", "2024-02-10", "2024-02-10"},   // no id: skipped
+		{"u7", "soon", "2024-02-10"},       // bad date: skipped
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "cohort.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestCohortAnalysis_MonthlyRetention(t *testing.T) {
+	c := &Cohorter{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createCohortWorkbook(t)
+
+	out, err := c.CohortAnalysis(context.Background(), CohortAnalysisInput{Path: path, Sheet: sh, Range: "A1:C15", IDIndex: 1, CohortIndex: 2, ActivityIndex: 3})
+	require.NoError(t, err)
+	require.Equal(t, "month", out.Granularity)
+	require.Equal(t, 12, out.Meta.ProcessedRows)
+	require.Equal(t, 2, out.Meta.SkippedRows)
+	require.Equal(t, 1, out.Meta.NegativeOffsets)
+	require.Equal(t, 3, out.Offsets)
+	require.Len(t, out.Cohorts, 2)
+
+	jan := out.Cohorts[0]
+	require.Equal(t, "2024-01", jan.Cohort)
+	require.Equal(t, 4, jan.Size)
+	require.Equal(t, []int{3, 2, 2}, jan.Counts)
+	require.Equal(t, []float64{75, 50, 50}, jan.Retention)
+	feb := out.Cohorts[1]
+	require.Equal(t, "2024-02", feb.Cohort)
+	require.Equal(t, 2, feb.Size)
+	require.Equal(t, []int{2, 1, 0}, feb.Counts)
+
+	out, err = c.CohortAnalysis(context.Background(), CohortAnalysisInput{Path: path, Sheet: sh, Range: "A1:C15", IDIndex: 1, CohortIndex: 2, ActivityIndex: 3, MaxCohorts: 1, MaxOffsets: 2})
+	require.NoError(t, err)
+	require.True(t, out.Meta.CohortsTruncated)
+	require.True(t, out.Meta.OffsetsTruncated)
+	require.Len(t, out.Cohorts, 1)
+	require.Equal(t, "2024-02", out.Cohorts[0].Cohort)
+	require.Equal(t, 2, out.Offsets)
+}
+
+func TestDistinctCounter_SketchFallback(t *testing.T) {
+	d := newDistinctCounter()
+	for i := 0; i < 3; i++ {
+		d.add("same")
+	}
+	n, approx := d.count()
+	require.Equal(t, 1, n)
+	require.False(t, approx)
+
+	const total = 50000
+	for i := 0; i < total; i++ {
+		d.add(fmt.Sprintf("id-%d", i))
+		d.add(fmt.Sprintf("id-%d", i/2)) // duplicates do not inflate the estimate
+	}
+	n, approx = d.count()
+	require.True(t, approx)
+	require.InDelta(t, total+1, n, total*0.1)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -23,8 +24,9 @@ type CompositionShiftInput struct {
 	DimIndex       int     `json:"dimension_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the grouping dimension"`
 	MeasureIndex   int     `json:"measure_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the numeric measure"`
 	TimeIndex      int     `json:"time_index,omitempty" validate:"omitempty,min=1" jsonschema_description:"Optional 1-based column index within the range for the period/time column"`
-	PeriodBaseline string  `json:"period_baseline,omitempty" jsonschema_description:"Optional baseline period value; if omitted, detected as earlier of last two periods"`
-	PeriodCurrent  string  `json:"period_current,omitempty" jsonschema_description:"Optional current period value; if omitted, detected as latest of last two periods"`
+	PeriodBaseline string  `json:"period_baseline,omitempty" jsonschema_description:"Optional baseline period: one value, a comma-separated list (2024-01,2024-02), or an inclusive range (2024-01..2024-03) whose periods are summed; if omitted, detected as earlier of last two periods"`
+	PeriodCurrent  string  `json:"period_current,omitempty" jsonschema_description:"Optional current period: one value, a comma-separated list, or an inclusive range (2024-04..2024-06) whose periods are summed; must not overlap the baseline; if omitted, detected as latest of last two periods"`
+	Granularity    string  `json:"granularity,omitempty" validate:"omitempty,oneof=auto day week month quarter year" jsonschema_description:"Roll date periods up to day, week (ISO, 2024-W05), month (2024-01), quarter (2024-Q1), or year; auto picks from date spacing. Period values then refer to bucket labels. Omit to treat each distinct value as a period"`
 	TopN           int     `json:"top_n,omitempty" validate:"omitempty,min=1,max=10" jsonschema_description:"Top-N groups to return explicitly; remaining combined into 'Other' (default 5)"`
 	MixThresholdPP float64 `json:"mix_threshold_pp,omitempty" validate:"omitempty,gt=0" jsonschema_description:"Highlight threshold in percentage points for mix shift (default 5)"`
 	MaxCells       int     `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
@@ -39,17 +41,20 @@ type GroupMix struct {
 
 // CompositionShiftOutput reports period shares and Top-N movers.
 type CompositionShiftOutput struct {
-	Path           string     `json:"path"`
-	Sheet          string     `json:"sheet"`
-	Range          string     `json:"range"`
-	PeriodBaseline string     `json:"period_baseline"`
-	PeriodCurrent  string     `json:"period_current"`
-	TopN           int        `json:"top_n"`
-	MixThresholdPP float64    `json:"mix_threshold_pp"`
-	Groups         []GroupMix `json:"groups"`
-	OtherBaseline  float64    `json:"other_share_baseline"`
-	OtherCurrent   float64    `json:"other_share_current"`
-	Meta           struct {
+	Path            string     `json:"path"`
+	Sheet           string     `json:"sheet"`
+	Range           string     `json:"range"`
+	PeriodBaseline  string     `json:"period_baseline"`
+	PeriodCurrent   string     `json:"period_current"`
+	BaselinePeriods []string   `json:"baseline_periods,omitempty"`
+	CurrentPeriods  []string   `json:"current_periods,omitempty"`
+	Granularity     string     `json:"granularity,omitempty"`
+	TopN            int        `json:"top_n"`
+	MixThresholdPP  float64    `json:"mix_threshold_pp"`
+	Groups          []GroupMix `json:"groups"`
+	OtherBaseline   float64    `json:"other_share_baseline"`
+	OtherCurrent    float64    `json:"other_share_current"`
+	Meta            struct {
 		ProcessedRows  int  `json:"processed_rows"`
 		ProcessedCells int  `json:"processed_cells"`
 		MaxCells       int  `json:"max_cells"`
@@ -92,7 +97,7 @@ func parseFloatStrict(s string) (float64, bool) {
 }
 
 func tryParseTime(s string) (time.Time, bool) {
-	layouts := []string{time.RFC3339, "2006-01-02", "2006/01/02", "01/02/2006", "1/2/2006", "1/2/06", "2006-01-02 15:04:05"}
+	layouts := []string{time.RFC3339, "2006-01-02", "2006/01/02", "01/02/2006", "1/2/2006", "1/2/06", "2006-01-02 15:04:05", "01-02-06", "2006-01", "Jan 2006", "January 2006", "Jan-06"}
 	for _, l := range layouts {
 		if t, err := time.Parse(l, strings.TrimSpace(s)); err == nil {
 			return t, true
@@ -120,40 +125,147 @@ func (c *Composer) CompositionShift(ctx context.Context, in CompositionShiftInpu
 	}
 	out.Path = canonical
 
-	maxCells := in.MaxCells
-	if maxCells <= 0 || maxCells > c.Limits.MaxCellsPerOp {
-		maxCells = c.Limits.MaxCellsPerOp
+	scan, err := c.scanPeriodGroupTotals(ctx, id, out.Sheet, in.Range, in.DimIndex, in.MeasureIndex, in.TimeIndex, in.MaxCells)
+	out.Range = scan.rng
+	out.Meta.ProcessedRows, out.Meta.ProcessedCells = scan.rows, scan.cells
+	out.Meta.MaxCells, out.Meta.Truncated = scan.maxCells, scan.truncated
+	if err != nil {
+		return out, err
+	}
+
+	// Determine baseline/current periods
+	if in.TimeIndex <= 0 {
+		return out, mcperr.Errorf(mcperr.Validation, "time_index is required to compute composition shift")
+	}
+	if g := strings.ToLower(strings.TrimSpace(in.Granularity)); g != "" {
+		if out.Granularity, err = scan.bucket(g); err != nil {
+			return out, err
+		}
+	}
+	perBaseline, perCurrent, err := pickPeriods(scan.periods, in.PeriodBaseline, in.PeriodCurrent)
+	if err != nil {
+		return out, err
+	}
+	out.PeriodBaseline = perBaseline
+	out.PeriodCurrent = perCurrent
+
+	base := scan.acc[perBaseline]
+	curr := scan.acc[perCurrent]
+	if isPeriodSet(perBaseline) || isPeriodSet(perCurrent) {
+		if out.BaselinePeriods, err = periodSet(scan.periods, perBaseline); err != nil {
+			return out, err
+		}
+		if out.CurrentPeriods, err = periodSet(scan.periods, perCurrent); err != nil {
+			return out, err
+		}
+		if p, ok := overlap(out.BaselinePeriods, out.CurrentPeriods); ok {
+			return out, mcperr.Errorf(mcperr.Validation, "period_baseline and period_current overlap on %q", p)
+		}
+		base, curr = sumPeriods(scan.acc, out.BaselinePeriods), sumPeriods(scan.acc, out.CurrentPeriods)
+	}
+	if base == nil || curr == nil {
+		return out, fmt.Errorf("missing period aggregates for baseline or current")
+	}
+	// Totals
+	var totBase, totCurr float64
+	for _, v := range base {
+		totBase += v
+	}
+	for _, v := range curr {
+		totCurr += v
+	}
+	if totBase == 0 || totCurr == 0 {
+		return out, fmt.Errorf("zero totals for baseline or current period")
+	}
+
+	// Union of groups
+	uniq := map[string]struct{}{}
+	for k := range base {
+		uniq[k] = struct{}{}
+	}
+	for k := range curr {
+		uniq[k] = struct{}{}
+	}
+	rows := make([]GroupMix, 0, len(uniq))
+	for g := range uniq {
+		b := base[g] / totBase
+		c := curr[g] / totCurr
+		pp := (c - b) * 100.0
+		rows = append(rows, GroupMix{Name: g, ShareBaseline: round3(b), ShareCurrent: round3(c), PPChange: round2(pp)})
 	}
-	out.Meta.MaxCells = maxCells
+	// Sort by absolute pp change desc
+	sort.Slice(rows, func(i, j int) bool {
+		ai := math.Abs(rows[i].PPChange)
+		aj := math.Abs(rows[j].PPChange)
+		if ai == aj {
+			return rows[i].Name < rows[j].Name
+		}
+		return ai > aj
+	})
 
-	// Accumulators: period -> group -> sum
-	acc := map[string]map[string]float64{}
-	periodsSeen := map[string]struct{}{}
+	keep := out.TopN
+	if keep > len(rows) {
+		keep = len(rows)
+	}
+	selected := rows[:keep]
+	var selBase, selCurr float64
+	for _, r := range selected {
+		selBase += r.ShareBaseline
+		selCurr += r.ShareCurrent
+	}
+	out.Groups = selected
+	out.OtherBaseline = round3(1.0 - selBase)
+	out.OtherCurrent = round3(1.0 - selCurr)
+	return out, nil
+}
+
+// periodScan holds per-period, per-group measure totals from one streaming pass.
+type periodScan struct {
+	rng       string
+	acc       map[string]map[string]float64 // period -> group -> sum
+	periods   map[string]struct{}
+	rows      int
+	cells     int
+	maxCells  int
+	truncated bool
+}
 
-	err = c.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
-		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, out.Sheet, in.Range)
+// scanPeriodGroupTotals streams the data rows below the range header once and
+// sums the measure per period and group. Without a time index every row falls
+// in the single period "all". Blank dimensions and periods become "(empty)".
+// Each new period × group accumulator costs one cell of the budget, so many
+// distinct pairs end the scan like wide rows do.
+func (c *Composer) scanPeriodGroupTotals(ctx context.Context, id, sheet, rng string, dimIndex, measureIndex, timeIndex, maxCells int) (periodScan, error) {
+	scan := periodScan{acc: map[string]map[string]float64{}, periods: map[string]struct{}{}, maxCells: maxCells}
+	if scan.maxCells <= 0 || scan.maxCells > c.Limits.MaxCellsPerOp {
+		scan.maxCells = c.Limits.MaxCellsPerOp
+	}
+	err := c.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, sheet, rng)
 		if rerr != nil {
 			return rerr
 		}
-		out.Range = normalized
+		scan.rng = normalized
 		colCount := x2 - x1 + 1
-		if in.DimIndex < 1 || in.DimIndex > colCount || in.MeasureIndex < 1 || in.MeasureIndex > colCount {
-			return fmt.Errorf("invalid dimension_index or measure_index; range has %d columns", colCount)
+		if dimIndex < 1 || dimIndex > colCount || measureIndex < 1 || measureIndex > colCount {
+			return mcperr.Errorf(mcperr.Validation, "invalid dimension_index or measure_index; range has %d columns", colCount)
 		}
-		if in.TimeIndex != 0 && (in.TimeIndex < 1 || in.TimeIndex > colCount) {
-			return fmt.Errorf("invalid time_index; range has %d columns", colCount)
+		if timeIndex != 0 && (timeIndex < 1 || timeIndex > colCount) {
+			return mcperr.Errorf(mcperr.Validation, "invalid time_index; range has %d columns", colCount)
 		}
 
-		r, rerr := f.Rows(out.Sheet)
+		r, rerr := f.Rows(sheet)
 		if rerr != nil {
 			return rerr
 		}
 		defer r.Close()
 
-		cellsProcessed := 0
 		rowIdx := 0
 		for r.Next() {
 			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
 			if rowIdx <= y1 { // skip header row
 				continue
 			}
@@ -164,18 +276,18 @@ func (c *Composer) CompositionShift(ctx context.Context, in CompositionShiftInpu
 			if cerr != nil {
 				return cerr
 			}
-			cellsProcessed += minInt(len(vals), colCount)
-			if cellsProcessed > maxCells {
-				out.Meta.Truncated = true
+			scan.cells += minInt(len(vals), colCount)
+			if scan.cells > scan.maxCells {
+				scan.truncated = true
 				break
 			}
 
 			// Extract fields within range
-			dimAbs := x1 + (in.DimIndex - 1) - 1
-			measAbs := x1 + (in.MeasureIndex - 1) - 1
+			dimAbs := x1 + (dimIndex - 1) - 1
+			measAbs := x1 + (measureIndex - 1) - 1
 			var timeAbs int
-			if in.TimeIndex > 0 {
-				timeAbs = x1 + (in.TimeIndex - 1) - 1
+			if timeIndex > 0 {
+				timeAbs = x1 + (timeIndex - 1) - 1
 			}
 			var dimVal string
 			var measVal string
@@ -198,114 +310,147 @@ func (c *Composer) CompositionShift(ctx context.Context, in CompositionShiftInpu
 				continue
 			}
 			periodKey := "all"
-			if in.TimeIndex > 0 {
+			if timeIndex > 0 {
 				periodKey = perVal
 				if periodKey == "" {
 					periodKey = "(empty)"
 				}
-				periodsSeen[periodKey] = struct{}{}
+				scan.periods[periodKey] = struct{}{}
 			}
-			m, ok := acc[periodKey]
+			m, ok := scan.acc[periodKey]
 			if !ok {
 				m = map[string]float64{}
-				acc[periodKey] = m
+				scan.acc[periodKey] = m
+			}
+			if _, ok := m[dimVal]; !ok {
+				if scan.cells++; scan.cells > scan.maxCells {
+					scan.truncated = true
+					break
+				}
 			}
 			m[dimVal] += mv
-			out.Meta.ProcessedRows++
+			scan.rows++
 		}
-		out.Meta.ProcessedCells = cellsProcessed
 		return r.Error()
 	})
-	if err != nil {
-		return out, err
-	}
+	return scan, err
+}
 
-	// Determine baseline/current periods
-	if in.TimeIndex <= 0 {
-		return out, fmt.Errorf("time_index is required to compute composition shift")
+// pickPeriods returns the requested baseline and current periods, or the
+// last two distinct periods (by date when parseable, else lexically) when
+// either is omitted.
+func pickPeriods(seen map[string]struct{}, baseline, current string) (string, string, error) {
+	perBaseline := strings.TrimSpace(baseline)
+	perCurrent := strings.TrimSpace(current)
+	if perBaseline != "" && perCurrent != "" {
+		return perBaseline, perCurrent, nil
+	}
+	// choose last two periods by time parse or lex order
+	var keys []string
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	if len(keys) < 2 {
+		return "", "", fmt.Errorf("not enough distinct periods; need at least 2, found %d", len(keys))
 	}
-	perBaseline := strings.TrimSpace(in.PeriodBaseline)
-	perCurrent := strings.TrimSpace(in.PeriodCurrent)
-	if perBaseline == "" || perCurrent == "" {
-		// choose last two periods by time parse or lex order
-		var keys []string
-		for k := range periodsSeen {
-			keys = append(keys, k)
+	sort.Slice(keys, func(i, j int) bool {
+		ti, okI := tryParseTime(keys[i])
+		tj, okJ := tryParseTime(keys[j])
+		if okI && okJ {
+			return ti.Before(tj)
 		}
-		if len(keys) < 2 {
-			return out, fmt.Errorf("not enough distinct periods; need at least 2, found %d", len(keys))
+		// fallback lexicographic
+		return keys[i] < keys[j]
+	})
+	return keys[len(keys)-2], keys[len(keys)-1], nil
+}
+
+// isPeriodSet reports whether a period spec lists or ranges several periods.
+func isPeriodSet(spec string) bool {
+	return strings.Contains(spec, ",") || strings.Contains(spec, "..")
+}
+
+// periodSet expands a spec of comma-separated periods and inclusive
+// "from..to" ranges into the seen periods it names, ordered like
+// sortPeriods. Listed periods must exist; a range must match at least one
+// period, and its endpoints need not appear in the data.
+func periodSet(seen map[string]struct{}, spec string) ([]string, error) {
+	members := map[string]struct{}{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, mcperr.Errorf(mcperr.Validation, "empty period in %q", spec)
 		}
-		sort.Slice(keys, func(i, j int) bool {
-			ti, okI := tryParseTime(keys[i])
-			tj, okJ := tryParseTime(keys[j])
-			if okI && okJ {
-				return ti.Before(tj)
+		from, to, isRange := strings.Cut(item, "..")
+		if !isRange {
+			if _, ok := seen[item]; !ok {
+				return nil, mcperr.Errorf(mcperr.Validation, "period %q not found in the data", item)
 			}
-			// fallback lexicographic
-			return keys[i] < keys[j]
-		})
-		perBaseline = keys[len(keys)-2]
-		perCurrent = keys[len(keys)-1]
-	}
-	out.PeriodBaseline = perBaseline
-	out.PeriodCurrent = perCurrent
-
-	base := acc[perBaseline]
-	curr := acc[perCurrent]
-	if base == nil || curr == nil {
-		return out, fmt.Errorf("missing period aggregates for baseline or current")
-	}
-	// Totals
-	var totBase, totCurr float64
-	for _, v := range base {
-		totBase += v
-	}
-	for _, v := range curr {
-		totCurr += v
+			members[item] = struct{}{}
+			continue
+		}
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if from == "" || to == "" {
+			return nil, mcperr.Errorf(mcperr.Validation, "period range %q needs both ends (from..to)", item)
+		}
+		// Order the endpoints with the data so ranges follow the same
+		// chronological-or-lexical rule as period detection.
+		ordered := []string{from, to}
+		for p := range seen {
+			if p != from && p != to {
+				ordered = append(ordered, p)
+			}
+		}
+		sortPeriods(ordered)
+		rank := make(map[string]int, len(ordered))
+		for i, p := range ordered {
+			rank[p] = i
+		}
+		if rank[from] > rank[to] {
+			return nil, mcperr.Errorf(mcperr.Validation, "period range %q ends before it starts", item)
+		}
+		matched := 0
+		for p := range seen {
+			if rank[p] >= rank[from] && rank[p] <= rank[to] {
+				members[p] = struct{}{}
+				matched++
+			}
+		}
+		if matched == 0 {
+			return nil, mcperr.Errorf(mcperr.Validation, "period range %q matches no periods in the data", item)
+		}
 	}
-	if totBase == 0 || totCurr == 0 {
-		return out, fmt.Errorf("zero totals for baseline or current period")
+	out := make([]string, 0, len(members))
+	for p := range members {
+		out = append(out, p)
 	}
+	sortPeriods(out)
+	return out, nil
+}
 
-	// Union of groups
-	uniq := map[string]struct{}{}
-	for k := range base {
-		uniq[k] = struct{}{}
-	}
-	for k := range curr {
-		uniq[k] = struct{}{}
-	}
-	rows := make([]GroupMix, 0, len(uniq))
-	for g := range uniq {
-		b := base[g] / totBase
-		c := curr[g] / totCurr
-		pp := (c - b) * 100.0
-		rows = append(rows, GroupMix{Name: g, ShareBaseline: round3(b), ShareCurrent: round3(c), PPChange: round2(pp)})
+// overlap returns the first period of a that also appears in b.
+func overlap(a, b []string) (string, bool) {
+	in := make(map[string]struct{}, len(b))
+	for _, p := range b {
+		in[p] = struct{}{}
 	}
-	// Sort by absolute pp change desc
-	sort.Slice(rows, func(i, j int) bool {
-		ai := math.Abs(rows[i].PPChange)
-		aj := math.Abs(rows[j].PPChange)
-		if ai == aj {
-			return rows[i].Name < rows[j].Name
+	for _, p := range a {
+		if _, ok := in[p]; ok {
+			return p, true
 		}
-		return ai > aj
-	})
-
-	keep := out.TopN
-	if keep > len(rows) {
-		keep = len(rows)
 	}
-	selected := rows[:keep]
-	var selBase, selCurr float64
-	for _, r := range selected {
-		selBase += r.ShareBaseline
-		selCurr += r.ShareCurrent
+	return "", false
+}
+
+// sumPeriods adds the per-group totals of every listed period.
+func sumPeriods(acc map[string]map[string]float64, periods []string) map[string]float64 {
+	sum := map[string]float64{}
+	for _, p := range periods {
+		for g, v := range acc[p] {
+			sum[g] += v
+		}
 	}
-	out.Groups = selected
-	out.OtherBaseline = round3(1.0 - selBase)
-	out.OtherCurrent = round3(1.0 - selCurr)
-	return out, nil
+	return sum
 }
 
 func minInt(a, b int) int {
@@ -62,3 +62,85 @@ func TestCompositionShift_ComputesPPChanges(t *testing.T) {
 	require.InDelta(t, 0.0, out.OtherBaseline, 0.001)
 	require.InDelta(t, 0.0, out.OtherCurrent, 0.001)
 }
+
+func TestCompositionShift_PeriodSets(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	c := &Composer{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Product", "Month", "Revenue"}))
+	// Q1: A=60 B=60; Q2: A=180 B=60.
+	rows := [][]string{
+		{"A", "2024-01", "10"}, {"B", "2024-01", "20"},
+		{"A", "2024-02", "20"}, {"B", "2024-02", "20"},
+		{"A", "2024-03", "30"}, {"B", "2024-03", "20"},
+		{"A", "2024-04", "40"}, {"B", "2024-04", "20"},
+		{"A", "2024-05", "60"}, {"B", "2024-05", "20"},
+		{"A", "2024-06", "80"}, {"B", "2024-06", "20"},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "monthly.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	base := CompositionShiftInput{Path: path, Sheet: sh, Range: "A1:C13", DimIndex: 1, MeasureIndex: 3, TimeIndex: 2}
+	shareOf := func(out CompositionShiftOutput, name string) GroupMix {
+		for _, g := range out.Groups {
+			if g.Name == name {
+				return g
+			}
+		}
+		t.Fatalf("group %q missing", name)
+		return GroupMix{}
+	}
+
+	in := base
+	in.PeriodBaseline, in.PeriodCurrent = "2024-01..2024-03", "2024-04, 2024-05, 2024-06"
+	out, err := c.CompositionShift(context.Background(), in)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-01", "2024-02", "2024-03"}, out.BaselinePeriods)
+	require.Equal(t, []string{"2024-04", "2024-05", "2024-06"}, out.CurrentPeriods)
+	a := shareOf(out, "A")
+	require.InDelta(t, 0.5, a.ShareBaseline, 0.001)
+	require.InDelta(t, 0.75, a.ShareCurrent, 0.001)
+	require.InDelta(t, 25.0, a.PPChange, 0.01)
+
+	// Range endpoints need not exist and match the quarter bucket rollup.
+	in.PeriodBaseline, in.PeriodCurrent = "2023-12..2024-03", "2024-04..2024-09"
+	ranged, err := c.CompositionShift(context.Background(), in)
+	require.NoError(t, err)
+	require.Equal(t, out.Groups, ranged.Groups)
+	quarterly := base
+	quarterly.Granularity = "quarter"
+	quarterly.PeriodBaseline, quarterly.PeriodCurrent = "2024-Q1", "2024-Q2"
+	rolled, err := c.CompositionShift(context.Background(), quarterly)
+	require.NoError(t, err)
+	require.Equal(t, out.Groups, rolled.Groups)
+
+	// Auto-detection is unchanged without explicit periods.
+	auto, err := c.CompositionShift(context.Background(), base)
+	require.NoError(t, err)
+	require.Equal(t, "2024-05", auto.PeriodBaseline)
+	require.Equal(t, "2024-06", auto.PeriodCurrent)
+	require.Empty(t, auto.BaselinePeriods)
+
+	for _, tc := range []struct{ baseline, current, msg string }{
+		{"2024-01..2024-04", "2024-04..2024-06", `overlap on "2024-04"`},
+		{"2024-01,2024-02", "2024-02", `overlap on "2024-02"`},
+		{"2024-03..2024-01", "2024-04", "ends before it starts"},
+		{"2024-01,2024-13", "2024-04", `"2024-13" not found`},
+		{"2025-01..2025-03", "2024-04", "matches no periods"},
+		{"2024-01,", "2024-04", "empty period"},
+	} {
+		in.PeriodBaseline, in.PeriodCurrent = tc.baseline, tc.current
+		_, err := c.CompositionShift(context.Background(), in)
+		require.Error(t, err, tc.baseline)
+		require.Contains(t, err.Error(), "VALIDATION")
+		require.Contains(t, err.Error(), tc.msg)
+	}
+}
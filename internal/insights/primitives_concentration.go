@@ -8,6 +8,7 @@ import (
 
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -19,13 +20,39 @@ type ConcentrationMetricsInput struct {
 	DimIndex     int    `json:"dimension_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the grouping dimension"`
 	MeasureIndex int    `json:"measure_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the numeric measure"`
 	TopN         int    `json:"top_n,omitempty" validate:"omitempty,min=1,max=10" jsonschema_description:"Top-N groups to report and to compute Top-N share (default 5)"`
-	MaxCells     int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+	TimeIndex    int    `json:"time_index,omitempty" validate:"omitempty,min=1" jsonschema_description:"Optional 1-based column index within the range for a period; adds HHI and Top-N share per period"`
+	Granularity  string `json:"granularity,omitempty" validate:"omitempty,oneof=auto day week month quarter year" jsonschema_description:"With time_index: roll dates up to day, week, month, quarter, or year (auto picks from date spacing); omit to use each distinct value as a period"`
+	MaxPeriods   int    `json:"max_periods,omitempty" validate:"omitempty,min=2,max=60" jsonschema_description:"With time_index: most recent periods to report (default 12)"`
+	MaxCells     int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits); with time_index each period × group accumulator also costs one cell"`
 }
 
+// ConcentrationPeriod is the concentration of one period.
+type ConcentrationPeriod struct {
+	Period    string  `json:"period"`
+	Total     float64 `json:"total"`
+	Groups    int     `json:"groups"`
+	HHI       float64 `json:"hhi"`
+	TopNShare float64 `json:"top_n_share"`
+	Band      string  `json:"band"`
+}
+
+// ConcentrationTrend is the per-period series of concentration_metrics.
+type ConcentrationTrend struct {
+	Granularity      string                `json:"granularity,omitempty"`
+	Periods          []ConcentrationPeriod `json:"periods" jsonschema_description:"Periods in chronological order; periods with a zero total are skipped"`
+	HHIDelta         float64               `json:"hhi_delta" jsonschema_description:"Last period's HHI minus the first's; positive means concentration is rising"`
+	TopNShareDelta   float64               `json:"top_n_share_delta"`
+	PeriodsTotal     int                   `json:"periods_total"`
+	PeriodsTruncated bool                  `json:"periods_truncated" jsonschema_description:"Older periods beyond max_periods were left out"`
+}
+
+// GroupShare is one group's total and its share of the overall total.
+// CumShare is set by pareto_analysis only.
 type GroupShare struct {
-	Name  string  `json:"name"`
-	Share float64 `json:"share"`
-	Total float64 `json:"total"`
+	Name     string  `json:"name"`
+	Share    float64 `json:"share"`
+	Total    float64 `json:"total"`
+	CumShare float64 `json:"cumulative_share,omitempty"`
 }
 
 // ConcentrationMetricsOutput provides HHI banding and Top-N share with breakdown.
@@ -38,7 +65,10 @@ type ConcentrationMetricsOutput struct {
 	OtherShare float64      `json:"other_share"`
 	HHI        float64      `json:"hhi"`
 	Band       string       `json:"band"`
-	Meta       struct {
+	// Trend is set when time_index is given; the fields above then cover
+	// all periods together.
+	Trend *ConcentrationTrend `json:"trend,omitempty"`
+	Meta  struct {
 		ProcessedRows  int  `json:"processed_rows"`
 		ProcessedCells int  `json:"processed_cells"`
 		MaxCells       int  `json:"max_cells"`
@@ -67,36 +97,167 @@ func (c *Concentrator) ConcentrationMetrics(ctx context.Context, in Concentratio
 	}
 	out.Path = canonical
 
-	maxCells := in.MaxCells
-	if maxCells <= 0 || maxCells > c.Limits.MaxCellsPerOp {
-		maxCells = c.Limits.MaxCellsPerOp
+	if in.TimeIndex > 0 {
+		return c.concentrationTrend(ctx, id, in, out)
+	}
+	scan, err := c.scanGroupTotals(ctx, id, out.Sheet, in.Range, in.DimIndex, in.MeasureIndex, in.MaxCells)
+	out.Range = scan.rng
+	out.Meta.ProcessedRows, out.Meta.ProcessedCells = scan.rows, scan.cells
+	out.Meta.MaxCells, out.Meta.Truncated = scan.maxCells, scan.truncated
+	if err != nil {
+		return out, err
+	}
+	snap, ok := concentrationOf(scan.totals, out.TopN)
+	if !ok {
+		return out, fmt.Errorf("zero total measure; cannot compute shares")
+	}
+	out.Groups, out.OtherShare, out.HHI, out.Band = snap.groups, round3(1.0-snap.topShare), round3(snap.hhi), hhiBand(snap.hhi)
+	return out, nil
+}
+
+// concentrationTrend computes the overall snapshot plus HHI and Top-N share
+// per period from one period × group scan.
+func (c *Concentrator) concentrationTrend(ctx context.Context, id string, in ConcentrationMetricsInput, out ConcentrationMetricsOutput) (ConcentrationMetricsOutput, error) {
+	maxPeriods := in.MaxPeriods
+	if maxPeriods < 2 || maxPeriods > 60 {
+		maxPeriods = 12
+	}
+	scan, err := (&Composer{Limits: c.Limits, Mgr: c.Mgr}).scanPeriodGroupTotals(ctx, id, out.Sheet, in.Range, in.DimIndex, in.MeasureIndex, in.TimeIndex, in.MaxCells)
+	out.Range = scan.rng
+	out.Meta.ProcessedRows, out.Meta.ProcessedCells = scan.rows, scan.cells
+	out.Meta.MaxCells, out.Meta.Truncated = scan.maxCells, scan.truncated
+	if err != nil {
+		return out, err
+	}
+	trend := &ConcentrationTrend{}
+	if g := strings.ToLower(strings.TrimSpace(in.Granularity)); g != "" {
+		if trend.Granularity, err = scan.bucket(g); err != nil {
+			return out, err
+		}
+	}
+
+	overall := map[string]float64{}
+	periods := make([]string, 0, len(scan.acc))
+	for p, groups := range scan.acc {
+		periods = append(periods, p)
+		for g, v := range groups {
+			overall[g] += v
+		}
+	}
+	snap, ok := concentrationOf(overall, out.TopN)
+	if !ok {
+		return out, fmt.Errorf("zero total measure; cannot compute shares")
+	}
+	out.Groups, out.OtherShare, out.HHI, out.Band = snap.groups, round3(1.0-snap.topShare), round3(snap.hhi), hhiBand(snap.hhi)
+
+	sortPeriods(periods)
+	trend.PeriodsTotal = len(periods)
+	if len(periods) > maxPeriods {
+		periods = periods[len(periods)-maxPeriods:]
+		trend.PeriodsTruncated = true
+	}
+	trend.Periods = make([]ConcentrationPeriod, 0, len(periods))
+	for _, p := range periods {
+		ps, ok := concentrationOf(scan.acc[p], out.TopN)
+		if !ok {
+			continue
+		}
+		trend.Periods = append(trend.Periods, ConcentrationPeriod{Period: p, Total: round3(ps.total), Groups: len(scan.acc[p]), HHI: round3(ps.hhi), TopNShare: round3(ps.topShare), Band: hhiBand(ps.hhi)})
+	}
+	if n := len(trend.Periods); n >= 2 {
+		first, last := trend.Periods[0], trend.Periods[n-1]
+		trend.HHIDelta = round3(last.HHI - first.HHI)
+		trend.TopNShareDelta = round3(last.TopNShare - first.TopNShare)
+	}
+	out.Trend = trend
+	return out, nil
+}
+
+// concentration holds the shares of one set of group totals.
+type concentration struct {
+	groups   []GroupShare // the top-N groups
+	total    float64
+	topShare float64
+	hhi      float64
+}
+
+// concentrationOf computes the top-N shares and HHI (sum of squared shares
+// over all groups) of acc. It reports false when the total is zero.
+func concentrationOf(acc map[string]float64, topN int) (concentration, bool) {
+	var res concentration
+	for _, v := range acc {
+		res.total += v
+	}
+	if res.total == 0 {
+		return res, false
+	}
+	arr := sortedGroupTotals(acc)
+	for i, kvp := range arr {
+		sh := kvp.v / res.total
+		if i < topN {
+			res.groups = append(res.groups, GroupShare{Name: kvp.k, Share: round3(sh), Total: kvp.v})
+			res.topShare += sh
+		}
+		res.hhi += sh * sh
+	}
+	return res, true
+}
+
+// hhiBand classifies an HHI by common antitrust thresholds.
+func hhiBand(hhi float64) string {
+	switch {
+	case hhi < 0.15:
+		return "unconcentrated"
+	case hhi < 0.25:
+		return "moderately_concentrated"
+	default:
+		return "highly_concentrated"
 	}
-	out.Meta.MaxCells = maxCells
+}
 
-	// Accumulate totals by group
-	acc := map[string]float64{}
+// groupScan holds per-group measure totals from one streaming pass.
+type groupScan struct {
+	rng       string
+	totals    map[string]float64
+	rows      int
+	cells     int
+	maxCells  int
+	truncated bool
+}
 
-	err = c.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
-		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, out.Sheet, in.Range)
+// scanGroupTotals streams the data rows below the range header once and sums
+// the measure per dimension value. Blank dimensions group as "(empty)" and
+// non-numeric measures are skipped.
+func (c *Concentrator) scanGroupTotals(ctx context.Context, id, sheet, rng string, dimIndex, measureIndex, maxCells int) (groupScan, error) {
+	scan := groupScan{totals: map[string]float64{}, maxCells: maxCells}
+	if scan.maxCells <= 0 || scan.maxCells > c.Limits.MaxCellsPerOp {
+		scan.maxCells = c.Limits.MaxCellsPerOp
+	}
+	err := c.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, sheet, rng)
 		if rerr != nil {
 			return rerr
 		}
-		out.Range = normalized
+		scan.rng = normalized
 		colCount := x2 - x1 + 1
-		if in.DimIndex < 1 || in.DimIndex > colCount || in.MeasureIndex < 1 || in.MeasureIndex > colCount {
-			return fmt.Errorf("invalid dimension_index or measure_index; range has %d columns", colCount)
+		if dimIndex < 1 || dimIndex > colCount || measureIndex < 1 || measureIndex > colCount {
+			return mcperr.Errorf(mcperr.Validation, "invalid dimension_index or measure_index; range has %d columns", colCount)
 		}
+		dimAbs := x1 + (dimIndex - 1) - 1
+		measAbs := x1 + (measureIndex - 1) - 1
 
-		r, rerr := f.Rows(out.Sheet)
+		r, rerr := f.Rows(sheet)
 		if rerr != nil {
 			return rerr
 		}
 		defer r.Close()
 
-		cellsProcessed := 0
 		rowIdx := 0
 		for r.Next() {
 			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
 			if rowIdx <= y1 { // skip header row
 				continue
 			}
@@ -107,14 +268,12 @@ func (c *Concentrator) ConcentrationMetrics(ctx context.Context, in Concentratio
 			if cerr != nil {
 				return cerr
 			}
-			cellsProcessed += minInt(len(vals), colCount)
-			if cellsProcessed > maxCells {
-				out.Meta.Truncated = true
+			scan.cells += minInt(len(vals), colCount)
+			if scan.cells > scan.maxCells {
+				scan.truncated = true
 				break
 			}
 
-			dimAbs := x1 + (in.DimIndex - 1) - 1
-			measAbs := x1 + (in.MeasureIndex - 1) - 1
 			var dimVal, measVal string
 			if dimAbs >= 0 && dimAbs < len(vals) {
 				dimVal = strings.TrimSpace(vals[dimAbs])
@@ -129,64 +288,32 @@ func (c *Concentrator) ConcentrationMetrics(ctx context.Context, in Concentratio
 			if !ok {
 				continue
 			}
-			acc[dimVal] += mv
-			out.Meta.ProcessedRows++
+			scan.totals[dimVal] += mv
+			scan.rows++
 		}
-		out.Meta.ProcessedCells = cellsProcessed
 		return r.Error()
 	})
-	if err != nil {
-		return out, err
-	}
+	return scan, err
+}
 
-	// Compute shares
-	var total float64
-	for _, v := range acc {
-		total += v
-	}
-	if total == 0 {
-		return out, fmt.Errorf("zero total measure; cannot compute shares")
-	}
+type groupTotal struct {
+	k string
+	v float64
+}
 
-	type kv struct {
-		k string
-		v float64
-	}
-	arr := make([]kv, 0, len(acc))
+// sortedGroupTotals orders groups by total descending, then by name.
+func sortedGroupTotals(acc map[string]float64) []groupTotal {
+	arr := make([]groupTotal, 0, len(acc))
 	for k, v := range acc {
-		arr = append(arr, kv{k: k, v: v})
-	}
-	sort.Slice(arr, func(i, j int) bool { return arr[i].v > arr[j].v })
-
-	keep := out.TopN
-	if keep > len(arr) {
-		keep = len(arr)
-	}
-	var topShare float64
-	for i := 0; i < keep; i++ {
-		sh := arr[i].v / total
-		out.Groups = append(out.Groups, GroupShare{Name: arr[i].k, Share: round3(sh), Total: arr[i].v})
-		topShare += sh
-	}
-	out.OtherShare = round3(1.0 - topShare)
-
-	// HHI: sum of squared shares over all groups
-	var hhi float64
-	for _, kvp := range arr {
-		sh := kvp.v / total
-		hhi += sh * sh
+		arr = append(arr, groupTotal{k: k, v: v})
 	}
-	out.HHI = round3(hhi)
-	// Bands based on common antitrust thresholds
-	switch {
-	case hhi < 0.15:
-		out.Band = "unconcentrated"
-	case hhi < 0.25:
-		out.Band = "moderately_concentrated"
-	default:
-		out.Band = "highly_concentrated"
-	}
-	return out, nil
+	sort.Slice(arr, func(i, j int) bool {
+		if arr[i].v == arr[j].v {
+			return arr[i].k < arr[j].k
+		}
+		return arr[i].v > arr[j].v
+	})
+	return arr
 }
 
 // round3 provided in detect_tables.go; reuse within package
@@ -40,3 +40,56 @@ func TestConcentrationMetrics_HighlyConcentrated(t *testing.T) {
 	require.Equal(t, "highly_concentrated", out.Band)
 	require.InDelta(t, 0.68, out.HHI, 0.01)
 }
+
+func TestConcentrationMetrics_Trend(t *testing.T) {
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Date", "Customer", "Revenue"}))
+	rows := [][]any{
+		// January: four equal customers; March: one customer dominates.
+		{"2024-01-05", "A", 25}, {"2024-01-09", "B", 25}, {"2024-01-12", "C", 25}, {"2024-01-20", "D", 25},
+		{"2024-02-03", "A", 40}, {"2024-02-10", "B", 30}, {"2024-02-17", "C", 30},
+		{"2024-03-01", "A", 90}, {"2024-03-15", "B", 10},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "trend.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	c := &Concentrator{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	in := ConcentrationMetricsInput{Path: path, Sheet: "Sheet1", Range: "A1:C10", DimIndex: 2, MeasureIndex: 3, TopN: 1, TimeIndex: 1, Granularity: "month"}
+	out, err := c.ConcentrationMetrics(context.Background(), in)
+	require.NoError(t, err)
+	require.NotNil(t, out.Trend)
+	require.Equal(t, "month", out.Trend.Granularity)
+	require.Len(t, out.Trend.Periods, 3)
+	jan, mar := out.Trend.Periods[0], out.Trend.Periods[2]
+	require.Equal(t, ConcentrationPeriod{Period: "2024-01", Total: 100, Groups: 4, HHI: 0.25, TopNShare: 0.25, Band: "highly_concentrated"}, jan)
+	require.Equal(t, "2024-03", mar.Period)
+	require.InDelta(t, 0.82, mar.HHI, 1e-9)
+	require.InDelta(t, 0.57, out.Trend.HHIDelta, 1e-9)
+	require.InDelta(t, 0.65, out.Trend.TopNShareDelta, 1e-9)
+	// The snapshot covers all periods together.
+	require.Equal(t, "A", out.Groups[0].Name)
+	require.InDelta(t, 155.0/300, out.Groups[0].Share, 0.001)
+
+	in.MaxPeriods = 2
+	out, err = c.ConcentrationMetrics(context.Background(), in)
+	require.NoError(t, err)
+	require.True(t, out.Trend.PeriodsTruncated)
+	require.Equal(t, "2024-02", out.Trend.Periods[0].Period)
+
+	// Accumulators count against the budget: 3 rows of 3 cells plus 3 pairs.
+	in.MaxCells = 14
+	out, err = c.ConcentrationMetrics(context.Background(), in)
+	require.NoError(t, err)
+	require.True(t, out.Meta.Truncated)
+	require.Equal(t, 3, out.Meta.ProcessedRows)
+
+	// Without time_index the snapshot is unchanged and no trend is returned.
+	out, err = c.ConcentrationMetrics(context.Background(), ConcentrationMetricsInput{Path: path, Sheet: "Sheet1", Range: "A1:C10", DimIndex: 2, MeasureIndex: 3})
+	require.NoError(t, err)
+	require.Nil(t, out.Trend)
+}
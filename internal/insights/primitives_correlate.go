@@ -0,0 +1,333 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxCorrelateColumns bounds the O(k²) pairwise accumulators.
+const MaxCorrelateColumns = 12
+
+// maxCorrelateTopPairs caps the strongest pairs listed alongside the matrix.
+const maxCorrelateTopPairs = 10
+
+// CorrelateInput selects numeric columns for a pairwise correlation matrix.
+type CorrelateInput struct {
+	Path            string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical Excel file path (allowed directories enforced)"`
+	Sheet           string `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
+	Range           string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
+	ColumnIndices   []int  `json:"column_indices,omitempty" validate:"omitempty,min=2,max=12,dive,min=1" jsonschema_description:"1-based column indices within the range to correlate (2-12); default all columns when the range has at most 12"`
+	Method          string `json:"method,omitempty" validate:"omitempty,oneof=pearson spearman" jsonschema_description:"pearson (default) or spearman (rank correlation; holds column values in memory up to max_cells)"`
+	MinObservations int    `json:"min_observations,omitempty" validate:"omitempty,min=2" jsonschema_description:"Pairs with fewer usable rows report no coefficient and a warning (default 10)"`
+	MaxCells        int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+}
+
+// CorrelationColumn identifies a matrix row/column.
+type CorrelationColumn struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+// CorrelationPair is one off-diagonal coefficient.
+type CorrelationPair struct {
+	A int     `json:"a" jsonschema_description:"1-based column index within the range"`
+	B int     `json:"b" jsonschema_description:"1-based column index within the range"`
+	R float64 `json:"r"`
+	N int     `json:"n"`
+}
+
+// CorrelateOutput holds the symmetric correlation matrix in column order.
+// Matrix entries are null when a pair has too few observations or a column is
+// constant over the pair's rows.
+type CorrelateOutput struct {
+	Path     string              `json:"path"`
+	Sheet    string              `json:"sheet"`
+	Range    string              `json:"range"`
+	Method   string              `json:"method"`
+	Columns  []CorrelationColumn `json:"columns"`
+	Matrix   [][]*float64        `json:"matrix"`
+	Counts   [][]int             `json:"counts" jsonschema_description:"Rows where both values were numeric, per pair; the diagonal counts numeric values per column"`
+	TopPairs []CorrelationPair   `json:"top_pairs" jsonschema_description:"Strongest defined pairs by |r|"`
+	Warnings []string            `json:"warnings,omitempty"`
+	Meta     struct {
+		ProcessedRows   int  `json:"processed_rows"`
+		ProcessedCells  int  `json:"processed_cells"`
+		MaxCells        int  `json:"max_cells"`
+		Truncated       bool `json:"truncated"`
+		MinObservations int  `json:"min_observations"`
+	} `json:"meta"`
+}
+
+// Correlator computes pairwise correlations using streaming reads.
+type Correlator struct {
+	Limits runtime.Limits
+	Mgr    *workbooks.Manager
+}
+
+// pairAcc accumulates the sums Pearson needs for one column pair.
+type pairAcc struct {
+	n                     int
+	sx, sy, sxx, syy, sxy float64
+}
+
+func (p *pairAcc) add(x, y float64) {
+	p.n++
+	p.sx += x
+	p.sy += y
+	p.sxx += x * x
+	p.syy += y * y
+	p.sxy += x * y
+}
+
+// r returns the Pearson coefficient, or false when either side is constant.
+func (p *pairAcc) r() (float64, bool) {
+	n := float64(p.n)
+	vx := n*p.sxx - p.sx*p.sx
+	vy := n*p.syy - p.sy*p.sy
+	if vx <= 0 || vy <= 0 {
+		return 0, false
+	}
+	r := (n*p.sxy - p.sx*p.sy) / math.Sqrt(vx*vy)
+	return math.Max(-1, math.Min(1, r)), true
+}
+
+// Correlate streams the range once and computes pairwise-complete
+// correlations among the selected columns.
+func (c *Correlator) Correlate(ctx context.Context, in CorrelateInput) (CorrelateOutput, error) {
+	var out CorrelateOutput
+	out.Sheet = strings.TrimSpace(in.Sheet)
+	out.Method = strings.ToLower(strings.TrimSpace(in.Method))
+	if out.Method == "" {
+		out.Method = "pearson"
+	}
+	if out.Method != "pearson" && out.Method != "spearman" {
+		return out, mcperr.Errorf(mcperr.Validation, "invalid method %q; use pearson or spearman", in.Method)
+	}
+	minObs := in.MinObservations
+	if minObs < 2 {
+		minObs = 10
+	}
+	out.Meta.MinObservations = minObs
+
+	id, canonical, err := c.Mgr.GetOrOpenByPath(ctx, in.Path)
+	if err != nil {
+		return out, err
+	}
+	out.Path = canonical
+
+	maxCells := in.MaxCells
+	if maxCells <= 0 || maxCells > c.Limits.MaxCellsPerOp {
+		maxCells = c.Limits.MaxCellsPerOp
+	}
+	out.Meta.MaxCells = maxCells
+
+	var cols []int // 1-based within range
+	var accs [][]pairAcc
+	var colN []int         // numeric values seen per column
+	var values [][]float64 // spearman: per-row values, NaN when non-numeric
+
+	err = c.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, out.Sheet, in.Range)
+		if rerr != nil {
+			return rerr
+		}
+		out.Range = normalized
+		colCount := x2 - x1 + 1
+		if len(in.ColumnIndices) > 0 {
+			seen := map[int]bool{}
+			for _, idx := range in.ColumnIndices {
+				if idx < 1 || idx > colCount {
+					return mcperr.Errorf(mcperr.Validation, "invalid column_indices entry %d; range has %d columns", idx, colCount)
+				}
+				if !seen[idx] {
+					seen[idx] = true
+					cols = append(cols, idx)
+				}
+			}
+			if len(cols) < 2 {
+				return mcperr.Errorf(mcperr.Validation, "invalid column_indices; need at least 2 distinct columns")
+			}
+		} else {
+			if colCount < 2 {
+				return mcperr.Errorf(mcperr.Validation, "invalid range for correlation; need at least 2 columns")
+			}
+			if colCount > MaxCorrelateColumns {
+				return mcperr.Errorf(mcperr.Validation, "range has %d columns; select at most %d via column_indices", colCount, MaxCorrelateColumns)
+			}
+			for i := 1; i <= colCount; i++ {
+				cols = append(cols, i)
+			}
+		}
+		k := len(cols)
+		out.Columns = make([]CorrelationColumn, k)
+		for i, idx := range cols {
+			out.Columns[i] = CorrelationColumn{Index: idx, Name: fmt.Sprintf("col%d", idx)}
+		}
+		colN = make([]int, k)
+		accs = make([][]pairAcc, k)
+		for i := range accs {
+			accs[i] = make([]pairAcc, k)
+		}
+
+		r, rerr := f.Rows(out.Sheet)
+		if rerr != nil {
+			return rerr
+		}
+		defer r.Close()
+
+		row := make([]float64, k)
+		cellsProcessed := 0
+		rowIdx := 0
+		for r.Next() {
+			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
+			if rowIdx < y1 {
+				continue
+			}
+			if rowIdx > y2 {
+				break
+			}
+			vals, cerr := r.Columns()
+			if cerr != nil {
+				return cerr
+			}
+			if rowIdx == y1 { // header row names the columns
+				for i, idx := range cols {
+					if abs := x1 + idx - 2; abs < len(vals) && strings.TrimSpace(vals[abs]) != "" {
+						out.Columns[i].Name = strings.TrimSpace(vals[abs])
+					}
+				}
+				continue
+			}
+			cellsProcessed += minInt(len(vals), colCount)
+			if cellsProcessed > maxCells {
+				out.Meta.Truncated = true
+				break
+			}
+			for i, idx := range cols {
+				row[i] = math.NaN()
+				if abs := x1 + idx - 2; abs < len(vals) {
+					if v, ok := parseFloatStrict(strings.TrimSpace(vals[abs])); ok {
+						row[i] = v
+						colN[i]++
+					}
+				}
+			}
+			if out.Method == "spearman" {
+				values = append(values, append([]float64(nil), row...))
+			} else {
+				for i := 0; i < k; i++ {
+					if math.IsNaN(row[i]) {
+						continue
+					}
+					for j := i + 1; j < k; j++ {
+						if !math.IsNaN(row[j]) {
+							accs[i][j].add(row[i], row[j])
+						}
+					}
+				}
+			}
+			out.Meta.ProcessedRows++
+		}
+		out.Meta.ProcessedCells = cellsProcessed
+		return r.Error()
+	})
+	if err != nil {
+		return out, err
+	}
+
+	k := len(cols)
+	if out.Method == "spearman" {
+		for i := 0; i < k; i++ {
+			for j := i + 1; j < k; j++ {
+				accs[i][j] = spearmanPair(values, i, j)
+			}
+		}
+	}
+
+	out.Matrix = make([][]*float64, k)
+	out.Counts = make([][]int, k)
+	for i := range out.Matrix {
+		out.Matrix[i] = make([]*float64, k)
+		out.Counts[i] = make([]int, k)
+	}
+	out.TopPairs = []CorrelationPair{}
+	for i := 0; i < k; i++ {
+		one := 1.0
+		out.Matrix[i][i] = &one
+		out.Counts[i][i] = colN[i]
+		for j := i + 1; j < k; j++ {
+			a := accs[i][j]
+			out.Counts[i][j], out.Counts[j][i] = a.n, a.n
+			if a.n < minObs {
+				out.Warnings = append(out.Warnings, fmt.Sprintf("%s × %s: only %d paired observations (min %d)", out.Columns[i].Name, out.Columns[j].Name, a.n, minObs))
+				continue
+			}
+			rv, ok := a.r()
+			if !ok {
+				out.Warnings = append(out.Warnings, fmt.Sprintf("%s × %s: a column is constant over the paired rows", out.Columns[i].Name, out.Columns[j].Name))
+				continue
+			}
+			rv = round3(rv)
+			out.Matrix[i][j], out.Matrix[j][i] = &rv, &rv
+			out.TopPairs = append(out.TopPairs, CorrelationPair{A: cols[i], B: cols[j], R: rv, N: a.n})
+		}
+	}
+	sort.SliceStable(out.TopPairs, func(a, b int) bool {
+		return math.Abs(out.TopPairs[a].R) > math.Abs(out.TopPairs[b].R)
+	})
+	if len(out.TopPairs) > maxCorrelateTopPairs {
+		out.TopPairs = out.TopPairs[:maxCorrelateTopPairs]
+	}
+	return out, nil
+}
+
+// spearmanPair ranks the rows where both columns are numeric (average ranks
+// for ties) and accumulates Pearson sums over the ranks.
+func spearmanPair(values [][]float64, i, j int) pairAcc {
+	var xs, ys []float64
+	for _, v := range values {
+		if !math.IsNaN(v[i]) && !math.IsNaN(v[j]) {
+			xs = append(xs, v[i])
+			ys = append(ys, v[j])
+		}
+	}
+	rx, ry := averageRanks(xs), averageRanks(ys)
+	var acc pairAcc
+	for n := range rx {
+		acc.add(rx[n], ry[n])
+	}
+	return acc
+}
+
+// averageRanks returns 1-based ranks, giving tied values their mean rank.
+func averageRanks(xs []float64) []float64 {
+	idx := make([]int, len(xs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return xs[idx[a]] < xs[idx[b]] })
+	ranks := make([]float64, len(xs))
+	for start := 0; start < len(idx); {
+		end := start
+		for end+1 < len(idx) && xs[idx[end+1]] == xs[idx[start]] {
+			end++
+		}
+		avg := float64(start+end)/2 + 1
+		for p := start; p <= end; p++ {
+			ranks[idx[p]] = avg
+		}
+		start = end + 1
+	}
+	return ranks
+}
@@ -0,0 +1,69 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createCorrelationWorkbook(t *testing.T) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Corr"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Spend", "Revenue", "Returns", "Sparse"}))
+	for i := 1; i <= 12; i++ {
+		sparse := ""
+		if i <= 3 {
+			sparse = fmt.Sprint(i)
+		}
+		// Revenue grows with spend (exponentially, so rank correlation is exact);
+		// returns fall with spend.
+		row := []string{fmt.Sprint(i), fmt.Sprint(1 << i), fmt.Sprint(100 - 2*i), sparse}
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow(sh, cell, &row))
+	}
+	require.NoError(t, f.SetSheetRow(sh, "A14", &[]string{"n/a", "5", "", ""}))
+	path := filepath.Join(t.TempDir(), "corr.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestCorrelate_PearsonAndSpearman(t *testing.T) {
+	c := &Correlator{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createCorrelationWorkbook(t)
+
+	out, err := c.Correlate(context.Background(), CorrelateInput{Path: path, Sheet: sh, Range: "A1:D14"})
+	require.NoError(t, err)
+	require.Equal(t, "pearson", out.Method)
+	require.Equal(t, 13, out.Meta.ProcessedRows)
+	require.Len(t, out.Columns, 4)
+	require.Equal(t, "Returns", out.Columns[2].Name)
+	require.Equal(t, 12, out.Counts[0][0])
+	require.Equal(t, 13, out.Counts[1][1])
+	require.Equal(t, 12, out.Counts[0][1])
+	require.Equal(t, -1.0, *out.Matrix[0][2])
+	require.Less(t, *out.Matrix[0][1], 1.0)
+	require.Greater(t, *out.Matrix[0][1], 0.7)
+	// Sparse has only 3 observations against every other column.
+	require.Nil(t, out.Matrix[0][3])
+	require.Equal(t, 3, out.Counts[3][0])
+	require.Len(t, out.Warnings, 3)
+	require.Equal(t, CorrelationPair{A: 1, B: 3, R: -1, N: 12}, out.TopPairs[0])
+
+	out, err = c.Correlate(context.Background(), CorrelateInput{Path: path, Sheet: sh, Range: "A1:D14", ColumnIndices: []int{1, 2}, Method: "spearman"})
+	require.NoError(t, err)
+	require.Len(t, out.Columns, 2)
+	require.Equal(t, 1.0, *out.Matrix[0][1])
+	require.Empty(t, out.Warnings)
+
+	_, err = c.Correlate(context.Background(), CorrelateInput{Path: path, Sheet: sh, Range: "A1:D14", ColumnIndices: []int{1, 9}})
+	require.ErrorContains(t, err, "column_indices")
+}
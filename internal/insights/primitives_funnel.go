@@ -9,6 +9,7 @@ import (
 
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -18,9 +19,18 @@ type FunnelAnalysisInput struct {
 	Sheet        string `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
 	Range        string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
 	StageIndices []int  `json:"stage_indices,omitempty" validate:"dive,min=1" jsonschema_description:"Ordered 1-based column indices within the range for funnel stages; if omitted, detect from header names"`
+	SegmentIndex int    `json:"segment_index,omitempty" validate:"omitempty,min=1" jsonschema_description:"Optional 1-based column index within the range to break the funnel down by (e.g. device)"`
+	MaxSegments  int    `json:"max_segments,omitempty" validate:"omitempty,min=1,max=20" jsonschema_description:"Segments with the largest first-stage totals to report; the rest are combined into 'Other' (default 5)"`
 	MaxCells     int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
 }
 
+// maxTrackedSegments bounds the distinct segment values accumulated during
+// the scan; later values fold straight into otherSegment.
+const maxTrackedSegments = 1000
+
+// otherSegment labels segments folded together beyond the reported ones.
+const otherSegment = "Other"
+
 type StageMetric struct {
 	Name           string  `json:"name"`
 	Total          float64 `json:"total"`
@@ -28,6 +38,16 @@ type StageMetric struct {
 	CumulativeConv float64 `json:"cumulative_conversion"`
 }
 
+// FunnelSegment is the funnel of the rows sharing one segment value.
+type FunnelSegment struct {
+	Value      string        `json:"value"`
+	Rows       int           `json:"rows"`
+	Stages     []StageMetric `json:"stages"`
+	Bottleneck string        `json:"bottleneck_stage"`
+	// BottleneckConversion is the step conversion into Bottleneck.
+	BottleneckConversion float64 `json:"bottleneck_conversion"`
+}
+
 type FunnelAnalysisOutput struct {
 	Path       string        `json:"path"`
 	Sheet      string        `json:"sheet"`
@@ -35,11 +55,16 @@ type FunnelAnalysisOutput struct {
 	StageNames []string      `json:"stage_names"`
 	Stages     []StageMetric `json:"stages"`
 	Bottleneck string        `json:"bottleneck_stage"`
-	Meta       struct {
+	// Segment breakdown, present when segment_index is set.
+	SegmentColumn string          `json:"segment_column,omitempty"`
+	Segments      []FunnelSegment `json:"segments,omitempty" jsonschema_description:"Per-segment funnels, largest first-stage total first, with 'Other' last"`
+	WorstSegment  string          `json:"worst_segment,omitempty" jsonschema_description:"Reported segment (excluding Other) whose bottleneck step converts worst"`
+	Meta          struct {
 		ProcessedRows  int  `json:"processed_rows"`
 		ProcessedCells int  `json:"processed_cells"`
 		MaxCells       int  `json:"max_cells"`
 		Truncated      bool `json:"truncated"`
+		SegmentsTotal  int  `json:"segments_total,omitempty" jsonschema_description:"Distinct segment values seen (values past the tracking cap count once as Other)"`
 	} `json:"meta"`
 }
 
@@ -67,9 +92,18 @@ func (f *Funneler) FunnelAnalysis(ctx context.Context, in FunnelAnalysisInput) (
 		maxCells = f.Limits.MaxCellsPerOp
 	}
 	out.Meta.MaxCells = maxCells
+	maxSegments := in.MaxSegments
+	if maxSegments <= 0 || maxSegments > 20 {
+		maxSegments = 5
+	}
 
 	// Stage indices within range; detect when empty
 	var stageIdx []int
+	type segmentAcc struct {
+		totals []float64
+		rows   int
+	}
+	segments := map[string]*segmentAcc{}
 
 	err = f.Mgr.WithRead(id, func(ef *excelize.File, _ int64) error {
 		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(ef, out.Sheet, in.Range)
@@ -88,6 +122,9 @@ func (f *Funneler) FunnelAnalysis(ctx context.Context, in FunnelAnalysisInput) (
 		rowIdx := 0
 		for r.Next() {
 			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
 			vals, cerr := r.Columns()
 			if cerr != nil {
 				return cerr
@@ -105,11 +142,20 @@ func (f *Funneler) FunnelAnalysis(ctx context.Context, in FunnelAnalysisInput) (
 		if err := r.Error(); err != nil {
 			return err
 		}
+		if in.SegmentIndex > colCount {
+			return mcperr.Errorf(mcperr.Validation, "invalid segment_index %d; range has %d columns", in.SegmentIndex, colCount)
+		}
+		if in.SegmentIndex > 0 {
+			out.SegmentColumn = headers[in.SegmentIndex-1]
+			if out.SegmentColumn == "" {
+				out.SegmentColumn = fmt.Sprintf("$%d", in.SegmentIndex)
+			}
+		}
 		if len(in.StageIndices) > 0 {
 			// Validate provided indices
 			for _, idx := range in.StageIndices {
 				if idx < 1 || idx > colCount {
-					return fmt.Errorf("invalid stage index %d; range has %d columns", idx, colCount)
+					return mcperr.Errorf(mcperr.Validation, "invalid stage index %d; range has %d columns", idx, colCount)
 				}
 			}
 			stageIdx = append(stageIdx, in.StageIndices...)
@@ -137,6 +183,9 @@ func (f *Funneler) FunnelAnalysis(ctx context.Context, in FunnelAnalysisInput) (
 		row := 0
 		for r2.Next() {
 			row++
+			if err := scanCanceled(ctx, row); err != nil {
+				return err
+			}
 			if row <= y1 {
 				continue
 			}
@@ -152,17 +201,44 @@ func (f *Funneler) FunnelAnalysis(ctx context.Context, in FunnelAnalysisInput) (
 				out.Meta.Truncated = true
 				break
 			}
+			var seg *segmentAcc
+			if in.SegmentIndex > 0 {
+				key := "(empty)"
+				if abs := x1 + in.SegmentIndex - 2; abs < len(vals) && strings.TrimSpace(vals[abs]) != "" {
+					key = strings.TrimSpace(vals[abs])
+				}
+				if seg = segments[key]; seg == nil {
+					if len(segments) >= maxTrackedSegments {
+						key = otherSegment
+					}
+					if seg = segments[key]; seg == nil {
+						// A new segment's accumulators count against the
+						// cell budget like the cells that feed them.
+						cells += len(stageIdx)
+						if cells > maxCells {
+							out.Meta.Truncated = true
+							break
+						}
+						seg = &segmentAcc{totals: make([]float64, len(stageIdx))}
+						segments[key] = seg
+					}
+				}
+				seg.rows++
+			}
 			for i, idx := range stageIdx {
 				abs := x1 + (idx - 1) - 1
 				if abs >= 0 && abs < len(vals) {
 					if v, ok := parseFloatStrict(vals[abs]); ok {
 						totals[i] += v
+						if seg != nil {
+							seg.totals[i] += v
+						}
 					}
 				}
 			}
 			out.Meta.ProcessedRows++
 		}
-		out.Meta.ProcessedCells = cells
+		out.Meta.ProcessedCells = min(cells, maxCells)
 
 		// Stage names from headers
 		for _, idx := range stageIdx {
@@ -173,50 +249,105 @@ func (f *Funneler) FunnelAnalysis(ctx context.Context, in FunnelAnalysisInput) (
 			out.StageNames = append(out.StageNames, name)
 		}
 
-		// Compute conversions
-		stages := make([]StageMetric, len(stageIdx))
-		var first float64
-		if len(totals) > 0 {
-			first = totals[0]
+		out.Stages = stageMetrics(out.StageNames, totals)
+		out.Bottleneck, _ = funnelBottleneck(out.Stages)
+		return nil
+	})
+	if err != nil {
+		return out, err
+	}
+	if in.SegmentIndex == 0 {
+		return out, nil
+	}
+
+	// Report the segments with the largest first-stage totals and fold the
+	// rest into Other.
+	out.Meta.SegmentsTotal = len(segments)
+	keys := make([]string, 0, len(segments))
+	for k := range segments {
+		if k != otherSegment {
+			keys = append(keys, k)
 		}
-		for i := range totals {
-			step := 0.0
-			if i == 0 {
-				step = 1.0
-			} else if totals[i-1] > 0 {
-				step = totals[i] / totals[i-1]
-			}
-			cum := 0.0
-			if first > 0 {
-				cum = totals[i] / first
-			}
-			stages[i] = StageMetric{
-				Name:           out.StageNames[i],
-				Total:          round3(totals[i]),
-				StepConversion: round3(step),
-				CumulativeConv: round3(cum),
-			}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := segments[keys[i]].totals, segments[keys[j]].totals
+		if len(a) > 0 && a[0] != b[0] {
+			return a[0] > b[0]
 		}
-		out.Stages = stages
-		// Bottleneck: minimal step conversion among transitions
-		type bi struct {
-			name string
-			v    float64
+		return keys[i] < keys[j]
+	})
+	other := segments[otherSegment]
+	if len(keys) > maxSegments {
+		if other == nil {
+			other = &segmentAcc{totals: make([]float64, len(stageIdx))}
 		}
-		var pairs []bi
-		for i := 1; i < len(stages); i++ {
-			pairs = append(pairs, bi{name: stages[i].Name, v: stages[i].StepConversion})
+		for _, k := range keys[maxSegments:] {
+			other.rows += segments[k].rows
+			for i, v := range segments[k].totals {
+				other.totals[i] += v
+			}
 		}
-		sort.Slice(pairs, func(i, j int) bool { return pairs[i].v < pairs[j].v })
-		if len(pairs) > 0 {
-			out.Bottleneck = pairs[0].name
+		keys = keys[:maxSegments]
+	}
+	worst := -1.0
+	for _, k := range keys {
+		seg := funnelSegment(k, segments[k].rows, out.StageNames, segments[k].totals)
+		if seg.Bottleneck != "" && (worst < 0 || seg.BottleneckConversion < worst) {
+			worst, out.WorstSegment = seg.BottleneckConversion, k
 		}
-		return nil
-	})
-	if err != nil {
-		return out, err
+		out.Segments = append(out.Segments, seg)
+	}
+	if other != nil {
+		out.Segments = append(out.Segments, funnelSegment(otherSegment, other.rows, out.StageNames, other.totals))
 	}
 	return out, nil
 }
 
+// funnelSegment builds the funnel of one segment.
+func funnelSegment(value string, rows int, names []string, totals []float64) FunnelSegment {
+	seg := FunnelSegment{Value: value, Rows: rows, Stages: stageMetrics(names, totals)}
+	seg.Bottleneck, seg.BottleneckConversion = funnelBottleneck(seg.Stages)
+	return seg
+}
+
+// stageMetrics computes step and cumulative conversion of stage totals.
+func stageMetrics(names []string, totals []float64) []StageMetric {
+	stages := make([]StageMetric, len(totals))
+	var first float64
+	if len(totals) > 0 {
+		first = totals[0]
+	}
+	for i := range totals {
+		step := 0.0
+		if i == 0 {
+			step = 1.0
+		} else if totals[i-1] > 0 {
+			step = totals[i] / totals[i-1]
+		}
+		cum := 0.0
+		if first > 0 {
+			cum = totals[i] / first
+		}
+		stages[i] = StageMetric{
+			Name:           names[i],
+			Total:          round3(totals[i]),
+			StepConversion: round3(step),
+			CumulativeConv: round3(cum),
+		}
+	}
+	return stages
+}
+
+// funnelBottleneck returns the stage with the lowest step conversion among
+// transitions (the first on ties) and that conversion.
+func funnelBottleneck(stages []StageMetric) (string, float64) {
+	name, conv := "", 0.0
+	for i := 1; i < len(stages); i++ {
+		if name == "" || stages[i].StepConversion < conv {
+			name, conv = stages[i].Name, stages[i].StepConversion
+		}
+	}
+	return name, conv
+}
+
 // round3 provided in detect_tables.go; reuse within package
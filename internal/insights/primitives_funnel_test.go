@@ -46,3 +46,48 @@ func TestFunnelAnalysis_DetectsStagesAndConversions(t *testing.T) {
 		t.Fatalf("unexpected bottleneck: %s", out.Bottleneck)
 	}
 }
+
+func TestFunnelAnalysis_Segments(t *testing.T) {
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Device", "Visits", "Cart", "Orders"}))
+	rows := [][]any{
+		{"desktop", 100, 40, 20},
+		{"mobile", 100, 40, 10},
+		{"desktop", 100, 40, 20},
+		{"mobile", 100, 40, 10},
+		{"tablet", 10, 5, 1},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "segments.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	fn := &Funneler{Limits: runtime.NewLimits(8, 8), Mgr: mgr}
+	out, err := fn.FunnelAnalysis(context.Background(), FunnelAnalysisInput{Path: path, Sheet: "Sheet1", Range: "A1:D6", StageIndices: []int{2, 3, 4}, SegmentIndex: 1, MaxSegments: 2})
+	require.NoError(t, err)
+	require.Equal(t, "Device", out.SegmentColumn)
+	require.Equal(t, 3, out.Meta.SegmentsTotal)
+	require.InDelta(t, 410, out.Stages[0].Total, 1e-9)
+
+	// desktop and mobile tie on visits and sort by name; tablet folds into Other.
+	require.Len(t, out.Segments, 3)
+	desktop, mobile, other := out.Segments[0], out.Segments[1], out.Segments[2]
+	require.Equal(t, "desktop", desktop.Value)
+	require.InDelta(t, 0.5, desktop.Stages[2].StepConversion, 1e-9)
+	require.Equal(t, "mobile", mobile.Value)
+	require.Equal(t, 2, mobile.Rows)
+	require.Equal(t, "Orders", mobile.Bottleneck)
+	require.InDelta(t, 0.25, mobile.BottleneckConversion, 1e-9)
+	require.Equal(t, FunnelSegment{Value: "Other", Rows: 1, Stages: other.Stages, Bottleneck: "Orders", BottleneckConversion: 0.2}, other)
+	require.Equal(t, "mobile", out.WorstSegment)
+
+	// Each new segment's accumulators count against the cell budget.
+	out, err = fn.FunnelAnalysis(context.Background(), FunnelAnalysisInput{Path: path, Sheet: "Sheet1", Range: "A1:D6", StageIndices: []int{2, 3, 4}, SegmentIndex: 1, MaxCells: 13})
+	require.NoError(t, err)
+	require.True(t, out.Meta.Truncated)
+	require.Equal(t, 1, out.Meta.ProcessedRows)
+}
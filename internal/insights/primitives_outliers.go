@@ -0,0 +1,365 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/xuri/excelize/v2"
+)
+
+// Default thresholds per outlier method: modified z-score 3.5 (Iglewicz and
+// Hoaglin), Tukey fences at 1.5 IQR, and a classic |z| of 3.
+var defaultOutlierThreshold = map[string]float64{
+	"mad":    3.5,
+	"iqr":    1.5,
+	"zscore": 3.0,
+}
+
+// maxOutlierGroups caps the per-group statistics returned.
+const maxOutlierGroups = 50
+
+// OutlierDetectionInput selects a numeric column to scan for outliers,
+// optionally scoring each value against its own group.
+type OutlierDetectionInput struct {
+	Path         string  `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical Excel file path (allowed directories enforced)"`
+	Sheet        string  `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
+	Range        string  `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
+	MeasureIndex int     `json:"measure_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the numeric measure"`
+	DimIndex     int     `json:"dimension_index,omitempty" validate:"omitempty,min=1" jsonschema_description:"Optional 1-based column index within the range; values are scored against their own group"`
+	Method       string  `json:"method,omitempty" validate:"omitempty,oneof=mad iqr zscore" jsonschema_description:"mad (modified z-score, default), iqr (Tukey fences), or zscore"`
+	Threshold    float64 `json:"threshold,omitempty" validate:"omitempty,gt=0" jsonschema_description:"Flag values whose |score| exceeds this (defaults: mad 3.5, iqr 1.5, zscore 3)"`
+	TopK         int     `json:"top_k,omitempty" validate:"omitempty,min=1,max=50" jsonschema_description:"Max outlier rows to return, most extreme first (default 5)"`
+	SnapshotCols int     `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max range columns to include in each row snapshot (default 16)"`
+	MaxCells     int     `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+}
+
+// OutlierRow is one flagged value with its score and a bounded row snapshot.
+type OutlierRow struct {
+	Row       int      `json:"row"`
+	Group     string   `json:"group,omitempty"`
+	Value     float64  `json:"value"`
+	Score     float64  `json:"score" jsonschema_description:"Modified z-score (mad), z-score (zscore), or IQRs beyond the nearest quartile (iqr); signed"`
+	Direction string   `json:"direction" jsonschema_description:"high or low"`
+	Snapshot  []string `json:"snapshot"`
+}
+
+// OutlierGroupStats summarizes the distribution a group's values were scored
+// against. Center is the median (mad, iqr) or mean (zscore); Spread is the
+// MAD, IQR, or standard deviation respectively.
+type OutlierGroupStats struct {
+	Group    string  `json:"group,omitempty"`
+	Count    int     `json:"count"`
+	Center   float64 `json:"center"`
+	Spread   float64 `json:"spread"`
+	Lower    float64 `json:"lower_bound"`
+	Upper    float64 `json:"upper_bound"`
+	Outliers int     `json:"outliers"`
+}
+
+// OutlierDetectionOutput reports the most extreme outliers and group stats.
+type OutlierDetectionOutput struct {
+	Path          string              `json:"path"`
+	Sheet         string              `json:"sheet"`
+	Range         string              `json:"range"`
+	Method        string              `json:"method"`
+	Threshold     float64             `json:"threshold"`
+	TopK          int                 `json:"top_k"`
+	Outliers      []OutlierRow        `json:"outliers"`
+	TotalOutliers int                 `json:"total_outliers"`
+	Groups        []OutlierGroupStats `json:"groups"`
+	Meta          struct {
+		ProcessedRows  int  `json:"processed_rows"`
+		ProcessedCells int  `json:"processed_cells"`
+		MaxCells       int  `json:"max_cells"`
+		Truncated      bool `json:"truncated"`
+		NonNumeric     int  `json:"non_numeric" jsonschema_description:"Rows skipped because the measure was blank or not a number"`
+		GroupsTotal    int  `json:"groups_total"`
+	} `json:"meta"`
+}
+
+// OutlierDetector finds outliers in a numeric column using streaming reads.
+type OutlierDetector struct {
+	Limits runtime.Limits
+	Mgr    *workbooks.Manager
+}
+
+type outlierPoint struct {
+	row   int
+	value float64
+	snap  []string
+}
+
+// DetectOutliers streams the range once, computes robust statistics for the
+// measure (per group when requested), and returns the top-K outliers.
+func (d *OutlierDetector) DetectOutliers(ctx context.Context, in OutlierDetectionInput) (OutlierDetectionOutput, error) {
+	var out OutlierDetectionOutput
+	out.Sheet = strings.TrimSpace(in.Sheet)
+	out.Method = strings.ToLower(strings.TrimSpace(in.Method))
+	if out.Method == "" {
+		out.Method = "mad"
+	}
+	def, ok := defaultOutlierThreshold[out.Method]
+	if !ok {
+		return out, mcperr.Errorf(mcperr.Validation, "invalid method %q; use mad, iqr, or zscore", in.Method)
+	}
+	out.Threshold = in.Threshold
+	if out.Threshold <= 0 {
+		out.Threshold = def
+	}
+	out.TopK = in.TopK
+	if out.TopK <= 0 || out.TopK > 50 {
+		out.TopK = 5
+	}
+	snapCols := in.SnapshotCols
+	if snapCols <= 0 || snapCols > 256 {
+		snapCols = 16
+	}
+
+	id, canonical, err := d.Mgr.GetOrOpenByPath(ctx, in.Path)
+	if err != nil {
+		return out, err
+	}
+	out.Path = canonical
+
+	maxCells := in.MaxCells
+	if maxCells <= 0 || maxCells > d.Limits.MaxCellsPerOp {
+		maxCells = d.Limits.MaxCellsPerOp
+	}
+	out.Meta.MaxCells = maxCells
+
+	groups := map[string][]outlierPoint{}
+	err = d.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, out.Sheet, in.Range)
+		if rerr != nil {
+			return rerr
+		}
+		out.Range = normalized
+		colCount := x2 - x1 + 1
+		if in.MeasureIndex < 1 || in.MeasureIndex > colCount {
+			return mcperr.Errorf(mcperr.Validation, "invalid measure_index; range has %d columns", colCount)
+		}
+		if in.DimIndex != 0 && (in.DimIndex < 1 || in.DimIndex > colCount) {
+			return mcperr.Errorf(mcperr.Validation, "invalid dimension_index; range has %d columns", colCount)
+		}
+		measAbs := x1 + in.MeasureIndex - 2
+		dimAbs := x1 + in.DimIndex - 2
+		snapEnd := minInt(x2, x1+snapCols-1)
+
+		r, rerr := f.Rows(out.Sheet)
+		if rerr != nil {
+			return rerr
+		}
+		defer r.Close()
+
+		cellsProcessed := 0
+		rowIdx := 0
+		for r.Next() {
+			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
+			if rowIdx <= y1 { // skip header row
+				continue
+			}
+			if rowIdx > y2 {
+				break
+			}
+			vals, cerr := r.Columns()
+			if cerr != nil {
+				return cerr
+			}
+			cellsProcessed += minInt(len(vals), colCount)
+			if cellsProcessed > maxCells {
+				out.Meta.Truncated = true
+				break
+			}
+			var measVal string
+			if measAbs < len(vals) {
+				measVal = strings.TrimSpace(vals[measAbs])
+			}
+			mv, ok := parseFloatStrict(measVal)
+			if !ok {
+				out.Meta.NonNumeric++
+				continue
+			}
+			g := ""
+			if in.DimIndex > 0 {
+				g = "(empty)"
+				if dimAbs < len(vals) && strings.TrimSpace(vals[dimAbs]) != "" {
+					g = strings.TrimSpace(vals[dimAbs])
+				}
+			}
+			snap := make([]string, 0, snapEnd-x1+1)
+			for c := x1; c <= snapEnd; c++ {
+				v := ""
+				if c-1 < len(vals) {
+					v = vals[c-1]
+				}
+				snap = append(snap, v)
+			}
+			groups[g] = append(groups[g], outlierPoint{row: rowIdx, value: mv, snap: snap})
+			out.Meta.ProcessedRows++
+		}
+		out.Meta.ProcessedCells = cellsProcessed
+		return r.Error()
+	})
+	if err != nil {
+		return out, err
+	}
+	if out.Meta.ProcessedRows == 0 {
+		return out, fmt.Errorf("no numeric values found in measure column")
+	}
+
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	out.Meta.GroupsTotal = len(names)
+
+	var flagged []OutlierRow
+	var stats []OutlierGroupStats
+	for _, g := range names {
+		pts := groups[g]
+		st, score := outlierScorer(out.Method, pts)
+		st.Group = g
+		st.Count = len(pts)
+		for _, p := range pts {
+			s := score(p.value)
+			if math.Abs(s) <= out.Threshold {
+				continue
+			}
+			dir := "high"
+			if s < 0 {
+				dir = "low"
+			}
+			flagged = append(flagged, OutlierRow{Row: p.row, Group: g, Value: p.value, Score: round3(s), Direction: dir, Snapshot: p.snap})
+			st.Outliers++
+		}
+		switch out.Method {
+		case "iqr":
+			st.Lower, st.Upper = st.Lower-out.Threshold*st.Spread, st.Upper+out.Threshold*st.Spread
+		case "mad":
+			// Modified z = 0.6745 (x - median) / MAD
+			st.Lower, st.Upper = st.Center-out.Threshold*st.Spread/0.6745, st.Center+out.Threshold*st.Spread/0.6745
+		default:
+			st.Lower, st.Upper = st.Center-out.Threshold*st.Spread, st.Center+out.Threshold*st.Spread
+		}
+		st.Center, st.Spread = round3(st.Center), round3(st.Spread)
+		st.Lower, st.Upper = round3(st.Lower), round3(st.Upper)
+		stats = append(stats, st)
+	}
+
+	sort.SliceStable(flagged, func(i, j int) bool {
+		ai, aj := math.Abs(flagged[i].Score), math.Abs(flagged[j].Score)
+		if ai == aj {
+			return flagged[i].Row < flagged[j].Row
+		}
+		return ai > aj
+	})
+	out.TotalOutliers = len(flagged)
+	if len(flagged) > out.TopK {
+		flagged = flagged[:out.TopK]
+	}
+	out.Outliers = flagged
+	if out.Outliers == nil {
+		out.Outliers = []OutlierRow{}
+	}
+	// Groups with the most outliers first, then by name.
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Outliers > stats[j].Outliers })
+	if len(stats) > maxOutlierGroups {
+		stats = stats[:maxOutlierGroups]
+	}
+	out.Groups = stats
+	return out, nil
+}
+
+// outlierScorer computes a group's center and spread for method and returns a
+// signed scoring function. For iqr, Lower/Upper carry Q1/Q3 until the caller
+// widens them into fences. A zero spread scores every value as 0 except under
+// mad, which falls back to the mean absolute deviation.
+func outlierScorer(method string, pts []outlierPoint) (OutlierGroupStats, func(float64) float64) {
+	xs := make([]float64, len(pts))
+	for i, p := range pts {
+		xs[i] = p.value
+	}
+	sort.Float64s(xs)
+	var st OutlierGroupStats
+	switch method {
+	case "iqr":
+		q1, q3 := quantile(xs, 0.25), quantile(xs, 0.75)
+		iqr := q3 - q1
+		st.Center, st.Spread, st.Lower, st.Upper = quantile(xs, 0.5), iqr, q1, q3
+		return st, func(x float64) float64 {
+			switch {
+			case iqr == 0:
+				return 0
+			case x > q3:
+				return (x - q3) / iqr
+			case x < q1:
+				return (x - q1) / iqr
+			}
+			return 0
+		}
+	case "zscore":
+		var sum float64
+		for _, x := range xs {
+			sum += x
+		}
+		mean := sum / float64(len(xs))
+		var ss float64
+		for _, x := range xs {
+			ss += (x - mean) * (x - mean)
+		}
+		sd := math.Sqrt(ss / float64(len(xs)))
+		st.Center, st.Spread = mean, sd
+		return st, func(x float64) float64 {
+			if sd == 0 {
+				return 0
+			}
+			return (x - mean) / sd
+		}
+	default: // mad
+		med := quantile(xs, 0.5)
+		dev := make([]float64, len(xs))
+		var absSum float64
+		for i, x := range xs {
+			dev[i] = math.Abs(x - med)
+			absSum += dev[i]
+		}
+		sort.Float64s(dev)
+		mad := quantile(dev, 0.5)
+		st.Center, st.Spread = med, mad
+		return st, func(x float64) float64 {
+			if mad > 0 {
+				return 0.6745 * (x - med) / mad
+			}
+			// Over half the values are identical; use the mean absolute
+			// deviation so lone departures still score.
+			if meanAD := absSum / float64(len(xs)); meanAD > 0 {
+				return (x - med) / (1.253314 * meanAD)
+			}
+			return 0
+		}
+	}
+}
+
+// quantile returns the q-quantile of sorted xs using linear interpolation.
+func quantile(xs []float64, q float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	pos := q * float64(len(xs)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	if lo == hi {
+		return xs[lo]
+	}
+	return xs[lo] + (xs[hi]-xs[lo])*(pos-float64(lo))
+}
@@ -0,0 +1,93 @@
+package insights
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createOrdersWorkbook(t *testing.T) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Orders"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Region", "Order", "Amount"}))
+	rows := [][]string{
+		{"East", "o1", "10"},
+		{"East", "o2", "11"},
+		{"East", "o3", "12"},
+		{"West", "o4", "10"},
+		{"West", "o5", "11"},
+		{"West", "o6", "12"},
+		{"West", "o7", "10"},
+		{"East", "o8", "100"},
+		{"West", "o9", "n/a"},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "orders.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestDetectOutliers_Methods(t *testing.T) {
+	d := &OutlierDetector{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createOrdersWorkbook(t)
+
+	out, err := d.DetectOutliers(context.Background(), OutlierDetectionInput{Path: path, Sheet: sh, Range: "A1:C10", MeasureIndex: 3, SnapshotCols: 2})
+	require.NoError(t, err)
+	require.Equal(t, "mad", out.Method)
+	require.Equal(t, 3.5, out.Threshold)
+	require.Equal(t, 8, out.Meta.ProcessedRows)
+	require.Equal(t, 1, out.Meta.NonNumeric)
+	require.Equal(t, 1, out.TotalOutliers)
+	o := out.Outliers[0]
+	require.Equal(t, 9, o.Row)
+	require.Equal(t, 100.0, o.Value)
+	require.Equal(t, "high", o.Direction)
+	require.InDelta(t, 60.03, o.Score, 0.01)
+	require.Equal(t, []string{"East", "o8"}, o.Snapshot)
+	require.Len(t, out.Groups, 1)
+	require.Equal(t, 11.0, out.Groups[0].Center)
+	require.Equal(t, 1.0, out.Groups[0].Spread)
+
+	out, err = d.DetectOutliers(context.Background(), OutlierDetectionInput{Path: path, Sheet: sh, Range: "A1:C10", MeasureIndex: 3, Method: "iqr"})
+	require.NoError(t, err)
+	require.Equal(t, 1, out.TotalOutliers)
+	require.InDelta(t, 44.0, out.Outliers[0].Score, 0.001)
+	require.Equal(t, 7.0, out.Groups[0].Lower)
+	require.Equal(t, 15.0, out.Groups[0].Upper)
+
+	// A single extreme value inflates the standard deviation enough to hide itself.
+	out, err = d.DetectOutliers(context.Background(), OutlierDetectionInput{Path: path, Sheet: sh, Range: "A1:C10", MeasureIndex: 3, Method: "zscore"})
+	require.NoError(t, err)
+	require.Equal(t, 0, out.TotalOutliers)
+	require.Empty(t, out.Outliers)
+}
+
+func TestDetectOutliers_GroupsAndErrors(t *testing.T) {
+	d := &OutlierDetector{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createOrdersWorkbook(t)
+
+	out, err := d.DetectOutliers(context.Background(), OutlierDetectionInput{Path: path, Sheet: sh, Range: "A1:C10", MeasureIndex: 3, DimIndex: 1, Threshold: 3})
+	require.NoError(t, err)
+	require.Equal(t, 2, out.Meta.GroupsTotal)
+	require.Equal(t, 1, out.TotalOutliers)
+	require.Equal(t, "East", out.Outliers[0].Group)
+	require.Equal(t, "East", out.Groups[0].Group)
+	require.Equal(t, 1, out.Groups[0].Outliers)
+
+	_, err = d.DetectOutliers(context.Background(), OutlierDetectionInput{Path: path, Sheet: sh, Range: "A1:C10", MeasureIndex: 4})
+	require.ErrorContains(t, err, "measure_index")
+
+	_, err = d.DetectOutliers(context.Background(), OutlierDetectionInput{Path: path, Sheet: sh, Range: "A1:C10", MeasureIndex: 1})
+	require.ErrorContains(t, err, "no numeric values")
+}
@@ -0,0 +1,120 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultParetoThresholds are the cumulative-share percentages reported when
+// the caller supplies none.
+var defaultParetoThresholds = []float64{50, 80, 95}
+
+// ParetoAnalysisInput computes the cumulative share curve over a grouping dimension.
+type ParetoAnalysisInput struct {
+	Path         string    `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical Excel file path (allowed directories enforced)"`
+	Sheet        string    `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
+	Range        string    `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
+	DimIndex     int       `json:"dimension_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the grouping dimension"`
+	MeasureIndex int       `json:"measure_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the numeric measure"`
+	Thresholds   []float64 `json:"thresholds,omitempty" validate:"omitempty,max=10,dive,gt=0,lte=100" jsonschema_description:"Cumulative share percentages to report group counts for (default 50, 80, 95)"`
+	MaxGroups    int       `json:"max_groups,omitempty" validate:"omitempty,min=1,max=200" jsonschema_description:"Max groups to list with cumulative shares, largest first (default 20)"`
+	MaxCells     int       `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+}
+
+// ParetoThreshold reports how many of the largest groups reach a cumulative share.
+type ParetoThreshold struct {
+	Percent       float64 `json:"percent"`
+	Groups        int     `json:"groups" jsonschema_description:"Fewest groups whose combined total reaches percent of the overall total"`
+	PctOfGroups   float64 `json:"pct_of_groups" jsonschema_description:"Those groups as a percentage of all groups"`
+	AchievedShare float64 `json:"achieved_share" jsonschema_description:"Cumulative share actually covered by those groups"`
+}
+
+// ParetoAnalysisOutput provides the threshold summary and the head of the
+// cumulative share curve.
+type ParetoAnalysisOutput struct {
+	Path        string            `json:"path"`
+	Sheet       string            `json:"sheet"`
+	Range       string            `json:"range"`
+	Total       float64           `json:"total"`
+	GroupsTotal int               `json:"groups_total"`
+	Thresholds  []ParetoThreshold `json:"thresholds"`
+	Groups      []GroupShare      `json:"groups"`
+	OtherShare  float64           `json:"other_share"`
+	Meta        struct {
+		ProcessedRows   int  `json:"processed_rows"`
+		ProcessedCells  int  `json:"processed_cells"`
+		MaxCells        int  `json:"max_cells"`
+		Truncated       bool `json:"truncated"`
+		GroupsTruncated bool `json:"groups_truncated"`
+	} `json:"meta"`
+}
+
+// ParetoAnalysis aggregates the measure by dimension, sorts groups by total
+// descending, and reports how many groups are needed to reach each threshold.
+func (c *Concentrator) ParetoAnalysis(ctx context.Context, in ParetoAnalysisInput) (ParetoAnalysisOutput, error) {
+	var out ParetoAnalysisOutput
+	out.Sheet = strings.TrimSpace(in.Sheet)
+	maxGroups := in.MaxGroups
+	if maxGroups <= 0 || maxGroups > 200 {
+		maxGroups = 20
+	}
+	thresholds := append([]float64(nil), in.Thresholds...)
+	if len(thresholds) == 0 {
+		thresholds = append(thresholds, defaultParetoThresholds...)
+	}
+	sort.Float64s(thresholds)
+
+	id, canonical, err := c.Mgr.GetOrOpenByPath(ctx, in.Path)
+	if err != nil {
+		return out, err
+	}
+	out.Path = canonical
+
+	scan, err := c.scanGroupTotals(ctx, id, out.Sheet, in.Range, in.DimIndex, in.MeasureIndex, in.MaxCells)
+	out.Range = scan.rng
+	out.Meta.ProcessedRows, out.Meta.ProcessedCells = scan.rows, scan.cells
+	out.Meta.MaxCells, out.Meta.Truncated = scan.maxCells, scan.truncated
+	if err != nil {
+		return out, err
+	}
+
+	var total float64
+	for _, v := range scan.totals {
+		total += v
+	}
+	if total <= 0 {
+		return out, fmt.Errorf("total measure is not positive; cannot compute cumulative shares")
+	}
+	out.Total = round2(total)
+	arr := sortedGroupTotals(scan.totals)
+	out.GroupsTotal = len(arr)
+
+	// Walk the curve once; each threshold is met by the first prefix whose
+	// cumulative share reaches it (with a small tolerance for float sums).
+	out.Thresholds = make([]ParetoThreshold, 0, len(thresholds))
+	next := 0
+	var cum, listed float64
+	for i, g := range arr {
+		cum += g.v / total
+		if i < maxGroups {
+			out.Groups = append(out.Groups, GroupShare{Name: g.k, Share: round3(g.v / total), Total: g.v, CumShare: round3(cum)})
+			listed = cum
+		}
+		for next < len(thresholds) && cum*100 >= thresholds[next]-1e-9 {
+			out.Thresholds = append(out.Thresholds, ParetoThreshold{
+				Percent:       thresholds[next],
+				Groups:        i + 1,
+				PctOfGroups:   round2(float64(i+1) / float64(len(arr)) * 100),
+				AchievedShare: round3(cum),
+			})
+			next++
+		}
+	}
+	if len(arr) > maxGroups {
+		out.Meta.GroupsTruncated = true
+		out.OtherShare = round3(1 - listed)
+	}
+	return out, nil
+}
@@ -0,0 +1,62 @@
+package insights
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createCustomerRevenueWorkbook(t *testing.T) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Sales"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Customer", "Revenue"}))
+	rows := [][]string{
+		{"A", "30"}, {"B", "20"}, {"A", "20"}, {"C", "15"},
+		{"D", "10"}, {"E", "5"}, {"", "n/a"},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "customers.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestParetoAnalysis_Thresholds(t *testing.T) {
+	c := &Concentrator{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createCustomerRevenueWorkbook(t)
+
+	// Totals: A=50, B=20, C=15, D=10, E=5 (sum 100)
+	out, err := c.ParetoAnalysis(context.Background(), ParetoAnalysisInput{Path: path, Sheet: sh, Range: "A1:B8", DimIndex: 1, MeasureIndex: 2})
+	require.NoError(t, err)
+	require.Equal(t, 6, out.Meta.ProcessedRows)
+	require.Equal(t, 5, out.GroupsTotal)
+	require.Equal(t, 100.0, out.Total)
+	require.Len(t, out.Thresholds, 3)
+	require.Equal(t, 1, out.Thresholds[0].Groups) // 50% by A alone
+	require.Equal(t, 3, out.Thresholds[1].Groups) // 80% needs A, B, C (85%)
+	require.Equal(t, 60.0, out.Thresholds[1].PctOfGroups)
+	require.Equal(t, 0.85, out.Thresholds[1].AchievedShare)
+	require.Equal(t, 4, out.Thresholds[2].Groups) // 95% reached exactly at D
+	require.Len(t, out.Groups, 5)
+	require.Equal(t, "A", out.Groups[0].Name)
+	require.Equal(t, 0.95, out.Groups[3].CumShare)
+	require.False(t, out.Meta.GroupsTruncated)
+
+	out, err = c.ParetoAnalysis(context.Background(), ParetoAnalysisInput{Path: path, Sheet: sh, Range: "A1:B8", DimIndex: 1, MeasureIndex: 2, Thresholds: []float64{70}, MaxGroups: 2})
+	require.NoError(t, err)
+	require.Len(t, out.Thresholds, 1)
+	require.Equal(t, 2, out.Thresholds[0].Groups)
+	require.Len(t, out.Groups, 2)
+	require.True(t, out.Meta.GroupsTruncated)
+	require.Equal(t, 0.3, out.OtherShare)
+}
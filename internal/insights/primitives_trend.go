@@ -0,0 +1,300 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/xuri/excelize/v2"
+)
+
+// trendFlatThreshold is the per-period slope, as a fraction of the mean
+// period total, below which a series is classified as flat.
+const trendFlatThreshold = 0.02
+
+// TrendAnalysisInput computes per-period totals of a measure and their trend,
+// optionally per group.
+type TrendAnalysisInput struct {
+	Path         string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical Excel file path (allowed directories enforced)"`
+	Sheet        string `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
+	Range        string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
+	TimeIndex    int    `json:"time_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the period/time column"`
+	MeasureIndex int    `json:"measure_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the numeric measure"`
+	DimIndex     int    `json:"dimension_index,omitempty" validate:"omitempty,min=1" jsonschema_description:"Optional 1-based column index within the range for per-group trends"`
+	MaxPeriods   int    `json:"max_periods,omitempty" validate:"omitempty,min=2,max=120" jsonschema_description:"Keep only the most recent N periods (default 24)"`
+	TopN         int    `json:"top_n,omitempty" validate:"omitempty,min=1,max=10" jsonschema_description:"Groups with the largest totals to trend when dimension_index is set (default 5)"`
+	MaxCells     int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+}
+
+// PeriodMetric is one period's total and its change from the previous period.
+// Changes are omitted for the first period; PctChange is omitted when the
+// previous total is zero.
+type PeriodMetric struct {
+	Period    string   `json:"period"`
+	Total     float64  `json:"total"`
+	AbsChange *float64 `json:"abs_change,omitempty"`
+	PctChange *float64 `json:"pct_change,omitempty"`
+}
+
+// TrendSeries describes the trend of one series (overall or a group).
+type TrendSeries struct {
+	Group     string         `json:"group,omitempty"`
+	Periods   []PeriodMetric `json:"periods"`
+	Slope     float64        `json:"slope" jsonschema_description:"Least-squares change in total per period"`
+	SlopePct  float64        `json:"slope_pct_of_mean" jsonschema_description:"Slope as a percentage of the mean period total"`
+	Direction string         `json:"direction" jsonschema_description:"growing, flat, or declining"`
+}
+
+// TrendAnalysisOutput reports the overall trend and optional group trends.
+type TrendAnalysisOutput struct {
+	Path    string        `json:"path"`
+	Sheet   string        `json:"sheet"`
+	Range   string        `json:"range"`
+	Overall TrendSeries   `json:"overall"`
+	Groups  []TrendSeries `json:"groups,omitempty"`
+	Meta    struct {
+		ProcessedRows    int  `json:"processed_rows"`
+		ProcessedCells   int  `json:"processed_cells"`
+		MaxCells         int  `json:"max_cells"`
+		Truncated        bool `json:"truncated"`
+		PeriodsTotal     int  `json:"periods_total"`
+		PeriodsTruncated bool `json:"periods_truncated"`
+		GroupsTotal      int  `json:"groups_total,omitempty"`
+	} `json:"meta"`
+}
+
+// Trender executes period-over-period trend analysis using streaming reads.
+type Trender struct {
+	Limits runtime.Limits
+	Mgr    *workbooks.Manager
+}
+
+// TrendAnalysis streams the range once, totals the measure per period (and
+// per group), and fits a linear trend to the ordered period totals.
+func (t *Trender) TrendAnalysis(ctx context.Context, in TrendAnalysisInput) (TrendAnalysisOutput, error) {
+	var out TrendAnalysisOutput
+	out.Sheet = strings.TrimSpace(in.Sheet)
+	maxPeriods := in.MaxPeriods
+	if maxPeriods < 2 || maxPeriods > 120 {
+		maxPeriods = 24
+	}
+	topN := in.TopN
+	if topN <= 0 || topN > 10 {
+		topN = 5
+	}
+
+	id, canonical, err := t.Mgr.GetOrOpenByPath(ctx, in.Path)
+	if err != nil {
+		return out, err
+	}
+	out.Path = canonical
+
+	maxCells := in.MaxCells
+	if maxCells <= 0 || maxCells > t.Limits.MaxCellsPerOp {
+		maxCells = t.Limits.MaxCellsPerOp
+	}
+	out.Meta.MaxCells = maxCells
+
+	// Accumulators: period -> total, and group -> period -> total
+	totals := map[string]float64{}
+	byGroup := map[string]map[string]float64{}
+
+	err = t.Mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		x1, y1, x2, y2, normalized, rerr := resolveRangeLocal(f, out.Sheet, in.Range)
+		if rerr != nil {
+			return rerr
+		}
+		out.Range = normalized
+		colCount := x2 - x1 + 1
+		if in.TimeIndex < 1 || in.TimeIndex > colCount || in.MeasureIndex < 1 || in.MeasureIndex > colCount {
+			return mcperr.Errorf(mcperr.Validation, "invalid time_index or measure_index; range has %d columns", colCount)
+		}
+		if in.DimIndex != 0 && (in.DimIndex < 1 || in.DimIndex > colCount) {
+			return mcperr.Errorf(mcperr.Validation, "invalid dimension_index; range has %d columns", colCount)
+		}
+		timeAbs := x1 + in.TimeIndex - 2
+		measAbs := x1 + in.MeasureIndex - 2
+		dimAbs := x1 + in.DimIndex - 2
+
+		r, rerr := f.Rows(out.Sheet)
+		if rerr != nil {
+			return rerr
+		}
+		defer r.Close()
+
+		cellsProcessed := 0
+		rowIdx := 0
+		for r.Next() {
+			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
+			if rowIdx <= y1 { // skip header row
+				continue
+			}
+			if rowIdx > y2 {
+				break
+			}
+			vals, cerr := r.Columns()
+			if cerr != nil {
+				return cerr
+			}
+			cellsProcessed += minInt(len(vals), colCount)
+			if cellsProcessed > maxCells {
+				out.Meta.Truncated = true
+				break
+			}
+			var perVal, measVal string
+			if timeAbs < len(vals) {
+				perVal = strings.TrimSpace(vals[timeAbs])
+			}
+			if measAbs < len(vals) {
+				measVal = strings.TrimSpace(vals[measAbs])
+			}
+			if perVal == "" {
+				continue
+			}
+			mv, ok := parseFloatStrict(measVal)
+			if !ok {
+				continue
+			}
+			totals[perVal] += mv
+			if in.DimIndex > 0 {
+				g := "(empty)"
+				if dimAbs < len(vals) && strings.TrimSpace(vals[dimAbs]) != "" {
+					g = strings.TrimSpace(vals[dimAbs])
+				}
+				m, ok := byGroup[g]
+				if !ok {
+					m = map[string]float64{}
+					byGroup[g] = m
+				}
+				m[perVal] += mv
+			}
+			out.Meta.ProcessedRows++
+		}
+		out.Meta.ProcessedCells = cellsProcessed
+		return r.Error()
+	})
+	if err != nil {
+		return out, err
+	}
+
+	periods := make([]string, 0, len(totals))
+	for p := range totals {
+		periods = append(periods, p)
+	}
+	if len(periods) < 2 {
+		return out, fmt.Errorf("not enough distinct periods; need at least 2, found %d", len(periods))
+	}
+	sortPeriods(periods)
+	out.Meta.PeriodsTotal = len(periods)
+	if len(periods) > maxPeriods {
+		periods = periods[len(periods)-maxPeriods:]
+		out.Meta.PeriodsTruncated = true
+	}
+
+	out.Overall = trendSeries("", periods, totals)
+	if in.DimIndex > 0 {
+		out.Meta.GroupsTotal = len(byGroup)
+		type groupTotal struct {
+			name  string
+			total float64
+		}
+		ranked := make([]groupTotal, 0, len(byGroup))
+		for g, m := range byGroup {
+			var sum float64
+			for _, p := range periods {
+				sum += m[p]
+			}
+			ranked = append(ranked, groupTotal{g, sum})
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			ai, aj := math.Abs(ranked[i].total), math.Abs(ranked[j].total)
+			if ai == aj {
+				return ranked[i].name < ranked[j].name
+			}
+			return ai > aj
+		})
+		if len(ranked) > topN {
+			ranked = ranked[:topN]
+		}
+		for _, g := range ranked {
+			out.Groups = append(out.Groups, trendSeries(g.name, periods, byGroup[g.name]))
+		}
+	}
+	return out, nil
+}
+
+// sortPeriods orders period labels chronologically when every label parses
+// as a date and lexicographically otherwise.
+func sortPeriods(periods []string) {
+	parsed := make(map[string]int64, len(periods))
+	for _, p := range periods {
+		tm, ok := tryParseTime(p)
+		if !ok {
+			sort.Strings(periods)
+			return
+		}
+		parsed[p] = tm.UnixNano()
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		if parsed[periods[i]] == parsed[periods[j]] {
+			return periods[i] < periods[j]
+		}
+		return parsed[periods[i]] < parsed[periods[j]]
+	})
+}
+
+// trendSeries builds period metrics and the least-squares slope over the
+// ordered periods; periods missing from totals count as zero.
+func trendSeries(group string, periods []string, totals map[string]float64) TrendSeries {
+	s := TrendSeries{Group: group, Periods: make([]PeriodMetric, 0, len(periods))}
+	ys := make([]float64, len(periods))
+	for i, p := range periods {
+		ys[i] = totals[p]
+		pm := PeriodMetric{Period: p, Total: round2(ys[i])}
+		if i > 0 {
+			abs := round2(ys[i] - ys[i-1])
+			pm.AbsChange = &abs
+			if ys[i-1] != 0 {
+				pct := round2((ys[i] - ys[i-1]) / math.Abs(ys[i-1]) * 100)
+				pm.PctChange = &pct
+			}
+		}
+		s.Periods = append(s.Periods, pm)
+	}
+
+	n := float64(len(ys))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range ys {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	if den := n*sumXX - sumX*sumX; den != 0 {
+		s.Slope = (n*sumXY - sumX*sumY) / den
+	}
+	rel := 0.0
+	if mean := sumY / n; mean != 0 {
+		rel = s.Slope / math.Abs(mean)
+	}
+	s.Slope = round3(s.Slope)
+	s.SlopePct = round2(rel * 100)
+	switch {
+	case rel > trendFlatThreshold:
+		s.Direction = "growing"
+	case rel < -trendFlatThreshold:
+		s.Direction = "declining"
+	default:
+		s.Direction = "flat"
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package insights
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createMonthlyRevenueWorkbook(t *testing.T) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Revenue"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Region", "Month", "Revenue"}))
+	rows := [][]string{
+		// Out of order on purpose: periods must sort chronologically.
+		{"East", "2024-03", "150"},
+		{"West", "2024-03", "50"},
+		{"East", "2024-01", "100"},
+		{"West", "2024-01", "100"},
+		{"East", "2024-02", "120"},
+		{"West", "2024-02", "80"},
+		{"East", "2024-04", "180"},
+		{"West", "2024-04", "20"},
+		{"West", "2024-04", "n/a"},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "revenue.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestTrendAnalysis_OverallAndGroups(t *testing.T) {
+	tr := &Trender{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createMonthlyRevenueWorkbook(t)
+
+	out, err := tr.TrendAnalysis(context.Background(), TrendAnalysisInput{Path: path, Sheet: sh, Range: "A1:C10", TimeIndex: 2, MeasureIndex: 3, DimIndex: 1})
+	require.NoError(t, err)
+	require.Equal(t, 8, out.Meta.ProcessedRows)
+
+	o := out.Overall
+	require.Len(t, o.Periods, 4)
+	require.Equal(t, []string{"2024-01", "2024-02", "2024-03", "2024-04"}, []string{o.Periods[0].Period, o.Periods[1].Period, o.Periods[2].Period, o.Periods[3].Period})
+	for _, p := range o.Periods {
+		require.Equal(t, 200.0, p.Total)
+	}
+	require.Nil(t, o.Periods[0].AbsChange)
+	require.Equal(t, 0.0, *o.Periods[1].AbsChange)
+	require.Equal(t, "flat", o.Direction)
+
+	require.Len(t, out.Groups, 2)
+	byName := map[string]TrendSeries{}
+	for _, g := range out.Groups {
+		byName[g.Group] = g
+	}
+	east, west := byName["East"], byName["West"]
+	require.Equal(t, "growing", east.Direction)
+	require.InDelta(t, 27.0, east.Slope, 0.001)
+	require.Equal(t, 20.0, *east.Periods[1].AbsChange)
+	require.Equal(t, 20.0, *east.Periods[1].PctChange)
+	require.Equal(t, "declining", west.Direction)
+	require.Equal(t, -60.0, *west.Periods[3].PctChange)
+}
+
+func TestTrendAnalysis_MaxPeriodsAndValidation(t *testing.T) {
+	tr := &Trender{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createMonthlyRevenueWorkbook(t)
+
+	out, err := tr.TrendAnalysis(context.Background(), TrendAnalysisInput{Path: path, Sheet: sh, Range: "A1:C10", TimeIndex: 2, MeasureIndex: 3, MaxPeriods: 2})
+	require.NoError(t, err)
+	require.True(t, out.Meta.PeriodsTruncated)
+	require.Equal(t, 4, out.Meta.PeriodsTotal)
+	require.Equal(t, "2024-03", out.Overall.Periods[0].Period)
+
+	_, err = tr.TrendAnalysis(context.Background(), TrendAnalysisInput{Path: path, Sheet: sh, Range: "A1:C10", TimeIndex: 5, MeasureIndex: 3})
+	require.ErrorContains(t, err, "time_index")
+
+	_, err = tr.TrendAnalysis(context.Background(), TrendAnalysisInput{Path: path, Sheet: sh, Range: "A1:C2", TimeIndex: 2, MeasureIndex: 3})
+	require.ErrorContains(t, err, "not enough distinct periods")
+}
@@ -0,0 +1,179 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+)
+
+// VarianceBridgeInput decomposes the change in a measure between two periods
+// into per-group contributions.
+type VarianceBridgeInput struct {
+	Path           string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical Excel file path (allowed directories enforced)"`
+	Sheet          string `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
+	Range          string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name covering header + data"`
+	DimIndex       int    `json:"dimension_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the grouping dimension"`
+	MeasureIndex   int    `json:"measure_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the numeric measure"`
+	TimeIndex      int    `json:"time_index" validate:"min=1" jsonschema_description:"1-based column index within the range for the period/time column"`
+	PeriodBaseline string `json:"period_baseline,omitempty" jsonschema_description:"Optional baseline period value; if omitted, detected as earlier of last two periods"`
+	PeriodCurrent  string `json:"period_current,omitempty" jsonschema_description:"Optional current period value; if omitted, detected as latest of last two periods"`
+	Granularity    string `json:"granularity,omitempty" validate:"omitempty,oneof=auto day week month quarter year" jsonschema_description:"Roll date periods up to day, week (ISO, 2024-W05), month (2024-01), quarter (2024-Q1), or year; auto picks from date spacing. Period values then refer to bucket labels. Omit to treat each distinct value as a period"`
+	TopN           int    `json:"top_n,omitempty" validate:"omitempty,min=1,max=20" jsonschema_description:"Largest contributors by absolute delta to return; remaining combined into 'Other' (default 5)"`
+	MaxCells       int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded by global limits)"`
+}
+
+// GroupContribution is one group's change between the two periods. A group
+// absent from a period counts as zero there. PctOfDelta is omitted when the
+// total delta is zero.
+type GroupContribution struct {
+	Name       string   `json:"name"`
+	Rank       int      `json:"rank" jsonschema_description:"1-based rank by absolute delta across all groups"`
+	Baseline   float64  `json:"baseline"`
+	Current    float64  `json:"current"`
+	Delta      float64  `json:"delta"`
+	PctOfDelta *float64 `json:"pct_of_delta,omitempty" jsonschema_description:"Delta as a percentage of the total delta; signs differ for groups moving against the total"`
+}
+
+// OtherContribution combines the groups outside the Top-N.
+type OtherContribution struct {
+	Groups     int      `json:"groups"`
+	Delta      float64  `json:"delta"`
+	PctOfDelta *float64 `json:"pct_of_delta,omitempty"`
+}
+
+// VarianceBridgeOutput walks from the baseline total to the current total.
+type VarianceBridgeOutput struct {
+	Path            string              `json:"path"`
+	Sheet           string              `json:"sheet"`
+	Range           string              `json:"range"`
+	PeriodBaseline  string              `json:"period_baseline"`
+	PeriodCurrent   string              `json:"period_current"`
+	Granularity     string              `json:"granularity,omitempty"`
+	TotalBaseline   float64             `json:"total_baseline"`
+	TotalCurrent    float64             `json:"total_current"`
+	TotalDelta      float64             `json:"total_delta"`
+	SharesUndefined bool                `json:"shares_undefined" jsonschema_description:"True when the total delta is zero, so pct_of_delta is omitted"`
+	TopN            int                 `json:"top_n"`
+	Positive        []GroupContribution `json:"positive"`
+	Negative        []GroupContribution `json:"negative"`
+	Other           OtherContribution   `json:"other"`
+	Meta            struct {
+		ProcessedRows  int  `json:"processed_rows"`
+		ProcessedCells int  `json:"processed_cells"`
+		MaxCells       int  `json:"max_cells"`
+		Truncated      bool `json:"truncated"`
+		GroupsTotal    int  `json:"groups_total"`
+	} `json:"meta"`
+}
+
+// VarianceBridge computes each group's absolute contribution to the change in
+// the measure total between the baseline and current periods.
+func (c *Composer) VarianceBridge(ctx context.Context, in VarianceBridgeInput) (VarianceBridgeOutput, error) {
+	var out VarianceBridgeOutput
+	out.Sheet = strings.TrimSpace(in.Sheet)
+	out.TopN = in.TopN
+	if out.TopN <= 0 || out.TopN > 20 {
+		out.TopN = 5
+	}
+	out.Positive = []GroupContribution{}
+	out.Negative = []GroupContribution{}
+
+	id, canonical, err := c.Mgr.GetOrOpenByPath(ctx, in.Path)
+	if err != nil {
+		return out, err
+	}
+	out.Path = canonical
+
+	if in.TimeIndex <= 0 {
+		return out, mcperr.Errorf(mcperr.Validation, "time_index is required to compute a variance bridge")
+	}
+	scan, err := c.scanPeriodGroupTotals(ctx, id, out.Sheet, in.Range, in.DimIndex, in.MeasureIndex, in.TimeIndex, in.MaxCells)
+	out.Range = scan.rng
+	out.Meta.ProcessedRows, out.Meta.ProcessedCells = scan.rows, scan.cells
+	out.Meta.MaxCells, out.Meta.Truncated = scan.maxCells, scan.truncated
+	if err != nil {
+		return out, err
+	}
+	if g := strings.ToLower(strings.TrimSpace(in.Granularity)); g != "" {
+		if out.Granularity, err = scan.bucket(g); err != nil {
+			return out, err
+		}
+	}
+	perBaseline, perCurrent, err := pickPeriods(scan.periods, in.PeriodBaseline, in.PeriodCurrent)
+	if err != nil {
+		return out, err
+	}
+	out.PeriodBaseline = perBaseline
+	out.PeriodCurrent = perCurrent
+
+	base, curr := scan.acc[perBaseline], scan.acc[perCurrent]
+	if base == nil || curr == nil {
+		return out, fmt.Errorf("missing period aggregates for baseline or current")
+	}
+
+	uniq := map[string]struct{}{}
+	var totBase, totCurr float64
+	for k, v := range base {
+		uniq[k] = struct{}{}
+		totBase += v
+	}
+	for k, v := range curr {
+		uniq[k] = struct{}{}
+		totCurr += v
+	}
+	delta := totCurr - totBase
+	out.TotalBaseline, out.TotalCurrent, out.TotalDelta = round2(totBase), round2(totCurr), round2(delta)
+	// Treat float noise relative to the totals as an exact zero delta.
+	scale := math.Max(1, math.Max(math.Abs(totBase), math.Abs(totCurr)))
+	out.SharesUndefined = math.Abs(delta) <= 1e-9*scale
+	pctOf := func(d float64) *float64 {
+		if out.SharesUndefined {
+			return nil
+		}
+		p := round2(d / delta * 100)
+		return &p
+	}
+
+	rows := make([]GroupContribution, 0, len(uniq))
+	for g := range uniq {
+		rows = append(rows, GroupContribution{Name: g, Baseline: base[g], Current: curr[g], Delta: curr[g] - base[g]})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		ai, aj := math.Abs(rows[i].Delta), math.Abs(rows[j].Delta)
+		if ai == aj {
+			return rows[i].Name < rows[j].Name
+		}
+		return ai > aj
+	})
+	out.Meta.GroupsTotal = len(rows)
+
+	var otherDelta float64
+	for i, r := range rows {
+		if i >= out.TopN {
+			out.Other.Groups++
+			otherDelta += r.Delta
+			continue
+		}
+		r.Rank = i + 1
+		r.PctOfDelta = pctOf(r.Delta)
+		r.Baseline, r.Current, r.Delta = round2(r.Baseline), round2(r.Current), round2(r.Delta)
+		switch {
+		case r.Delta > 0:
+			out.Positive = append(out.Positive, r)
+		case r.Delta < 0:
+			out.Negative = append(out.Negative, r)
+		default:
+			// Unchanged groups contribute nothing; report them with Other.
+			out.Other.Groups++
+		}
+	}
+	out.Other.Delta = round2(otherDelta)
+	if out.Other.Groups > 0 {
+		out.Other.PctOfDelta = pctOf(otherDelta)
+	}
+	return out, nil
+}
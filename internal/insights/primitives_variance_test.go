@@ -0,0 +1,85 @@
+package insights
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createBridgeWorkbook(t *testing.T, rows [][]string) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Bridge"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Product", "Month", "Revenue"}))
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "bridge.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestVarianceBridge_GroupsInOnePeriod(t *testing.T) {
+	c := &Composer{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createBridgeWorkbook(t, [][]string{
+		{"A", "2024-01", "100"},
+		{"B", "2024-01", "50"},
+		{"C", "2024-01", "30"}, // discontinued
+		{"A", "2024-02", "160"},
+		{"B", "2024-02", "40"},
+		{"D", "2024-02", "20"}, // new
+	})
+
+	out, err := c.VarianceBridge(context.Background(), VarianceBridgeInput{Path: path, Sheet: sh, Range: "A1:C7", DimIndex: 1, MeasureIndex: 3, TimeIndex: 2, TopN: 3})
+	require.NoError(t, err)
+	require.Equal(t, "2024-01", out.PeriodBaseline)
+	require.Equal(t, "2024-02", out.PeriodCurrent)
+	require.Equal(t, 180.0, out.TotalBaseline)
+	require.Equal(t, 220.0, out.TotalCurrent)
+	require.Equal(t, 40.0, out.TotalDelta)
+	require.False(t, out.SharesUndefined)
+
+	// Ranked by |delta|: A +60, C -30, D +20, B -10
+	require.Len(t, out.Positive, 2)
+	require.Equal(t, "A", out.Positive[0].Name)
+	require.Equal(t, 1, out.Positive[0].Rank)
+	require.Equal(t, 150.0, *out.Positive[0].PctOfDelta)
+	require.Equal(t, "D", out.Positive[1].Name)
+	require.Equal(t, 0.0, out.Positive[1].Baseline)
+	require.Len(t, out.Negative, 1)
+	require.Equal(t, "C", out.Negative[0].Name)
+	require.Equal(t, 0.0, out.Negative[0].Current)
+	require.Equal(t, -75.0, *out.Negative[0].PctOfDelta)
+	require.Equal(t, 1, out.Other.Groups)
+	require.Equal(t, -10.0, out.Other.Delta)
+	require.Equal(t, -25.0, *out.Other.PctOfDelta)
+}
+
+func TestVarianceBridge_ZeroTotalDelta(t *testing.T) {
+	c := &Composer{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+	path, sh := createBridgeWorkbook(t, [][]string{
+		{"A", "Q1", "100"},
+		{"B", "Q1", "100"},
+		{"A", "Q2", "130"},
+		{"B", "Q2", "70"},
+	})
+
+	out, err := c.VarianceBridge(context.Background(), VarianceBridgeInput{Path: path, Sheet: sh, Range: "A1:C5", DimIndex: 1, MeasureIndex: 3, TimeIndex: 2, PeriodBaseline: "Q1", PeriodCurrent: "Q2"})
+	require.NoError(t, err)
+	require.Equal(t, 0.0, out.TotalDelta)
+	require.True(t, out.SharesUndefined)
+	require.Len(t, out.Positive, 1)
+	require.Len(t, out.Negative, 1)
+	require.Equal(t, 30.0, out.Positive[0].Delta)
+	require.Nil(t, out.Positive[0].PctOfDelta)
+	require.Nil(t, out.Negative[0].PctOfDelta)
+	require.Nil(t, out.Other.PctOfDelta)
+}
@@ -3,7 +3,10 @@ package insights
 import (
 	"context"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"math"
+	"math/rand/v2"
 	"regexp"
 	"sort"
 	"strconv"
@@ -12,6 +15,7 @@ import (
 
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -21,6 +25,8 @@ type ProfileSchemaInput struct {
 	Sheet         string `json:"sheet" validate:"required" jsonschema_description:"Sheet name to analyze"`
 	Range         string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name for the table region"`
 	MaxSampleRows int    `json:"max_sample_rows,omitempty" validate:"omitempty,min=1,max=100" jsonschema_description:"Max non-header rows to sample per column (default 100)"`
+	Examples      *bool  `json:"examples,omitempty" jsonschema_description:"Return up to 3 distinct sample values per column (default true); set false for sensitive data"`
+	HeaderRows    *int   `json:"header_rows,omitempty" validate:"omitempty,min=0,max=2" jsonschema_description:"Header rows at the top of the range: 0 (none; columns are named $1..$N), 1, or 2 (names joined as 'top / bottom'). Omit to detect the most header-like row among the first few"`
 }
 
 // ColumnProfile summarizes inferred role, type, and quality for one column.
@@ -32,6 +38,7 @@ type ColumnProfile struct {
 	Sampled     int      `json:"sampled"`
 	MissingPct  float64  `json:"missing_pct"`
 	UniqueRatio float64  `json:"unique_ratio"`
+	Examples    []string `json:"examples,omitempty" jsonschema_description:"Up to 3 distinct non-empty sampled values, randomly chosen and truncated"`
 	Flags       []string `json:"flags,omitempty"`
 	Warnings    []string `json:"warnings,omitempty"`
 }
@@ -44,12 +51,29 @@ type ProfileSchemaOutput struct {
 	Columns   []ColumnProfile `json:"columns"`
 	Questions []string        `json:"questions,omitempty"`
 	Meta      struct {
-		SampledRows int  `json:"sampled_rows"`
-		MaxSample   int  `json:"max_sample"`
-		Truncated   bool `json:"truncated"`
+		SampledRows    int  `json:"sampled_rows"`
+		MaxSample      int  `json:"max_sample"`
+		Truncated      bool `json:"truncated"`
+		HeaderRow      int  `json:"header_row" jsonschema_description:"Sheet row of the (first) header row; 0 when the range has no header"`
+		HeaderRows     int  `json:"header_rows" jsonschema_description:"Number of header rows used for column names"`
+		HeaderDetected bool `json:"header_detected" jsonschema_description:"True when the header row was chosen automatically"`
+		DataStartRow   int  `json:"data_start_row" jsonschema_description:"Sheet row of the first data row; rows above it in the range are headers or titles"`
+		// Text payload size and the bounds it was built under.
+		BytesReturned int         `json:"bytes_returned,omitempty"`
+		Limits        *PageLimits `json:"limits,omitempty"`
 	} `json:"meta"`
 }
 
+// Example values reported per column and the rune length they are cut to.
+const (
+	maxExamples     = 3
+	maxExampleRunes = 40
+)
+
+// maxDuplicateCols bounds the leading columns compared pairwise for exact
+// duplicates.
+const maxDuplicateCols = 24
+
 // Profiler holds dependencies/limits for schema profiling.
 type Profiler struct {
 	Limits runtime.Limits
@@ -84,8 +108,12 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 		if colCount <= 0 {
 			return fmt.Errorf("empty range: no columns")
 		}
-		// Extract header names from first row of range
-		headers := make([]string, colCount)
+		// Read the top of the range to locate the header row(s)
+		scanRows := headerScanRows
+		if h := y2 - y1 + 1; scanRows > h {
+			scanRows = h
+		}
+		top := make([][]string, 0, scanRows)
 		rowsIter, rerr := f.Rows(out.Sheet)
 		if rerr != nil {
 			return rerr
@@ -95,17 +123,25 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 		rowIdx := 0
 		for rowsIter.Next() {
 			rowIdx++
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
+			if rowIdx < y1 {
+				continue
+			}
 			vals, cerr := rowsIter.Columns()
 			if cerr != nil {
 				return cerr
 			}
-			if rowIdx == y1 {
-				for i := 0; i < colCount; i++ {
-					absCol := x1 + i - 1 // zero-based
-					if absCol >= 0 && absCol < len(vals) {
-						headers[i] = strings.TrimSpace(vals[absCol])
-					}
+			row := make([]string, colCount)
+			for i := 0; i < colCount; i++ {
+				absCol := x1 + i - 1 // zero-based
+				if absCol >= 0 && absCol < len(vals) {
+					row[i] = strings.TrimSpace(vals[absCol])
 				}
+			}
+			top = append(top, row)
+			if len(top) >= scanRows {
 				break
 			}
 		}
@@ -113,6 +149,28 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 			return err
 		}
 
+		offset, nRows := 0, 1
+		if in.HeaderRows != nil {
+			nRows = *in.HeaderRows
+		} else {
+			offset, nRows = detectHeaderRow(top, colCount)
+			out.Meta.HeaderDetected = true
+		}
+		if offset+nRows > y2-y1+1 {
+			return mcperr.Errorf(mcperr.Validation, "range has too few rows for %d header rows", nRows)
+		}
+		headers := make([]string, colCount)
+		if nRows > 0 {
+			for len(top) < offset+nRows { // sheet ends before the range does
+				top = append(top, make([]string, colCount))
+			}
+			headers = combineHeaderRows(top[offset : offset+nRows])
+			out.Meta.HeaderRow = y1 + offset
+		}
+		out.Meta.HeaderRows = nRows
+		dataStart := y1 + offset + nRows
+		out.Meta.DataStartRow = dataStart
+
 		// Prepare samplers for each column
 		types := make([]typeCounter, colCount)
 		uniqs := make([]map[string]int, colCount) // count duplicates
@@ -122,6 +180,17 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 		for i := range uniqs {
 			uniqs[i] = make(map[string]int)
 		}
+		// Reservoir-sample distinct values so examples are not always the
+		// first rows; the fixed seed keeps repeated calls stable.
+		withExamples := in.Examples == nil || *in.Examples
+		examples := make([][]string, colCount)
+		rng := rand.New(rand.NewPCG(1, 2))
+		// Hash each leading column's sampled values in row order so columns
+		// holding identical sequences can be found without keeping the values.
+		seqs := make([]hash.Hash64, min(colCount, maxDuplicateCols))
+		for i := range seqs {
+			seqs[i] = fnv.New64a()
+		}
 
 		rowsIter2, rerr2 := f.Rows(out.Sheet)
 		if rerr2 != nil {
@@ -133,7 +202,10 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 		sampledRows := 0
 		for rowsIter2.Next() {
 			rowIdx++
-			if rowIdx <= y1 { // skip header row
+			if err := scanCanceled(ctx, rowIdx); err != nil {
+				return err
+			}
+			if rowIdx < dataStart { // skip header and title rows
 				continue
 			}
 			if rowIdx > y2 {
@@ -156,12 +228,23 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 				if absCol >= 0 && absCol < len(vals) {
 					cell = strings.TrimSpace(vals[absCol])
 				}
+				if i < len(seqs) {
+					seqs[i].Write([]byte(cell))
+					seqs[i].Write([]byte{0})
+				}
 				if cell == "" {
 					miss[i]++
 					continue
 				}
 				types[i].observe(cell)
 				uniqs[i][cell]++
+				if withExamples && uniqs[i][cell] == 1 {
+					if n := len(uniqs[i]); n <= maxExamples {
+						examples[i] = append(examples[i], cell)
+					} else if j := rng.IntN(n); j < maxExamples {
+						examples[i][j] = cell
+					}
+				}
 			}
 		}
 		if err := rowsIter2.Error(); err != nil {
@@ -170,7 +253,7 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 
 		out.Meta.SampledRows = sampledRows
 		out.Meta.MaxSample = maxSample
-		out.Meta.Truncated = (y1+sampledRows < y2)
+		out.Meta.Truncated = (dataStart-1+sampledRows < y2)
 
 		// Build column profiles with role inference and quality checks
 		profiles := make([]ColumnProfile, colCount)
@@ -179,6 +262,9 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 		for i := 0; i < colCount; i++ {
 			name := strings.TrimSpace(headers[i])
 			cp := ColumnProfile{Index: i + 1, Name: name}
+			if nRows == 0 {
+				cp.Name = fmt.Sprintf("$%d", i+1)
+			}
 			nonEmpty := sampledRows - miss[i]
 			if sampledRows > 0 {
 				cp.MissingPct = round2(100.0 * float64(miss[i]) / float64(sampledRows))
@@ -195,6 +281,12 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 			// Type inference
 			cp.Type = types[i].dominantType()
 			cp.Sampled = sampledRows
+			for _, ex := range examples[i] {
+				if r := []rune(ex); len(r) > maxExampleRunes {
+					ex = string(r[:maxExampleRunes-1]) + "…"
+				}
+				cp.Examples = append(cp.Examples, ex)
+			}
 
 			// Role inference rules
 			role := inferRole(name, types[i], cp.UniqueRatio, nonEmpty)
@@ -209,10 +301,36 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 
 			// Quality checks
 			cp.Flags, cp.Warnings = qualityChecks(name, types[i], uniqs[i], sampledRows)
+			if sampledRows > 1 && nonEmpty > 1 && uniqNonEmpty == 1 {
+				cp.Warnings = append(cp.Warnings, "constant")
+			}
 
 			profiles[i] = cp
 		}
 
+		// A column whose sampled values match an earlier column row for row
+		// is likely a copy; it is marked as a duplicate of the first one.
+		var dupMeasures []string
+		if sampledRows > 1 {
+			first := map[uint64]int{}
+			for i, h := range seqs {
+				if sampledRows == miss[i] {
+					continue
+				}
+				sum := h.Sum64()
+				j, ok := first[sum]
+				if !ok {
+					first[sum] = i
+					continue
+				}
+				orig := columnNames([]int{j}, headers)[0]
+				profiles[i].Warnings = append(profiles[i].Warnings, "duplicate_of: "+orig)
+				if r := profiles[i].Role; r == "measure" || r == "target" {
+					dupMeasures = append(dupMeasures, fmt.Sprintf("%s and %s", columnNames([]int{i}, headers)[0], orig))
+				}
+			}
+		}
+
 		// Clarifying questions for ambiguity
 		var questions []string
 		if len(candidateIDs) > 1 {
@@ -233,6 +351,9 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 		if numericCols == 0 {
 			questions = append(questions, "Which column is the primary KPI/measure to analyze?")
 		}
+		for _, pair := range dupMeasures {
+			questions = append(questions, fmt.Sprintf("Columns %s hold identical sampled values. Is one a copy-paste error, and which should be analyzed as the measure?", pair))
+		}
 
 		// Stable output ordering: preserve input order but ensure any target/id/time highlighted first in summaries
 		out.Columns = profiles
@@ -247,19 +368,102 @@ func (p *Profiler) ProfileSchema(ctx context.Context, in ProfileSchemaInput) (Pr
 
 // Helpers and inference utilities
 
+// headerScanRows bounds how many rows at the top of a range are considered
+// when detecting the header row.
+const headerScanRows = 5
+
+// detectHeaderRow picks the header among the first rows of a range. Each row
+// is scored by headerConfidence, discounted for blank and value-like (numeric,
+// percent, date) cells; the chosen row is the first header-like row that
+// stands out from the rows below it, so sparse title rows above a header are
+// skipped and a units row under the header is not preferred over it. When no
+// row stands out, the first row is kept as the header if it looks like one on
+// its own, otherwise the range is treated as having no header. It returns the
+// header's offset within the range and the number of header rows (0 or 1).
+func detectHeaderRow(top [][]string, colCount int) (int, int) {
+	if len(top) == 0 {
+		return 0, 0
+	}
+	scores := make([]float64, len(top))
+	for i, row := range top {
+		scores[i] = headerRowScore(row, colCount)
+	}
+	for i := 0; i < len(top)-1; i++ {
+		if scores[i] < 0.5 {
+			continue
+		}
+		var below float64
+		for _, sc := range scores[i+1:] {
+			below += sc
+		}
+		if scores[i]-below/float64(len(scores)-i-1) >= 0.2 {
+			return i, 1
+		}
+	}
+	if scores[0] >= 0.5 {
+		return 0, 1
+	}
+	return 0, 0
+}
+
+// headerRowScore rates how header-like a row is: headerConfidence scaled by
+// the share of filled cells and the share of those that are plain text.
+func headerRowScore(row []string, colCount int) float64 {
+	var tc typeCounter
+	nonEmpty := 0
+	for _, v := range row {
+		if v == "" {
+			continue
+		}
+		nonEmpty++
+		tc.observe(v)
+	}
+	if nonEmpty == 0 || colCount == 0 {
+		return 0
+	}
+	textual := float64(tc.textCount) / float64(nonEmpty)
+	filled := float64(nonEmpty) / float64(colCount)
+	return headerConfidence(row) * textual * filled
+}
+
+// combineHeaderRows builds column names from one or two header rows. With two
+// rows, blanks in the top row inherit the value to their left (merged group
+// headers) and the rows are joined as "top / bottom".
+func combineHeaderRows(rows [][]string) []string {
+	names := append([]string(nil), rows[0]...)
+	if len(rows) < 2 {
+		return names
+	}
+	group := ""
+	for i, bottom := range rows[1] {
+		if names[i] != "" {
+			group = names[i]
+		}
+		switch {
+		case group == "":
+			names[i] = bottom
+		case bottom == "":
+			names[i] = group
+		default:
+			names[i] = group + " / " + bottom
+		}
+	}
+	return names
+}
+
 // resolveRangeLocal parses an A1-style or defined name range relative to a sheet.
 // Returns x1,y1,x2,y2 and normalized textual range without sheet qualifier.
 func resolveRangeLocal(f *excelize.File, sheet, input string) (int, int, int, int, string, error) {
 	in := strings.TrimSpace(input)
 	if in == "" {
-		return 0, 0, 0, 0, "", fmt.Errorf("invalid range: empty")
+		return 0, 0, 0, 0, "", fmt.Errorf("%w: empty", mcperr.ErrInvalidRange)
 	}
 	if strings.Contains(in, "!") {
 		parts := strings.SplitN(in, "!", 2)
 		if len(parts) == 2 {
 			s := strings.Trim(parts[0], "'")
 			if s != "" && !strings.EqualFold(s, sheet) {
-				return 0, 0, 0, 0, "", fmt.Errorf("invalid range: sheet mismatch")
+				return 0, 0, 0, 0, "", fmt.Errorf("%w: sheet mismatch", mcperr.ErrInvalidRange)
 			}
 			in = parts[1]
 		}
@@ -267,12 +471,12 @@ func resolveRangeLocal(f *excelize.File, sheet, input string) (int, int, int, in
 	if strings.Contains(in, ":") {
 		parts := strings.Split(in, ":")
 		if len(parts) != 2 {
-			return 0, 0, 0, 0, "", fmt.Errorf("invalid range: %s", input)
+			return 0, 0, 0, 0, "", fmt.Errorf("%w: %s", mcperr.ErrInvalidRange, input)
 		}
 		x1, y1, err1 := excelize.CellNameToCoordinates(parts[0])
 		x2, y2, err2 := excelize.CellNameToCoordinates(parts[1])
 		if err1 != nil || err2 != nil {
-			return 0, 0, 0, 0, "", fmt.Errorf("invalid range coordinates")
+			return 0, 0, 0, 0, "", fmt.Errorf("%w coordinates", mcperr.ErrInvalidRange)
 		}
 		if x2 < x1 {
 			x1, x2 = x2, x1
@@ -321,7 +525,23 @@ func resolveRangeLocal(f *excelize.File, sheet, input string) (int, int, int, in
 			}
 		}
 	}
-	return 0, 0, 0, 0, "", fmt.Errorf("invalid range: %s", input)
+	return 0, 0, 0, 0, "", fmt.Errorf("%w: %s", mcperr.ErrInvalidRange, input)
+}
+
+// DateLayouts are the text layouts recognized as dates when profiling a
+// column; compute_statistics shares them so both agree on what a date is.
+var DateLayouts = []string{
+	time.RFC3339, "2006-01-02", "01/02/2006", "2006/01/02", "1/2/2006", "1/2/06", "2006-01-02 15:04:05",
+}
+
+// ParseDate parses s with the first matching layout in DateLayouts.
+func ParseDate(s string) (time.Time, bool) {
+	for _, layout := range DateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
 }
 
 // typeCounter tracks observed value categories for a column.
@@ -381,14 +601,9 @@ func (t *typeCounter) observe(s string) {
 		return
 	}
 	// date/time detection with a few common layouts
-	dateLayouts := []string{
-		time.RFC3339, "2006-01-02", "01/02/2006", "2006/01/02", "1/2/2006", "1/2/06", "2006-01-02 15:04:05",
-	}
-	for _, layout := range dateLayouts {
-		if _, err := time.Parse(layout, s); err == nil {
-			t.dateCount++
-			return
-		}
+	if _, ok := ParseDate(s); ok {
+		t.dateCount++
+		return
 	}
 	// fallback to text
 	t.textCount++
@@ -427,6 +642,22 @@ func (t *typeCounter) dominantType() string {
 	return typeName
 }
 
+// InferType returns the dominant category of the non-empty values (numeric,
+// percent, date, boolean, text, mixed, or unknown when all are empty) as
+// profile_schema reports it, and how many values were non-empty.
+func InferType(values []string) (string, int) {
+	var tc typeCounter
+	nonEmpty := 0
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		nonEmpty++
+		tc.observe(v)
+	}
+	return tc.dominantType(), nonEmpty
+}
+
 func inferRole(name string, t typeCounter, uniqueRatio float64, nonEmpty int) string {
 	low := strings.ToLower(strings.TrimSpace(name))
 	// name hints for time and id/target
@@ -3,6 +3,9 @@ package insights
 import (
 	"context"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -51,4 +54,127 @@ func TestProfileSchema_InferenceAndQuality(t *testing.T) {
 	require.Equal(t, "measure", out.Columns[3].Role)
 	// plan% detected as target due to name and percent type
 	require.Equal(t, "target", out.Columns[4].Role)
+
+	// examples: distinct values only, at most 3, drawn from the sample
+	require.Equal(t, []string{"X", "Y", "Z"}, sortedCopy(out.Columns[2].Examples))
+	require.Len(t, out.Columns[3].Examples, 3)
+	require.Subset(t, []string{"100", "200", "300", "400"}, out.Columns[3].Examples)
+
+	off := false
+	in.Examples = &off
+	out, err = p.ProfileSchema(context.Background(), in)
+	require.NoError(t, err)
+	for _, c := range out.Columns {
+		require.Empty(t, c.Examples)
+	}
+}
+
+func sortedCopy(v []string) []string {
+	c := append([]string(nil), v...)
+	sort.Strings(c)
+	return c
+}
+
+func TestProfileSchema_HeaderDetection(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	p := &Profiler{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Quarterly sales report"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]string{"region", "Q1", "", "Q2"}))
+	require.NoError(t, f.SetSheetRow(sh, "A3", &[]string{"", "units", "revenue", "units"}))
+	require.NoError(t, f.SetSheetRow(sh, "A4", &[]string{"East", "10", "100", "12"}))
+	require.NoError(t, f.SetSheetRow(sh, "A5", &[]string{"West", "20", "200", "18"}))
+	require.NoError(t, f.SetSheetRow(sh, "A6", &[]string{"North", "30", "300", "25"}))
+	path := filepath.Join(t.TempDir(), "headers.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	// Auto: the title row is skipped and row 2 becomes the header.
+	out, err := p.ProfileSchema(context.Background(), ProfileSchemaInput{Path: path, Sheet: sh, Range: "A1:D6"})
+	require.NoError(t, err)
+	require.True(t, out.Meta.HeaderDetected)
+	require.Equal(t, 2, out.Meta.HeaderRow)
+	require.Equal(t, 1, out.Meta.HeaderRows)
+	require.Equal(t, "region", out.Columns[0].Name)
+
+	// Two header rows joined, with the merged group carried right.
+	two := 2
+	out, err = p.ProfileSchema(context.Background(), ProfileSchemaInput{Path: path, Sheet: sh, Range: "A2:D6", HeaderRows: &two})
+	require.NoError(t, err)
+	require.False(t, out.Meta.HeaderDetected)
+	require.Equal(t, 2, out.Meta.HeaderRow)
+	require.Equal(t, 4, out.Meta.DataStartRow)
+	require.Equal(t, 3, out.Meta.SampledRows)
+	require.Equal(t, []string{"region", "Q1 / units", "Q1 / revenue", "Q2 / units"}, []string{out.Columns[0].Name, out.Columns[1].Name, out.Columns[2].Name, out.Columns[3].Name})
+	require.Equal(t, "measure", out.Columns[1].Role)
+
+	// A range starting at the data is detected as headerless.
+	out, err = p.ProfileSchema(context.Background(), ProfileSchemaInput{Path: path, Sheet: sh, Range: "A4:D6"})
+	require.NoError(t, err)
+	require.Equal(t, 0, out.Meta.HeaderRow)
+	require.Equal(t, 0, out.Meta.HeaderRows)
+	require.Equal(t, 4, out.Meta.DataStartRow)
+	require.Equal(t, 3, out.Meta.SampledRows)
+	require.Equal(t, "$1", out.Columns[0].Name)
+}
+
+func TestProfileSchema_ConstantAndDuplicateColumns(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	p := &Profiler{Limits: limits, Mgr: mgr}
+
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"region", "currency", "revenue", "revenue_copy", "cost", "notes", "label"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]string{"East", "USD", "100", "100", "40", "", "East"}))
+	require.NoError(t, f.SetSheetRow(sh, "A3", &[]string{"West", "USD", "200", "200", "40", "", "West"}))
+	require.NoError(t, f.SetSheetRow(sh, "A4", &[]string{"North", "USD", "300", "300", "55", "", "North"}))
+	path := filepath.Join(t.TempDir(), "dups.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	out, err := p.ProfileSchema(context.Background(), ProfileSchemaInput{Path: path, Sheet: sh, Range: "A1:G4"})
+	require.NoError(t, err)
+	warnings := map[string][]string{}
+	for _, c := range out.Columns {
+		warnings[c.Name] = c.Warnings
+	}
+	require.Equal(t, []string{"constant"}, warnings["currency"])
+	require.Equal(t, []string{"duplicate_of: revenue($3)"}, warnings["revenue_copy"])
+	require.Equal(t, []string{"duplicate_of: region($1)"}, warnings["label"])
+	// Empty columns are neither constant nor duplicates of each other.
+	require.Empty(t, warnings["notes"])
+	require.Empty(t, warnings["revenue"])
+	require.Empty(t, warnings["cost"])
+	// Only the duplicated measure raises a question.
+	var asked []string
+	for _, q := range out.Questions {
+		if strings.Contains(q, "identical sampled values") {
+			asked = append(asked, q)
+		}
+	}
+	require.Len(t, asked, 1)
+	require.Contains(t, asked[0], "revenue_copy($4) and revenue($3)")
+
+	// Columns past the first 24 are not compared.
+	f = excelize.NewFile()
+	row1, row2, row3 := make([]any, 26), make([]any, 26), make([]any, 26)
+	for i := range row1 {
+		row1[i], row2[i], row3[i] = "h"+strconv.Itoa(i+1), i, i*2+1
+	}
+	row2[25], row3[25] = row2[24], row3[24]
+	row2[23], row3[23] = row2[0], row3[0]
+	for r, row := range [][]any{row1, row2, row3} {
+		require.NoError(t, f.SetSheetRow(sh, "A"+strconv.Itoa(r+1), &row))
+	}
+	path = filepath.Join(t.TempDir(), "wide.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	out, err = p.ProfileSchema(context.Background(), ProfileSchemaInput{Path: path, Sheet: sh, Range: "A1:Z3"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"duplicate_of: h1($1)"}, out.Columns[23].Warnings)
+	require.Empty(t, out.Columns[25].Warnings)
 }
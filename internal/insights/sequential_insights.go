@@ -3,9 +3,11 @@ package insights
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 )
 
 // Input schema for the generalized sequential_insights tool (reference-inspired).
@@ -16,14 +18,21 @@ type SequentialInsightsInput struct {
 	ThoughtNumber     int    `json:"thought_number" validate:"min=1" jsonschema_description:"Current thought number (>=1)"`
 	TotalThoughts     int    `json:"total_thoughts" validate:"min=1" jsonschema_description:"Estimated total thoughts needed (>=1)"`
 
-	IsRevision        bool   `json:"is_revision,omitempty"`
-	RevisesThought    int    `json:"revises_thought,omitempty"`
-	BranchFromThought int    `json:"branch_from_thought,omitempty"`
-	BranchID          string `json:"branch_id,omitempty"`
-	NeedsMoreThoughts bool   `json:"needs_more_thoughts,omitempty"`
+	IsRevision        bool   `json:"is_revision,omitempty" jsonschema_description:"Whether this thought corrects an earlier one"`
+	RevisesThought    int    `json:"revises_thought,omitempty" validate:"omitempty,min=1" jsonschema_description:"Thought number being revised (with is_revision)"`
+	BranchFromThought int    `json:"branch_from_thought,omitempty" validate:"omitempty,min=1" jsonschema_description:"Thought number this branch starts from"`
+	BranchID          string `json:"branch_id,omitempty" jsonschema_description:"Identifier of the branch this thought belongs to"`
+	NeedsMoreThoughts bool   `json:"needs_more_thoughts,omitempty" jsonschema_description:"The estimated end was reached but more thoughts are needed"`
+
+	// Objective and Hints are optional context kept with the session.
+	Objective string   `json:"objective,omitempty" validate:"omitempty,max=500" jsonschema_description:"Overall analysis goal; stored on the session and echoed until replaced by a later objective"`
+	Hints     []string `json:"hints,omitempty" validate:"omitempty,max=10,dive,max=200" jsonschema_description:"Short notes kept with this thought (e.g. constraints or columns to focus on)"`
+
+	// Observations record domain tool calls made since the previous thought.
+	Observations []Observation `json:"observations,omitempty" validate:"omitempty,max=20,dive" jsonschema_description:"Outcomes of tool calls made since the previous thought ({tool, summary}); the last 5 are stored with this thought (summaries truncated to 280 characters) so later steps can cite them"`
 
 	// Sessions & flags
-	SessionID          string `json:"session_id,omitempty" jsonschema_description:"Optional session identifier to resume in-memory planning state"`
+	SessionID          string `json:"session_id,omitempty" jsonschema_description:"Optional session identifier to resume planning state; sessions survive server restarts when MCPXCEL_SESSION_DIR is set"`
 	ResetSession       bool   `json:"reset_session,omitempty" jsonschema_description:"When true, reset the session referenced by session_id"`
 	ShowAvailableTools bool   `json:"show_available_tools,omitempty" jsonschema_description:"When true, include the available tool catalog in text output"`
 }
@@ -42,6 +51,9 @@ type PlannerMeta struct {
 	Limits       runtime.Limits `json:"limits"`
 	PlanningOnly bool           `json:"planning_only"`
 	Truncated    bool           `json:"truncated"`
+	// ResumedFromDisk reports that session_id was not in memory and its
+	// history was reloaded from the session directory.
+	ResumedFromDisk bool `json:"resumed_from_disk,omitempty"`
 }
 
 // Output schema for the generalized sequential_insights tool.
@@ -51,15 +63,28 @@ type SequentialInsightsOutput struct {
 	TotalThoughts     int    `json:"total_thoughts"`
 	NextThoughtNeeded bool   `json:"next_thought_needed"`
 	SessionID         string `json:"session_id"`
+	Objective         string `json:"objective,omitempty" jsonschema_description:"Current objective of the session"`
 
 	// Minimal state summary
-	Branches             []string      `json:"branches,omitempty"`
-	ThoughtHistoryLength int           `json:"thought_history_length"`
-	InsightCards         []InsightCard `json:"insight_cards,omitempty"`
-	Meta                 PlannerMeta   `json:"meta"`
+	Branches             []string            `json:"branches,omitempty" jsonschema_description:"Branch ids recorded in the session, sorted"`
+	ThoughtHistoryLength int                 `json:"thought_history_length"`
+	InsightCards         []InsightCard       `json:"insight_cards,omitempty"`
+	RecentObservations   []RecentObservation `json:"recent_observations,omitempty" jsonschema_description:"Latest tool-call observations in this session, oldest first"`
+	Meta                 PlannerMeta         `json:"meta"`
 }
 
-// Planner encapsulates runtime limits and the in-memory session store.
+// RecentObservation is a stored observation with the thought it was
+// recorded with.
+type RecentObservation struct {
+	ThoughtNumber int    `json:"thought_number"`
+	Tool          string `json:"tool"`
+	Summary       string `json:"summary"`
+}
+
+// recentObservationLimit bounds RecentObservations in the output.
+const recentObservationLimit = 5
+
+// Planner encapsulates runtime limits and the session store.
 type Planner struct {
 	Limits   runtime.Limits
 	Sessions *SessionStore
@@ -73,7 +98,7 @@ func (p *Planner) Plan(ctx context.Context, in SequentialInsightsInput) (Sequent
 
 	// Basic validation (mirrors reference expectations)
 	if strings.TrimSpace(in.Thought) == "" || in.ThoughtNumber <= 0 || in.TotalThoughts <= 0 {
-		return out, fmt.Errorf("VALIDATION: thought, thought_number>=1, total_thoughts>=1 are required")
+		return out, mcperr.Errorf(mcperr.Validation, "thought, thought_number>=1, total_thoughts>=1 are required")
 	}
 
 	// Resolve session (create or resume)
@@ -85,7 +110,7 @@ func (p *Planner) Plan(ctx context.Context, in SequentialInsightsInput) (Sequent
 	if strings.TrimSpace(in.SessionID) != "" {
 		if in.ResetSession {
 			sess = p.Sessions.Reset(in.SessionID)
-		} else if sess, ok = p.Sessions.Get(in.SessionID); !ok {
+		} else if sess, out.Meta.ResumedFromDisk, ok = p.Sessions.Resume(in.SessionID); !ok {
 			// Resume failed; create a fresh session with the requested ID
 			sess = p.Sessions.Reset(in.SessionID)
 		}
@@ -110,13 +135,18 @@ func (p *Planner) Plan(ctx context.Context, in SequentialInsightsInput) (Sequent
 		BranchFromThought: in.BranchFromThought,
 		BranchID:          in.BranchID,
 		NeedsMoreThoughts: in.NeedsMoreThoughts,
+		Objective:         strings.TrimSpace(in.Objective),
+		Hints:             in.Hints,
+		Observations:      in.Observations,
 	})
 
 	// Build branches list
+	snap, _ := p.Sessions.Snapshot(sess.ID)
 	var branches []string
-	for k := range sess.Branches {
+	for k := range snap.Branches {
 		branches = append(branches, k)
 	}
+	sort.Strings(branches)
 
 	// Always include a tiny planning card with stronger interleaving cues.
 	out.InsightCards = append(out.InsightCards, InsightCard{
@@ -129,7 +159,25 @@ func (p *Planner) Plan(ctx context.Context, in SequentialInsightsInput) (Sequent
 	out.TotalThoughts = total
 	out.NextThoughtNeeded = in.NextThoughtNeeded
 	out.SessionID = sess.ID
-	out.ThoughtHistoryLength = len(sess.Thoughts)
+	out.Objective = snap.Objective
+	out.ThoughtHistoryLength = len(snap.Thoughts)
 	out.Branches = branches
+	out.RecentObservations = recentObservations(snap.Thoughts, recentObservationLimit)
 	return out, nil
 }
+
+// recentObservations returns the last k observations across thoughts,
+// oldest first.
+func recentObservations(thoughts []Thought, k int) []RecentObservation {
+	var out []RecentObservation
+	for i := len(thoughts) - 1; i >= 0 && len(out) < k; i-- {
+		obs := thoughts[i].Observations
+		for j := len(obs) - 1; j >= 0 && len(out) < k; j-- {
+			out = append(out, RecentObservation{ThoughtNumber: thoughts[i].ThoughtNumber, Tool: obs[j].Tool, Summary: obs[j].Summary})
+		}
+	}
+	for l, r := 0, len(out)-1; l < r; l, r = l+1, r-1 {
+		out[l], out[r] = out[r], out[l]
+	}
+	return out
+}
@@ -2,7 +2,11 @@ package insights
 
 import (
 	"context"
+	"os"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/require"
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
@@ -74,3 +78,137 @@ func TestPlanner_BranchRecording(t *testing.T) {
 	}
 	require.True(t, found, "expected branch id A recorded")
 }
+
+func TestPlanner_SessionPersistsAcrossStores(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	dir := t.TempDir()
+	store := NewSessionStore(10)
+	require.NoError(t, store.EnablePersistence(dir, 10, time.Hour))
+	p := &Planner{Limits: limits, Sessions: store}
+
+	out, err := p.Plan(context.Background(), SequentialInsightsInput{Thought: "Profile the sheet", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true, SessionID: "abc"})
+	require.NoError(t, err)
+	require.False(t, out.Meta.ResumedFromDisk)
+	_, err = p.Plan(context.Background(), SequentialInsightsInput{Thought: "Branch", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, SessionID: "abc", BranchFromThought: 1, BranchID: "alt"})
+	require.NoError(t, err)
+
+	// A fresh store over the same directory stands in for a restarted server.
+	restarted := NewSessionStore(10)
+	require.NoError(t, restarted.EnablePersistence(dir, 10, time.Hour))
+	p = &Planner{Limits: limits, Sessions: restarted}
+	out, err = p.Plan(context.Background(), SequentialInsightsInput{Thought: "Continue", ThoughtNumber: 3, TotalThoughts: 3, SessionID: "abc"})
+	require.NoError(t, err)
+	require.True(t, out.Meta.ResumedFromDisk)
+	require.Equal(t, 3, out.ThoughtHistoryLength)
+	require.Equal(t, []string{"alt"}, out.Branches)
+
+	out, err = p.Plan(context.Background(), SequentialInsightsInput{Thought: "Again", ThoughtNumber: 4, TotalThoughts: 4, SessionID: "abc"})
+	require.NoError(t, err)
+	require.False(t, out.Meta.ResumedFromDisk)
+}
+
+func TestSessionStore_SweepPrunesOldAndExcess(t *testing.T) {
+	dir := t.TempDir()
+	store := NewSessionStore(10)
+	require.NoError(t, store.EnablePersistence(dir, 2, time.Hour))
+	for _, id := range []string{"a", "b", "c"} {
+		store.AppendThought(store.Reset(id), Thought{Thought: id, ThoughtNumber: 1, TotalThoughts: 1})
+	}
+	old := store.sessionPath(dir, "a")
+	stale := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(old, stale, stale))
+
+	store.sweep(time.Now().Add(2 * sweepInterval))
+	_, err := os.Stat(old)
+	require.True(t, os.IsNotExist(err))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
+func TestPlanner_Observations(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	p := &Planner{Limits: limits, Sessions: NewSessionStore(10)}
+
+	out, err := p.Plan(context.Background(), SequentialInsightsInput{Thought: "Find the data", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true})
+	require.NoError(t, err)
+	require.Empty(t, out.RecentObservations)
+
+	long := strings.Repeat("x", 400)
+	obs := []Observation{{Tool: "detect_tables", Summary: "candidates=1 A1:D20"}}
+	for i := 0; i < 6; i++ {
+		obs = append(obs, Observation{Tool: "read_range", Summary: long})
+	}
+	out, err = p.Plan(context.Background(), SequentialInsightsInput{Thought: "Read it", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, SessionID: out.SessionID, Observations: obs})
+	require.NoError(t, err)
+	// Only the last 5 observations per thought are kept, each truncated.
+	require.Len(t, out.RecentObservations, 5)
+	for _, o := range out.RecentObservations {
+		require.Equal(t, 2, o.ThoughtNumber)
+		require.Equal(t, "read_range", o.Tool)
+		require.Equal(t, maxObservationRunes, utf8.RuneCountInString(o.Summary))
+	}
+
+	out, err = p.Plan(context.Background(), SequentialInsightsInput{Thought: "Profile", ThoughtNumber: 3, TotalThoughts: 3, SessionID: out.SessionID, Observations: []Observation{{Tool: "profile_schema", Summary: "cols=4"}}})
+	require.NoError(t, err)
+	require.Len(t, out.RecentObservations, 5)
+	last := out.RecentObservations[4]
+	require.Equal(t, RecentObservation{ThoughtNumber: 3, Tool: "profile_schema", Summary: "cols=4"}, last)
+}
+
+func TestPlanner_ObjectiveAndHints(t *testing.T) {
+	dir := t.TempDir()
+	store := NewSessionStore(10)
+	require.NoError(t, store.EnablePersistence(dir, 10, time.Hour))
+	p := &Planner{Limits: runtime.NewLimits(8, 8), Sessions: store}
+
+	out, err := p.Plan(context.Background(), SequentialInsightsInput{Thought: "Start", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true, Objective: " Explain churn ", Hints: []string{"use Region", " "}})
+	require.NoError(t, err)
+	require.Equal(t, "Explain churn", out.Objective)
+
+	// Later thoughts keep the objective until one replaces it; branches sort.
+	for i, b := range []string{"b", "a"} {
+		out, err = p.Plan(context.Background(), SequentialInsightsInput{Thought: "Branch", ThoughtNumber: i + 2, TotalThoughts: 3, SessionID: out.SessionID, BranchFromThought: 1, BranchID: b})
+		require.NoError(t, err)
+	}
+	require.Equal(t, "Explain churn", out.Objective)
+	require.Equal(t, []string{"a", "b"}, out.Branches)
+
+	restarted := NewSessionStore(10)
+	require.NoError(t, restarted.EnablePersistence(dir, 10, time.Hour))
+	reloaded, ok := restarted.Snapshot(out.SessionID)
+	require.True(t, ok)
+	require.Equal(t, "Explain churn", reloaded.Objective)
+	require.Equal(t, []string{"use Region"}, reloaded.Thoughts[0].Hints)
+}
+
+func TestSessionStore_ListAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	disk := NewSessionStore(10)
+	require.NoError(t, disk.EnablePersistence(dir, 10, time.Hour))
+	disk.AppendThought(disk.Reset("saved"), Thought{Thought: "on disk", ThoughtNumber: 1, TotalThoughts: 1})
+
+	store := NewSessionStore(10)
+	require.NoError(t, store.EnablePersistence(dir, 10, time.Hour))
+	live := store.Reset("live")
+	store.AppendThought(live, Thought{Thought: "one", ThoughtNumber: 1, TotalThoughts: 2})
+	store.AppendThought(live, Thought{Thought: "two", ThoughtNumber: 2, TotalThoughts: 2})
+
+	infos := store.List()
+	require.Len(t, infos, 2)
+	require.Equal(t, "live", infos[0].ID)
+	require.True(t, infos[0].InMemory)
+	require.Equal(t, 2, infos[0].Thoughts)
+	require.Equal(t, "saved", infos[1].ID)
+	require.False(t, infos[1].InMemory)
+
+	snap, ok := store.Snapshot("saved")
+	require.True(t, ok)
+	require.Equal(t, "on disk", snap.Thoughts[0].Thought)
+
+	require.True(t, store.Delete("saved"))
+	require.False(t, store.Delete("saved"))
+	_, ok = store.Get("saved")
+	require.False(t, ok)
+	require.Len(t, store.List(), 1)
+}
@@ -2,41 +2,93 @@ package insights
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // Thought captures a single planning step submitted by the client.
 type Thought struct {
-	Thought           string
-	ThoughtNumber     int
-	TotalThoughts     int
-	NextThoughtNeeded bool
+	Thought           string `json:"thought"`
+	ThoughtNumber     int    `json:"thought_number"`
+	TotalThoughts     int    `json:"total_thoughts"`
+	NextThoughtNeeded bool   `json:"next_thought_needed"`
 
-	IsRevision        bool
-	RevisesThought    int
-	BranchFromThought int
-	BranchID          string
-	NeedsMoreThoughts bool
+	IsRevision        bool   `json:"is_revision,omitempty"`
+	RevisesThought    int    `json:"revises_thought,omitempty"`
+	BranchFromThought int    `json:"branch_from_thought,omitempty"`
+	BranchID          string `json:"branch_id,omitempty"`
+	NeedsMoreThoughts bool   `json:"needs_more_thoughts,omitempty"`
+
+	// Objective replaces the session objective when set.
+	Objective string   `json:"objective,omitempty"`
+	Hints     []string `json:"hints,omitempty"`
+
+	Observations []Observation `json:"observations,omitempty"`
+}
+
+// Observation records the outcome of a domain tool call made since the
+// previous thought.
+type Observation struct {
+	Tool    string `json:"tool" validate:"required" jsonschema_description:"Name of the tool that was called"`
+	Summary string `json:"summary" validate:"required" jsonschema_description:"Short summary of what the call returned"`
 }
 
+// Bounds on observations and hints stored with each thought.
+const (
+	MaxObservationsPerThought = 5
+	maxObservationToolRunes   = 64
+	maxObservationRunes       = 280
+	maxHintsPerThought        = 10
+	maxHintRunes              = 200
+	maxObjectiveRunes         = 500
+)
+
 // Session holds a short history of thoughts and optional branches.
 type Session struct {
-	ID        string
-	Thoughts  []Thought
-	Branches  map[string][]Thought
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID string `json:"id"`
+	// Objective is the latest objective submitted with a thought.
+	Objective string               `json:"objective,omitempty"`
+	Thoughts  []Thought            `json:"thoughts"`
+	Branches  map[string][]Thought `json:"branches,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
 }
 
-// SessionStore is an in-memory store for sessions. It is not persisted and
-// is intended for short-lived use. It is safe for concurrent access.
+// Defaults for session persistence when the environment leaves them unset.
+const (
+	DefaultMaxPersistedSessions = 200
+	DefaultSessionMaxAge        = 7 * 24 * time.Hour
+)
+
+// sweepInterval throttles how often the session directory is pruned.
+const sweepInterval = time.Minute
+
+// SessionStore keeps sessions in memory and is safe for concurrent access.
+// With persistence enabled it also writes each session to a JSON file under a
+// directory on every change and loads sessions missing from memory on Get,
+// so session ids survive server restarts.
 type SessionStore struct {
 	mu       sync.RWMutex
 	sessions map[string]*Session
 	maxKeep  int           // max thoughts to keep per session
 	ttl      time.Duration // future: optional TTL management
+
+	// Persistence; dir is empty when disabled.
+	dir         string
+	maxSessions int
+	maxAge      time.Duration
+	lastSweep   time.Time
+	onError     func(error)
 }
 
 func NewSessionStore(maxKeep int) *SessionStore {
@@ -50,6 +102,66 @@ func NewSessionStore(maxKeep int) *SessionStore {
 	}
 }
 
+// NewSessionStoreFromEnv returns a store that persists sessions under
+// MCPXCEL_SESSION_DIR when it is set, keeping at most MCPXCEL_SESSION_MAX
+// session files no older than MCPXCEL_SESSION_MAX_AGE (a Go duration).
+func NewSessionStoreFromEnv(maxKeep int) (*SessionStore, error) {
+	s := NewSessionStore(maxKeep)
+	dir := strings.TrimSpace(os.Getenv("MCPXCEL_SESSION_DIR"))
+	if dir == "" {
+		return s, nil
+	}
+	maxSessions := DefaultMaxPersistedSessions
+	if v := strings.TrimSpace(os.Getenv("MCPXCEL_SESSION_MAX")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid MCPXCEL_SESSION_MAX %q: want a positive integer", v)
+		}
+		maxSessions = n
+	}
+	maxAge := DefaultSessionMaxAge
+	if v := strings.TrimSpace(os.Getenv("MCPXCEL_SESSION_MAX_AGE")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid MCPXCEL_SESSION_MAX_AGE %q: want a positive duration such as 72h", v)
+		}
+		maxAge = d
+	}
+	if err := s.EnablePersistence(dir, maxSessions, maxAge); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// EnablePersistence stores sessions as JSON files under dir, creating it if
+// needed, and prunes files beyond maxSessions (oldest first) or older than
+// maxAge.
+func (s *SessionStore) EnablePersistence(dir string, maxSessions int, maxAge time.Duration) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("session dir %q: %w", dir, err)
+	}
+	s.mu.Lock()
+	s.dir, s.maxSessions, s.maxAge = dir, maxSessions, maxAge
+	s.mu.Unlock()
+	s.sweep(time.Now())
+	return nil
+}
+
+// Dir returns the persistence directory, or "" when sessions are memory-only.
+func (s *SessionStore) Dir() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dir
+}
+
+// SetErrorHandler installs a callback for sessions that could not be saved.
+// Persistence failures never fail the calling tool.
+func (s *SessionStore) SetErrorHandler(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = fn
+}
+
 func (s *SessionStore) NewSession() *Session {
 	id := randomID()
 	sess := &Session{ID: id, Thoughts: []Thought{}, Branches: map[string][]Thought{}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
@@ -60,24 +172,153 @@ func (s *SessionStore) NewSession() *Session {
 }
 
 func (s *SessionStore) Get(id string) (*Session, bool) {
+	sess, _, ok := s.Resume(id)
+	return sess, ok
+}
+
+// Resume returns the session with id, loading it from the persistence
+// directory when it is not in memory; fromDisk reports such a load.
+func (s *SessionStore) Resume(id string) (sess *Session, fromDisk bool, ok bool) {
 	s.mu.RLock()
-	sess, ok := s.sessions[id]
+	sess, ok = s.sessions[id]
+	dir := s.dir
 	s.mu.RUnlock()
-	return sess, ok
+	if ok || dir == "" {
+		return sess, false, ok
+	}
+	data, err := os.ReadFile(s.sessionPath(dir, id))
+	if err != nil {
+		return nil, false, false
+	}
+	var loaded Session
+	if err := json.Unmarshal(data, &loaded); err != nil || loaded.ID != id {
+		return nil, false, false
+	}
+	if loaded.Thoughts == nil {
+		loaded.Thoughts = []Thought{}
+	}
+	if loaded.Branches == nil {
+		loaded.Branches = map[string][]Thought{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, exists := s.sessions[id]; exists { // loaded concurrently
+		return cur, false, true
+	}
+	s.sessions[id] = &loaded
+	return &loaded, true, true
+}
+
+// Snapshot returns a copy of the session with id, loading it from disk like
+// Resume, that is safe to read while other calls append thoughts.
+func (s *SessionStore) Snapshot(id string) (Session, bool) {
+	sess, _, ok := s.Resume(id)
+	if !ok {
+		return Session{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp := *sess
+	cp.Thoughts = append([]Thought(nil), sess.Thoughts...)
+	cp.Branches = make(map[string][]Thought, len(sess.Branches))
+	for k, v := range sess.Branches {
+		cp.Branches[k] = append([]Thought(nil), v...)
+	}
+	return cp, true
+}
+
+// SessionInfo summarizes a session for listing.
+type SessionInfo struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Thoughts  int
+	Branches  int
+	InMemory  bool // false for sessions only in the persistence directory
+}
+
+// List returns all sessions in memory plus any persisted ones not yet
+// loaded, most recently updated first.
+func (s *SessionStore) List() []SessionInfo {
+	s.mu.RLock()
+	infos := make([]SessionInfo, 0, len(s.sessions))
+	seen := make(map[string]struct{}, len(s.sessions))
+	for id, sess := range s.sessions {
+		seen[id] = struct{}{}
+		infos = append(infos, SessionInfo{ID: id, CreatedAt: sess.CreatedAt, UpdatedAt: sess.UpdatedAt, Thoughts: len(sess.Thoughts), Branches: len(sess.Branches), InMemory: true})
+	}
+	dir := s.dir
+	s.mu.RUnlock()
+	if dir != "" {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			var sess Session
+			if json.Unmarshal(data, &sess) != nil || sess.ID == "" {
+				continue
+			}
+			if _, ok := seen[sess.ID]; ok {
+				continue
+			}
+			infos = append(infos, SessionInfo{ID: sess.ID, CreatedAt: sess.CreatedAt, UpdatedAt: sess.UpdatedAt, Thoughts: len(sess.Thoughts), Branches: len(sess.Branches)})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].UpdatedAt.Equal(infos[j].UpdatedAt) {
+			return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Delete removes the session from memory and from the persistence
+// directory. It reports whether the session existed in either.
+func (s *SessionStore) Delete(id string) bool {
+	s.mu.Lock()
+	_, found := s.sessions[id]
+	delete(s.sessions, id)
+	dir := s.dir
+	s.mu.Unlock()
+	if dir != "" {
+		if err := os.Remove(s.sessionPath(dir, id)); err == nil {
+			found = true
+		} else if !os.IsNotExist(err) {
+			s.reportError(fmt.Errorf("delete session: %w", err))
+		}
+	}
+	return found
 }
 
 func (s *SessionStore) Reset(id string) *Session {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	sess := &Session{ID: id, Thoughts: []Thought{}, Branches: map[string][]Thought{}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
 	s.sessions[id] = sess
+	data, dir := s.snapshotLocked(sess)
+	s.mu.Unlock()
+	s.persist(dir, id, data)
 	return sess
 }
 
+// AppendThought records t in sess, keeping the last maxKeep thoughts. Only
+// the last MaxObservationsPerThought observations are kept, each truncated,
+// and hints are bounded likewise. A non-empty objective replaces the
+// session's.
 func (s *SessionStore) AppendThought(sess *Session, t Thought) {
+	t.Observations = boundObservations(t.Observations)
+	t.Hints = boundHints(t.Hints)
+	t.Objective = truncateRunes(strings.TrimSpace(t.Objective), maxObjectiveRunes)
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	sess.UpdatedAt = time.Now()
+	if t.Objective != "" {
+		sess.Objective = t.Objective
+	}
 	sess.Thoughts = append(sess.Thoughts, t)
 	if len(sess.Thoughts) > s.maxKeep {
 		// keep only the last maxKeep thoughts
@@ -89,6 +330,142 @@ func (s *SessionStore) AppendThought(sess *Session, t Thought) {
 		}
 		sess.Branches[t.BranchID] = append(sess.Branches[t.BranchID], t)
 	}
+	data, dir := s.snapshotLocked(sess)
+	s.mu.Unlock()
+	s.persist(dir, sess.ID, data)
+}
+
+func boundObservations(obs []Observation) []Observation {
+	if len(obs) == 0 {
+		return nil
+	}
+	if len(obs) > MaxObservationsPerThought {
+		obs = obs[len(obs)-MaxObservationsPerThought:]
+	}
+	out := make([]Observation, len(obs))
+	for i, o := range obs {
+		out[i] = Observation{Tool: truncateRunes(strings.TrimSpace(o.Tool), maxObservationToolRunes), Summary: truncateRunes(strings.TrimSpace(o.Summary), maxObservationRunes)}
+	}
+	return out
+}
+
+func boundHints(hints []string) []string {
+	var out []string
+	for _, h := range hints {
+		if h = strings.TrimSpace(h); h != "" && len(out) < maxHintsPerThought {
+			out = append(out, truncateRunes(h, maxHintRunes))
+		}
+	}
+	return out
+}
+
+// truncateRunes cuts s to at most n runes, ending in an ellipsis when cut.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// snapshotLocked encodes sess for persistence while s.mu is held; it returns
+// nil data when persistence is disabled.
+func (s *SessionStore) snapshotLocked(sess *Session) ([]byte, string) {
+	if s.dir == "" {
+		return nil, ""
+	}
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return nil, ""
+	}
+	return data, s.dir
+}
+
+// persist writes a session file atomically (temp file + rename) and prunes
+// the directory at most once per sweepInterval.
+func (s *SessionStore) persist(dir, id string, data []byte) {
+	if dir == "" || data == nil {
+		return
+	}
+	path := s.sessionPath(dir, id)
+	tmp, err := os.CreateTemp(dir, ".session-*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		s.reportError(fmt.Errorf("save session: %w", err))
+	}
+	s.sweep(time.Now())
+}
+
+// sweep removes session files older than maxAge, then the oldest files
+// beyond maxSessions. Sessions already in memory are unaffected.
+func (s *SessionStore) sweep(now time.Time) {
+	s.mu.Lock()
+	if s.dir == "" || now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	dir, maxSessions, maxAge := s.dir, s.maxSessions, s.maxAge
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.reportError(fmt.Errorf("sweep sessions: %w", err))
+		return
+	}
+	type file struct {
+		path string
+		mod  time.Time
+	}
+	var kept []file
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if maxAge > 0 && now.Sub(info.ModTime()) > maxAge {
+			_ = os.Remove(path)
+			continue
+		}
+		kept = append(kept, file{path: path, mod: info.ModTime()})
+	}
+	if maxSessions <= 0 || len(kept) <= maxSessions {
+		return
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].mod.After(kept[j].mod) })
+	for _, f := range kept[maxSessions:] {
+		_ = os.Remove(f.path)
+	}
+}
+
+func (s *SessionStore) reportError(err error) {
+	s.mu.RLock()
+	fn := s.onError
+	s.mu.RUnlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// sessionPath maps a client-chosen session id to a file name that is safe on
+// any filesystem.
+func (s *SessionStore) sessionPath(dir, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".json")
 }
 
 func randomID() string {
@@ -0,0 +1,150 @@
+package insights
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported period granularities, finest first.
+var granularities = []string{"day", "week", "month", "quarter", "year"}
+
+// parsePeriodTime parses a period cell as a date using the known string
+// layouts, falling back to an Excel serial date number.
+func parsePeriodTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, ok := tryParseTime(s); ok {
+		return t, true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 1 || v > 2958465 { // 9999-12-31
+		return time.Time{}, false
+	}
+	t, err := excelize.ExcelDateToTime(v, false)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// bucketLabel formats t as the sortable label of its bucket: 2024-03-15,
+// 2024-W11 (ISO week), 2024-03, 2024-Q1, or 2024.
+func bucketLabel(t time.Time, granularity string) string {
+	switch granularity {
+	case "day":
+		return t.Format("2006-01-02")
+	case "week":
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", y, w)
+	case "quarter":
+		return fmt.Sprintf("%04d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case "year":
+		return fmt.Sprintf("%04d", t.Year())
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// bucketIndex numbers t's bucket so consecutive buckets differ by one.
+func bucketIndex(t time.Time, granularity string) int {
+	switch granularity {
+	case "day":
+		return int(t.Unix() / 86400)
+	case "week":
+		// 1970-01-05 was a Monday, matching ISO week starts.
+		return int((t.Unix()/86400 - 4) / 7)
+	case "quarter":
+		return t.Year()*4 + (int(t.Month())-1)/3
+	case "year":
+		return t.Year()
+	default:
+		return t.Year()*12 + int(t.Month()) - 1
+	}
+}
+
+// detectGranularity picks a bucket size from the spacing of the distinct
+// dates: data spaced a year or a quarter apart keeps that granularity and
+// anything finer rolls up to months. When that leaves fewer than two buckets
+// it steps down to weeks, then days.
+func detectGranularity(times []time.Time) string {
+	days := map[int64]struct{}{}
+	for _, t := range times {
+		days[t.Unix()/86400] = struct{}{}
+	}
+	sorted := make([]int64, 0, len(days))
+	for d := range days {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	gaps := make([]int64, 0, len(sorted))
+	for i := 1; i < len(sorted); i++ {
+		gaps = append(gaps, sorted[i]-sorted[i-1])
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+
+	start := 2 // month
+	if len(gaps) > 0 {
+		switch median := gaps[len(gaps)/2]; {
+		case median >= 300:
+			start = 4
+		case median >= 80:
+			start = 3
+		}
+	}
+	for g := start; g > 0; g-- {
+		labels := map[string]struct{}{}
+		for _, t := range times {
+			labels[bucketLabel(t, granularities[g])] = struct{}{}
+		}
+		if len(labels) >= 2 {
+			return granularities[g]
+		}
+	}
+	return "day"
+}
+
+// bucket re-keys the scan's periods by time bucket, merging raw period values
+// that fall in the same bucket. granularity is one of granularities or "auto";
+// it returns the granularity applied. The "(empty)" period is kept as is; any
+// other value that is not a date is an error.
+func (s *periodScan) bucket(granularity string) (string, error) {
+	parsed := make(map[string]time.Time, len(s.periods))
+	times := make([]time.Time, 0, len(s.periods))
+	for p := range s.periods {
+		if p == "(empty)" {
+			continue
+		}
+		t, ok := parsePeriodTime(p)
+		if !ok {
+			return "", fmt.Errorf("period value %q is not a date; omit granularity to use raw period values", p)
+		}
+		parsed[p] = t
+		times = append(times, t)
+	}
+	if granularity == "auto" {
+		granularity = detectGranularity(times)
+	}
+	outAcc := map[string]map[string]float64{}
+	outPeriods := map[string]struct{}{}
+	for p, groups := range s.acc {
+		key := p
+		if t, ok := parsed[p]; ok {
+			key = bucketLabel(t, granularity)
+		}
+		outPeriods[key] = struct{}{}
+		m, ok := outAcc[key]
+		if !ok {
+			m = map[string]float64{}
+			outAcc[key] = m
+		}
+		for g, v := range groups {
+			m[g] += v
+		}
+	}
+	s.acc, s.periods = outAcc, outPeriods
+	return granularity, nil
+}
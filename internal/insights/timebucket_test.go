@@ -0,0 +1,104 @@
+package insights
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+// createDailyMixWorkbook writes one row per product per day from 2024-01-01
+// through 2024-03-31. A sells 1/day throughout; B sells 1/day in January and
+// February and 3/day in March.
+func createDailyMixWorkbook(t *testing.T, serial bool) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Daily"
+	f.SetSheetName("Sheet1", sh)
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Product", "Date", "Units"}))
+	row := 2
+	for d := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); d.Month() <= time.March && d.Year() == 2024; d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		if serial {
+			v, err := excelize.ExcelDateToTime(0, false)
+			require.NoError(t, err)
+			date = fmt.Sprint(int(d.Sub(v).Hours() / 24))
+		}
+		b := "1"
+		if d.Month() == time.March {
+			b = "3"
+		}
+		for _, r := range [][]string{{"A", date, "1"}, {"B", date, b}} {
+			cell, _ := excelize.CoordinatesToCellName(1, row)
+			require.NoError(t, f.SetSheetRow(sh, cell, &r))
+			row++
+		}
+	}
+	path := filepath.Join(t.TempDir(), "daily.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path, sh
+}
+
+func TestCompositionShift_DailyRolledUpToMonths(t *testing.T) {
+	c := &Composer{Limits: runtime.NewLimits(8, 8), Mgr: workbooks.NewManager(0, 0, nil, nil)}
+
+	for _, serial := range []bool{false, true} {
+		path, sh := createDailyMixWorkbook(t, serial)
+		for _, gran := range []string{"auto", "month"} {
+			out, err := c.CompositionShift(context.Background(), CompositionShiftInput{Path: path, Sheet: sh, Range: "A1:C183", DimIndex: 1, MeasureIndex: 3, TimeIndex: 2, Granularity: gran})
+			require.NoError(t, err, "serial=%v granularity=%s", serial, gran)
+			require.Equal(t, "month", out.Granularity)
+			require.Equal(t, "2024-02", out.PeriodBaseline)
+			require.Equal(t, "2024-03", out.PeriodCurrent)
+			for _, g := range out.Groups {
+				if g.Name == "B" {
+					require.Equal(t, 0.5, g.ShareBaseline)
+					require.Equal(t, 0.75, g.ShareCurrent)
+				}
+			}
+		}
+	}
+
+	// Without granularity each day is its own period.
+	path, sh := createDailyMixWorkbook(t, false)
+	out, err := c.CompositionShift(context.Background(), CompositionShiftInput{Path: path, Sheet: sh, Range: "A1:C183", DimIndex: 1, MeasureIndex: 3, TimeIndex: 2})
+	require.NoError(t, err)
+	require.Equal(t, "2024-03-31", out.PeriodCurrent)
+	require.Empty(t, out.Granularity)
+
+	_, err = c.CompositionShift(context.Background(), CompositionShiftInput{Path: path, Sheet: sh, Range: "A1:C183", DimIndex: 1, MeasureIndex: 3, TimeIndex: 2, Granularity: "quarter"})
+	require.ErrorContains(t, err, "not enough distinct periods")
+}
+
+func TestDetectGranularityAndLabels(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	var quarterly, yearly, sameMonth []time.Time
+	for i := 0; i < 6; i++ {
+		quarterly = append(quarterly, day(2023, time.January, 1).AddDate(0, 3*i, 0))
+		yearly = append(yearly, day(2018+i, time.June, 30))
+		sameMonth = append(sameMonth, day(2024, time.May, 1+i*5))
+	}
+	require.Equal(t, "quarter", detectGranularity(quarterly))
+	require.Equal(t, "year", detectGranularity(yearly))
+	require.Equal(t, "week", detectGranularity(sameMonth))
+	require.Equal(t, "day", detectGranularity([]time.Time{day(2024, time.May, 6), day(2024, time.May, 7)}))
+
+	ts := day(2024, time.December, 30)
+	require.Equal(t, "2024-12-30", bucketLabel(ts, "day"))
+	require.Equal(t, "2025-W01", bucketLabel(ts, "week"))
+	require.Equal(t, "2024-12", bucketLabel(ts, "month"))
+	require.Equal(t, "2024-Q4", bucketLabel(ts, "quarter"))
+	require.Equal(t, "2024", bucketLabel(ts, "year"))
+
+	got, ok := parsePeriodTime("45292") // Excel serial for 2024-01-01
+	require.True(t, ok)
+	require.Equal(t, day(2024, time.January, 1), got)
+}
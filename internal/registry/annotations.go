@@ -0,0 +1,43 @@
+package registry
+
+import "github.com/mark3labs/mcp-go/mcp"
+
+// readOnlyTool annotates a tool that never changes files on disk. Tools that
+// only record server-side state (planning sessions, change baselines) pass
+// idempotent=false.
+func readOnlyTool(idempotent bool) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		ReadOnlyHint:    mcp.ToBoolPtr(true),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(idempotent),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// writeTool annotates a tool that modifies workbooks or other files;
+// destructive marks tools that overwrite or remove existing data.
+func writeTool(destructive, idempotent bool) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(destructive),
+		IdempotentHint:  mcp.ToBoolPtr(idempotent),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// IsWriteTool reports whether t may modify files, judged by its read-only
+// annotation. Tools without an explicit readOnlyHint=true count as writes,
+// matching the MCP default.
+func IsWriteTool(t mcp.Tool) bool {
+	return t.Annotations.ReadOnlyHint == nil || !*t.Annotations.ReadOnlyHint
+}
+
+// IsWriteTool reports whether the registered tool name may modify files; ok
+// is false when no such tool is registered.
+func (r *Registry) IsWriteTool(name string) (write bool, ok bool) {
+	t, ok := r.Get(name)
+	if !ok {
+		return false, false
+	}
+	return IsWriteTool(t), true
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+)
+
+// SetAuditLogger installs the logger that records every write; nil disables
+// auditing.
+func (r *Registry) SetAuditLogger(l *audit.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditLog = l
+}
+
+// auditWrite records rec for the calling session. Write tools call it under
+// the workbook write lock just before saving so that, in strict mode, a write
+// that cannot be audited is never persisted.
+func (r *Registry) auditWrite(ctx context.Context, rec audit.Record) error {
+	r.mu.RLock()
+	l := r.auditLog
+	r.mu.RUnlock()
+	rec.SessionID = audit.SessionFromContext(ctx)
+	return l.Log(rec)
+}
+
+// discardUnaudited drops the handle after a strict audit failure or a failed
+// write-ahead backup so edits applied in memory but never saved are not
+// served to later calls. It returns err, amended as discardFailedWrite
+// describes when the handle has to be kept.
+func discardUnaudited(mgr *workbooks.Manager, id string, err error) error {
+	if errors.Is(err, audit.ErrAuditFailed) || errors.Is(err, workbooks.ErrBackupFailed) {
+		return discardFailedWrite(mgr, id, err)
+	}
+	return err
+}
+
+// discardFailedWrite drops the handle after a write failed with its edits
+// partly or fully applied in memory, so later calls reload the file. When
+// earlier writes deferred changes that are not yet on disk, discarding would
+// lose them too; the handle is kept instead and the returned error, under
+// err's code, says the failed call's edits will be saved with them.
+func discardFailedWrite(mgr *workbooks.Manager, id string, err error) error {
+	if derr := mgr.DiscardUnlessPending(id); !errors.Is(derr, workbooks.ErrPendingChanges) {
+		return err
+	}
+	code := mcperr.Classify(err)
+	if code == "" {
+		code = mcperr.WriteFailed
+	}
+	return mcperr.Errorf(code, "%v; this call's edits could not be discarded without losing earlier deferred changes, so they stay in memory and will be saved with them (flush_workbook saves now)", err)
+}
@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+// textTruncatedMarker ends text content cut short by a budgetedWriter.
+const textTruncatedMarker = "\n…output truncated (use structured content / cursor)"
+
+// budgetedWriter builds a tool's text content within a byte budget. Writes
+// past the budget are dropped, the kept text is cut on a rune boundary, and
+// String appends textTruncatedMarker, so the result never exceeds the
+// budget. It implements io.Writer for use with fmt.Fprintf.
+type budgetedWriter struct {
+	b         strings.Builder
+	limit     int // bytes including the marker; <= 0 means unlimited
+	truncated bool
+}
+
+// newBudgetedWriter returns a writer bounded by limits.TextBudgetBytes.
+func newBudgetedWriter(limits runtime.Limits) *budgetedWriter {
+	return &budgetedWriter{limit: limits.TextBudgetBytes()}
+}
+
+// Write appends p, or as much of it as fits; it never fails.
+func (w *budgetedWriter) Write(p []byte) (int, error) {
+	w.WriteString(string(p))
+	return len(p), nil
+}
+
+// WriteString appends s, or as much of it as fits, and reports whether all
+// of s was kept. Once a write is cut, later writes are dropped.
+func (w *budgetedWriter) WriteString(s string) bool {
+	if w.truncated {
+		return false
+	}
+	if w.limit <= 0 || w.b.Len()+len(s) <= w.limit {
+		w.b.WriteString(s)
+		return true
+	}
+	w.b.WriteString(truncateBytes(s, w.limit-w.b.Len()))
+	w.truncated = true
+	return false
+}
+
+// Line appends s, preceded by a newline unless it is the first text.
+func (w *budgetedWriter) Line(s string) bool {
+	if w.b.Len() > 0 {
+		return w.WriteString("\n" + s)
+	}
+	return w.WriteString(s)
+}
+
+// Truncated reports whether any write was cut.
+func (w *budgetedWriter) Truncated() bool { return w.truncated }
+
+// String returns the text, ending with textTruncatedMarker when cut.
+func (w *budgetedWriter) String() string {
+	if !w.truncated {
+		return w.b.String()
+	}
+	keep := w.limit - len(textTruncatedMarker)
+	if keep < 0 {
+		return truncateBytes(textTruncatedMarker, w.limit)
+	}
+	return truncateBytes(w.b.String(), keep) + textTruncatedMarker
+}
+
+// budgetText bounds an already built text by limits.TextBudgetBytes.
+func budgetText(limits runtime.Limits, text string) string {
+	w := newBudgetedWriter(limits)
+	w.WriteString(text)
+	return w.String()
+}
+
+// truncateBytes cuts s to at most n bytes on a rune boundary.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+func TestBudgetedWriter(t *testing.T) {
+	w := &budgetedWriter{limit: 80}
+	require.True(t, w.Line("first"))
+	require.True(t, w.Line("second"))
+	require.False(t, w.Truncated())
+	require.Equal(t, "first\nsecond", w.String())
+
+	// A cut lands on a rune boundary and later writes are dropped.
+	require.False(t, w.Line(strings.Repeat("é", 40)))
+	require.False(t, w.WriteString("dropped"))
+	require.True(t, w.Truncated())
+	got := w.String()
+	require.LessOrEqual(t, len(got), 80)
+	require.True(t, utf8.ValidString(got))
+	require.True(t, strings.HasPrefix(got, "first\nsecond"))
+	require.True(t, strings.HasSuffix(got, textTruncatedMarker))
+	require.NotContains(t, got, "dropped")
+
+	// A budget smaller than the marker still holds.
+	w = &budgetedWriter{limit: 10}
+	w.WriteString(strings.Repeat("x", 20))
+	require.LessOrEqual(t, len(w.String()), 10)
+
+	// No budget keeps everything.
+	w = &budgetedWriter{}
+	w.WriteString(strings.Repeat("x", 1<<16))
+	require.Len(t, w.String(), 1<<16)
+}
+
+func TestToolTextRespectsBudget(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	limits.TextBudgetPercent = 1
+	budget := limits.TextBudgetBytes()
+	require.Positive(t, budget)
+
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	RegisterFoundationTools(srv, reg, limits, mgr)
+	RegisterInsightsTools(srv, reg, limits, mgr)
+
+	// A wide fixture: long header names on many sheets, each row matching
+	// the search and filter.
+	f := excelize.NewFile()
+	header := make([]any, 12)
+	for c := range header {
+		header[c] = fmt.Sprintf("%scolumn header %02d", strings.Repeat("wide ", 12), c+1)
+	}
+	for s := 1; s <= 30; s++ {
+		sh := fmt.Sprintf("Sheet%d", s)
+		if s > 1 {
+			_, err := f.NewSheet(sh)
+			require.NoError(t, err)
+		}
+		require.NoError(t, f.SetSheetRow(sh, "A1", &header))
+		for r := 2; r <= 41; r++ {
+			row := make([]any, len(header))
+			row[0] = "match"
+			for c := 1; c < len(row); c++ {
+				row[c] = r * c
+				if c%2 == 1 {
+					row[c] = fmt.Sprintf("wide value r%d c%d", r, c+1)
+				}
+			}
+			require.NoError(t, f.SetSheetRow(sh, fmt.Sprintf("A%d", r), &row))
+		}
+	}
+	path := filepath.Join(t.TempDir(), "wide.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	call := func(name string, args map[string]any, out any) {
+		t.Helper()
+		res := callTool(t, srv, name, args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		text := resultText(t, res)
+		require.LessOrEqual(t, len(text), budget, name)
+		require.True(t, strings.HasSuffix(text, textTruncatedMarker), name)
+		decodeStructured(t, res, out)
+	}
+
+	// Structured content stays complete while the text is cut.
+	var ls ListStructureOutput
+	call("list_structure", map[string]any{"path": path}, &ls)
+	require.Len(t, ls.Sheets, 30)
+
+	var sd SearchDataOutput
+	call("search_data", map[string]any{"path": path, "sheet": "Sheet1", "query": "match", "max_results": 40}, &sd)
+	require.Len(t, sd.Results, 40)
+
+	var fd struct {
+		Results []struct {
+			Row int `json:"row"`
+		} `json:"results"`
+	}
+	call("filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": `$1 = "match"`, "max_rows": 40}, &fd)
+	require.Len(t, fd.Results, 40)
+
+	var dt insights.DetectTablesOutput
+	call("detect_tables", map[string]any{"path": path, "all_sheets": true, "max_tables": 10, "max_scan_cols": 12}, &dt)
+	require.Len(t, dt.Candidates, 10)
+
+	var ps insights.ProfileSchemaOutput
+	call("profile_schema", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:L41"}, &ps)
+	require.Len(t, ps.Columns, 12)
+}
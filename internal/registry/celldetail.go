@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// cellDetailFactor approximates how much larger a cell_detail object is than a
+// bare value; read_range divides its page size by it in detail mode.
+const cellDetailFactor = 3
+
+// cellDetail is the per-cell object emitted by read_range when cell_detail=true.
+type cellDetail struct {
+	V string `json:"v"`
+	F string `json:"f,omitempty"`
+	T string `json:"t"`
+}
+
+// excelErrors are the cached values Excel uses for formula errors.
+var excelErrors = map[string]struct{}{
+	"#NULL!": {}, "#DIV/0!": {}, "#VALUE!": {}, "#REF!": {}, "#NAME?": {},
+	"#NUM!": {}, "#N/A": {}, "#GETTING_DATA": {}, "#SPILL!": {}, "#CALC!": {},
+}
+
+// cellDetailReader builds cellDetail values for one sheet, caching whether each
+// style ID carries a date number format.
+type cellDetailReader struct {
+	f         *excelize.File
+	sheet     string
+	dateStyle map[int]bool
+}
+
+func newCellDetailReader(f *excelize.File, sheet string) *cellDetailReader {
+	return &cellDetailReader{f: f, sheet: sheet, dateStyle: make(map[int]bool)}
+}
+
+// read returns the formula and inferred type for cell alongside its display
+// value val. The type is one of empty, number, date, bool, error, or string.
+func (r *cellDetailReader) read(cell, val string) cellDetail {
+	d := cellDetail{V: val}
+	d.F, _ = r.f.GetCellFormula(r.sheet, cell)
+	d.T = r.inferType(cell, val)
+	return d
+}
+
+func (r *cellDetailReader) inferType(cell, val string) string {
+	if val == "" {
+		return "empty"
+	}
+	if _, isErr := excelErrors[val]; isErr {
+		return "error"
+	}
+	switch ct, _ := r.f.GetCellType(r.sheet, cell); ct {
+	case excelize.CellTypeBool:
+		return "bool"
+	case excelize.CellTypeDate:
+		return "date"
+	case excelize.CellTypeError:
+		return "error"
+	case excelize.CellTypeInlineString, excelize.CellTypeSharedString:
+		return "string"
+	}
+	// Numbers, and formula results cached as strings, are judged by the raw
+	// value; a numeric value under a date format is a date serial.
+	raw, _ := r.f.GetCellValue(r.sheet, cell, excelize.Options{RawCellValue: true})
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		if raw == "TRUE" || raw == "FALSE" {
+			return "bool"
+		}
+		return "string"
+	}
+	styleID, err := r.f.GetCellStyle(r.sheet, cell)
+	if err != nil || styleID == 0 {
+		return "number"
+	}
+	isDate, seen := r.dateStyle[styleID]
+	if !seen {
+		if st, serr := r.f.GetStyle(styleID); serr == nil {
+			custom := ""
+			if st.CustomNumFmt != nil {
+				custom = *st.CustomNumFmt
+			}
+			isDate = isDateNumFmt(st.NumFmt, custom)
+		}
+		r.dateStyle[styleID] = isDate
+	}
+	if isDate {
+		return "date"
+	}
+	return "number"
+}
+
+// isDateNumFmt reports whether a built-in number format ID or custom format
+// code renders dates or times.
+func isDateNumFmt(id int, custom string) bool {
+	if custom == "" {
+		return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+	}
+	// Drop quoted literals, escaped characters, and bracketed sections such
+	// as colors or locales before looking for date/time tokens.
+	var b strings.Builder
+	inQuote, inBracket := false, false
+	for i := 0; i < len(custom); i++ {
+		c := custom[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\':
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ydhms")
+}
@@ -0,0 +1,438 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/config"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// SheetFingerprint is a cheap shape signature for one sheet.
+type SheetFingerprint struct {
+	Dimension  string `json:"dimension"`
+	HeaderHash string `json:"headerHash"`
+	CellCount  int    `json:"cellCount"`
+	// Partial reports that the used-range scan stopped at the cell cap, so
+	// Dimension and CellCount cover only the rows scanned.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// WorkbookFingerprint captures file-level and per-sheet signatures at a point
+// in time. Read tools record only the file-level state and the cached handle
+// it was read from, leaving Sheets nil; what_changed fingerprints sheets.
+type WorkbookFingerprint struct {
+	ModTime int64                       `json:"modTime"`
+	Size    int64                       `json:"size"`
+	Sheets  map[string]SheetFingerprint `json:"sheets"`
+	// Handle and Version identify the cached workbook state observed, which
+	// differs from the file while deferred saves are pending.
+	Handle  string `json:"-"`
+	Version int64  `json:"-"`
+}
+
+// sameState reports whether cur shows the same workbook as a read-tool
+// baseline b: the file is unchanged and, when the handle is still the one b
+// was read from, no write has touched it since. A new handle was loaded from
+// the file, so an unchanged file means unchanged contents.
+func (b WorkbookFingerprint) sameState(cur WorkbookFingerprint) bool {
+	return b.ModTime == cur.ModTime && b.Size == cur.Size && (b.Handle != cur.Handle || b.Version == cur.Version)
+}
+
+// ChangeTracker remembers the last-seen fingerprint of each workbook per client
+// session so what_changed can report differences. It keeps at most maxSessions
+// sessions, dropping the least recently used, and each session at most
+// maxPerSession workbooks, dropping the oldest entry.
+type ChangeTracker struct {
+	mu            sync.Mutex
+	maxSessions   int
+	maxPerSession int
+	tick          uint64
+	sessions      map[string]*sessionFingerprints
+}
+
+type sessionFingerprints struct {
+	used  uint64   // tracker tick of the last access
+	order []string // canonical paths, oldest first
+	byKey map[string]WorkbookFingerprint
+}
+
+// NewChangeTracker constructs a tracker bounded to maxSessions sessions of
+// maxPerSession workbooks each. Pass a bound <= 0 to use its default from
+// config.
+func NewChangeTracker(maxSessions, maxPerSession int) *ChangeTracker {
+	if maxSessions <= 0 {
+		maxSessions = config.DefaultMaxTrackedSessions
+	}
+	if maxPerSession <= 0 {
+		maxPerSession = config.DefaultMaxTrackedWorkbooksPerSession
+	}
+	return &ChangeTracker{maxSessions: maxSessions, maxPerSession: maxPerSession, sessions: make(map[string]*sessionFingerprints)}
+}
+
+// Baseline returns the recorded fingerprint for a session and path.
+func (t *ChangeTracker) Baseline(sessionID, path string) (WorkbookFingerprint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sf, ok := t.sessions[sessionID]
+	if !ok {
+		return WorkbookFingerprint{}, false
+	}
+	t.tick++
+	sf.used = t.tick
+	fp, ok := sf.byKey[path]
+	return fp, ok
+}
+
+// Record stores fp as the baseline for a session and path, evicting the oldest
+// path in the session, or the least recently used session, when a bound is
+// exceeded.
+func (t *ChangeTracker) Record(sessionID, path string, fp WorkbookFingerprint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tick++
+	sf, ok := t.sessions[sessionID]
+	if !ok {
+		if len(t.sessions) >= t.maxSessions {
+			t.evictSessionLocked()
+		}
+		sf = &sessionFingerprints{byKey: make(map[string]WorkbookFingerprint)}
+		t.sessions[sessionID] = sf
+	}
+	sf.used = t.tick
+	if _, exists := sf.byKey[path]; exists {
+		for i, p := range sf.order {
+			if p == path {
+				sf.order = append(sf.order[:i], sf.order[i+1:]...)
+				break
+			}
+		}
+	}
+	sf.order = append(sf.order, path)
+	sf.byKey[path] = fp
+	for len(sf.order) > t.maxPerSession {
+		delete(sf.byKey, sf.order[0])
+		sf.order = sf.order[1:]
+	}
+}
+
+// evictSessionLocked drops the least recently used session; t.mu is held.
+func (t *ChangeTracker) evictSessionLocked() {
+	var oldest string
+	var oldestUsed uint64
+	for id, sf := range t.sessions {
+		if oldest == "" || sf.used < oldestUsed {
+			oldest, oldestUsed = id, sf.used
+		}
+	}
+	delete(t.sessions, oldest)
+}
+
+// ForgetSession drops all fingerprints recorded for a session.
+func (t *ChangeTracker) ForgetSession(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, sessionID)
+}
+
+// observe records a baseline for the calling session when none exists yet.
+// Read tools call it opportunistically with the handle version they read; it
+// only stats the file, so it is cheap enough for every read and safe under
+// any workbook lock.
+func (t *ChangeTracker) observe(ctx context.Context, id, path string, version int64) {
+	sid := sessionIDFromContext(ctx)
+	if _, ok := t.Baseline(sid, path); ok {
+		return
+	}
+	fp := WorkbookFingerprint{Handle: id, Version: version}
+	fp.ModTime, fp.Size = fileState(path)
+	t.Record(sid, path, fp)
+}
+
+// observeWorkbook is observe for handlers that do not otherwise hold a lock
+// that reports the handle version.
+func (t *ChangeTracker) observeWorkbook(ctx context.Context, mgr *workbooks.Manager, id, path string) {
+	if _, ok := t.Baseline(sessionIDFromContext(ctx), path); ok {
+		return
+	}
+	if version, err := mgr.VersionOf(id); err == nil {
+		t.observe(ctx, id, path, version)
+	}
+}
+
+// sessionIDFromContext returns the MCP client session ID or "" when the call
+// is not bound to a session.
+func sessionIDFromContext(ctx context.Context) string {
+	if cs := server.ClientSessionFromContext(ctx); cs != nil {
+		return cs.SessionID()
+	}
+	return ""
+}
+
+// fileState returns path's mtime (Unix seconds) and size, or zeros when it
+// cannot be statted.
+func fileState(path string) (int64, int64) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0
+	}
+	return fi.ModTime().Unix(), fi.Size()
+}
+
+// computeFingerprint derives per-sheet dimension, header hash, and used-range
+// cell count, plus file mtime/size from disk. Sheets without a stored
+// dimension are scanned for at most maxCells cells each and marked Partial
+// past that.
+func computeFingerprint(ctx context.Context, f *excelize.File, path string, maxCells int) (WorkbookFingerprint, error) {
+	fp := WorkbookFingerprint{Sheets: make(map[string]SheetFingerprint)}
+	fp.ModTime, fp.Size = fileState(path)
+	for _, name := range f.GetSheetList() {
+		if err := ctx.Err(); err != nil {
+			return WorkbookFingerprint{}, err
+		}
+		var sf SheetFingerprint
+		dim, err := f.GetSheetDimension(name)
+		if err != nil {
+			return WorkbookFingerprint{}, err
+		}
+		var header []string
+		if strings.Contains(dim, ":") {
+			if rows, rerr := f.Rows(name); rerr == nil {
+				if rows.Next() {
+					header, _ = rows.Columns()
+				}
+				_ = rows.Close()
+			}
+		} else {
+			// Writers that skip the <dimension> element (excelize among them) leave
+			// only "A1"; derive the used range by streaming rows instead.
+			scanned, h, partial, serr := scanUsedRangeLimit(ctx, f, name, maxCells)
+			if serr != nil {
+				return WorkbookFingerprint{}, serr
+			}
+			if scanned != "" {
+				dim = scanned
+			}
+			header, sf.Partial = h, partial
+		}
+		sf.Dimension = dim
+		if parts := strings.Split(dim, ":"); len(parts) == 2 {
+			x1, y1, e1 := excelize.CellNameToCoordinates(parts[0])
+			x2, y2, e2 := excelize.CellNameToCoordinates(parts[1])
+			if e1 == nil && e2 == nil && x2 >= x1 && y2 >= y1 {
+				sf.CellCount = (x2 - x1 + 1) * (y2 - y1 + 1)
+			}
+		} else if dim != "" {
+			sf.CellCount = 1
+		}
+		sum := sha256.Sum256([]byte(strings.Join(header, "\x1f")))
+		sf.HeaderHash = hex.EncodeToString(sum[:8])
+		fp.Sheets[name] = sf
+	}
+	return fp, nil
+}
+
+// scanUsedRange streams a sheet to find its used range (A1:<maxcol><lastrow>)
+// and returns the first row as the header. Returns "" for an empty sheet.
+func scanUsedRange(f *excelize.File, sheet string) (string, []string) {
+	rng, header, _, _ := scanUsedRangeLimit(context.Background(), f, sheet, 0)
+	return rng, header
+}
+
+// scanUsedRangeLimit is scanUsedRange stopping once more than maxCells cells
+// have been read (no limit when maxCells <= 0); partial reports that it
+// stopped early, leaving the range covering the rows read. It checks ctx
+// between rows.
+func scanUsedRangeLimit(ctx context.Context, f *excelize.File, sheet string, maxCells int) (rng string, header []string, partial bool, err error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return "", nil, false, nil
+	}
+	defer func() { _ = rows.Close() }()
+	n, lastRow, maxCols, cells := 0, 0, 0, 0
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return "", nil, false, err
+		}
+		n++
+		cols, cerr := rows.Columns()
+		if cerr != nil {
+			break
+		}
+		if n == 1 {
+			header = cols
+		}
+		if len(cols) > 0 {
+			lastRow = n
+			if len(cols) > maxCols {
+				maxCols = len(cols)
+			}
+		}
+		if cells += len(cols); maxCells > 0 && cells > maxCells {
+			partial = true
+			break
+		}
+	}
+	if lastRow == 0 {
+		return "", header, partial, nil
+	}
+	end, _ := excelize.CoordinatesToCellName(maxCols, lastRow)
+	return "A1:" + end, header, partial, nil
+}
+
+// SheetChange describes how a single sheet differs from the baseline.
+type SheetChange struct {
+	Sheet  string `json:"sheet"`
+	Change string `json:"change" jsonschema_description:"One of added, removed, shape, header"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// WhatChangedInput identifies the workbook to compare.
+type WhatChangedInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+}
+
+// WhatChangedOutput reports differences against the session's last-seen state.
+type WhatChangedOutput struct {
+	Path         string        `json:"path"`
+	HasBaseline  bool          `json:"hasBaseline"`
+	Changed      bool          `json:"changed"`
+	ModTimeDelta int64         `json:"modTimeDelta"`
+	SizeDelta    int64         `json:"sizeDelta"`
+	Sheets       []SheetChange `json:"sheets,omitempty"`
+	Partial      bool          `json:"partial,omitempty" jsonschema_description:"Sheet changes may be incomplete: the baseline was recorded by a read tool, which keeps only file-level state, or a sheet was larger than the scan cap"`
+}
+
+// diffFingerprints lists sheet-level differences in stable order.
+func diffFingerprints(before, after WorkbookFingerprint) []SheetChange {
+	var changes []SheetChange
+	for name, a := range after.Sheets {
+		b, ok := before.Sheets[name]
+		if !ok {
+			changes = append(changes, SheetChange{Sheet: name, Change: "added", After: a.Dimension})
+			continue
+		}
+		if b.Dimension != a.Dimension || b.CellCount != a.CellCount {
+			changes = append(changes, SheetChange{Sheet: name, Change: "shape", Before: b.Dimension, After: a.Dimension})
+		}
+		if b.HeaderHash != a.HeaderHash {
+			changes = append(changes, SheetChange{Sheet: name, Change: "header", Before: b.HeaderHash, After: a.HeaderHash})
+		}
+	}
+	for name, b := range before.Sheets {
+		if _, ok := after.Sheets[name]; !ok {
+			changes = append(changes, SheetChange{Sheet: name, Change: "removed", Before: b.Dimension})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Sheet != changes[j].Sheet {
+			return changes[i].Sheet < changes[j].Sheet
+		}
+		return changes[i].Change < changes[j].Change
+	})
+	return changes
+}
+
+// RegisterChangeTools registers what_changed, backed by the registry's ChangeTracker.
+func RegisterChangeTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	whatChanged := mcp.NewTool(
+		"what_changed",
+		mcp.WithDescription(fmt.Sprintf("Report whether a workbook changed since this session last looked at it. Compares per‑sheet fingerprints (used‑range dimension, header hash, used‑range cell count) recorded by the previous what_changed call against the workbook as it is now, and reports added/removed sheets, shape and header changes, and the file‑level mtime/size delta. Read tools record only the file‑level state they saw; when that is the baseline and the workbook changed since, the change is reported without sheet detail and partial=true. Sheets without a stored dimension are scanned for at most %d cells; larger ones are compared on the rows scanned and also set partial. When no prior state exists it says so and records the current state as the baseline; every call re-baselines. State is per session, bounded, and cleared when the session ends. Errors: VALIDATION, OPEN_FAILED, INVALID_HANDLE, TIMEOUT, DISCOVERY_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[WhatChangedInput](),
+		mcp.WithOutputSchema[WhatChangedOutput](),
+		readOnlyTool(false),
+	)
+	s.AddTool(whatChanged, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in WhatChangedInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		p := strings.TrimSpace(in.Path)
+		if p == "" {
+			return mcperr.FromText("VALIDATION: path is required"), nil
+		}
+		// The manager reloads a handle whose file changed on disk, so the
+		// fingerprint below always reflects the current file.
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sid := sessionIDFromContext(ctx)
+		before, hasBaseline := reg.changes.Baseline(sid, canonical)
+
+		var current WorkbookFingerprint
+		err := mgr.WithRead(id, func(f *excelize.File, version int64) error {
+			fp, ferr := computeFingerprint(ctx, f, canonical, limits.MaxCellsPerOp)
+			if ferr != nil {
+				return ferr
+			}
+			fp.Handle, fp.Version = id, version
+			current = fp
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
+			}
+			if res := workbookAccessError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.FromText(fmt.Sprintf("DISCOVERY_FAILED: %v", err)), nil
+		}
+		reg.changes.Record(sid, canonical, current)
+
+		out := WhatChangedOutput{Path: canonical, HasBaseline: hasBaseline}
+		var b strings.Builder
+		if !hasBaseline {
+			b.WriteString("no prior state for this session; baseline recorded")
+		} else {
+			out.ModTimeDelta = current.ModTime - before.ModTime
+			out.SizeDelta = current.Size - before.Size
+			switch {
+			case before.Sheets != nil:
+				out.Sheets = diffFingerprints(before, current)
+				out.Changed = len(out.Sheets) > 0 || out.ModTimeDelta != 0 || out.SizeDelta != 0
+			case before.sameState(current):
+				// A read baseline of the state fingerprinted now.
+			default:
+				// A read baseline of an earlier state: which sheets changed is unknown.
+				out.Changed, out.Partial = true, true
+			}
+			for _, sf := range current.Sheets {
+				out.Partial = out.Partial || sf.Partial
+			}
+			for _, sf := range before.Sheets {
+				out.Partial = out.Partial || sf.Partial
+			}
+			fmt.Fprintf(&b, "changed=%v sheets=%d mtimeDelta=%ds sizeDelta=%dB", out.Changed, len(out.Sheets), out.ModTimeDelta, out.SizeDelta)
+			if out.Partial {
+				b.WriteString(" partial=true")
+			}
+			for _, c := range out.Sheets {
+				fmt.Fprintf(&b, "\n- %q %s", c.Sheet, c.Change)
+				if c.Change == "shape" {
+					fmt.Fprintf(&b, " %s -> %s", c.Before, c.After)
+				}
+			}
+		}
+		summary := b.String()
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(summary)}
+		return res, nil
+	}))
+	reg.Register(whatChanged)
+}
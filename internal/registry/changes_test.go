@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func writeChangesWorkbook(t *testing.T, path string, header []string, rows int, extraSheet bool) {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &header))
+	for i := 0; i < rows; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]any{i, i * 2}))
+	}
+	if extraSheet {
+		_, err := f.NewSheet("Extra")
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+}
+
+func TestWhatChanged_BaselineThenDiff(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "changes.xlsx")
+	writeChangesWorkbook(t, path, []string{"id", "amount"}, 3, false)
+
+	// First call without prior reads records a baseline.
+	res := callTool(t, srv, "what_changed", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(WhatChangedOutput)
+	require.False(t, out.HasBaseline)
+	require.False(t, out.Changed)
+
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path})
+	out = res.StructuredContent.(WhatChangedOutput)
+	require.True(t, out.HasBaseline)
+	require.False(t, out.Changed)
+	require.Empty(t, out.Sheets)
+
+	// Rewrite with a new header, more rows, and an extra sheet.
+	writeChangesWorkbook(t, path, []string{"id", "total"}, 5, true)
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(WhatChangedOutput)
+	require.True(t, out.Changed)
+	require.NotZero(t, out.SizeDelta)
+	require.Equal(t, []SheetChange{
+		{Sheet: "Extra", Change: "added", After: "A1"},
+		{Sheet: "Sheet1", Change: "header", Before: out.Sheets[1].Before, After: out.Sheets[1].After},
+		{Sheet: "Sheet1", Change: "shape", Before: "A1:B4", After: "A1:B6"},
+	}, out.Sheets)
+	require.NotEqual(t, out.Sheets[1].Before, out.Sheets[1].After)
+}
+
+func TestWhatChanged_ReadsCaptureBaseline(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "observed.xlsx")
+	writeChangesWorkbook(t, path, []string{"id", "amount"}, 2, false)
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path})
+	out := res.StructuredContent.(WhatChangedOutput)
+	require.True(t, out.HasBaseline)
+	require.False(t, out.Changed)
+}
+
+func TestWhatChanged_ChangeSinceReadIsPartial(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "observed.xlsx")
+	writeChangesWorkbook(t, path, []string{"id", "amount"}, 2, false)
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	writeChangesWorkbook(t, path, []string{"id", "amount"}, 40, false)
+
+	// The read kept file-level state only, so sheet detail is unknown.
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(WhatChangedOutput)
+	require.True(t, out.HasBaseline)
+	require.True(t, out.Changed)
+	require.True(t, out.Partial)
+	require.Empty(t, out.Sheets)
+	require.NotZero(t, out.SizeDelta)
+	require.Contains(t, resultText(t, res), "partial=true")
+
+	// what_changed itself records sheet fingerprints.
+	writeChangesWorkbook(t, path, []string{"id", "amount"}, 41, false)
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path})
+	out = res.StructuredContent.(WhatChangedOutput)
+	require.False(t, out.Partial)
+	require.Equal(t, []SheetChange{{Sheet: "Sheet1", Change: "shape", Before: "A1:B41", After: "A1:B42"}}, out.Sheets)
+}
+
+func TestComputeFingerprint_Bounded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.xlsx")
+	writeChangesWorkbook(t, path, []string{"id", "amount"}, 10, false)
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	fp, err := computeFingerprint(context.Background(), f, path, 4)
+	require.NoError(t, err)
+	require.Equal(t, SheetFingerprint{Dimension: "A1:B3", HeaderHash: fp.Sheets["Sheet1"].HeaderHash, CellCount: 6, Partial: true}, fp.Sheets["Sheet1"])
+	fp, err = computeFingerprint(context.Background(), f, path, 100)
+	require.NoError(t, err)
+	require.Equal(t, "A1:B11", fp.Sheets["Sheet1"].Dimension)
+	require.False(t, fp.Sheets["Sheet1"].Partial)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = computeFingerprint(ctx, f, path, 100)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWhatChanged_ReloadsCSVAndEncryptedWorkbooks(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	// A CSV edited on disk is reloaded through the manager, not reopened as a zip.
+	csvPath := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("region,units\nNorth,10\n"), 0o644))
+	res := callTool(t, srv, "what_changed", map[string]any{"path": csvPath})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.NoError(t, os.WriteFile(csvPath, []byte("region,units\nNorth,10\nSouth,20\n"), 0o644))
+	res = callTool(t, srv, "what_changed", map[string]any{"path": csvPath})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(WhatChangedOutput)
+	require.True(t, out.Changed)
+	require.Equal(t, []SheetChange{{Sheet: "orders", Change: "shape", Before: "A1:B2", After: "A1:B3"}}, out.Sheets)
+
+	// An encrypted workbook reloads with the password.
+	path := filepath.Join(t.TempDir(), "secret.xlsx")
+	save := func(rows int) {
+		f := excelize.NewFile()
+		for r := 1; r <= rows; r++ {
+			cell, _ := excelize.CoordinatesToCellName(1, r)
+			require.NoError(t, f.SetCellValue("Sheet1", cell, r))
+		}
+		require.NoError(t, f.SaveAs(path, excelize.Options{Password: "s3cret"}))
+		require.NoError(t, f.Close())
+	}
+	save(2)
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PASSWORD_REQUIRED")
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path, "password": "s3cret"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	save(4)
+	res = callTool(t, srv, "what_changed", map[string]any{"path": path, "password": "s3cret"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(WhatChangedOutput)
+	require.True(t, out.Changed)
+	require.Equal(t, []SheetChange{{Sheet: "Sheet1", Change: "shape", Before: "A1:A2", After: "A1:A4"}}, out.Sheets)
+
+	res = callTool(t, srv, "what_changed", map[string]any{"path": filepath.Join(t.TempDir(), "notes.txt")})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
+
+func TestChangeTracker_BoundedAndForget(t *testing.T) {
+	tr := NewChangeTracker(0, 2)
+	tr.Record("s1", "/a.xlsx", WorkbookFingerprint{Size: 1})
+	tr.Record("s1", "/b.xlsx", WorkbookFingerprint{Size: 2})
+	tr.Record("s1", "/c.xlsx", WorkbookFingerprint{Size: 3})
+	tr.Record("s2", "/a.xlsx", WorkbookFingerprint{Size: 4})
+
+	_, ok := tr.Baseline("s1", "/a.xlsx")
+	require.False(t, ok, "oldest path should be evicted")
+	fp, ok := tr.Baseline("s1", "/c.xlsx")
+	require.True(t, ok)
+	require.EqualValues(t, 3, fp.Size)
+
+	tr.ForgetSession("s1")
+	_, ok = tr.Baseline("s1", "/c.xlsx")
+	require.False(t, ok)
+	_, ok = tr.Baseline("s2", "/a.xlsx")
+	require.True(t, ok)
+}
+
+func TestChangeTracker_EvictsLeastRecentSession(t *testing.T) {
+	tr := NewChangeTracker(2, 0)
+	tr.Record("s1", "/a.xlsx", WorkbookFingerprint{Size: 1})
+	tr.Record("s2", "/a.xlsx", WorkbookFingerprint{Size: 2})
+	_, ok := tr.Baseline("s1", "/a.xlsx")
+	require.True(t, ok)
+	tr.Record("s3", "/a.xlsx", WorkbookFingerprint{Size: 3})
+
+	_, ok = tr.Baseline("s2", "/a.xlsx")
+	require.False(t, ok, "least recently used session should be evicted")
+	for _, sid := range []string{"s1", "s3"} {
+		_, ok = tr.Baseline(sid, "/a.xlsx")
+		require.True(t, ok, sid)
+	}
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+)
+
+// ColumnRef names a sheet column by 1-based index (a JSON number) or by
+// header name (a JSON string).
+type ColumnRef struct {
+	Index int
+	Name  string
+}
+
+// UnmarshalJSON accepts a positive integer or a non-empty string.
+func (c *ColumnRef) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("column name must not be empty")
+		}
+		*c = ColumnRef{Name: name}
+		return nil
+	}
+	var idx int
+	if err := json.Unmarshal(b, &idx); err != nil || idx < 1 {
+		return fmt.Errorf("column must be a 1-based index or a header name, got %s", b)
+	}
+	*c = ColumnRef{Index: idx}
+	return nil
+}
+
+// MarshalJSON writes the index or name back in the form it was given.
+func (c ColumnRef) MarshalJSON() ([]byte, error) {
+	if c.Name != "" {
+		return json.Marshal(c.Name)
+	}
+	return json.Marshal(c.Index)
+}
+
+// JSONSchema describes the number-or-string form for tool input schemas.
+func (ColumnRef) JSONSchema() *jsonschema.Schema {
+	one, empty := json.Number("1"), uint64(1)
+	return &jsonschema.Schema{OneOf: []*jsonschema.Schema{
+		{Type: "integer", Minimum: one},
+		{Type: "string", MinLength: &empty},
+	}}
+}
+
+// columnRefsNeedHeader reports whether any ref is a header name.
+func columnRefsNeedHeader(refs []ColumnRef) bool {
+	for _, r := range refs {
+		if r.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveColumnRefs maps refs to 1-based sheet columns in the order given.
+// Names match header (values from column A of headerRow) case-insensitively.
+func resolveColumnRefs(refs []ColumnRef, header []string, headerRow int) ([]int, error) {
+	cols := make([]int, 0, len(refs))
+	for _, r := range refs {
+		if r.Name == "" {
+			if r.Index > excelize.MaxColumns {
+				return nil, mcperr.Errorf(mcperr.Validation, "column %d exceeds the sheet maximum of %d", r.Index, excelize.MaxColumns)
+			}
+			cols = append(cols, r.Index)
+			continue
+		}
+		found := 0
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), r.Name) {
+				found = i + 1
+				break
+			}
+		}
+		if found == 0 {
+			return nil, mcperr.Errorf(mcperr.Validation, "column name %q not found in header row %d", r.Name, headerRow)
+		}
+		cols = append(cols, found)
+	}
+	return cols, nil
+}
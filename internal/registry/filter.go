@@ -21,17 +21,21 @@ func NewWriteToolFilterFromEnv() *WriteToolFilter {
 	return &WriteToolFilter{allowWrites: allow}
 }
 
+// WritesEnabled reports whether write tools are exposed.
+func (f *WriteToolFilter) WritesEnabled() bool {
+	return f.allowWrites
+}
+
 // FilterTools implements server tool filtering semantics.
-// When writes are disabled, tools with prefixes commonly used for writes
-// are excluded from discovery: write_, update_, transform_.
+// When writes are disabled, every tool not annotated read-only (see
+// IsWriteTool) is excluded from discovery.
 func (f *WriteToolFilter) FilterTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
 	if f.allowWrites {
 		return tools
 	}
 	out := make([]mcp.Tool, 0, len(tools))
 	for _, t := range tools {
-		name := strings.ToLower(t.Name)
-		if strings.HasPrefix(name, "write_") || strings.HasPrefix(name, "update_") || strings.HasPrefix(name, "transform_") {
+		if IsWriteTool(t) {
 			continue
 		}
 		out = append(out, t)
@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSortedPages caps how many pages of a sorted filter_data result are
+// materialized: at most max_rows × maxSortedPages matching rows are kept, so
+// sorting never buffers more than a few thousand snapshots.
+const maxSortedPages = 10
+
+// filterSort orders filter_data matches by one column.
+type filterSort struct {
+	col  ColumnRef
+	desc bool
+}
+
+// String encodes the sort for the cursor, e.g. `desc 3` or `asc "Amount"`.
+func (s filterSort) String() string {
+	b, _ := s.col.MarshalJSON()
+	order := "asc"
+	if s.desc {
+		order = "desc"
+	}
+	return order + " " + string(b)
+}
+
+func parseFilterSort(v string) (filterSort, error) {
+	order, ref, ok := strings.Cut(v, " ")
+	if !ok || (order != "asc" && order != "desc") {
+		return filterSort{}, fmt.Errorf("invalid sort %q", v)
+	}
+	var col ColumnRef
+	if err := json.Unmarshal([]byte(ref), &col); err != nil {
+		return filterSort{}, fmt.Errorf("invalid sort %q", v)
+	}
+	return filterSort{col: col, desc: order == "desc"}, nil
+}
+
+// compareCells orders cell text: numbers numerically, then text
+// case-insensitively. Empty cells are handled by sortedRow.before.
+func compareCells(a, b string) int {
+	na, aNum := parseSortNumber(a)
+	nb, bNum := parseSortNumber(b)
+	switch {
+	case aNum && bNum:
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+		return 0
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func parseSortNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	return v, err == nil
+}
+
+// sortedRow is one matching row held for ordering.
+type sortedRow struct {
+	row      int
+	key      string
+	snapshot []string
+}
+
+// before reports whether r sorts ahead of o. Empty keys sort last in either
+// direction and ties keep sheet order, so pages are deterministic.
+func (r sortedRow) before(o sortedRow, desc bool) bool {
+	re, oe := strings.TrimSpace(r.key) == "", strings.TrimSpace(o.key) == ""
+	if re != oe {
+		return oe
+	}
+	if c := compareCells(r.key, o.key); c != 0 && !re {
+		return (c < 0) != desc
+	}
+	return r.row < o.row
+}
+
+// topRows keeps the first k rows in sort order seen so far. The root of the
+// heap is the last kept row, which is evicted when a better row arrives.
+type topRows struct {
+	k    int
+	desc bool
+	rows []sortedRow
+}
+
+func newTopRows(k int, desc bool) *topRows {
+	return &topRows{k: k, desc: desc}
+}
+
+func (t *topRows) Len() int           { return len(t.rows) }
+func (t *topRows) Less(i, j int) bool { return t.rows[j].before(t.rows[i], t.desc) }
+func (t *topRows) Swap(i, j int)      { t.rows[i], t.rows[j] = t.rows[j], t.rows[i] }
+func (t *topRows) Push(x any)         { t.rows = append(t.rows, x.(sortedRow)) }
+func (t *topRows) Pop() any {
+	old := t.rows
+	r := old[len(old)-1]
+	t.rows = old[:len(old)-1]
+	return r
+}
+
+// accepts reports whether r would be kept, so callers can skip building its
+// snapshot. Rows arrive in sheet order, so a row tying the last kept row is
+// never better.
+func (t *topRows) accepts(r sortedRow) bool {
+	return len(t.rows) < t.k || r.before(t.rows[0], t.desc)
+}
+
+// offer adds r to the kept set, evicting the last kept row when full.
+func (t *topRows) offer(r sortedRow) {
+	if len(t.rows) < t.k {
+		heap.Push(t, r)
+		return
+	}
+	if !r.before(t.rows[0], t.desc) {
+		return
+	}
+	t.rows[0] = r
+	heap.Fix(t, 0)
+}
+
+// sorted returns the kept rows in sort order.
+func (t *topRows) sorted() []sortedRow {
+	out := append([]sortedRow(nil), t.rows...)
+	sort.Slice(out, func(i, j int) bool { return out[i].before(out[j], t.desc) })
+	return out
+}
@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopRows_OrdersAndBounds(t *testing.T) {
+	keys := []string{"10", "", "b", "2", "A", "2", "1,000"}
+	collect := func(k int, desc bool) []int {
+		top := newTopRows(k, desc)
+		for i, key := range keys {
+			r := sortedRow{row: i + 1, key: key}
+			if top.accepts(r) {
+				top.offer(r)
+			}
+		}
+		var rows []int
+		for _, r := range top.sorted() {
+			rows = append(rows, r.row)
+		}
+		return rows
+	}
+
+	// Numbers before text, ties in sheet order, empty last.
+	require.Equal(t, []int{4, 6, 1, 7, 5, 3, 2}, collect(10, false))
+	require.Equal(t, []int{3, 5, 7, 1, 4, 6, 2}, collect(10, true))
+	require.Equal(t, []int{4, 6, 1}, collect(3, false))
+	require.Equal(t, []int{3, 5}, collect(2, true))
+}
+
+func TestFilterSort_RoundTrip(t *testing.T) {
+	for _, fs := range []filterSort{{col: ColumnRef{Index: 3}, desc: true}, {col: ColumnRef{Name: "Net Amount"}}} {
+		got, err := parseFilterSort(fs.String())
+		require.NoError(t, err)
+		require.Equal(t, fs, got)
+	}
+	_, err := parseFilterSort("sideways 3")
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+func TestWriteToolFilter_UsesAnnotations(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	RegisterFoundationTools(srv, reg, limits, mgr)
+	RegisterChangeTools(srv, reg, limits, mgr)
+	RegisterStructureTools(srv, reg, limits, mgr)
+	RegisterRecalcTools(srv, reg, limits, mgr)
+	RegisterWorkbookTools(srv, reg, limits, mgr)
+	RegisterExportTools(srv, reg, limits, mgr)
+	RegisterDuplicateTools(srv, reg, limits, mgr)
+	RegisterHistogramTools(srv, reg, limits, mgr)
+	RegisterCrosstabTools(srv, reg, limits, mgr)
+	RegisterDiffTools(srv, reg, limits, mgr)
+	RegisterMergeTools(srv, reg, limits, mgr)
+	RegisterLookupTools(srv, reg, limits, mgr)
+	RegisterStyleTools(srv, reg, limits, mgr)
+	RegisterCleanTools(srv, reg, limits, mgr)
+	RegisterNameTools(srv, reg, mgr)
+	RegisterTableTools(srv, reg, limits, mgr)
+	RegisterCommentTools(srv, reg, limits, mgr)
+	RegisterInsightsTools(srv, reg, limits, mgr)
+
+	tools, err := reg.Tools(context.Background())
+	require.NoError(t, err)
+	var writes []string
+	for _, tool := range tools {
+		require.NotNil(t, tool.Annotations.ReadOnlyHint, tool.Name)
+		if IsWriteTool(tool) {
+			writes = append(writes, tool.Name)
+			require.NotNil(t, tool.Annotations.DestructiveHint, tool.Name)
+		}
+	}
+	require.ElementsMatch(t, []string{
+		"write_range", "apply_formula", "insert_rows", "delete_rows", "add_sheet", "rename_sheet",
+		"delete_sheet", "copy_sheet", "recalculate_workbook", "export_range_csv", "delete_insight_session", "format_range",
+		"create_named_range", "delete_named_range", "add_comment", "flush_workbook", "create_merged_sheet",
+		"clean_range", "restore_backup",
+	}, writes)
+
+	visible := (&WriteToolFilter{}).FilterTools(context.Background(), tools)
+	require.Len(t, visible, len(tools)-len(writes))
+	for _, tool := range visible {
+		require.False(t, IsWriteTool(tool), tool.Name)
+	}
+	require.Len(t, (&WriteToolFilter{allowWrites: true}).FilterTools(context.Background(), tools), len(tools))
+
+	write, ok := reg.IsWriteTool("write_range")
+	require.True(t, ok)
+	require.True(t, write)
+	write, ok = reg.IsWriteTool("read_range")
+	require.True(t, ok)
+	require.False(t, write)
+	_, ok = reg.IsWriteTool("nope")
+	require.False(t, ok)
+	tool, _ := reg.Get("write_range")
+	require.True(t, *tool.Annotations.DestructiveHint)
+	require.False(t, *tool.Annotations.IdempotentHint)
+}
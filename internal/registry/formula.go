@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// refPattern matches an A1-style reference at the start of the input with an
+// optional sheet qualifier: a cell or cell range (A1, $A$1:B2), a whole-column
+// range (A:C), or a whole-row range (1:3).
+var refPattern = regexp.MustCompile(`^((?:'(?:[^']|'')+'|[A-Za-z_][A-Za-z0-9_.]*)!)?(\$?[A-Za-z]{1,3}\$?[0-9]+(?::\$?[A-Za-z]{1,3}\$?[0-9]+)?|\$?[A-Za-z]{1,3}:\$?[A-Za-z]{1,3}|\$?[0-9]+:\$?[0-9]+)`)
+
+// translateFormula shifts relative references in formula by (dCol, dRow) the
+// way Excel's fill handle does. Columns or rows anchored with '$' stay fixed,
+// string literals are untouched, and references qualified with a sheet other
+// than sheet are left as-is. References shifted outside the grid become #REF!.
+func translateFormula(formula, sheet string, dCol, dRow int) string {
+	if dCol == 0 && dRow == 0 {
+		return formula
+	}
+	var b strings.Builder
+	b.Grow(len(formula) + 8)
+	for i := 0; i < len(formula); {
+		ch := formula[i]
+		if ch == '"' {
+			// Copy a string literal verbatim ("" escapes a quote).
+			j := i + 1
+			for j < len(formula) {
+				if formula[j] == '"' {
+					if j+1 < len(formula) && formula[j+1] == '"' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			b.WriteString(formula[i:j])
+			i = j
+			continue
+		}
+		if i == 0 || !isRefBoundaryBlocker(formula[i-1]) {
+			if m := refPattern.FindStringSubmatch(formula[i:]); m != nil {
+				end := i + len(m[0])
+				// A trailing '(' means a function name such as LOG10; identifier
+				// characters mean the match is only a prefix of a longer name.
+				if end == len(formula) || !(formula[end] == '(' || isIdentChar(formula[end])) {
+					b.WriteString(m[1])
+					if m[1] != "" && !strings.EqualFold(unquoteSheet(strings.TrimSuffix(m[1], "!")), sheet) {
+						b.WriteString(m[2])
+					} else {
+						b.WriteString(shiftRef(m[2], dCol, dRow))
+					}
+					i = end
+					continue
+				}
+			}
+		}
+		b.WriteByte(ch)
+		i++
+	}
+	return b.String()
+}
+
+// isRefBoundaryBlocker reports whether a preceding byte means the current
+// position cannot begin a reference.
+func isRefBoundaryBlocker(c byte) bool {
+	return isIdentChar(c) || c == '$' || c == ':' || c == '!' || c == '\'' || c == '.'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// unquoteSheet strips the quotes around a sheet qualifier and collapses doubled
+// apostrophes inside it.
+func unquoteSheet(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// shiftRef shifts each endpoint of a reference (cell, range, column, or row range).
+func shiftRef(ref string, dCol, dRow int) string {
+	parts := strings.Split(ref, ":")
+	for i, p := range parts {
+		shifted, ok := shiftEndpoint(p, dCol, dRow)
+		if !ok {
+			return "#REF!"
+		}
+		parts[i] = shifted
+	}
+	return strings.Join(parts, ":")
+}
+
+// shiftEndpoint shifts one endpoint like $A1, B$2, C, or 7.
+func shiftEndpoint(p string, dCol, dRow int) (string, bool) {
+	i := 0
+	colAbs := false
+	if i < len(p) && p[i] == '$' {
+		colAbs = true
+		i++
+	}
+	j := i
+	for j < len(p) && ((p[j] >= 'A' && p[j] <= 'Z') || (p[j] >= 'a' && p[j] <= 'z')) {
+		j++
+	}
+	colName := p[i:j]
+	rowAbs := false
+	if j < len(p) && p[j] == '$' {
+		rowAbs = true
+		j++
+	}
+	if colName == "" {
+		// Row-only endpoint: the leading '$' anchors the row.
+		rowAbs = colAbs
+	}
+	rowText := p[j:]
+
+	var out strings.Builder
+	if colName != "" {
+		col, err := excelize.ColumnNameToNumber(colName)
+		if err != nil {
+			return "", false
+		}
+		if !colAbs {
+			col += dCol
+		}
+		name, err := excelize.ColumnNumberToName(col)
+		if err != nil {
+			return "", false
+		}
+		if colAbs {
+			out.WriteByte('$')
+		}
+		out.WriteString(name)
+	}
+	if rowText != "" {
+		row, err := strconv.Atoi(rowText)
+		if err != nil {
+			return "", false
+		}
+		if !rowAbs {
+			row += dRow
+		}
+		if row < 1 || row > excelize.TotalRows {
+			return "", false
+		}
+		if rowAbs {
+			out.WriteByte('$')
+		}
+		out.WriteString(strconv.Itoa(row))
+	}
+	return out.String(), true
+}
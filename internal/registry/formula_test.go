@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestTranslateFormula(t *testing.T) {
+	cases := []struct {
+		name       string
+		formula    string
+		dCol, dRow int
+		want       string
+	}{
+		{"relative range in function", "=SUM(A1:B1)", 0, 2, "=SUM(A3:B3)"},
+		{"mixed anchors", "=$A1+A$1+$A$1+A1", 1, 1, "=$A2+B$1+$A$1+B2"},
+		{"cross-sheet ref untouched", "=Sheet2!A1+A1", 0, 1, "=Sheet2!A1+A2"},
+		{"quoted cross-sheet ref untouched", "='Q1 Data'!B2*C2", 0, 1, "='Q1 Data'!B2*C3"},
+		{"same-sheet qualifier shifts", "=Sheet1!A1", 0, 1, "=Sheet1!A2"},
+		{"whole column and row ranges", "=SUM(A:A)+SUM(1:1)+SUM($B:$B)", 1, 1, "=SUM(B:B)+SUM(2:2)+SUM($B:$B)"},
+		{"string literal untouched", `=IF(A1="A1","B2",B2)`, 0, 1, `=IF(A2="A1","B2",B3)`},
+		{"function names with digits untouched", "=LOG10(A1)+ATAN2(B1,C1)", 0, 1, "=LOG10(A2)+ATAN2(B2,C2)"},
+		{"nested functions with ranges", "=IFERROR(VLOOKUP(A2,$D$2:$E$10,2,FALSE),0)", 0, 3, "=IFERROR(VLOOKUP(A5,$D$2:$E$10,2,FALSE),0)"},
+		{"off-grid becomes #REF!", "=XFD1", 1, 0, "=#REF!"},
+		{"zero offset is identity", "=A1", 0, 0, "=A1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, translateFormula(tc.formula, "Sheet1", tc.dCol, tc.dRow))
+		})
+	}
+}
+
+func TestApplyFormula_AutofillDefaultAndLiteral(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	for r := 1; r <= 3; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]int{r, r * 10}))
+	}
+	path := filepath.Join(t.TempDir(), "formula.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "apply_formula", map[string]any{"path": path, "sheet": "Sheet1", "range": "C1:C3", "formula": "=SUM(A1:B1)"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	res = callTool(t, srv, "apply_formula", map[string]any{"path": path, "sheet": "Sheet1", "range": "D1:D3", "formula": "=A1", "autofill": false})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	g, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer g.Close()
+	for r, want := range []string{"=SUM(A1:B1)", "=SUM(A2:B2)", "=SUM(A3:B3)"} {
+		cell, _ := excelize.CoordinatesToCellName(3, r+1)
+		got, err := g.GetCellFormula("Sheet1", cell)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		cell, _ = excelize.CoordinatesToCellName(4, r+1)
+		got, err = g.GetCellFormula("Sheet1", cell)
+		require.NoError(t, err)
+		require.Equal(t, "=A1", got)
+	}
+}
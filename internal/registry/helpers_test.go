@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+// newTestServer builds an MCP server with the foundation, change, structure,
+// recalc, workbook, export, duplicate, histogram, crosstab, diff, merge,
+// lookup, style, clean, named range, table, and comment tools registered against a fresh workbook manager.
+func newTestServer(t *testing.T) (*server.MCPServer, *workbooks.Manager) {
+	t.Helper()
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	RegisterFoundationTools(srv, reg, limits, mgr)
+	RegisterChangeTools(srv, reg, limits, mgr)
+	RegisterStructureTools(srv, reg, limits, mgr)
+	RegisterRecalcTools(srv, reg, limits, mgr)
+	RegisterWorkbookTools(srv, reg, limits, mgr)
+	RegisterExportTools(srv, reg, limits, mgr)
+	RegisterDuplicateTools(srv, reg, limits, mgr)
+	RegisterHistogramTools(srv, reg, limits, mgr)
+	RegisterCrosstabTools(srv, reg, limits, mgr)
+	RegisterDiffTools(srv, reg, limits, mgr)
+	RegisterMergeTools(srv, reg, limits, mgr)
+	RegisterLookupTools(srv, reg, limits, mgr)
+	RegisterStyleTools(srv, reg, limits, mgr)
+	RegisterCleanTools(srv, reg, limits, mgr)
+	RegisterNameTools(srv, reg, mgr)
+	RegisterTableTools(srv, reg, limits, mgr)
+	RegisterCommentTools(srv, reg, limits, mgr)
+	return srv, mgr
+}
+
+// callTool dispatches a tools/call request through the server and returns the result.
+func callTool(t *testing.T, srv *server.MCPServer, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	msg, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	require.NoError(t, err)
+	resp := srv.HandleMessage(context.Background(), msg)
+	rpc, ok := resp.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response: %#v", resp)
+	res, ok := rpc.Result.(mcp.CallToolResult)
+	require.True(t, ok, "unexpected result type: %T", rpc.Result)
+	return &res
+}
+
+// resultText returns the first text content block of a tool result.
+func resultText(t *testing.T, res *mcp.CallToolResult) string {
+	t.Helper()
+	require.NotEmpty(t, res.Content)
+	tc, ok := res.Content[0].(mcp.TextContent)
+	require.True(t, ok, "unexpected content type: %T", res.Content[0])
+	return tc.Text
+}
+
+// splitSummary separates the one-line summary from the data payload.
+func splitSummary(t *testing.T, text string) (string, string) {
+	t.Helper()
+	summary, body, _ := strings.Cut(text, "\n")
+	return summary, body
+}
+
+// decodeStructured round-trips structured content through JSON into v, which
+// lets tests inspect outputs whose Go types are local to a handler.
+func decodeStructured(t *testing.T, res *mcp.CallToolResult, v any) {
+	t.Helper()
+	b, err := json.Marshal(res.StructuredContent)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, v))
+}
@@ -11,12 +11,20 @@ import (
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
 	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
 	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
 )
 
 // RegisterInsightsTools wires the sequential_insights planning tool.
 func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
-	planner := &insights.Planner{Limits: limits, Sessions: insights.NewSessionStore(20)}
+	reg.mu.RLock()
+	sessions := reg.sessions
+	reg.mu.RUnlock()
+	if sessions == nil {
+		sessions = insights.NewSessionStore(20)
+	}
+	planner := &insights.Planner{Limits: limits, Sessions: sessions}
 
 	// Define tool with typed schemas
 	tool := mcp.NewTool(
@@ -42,22 +50,30 @@ func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Li
   - total_thoughts: Estimated steps (adjustable during process)
   - is_revision/revises_thought: Mark corrections to previous thinking
   - branch_from_thought/branch_id: Explore alternative analysis paths
+  - needs_more_thoughts: The estimated end was reached but more steps are needed
+  - objective: Optional overall goal; kept on the session until replaced
+  - hints: Optional short notes kept with this thought (constraints, focus columns)
+  - observations: [{tool, summary}] for domain calls made since the last thought
   - session_id: Resume session or start new (auto-created if omitted)
   - reset_session: Clear and restart the referenced session
   - show_available_tools: Include MCP tool catalog in response
 
   Outputs:
-  - thought_number/total_thoughts/next_thought_needed/session_id
-  - branches[] and thought_history_length
+  - thought_number/total_thoughts/next_thought_needed/session_id: Loop state
+  - objective: The session's current objective
+  - branches[]/thought_history_length: Sorted branch ids and retained history size
+  - recent_observations[]: Last 5 recorded tool results with their thought_number
   - insight_cards[]: Always-on tiny planning card with next-action cue
-  - meta: limits and planning_only=true
+  - meta: limits, planning_only=true, and resumed_from_disk after a restart
 
   Guidance:
   - Interleave: call this tool between domain tool calls (list_structure, preview_sheet, read_range, detect_tables, profile_schema, etc.)
+  - For anomalies in a numeric column, follow profile_schema with outlier_detection
   - If unsure what to do next, set show_available_tools=true to review the tool catalog
   - Keep thoughts concise and focused on the immediate next action`),
 		mcp.WithInputSchema[insights.SequentialInsightsInput](),
 		mcp.WithOutputSchema[insights.SequentialInsightsOutput](),
+		readOnlyTool(false),
 	)
 
 	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.SequentialInsightsInput) (*mcp.CallToolResult, error) {
@@ -66,7 +82,7 @@ func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Li
 		}
 		out, err := planner.Plan(ctx, in)
 		if err != nil {
-			return mcperr.FromText("PLANNING_FAILED: " + err.Error()), nil
+			return mcperr.New(mcperr.PlanningFailed, err.Error()), nil
 		}
 
 		// Build a readable text response for clients that only render text
@@ -74,11 +90,23 @@ func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Li
 		// Thought summary with loop tracking
 		lines = append(lines, fmt.Sprintf("Thought %d/%d next=%v", out.ThoughtNumber, out.TotalThoughts, out.NextThoughtNeeded))
 		lines = append(lines, fmt.Sprintf("Session: %s", out.SessionID))
+		if out.Objective != "" {
+			lines = append(lines, "Objective: "+truncateText(out.Objective, 200))
+		}
+		if out.Meta.ResumedFromDisk {
+			lines = append(lines, "Session history reloaded from disk after a restart.")
+		}
 
 		if len(out.Branches) > 0 {
 			lines = append(lines, fmt.Sprintf("Branches: %v", out.Branches))
 		}
 		lines = append(lines, fmt.Sprintf("History length: %d", out.ThoughtHistoryLength))
+		if len(out.RecentObservations) > 0 {
+			lines = append(lines, "Recent results:")
+			for _, o := range out.RecentObservations {
+				lines = append(lines, fmt.Sprintf("- [t%d] %s: %s", o.ThoughtNumber, o.Tool, truncateText(o.Summary, 120)))
+			}
+		}
 
 		// Interleaving cue to encourage calling this tool between domain actions
 		lines = append(lines, "NextAction: summarize findings here, then call your next MCP tool; loop back with your next thought.")
@@ -115,45 +143,114 @@ func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Li
 	}))
 
 	reg.Register(tool)
+	registerInsightSessionTools(s, reg, sessions)
 
 	// detect_tables
 	detector := &insights.Detector{Limits: limits, Mgr: mgr}
 	dt := mcp.NewTool(
 		"detect_tables",
-		mcp.WithDescription("Detect multiple rectangular table regions within a sheet using a bounded streaming scan and simple header heuristics. Returns Top‑K ranked candidates with range, header preview, confidence, and optional header samples. Use when a sheet contains several tables separated by blanks and you need a suggested range to analyze. Limits/caps constrain scan rows/cols; errors include INVALID_SHEET and DETECTION_FAILED."),
+		mcp.WithDescription("Detect multiple rectangular table regions within a sheet using a bounded streaming scan and simple header heuristics. Returns Top‑K ranked candidates with range, header preview, confidence, and optional header samples. Use when a sheet contains several tables separated by blanks and you need a suggested range to analyze. The whole used range is scanned in row bands sized to the cell limit; set max_scan_rows to scan a window instead, and meta.start_row/end_row report the rows covered with a cursor (meta.next_cursor) for the next window. start_row and start_col (1-based) move the window's top-left corner, e.g. to skip straight to row 5000; the window must start inside the used range (VALIDATION otherwise), max_scan_cols and the cell limit then apply to the columns from start_col, candidate ranges stay in sheet coordinates, and meta.start_col/end_col report the columns covered. Candidates marked open may continue past the window. Set all_sheets=true instead of sheet to scan every sheet in one call: each sheet covers an equal share of the cell limit (meta.sheets reports coverage), candidates carry their sheet and are ranked across the workbook, and unreadable sheets are skipped with a warning. Merged cells count as filled and gaps of up to gap_tolerance empty cells (default 1) are bridged so spacer columns stay inside one table; such candidates are marked gap_bridged. Errors include INVALID_SHEET, CURSOR_INVALID, and DETECTION_FAILED."),
 		mcp.WithInputSchema[insights.DetectTablesInput](),
 		mcp.WithOutputSchema[insights.DetectTablesOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(dt, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.DetectTablesInput) (*mcp.CallToolResult, error) {
 		if msg := validation.ValidateStruct(in); msg != "" {
 			return mcperr.FromText(msg), nil
 		}
 		if strings.TrimSpace(in.Path) == "" {
-			return mcperr.FromText("VALIDATION: path is required"), nil
+			return mcperr.New(mcperr.Validation, "path is required"), nil
+		}
+		if in.AllSheets && (in.Cursor != "" || in.StartRow > 0 || in.StartCol > 0) {
+			return mcperr.New(mcperr.Validation, "cursor, start_row, and start_col cannot be combined with all_sheets"), nil
+		}
+		var pc *pagination.Cursor
+		if curTok := strings.TrimSpace(in.Cursor); curTok != "" {
+			var cres *mcp.CallToolResult
+			if pc, cres = decodeCursor(curTok, limits.CursorTTL); cres != nil {
+				return cres, nil
+			}
+		}
+		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, in.Path)
+		if openErr != nil {
+			return openFailed(openErr), nil
 		}
-		if strings.TrimSpace(in.Sheet) == "" {
-			return mcperr.FromText("VALIDATION: sheet is required"), nil
+		// The detector reads under its own lock, so take the version first: a
+		// write racing the scan then invalidates the cursor instead of
+		// slipping past it.
+		version, verr := mgr.VersionOf(id)
+		if verr != nil {
+			return openFailed(verr), nil
+		}
+		if pc != nil {
+			if pc.Pt != canonical {
+				return mcperr.New(mcperr.CursorInvalid, "cursor path does not match provided path"), nil
+			}
+			if pc.U != pagination.UnitRows || pc.Ps <= 0 || pc.Mc <= 0 || pc.R != scanColumns(pc.Sc, pc.Mc) {
+				return mcperr.New(mcperr.CursorInvalid, "cursor was not issued by detect_tables"), nil
+			}
+			if sh := strings.TrimSpace(in.Sheet); sh != "" && sh != pc.S {
+				return mcperr.New(mcperr.CursorInvalid, "cursor sheet does not match provided sheet"), nil
+			}
+			mt, fp := fileSnapshot(canonical)
+			if res := cursorMismatch(checkCursor(mgr, pc, id, version, mt, fp)); res != nil {
+				return res, nil
+			}
+			in.Sheet, in.StartRow, in.StartCol, in.MaxScanRows, in.MaxScanCols = pc.S, pc.Off+1, pc.Sc, pc.Ps, pc.Mc
+		}
+		if strings.TrimSpace(in.Sheet) == "" && !in.AllSheets {
+			return mcperr.New(mcperr.Validation, "sheet is required (or set all_sheets)"), nil
 		}
 		out, err := detector.DetectTables(ctx, in)
 		if err != nil {
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.DetectionFailed, err.Error()), nil
+		}
+		if out.Meta.NextStartRow > 0 {
+			mt, fp := fileSnapshot(out.Path)
+			next := pagination.Cursor{V: 1, Pt: out.Path, S: out.Sheet, R: scanColumns(out.Meta.StartCol, out.Meta.ScannedCols), U: pagination.UnitRows, Off: out.Meta.NextStartRow - 1, Ps: in.MaxScanRows, Sc: out.Meta.StartCol, Mc: out.Meta.ScannedCols, Mt: mt, Fp: fp, Hid: id, Wbv: version}
+			if token, encErr := pagination.EncodeCursor(next); encErr == nil {
+				out.Meta.NextCursor = token
 			}
-			return mcperr.FromText("DETECTION_FAILED: " + err.Error()), nil
 		}
 		// Build concise summary
-		summary := fmt.Sprintf("candidates=%d scanned_rows=%d scanned_cols=%d truncated=%v", len(out.Candidates), out.Meta.ScannedRows, out.Meta.ScannedCols, out.Meta.Truncated)
-		var lines []string
-		lines = append(lines, summary)
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ScannedRows*out.Meta.ScannedCols, len(out.Candidates), out.Meta.Truncated || out.Meta.ScanTruncated)
+		summary := fmt.Sprintf("candidates=%d rows=%d-%d cols=%d-%d scanned_cols=%d bands=%d truncated=%v", len(out.Candidates), out.Meta.StartRow, out.Meta.EndRow, out.Meta.StartCol, out.Meta.EndCol, out.Meta.ScannedCols, out.Meta.Bands, out.Meta.Truncated)
+		if in.AllSheets {
+			summary = fmt.Sprintf("candidates=%d sheets=%d skipped=%d scan_truncated=%v truncated=%v", len(out.Candidates), len(out.Meta.Sheets), len(out.Meta.Warnings), out.Meta.ScanTruncated, out.Meta.Truncated)
+		}
+		if out.Meta.ColumnsTruncated {
+			summary += " columns_truncated=true"
+		}
+		if out.Meta.NextCursor != "" {
+			summary += " nextCursor=" + out.Meta.NextCursor
+		}
+		w := newBudgetedWriter(limits)
+		w.Line(summary)
 		maxLines := len(out.Candidates)
 		if maxLines > 5 {
 			maxLines = 5
 		}
 		for i := 0; i < maxLines; i++ {
 			c := out.Candidates[i]
-			lines = append(lines, fmt.Sprintf("- %s rows=%d cols=%d conf=%.3f hdr=%v", c.Range, c.Rows, c.Cols, c.Confidence, previewHeader(c.Header, 6)))
+			rng := c.Range
+			if c.Sheet != "" {
+				rng = c.Sheet + "!" + rng
+			}
+			w.Line(fmt.Sprintf("- %s rows=%d cols=%d conf=%.3f hdr=%v", rng, c.Rows, c.Cols, c.Confidence, previewHeader(c.Header, 6)))
 		}
-		text := strings.Join(lines, "\n")
+		for _, warning := range out.Meta.Warnings {
+			w.Line("warning: " + warning)
+		}
+		text := w.String()
+		window := in.MaxScanRows
+		if window <= 0 {
+			window = out.Meta.ScannedRows
+		}
+		out.Meta.BytesReturned = len(text)
+		out.Meta.Limits = &insights.PageLimits{Unit: string(pagination.UnitRows), PageSize: window, MaxPayloadBytes: limits.MaxPayloadBytes}
 		res := mcp.NewToolResultStructured(out, summary)
 		res.Content = []mcp.Content{mcp.NewTextContent(text)}
 		return res, nil
@@ -164,41 +261,49 @@ func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Li
 	profiler := &insights.Profiler{Limits: limits, Mgr: mgr}
 	ps := mcp.NewTool(
 		"profile_schema",
-		mcp.WithDescription("Profile a bounded range to infer column roles (measure, dimension, time, id, target) and run data quality checks (missingness, duplicates, negative values in nonnegative fields, >100% in percent‑like, mixed types). Use this after choosing a table/range to ground downstream analysis. Sampling is bounded by config; errors include VALIDATION (range), INVALID_SHEET, and PROFILING_FAILED."),
+		mcp.WithDescription("Profile a bounded range to infer column roles (measure, dimension, time, id, target) and run data quality checks (missingness, duplicates, negative values in nonnegative fields, >100% in percent‑like, mixed types, constant columns, and columns duplicating an earlier column row for row, checked among the first 24 columns: warnings 'constant' and 'duplicate_of: <col>', plus a clarifying question when a duplicate is a measure). The header row is detected among the first rows of the range unless header_rows is set (0 for none, 2 for two-row headers); meta.header_row and meta.data_start_row report what was used so later calls can skip the same rows. Each column lists up to 3 sampled example values unless examples=false. Use this after choosing a table/range to ground downstream analysis. Sampling is bounded by config; errors include VALIDATION (range), INVALID_SHEET, and PROFILING_FAILED."),
 		mcp.WithInputSchema[insights.ProfileSchemaInput](),
 		mcp.WithOutputSchema[insights.ProfileSchemaOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(ps, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.ProfileSchemaInput) (*mcp.CallToolResult, error) {
 		if msg := validation.ValidateStruct(in); msg != "" {
 			return mcperr.FromText(msg), nil
 		}
 		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, and range are required"), nil
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
 		}
 		out, err := profiler.ProfileSchema(ctx, in)
 		if err != nil {
-			low := strings.ToLower(err.Error())
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if strings.Contains(low, "invalid range") || strings.Contains(low, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
-			}
-			return mcperr.FromText("PROFILING_FAILED: " + err.Error()), nil
+			return mcperr.New(mcperr.ProfilingFailed, err.Error()), nil
 		}
 		// Build concise text summary
-		summary := fmt.Sprintf("cols=%d sampled_rows=%d truncated=%v", len(out.Columns), out.Meta.SampledRows, out.Meta.Truncated)
-		var lines []string
-		lines = append(lines, summary)
+		runtime.CallStatsFrom(ctx).Record(out.Meta.SampledRows*len(out.Columns), len(out.Columns), out.Meta.Truncated)
+		summary := fmt.Sprintf("cols=%d sampled_rows=%d truncated=%v header_row=%d header_rows=%d data_start_row=%d", len(out.Columns), out.Meta.SampledRows, out.Meta.Truncated, out.Meta.HeaderRow, out.Meta.HeaderRows, out.Meta.DataStartRow)
+		w := newBudgetedWriter(limits)
+		w.Line(summary)
 		max := len(out.Columns)
 		if max > 8 {
 			max = 8
 		}
 		for i := 0; i < max; i++ {
 			c := out.Columns[i]
-			lines = append(lines, fmt.Sprintf("$%d %q role=%s type=%s miss=%.1f%% uniq=%.3f warnings=%v", c.Index, c.Name, c.Role, c.Type, c.MissingPct, c.UniqueRatio, previewHeader(c.Warnings, 3)))
+			line := fmt.Sprintf("$%d %q role=%s type=%s miss=%.1f%% uniq=%.3f warnings=%v", c.Index, c.Name, c.Role, c.Type, c.MissingPct, c.UniqueRatio, previewHeader(c.Warnings, 3))
+			if len(c.Examples) > 0 {
+				ex := make([]string, len(c.Examples))
+				for j, v := range c.Examples {
+					ex[j] = fmt.Sprintf("%q", truncateText(v, 16))
+				}
+				line += " e.g. " + strings.Join(ex, ", ")
+			}
+			w.Line(line)
 		}
-		text := strings.Join(lines, "\n")
+		text := w.String()
+		out.Meta.BytesReturned = len(text)
+		out.Meta.Limits = &insights.PageLimits{Unit: string(pagination.UnitRows), PageSize: out.Meta.MaxSample, MaxPayloadBytes: limits.MaxPayloadBytes}
 		res := mcp.NewToolResultStructured(out, summary)
 		res.Content = []mcp.Content{mcp.NewTextContent(text)}
 		return res, nil
@@ -209,100 +314,355 @@ func RegisterInsightsTools(s *server.MCPServer, reg *Registry, limits runtime.Li
 	composer := &insights.Composer{Limits: limits, Mgr: mgr}
 	cs := mcp.NewTool(
 		"composition_shift",
-		mcp.WithDescription("Compute share‑of‑total by group across two periods and highlight mix shifts in percentage points. Accepts 1‑based indices for dimension/measure (and optional time), detects baseline/current periods when not provided, and caps results to Top‑N with the rest grouped into 'Other'. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithDescription("Compute share‑of‑total by group across two periods and highlight mix shifts in percentage points. Accepts 1‑based indices for dimension/measure (and optional time), detects baseline/current periods when not provided (the last two distinct period values), and caps results to Top‑N with the rest grouped into 'Other'. Set granularity (day/week/month/quarter/year, or auto from date spacing) to roll dates or Excel serial dates up to periods such as 2024-01 or 2024-Q1 first; period_baseline/period_current then name bucket labels. Either side may list periods (2024-01,2024-02) or give an inclusive range (2024-01..2024-03) whose totals are summed before shares are computed, e.g. to compare quarters from monthly rows; the two sets must not overlap. Limits cap processed cells; errors include VALIDATION (range/indices, unknown or overlapping periods), INVALID_SHEET, and ANALYSIS_FAILED."),
 		mcp.WithInputSchema[insights.CompositionShiftInput](),
 		mcp.WithOutputSchema[insights.CompositionShiftOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(cs, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.CompositionShiftInput) (*mcp.CallToolResult, error) {
 		if msg := validation.ValidateStruct(in); msg != "" {
 			return mcperr.FromText(msg), nil
 		}
 		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, and range are required"), nil
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
 		}
 		out, err := composer.CompositionShift(ctx, in)
 		if err != nil {
-			low := strings.ToLower(err.Error())
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
-			}
-			if strings.Contains(low, "invalid range") || strings.Contains(low, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			return mcperr.FromText("ANALYSIS_FAILED: " + err.Error()), nil
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
 		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Groups), out.Meta.Truncated)
 		summary := fmt.Sprintf("periods=[%s→%s] groups=%d topN=%d truncated=%v", out.PeriodBaseline, out.PeriodCurrent, len(out.Groups), out.TopN, out.Meta.Truncated)
+		if len(out.BaselinePeriods) > 0 {
+			summary += fmt.Sprintf(" baselinePeriods=%d currentPeriods=%d", len(out.BaselinePeriods), len(out.CurrentPeriods))
+		}
 		res := mcp.NewToolResultStructured(out, summary)
 		res.Content = []mcp.Content{mcp.NewTextContent(summary)}
 		return res, nil
 	}))
 	reg.Register(cs)
 
+	// variance_bridge
+	vb := mcp.NewTool(
+		"variance_bridge",
+		mcp.WithDescription("Explain why a measure total changed between two periods: returns baseline and current totals, the total delta, and each group's absolute delta with its percent of the total delta and rank, split into positive and negative contributors. The Top‑N groups by absolute delta are listed; the rest are combined into 'Other'. Groups present in only one period count as zero in the other. When the total delta is zero, shares_undefined=true and percentages are omitted. Accepts 1‑based dimension, measure, and time indices; periods default to the last two detected (like composition_shift, which reports share-of-total shifts instead); granularity rolls dates up to week/month/quarter/year buckets first. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithInputSchema[insights.VarianceBridgeInput](),
+		mcp.WithOutputSchema[insights.VarianceBridgeOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(vb, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.VarianceBridgeInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
+		}
+		out, err := composer.VarianceBridge(ctx, in)
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
+		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Positive)+len(out.Negative), out.Meta.Truncated)
+		summary := fmt.Sprintf("periods=[%s→%s] baseline=%.2f current=%.2f delta=%+.2f positive=%d negative=%d other=%d truncated=%v", out.PeriodBaseline, out.PeriodCurrent, out.TotalBaseline, out.TotalCurrent, out.TotalDelta, len(out.Positive), len(out.Negative), out.Other.Groups, out.Meta.Truncated)
+		lines := []string{summary}
+		for _, list := range [][]insights.GroupContribution{out.Positive, out.Negative} {
+			for _, g := range list {
+				line := fmt.Sprintf("- #%d %s delta=%+.2f", g.Rank, g.Name, g.Delta)
+				if g.PctOfDelta != nil {
+					line += fmt.Sprintf(" (%.2f%% of delta)", *g.PctOfDelta)
+				}
+				lines = append(lines, line)
+			}
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(vb)
+
 	// concentration_metrics
 	concentrator := &insights.Concentrator{Limits: limits, Mgr: mgr}
 	cm := mcp.NewTool(
 		"concentration_metrics",
-		mcp.WithDescription("Compute Top‑N share and Herfindahl‑Hirschman Index (HHI) for a grouping dimension. Accepts 1‑based indices for dimension and numeric measure within the range; returns Top‑N group shares, 'Other' share, HHI value, and a concentration band. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithDescription("Compute Top‑N share and Herfindahl‑Hirschman Index (HHI) for a grouping dimension. Accepts 1‑based indices for dimension and numeric measure within the range; returns Top‑N group shares, 'Other' share, HHI value, and a concentration band. With time_index (optionally rolled up by granularity day/week/month/quarter/year/auto) it also returns trend: HHI, Top‑N share, and band per period for the most recent max_periods (default 12) periods, plus hhi_delta and top_n_share_delta from the first to the last period; each period × group accumulator counts against the cell limit. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
 		mcp.WithInputSchema[insights.ConcentrationMetricsInput](),
 		mcp.WithOutputSchema[insights.ConcentrationMetricsOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(cm, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.ConcentrationMetricsInput) (*mcp.CallToolResult, error) {
 		if msg := validation.ValidateStruct(in); msg != "" {
 			return mcperr.FromText(msg), nil
 		}
 		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, and range are required"), nil
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
 		}
 		out, err := concentrator.ConcentrationMetrics(ctx, in)
 		if err != nil {
-			low := strings.ToLower(err.Error())
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if strings.Contains(low, "invalid range") || strings.Contains(low, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
-			}
-			return mcperr.FromText("ANALYSIS_FAILED: " + err.Error()), nil
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
 		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Groups), out.Meta.Truncated)
 		summary := fmt.Sprintf("topN=%d HHI=%.3f band=%s groups=%d truncated=%v", out.TopN, out.HHI, out.Band, len(out.Groups), out.Meta.Truncated)
+		text := summary
+		if tr := out.Trend; tr != nil {
+			summary += fmt.Sprintf(" periods=%d hhiDelta=%+.3f topNShareDelta=%+.3f", len(tr.Periods), tr.HHIDelta, tr.TopNShareDelta)
+			lines := []string{summary}
+			for _, p := range tr.Periods {
+				lines = append(lines, fmt.Sprintf("- %s HHI=%.3f topNShare=%.3f band=%s", p.Period, p.HHI, p.TopNShare, p.Band))
+			}
+			text = strings.Join(lines, "\n")
+		}
 		res := mcp.NewToolResultStructured(out, summary)
-		res.Content = []mcp.Content{mcp.NewTextContent(summary)}
+		res.Content = []mcp.Content{mcp.NewTextContent(text)}
 		return res, nil
 	}))
 	reg.Register(cm)
 
+	// pareto_analysis
+	pa := mcp.NewTool(
+		"pareto_analysis",
+		mcp.WithDescription("Aggregate a numeric measure by a grouping dimension, sort groups by total descending, and return the cumulative share curve: for each threshold (default 50/80/95%) the fewest groups needed to reach it, plus the largest groups (up to max_groups) with share and cumulative share. Answers \"how many customers make up 80% of revenue\"; use concentration_metrics for HHI. Accepts 1‑based indices for dimension and numeric measure within the range. Thresholds a curve with negative totals never reaches are omitted. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithInputSchema[insights.ParetoAnalysisInput](),
+		mcp.WithOutputSchema[insights.ParetoAnalysisOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(pa, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.ParetoAnalysisInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
+		}
+		out, err := concentrator.ParetoAnalysis(ctx, in)
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
+		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Groups), out.Meta.Truncated || out.Meta.GroupsTruncated)
+		summary := fmt.Sprintf("groups=%d total=%.2f listed=%d truncated=%v", out.GroupsTotal, out.Total, len(out.Groups), out.Meta.Truncated || out.Meta.GroupsTruncated)
+		lines := []string{summary}
+		for _, t := range out.Thresholds {
+			lines = append(lines, fmt.Sprintf("- %g%% reached by %d groups (%.2f%% of groups, share=%.3f)", t.Percent, t.Groups, t.PctOfGroups, t.AchievedShare))
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(pa)
+
+	// correlate
+	correlator := &insights.Correlator{Limits: limits, Mgr: mgr}
+	co := mcp.NewTool(
+		"correlate",
+		mcp.WithDescription(fmt.Sprintf("Compute a pairwise correlation matrix among numeric columns to see which move together. method=pearson (default, streaming sums) or spearman (rank correlation, robust to outliers and monotonic curves). Accepts 1‑based column_indices within the range (2–%[1]d); omitted means every column when the range has at most %[1]d. Each pair uses only rows where both values are numeric; counts report those rows, and pairs under min_observations (default 10) or with a constant column get a null coefficient and a warning. Returns the matrix keyed by column index and header name plus the strongest pairs by |r|. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED.", insights.MaxCorrelateColumns)),
+		mcp.WithInputSchema[insights.CorrelateInput](),
+		mcp.WithOutputSchema[insights.CorrelateOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(co, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.CorrelateInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
+		}
+		out, err := correlator.Correlate(ctx, in)
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
+		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.TopPairs), out.Meta.Truncated)
+		summary := fmt.Sprintf("method=%s columns=%d rows=%d pairs=%d warnings=%d truncated=%v", out.Method, len(out.Columns), out.Meta.ProcessedRows, len(out.TopPairs), len(out.Warnings), out.Meta.Truncated)
+		names := map[int]string{}
+		for _, c := range out.Columns {
+			names[c.Index] = c.Name
+		}
+		lines := []string{summary}
+		for _, p := range out.TopPairs {
+			lines = append(lines, fmt.Sprintf("- %s × %s r=%+.3f n=%d", names[p.A], names[p.B], p.R, p.N))
+		}
+		for _, w := range out.Warnings {
+			lines = append(lines, "warning: "+w)
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(co)
+
+	// trend_analysis
+	trender := &insights.Trender{Limits: limits, Mgr: mgr}
+	ta := mcp.NewTool(
+		"trend_analysis",
+		mcp.WithDescription("Compute per‑period totals of a numeric measure with absolute and percent change between consecutive periods, plus a least‑squares slope classified as growing, flat, or declining (|slope| under 2% of the mean period total is flat). Accepts 1‑based indices for the time and measure columns and an optional dimension_index for per‑group trends (Top‑N groups by total). Periods are ordered by date when every label parses as one, otherwise lexically; max_periods keeps the most recent. Use when more than two periods matter; composition_shift compares exactly two. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithInputSchema[insights.TrendAnalysisInput](),
+		mcp.WithOutputSchema[insights.TrendAnalysisOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(ta, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.TrendAnalysisInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
+		}
+		out, err := trender.TrendAnalysis(ctx, in)
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
+		}
+		o := out.Overall
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Groups), out.Meta.Truncated || out.Meta.PeriodsTruncated)
+		summary := fmt.Sprintf("periods=%d direction=%s slope=%.3f slope_pct=%.2f groups=%d truncated=%v", len(o.Periods), o.Direction, o.Slope, o.SlopePct, len(out.Groups), out.Meta.Truncated || out.Meta.PeriodsTruncated)
+		lines := []string{summary}
+		for _, p := range o.Periods {
+			line := fmt.Sprintf("- %s total=%.2f", p.Period, p.Total)
+			if p.AbsChange != nil {
+				line += fmt.Sprintf(" abs=%+.2f", *p.AbsChange)
+			}
+			if p.PctChange != nil {
+				line += fmt.Sprintf(" pct=%+.2f%%", *p.PctChange)
+			}
+			lines = append(lines, line)
+		}
+		for _, g := range out.Groups {
+			lines = append(lines, fmt.Sprintf("group %q direction=%s slope=%.3f slope_pct=%.2f", g.Group, g.Direction, g.Slope, g.SlopePct))
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(ta)
+
+	// outlier_detection
+	outliers := &insights.OutlierDetector{Limits: limits, Mgr: mgr}
+	od := mcp.NewTool(
+		"outlier_detection",
+		mcp.WithDescription("Flag unusual values in a numeric column and return the most extreme rows with their row numbers, values, signed scores, and a bounded row snapshot (like filter_data). method=mad (default) scores the modified z‑score 0.6745·(x−median)/MAD with threshold 3.5; iqr scores distance beyond Q1/Q3 in IQRs (Tukey fences, threshold 1.5); zscore uses mean and standard deviation (threshold 3). Accepts a 1‑based measure_index and an optional dimension_index to score each value against its own group; per‑group center, spread, and bounds are returned. Blank and non‑numeric measures are skipped and counted. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithInputSchema[insights.OutlierDetectionInput](),
+		mcp.WithOutputSchema[insights.OutlierDetectionOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(od, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.OutlierDetectionInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
+		}
+		out, err := outliers.DetectOutliers(ctx, in)
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
+		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Outliers), out.Meta.Truncated)
+		summary := fmt.Sprintf("method=%s threshold=%.2f outliers=%d shown=%d rows=%d non_numeric=%d truncated=%v", out.Method, out.Threshold, out.TotalOutliers, len(out.Outliers), out.Meta.ProcessedRows, out.Meta.NonNumeric, out.Meta.Truncated)
+		lines := []string{summary}
+		for _, o := range out.Outliers {
+			line := fmt.Sprintf("- row %d value=%g score=%+.3f %s", o.Row, o.Value, o.Score, o.Direction)
+			if o.Group != "" {
+				line += fmt.Sprintf(" group=%q", o.Group)
+			}
+			lines = append(lines, line)
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(od)
+
 	// funnel_analysis
 	funneler := &insights.Funneler{Limits: limits, Mgr: mgr}
 	fa := mcp.NewTool(
 		"funnel_analysis",
-		mcp.WithDescription("Compute stage and cumulative conversion across ordered funnel stages and identify bottlenecks. Stages are detected from header names when not provided, or specified via 1‑based stage_indices within the range. Use this for pipeline/step data; results include per‑stage and cumulative conversion. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithDescription("Compute stage and cumulative conversion across ordered funnel stages and identify bottlenecks. Stages are detected from header names when not provided, or specified via 1‑based stage_indices within the range. Use this for pipeline/step data; results include per‑stage and cumulative conversion. segment_index (1‑based within the range) breaks the funnel down by a dimension such as device: the max_segments (default 5) values with the largest first‑stage totals get their own stage metrics and bottleneck, the rest are combined into 'Other', and worst_segment names the segment whose bottleneck converts worst. Each new segment's accumulators count against the cell limit. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
 		mcp.WithInputSchema[insights.FunnelAnalysisInput](),
 		mcp.WithOutputSchema[insights.FunnelAnalysisOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(fa, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.FunnelAnalysisInput) (*mcp.CallToolResult, error) {
 		if msg := validation.ValidateStruct(in); msg != "" {
 			return mcperr.FromText(msg), nil
 		}
 		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, and range are required"), nil
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
 		}
 		out, err := funneler.FunnelAnalysis(ctx, in)
 		if err != nil {
-			low := strings.ToLower(err.Error())
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
-			}
-			if strings.Contains(low, "invalid range") || strings.Contains(low, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			return mcperr.FromText("ANALYSIS_FAILED: " + err.Error()), nil
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
 		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Stages), out.Meta.Truncated)
 		summary := fmt.Sprintf("stages=%d bottleneck=%s truncated=%v", len(out.Stages), out.Bottleneck, out.Meta.Truncated)
+		text := summary
+		if in.SegmentIndex > 0 {
+			summary += fmt.Sprintf(" segments=%d worst=%s", out.Meta.SegmentsTotal, out.WorstSegment)
+			lines := []string{summary}
+			for _, seg := range out.Segments {
+				lines = append(lines, fmt.Sprintf("- %s rows=%d bottleneck=%s conversion=%.3f", seg.Value, seg.Rows, seg.Bottleneck, seg.BottleneckConversion))
+			}
+			text = strings.Join(lines, "\n")
+		}
 		res := mcp.NewToolResultStructured(out, summary)
-		res.Content = []mcp.Content{mcp.NewTextContent(summary)}
+		res.Content = []mcp.Content{mcp.NewTextContent(text)}
 		return res, nil
 	}))
 	reg.Register(fa)
+
+	// cohort_analysis
+	cohorter := &insights.Cohorter{Limits: limits, Mgr: mgr}
+	ca := mcp.NewTool(
+		"cohort_analysis",
+		mcp.WithDescription("Build a cohort × period‑offset retention matrix: ids are grouped into cohorts by the bucket of their cohort date (e.g. signup month), and each offset counts distinct ids active that many buckets later, with counts and percentages of cohort size. Accepts 1‑based id_index, cohort_index, and activity_index within the range and granularity day/week/month (default)/quarter/year; dates may be text or Excel serial numbers. Returns the most recent max_cohorts cohorts and offsets 0..max_offsets‑1; rows with blank ids or unparseable dates are skipped and counted. Distinct counts are exact up to 5000 ids per cell and estimated (~3% error, approximate=true) beyond. Use funnel_analysis for stage conversion. Limits cap processed cells; errors include VALIDATION (range/indices), INVALID_SHEET, and ANALYSIS_FAILED."),
+		mcp.WithInputSchema[insights.CohortAnalysisInput](),
+		mcp.WithOutputSchema[insights.CohortAnalysisOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(ca, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in insights.CohortAnalysisInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.Sheet) == "" || strings.TrimSpace(in.Range) == "" {
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
+		}
+		out, err := cohorter.CohortAnalysis(ctx, in)
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.New(mcperr.AnalysisFailed, err.Error()), nil
+		}
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Cohorts), out.Meta.Truncated || out.Meta.CohortsTruncated || out.Meta.OffsetsTruncated)
+		summary := fmt.Sprintf("granularity=%s cohorts=%d offsets=%d rows=%d skipped=%d approximate=%v truncated=%v", out.Granularity, len(out.Cohorts), out.Offsets, out.Meta.ProcessedRows, out.Meta.SkippedRows, out.Meta.Approximate, out.Meta.Truncated || out.Meta.CohortsTruncated || out.Meta.OffsetsTruncated)
+		lines := []string{summary}
+		for _, c := range out.Cohorts {
+			pcts := make([]string, len(c.Retention))
+			for i, p := range c.Retention {
+				pcts[i] = fmt.Sprintf("%.1f%%", p)
+			}
+			lines = append(lines, fmt.Sprintf("- %s size=%d %s", c.Cohort, c.Size, strings.Join(pcts, " ")))
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(ca)
 }
 
 // previewHeader returns a bounded preview slice for compact summaries.
@@ -327,3 +687,12 @@ func truncateText(s string, max int) string {
 	}
 	return string(r[:max]) + "…"
 }
+
+// scanColumns names the columns a detect_tables window covers, e.g. C:H;
+// its cursors carry it as their range.
+func scanColumns(startCol, cols int) string {
+	startCol = max(startCol, 1)
+	first, _ := excelize.ColumnNumberToName(startCol)
+	last, _ := excelize.ColumnNumberToName(startCol + cols - 1)
+	return first + ":" + last
+}
@@ -0,0 +1,213 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+const (
+	// maxListedSessions caps list_insight_sessions output.
+	maxListedSessions = 50
+	// maxSessionThoughts caps the thoughts get_insight_session returns.
+	maxSessionThoughts = 50
+	// maxSessionThoughtRunes caps each thought's text in get_insight_session.
+	maxSessionThoughtRunes = 500
+)
+
+// ListInsightSessionsInput is empty; list_insight_sessions takes no parameters.
+type ListInsightSessionsInput struct{}
+
+// InsightSessionInfo describes one sequential_insights session.
+type InsightSessionInfo struct {
+	SessionID string `json:"session_id"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Thoughts  int    `json:"thoughts" jsonschema_description:"Thoughts retained in the session history"`
+	Branches  int    `json:"branches"`
+	InMemory  bool   `json:"in_memory" jsonschema_description:"False when the session is only in the persistence directory"`
+}
+
+// ListInsightSessionsOutput lists sessions, most recently updated first.
+type ListInsightSessionsOutput struct {
+	Sessions  []InsightSessionInfo `json:"sessions"`
+	Total     int                  `json:"total"`
+	Truncated bool                 `json:"truncated" jsonschema_description:"More sessions exist than were listed"`
+}
+
+// GetInsightSessionInput identifies a session to read.
+type GetInsightSessionInput struct {
+	SessionID string `json:"session_id" validate:"required" jsonschema_description:"Session id from sequential_insights or list_insight_sessions"`
+}
+
+// InsightSessionThought is one recorded thought, with its text bounded.
+type InsightSessionThought struct {
+	ThoughtNumber     int                    `json:"thought_number"`
+	TotalThoughts     int                    `json:"total_thoughts"`
+	Thought           string                 `json:"thought"`
+	NextThoughtNeeded bool                   `json:"next_thought_needed"`
+	RevisesThought    int                    `json:"revises_thought,omitempty"`
+	BranchFromThought int                    `json:"branch_from_thought,omitempty"`
+	BranchID          string                 `json:"branch_id,omitempty"`
+	Hints             []string               `json:"hints,omitempty"`
+	Observations      []insights.Observation `json:"observations,omitempty"`
+}
+
+// GetInsightSessionOutput is the bounded history of one session.
+type GetInsightSessionOutput struct {
+	SessionID     string                  `json:"session_id"`
+	Objective     string                  `json:"objective,omitempty"`
+	CreatedAt     string                  `json:"created_at"`
+	UpdatedAt     string                  `json:"updated_at"`
+	Branches      []string                `json:"branches"`
+	Thoughts      []InsightSessionThought `json:"thoughts" jsonschema_description:"Most recent thoughts, oldest first"`
+	TotalThoughts int                     `json:"total_thoughts" jsonschema_description:"Thoughts retained in the session history"`
+	Truncated     bool                    `json:"truncated" jsonschema_description:"Older thoughts or long thought text were cut"`
+}
+
+// DeleteInsightSessionInput identifies a session to delete.
+type DeleteInsightSessionInput struct {
+	SessionID string `json:"session_id" validate:"required" jsonschema_description:"Session id to delete"`
+}
+
+// DeleteInsightSessionOutput confirms the deleted session.
+type DeleteInsightSessionOutput struct {
+	SessionID string `json:"session_id"`
+	Deleted   bool   `json:"deleted"`
+}
+
+// registerInsightSessionTools registers list_insight_sessions,
+// get_insight_session, and delete_insight_session over sessions.
+func registerInsightSessionTools(s *server.MCPServer, reg *Registry, sessions *insights.SessionStore) {
+	list := mcp.NewTool(
+		"list_insight_sessions",
+		mcp.WithDescription(fmt.Sprintf("List sequential_insights sessions, most recently updated first: session id, created/updated timestamps (RFC 3339, UTC), and thought and branch counts. Includes sessions saved under MCPXCEL_SESSION_DIR that are not loaded yet. At most %d sessions are listed.", maxListedSessions)),
+		mcp.WithInputSchema[ListInsightSessionsInput](),
+		mcp.WithOutputSchema[ListInsightSessionsOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(list, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ListInsightSessionsInput) (*mcp.CallToolResult, error) {
+		infos := sessions.List()
+		out := ListInsightSessionsOutput{Total: len(infos)}
+		if len(infos) > maxListedSessions {
+			infos = infos[:maxListedSessions]
+			out.Truncated = true
+		}
+		out.Sessions = make([]InsightSessionInfo, 0, len(infos))
+		var b strings.Builder
+		fmt.Fprintf(&b, "sessions=%d", out.Total)
+		if out.Truncated {
+			fmt.Fprintf(&b, " (showing %d)", len(infos))
+		}
+		for _, info := range infos {
+			out.Sessions = append(out.Sessions, InsightSessionInfo{
+				SessionID: info.ID,
+				CreatedAt: formatHandleTime(info.CreatedAt),
+				UpdatedAt: formatHandleTime(info.UpdatedAt),
+				Thoughts:  info.Thoughts,
+				Branches:  info.Branches,
+				InMemory:  info.InMemory,
+			})
+			fmt.Fprintf(&b, "\n- %s thoughts=%d branches=%d updated=%s", info.ID, info.Thoughts, info.Branches, formatHandleTime(info.UpdatedAt))
+		}
+		return mcp.NewToolResultStructured(out, b.String()), nil
+	}))
+	reg.Register(list)
+
+	get := mcp.NewTool(
+		"get_insight_session",
+		mcp.WithDescription(fmt.Sprintf("Return the recorded history of one sequential_insights session: thoughts with their numbers, revision and branch markers, and recorded observations, plus branch ids. Only the last %d thoughts are returned and each thought is cut to %d characters. Errors: VALIDATION (unknown session_id).", maxSessionThoughts, maxSessionThoughtRunes)),
+		mcp.WithInputSchema[GetInsightSessionInput](),
+		mcp.WithOutputSchema[GetInsightSessionOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(get, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in GetInsightSessionInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id := strings.TrimSpace(in.SessionID)
+		sess, ok := sessions.Snapshot(id)
+		if !ok {
+			return mcperr.FromText("VALIDATION: session not found"), nil
+		}
+		out := GetInsightSessionOutput{
+			SessionID:     sess.ID,
+			Objective:     sess.Objective,
+			CreatedAt:     formatHandleTime(sess.CreatedAt),
+			UpdatedAt:     formatHandleTime(sess.UpdatedAt),
+			Branches:      make([]string, 0, len(sess.Branches)),
+			TotalThoughts: len(sess.Thoughts),
+		}
+		for b := range sess.Branches {
+			out.Branches = append(out.Branches, b)
+		}
+		sort.Strings(out.Branches)
+		thoughts := sess.Thoughts
+		if len(thoughts) > maxSessionThoughts {
+			thoughts = thoughts[len(thoughts)-maxSessionThoughts:]
+			out.Truncated = true
+		}
+		out.Thoughts = make([]InsightSessionThought, 0, len(thoughts))
+		var b strings.Builder
+		fmt.Fprintf(&b, "Session %s thoughts=%d branches=%d updated=%s", out.SessionID, out.TotalThoughts, len(out.Branches), out.UpdatedAt)
+		for _, t := range thoughts {
+			text := truncateText(t.Thought, maxSessionThoughtRunes)
+			if text != t.Thought {
+				out.Truncated = true
+			}
+			out.Thoughts = append(out.Thoughts, InsightSessionThought{
+				ThoughtNumber:     t.ThoughtNumber,
+				TotalThoughts:     t.TotalThoughts,
+				Thought:           text,
+				NextThoughtNeeded: t.NextThoughtNeeded,
+				RevisesThought:    t.RevisesThought,
+				BranchFromThought: t.BranchFromThought,
+				BranchID:          t.BranchID,
+				Hints:             t.Hints,
+				Observations:      t.Observations,
+			})
+			label := fmt.Sprintf("%d/%d", t.ThoughtNumber, t.TotalThoughts)
+			if t.BranchID != "" {
+				label += " [" + t.BranchID + "]"
+			}
+			fmt.Fprintf(&b, "\n- %s %s", label, truncateText(t.Thought, 160))
+			for _, o := range t.Observations {
+				fmt.Fprintf(&b, "\n  · %s: %s", o.Tool, truncateText(o.Summary, 120))
+			}
+		}
+		if len(thoughts) < out.TotalThoughts {
+			fmt.Fprintf(&b, "\n(showing last %d thoughts)", len(thoughts))
+		}
+		res := mcp.NewToolResultStructured(out, fmt.Sprintf("session %s thoughts=%d", out.SessionID, out.TotalThoughts))
+		res.Content = []mcp.Content{mcp.NewTextContent(b.String())}
+		return res, nil
+	}))
+	reg.Register(get)
+
+	del := mcp.NewTool(
+		"delete_insight_session",
+		mcp.WithDescription("Delete a sequential_insights session from memory and, when MCPXCEL_SESSION_DIR is set, from disk. Write tool: hidden unless writes are enabled. Errors: VALIDATION (unknown session_id)."),
+		mcp.WithInputSchema[DeleteInsightSessionInput](),
+		mcp.WithOutputSchema[DeleteInsightSessionOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(del, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in DeleteInsightSessionInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id := strings.TrimSpace(in.SessionID)
+		if !sessions.Delete(id) {
+			return mcperr.FromText("VALIDATION: session not found"), nil
+		}
+		out := DeleteInsightSessionOutput{SessionID: id, Deleted: true}
+		return mcp.NewToolResultStructured(out, "deleted session "+id), nil
+	}))
+	reg.Register(del)
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+// documentedFields returns the field names listed as "- a/b[]: ..." bullets
+// under heading in a tool description.
+func documentedFields(t *testing.T, desc, heading string) []string {
+	t.Helper()
+	_, section, ok := strings.Cut(desc, "\n  "+heading+":\n")
+	require.True(t, ok, "description has no %s section", heading)
+	section, _, _ = strings.Cut(section, "\n\n")
+	var fields []string
+	for _, line := range strings.Split(section, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+		names, _, _ := strings.Cut(line, ":")
+		for _, n := range strings.Split(names, "/") {
+			fields = append(fields, strings.TrimSuffix(strings.TrimSpace(n), "[]"))
+		}
+	}
+	return fields
+}
+
+// schemaProperties returns the top-level property names of schema.
+func schemaProperties(t *testing.T, schema json.RawMessage) []string {
+	t.Helper()
+	var s struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(schema, &s))
+	var names []string
+	for n := range s.Properties {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSequentialInsights_SchemaMatchesDescription(t *testing.T) {
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	RegisterInsightsTools(srv, reg, runtime.NewLimits(8, 8), nil)
+	tools, err := reg.Tools(context.Background())
+	require.NoError(t, err)
+	var tool mcp.Tool
+	for _, tl := range tools {
+		if tl.Name == "sequential_insights" {
+			tool = tl
+		}
+	}
+	require.Equal(t, "sequential_insights", tool.Name)
+	b, err := json.Marshal(tool)
+	require.NoError(t, err)
+	var schemas struct {
+		Input  json.RawMessage `json:"inputSchema"`
+		Output json.RawMessage `json:"outputSchema"`
+	}
+	require.NoError(t, json.Unmarshal(b, &schemas))
+
+	// Every documented parameter is accepted and every accepted one is
+	// documented; every documented output is produced.
+	params := documentedFields(t, tool.Description, "Parameters")
+	inputs := schemaProperties(t, schemas.Input)
+	require.ElementsMatch(t, inputs, params)
+	outputs := schemaProperties(t, schemas.Output)
+	for _, f := range documentedFields(t, tool.Description, "Outputs") {
+		require.Contains(t, outputs, f)
+	}
+
+	res := callTool(t, srv, "sequential_insights", map[string]any{
+		"thought": "Find the revenue driver", "thought_number": 1, "total_thoughts": 2, "next_thought_needed": true,
+		"objective": "Explain Q3 revenue", "hints": []string{"focus on Region"}, "needs_more_thoughts": true,
+	})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "Objective: Explain Q3 revenue")
+}
+
+func TestDetectTables_CursorKeepsStartColumn(t *testing.T) {
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	RegisterInsightsTools(srv, New(), runtime.NewLimits(8, 8), mgr)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"noise", "noise"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "C3", &[]string{"Prod", "Qty"}))
+	for r := 4; r <= 7; r++ {
+		require.NoError(t, f.SetSheetRow("Sheet1", "C"+strconv.Itoa(r), &[]any{"X", r}))
+	}
+	path := filepath.Join(t.TempDir(), "window.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	detect := func(args map[string]any) insights.DetectTablesOutput {
+		t.Helper()
+		res := callTool(t, srv, "detect_tables", args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		var out insights.DetectTablesOutput
+		decodeStructured(t, res, &out)
+		return out
+	}
+	out := detect(map[string]any{"path": path, "sheet": "Sheet1", "start_row": 3, "start_col": 3, "max_scan_rows": 3})
+	require.Equal(t, "C3:D5", out.Candidates[0].Range)
+	require.Equal(t, 3, out.Meta.StartCol)
+	require.NotEmpty(t, out.Meta.NextCursor)
+
+	out = detect(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Equal(t, 6, out.Meta.StartRow)
+	require.Equal(t, 3, out.Meta.StartCol)
+	require.Equal(t, "C6:D7", out.Candidates[0].Range)
+
+	res := callTool(t, srv, "detect_tables", map[string]any{"path": path, "all_sheets": true, "start_col": 2})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
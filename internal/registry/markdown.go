@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vinodismyname/mcpxcel/config"
+)
+
+// maxMarkdownCellWidth bounds the caller-supplied cell_width.
+const maxMarkdownCellWidth = 1000
+
+// markdownCellWidth returns the requested truncation width or the default.
+func markdownCellWidth(w int) int {
+	if w <= 0 {
+		return config.DefaultMarkdownCellWidth
+	}
+	if w > maxMarkdownCellWidth {
+		return maxMarkdownCellWidth
+	}
+	return w
+}
+
+// cellWidthFor returns the width to record in a cursor: only markdown pages
+// carry it.
+func cellWidthFor(enc string, width int) int {
+	if enc != "markdown" {
+		return 0
+	}
+	return width
+}
+
+// renderMarkdownTable renders rows as a GitHub-flavored table whose header is
+// rows[0]. Cells are escaped and truncated to width characters. Data rows are
+// added while the output stays within budget bytes (budget <= 0 disables the
+// check); the header is always emitted. It returns the table and how many of
+// rows, counting the header row, were rendered.
+func renderMarkdownTable(rows [][]string, width, budget int) (string, int) {
+	if len(rows) == 0 {
+		return "", 0
+	}
+	ncols := 1
+	for _, r := range rows {
+		if len(r) > ncols {
+			ncols = len(r)
+		}
+	}
+	var b strings.Builder
+	writeMarkdownRow(&b, rows[0], ncols, width)
+	b.WriteByte('|')
+	for i := 0; i < ncols; i++ {
+		b.WriteString(" --- |")
+	}
+	b.WriteByte('\n')
+
+	used := 1
+	var line strings.Builder
+	for _, r := range rows[1:] {
+		line.Reset()
+		writeMarkdownRow(&line, r, ncols, width)
+		if budget > 0 && b.Len()+line.Len() > budget {
+			break
+		}
+		b.WriteString(line.String())
+		used++
+	}
+	return b.String(), used
+}
+
+// writeMarkdownRow writes one table row padded to ncols cells.
+func writeMarkdownRow(b *strings.Builder, row []string, ncols, width int) {
+	b.WriteByte('|')
+	for i := 0; i < ncols; i++ {
+		b.WriteByte(' ')
+		if i < len(row) {
+			b.WriteString(markdownCell(row[i], width))
+		}
+		b.WriteString(" |")
+	}
+	b.WriteByte('\n')
+}
+
+// markdownCell flattens line breaks, truncates to width characters with an
+// ellipsis, and escapes pipes so the value stays inside its column.
+func markdownCell(v string, width int) string {
+	v = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(v)
+	if utf8.RuneCountInString(v) > width {
+		r := []rune(v)
+		v = string(r[:width-1]) + "…"
+	}
+	return strings.ReplaceAll(v, "|", `\|`)
+}
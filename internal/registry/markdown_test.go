@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestRenderMarkdownTable(t *testing.T) {
+	rows := [][]string{{"Name", "Note"}, {"a|b", "line1\nline2"}, {"long", "abcdefghij"}, {"short"}}
+	got, used := renderMarkdownTable(rows, 6, 0)
+	require.Equal(t, 4, used)
+	want := "| Name | Note |\n" +
+		"| --- | --- |\n" +
+		"| a\\|b | line1… |\n" +
+		"| long | abcde… |\n" +
+		"| short |  |\n"
+	require.Equal(t, want, got)
+
+	// A budget that fits the header and one data row stops before the second.
+	header, _ := renderMarkdownTable(rows[:1], 6, 0)
+	got, used = renderMarkdownTable(rows, 6, len(header)+len("| a\\|b | line1… |\n"))
+	require.Equal(t, 2, used)
+	require.True(t, strings.HasSuffix(got, "| a\\|b | line1… |\n"))
+}
+
+func TestReadRange_MarkdownRespectsPayloadCap(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	require.NoError(t, err)
+	long := strings.Repeat("x", 80)
+	for r := 1; r <= 1500; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, sw.SetRow(cell, []any{fmt.Sprintf("row%d", r), long, long}))
+	}
+	require.NoError(t, sw.Flush())
+	path := filepath.Join(t.TempDir(), "wide.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C1500", "encoding": "markdown", "cell_width": 70})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, "markdown", out.Encoding)
+	require.True(t, out.Meta.Truncated)
+	require.Less(t, out.Meta.Returned, 4500)
+	require.Zero(t, out.Meta.Returned%3, "pages end at row boundaries")
+	text := resultText(t, res)
+	require.LessOrEqual(t, len(text), 128*1024)
+	_, body := splitSummary(t, text)
+	require.True(t, strings.HasPrefix(body, "| row1 | "+strings.Repeat("x", 69)+"… |"))
+
+	// The next page keeps markdown and starts at the first row that was cut.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.True(t, strings.HasPrefix(body, fmt.Sprintf("| row%d |", out.Meta.Returned/3+1)), body[:40])
+}
+
+func TestPreviewSheet_Markdown(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createMergedWorkbook(t)
+
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "rows": 2, "encoding": "markdown"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(PreviewSheetOutput)
+	require.Equal(t, "markdown", out.Encoding)
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, "| Region | Q1 | Q2 |\n| --- | --- | --- |\n| North | 10 | 20 |\n", body)
+
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "encoding": "xml"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
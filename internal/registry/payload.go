@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+)
+
+// payloadSummaryReserve is payload space kept for the summary line that
+// precedes a page of cells.
+const payloadSummaryReserve = 512
+
+// payloadBudget returns the byte budget for the data portion of a page.
+func payloadBudget(maxPayloadBytes int) int {
+	if maxPayloadBytes <= payloadSummaryReserve {
+		return maxPayloadBytes
+	}
+	return maxPayloadBytes - payloadSummaryReserve
+}
+
+// recordPayload sets meta's payload size to the length of text and echoes the
+// page size and payload cap the page was built under.
+func recordPayload(meta *PageMeta, text string, unit pagination.Unit, pageSize, maxPayloadBytes int) {
+	meta.BytesReturned = len(text)
+	meta.Limits = &PageLimits{Unit: unit, PageSize: pageSize, MaxPayloadBytes: maxPayloadBytes}
+}
+
+// jsonCellSize returns the bytes v occupies inside a json.Marshal'd array.
+func jsonCellSize(v any) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// csvCellSize returns the bytes v occupies as one field written by csv.Writer,
+// including any quoting.
+func csvCellSize(v string) int {
+	if v == "" {
+		return 0
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{v})
+	w.Flush()
+	return buf.Len() - 1 // trailing newline
+}
+
+// fitGrid measures how much of a row-major grid encodes within budget bytes
+// as a JSON array of arrays (asJSON) or CSV records. rowLen gives each row's
+// cell count and size each cell's encoded size. It returns the number of
+// complete rows that fit plus the cells of the following row that also fit.
+// A budget <= 0 disables the check.
+func fitGrid(rows int, rowLen func(r int) int, size func(r, c int) int, asJSON bool, budget int) (fullRows, partial int) {
+	if budget <= 0 {
+		return rows, 0
+	}
+	used := 0
+	if asJSON {
+		used = 2 // outer brackets
+	}
+	for r := 0; r < rows; r++ {
+		if asJSON {
+			used += 2 // row brackets
+			if r > 0 {
+				used++ // comma between rows
+			}
+		} else {
+			used++ // record newline
+		}
+		if used > budget {
+			return r, 0
+		}
+		n := rowLen(r)
+		for c := 0; c < n; c++ {
+			used += size(r, c)
+			if c > 0 {
+				used++ // comma between cells
+			}
+			if used > budget {
+				return r, c
+			}
+		}
+	}
+	return rows, 0
+}
+
+// pinnedHeaderSize returns the bytes a header row pinned above a json or csv
+// page adds: one CSV record, or one JSON row (typed when given) plus its comma.
+func pinnedHeaderSize(enc string, header []string, typed []any) int {
+	if enc == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write(header)
+		w.Flush()
+		return buf.Len()
+	}
+	var v any = header
+	if typed != nil {
+		v = typed
+	}
+	return jsonCellSize(v) + 1
+}
+
+// cellsBefore counts the cells in the first rows of grid.
+func cellsBefore[T any](grid [][]T, rows int) int {
+	n := 0
+	for _, row := range grid[:rows] {
+		n += len(row)
+	}
+	return n
+}
+
+// trimGrid keeps the first n cells of grid in row-major order.
+func trimGrid[T any](grid [][]T, n int) [][]T {
+	for r, row := range grid {
+		if n <= len(row) {
+			if n == 0 {
+				return grid[:r]
+			}
+			grid[r] = row[:n]
+			return grid[:r+1]
+		}
+		n -= len(row)
+	}
+	return grid
+}
@@ -0,0 +1,191 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/config"
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestFitGridMatchesEncodedSize(t *testing.T) {
+	grid := [][]string{
+		{"plain", "<html>&", `quote "inside"`},
+		{"", "line\nbreak", " lead"},
+		{"ünïcødé", `\.`, strings.Repeat("x", 40)},
+	}
+	rowLen := func(r int) int { return len(grid[r]) }
+	encodeJSON := func(g [][]string) int { b, _ := json.Marshal(g); return len(b) }
+	encodeCSV := func(g [][]string) int {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.WriteAll(g)
+		return buf.Len()
+	}
+	full := encodeJSON(grid)
+	fr, p := fitGrid(len(grid), rowLen, func(r, c int) int { return jsonCellSize(grid[r][c]) }, true, full)
+	require.Equal(t, 3, fr)
+	require.Zero(t, p)
+	fr, p = fitGrid(len(grid), rowLen, func(r, c int) int { return jsonCellSize(grid[r][c]) }, true, full-1)
+	require.Equal(t, 2, fr)
+	require.Equal(t, 2, p)
+
+	full = encodeCSV(grid)
+	fr, _ = fitGrid(len(grid), rowLen, func(r, c int) int { return csvCellSize(grid[r][c]) }, false, full)
+	require.Equal(t, 3, fr)
+	for budget := 1; budget < full; budget++ {
+		fr, p = fitGrid(len(grid), rowLen, func(r, c int) int { return csvCellSize(grid[r][c]) }, false, budget)
+		kept := trimGrid(cloneGrid(grid), cellsBefore(grid, fr)+p)
+		require.LessOrEqual(t, encodeCSV(kept), budget+1, "budget %d", budget) // +1: newline of a partial record
+	}
+}
+
+func cloneGrid(g [][]string) [][]string {
+	out := make([][]string, len(g))
+	for i, r := range g {
+		out[i] = append([]string(nil), r...)
+	}
+	return out
+}
+
+func TestReadRange_PayloadCapPaginates(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	// Ten 30 KB cells (about 105 KB once JSON escapes "<") exceed the
+	// payload cap long before the cell limit.
+	big := strings.Repeat("<a", 15_000)
+	for i := 1; i <= 10; i++ {
+		cell, _ := excelize.CoordinatesToCellName(i, 1)
+		require.NoError(t, f.SetCellValue("Sheet1", cell, big))
+	}
+	// Escapes to about 180 KB: larger than the whole budget on its own.
+	require.NoError(t, f.SetCellValue("Sheet1", "A2", strings.Repeat("<", 30_000)))
+	path := filepath.Join(t.TempDir(), "big.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	for _, enc := range []string{"json", "csv"} {
+		args := map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:J1", "encoding": enc}
+		total, pages := 0, 0
+		for {
+			res := callTool(t, srv, "read_range", args)
+			require.False(t, res.IsError, "%s", resultText(t, res))
+			text := resultText(t, res)
+			require.LessOrEqual(t, len(text), config.DefaultMaxPayloadBytes, enc)
+			out := res.StructuredContent.(ReadRangeOutput)
+			require.Positive(t, out.Meta.Returned)
+			total += out.Meta.Returned
+			pages++
+			if !out.Meta.Truncated {
+				break
+			}
+			require.True(t, out.Meta.PayloadCapped)
+			args = map[string]any{"path": path, "cursor": out.Meta.NextCursor}
+		}
+		require.Equal(t, 10, total, enc)
+		require.Greater(t, pages, 1, enc)
+	}
+
+	// A single cell larger than the budget is still returned so paging advances.
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A2:B2"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, 1, out.Meta.Returned)
+	require.True(t, out.Meta.Truncated)
+}
+
+func TestPreviewSheet_PayloadCapEndsAtRowBoundary(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	big := strings.Repeat("x", 30_000)
+	for r := 1; r <= 8; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, f.SetCellValue("Sheet1", cell, big))
+	}
+	path := filepath.Join(t.TempDir(), "rows.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "max_rows": 8})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.LessOrEqual(t, len(resultText(t, res)), config.DefaultMaxPayloadBytes)
+	out := res.StructuredContent.(PreviewSheetOutput)
+	require.Equal(t, 4, out.Meta.Returned)
+	require.True(t, out.Meta.Truncated)
+	require.True(t, out.Meta.PayloadCapped)
+
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(PreviewSheetOutput)
+	require.Equal(t, 4, out.Meta.Returned)
+	require.False(t, out.Meta.Truncated)
+}
+
+func TestPageMetaReportsPayloadAndLimits(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	RegisterFoundationTools(srv, reg, limits, mgr)
+	RegisterInsightsTools(srv, reg, limits, mgr)
+	path := createSalesWorkbook(t, 8)
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+		want PageLimits
+	}{
+		{"preview_sheet", map[string]any{"rows": 3}, PageLimits{Unit: pagination.UnitRows, PageSize: 3}},
+		{"read_range", map[string]any{"range": "A1:B9", "max_cells": 6}, PageLimits{Unit: pagination.UnitCells, PageSize: 6}},
+		{"read_range", map[string]any{"ranges": []string{"A1:A2", "B1:B2"}}, PageLimits{Unit: pagination.UnitCells, PageSize: limits.MaxCellsPerOp}},
+		{"search_data", map[string]any{"query": "North", "max_results": 4}, PageLimits{Unit: pagination.UnitRows, PageSize: 4}},
+		{"filter_data", map[string]any{"predicate": "$2 > 10", "max_rows": 5}, PageLimits{Unit: pagination.UnitRows, PageSize: 5}},
+	} {
+		tc.args["path"], tc.args["sheet"] = path, "Sheet1"
+		res := callTool(t, srv, tc.tool, tc.args)
+		require.False(t, res.IsError, "%s: %s", tc.tool, resultText(t, res))
+		var got struct {
+			Meta PageMeta `json:"meta"`
+		}
+		decodeStructured(t, res, &got)
+		require.Equal(t, len(resultText(t, res)), got.Meta.BytesReturned, tc.tool)
+		tc.want.MaxPayloadBytes = limits.MaxPayloadBytes
+		require.Equal(t, &tc.want, got.Meta.Limits, tc.tool)
+	}
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+		want insights.PageLimits
+	}{
+		{"detect_tables", map[string]any{"max_scan_rows": 5}, insights.PageLimits{Unit: "rows", PageSize: 5}},
+		{"detect_tables", map[string]any{}, insights.PageLimits{Unit: "rows", PageSize: 9}},
+		{"profile_schema", map[string]any{"range": "A1:B9", "max_sample_rows": 4}, insights.PageLimits{Unit: "rows", PageSize: 4}},
+	} {
+		tc.args["path"], tc.args["sheet"] = path, "Sheet1"
+		res := callTool(t, srv, tc.tool, tc.args)
+		require.False(t, res.IsError, "%s: %s", tc.tool, resultText(t, res))
+		var got struct {
+			Meta struct {
+				BytesReturned int                  `json:"bytes_returned"`
+				Limits        *insights.PageLimits `json:"limits"`
+			} `json:"meta"`
+		}
+		decodeStructured(t, res, &got)
+		require.Equal(t, len(resultText(t, res)), got.Meta.BytesReturned, tc.tool)
+		tc.want.MaxPayloadBytes = limits.MaxPayloadBytes
+		require.Equal(t, &tc.want, got.Meta.Limits, tc.tool)
+	}
+}
@@ -0,0 +1,487 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Predicate parsing and evaluation
+// Grammar:
+//   expr := orExpr
+//   orExpr := andExpr { OR andExpr }
+//   andExpr := unaryExpr { AND unaryExpr }
+//   unaryExpr := NOT unaryExpr | primary
+//   primary := comparison | emptiness | '(' expr ')'
+//   comparison := value ( = | == | != | > | < | >= | <= | CONTAINS ) value
+//   emptiness := value IS [NOT] EMPTY
+//   value := $N | number | string | bareword
+// Columns referenced with $N are 1-based absolute column indices. Keywords
+// are case-insensitive; any other bareword is a string value.
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkLParen
+	tkRParen
+	tkAnd
+	tkOr
+	tkNot
+	tkIs
+	tkEmpty
+	tkOp // comparison op or 'contains'
+	tkCol
+	tkString
+	tkNumber
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+}
+
+// predNode is a node of a parsed predicate: predCompare, predEmpty,
+// predAnd, predOr, or predNot. String returns its normalized form: fully
+// parenthesized, with upper-case keywords and every literal quoted.
+type predNode interface {
+	String() string
+}
+
+// predValue is a comparison operand: a 1-based column reference, or a
+// literal (quoted string, bareword, or number) compared as text.
+type predValue struct {
+	col int // 0 for a literal
+	lit string
+}
+
+func (v predValue) String() string {
+	if v.col > 0 {
+		return "$" + strconv.Itoa(v.col)
+	}
+	return strconv.Quote(v.lit)
+}
+
+// resolve returns the operand's text in row; columns past the row are empty.
+func (v predValue) resolve(row []string) string {
+	if v.col == 0 {
+		return v.lit
+	}
+	if v.col <= len(row) {
+		return row[v.col-1]
+	}
+	return ""
+}
+
+type predCompare struct {
+	op          string // =, !=, >, >=, <, <=, contains
+	left, right predValue
+}
+
+func (n predCompare) String() string {
+	op := n.op
+	if op == "contains" {
+		op = "CONTAINS"
+	}
+	return n.left.String() + " " + op + " " + n.right.String()
+}
+
+// predEmpty is value IS EMPTY, or value IS NOT EMPTY when not is set. A
+// value holding only whitespace is empty.
+type predEmpty struct {
+	value predValue
+	not   bool
+}
+
+func (n predEmpty) String() string {
+	if n.not {
+		return n.value.String() + " IS NOT EMPTY"
+	}
+	return n.value.String() + " IS EMPTY"
+}
+
+type predAnd struct{ left, right predNode }
+
+func (n predAnd) String() string { return "(" + n.left.String() + " AND " + n.right.String() + ")" }
+
+type predOr struct{ left, right predNode }
+
+func (n predOr) String() string { return "(" + n.left.String() + " OR " + n.right.String() + ")" }
+
+type predNot struct{ expr predNode }
+
+func (n predNot) String() string { return "NOT " + n.expr.String() }
+
+// compilePredicate compiles a predicate string into an evaluator function.
+func compilePredicate(src string) (func([]string) bool, error) {
+	n, err := parsePredicate(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(row []string) bool { return evalPredicate(n, row) }, nil
+}
+
+// filterPredicateHash binds a filter_data predicate and column scope to a
+// cursor. The predicate is hashed in its normalized form, so spacing,
+// keyword case, quoting, and redundant parentheses do not invalidate a
+// cursor; a predicate that does not parse is hashed as written.
+func filterPredicateHash(predicate string, columns []int) string {
+	if n, err := parsePredicate(predicate); err == nil {
+		predicate = n.String()
+	}
+	return computePredicateHash(predicate, columns)
+}
+
+// parsePredicate parses src into an AST by recursive descent over the
+// grammar above.
+func parsePredicate(src string) (predNode, error) {
+	toks, err := tokenizePredicate(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("invalid expression result")
+	}
+	p := &predParser{toks: toks}
+	n, err := p.orExpr()
+	if err != nil {
+		return nil, err
+	}
+	switch p.peek().kind {
+	case tkEOF:
+		return n, nil
+	case tkRParen:
+		return nil, fmt.Errorf("mismatched parentheses")
+	default:
+		return nil, fmt.Errorf("unexpected token in expression")
+	}
+}
+
+type predParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *predParser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{kind: tkEOF}
+}
+
+func (p *predParser) next() token {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *predParser) orExpr() (predNode, error) {
+	left, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tkOr {
+		p.next()
+		if p.peek().kind == tkEOF {
+			return nil, fmt.Errorf("invalid boolean operands")
+		}
+		right, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = predOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *predParser) andExpr() (predNode, error) {
+	left, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tkAnd {
+		p.next()
+		if p.peek().kind == tkEOF {
+			return nil, fmt.Errorf("invalid boolean operands")
+		}
+		right, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = predAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *predParser) unaryExpr() (predNode, error) {
+	if p.peek().kind != tkNot {
+		return p.primary()
+	}
+	p.next()
+	if p.peek().kind == tkEOF {
+		return nil, fmt.Errorf("invalid NOT operand")
+	}
+	n, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	return predNot{expr: n}, nil
+}
+
+func (p *predParser) primary() (predNode, error) {
+	switch p.peek().kind {
+	case tkLParen:
+		p.next()
+		n, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tkRParen {
+			return nil, fmt.Errorf("mismatched parentheses")
+		}
+		return n, nil
+	case tkCol, tkString, tkNumber:
+		return p.comparison()
+	default:
+		return nil, fmt.Errorf("unexpected token in expression")
+	}
+}
+
+func (p *predParser) comparison() (predNode, error) {
+	left, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tkIs {
+		return p.emptiness(left)
+	}
+	op := p.next()
+	if op.kind != tkOp {
+		return nil, fmt.Errorf("invalid comparison operands")
+	}
+	n := predCompare{op: op.val, left: left}
+	switch op.val {
+	case "==":
+		n.op = "="
+	case "=", "!=", ">", ">=", "<", "<=", "contains":
+	default:
+		return nil, fmt.Errorf("unsupported operator %q", op.val)
+	}
+	if n.right, err = p.value(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// emptiness parses the IS [NOT] EMPTY suffix following v.
+func (p *predParser) emptiness(v predValue) (predNode, error) {
+	p.next()
+	n := predEmpty{value: v}
+	if p.peek().kind == tkNot {
+		p.next()
+		n.not = true
+	}
+	if p.next().kind != tkEmpty {
+		return nil, fmt.Errorf("expected EMPTY after IS")
+	}
+	return n, nil
+}
+
+func (p *predParser) value() (predValue, error) {
+	t := p.next()
+	switch t.kind {
+	case tkString, tkNumber:
+		return predValue{lit: t.val}, nil
+	case tkCol:
+		idx, err := strconv.Atoi(strings.TrimPrefix(t.val, "$"))
+		if err != nil || idx <= 0 {
+			return predValue{}, fmt.Errorf("invalid column index")
+		}
+		return predValue{col: idx}, nil
+	default:
+		return predValue{}, fmt.Errorf("invalid comparison operands")
+	}
+}
+
+// evalPredicate evaluates n against a row of cell strings.
+func evalPredicate(n predNode, row []string) bool {
+	switch n := n.(type) {
+	case predCompare:
+		return compareValues(n.op, n.left.resolve(row), n.right.resolve(row))
+	case predEmpty:
+		return (strings.TrimSpace(n.value.resolve(row)) == "") != n.not
+	case predAnd:
+		return evalPredicate(n.left, row) && evalPredicate(n.right, row)
+	case predOr:
+		return evalPredicate(n.left, row) || evalPredicate(n.right, row)
+	case predNot:
+		return !evalPredicate(n.expr, row)
+	default:
+		return false
+	}
+}
+
+// compareValues applies a comparison operator. = and != compare text
+// exactly, contains is case-insensitive, and ordering operators compare
+// numbers (thousands separators allowed) and are false when either side is
+// not a number.
+func compareValues(op, l, r string) bool {
+	switch op {
+	case "=":
+		return l == r
+	case "!=":
+		return l != r
+	case "contains":
+		return strings.Contains(strings.ToLower(l), strings.ToLower(r))
+	}
+	ln, lok := predicateNumber(l)
+	rn, rok := predicateNumber(r)
+	if !lok || !rok {
+		return false
+	}
+	switch op {
+	case ">":
+		return ln > rn
+	case ">=":
+		return ln >= rn
+	case "<":
+		return ln < rn
+	case "<=":
+		return ln <= rn
+	}
+	return false
+}
+
+func predicateNumber(s string) (float64, bool) {
+	s = strings.ReplaceAll(s, ",", "")
+	if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+		return v, true
+	}
+	return 0, false
+}
+
+func tokenizePredicate(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		ch := s[i]
+		// whitespace
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' {
+			i++
+			continue
+		}
+		// parentheses
+		if ch == '(' {
+			toks = append(toks, token{kind: tkLParen, val: "("})
+			i++
+			continue
+		}
+		if ch == ')' {
+			toks = append(toks, token{kind: tkRParen, val: ")"})
+			i++
+			continue
+		}
+		// operators: >= <= != == = > <
+		if ch == '>' || ch == '<' || ch == '!' || ch == '=' {
+			if i+1 < len(s) {
+				pair := s[i : i+2]
+				switch pair {
+				case ">=", "<=", "!=", "==":
+					toks = append(toks, token{kind: tkOp, val: pair})
+					i += 2
+					continue
+				}
+			}
+			// single-char ops
+			toks = append(toks, token{kind: tkOp, val: string(ch)})
+			i++
+			continue
+		}
+		// column ref: $N
+		if ch == '$' {
+			j := i + 1
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid column reference at %d", i)
+			}
+			toks = append(toks, token{kind: tkCol, val: s[i:j]})
+			i = j
+			continue
+		}
+		// string literal '...' or "..."
+		if ch == '\'' || ch == '"' {
+			quote := ch
+			j := i + 1
+			var b strings.Builder
+			for j < len(s) {
+				if s[j] == '\\' && j+1 < len(s) {
+					b.WriteByte(s[j+1])
+					j += 2
+					continue
+				}
+				if s[j] == quote {
+					break
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			if j >= len(s) || s[j] != quote {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			toks = append(toks, token{kind: tkString, val: b.String()})
+			i = j + 1
+			continue
+		}
+		// identifier: AND OR NOT IS EMPTY CONTAINS (case-insensitive)
+		if isAlpha(ch) {
+			j := i + 1
+			for j < len(s) && (isAlphaNum(s[j]) || s[j] == '_') {
+				j++
+			}
+			word := strings.ToUpper(s[i:j])
+			switch word {
+			case "AND":
+				toks = append(toks, token{kind: tkAnd, val: word})
+			case "OR":
+				toks = append(toks, token{kind: tkOr, val: word})
+			case "NOT":
+				toks = append(toks, token{kind: tkNot, val: word})
+			case "IS":
+				toks = append(toks, token{kind: tkIs, val: word})
+			case "EMPTY":
+				toks = append(toks, token{kind: tkEmpty, val: word})
+			case "CONTAINS":
+				toks = append(toks, token{kind: tkOp, val: "contains"})
+			default:
+				// treat as bareword string value
+				toks = append(toks, token{kind: tkString, val: s[i:j]})
+			}
+			i = j
+			continue
+		}
+		// number literal (digits, optional dot, optional commas)
+		if (ch >= '0' && ch <= '9') || ch == '-' || ch == '+' {
+			j := i + 1
+			for j < len(s) {
+				c := s[j]
+				if (c >= '0' && c <= '9') || c == '.' || c == ',' {
+					j++
+					continue
+				}
+				break
+			}
+			toks = append(toks, token{kind: tkNumber, val: s[i:j]})
+			i = j
+			continue
+		}
+		return nil, fmt.Errorf("unexpected character %q at %d", ch, i)
+	}
+	return toks, nil
+}
+
+func isAlpha(b byte) bool    { return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') }
+func isAlphaNum(b byte) bool { return isAlpha(b) || (b >= '0' && b <= '9') }
@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicateEvaluation(t *testing.T) {
+	for _, tc := range []struct {
+		pred string
+		row  []string
+		want bool
+	}{
+		// Comparisons.
+		{`$1 = "a"`, []string{"a"}, true},
+		{`$1 == "a"`, []string{"a"}, true},
+		{`$1 = "a"`, []string{"A"}, false},
+		{`$1 != "a"`, []string{"b"}, true},
+		{`$1 contains "OO"`, []string{"food"}, true},
+		{`$1 CONTAINS 'x'`, []string{"food"}, false},
+		{`$2 > 100`, []string{"", "1,500"}, true},
+		{`$2 >= -2.5`, []string{"", "-2.5"}, true},
+		{`$2 < 10`, []string{"", "n/a"}, false},
+		{`$2 <= $3`, []string{"", "3", "3.0"}, true},
+		{`100 < $2`, []string{"", "250"}, true},
+		{`$9 = ""`, []string{"a"}, true},
+		{`$1 = 'it\'s'`, []string{"it's"}, true},
+
+		// Barewords are strings; keywords are case-insensitive.
+		{`$1 = foo`, []string{"foo"}, true},
+		{`$1 = andy`, []string{"andy"}, true},
+		{`$1 contains BAR and $2 = x`, []string{"rebar", "x"}, true},
+		{`$1 = orange or $1 = pear`, []string{"pear"}, true},
+
+		// AND binds tighter than OR, both associate left.
+		{`$1 = "a" OR $2 = "b" AND $3 = "c"`, []string{"a", "x", "x"}, true},
+		{`$1 = "a" OR $2 = "b" AND $3 = "c"`, []string{"x", "b", "x"}, false},
+		{`($1 = "a" OR $2 = "b") AND $3 = "c"`, []string{"a", "x", "x"}, false},
+		{`$1 = "a" AND $2 = "b" OR $3 = "c"`, []string{"x", "x", "c"}, true},
+
+		// NOT applies to the comparison or group that follows it.
+		{`NOT $1 = "x"`, []string{"y"}, true},
+		{`NOT $1 = "x"`, []string{"x"}, false},
+		{`NOT $1 = "x" AND $2 = "y"`, []string{"z", "y"}, true},
+		{`NOT $1 = "x" AND $2 = "y"`, []string{"x", "y"}, false},
+		{`$2 = "y" AND NOT $1 = "x"`, []string{"z", "y"}, true},
+		{`NOT ($1 = "x" AND $2 = "y")`, []string{"x", "y"}, false},
+		{`NOT ($1 = "x" AND $2 = "y")`, []string{"x", "z"}, true},
+		{`NOT ($1 = "x" AND $2 = "y")`, []string{"z", "y"}, true},
+		{`NOT ($1 = "x" OR $2 = "y")`, []string{"z", "z"}, true},
+		{`NOT ($1 = "x" OR $2 = "y")`, []string{"z", "y"}, false},
+		{`($1 = "a" AND $4 >= 0.5) OR NOT $5 = "y"`, []string{"b", "", "", "0", "z"}, true},
+		{`($1 = "a" AND $4 >= 0.5) OR NOT $5 = "y"`, []string{"b", "", "", "0", "y"}, false},
+		{`NOT NOT $1 = "x"`, []string{"x"}, true},
+		{`NOT (NOT $1 = "x" OR NOT $2 = "y")`, []string{"x", "y"}, true},
+		{`((($1 = "x")))`, []string{"x"}, true},
+
+		// Emptiness trims whitespace; columns past the row are empty.
+		{`$2 IS EMPTY`, []string{"a", ""}, true},
+		{`$2 is empty`, []string{"a", " \t\u00a0"}, true},
+		{`$2 IS EMPTY`, []string{"a", " b "}, false},
+		{`$9 IS EMPTY`, []string{"a"}, true},
+		{`$2 IS NOT EMPTY`, []string{"a", "  "}, false},
+		{`$2 IS NOT EMPTY`, []string{"a", "0"}, true},
+		{`NOT $2 IS EMPTY`, []string{"a", "0"}, true},
+		{`$1 = "a" AND $2 IS EMPTY`, []string{"a", " "}, true},
+		{`$1 = "a" AND $2 IS EMPTY`, []string{"b", ""}, false},
+		{`$1 IS EMPTY OR $2 IS EMPTY`, []string{"a", ""}, true},
+		{`$1 IS EMPTY OR $2 IS EMPTY AND $3 > 5`, []string{"a", "", "3"}, false},
+		{`($1 IS NOT EMPTY OR $2 IS NOT EMPTY) AND NOT $3 IS EMPTY`, []string{"", "b", "x"}, true},
+		{`"" IS EMPTY`, nil, true},
+	} {
+		eval, err := compilePredicate(tc.pred)
+		require.NoError(t, err, tc.pred)
+		require.Equal(t, tc.want, eval(tc.row), "%s on %q", tc.pred, tc.row)
+	}
+}
+
+func TestPredicateMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		pred string
+		want string
+	}{
+		{``, "invalid expression result"},
+		{`   `, "invalid expression result"},
+		{`$1`, "invalid comparison operands"},
+		{`$1 =`, "invalid comparison operands"},
+		{`= "x"`, "unexpected token in expression"},
+		{`$1 = AND`, "invalid comparison operands"},
+		{`$1 = "x" AND`, "invalid boolean operands"},
+		{`$1 = "x" OR`, "invalid boolean operands"},
+		{`AND $1 = "x"`, "unexpected token in expression"},
+		{`NOT`, "invalid NOT operand"},
+		{`$1 = "x" NOT`, "unexpected token in expression"},
+		{`($1 = "x"`, "mismatched parentheses"},
+		{`$1 = "x")`, "mismatched parentheses"},
+		{`()`, "unexpected token in expression"},
+		{`$1 = "x" $2 = "y"`, "unexpected token in expression"},
+		{`$1 = $2 = $3`, "unexpected token in expression"},
+		{`$1 ! "x"`, `unsupported operator "!"`},
+		{`$ = 1`, "invalid column reference at 0"},
+		{`$0 = 1`, "invalid column index"},
+		{`$1 = "open`, "unterminated string literal"},
+		{`$1 & 2`, `unexpected character '&' at 3`},
+		{`$1 IS`, "expected EMPTY after IS"},
+		{`$1 IS NOT`, "expected EMPTY after IS"},
+		{`$1 IS "x"`, "expected EMPTY after IS"},
+		{`$1 IS EMPTY EMPTY`, "unexpected token in expression"},
+		{`IS EMPTY`, "unexpected token in expression"},
+		{`$1 = empty`, "invalid comparison operands"},
+	} {
+		_, err := compilePredicate(tc.pred)
+		require.Error(t, err, tc.pred)
+		require.Contains(t, err.Error(), tc.want, tc.pred)
+	}
+}
+
+func TestPredicateNormalizedHash(t *testing.T) {
+	base := `($1 = "x" AND $2 > 10) OR NOT $3 contains "y"`
+	n, err := parsePredicate(base)
+	require.NoError(t, err)
+	require.Equal(t, `(($1 = "x" AND $2 > "10") OR NOT $3 CONTAINS "y")`, n.String())
+
+	// The normalized form parses back to itself.
+	again, err := parsePredicate(n.String())
+	require.NoError(t, err)
+	require.Equal(t, n.String(), again.String())
+
+	h := filterPredicateHash(base, []int{2, 1})
+	for _, same := range []string{
+		`  ( $1="x"   and $2>10 )   or not $3 CONTAINS 'y'`,
+		"($1 == x AND $2 > \"10\")\n\tOR NOT ($3 contains y)",
+		`((($1 = "x") AND ($2 > 10))) OR NOT $3 contains "y"`,
+	} {
+		require.Equal(t, h, filterPredicateHash(same, []int{1, 2}), same)
+	}
+	require.NotEqual(t, h, filterPredicateHash(`($1 = "x" AND $2 > 11) OR NOT $3 contains "y"`, []int{1, 2}))
+	require.NotEqual(t, h, filterPredicateHash(`$1 = "x" AND ($2 > 10 OR NOT $3 contains "y")`, []int{1, 2}))
+	require.NotEqual(t, h, filterPredicateHash(base, []int{1}))
+
+	n, err = parsePredicate(`$1 is not empty and $2 IS  EMPTY`)
+	require.NoError(t, err)
+	require.Equal(t, `($1 IS NOT EMPTY AND $2 IS EMPTY)`, n.String())
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+// analysisPrompt describes one workflow prompt: the discovery steps shared by
+// every prompt are followed by steps specific to the goal.
+type analysisPrompt struct {
+	name        string
+	description string
+	goal        string
+	// extraArg is an optional argument beyond path and sheet, with its description.
+	extraArg, extraDesc string
+	// steps are the workflow-specific instructions after profile_schema; %s
+	// in a step is replaced by the extra argument's value or a default hint.
+	steps []string
+}
+
+var analysisPrompts = []analysisPrompt{
+	{
+		name:        "profile_workbook",
+		description: "Profile a workbook: map its sheets and tables, infer column roles and types, and flag data-quality issues.",
+		goal:        "profile the data and report its structure and data-quality issues",
+		steps: []string{
+			"For each numeric measure that profile_schema flags (skew, mixed types, suspicious ranges), call outlier_detection on that column.",
+			"If an identifier column looks like it should be unique, call find_duplicates on it.",
+			"Summarize: tables found, column roles and types, and each quality issue with the evidence from the tool outputs.",
+		},
+	},
+	{
+		name:        "find_change_drivers",
+		description: "Explain what drove a change in a measure between two periods.",
+		goal:        "explain which groups drove the change in a measure between periods",
+		extraArg:    "measure",
+		extraDesc:   "Measure column header to explain (optional; otherwise pick the main numeric measure)",
+		steps: []string{
+			"Using the measure %s, the time column, and the most relevant dimension identified by profile_schema, call trend_analysis to see how the total moved across periods.",
+			"Call variance_bridge for the two periods of interest (default: the last two) to rank the groups by their contribution to the change.",
+			"Call composition_shift on the same columns to check whether the mix between groups shifted.",
+			"Summarize the size of the change and the top positive and negative drivers, citing the numbers returned.",
+		},
+	},
+	{
+		name:        "assess_concentration",
+		description: "Measure how concentrated a measure is across groups such as customers, products, or regions.",
+		goal:        "measure how concentrated a measure is across groups",
+		extraArg:    "dimension",
+		extraDesc:   "Dimension column header to group by (optional; otherwise pick the main categorical column)",
+		steps: []string{
+			"Using the dimension %s and the main numeric measure identified by profile_schema, call concentration_metrics to get Top-N shares, HHI, and the concentration band.",
+			"Call pareto_analysis on the same columns to find how many groups reach 50/80/95% of the total.",
+			"Summarize the concentration level and the groups that dominate the total.",
+		},
+	},
+	{
+		name:        "analyze_retention",
+		description: "Build cohort retention and stage conversion views from activity data.",
+		goal:        "measure retention and conversion from activity data",
+		steps: []string{
+			"Identify the entity id, cohort date (e.g. signup), and activity date columns from profile_schema, then call cohort_analysis.",
+			"If the table has ordered stage columns or a stage field, call funnel_analysis to measure stage-to-stage conversion.",
+			"Summarize retention by cohort and offset, and the weakest funnel stage, citing the returned percentages.",
+		},
+	},
+}
+
+// RegisterPrompts registers parameterized MCP prompts that walk the model
+// through list_structure → detect_tables → profile_schema → an insight tool.
+func RegisterPrompts(s *server.MCPServer, limits runtime.Limits) {
+	for _, ap := range analysisPrompts {
+		opts := []mcp.PromptOption{
+			mcp.WithPromptDescription(ap.description),
+			mcp.WithArgument("path", mcp.RequiredArgument(), mcp.ArgumentDescription("Absolute workbook path inside an allow-listed directory")),
+			mcp.WithArgument("sheet", mcp.ArgumentDescription("Sheet to analyze (optional; otherwise choose from list_structure)")),
+		}
+		if ap.extraArg != "" {
+			opts = append(opts, mcp.WithArgument(ap.extraArg, mcp.ArgumentDescription(ap.extraDesc)))
+		}
+		s.AddPrompt(mcp.NewPrompt(ap.name, opts...), func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			text, err := ap.render(req.Params.Arguments, limits)
+			if err != nil {
+				return nil, err
+			}
+			return mcp.NewGetPromptResult(ap.description, []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+			}), nil
+		})
+	}
+}
+
+// render builds the prompt text for args, which must include path.
+func (ap analysisPrompt) render(args map[string]string, limits runtime.Limits) (string, error) {
+	path := strings.TrimSpace(args["path"])
+	if path == "" {
+		return "", fmt.Errorf("VALIDATION: path argument is required")
+	}
+	sheet := strings.TrimSpace(args["sheet"])
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Use the mcpxcel tools to %s in the workbook %s", ap.goal, path)
+	if sheet != "" {
+		fmt.Fprintf(&b, ", focusing on sheet %q", sheet)
+	}
+	b.WriteString(".\n\nWork step by step, calling sequential_insights between tool calls to record what you learned and your next step:\n")
+
+	steps := []string{
+		fmt.Sprintf("Call list_structure with path=%q to see the sheets, their sizes, headers, and any named tables.", path),
+	}
+	target := "the sheet that holds the data"
+	if sheet != "" {
+		target = fmt.Sprintf("sheet %q", sheet)
+	}
+	steps = append(steps,
+		fmt.Sprintf("Call detect_tables on %s to find the table ranges; use the highest-confidence candidate's range in the steps below.", target),
+		"Call profile_schema on that range to learn each column's role (dimension, measure, time, id) and type; use its 1-based column indices for the analysis tools.",
+	)
+	for _, st := range ap.steps {
+		if strings.Contains(st, "%s") {
+			v := strings.TrimSpace(args[ap.extraArg])
+			if v == "" {
+				v = "you judge most relevant"
+			} else {
+				v = fmt.Sprintf("%q", v)
+			}
+			st = fmt.Sprintf(st, v)
+		}
+		steps = append(steps, st)
+	}
+	for i, st := range steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, st)
+	}
+	fmt.Fprintf(&b, "\nLimits: each call processes at most %d cells, previews return up to %d rows, and responses are capped near %d bytes. If a result is truncated, narrow the range or follow nextCursor instead of reading whole sheets. Base every conclusion on tool output, not assumptions.", limits.MaxCellsPerOp, limits.PreviewRowLimit, limits.MaxPayloadBytes)
+	return b.String(), nil
+}
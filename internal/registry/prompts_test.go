@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+func TestPrompts_Render(t *testing.T) {
+	srv := server.NewMCPServer("test", "0.0.0", server.WithPromptCapabilities(false))
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxCellsPerOp = 4321
+	RegisterPrompts(srv, limits)
+
+	res, _ := rpc(t, srv, "prompts/list", map[string]any{})
+	list := res.(mcp.ListPromptsResult)
+	require.Len(t, list.Prompts, len(analysisPrompts))
+
+	for _, ap := range analysisPrompts {
+		args := map[string]any{"path": "/data/sales.xlsx", "sheet": "Orders"}
+		if ap.extraArg != "" {
+			args[ap.extraArg] = "Revenue"
+		}
+		res, msg := rpc(t, srv, "prompts/get", map[string]any{"name": ap.name, "arguments": args})
+		require.Empty(t, msg, ap.name)
+		got := res.(mcp.GetPromptResult)
+		require.Len(t, got.Messages, 1, ap.name)
+		require.Equal(t, mcp.RoleUser, got.Messages[0].Role)
+		text := got.Messages[0].Content.(mcp.TextContent).Text
+		for _, want := range []string{"/data/sales.xlsx", `"Orders"`, "list_structure", "detect_tables", "profile_schema", "sequential_insights", "4321 cells"} {
+			require.Contains(t, text, want, ap.name)
+		}
+		if ap.extraArg != "" {
+			require.Contains(t, text, `"Revenue"`, ap.name)
+		}
+		require.NotContains(t, text, "%!", ap.name)
+
+		_, msg = rpc(t, srv, "prompts/get", map[string]any{"name": ap.name, "arguments": map[string]any{}})
+		require.Contains(t, msg, "path argument is required", ap.name)
+	}
+
+	res, _ = rpc(t, srv, "prompts/get", map[string]any{"name": "find_change_drivers", "arguments": map[string]any{"path": "/data/sales.xlsx"}})
+	text := res.(mcp.GetPromptResult).Messages[0].Content.(mcp.TextContent).Text
+	require.Contains(t, text, "variance_bridge")
+	require.Contains(t, text, "the sheet that holds the data")
+	require.Contains(t, text, "measure you judge most relevant")
+}
@@ -0,0 +1,302 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+)
+
+// maxReadRanges caps how many ranges one read_range call may list.
+const maxReadRanges = 16
+
+// RangeSection reports one range's share of a multi-range read_range page.
+type RangeSection struct {
+	Range    string `json:"range"`
+	Offset   int    `json:"offset" jsonschema_description:"0-based row-major cell offset within the range where this page starts"`
+	Total    int    `json:"total"`
+	Returned int    `json:"returned"`
+}
+
+// cellWindow is a row-major run of cells read from one rectangle. typed holds
+// the JSON values of a value_mode=typed read. mergedAt holds each merged
+// cell's ordinal so a page cut short at the payload cap can drop flags for
+// cells it did not emit.
+type cellWindow struct {
+	grid     [][]string
+	typed    [][]any
+	details  [][]cellDetail
+	merged   []string
+	mergedAt []int
+	cells    int
+}
+
+// readCellWindow reads up to maxCells cells of the rectangle x1,y1:x2,y2 in
+// row-major order, starting startOffset cells in. Cells covered by a merged
+// region take the anchor value from merges; details, when non-nil, adds a
+// per-cell detail object. values reads each cell in the caller's value mode.
+func readCellWindow(ctx context.Context, f *excelize.File, sheet string, x1, y1, x2, y2, startOffset, maxCells int, merges []mergedRegion, details *cellDetailReader, values *valueReader) (cellWindow, error) {
+	win := cellWindow{grid: make([][]string, 0), details: make([][]cellDetail, 0)}
+	isTyped := values.mode == valueModeTyped
+	cols := x2 - x1 + 1
+	startRow := y1 + startOffset/cols
+	startCol := x1 + startOffset%cols
+	for row := startRow; row <= y2 && win.cells < maxCells; row++ {
+		if ctx.Err() != nil {
+			return win, ctx.Err()
+		}
+		cstart := x1
+		if row == startRow {
+			cstart = startCol
+		}
+		vals := make([]string, 0, x2-cstart+1)
+		var dets []cellDetail
+		var typed []any
+		for col := cstart; col <= x2 && win.cells < maxCells; col++ {
+			if ctx.Err() != nil {
+				return win, ctx.Err()
+			}
+			cellName, _ := excelize.CoordinatesToCellName(col, row)
+			var val string
+			var tv any
+			// Covered cells take the anchor value captured above, as excelize
+			// would return for them anyway, and are flagged so callers can tell
+			// replicated values from stored ones. Raw and typed reads re-read
+			// the anchor in that mode.
+			if mr, ok := findMergedRegion(merges, col, row); ok && (col != mr.x1 || row != mr.y1) {
+				val = mr.value
+				if values.raw() {
+					anchor, _ := excelize.CoordinatesToCellName(mr.x1, mr.y1)
+					val, tv = values.read(anchor)
+				}
+				win.merged = append(win.merged, cellName)
+				win.mergedAt = append(win.mergedAt, win.cells)
+			} else {
+				val, tv = values.read(cellName)
+			}
+			vals = append(vals, val)
+			if isTyped {
+				typed = append(typed, tv)
+			}
+			if details != nil {
+				dets = append(dets, details.read(cellName, val))
+			}
+			win.cells++
+		}
+		win.grid = append(win.grid, vals)
+		if isTyped {
+			win.typed = append(win.typed, typed)
+		}
+		if details != nil {
+			win.details = append(win.details, dets)
+		}
+	}
+	return win, nil
+}
+
+// trim keeps the first n cells of the window.
+func (w *cellWindow) trim(n int) {
+	if n >= w.cells {
+		return
+	}
+	w.cells = n
+	w.grid = trimGrid(w.grid, n)
+	if len(w.typed) > 0 {
+		w.typed = trimGrid(w.typed, n)
+	}
+	if len(w.details) > 0 {
+		w.details = trimGrid(w.details, n)
+	}
+	kept := w.merged[:0]
+	for i, c := range w.merged {
+		if w.mergedAt[i] < n {
+			kept = append(kept, c)
+		}
+	}
+	w.merged = kept
+}
+
+// sheetExists reports whether sheet names a sheet, case-insensitively.
+// GetSheetMap avoids mutating iterators and is safe under the read lock.
+func sheetExists(f *excelize.File, sheet string) bool {
+	for _, name := range f.GetSheetMap() {
+		if strings.EqualFold(name, sheet) {
+			return true
+		}
+	}
+	return false
+}
+
+// multiRangeRead is a read_range call over several ranges, paged as if the
+// ranges were concatenated in order. startRange and startOffset locate the
+// first cell: a range index and a cell offset within that range.
+type multiRangeRead struct {
+	id, canonical string
+	sheet         string
+	ranges        []string
+	startRange    int
+	startOffset   int
+	maxCells      int
+	expandMerged  bool
+	detailMode    bool
+	valueMode     string
+	cursor        *pagination.Cursor
+}
+
+// rangeSectionText is one element of the multi-range text payload.
+type rangeSectionText struct {
+	Range string `json:"range"`
+	Rows  any    `json:"rows"`
+}
+
+// readMultiRange serves a multi-range read_range page. The text payload is a
+// JSON array of {range, rows} sections; the cursor records the range index
+// and the offset within it where the next page starts.
+func (reg *Registry) readMultiRange(ctx context.Context, mgr *workbooks.Manager, maxPayloadBytes, maxCellsPerOp int, q multiRangeRead) (*mcp.CallToolResult, error) {
+	var sections []RangeSection
+	var payload []rangeSectionText
+	var mergedCells []string
+	var meta PageMeta
+	var resolved []string
+	reg.changes.observeWorkbook(ctx, mgr, q.id, q.canonical)
+	err := mgr.WithSheetRead(q.id, q.sheet, func(f *excelize.File, version int64) error {
+		fileMT, fileFP := fileSnapshot(q.canonical)
+		if err := checkCursor(mgr, q.cursor, q.id, version, fileMT, fileFP); err != nil {
+			return err
+		}
+		if !sheetExists(f, q.sheet) {
+			return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+		}
+		type rect struct{ x1, y1, x2, y2, cells int }
+		rects := make([]rect, 0, len(q.ranges))
+		for _, rng := range q.ranges {
+			x1, y1, x2, y2, a1, perr := resolveRange(f, q.sheet, rng)
+			if perr != nil {
+				return perr
+			}
+			if x2 < x1 || y2 < y1 {
+				return fmt.Errorf("%w bounds after parse", mcperr.ErrInvalidRange)
+			}
+			r := rect{x1, y1, x2, y2, (x2 - x1 + 1) * (y2 - y1 + 1)}
+			rects = append(rects, r)
+			resolved = append(resolved, a1)
+			meta.Total += r.cells
+		}
+		if meta.Total > maxCellsPerOp {
+			return mcperr.Errorf(mcperr.PayloadTooLarge, "ranges have %d cells combined, max %d per operation; request fewer or smaller ranges", meta.Total, maxCellsPerOp)
+		}
+		meta.CellDetail = q.detailMode
+		var details *cellDetailReader
+		if q.detailMode {
+			details = newCellDetailReader(f, q.sheet)
+		}
+		values := newValueReader(f, q.sheet, q.valueMode)
+
+		// Read each range in turn until maxCells or the payload cap ends the
+		// page; at least one cell is kept so the cursor advances.
+		budget := payloadBudget(maxPayloadBytes)
+		used := 2 // outer brackets
+		ri, off := q.startRange, q.startOffset
+		for ri < len(rects) && meta.Returned < q.maxCells && !meta.PayloadCapped {
+			r := rects[ri]
+			var merges []mergedRegion
+			if q.expandMerged {
+				var merr error
+				merges, merr = mergedRegionsInRange(f, q.sheet, r.x1, r.y1, r.x2, r.y2)
+				if merr != nil {
+					return merr
+				}
+			}
+			win, werr := readCellWindow(ctx, f, q.sheet, r.x1, r.y1, r.x2, r.y2, off, q.maxCells-meta.Returned, merges, details, values)
+			if werr != nil {
+				return werr
+			}
+			overhead := jsonCellSize(rangeSectionText{Range: resolved[ri], Rows: [][]string{}}) - 2
+			if len(payload) > 0 {
+				overhead++ // comma between sections
+			}
+			size := func(r, c int) int { return jsonCellSize(win.grid[r][c]) }
+			switch {
+			case details != nil:
+				size = func(r, c int) int { return jsonCellSize(win.details[r][c]) }
+			case win.typed != nil:
+				size = func(r, c int) int { return jsonCellSize(win.typed[r][c]) }
+			}
+			if budget > 0 {
+				full, partial := fitGrid(len(win.grid), func(r int) int { return len(win.grid[r]) }, size, true, budget-used-overhead)
+				if keep := cellsBefore(win.grid, full) + partial; keep < win.cells {
+					if keep < 1 && meta.Returned == 0 {
+						keep = 1
+					}
+					meta.PayloadCapped = true
+					win.trim(keep)
+				}
+			}
+			if win.cells == 0 {
+				break
+			}
+			var rows any = win.grid
+			switch {
+			case details != nil:
+				rows = win.details
+			case win.typed != nil:
+				rows = win.typed
+			}
+			payload = append(payload, rangeSectionText{Range: resolved[ri], Rows: rows})
+			used += overhead + jsonCellSize(rows)
+			sections = append(sections, RangeSection{Range: resolved[ri], Offset: off, Total: r.cells, Returned: win.cells})
+			mergedCells = append(mergedCells, win.merged...)
+			meta.Returned += win.cells
+			off += win.cells
+			if off >= r.cells {
+				ri, off = ri+1, 0
+			}
+		}
+
+		runtime.CallStatsFrom(ctx).AddCells(meta.Returned)
+		meta.Truncated = ri < len(rects)
+		if meta.Truncated {
+			next := pagination.Cursor{V: 1, Pt: q.canonical, S: q.sheet, R: resolved[ri], U: pagination.UnitCells, Off: off, Ps: q.maxCells, Mt: fileMT, Fp: fileFP, Hid: q.id, Wbv: version, Em: q.expandMerged, Cd: q.detailMode, Enc: "json", Rs: resolved, Ri: ri, Vm: cursorValueMode(q.valueMode)}
+			token, _ := pagination.EncodeCursor(next)
+			meta.NextCursor = token
+		}
+		return nil
+	})
+	if err != nil {
+		if res := classifyError(err); res != nil {
+			return res, nil
+		}
+		if res := cursorMismatch(err); res != nil {
+			return res, nil
+		}
+		return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+	}
+
+	runtime.CallStatsFrom(ctx).SetResult(meta.Returned, meta.Truncated)
+	if payload == nil {
+		payload = []rangeSectionText{}
+	}
+	text, _ := json.Marshal(payload)
+	out := ReadRangeOutput{Path: q.canonical, Sheet: q.sheet, RangeA1: strings.Join(resolved, ","), Encoding: "json", MergedCells: mergedCells, Sections: sections, Meta: meta}
+	summary := fmt.Sprintf("total=%d returned=%d truncated=%v ranges=%d", meta.Total, meta.Returned, meta.Truncated, len(resolved))
+	if meta.CellDetail {
+		summary += " cellDetail=true"
+	}
+	if q.valueMode != valueModeFormatted {
+		summary += " valueMode=" + q.valueMode
+	}
+	summary += " nextCursor=" + meta.NextCursor
+	body := summary + "\n" + string(text)
+	recordPayload(&out.Meta, body, pagination.UnitCells, q.maxCells, maxPayloadBytes)
+	res := mcp.NewToolResultStructured(out, "range read complete")
+	res.Content = []mcp.Content{mcp.NewTextContent(body)}
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// recordsFactor approximates how much larger a records page is than a bare
+// array of values, since every cell repeats its key; read_range divides its
+// page size by it for encoding=records.
+const recordsFactor = 2
+
+// recordKeys turns a header row into object keys for encoding=records. Blank
+// headers become col_<letter> (x1 is the first column's number) and repeated
+// names get _2, _3, ... suffixes, so every key is unique.
+func recordKeys(header []string, x1 int) []string {
+	keys := make([]string, len(header))
+	used := make(map[string]bool, len(header))
+	next := map[string]int{}
+	for i, h := range header {
+		base := strings.TrimSpace(h)
+		if base == "" {
+			letter, _ := excelize.ColumnNumberToName(x1 + i)
+			base = "col_" + letter
+		}
+		key := base
+		if used[key] {
+			n := max(next[base], 2)
+			for key = base + "_" + strconv.Itoa(n); used[key]; key = base + "_" + strconv.Itoa(n) {
+				n++
+			}
+			next[base] = n + 1
+		}
+		used[key] = true
+		keys[i] = key
+	}
+	return keys
+}
+
+// recordKeysHash binds a records cursor to the keys of its first page.
+func recordKeysHash(keys []string) string {
+	sum := sha1.Sum([]byte(strings.Join(keys, "\x1f")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// renderRecords encodes rows as a JSON array of objects keyed by keys, in
+// column order. Rows are added while the output stays within budget bytes
+// (budget <= 0 disables the check), but the first row is always emitted.
+// typed, when non-nil, supplies the values of a value_mode=typed read in
+// place of rows' text. It returns the payload and how many rows it holds.
+func renderRecords(keys []string, rows [][]string, typed [][]any, budget int) (string, int) {
+	encKeys := make([][]byte, len(keys))
+	for i, k := range keys {
+		encKeys[i], _ = json.Marshal(k)
+	}
+	var b bytes.Buffer
+	b.WriteByte('[')
+	n := 0
+	for r, row := range rows {
+		var rec bytes.Buffer
+		if n > 0 {
+			rec.WriteByte(',')
+		}
+		rec.WriteByte('{')
+		for i, k := range encKeys {
+			if i > 0 {
+				rec.WriteByte(',')
+			}
+			rec.Write(k)
+			rec.WriteByte(':')
+			var v any = ""
+			switch {
+			case typed != nil:
+				v = nil
+				if i < len(typed[r]) {
+					v = typed[r][i]
+				}
+			case i < len(row):
+				v = row[i]
+			}
+			ev, _ := json.Marshal(v)
+			rec.Write(ev)
+		}
+		rec.WriteByte('}')
+		if budget > 0 && n > 0 && b.Len()+rec.Len()+1 > budget {
+			break
+		}
+		b.Write(rec.Bytes())
+		n++
+	}
+	b.WriteByte(']')
+	return b.String(), n
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/tmc/langchaingo/llms"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/insights"
 )
 
 // ToolProvider resolves MCP tool definitions and associates runtime metadata.
@@ -19,15 +21,41 @@ type Registry struct {
 	mu    sync.RWMutex
 	tools map[string]mcp.Tool
 	model llms.Model
+	// changes holds per-session workbook fingerprints for what_changed.
+	changes *ChangeTracker
+	// auditLog records write operations; nil when auditing is disabled.
+	auditLog *audit.Logger
+	// writeFilter and allowList back get_limits; both are optional.
+	writeFilter *WriteToolFilter
+	allowList   AllowList
+	// sessions backs sequential_insights; nil means a memory-only store.
+	sessions *insights.SessionStore
+	// transports lists how the server is reached, for get_server_info.
+	transports []string
+}
+
+// AllowList exposes the configured allow-list roots and file patterns for
+// reporting.
+type AllowList interface {
+	AllowedDirectories() []string
+	WritableDirectories() []string
+	AllowedPatterns() []string
+	WritablePatterns() []string
 }
 
 // New constructs an empty Registry ready for tool population.
 func New() *Registry {
 	return &Registry{
-		tools: map[string]mcp.Tool{},
+		tools:   map[string]mcp.Tool{},
+		changes: NewChangeTracker(0, 0),
 	}
 }
 
+// Changes returns the per-session workbook change tracker.
+func (r *Registry) Changes() *ChangeTracker {
+	return r.changes
+}
+
 // WithModel assigns the configured LLM model used for insight-generating tools.
 func (r *Registry) WithModel(model llms.Model) {
 	r.mu.Lock()
@@ -36,6 +64,37 @@ func (r *Registry) WithModel(model llms.Model) {
 	r.model = model
 }
 
+// SetWriteFilter records the write-tool filter so get_limits can report
+// whether writes are enabled.
+func (r *Registry) SetWriteFilter(f *WriteToolFilter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeFilter = f
+}
+
+// SetAllowList records the allow-list whose roots get_limits reports.
+func (r *Registry) SetAllowList(a AllowList) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.allowList = a
+}
+
+// SetSessionStore installs the sequential_insights session store; call it
+// before RegisterInsightsTools.
+func (r *Registry) SetSessionStore(s *insights.SessionStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = s
+}
+
+// SetTransports records the transports get_server_info reports (stdio,
+// http).
+func (r *Registry) SetTransports(transports ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transports = append([]string(nil), transports...)
+}
+
 // Register stores a tool definition for discovery.
 func (r *Registry) Register(tool mcp.Tool) {
 	r.mu.Lock()
@@ -0,0 +1,248 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// resourceScheme prefixes workbook resource URIs: xlsx:///abs/path.xlsx,
+	// with a #Sheet fragment for a sheet preview.
+	resourceScheme = "xlsx"
+	// maxListedWorkbooks caps the workbooks published in resources/list.
+	maxListedWorkbooks = 200
+	// maxResourceScanEntries caps directory entries visited per refresh so a
+	// large allow-listed tree cannot stall resources/list.
+	maxResourceScanEntries = 20000
+)
+
+// workbookExtensions are the file types published as resources; they match
+// the extensions the security manager accepts.
+var workbookExtensions = map[string]struct{}{".xlsx": {}, ".xlsm": {}, ".xltx": {}, ".xltm": {}, ".csv": {}}
+
+// ResourceProvider publishes allow-listed workbooks as MCP resources. Reading
+// a workbook URI returns its list_structure JSON; a sheet URI (workbook URI
+// plus #SheetName) returns a CSV preview bounded like preview_sheet.
+type ResourceProvider struct {
+	srv    *server.MCPServer
+	reg    *Registry
+	limits runtime.Limits
+	mgr    *workbooks.Manager
+
+	mu     sync.Mutex
+	listed map[string]struct{} // URIs currently registered with srv
+}
+
+// RegisterResources registers the workbook resource template and publishes
+// the workbooks found under the registry's allow-list; call it after
+// SetAllowList. Call Refresh to pick up files added later.
+func RegisterResources(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) *ResourceProvider {
+	p := &ResourceProvider{srv: s, reg: reg, limits: limits, mgr: mgr, listed: map[string]struct{}{}}
+	tmpl := mcp.NewResourceTemplate(
+		resourceScheme+"://{+path}",
+		"Workbook or sheet",
+		mcp.WithTemplateDescription(fmt.Sprintf("Allow-listed workbook by absolute path: returns list_structure JSON. Append #SheetName for a CSV preview of the first %d rows.", limits.PreviewRowLimit)),
+	)
+	s.AddResourceTemplate(tmpl, p.read)
+	p.Refresh(context.Background())
+	return p
+}
+
+// Refresh rescans the allow-listed directories, registering workbooks that
+// appeared and removing ones that disappeared. It returns the number listed.
+func (p *ResourceProvider) Refresh(ctx context.Context) int {
+	p.reg.mu.RLock()
+	allow := p.reg.allowList
+	p.reg.mu.RUnlock()
+	var roots []string
+	if allow != nil {
+		roots = allow.AllowedDirectories()
+		// Walk below each pattern's fixed prefix; Canonicalize filters matches.
+		for _, pat := range allow.AllowedPatterns() {
+			roots = append(roots, security.PatternBase(pat))
+		}
+	}
+
+	found := map[string]string{} // uri -> canonical path
+	visited := 0
+	for _, root := range roots {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			visited++
+			if ctx.Err() != nil || visited > maxResourceScanEntries || len(found) >= maxListedWorkbooks {
+				return filepath.SkipAll
+			}
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if _, ok := workbookExtensions[strings.ToLower(filepath.Ext(path))]; !ok {
+				return nil
+			}
+			canonical, cerr := p.mgr.Canonicalize(path)
+			if cerr != nil {
+				return nil
+			}
+			found[workbookURI(canonical, "")] = canonical
+			return nil
+		})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var stale []string
+	for uri := range p.listed {
+		if _, ok := found[uri]; !ok {
+			stale = append(stale, uri)
+			delete(p.listed, uri)
+		}
+	}
+	if len(stale) > 0 {
+		p.srv.DeleteResources(stale...)
+	}
+	var added []server.ServerResource
+	for uri, canonical := range found {
+		if _, ok := p.listed[uri]; ok {
+			continue
+		}
+		p.listed[uri] = struct{}{}
+		res := mcp.NewResource(uri, filepath.Base(canonical),
+			mcp.WithResourceDescription("Workbook structure (list_structure JSON) for "+canonical+"; append #SheetName for a CSV preview"),
+			mcp.WithMIMEType("application/json"),
+		)
+		added = append(added, server.ServerResource{Resource: res, Handler: p.read})
+	}
+	if len(added) > 0 {
+		p.srv.AddResources(added...)
+	}
+	return len(p.listed)
+}
+
+// read serves workbook and sheet URIs. Every read revalidates the path
+// against the allow-list through the workbook manager.
+func (p *ResourceProvider) read(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	path, sheet, err := parseWorkbookURI(req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+	id, canonical, err := p.mgr.GetOrOpenByPath(ctx, path)
+	if err != nil {
+		return nil, resultError(openFailed(err))
+	}
+	if sheet == "" {
+		out := ListStructureOutput{Path: canonical}
+		err = p.mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+			return collectStructure(ctx, f, &out, false, false, p.limits.MaxCellsPerOp)
+		})
+		if err != nil {
+			return nil, resourceReadError(err, "DISCOVERY_FAILED")
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, MIMEType: "application/json", Text: string(b)}}, nil
+	}
+
+	var text string
+	err = p.mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		var perr error
+		text, perr = p.previewCSV(ctx, f, sheet)
+		return perr
+	})
+	if err != nil {
+		return nil, resourceReadError(err, "PREVIEW_FAILED")
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, MIMEType: "text/csv", Text: text}}, nil
+}
+
+// previewCSV renders the first PreviewRowLimit rows of sheet as CSV, capped
+// at maxPreviewCols columns and the payload budget like preview_sheet.
+func (p *ResourceProvider) previewCSV(ctx context.Context, f *excelize.File, sheet string) (string, error) {
+	r, err := f.Rows(sheet)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	grid := make([][]string, 0, p.limits.PreviewRowLimit)
+	for len(grid) < p.limits.PreviewRowLimit && r.Next() {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		row, cerr := r.Columns()
+		if cerr != nil {
+			return "", cerr
+		}
+		grid = append(grid, columnWindow(row, 1, maxPreviewCols))
+	}
+	if err := r.Error(); err != nil {
+		return "", err
+	}
+	full, _ := fitGrid(len(grid), func(r int) int { return len(grid[r]) }, func(r, c int) int {
+		return csvCellSize(grid[r][c])
+	}, false, payloadBudget(p.limits.MaxPayloadBytes))
+	if full < 1 {
+		full = 1
+	}
+	if full < len(grid) {
+		grid = grid[:full]
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(grid); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// workbookURI builds the resource URI for canonical, with sheet as the
+// fragment when non-empty.
+func workbookURI(canonical, sheet string) string {
+	u := url.URL{Scheme: resourceScheme, Path: filepath.ToSlash(canonical), Fragment: sheet}
+	return u.String()
+}
+
+// parseWorkbookURI splits a workbook resource URI into path and sheet.
+func parseWorkbookURI(uri string) (path, sheet string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != resourceScheme || u.Host != "" || u.Path == "" {
+		return "", "", resultError(mcperr.FromText(fmt.Sprintf("VALIDATION: resource URI must look like %s:///absolute/path.xlsx[#Sheet]", resourceScheme)))
+	}
+	return filepath.FromSlash(u.Path), u.Fragment, nil
+}
+
+// resourceReadError maps a WithRead failure to the error text the matching
+// tool would return, using fallback as the code for unexpected failures.
+func resourceReadError(err error, fallback string) error {
+	if res := classifyError(err); res != nil {
+		return resultError(res)
+	}
+	return resultError(mcperr.FromText(fmt.Sprintf("%s: %v", fallback, err)))
+}
+
+// resultError converts an error tool result into a Go error carrying its
+// text, so resource reads fail with the same codes as tools.
+func resultError(res *mcp.CallToolResult) error {
+	var parts []string
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			parts = append(parts, tc.Text)
+		}
+	}
+	return errors.New(strings.Join(parts, "\n"))
+}
@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+// rpc dispatches a JSON-RPC request and returns its result, or the error
+// message when the server answers with a JSON-RPC error.
+func rpc(t *testing.T, srv *server.MCPServer, method string, params map[string]any) (any, string) {
+	t.Helper()
+	msg, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	require.NoError(t, err)
+	switch resp := srv.HandleMessage(context.Background(), msg).(type) {
+	case mcp.JSONRPCResponse:
+		return resp.Result, ""
+	case mcp.JSONRPCError:
+		return nil, resp.Error.Message
+	default:
+		t.Fatalf("unexpected response: %#v", resp)
+		return nil, ""
+	}
+}
+
+func TestResources_ListAndRead(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	sec, err := security.NewManagerWithModes([]string{root}, nil, nil)
+	require.NoError(t, err)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	mgr.SetPathValidator(sec)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+
+	path := filepath.Join(root, "sub", "Sales Q1.xlsx")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]any{"Region", "Amount"}))
+	for i := 2; i <= 30; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]any{"North", i}))
+	}
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("x"), 0o644))
+	outsidePath := filepath.Join(outside, "secret.xlsx")
+	require.NoError(t, excelize.NewFile().SaveAs(outsidePath))
+
+	srv := server.NewMCPServer("test", "0.0.0", server.WithResourceCapabilities(true, false))
+	reg := New()
+	reg.SetAllowList(sec)
+	limits := runtime.NewLimits(8, 8)
+	limits.PreviewRowLimit = 10
+	p := RegisterResources(srv, reg, limits, mgr)
+
+	res, _ := rpc(t, srv, "resources/list", map[string]any{})
+	list := res.(mcp.ListResourcesResult)
+	require.Len(t, list.Resources, 1)
+	canonical, err := mgr.Canonicalize(path)
+	require.NoError(t, err)
+	uri := workbookURI(canonical, "")
+	require.Equal(t, uri, list.Resources[0].URI)
+	require.Equal(t, "Sales Q1.xlsx", list.Resources[0].Name)
+
+	res, _ = rpc(t, srv, "resources/read", map[string]any{"uri": uri})
+	contents := res.(mcp.ReadResourceResult).Contents
+	require.Len(t, contents, 1)
+	var structure ListStructureOutput
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &structure))
+	require.Equal(t, canonical, structure.Path)
+	require.Len(t, structure.Sheets, 1)
+	require.Equal(t, []string{"Region", "Amount"}, structure.Sheets[0].Headers)
+
+	// Sheet sub-resources are served through the template and bounded by PreviewRowLimit.
+	res, _ = rpc(t, srv, "resources/read", map[string]any{"uri": workbookURI(canonical, "Sheet1")})
+	sheet := res.(mcp.ReadResourceResult).Contents[0].(mcp.TextResourceContents)
+	require.Equal(t, "text/csv", sheet.MIMEType)
+	lines := strings.Split(strings.TrimSpace(sheet.Text), "\n")
+	require.Len(t, lines, 10)
+	require.Equal(t, "Region,Amount", lines[0])
+
+	_, msg := rpc(t, srv, "resources/read", map[string]any{"uri": workbookURI(canonical, "Missing")})
+	require.Contains(t, msg, "INVALID_SHEET")
+	_, msg = rpc(t, srv, "resources/read", map[string]any{"uri": workbookURI(outsidePath, "")})
+	require.NotEmpty(t, msg)
+	require.Contains(t, msg, "OPEN_FAILED")
+
+	// Refresh drops deleted files and picks up new ones.
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, excelize.NewFile().SaveAs(filepath.Join(root, "new.xlsx")))
+	require.Equal(t, 1, p.Refresh(context.Background()))
+	res, _ = rpc(t, srv, "resources/list", map[string]any{})
+	list = res.(mcp.ListResourcesResult)
+	require.Len(t, list.Resources, 1)
+	require.Equal(t, "new.xlsx", list.Resources[0].Name)
+}
@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// maxSearchSuggestions caps the near misses search_data returns when a
+// literal value search finds nothing.
+const maxSearchSuggestions = 5
+
+// maxNearMissQueryRunes is the longest query compared by edit distance;
+// longer queries only suggest values that contain them.
+const maxNearMissQueryRunes = 16
+
+// SearchSuggestion is a near miss for a literal query that matched nothing.
+type SearchSuggestion struct {
+	Cell  string `json:"cell"`
+	Value string `json:"value"`
+}
+
+// searchSheetLiteral streams sheet and returns, in row-major order, the cells
+// whose value equals query exactly. The same pass collects up to
+// maxSearchSuggestions distinct near misses within cols (every column when
+// nil), so a search that finds nothing costs no second scan.
+func searchSheetLiteral(ctx context.Context, f *excelize.File, sheet, query string, cols map[int]struct{}) ([]string, []SearchSuggestion, error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	near := newNearMatcher(query)
+	seen := make(map[string]struct{})
+	var cells []string
+	var suggestions []SearchSuggestion
+	for row := 1; rows.Next(); row++ {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		vals, cerr := rows.Columns()
+		if cerr != nil {
+			return nil, nil, cerr
+		}
+		for i, val := range vals {
+			if val == "" {
+				continue
+			}
+			if val == query {
+				cell, _ := excelize.CoordinatesToCellName(i+1, row)
+				cells = append(cells, cell)
+				continue
+			}
+			if len(suggestions) == maxSearchSuggestions {
+				continue
+			}
+			if _, ok := seen[val]; ok {
+				continue
+			}
+			if cols != nil {
+				if _, ok := cols[i+1]; !ok {
+					continue
+				}
+			}
+			if near.match(val) {
+				seen[val] = struct{}{}
+				cell, _ := excelize.CoordinatesToCellName(i+1, row)
+				suggestions = append(suggestions, SearchSuggestion{Cell: cell, Value: val})
+			}
+		}
+	}
+	return cells, suggestions, rows.Error()
+}
+
+// nearMatcher recognizes values close to a literal query: values containing
+// it ignoring case, or, for short queries, values within a small edit
+// distance of it ignoring case.
+type nearMatcher struct {
+	lower   string
+	runes   []rune
+	maxDist int // 0 disables edit-distance matching
+}
+
+func newNearMatcher(query string) nearMatcher {
+	m := nearMatcher{lower: strings.ToLower(query)}
+	m.runes = []rune(m.lower)
+	switch n := len(m.runes); {
+	case n < 3:
+		// Too short for a typo to be told from a different value.
+	case n <= 7:
+		m.maxDist = 1
+	case n <= maxNearMissQueryRunes:
+		m.maxDist = 2
+	}
+	return m
+}
+
+func (m nearMatcher) match(val string) bool {
+	v := strings.ToLower(strings.TrimSpace(val))
+	if v == "" || m.lower == "" {
+		return false
+	}
+	if strings.Contains(v, m.lower) {
+		return true
+	}
+	if m.maxDist == 0 {
+		return false
+	}
+	if d := utf8.RuneCountInString(v) - len(m.runes); d > m.maxDist || -d > m.maxDist {
+		return false
+	}
+	return editDistance([]rune(v), m.runes) <= m.maxDist
+}
+
+// editDistance returns the edit distance between a and b, counting an
+// insertion, deletion, substitution, or swap of adjacent runes as one edit
+// (optimal string alignment).
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// searchMissHint explains a literal value search that matched nothing and
+// lists its near misses.
+func searchMissHint(suggestions []SearchSuggestion) string {
+	hint := "no exact matches: a literal query must equal the whole cell value, case included; use regex=true with (?i) for partial or case-insensitive matches"
+	if len(suggestions) == 0 {
+		return hint + "; no near misses found"
+	}
+	parts := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		parts = append(parts, fmt.Sprintf("%s (%s)", strconv.Quote(truncateText(s.Value, 60)), s.Cell))
+	}
+	return hint + "; did you mean " + strings.Join(parts, ", ") + "?"
+}
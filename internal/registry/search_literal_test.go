@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNearMatcher(t *testing.T) {
+	require.Equal(t, 0, editDistance([]rune("widget"), []rune("widget")))
+	require.Equal(t, 1, editDistance([]rune("widget"), []rune("widgets")))
+	require.Equal(t, 1, editDistance([]rune("widget"), []rune("wigdet")))
+	require.Equal(t, 2, editDistance([]rune("widget"), []rune("gadget")))
+	require.Equal(t, 3, editDistance([]rune("kitten"), []rune("sitting")))
+	require.Equal(t, 1, editDistance([]rune("café"), []rune("cafe")))
+
+	for _, tc := range []struct {
+		query, value string
+		want         bool
+	}{
+		{"acme", "ACME", true},
+		{"acme", " Acme Corp ", true},
+		{"1000", "1,000", true},
+		{"north", "nroth", true},
+		{"north", "nrohh", false}, // two edits exceed one for five runes
+		{"widget", "widgte", true},
+		{"quarterly", "quartelry", true},
+		{"quarterly", "qaurtelry", true},
+		{"quarterly", "quartz", false},
+		{"widget", "gadget", false},
+		{"ab", "ac", false}, // too short for typos
+		{"ab", "lab", true},
+		{"a rather long query text", "a rather long query test", false},
+		{"a rather long query text", "A RATHER LONG QUERY TEXT!", true},
+		{"acme", "   ", false},
+	} {
+		require.Equal(t, tc.want, newNearMatcher(tc.query).match(tc.value), "%q vs %q", tc.query, tc.value)
+	}
+}
+
+func TestSearchData_Suggestions(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]any{"Customer", "Product", "Region"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]any{"Acme Corp", "Widget", "North"}))
+	require.NoError(t, f.SetSheetRow(sh, "A3", &[]any{"ACME", "Gadget", "north"}))
+	require.NoError(t, f.SetSheetRow(sh, "A4", &[]any{"Acme Corp", "Widgets", "South"}))
+	require.NoError(t, f.SetSheetRow(sh, "A5", &[]any{"Bolt", "acme widget", "East"}))
+	for r := 6; r <= 15; r++ {
+		require.NoError(t, f.SetSheetRow(sh, fmt.Sprintf("A%d", r), &[]any{fmt.Sprintf("Acme %d", r), "Sprocket", "West"}))
+	}
+	path := filepath.Join(t.TempDir(), "customers.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	search := func(args map[string]any) (SearchDataOutput, string) {
+		t.Helper()
+		args["path"], args["sheet"] = path, sh
+		res := callTool(t, srv, "search_data", args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		var out SearchDataOutput
+		decodeStructured(t, res, &out)
+		return out, resultText(t, res)
+	}
+
+	// Case and substring near misses, distinct and capped, in row-major order.
+	out, text := search(map[string]any{"query": "acme"})
+	require.Zero(t, out.Meta.Total)
+	require.Empty(t, out.Results)
+	require.Equal(t, []SearchSuggestion{
+		{Cell: "A2", Value: "Acme Corp"},
+		{Cell: "A3", Value: "ACME"},
+		{Cell: "B5", Value: "acme widget"},
+		{Cell: "A6", Value: "Acme 6"},
+		{Cell: "A7", Value: "Acme 7"},
+	}, out.Suggestions)
+	require.Contains(t, text, "no exact matches")
+	require.Contains(t, text, `did you mean "Acme Corp" (A2), "ACME" (A3)`)
+
+	// Typos of short queries; the column filter also bounds suggestions.
+	out, _ = search(map[string]any{"query": "Widgte", "columns": []int{2}})
+	require.Equal(t, []SearchSuggestion{{Cell: "B2", Value: "Widget"}}, out.Suggestions)
+	out, _ = search(map[string]any{"query": "acme", "columns": []int{3}})
+	require.Empty(t, out.Suggestions)
+	out, text = search(map[string]any{"query": "Zebra"})
+	require.Empty(t, out.Suggestions)
+	require.Contains(t, text, "no near misses found")
+
+	// Exact matches return no suggestions or hint.
+	out, text = search(map[string]any{"query": "ACME"})
+	require.Equal(t, 1, out.Meta.Total)
+	require.Equal(t, "A3", out.Results[0].Cell)
+	require.Empty(t, out.Suggestions)
+	require.NotContains(t, text, "no exact matches")
+
+	// Regex, formula, and range searches skip suggestions.
+	for _, args := range []map[string]any{
+		{"query": "^acme$", "regex": true},
+		{"query": "acme", "scope": "formulas"},
+		{"range_query": map[string]any{"type": "number", "min": 1e9}},
+	} {
+		out, text = search(args)
+		require.Zero(t, out.Meta.Total)
+		require.Empty(t, out.Suggestions)
+		require.NotContains(t, text, "no exact matches")
+	}
+}
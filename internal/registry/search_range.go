@@ -0,0 +1,219 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+)
+
+// SearchRangeQuery matches cells whose number or date falls within optional
+// inclusive bounds; search_data accepts it in place of a string query.
+type SearchRangeQuery struct {
+	Type string `json:"type" validate:"required,oneof=number date" jsonschema_description:"number or date"`
+	Min  any    `json:"min,omitempty" jsonschema_description:"Inclusive lower bound: a number, or a date such as 2024-03-01; omit for no lower bound"`
+	Max  any    `json:"max,omitempty" jsonschema_description:"Inclusive upper bound: a number, or a date such as 2024-03-31 (covers that whole day); omit for no upper bound"`
+}
+
+// searchRange is a parsed SearchRangeQuery. Date bounds are held as times;
+// a date-only max is widened to the end of its day.
+type searchRange struct {
+	date             bool
+	min, max         *float64
+	minTime, maxTime *time.Time
+	text             string // canonical form, e.g. number:1000..2000
+}
+
+// String returns the canonical form carried in cursors and bound into the
+// query hash.
+func (r searchRange) String() string { return r.text }
+
+// parseSearchRange validates q and returns its parsed bounds.
+func parseSearchRange(q SearchRangeQuery) (searchRange, error) {
+	bound := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+	lo, hi := bound(q.Min), bound(q.Max)
+	if lo == "" && hi == "" {
+		return searchRange{}, fmt.Errorf("range_query needs min, max, or both")
+	}
+	r := searchRange{date: q.Type == "date"}
+	if r.date {
+		for _, b := range []struct {
+			name, text string
+			dst        **time.Time
+		}{{"min", lo, &r.minTime}, {"max", hi, &r.maxTime}} {
+			if b.text == "" {
+				continue
+			}
+			t, ok := insights.ParseDate(b.text)
+			if !ok {
+				return searchRange{}, fmt.Errorf("range_query %s %q is not a date; use a form such as 2024-03-01", b.name, b.text)
+			}
+			*b.dst = &t
+		}
+		if r.minTime != nil && r.maxTime != nil && r.maxTime.Before(*r.minTime) {
+			return searchRange{}, fmt.Errorf("range_query max is before min")
+		}
+		r.text = "date:" + dateBound(r.minTime) + ".." + dateBound(r.maxTime)
+		if r.maxTime != nil && dateOnly(*r.maxTime) {
+			end := r.maxTime.Add(24*time.Hour - time.Nanosecond)
+			r.maxTime = &end
+		}
+		return r, nil
+	}
+	for _, b := range []struct {
+		name, text string
+		dst        **float64
+	}{{"min", lo, &r.min}, {"max", hi, &r.max}} {
+		if b.text == "" {
+			continue
+		}
+		v, ok := parseNumber(b.text)
+		if !ok {
+			return searchRange{}, fmt.Errorf("range_query %s %q is not a number", b.name, b.text)
+		}
+		*b.dst = &v
+	}
+	if r.min != nil && r.max != nil && *r.max < *r.min {
+		return searchRange{}, fmt.Errorf("range_query max is below min")
+	}
+	r.text = "number:" + numberBound(r.min) + ".." + numberBound(r.max)
+	return r, nil
+}
+
+// parseSearchRangeText parses the canonical form produced by String.
+func parseSearchRangeText(s string) (searchRange, error) {
+	typ, bounds, ok := strings.Cut(s, ":")
+	lo, hi, ok2 := strings.Cut(bounds, "..")
+	if !ok || !ok2 || (typ != "number" && typ != "date") {
+		return searchRange{}, fmt.Errorf("invalid range query %q", s)
+	}
+	q := SearchRangeQuery{Type: typ}
+	if lo != "" {
+		q.Min = lo
+	}
+	if hi != "" {
+		q.Max = hi
+	}
+	return parseSearchRange(q)
+}
+
+func numberBound(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+// dateBound formats a date bound so insights.ParseDate reads it back.
+func dateBound(t *time.Time) string {
+	switch {
+	case t == nil:
+		return ""
+	case dateOnly(*t):
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+func dateOnly(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// query returns the normalized bounds for output: numbers for a number
+// range, date text for a date range.
+func (r searchRange) query() *SearchRangeQuery {
+	typ, bounds, _ := strings.Cut(r.text, ":")
+	lo, hi, _ := strings.Cut(bounds, "..")
+	q := &SearchRangeQuery{Type: typ}
+	switch {
+	case r.min != nil:
+		q.Min = *r.min
+	case lo != "":
+		q.Min = lo
+	}
+	switch {
+	case r.max != nil:
+		q.Max = *r.max
+	case hi != "":
+		q.Max = hi
+	}
+	return q
+}
+
+// searchSheetRange streams sheet and returns, in row-major order, the cells
+// whose number (including numeric text) or date lies within r. Numbers are
+// read from stored values, so formatting such as currency or percentages does
+// not hide them; booleans and dates never match a number range.
+func searchSheetRange(ctx context.Context, f *excelize.File, sheet string, r searchRange) ([]string, error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types := newCellDetailReader(f, sheet)
+	dates := newStatDateParser(f, sheet)
+	var cells []string
+	for row := 1; rows.Next(); row++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		vals, cerr := rows.Columns()
+		if cerr != nil {
+			return nil, cerr
+		}
+		for i, val := range vals {
+			if strings.TrimSpace(val) == "" {
+				continue
+			}
+			cell, _ := excelize.CoordinatesToCellName(i+1, row)
+			if r.inRange(f, sheet, cell, val, types, dates) {
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return cells, rows.Error()
+}
+
+func (r searchRange) inRange(f *excelize.File, sheet, cell, val string, types *cellDetailReader, dates *statDateParser) bool {
+	if r.date {
+		t, ok := dates.parse(cell, val)
+		return ok && (r.minTime == nil || !t.Before(*r.minTime)) && (r.maxTime == nil || !t.After(*r.maxTime))
+	}
+	var n float64
+	switch types.inferType(cell, val) {
+	case "number":
+		raw, _ := f.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+		v, ok := parseNumber(strings.TrimSpace(raw))
+		if !ok {
+			return false
+		}
+		n = v
+	case "string":
+		v, ok := parseNumber(strings.TrimSpace(val))
+		if !ok {
+			return false
+		}
+		n = v
+	default:
+		return false
+	}
+	return (r.min == nil || n >= *r.min) && (r.max == nil || n <= *r.max)
+}
+
+// rangeText returns r's canonical form, or "" when no range query is set.
+func rangeText(r *searchRange) string {
+	if r == nil {
+		return ""
+	}
+	return r.String()
+}
@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSearchData_RangeQuery(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]any{"Date", "Amount", "Note"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]any{time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), 950, "1500"}))
+	require.NoError(t, f.SetSheetRow(sh, "A3", &[]any{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1000, "n/a"}))
+	require.NoError(t, f.SetSheetRow(sh, "A4", &[]any{time.Date(2024, 3, 31, 18, 30, 0, 0, time.UTC), 2000, true}))
+	require.NoError(t, f.SetSheetRow(sh, "A5", &[]any{"2024-04-01", 2000.5, nil}))
+	path := filepath.Join(t.TempDir(), "ranges.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	search := func(args map[string]any) SearchDataOutput {
+		t.Helper()
+		res := callTool(t, srv, "search_data", args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		var out SearchDataOutput
+		decodeStructured(t, res, &out)
+		return out
+	}
+	cells := func(out SearchDataOutput) []string {
+		got := make([]string, 0, len(out.Results))
+		for _, m := range out.Results {
+			got = append(got, m.Cell)
+		}
+		return got
+	}
+
+	// Numeric text counts; dates and booleans never match a number range.
+	out := search(map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number", "min": 1000, "max": 2000}})
+	require.Equal(t, []string{"C2", "B3", "B4"}, cells(out))
+	require.Equal(t, &SearchRangeQuery{Type: "number", Min: 1000.0, Max: 2000.0}, out.RangeQuery)
+	require.Empty(t, out.Query)
+
+	// Open-ended bounds.
+	out = search(map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number", "min": 2000}})
+	require.Equal(t, []string{"B4", "B5"}, cells(out))
+	require.Nil(t, out.RangeQuery.Max)
+	out = search(map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number", "max": "999"}, "columns": []int{2}})
+	require.Equal(t, []string{"B2"}, cells(out))
+
+	// A date-only max covers the whole day; date text parses too.
+	out = search(map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "date", "min": "2024-03-01", "max": "2024-03-31"}})
+	require.Equal(t, []string{"A3", "A4"}, cells(out))
+	require.Equal(t, &SearchRangeQuery{Type: "date", Min: "2024-03-01", Max: "2024-03-31"}, out.RangeQuery)
+	out = search(map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "date", "min": "2024-03-15"}})
+	require.Equal(t, []string{"A4", "A5"}, cells(out))
+
+	// The cursor carries the range and rejects a different one.
+	args := map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number", "min": 1000}, "max_results": 2}
+	out = search(args)
+	require.Equal(t, []string{"C2", "B3"}, cells(out))
+	require.NotEmpty(t, out.Meta.NextCursor)
+	next := search(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Equal(t, []string{"B4", "B5"}, cells(next))
+	require.Equal(t, "number", next.RangeQuery.Type)
+	res := callTool(t, srv, "search_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "range_query": map[string]any{"type": "number", "min": 1500}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"path": path, "sheet": sh, "query": "1000", "range_query": map[string]any{"type": "number", "min": 1}}, "VALIDATION: use query or range_query, not both"},
+		{map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number"}}, "VALIDATION: range_query needs min, max, or both"},
+		{map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number", "min": 5, "max": 1}}, "VALIDATION: range_query max is below min"},
+		{map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "date", "min": "soon"}}, "VALIDATION: range_query min \"soon\" is not a date"},
+		{map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "text", "min": 1}}, "VALIDATION: type must be one of"},
+		{map[string]any{"path": path, "sheet": sh, "range_query": map[string]any{"type": "number", "min": 1}, "scope": "formulas"}, "VALIDATION: range_query searches cell values"},
+	} {
+		res := callTool(t, srv, "search_data", tc.args)
+		require.True(t, res.IsError)
+		require.Contains(t, resultText(t, res), tc.want)
+	}
+}
@@ -0,0 +1,128 @@
+//go:build unix
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+// TestWriteRangeLeavesOtherSheetsReadable holds a Sheet1 write_range inside
+// its lock by auditing to a full FIFO, and reads Sheet2 meanwhile.
+func TestWriteRangeLeavesOtherSheetsReadable(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	RegisterFoundationTools(srv, reg, limits, mgr)
+
+	dir := t.TempDir()
+	fifo := filepath.Join(dir, "audit.fifo")
+	require.NoError(t, syscall.Mkfifo(fifo, 0o600))
+	reader, err := os.OpenFile(fifo, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = reader.Close() })
+	auditLog, err := audit.Open(fifo, true)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = auditLog.Close() })
+	reg.SetAuditLogger(auditLog)
+	// Draining unblocks the writer; cleanup drains too so a failed run ends.
+	var drainOnce sync.Once
+	drain := func() { drainOnce.Do(func() { go func() { _, _ = io.Copy(io.Discard, reader) }() }) }
+	t.Cleanup(drain)
+
+	// Fill the pipe so the next audit record blocks until it is drained.
+	filler, err := syscall.Open(fifo, syscall.O_WRONLY|syscall.O_NONBLOCK, 0)
+	require.NoError(t, err)
+	chunk := make([]byte, 4096)
+	for {
+		if _, werr := syscall.Write(filler, chunk); werr != nil {
+			require.True(t, errors.Is(werr, syscall.EAGAIN), "%v", werr)
+			break
+		}
+	}
+	require.NoError(t, syscall.Close(filler))
+
+	path := filepath.Join(dir, "two.xlsx")
+	f := excelize.NewFile()
+	_, err = f.NewSheet("Sheet2")
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellValue("Sheet2", "A1", "other"))
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	id, _, err := mgr.GetOrOpenWithOptions(context.Background(), path, workbooks.OpenOptions{})
+	require.NoError(t, err)
+
+	call := func(name string, args map[string]any) <-chan mcp.JSONRPCMessage {
+		msg, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": name, "arguments": args},
+		})
+		done := make(chan mcp.JSONRPCMessage, 1)
+		go func() { done <- srv.HandleMessage(context.Background(), msg) }()
+		return done
+	}
+	result := func(ch <-chan mcp.JSONRPCMessage) *mcp.CallToolResult {
+		t.Helper()
+		var resp mcp.JSONRPCMessage
+		select {
+		case resp = <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("tool call did not complete")
+		}
+		rpc, ok := resp.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", resp)
+		res := rpc.Result.(mcp.CallToolResult)
+		require.False(t, res.IsError, "%s", resultText(t, &res))
+		return &res
+	}
+
+	result(call("read_range", map[string]any{"path": path, "sheet": "Sheet2", "range": "A1:A1"}))
+
+	write := call("write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B1", "values": [][]string{{"x", "y"}}})
+	// The writer holds Sheet1 once a Sheet1 reader stops getting through.
+	require.Eventually(t, func() bool {
+		probe := make(chan struct{})
+		go func() {
+			_ = mgr.WithSheetRead(id, "Sheet1", func(*excelize.File, int64) error { return nil })
+			close(probe)
+		}()
+		select {
+		case <-probe:
+			return false
+		case <-time.After(20 * time.Millisecond):
+			return true
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	res := result(call("read_range", map[string]any{"path": path, "sheet": "Sheet2", "range": "A1:A1"}))
+	require.Contains(t, resultText(t, res), "other")
+	select {
+	case <-write:
+		t.Fatal("write_range finished before its audit record was drained")
+	default:
+	}
+
+	drain()
+	result(write)
+	res = result(call("read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B1"}))
+	require.Contains(t, resultText(t, res), `["x","y"]`)
+}
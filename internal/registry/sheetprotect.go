@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+)
+
+// workbookRelsPart is the relationships part mapping sheet r:ids to worksheet
+// parts in workbooks written by Excel and excelize.
+const workbookRelsPart = "xl/_rels/workbook.xml.rels"
+
+// sheetProtected reports whether sheet carries sheet protection. excelize has
+// no getter for it, so the <sheetProtection> element is read from the
+// worksheet part as last saved; write tools save after every edit, so the
+// part is current whenever a tool holds the lock.
+func sheetProtected(f *excelize.File, sheet string) bool {
+	part, ok := worksheetPart(f, sheet)
+	if !ok {
+		return false
+	}
+	data := pkgPart(f, part)
+	i := bytes.Index(data, []byte("sheetProtection"))
+	if i < 0 {
+		return false
+	}
+	// Back up to the element's '<', past any namespace prefix.
+	if j := bytes.LastIndexByte(data[:i], '<'); j >= 0 {
+		i = j
+	}
+	var sp struct {
+		Sheet bool `xml:"sheet,attr"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data[i:])).Decode(&sp); err != nil {
+		return false
+	}
+	return sp.Sheet
+}
+
+// worksheetPart resolves sheet to its worksheet part name via the workbook
+// relationships, e.g. "xl/worksheets/sheet2.xml".
+func worksheetPart(f *excelize.File, sheet string) (string, bool) {
+	if f.WorkBook == nil {
+		return "", false
+	}
+	var rid string
+	for _, s := range f.WorkBook.Sheets.Sheet {
+		if strings.EqualFold(s.Name, sheet) {
+			rid = s.ID
+			break
+		}
+	}
+	if rid == "" {
+		return "", false
+	}
+	var rels struct {
+		Relationship []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(pkgPart(f, workbookRelsPart), &rels); err != nil {
+		return "", false
+	}
+	for _, r := range rels.Relationship {
+		if r.ID != rid {
+			continue
+		}
+		if strings.HasPrefix(r.Target, "/") {
+			return strings.TrimPrefix(path.Clean(r.Target), "/"), true
+		}
+		return path.Join("xl", r.Target), true
+	}
+	return "", false
+}
+
+// pkgPart returns the bytes of a package part, or nil when it is absent.
+// Parts excelize no longer holds in memory (a sheet written by a stream
+// writer, or one spilled to a temp file) are read from the saved file.
+func pkgPart(f *excelize.File, name string) []byte {
+	if v, ok := f.Pkg.Load(name); ok {
+		b, _ := v.([]byte)
+		return b
+	}
+	if f.Path == "" {
+		return nil
+	}
+	zr, err := zip.OpenReader(f.Path)
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if zf.Name != name {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil
+		}
+		defer rc.Close()
+		b, _ := io.ReadAll(rc)
+		return b
+	}
+	return nil
+}
+
+// frozenPane returns the top-left cell of the scrollable pane when sheet has
+// frozen panes (e.g. "B2" freezes row 1 and column A), or "".
+func frozenPane(f *excelize.File, sheet string) string {
+	panes, err := f.GetPanes(sheet)
+	if err != nil || !panes.Freeze || (panes.XSplit == 0 && panes.YSplit == 0) {
+		return ""
+	}
+	cell, _ := excelize.CoordinatesToCellName(panes.XSplit+1, panes.YSplit+1)
+	return cell
+}
+
+// checkSheetProtection refuses edits to a protected sheet unless force is set.
+func checkSheetProtection(f *excelize.File, sheet string, force bool) error {
+	if force || !sheetProtected(f, sheet) {
+		return nil
+	}
+	return mcperr.Errorf(mcperr.PermissionDenied, "sheet %q is protected; pass force=true to edit it anyway", sheet)
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/insights"
+)
+
+// maxDatePeriods bounds the period buckets compute_statistics reports per
+// column: months while they fit, otherwise years.
+const maxDatePeriods = 120
+
+// DatePeriod counts dates falling in one month ("2024-03") or year ("2024").
+type DatePeriod struct {
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// DateStats summarizes the date values of a column.
+type DateStats struct {
+	Count    int    `json:"count"`
+	Earliest string `json:"earliest"`
+	Latest   string `json:"latest"`
+	SpanDays int    `json:"spanDays"`
+	// Granularity is "month", or "year" when the months exceed maxDatePeriods.
+	Granularity      string       `json:"granularity"`
+	Periods          []DatePeriod `json:"periods"`
+	PeriodsTruncated bool         `json:"periodsTruncated,omitempty" jsonschema_description:"More years than the bucket cap; only the earliest are listed"`
+
+	min, max time.Time
+	months   map[string]int
+}
+
+func (d *DateStats) observe(t time.Time) {
+	if d.months == nil {
+		d.months = make(map[string]int)
+	}
+	d.Count++
+	if d.Count == 1 || t.Before(d.min) {
+		d.min = t
+	}
+	if d.Count == 1 || t.After(d.max) {
+		d.max = t
+	}
+	d.months[t.Format("2006-01")]++
+}
+
+// finish fills the reported fields from the observations.
+func (d *DateStats) finish() {
+	d.Earliest, d.Latest = statDate(d.min), statDate(d.max)
+	d.SpanDays = int(d.max.Sub(d.min).Hours() / 24)
+	buckets := d.months
+	d.Granularity = "month"
+	if len(buckets) > maxDatePeriods {
+		buckets = make(map[string]int)
+		for m, n := range d.months {
+			buckets[m[:4]] += n
+		}
+		d.Granularity = "year"
+	}
+	d.Periods = make([]DatePeriod, 0, len(buckets))
+	for p, n := range buckets {
+		d.Periods = append(d.Periods, DatePeriod{Period: p, Count: n})
+	}
+	sort.Slice(d.Periods, func(i, j int) bool { return d.Periods[i].Period < d.Periods[j].Period })
+	if len(d.Periods) > maxDatePeriods {
+		d.Periods = d.Periods[:maxDatePeriods]
+		d.PeriodsTruncated = true
+	}
+}
+
+// statDate formats t as an ISO-8601 date, adding the time when it has one.
+func statDate(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
+
+// statDateParser recognizes date values for compute_statistics: text in one
+// of insights.DateLayouts, or a serial stored under a date number format.
+type statDateParser struct {
+	f        *excelize.File
+	sheet    string
+	types    *cellDetailReader
+	date1904 bool
+}
+
+func newStatDateParser(f *excelize.File, sheet string) *statDateParser {
+	p := &statDateParser{f: f, sheet: sheet, types: newCellDetailReader(f, sheet)}
+	if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		p.date1904 = *props.Date1904
+	}
+	return p
+}
+
+// parse returns the date held by cell, whose display value is val.
+func (p *statDateParser) parse(cell, val string) (time.Time, bool) {
+	if t, ok := insights.ParseDate(val); ok {
+		return t, true
+	}
+	if p.types.inferType(cell, val) != "date" {
+		return time.Time{}, false
+	}
+	raw, _ := p.f.GetCellValue(p.sheet, cell, excelize.Options{RawCellValue: true})
+	serial, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := excelize.ExcelDateToTime(serial, p.date1904)
+	return t, err == nil
+}
@@ -0,0 +1,258 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+// maxCleanChanges caps the before/after pairs clean_range reports.
+const maxCleanChanges = 50
+
+// cleanOps lists clean_range operations in the order they are applied, so
+// results do not depend on the order they were requested in.
+var cleanOps = []string{"normalize_nfc", "trim", "collapse_whitespace", "remove_thousands_separators", "to_upper", "to_lower"}
+
+// thousandsNumber matches a number written with comma thousands separators.
+var thousandsNumber = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+// CleanRangeInput defines parameters for clean_range.
+type CleanRangeInput struct {
+	Path       string   `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password   string   `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet      string   `json:"sheet" validate:"required" jsonschema_description:"Target sheet name"`
+	RangeA1    string   `json:"range" validate:"required,a1orname" jsonschema_description:"A1 range or defined name to clean"`
+	Operations []string `json:"operations" validate:"required,min=1,max=6,dive,oneof=trim collapse_whitespace to_upper to_lower remove_thousands_separators normalize_nfc" jsonschema_description:"Operations to apply to text cells: trim, collapse_whitespace, to_upper, to_lower, remove_thousands_separators, normalize_nfc"`
+	DryRun     bool     `json:"dry_run,omitempty" jsonschema_description:"Report what would change without modifying the workbook"`
+	Force      bool     `json:"force,omitempty" jsonschema_description:"Clean even when the sheet is protected"`
+	Backup     bool     `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// CleanChange is one text cell's value before and after cleaning.
+type CleanChange struct {
+	Cell   string `json:"cell"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// CleanRangeOutput reports the cells clean_range changed (or would change).
+type CleanRangeOutput struct {
+	Path               string         `json:"path"`
+	Sheet              string         `json:"sheet"`
+	RangeA1            string         `json:"range"`
+	DryRun             bool           `json:"dryRun"`
+	TextCells          int            `json:"textCells" jsonschema_description:"Text cells examined; numbers, dates, booleans, and formulas are never changed"`
+	CellsChanged       int            `json:"cellsChanged"`
+	ChangedByOperation map[string]int `json:"changedByOperation" jsonschema_description:"Cells each operation changed; a cell changed by several operations counts once per operation"`
+	Changes            []CleanChange  `json:"changes" jsonschema_description:"First changed cells in row-major order with their before and after values"`
+	ChangesTruncated   bool           `json:"changesTruncated,omitempty"`
+	Save               string         `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup             string         `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// cleaner applies a set of clean_range operations to text values.
+type cleaner struct {
+	ops map[string]bool
+}
+
+// clean returns s after every selected operation, calling changed with the
+// name of each operation that altered it.
+func (c cleaner) clean(s string, changed func(op string)) string {
+	for _, op := range cleanOps {
+		if !c.ops[op] {
+			continue
+		}
+		next := s
+		switch op {
+		case "normalize_nfc":
+			next = norm.NFC.String(s)
+		case "trim":
+			next = strings.TrimFunc(s, unicode.IsSpace)
+		case "collapse_whitespace":
+			next = collapseSpace(s)
+		case "remove_thousands_separators":
+			if thousandsNumber.MatchString(s) {
+				next = strings.ReplaceAll(s, ",", "")
+			}
+		case "to_upper":
+			next = strings.ToUpper(s)
+		case "to_lower":
+			next = strings.ToLower(s)
+		}
+		if next != s {
+			changed(op)
+			s = next
+		}
+	}
+	return s
+}
+
+// collapseSpace replaces each run of Unicode whitespace, including
+// non-breaking spaces, with a single ASCII space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	inSpace := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cleanEdit is one pending cell update.
+type cleanEdit struct {
+	cell, value string
+}
+
+// planClean computes the cleaned value of every text cell in the range,
+// filling out's counts and change sample, and returns the cells to update.
+func planClean(ctx context.Context, f *excelize.File, sheet string, x1, y1, x2, y2 int, c cleaner, out *CleanRangeOutput) ([]cleanEdit, error) {
+	var edits []cleanEdit
+	for row := y1; row <= y2; row++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for col := x1; col <= x2; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			if ct, _ := f.GetCellType(sheet, cell); ct != excelize.CellTypeSharedString && ct != excelize.CellTypeInlineString {
+				continue
+			}
+			if formula, _ := f.GetCellFormula(sheet, cell); formula != "" {
+				continue
+			}
+			before, err := f.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+			if err != nil {
+				return nil, err
+			}
+			out.TextCells++
+			after := c.clean(before, func(op string) { out.ChangedByOperation[op]++ })
+			if after == before {
+				continue
+			}
+			edits = append(edits, cleanEdit{cell: cell, value: after})
+			if len(out.Changes) < maxCleanChanges {
+				out.Changes = append(out.Changes, CleanChange{Cell: cell, Before: before, After: after})
+			}
+		}
+	}
+	out.CellsChanged = len(edits)
+	out.ChangesTruncated = out.CellsChanged > len(out.Changes)
+	return edits, nil
+}
+
+// RegisterCleanTools registers the write-gated clean_range.
+func RegisterCleanTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"clean_range",
+		mcp.WithDescription(fmt.Sprintf("Normalize messy text in a range so group-bys, duplicates, and lookups match: trim (leading/trailing whitespace, including non-breaking spaces), collapse_whitespace (runs of internal whitespace to one space), to_upper or to_lower, remove_thousands_separators (text such as '1,234.50' becomes '1234.50'), and normalize_nfc (Unicode NFC). Operations apply in that fixed order, all in one pass, to text cells only; numbers, dates, booleans, and formula cells are never touched. Returns cellsChanged, changedByOperation, and the first %d before/after pairs. dry_run=true reports the same without modifying the workbook; run it first on unfamiliar data. The range is capped at %d cells. Protected sheets are refused unless force=true. The workbook is saved atomically after all changes; on failure the file is left unchanged. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, PAYLOAD_TOO_LARGE, PERMISSION_DENIED, WRITE_FAILED.", maxCleanChanges, limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[CleanRangeInput](),
+		mcp.WithOutputSchema[CleanRangeOutput](),
+		writeTool(false, true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in CleanRangeInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		c := cleaner{ops: make(map[string]bool)}
+		for _, op := range in.Operations {
+			c.ops[op] = true
+		}
+		if c.ops["to_upper"] && c.ops["to_lower"] {
+			return mcperr.New(mcperr.Validation, "use to_upper or to_lower, not both"), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		out := CleanRangeOutput{Path: canonical, Sheet: sheet, DryRun: in.DryRun, ChangedByOperation: make(map[string]int), Changes: []CleanChange{}}
+		for op := range c.ops {
+			out.ChangedByOperation[op] = 0
+		}
+		plan := func(f *excelize.File) ([]cleanEdit, error) {
+			if !sheetExists(f, sheet) {
+				return nil, mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			x1, y1, x2, y2, a1, perr := resolveRange(f, sheet, strings.TrimSpace(in.RangeA1))
+			if perr != nil {
+				return nil, perr
+			}
+			out.RangeA1 = a1
+			if cells := (x2 - x1 + 1) * (y2 - y1 + 1); cells > limits.MaxCellsPerOp {
+				return nil, mcperr.Errorf(mcperr.PayloadTooLarge, "range has %d cells, max %d per operation; reduce range size or split into batches", cells, limits.MaxCellsPerOp)
+			}
+			return planClean(ctx, f, sheet, x1, y1, x2, y2, c, &out)
+		}
+
+		var err error
+		var mutated bool
+		if in.DryRun {
+			err = mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+				_, perr := plan(f)
+				return perr
+			})
+		} else {
+			err = mgr.WithSheetWrite(id, sheet, func(f *excelize.File, save workbooks.SaveFunc) error {
+				if !sheetExists(f, sheet) {
+					return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+				}
+				if err := checkSheetProtection(f, sheet, in.Force); err != nil {
+					return err
+				}
+				edits, perr := plan(f)
+				if perr != nil || len(edits) == 0 {
+					return perr
+				}
+				values := make([][]string, 0, len(edits))
+				for _, e := range edits {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					mutated = true
+					if err := f.SetCellStr(sheet, e.cell, e.value); err != nil {
+						return err
+					}
+					values = append(values, []string{e.cell, e.value})
+				}
+				if err := reg.auditWrite(ctx, audit.Record{Tool: "clean_range", Path: canonical, Sheet: sheet, Range: out.RangeA1, Cells: len(edits), ContentHash: audit.HashValues(values)}); err != nil {
+					return err
+				}
+				saved, err := save(canonical, in.Backup)
+				out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+				return err
+			})
+		}
+		if err != nil {
+			// Values cleaned in memory but never saved must not reach later
+			// calls; dropping the handle reloads the untouched file.
+			if mutated {
+				err = discardFailedWrite(mgr, id, err)
+			}
+			return structureEditError(err), nil
+		}
+		runtime.CallStatsFrom(ctx).AddCells(out.TextCells)
+		summary := fmt.Sprintf("changed=%d textCells=%d range=%s dryRun=%v", out.CellsChanged, out.TextCells, out.RangeA1, out.DryRun) + saveSummary(out.Save, out.Backup)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(tool)
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestCleanRange(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]any{"  North  East ", "1,234.50", 1234.5, "Cafe\u0301"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]any{"west", "CLEAN", nil, nil}))
+	require.NoError(t, f.SetCellFormula(sh, "C2", `" padded "`))
+	path := filepath.Join(t.TempDir(), "clean.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	args := map[string]any{"path": path, "sheet": sh, "range": "A1:D2", "operations": []string{"to_upper", "collapse_whitespace", "trim", "remove_thousands_separators", "normalize_nfc"}, "dry_run": true}
+	res := callTool(t, srv, "clean_range", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got CleanRangeOutput
+	decodeStructured(t, res, &got)
+	require.True(t, got.DryRun)
+	require.Equal(t, 5, got.TextCells)
+	require.Equal(t, 4, got.CellsChanged)
+	require.Equal(t, map[string]int{"trim": 1, "collapse_whitespace": 1, "to_upper": 3, "remove_thousands_separators": 1, "normalize_nfc": 1}, got.ChangedByOperation)
+	require.Equal(t, []CleanChange{
+		{Cell: "A1", Before: "  North  East ", After: "NORTH EAST"},
+		{Cell: "B1", Before: "1,234.50", After: "1234.50"},
+		{Cell: "D1", Before: "Cafe\u0301", After: "CAFÉ"},
+		{Cell: "A2", Before: "west", After: "WEST"},
+	}, got.Changes)
+	require.Empty(t, got.Save)
+
+	// The dry run left the file alone; applying writes every change at once.
+	args["dry_run"] = false
+	res = callTool(t, srv, "clean_range", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	got = CleanRangeOutput{}
+	decodeStructured(t, res, &got)
+	require.Equal(t, 4, got.CellsChanged)
+	require.Equal(t, "immediate", got.Save)
+
+	saved, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer saved.Close()
+	rows, err := saved.GetRows(sh, excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"NORTH EAST", "1234.50", "1234.5", "CAFÉ"}, rows[0])
+	formula, err := saved.GetCellFormula(sh, "C2")
+	require.NoError(t, err)
+	require.Equal(t, `" padded "`, formula)
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"path": path, "sheet": sh, "range": "A1:B2", "operations": []string{"to_upper", "to_lower"}}, "VALIDATION: use to_upper or to_lower"},
+		{map[string]any{"path": path, "sheet": sh, "range": "A1:B2", "operations": []string{"strip"}}, "VALIDATION: operations[0] must be one of"},
+		{map[string]any{"path": path, "sheet": sh, "range": "A1:B2"}, "VALIDATION: operations is required"},
+		{map[string]any{"path": path, "sheet": "Missing", "range": "A1:B2", "operations": []string{"trim"}}, "INVALID_SHEET"},
+	} {
+		res = callTool(t, srv, "clean_range", tc.args)
+		require.True(t, res.IsError)
+		require.Contains(t, resultText(t, res), tc.want)
+	}
+}
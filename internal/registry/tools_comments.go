@@ -0,0 +1,323 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/config"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// defaultCommentPage and maxCommentPage bound read_comments pages.
+	defaultCommentPage = 100
+	maxCommentPage     = 500
+	// maxCommentTextRunes caps the max_text_runes input.
+	maxCommentTextRunes = 10_000
+	// maxCommentLen is Excel's limit on comment text length.
+	maxCommentLen = 32767
+	// defaultCommentAuthor is recorded when add_comment is given no author.
+	defaultCommentAuthor = "mcpxcel"
+)
+
+// ReadCommentsInput defines parameters for read_comments.
+type ReadCommentsInput struct {
+	Path         string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password     string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet        string `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Sheet whose comments to read"`
+	MaxComments  int    `json:"max_comments,omitempty" validate:"omitempty,min=1,max=500" jsonschema_description:"Max comments per page (unit=rows, default 100)"`
+	MaxTextRunes int    `json:"max_text_runes,omitempty" validate:"omitempty,min=1,max=10000" jsonschema_description:"Truncate comment texts longer than this many characters (default 500)"`
+	Cursor       string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor from a previous page; carries sheet and text cap"`
+}
+
+// CommentInfo is one cell comment (note).
+type CommentInfo struct {
+	Cell      string `json:"cell"`
+	Author    string `json:"author,omitempty"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated,omitempty" jsonschema_description:"Text was cut at max_text_runes"`
+}
+
+// ReadCommentsOutput reports one page of a sheet's comments in row-major order.
+type ReadCommentsOutput struct {
+	Path     string        `json:"path"`
+	Sheet    string        `json:"sheet"`
+	Comments []CommentInfo `json:"comments"`
+	Meta     PageMeta      `json:"meta"`
+}
+
+// AddCommentInput defines parameters for add_comment.
+type AddCommentInput struct {
+	Path    string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Sheet   string `json:"sheet" validate:"required" jsonschema_description:"Target sheet name"`
+	Cell    string `json:"cell" validate:"required" jsonschema_description:"Cell to annotate, e.g. B7"`
+	Text    string `json:"text" validate:"required,max=32767" jsonschema_description:"Comment text"`
+	Author  string `json:"author,omitempty" validate:"omitempty,max=255" jsonschema_description:"Comment author (default mcpxcel)"`
+	Replace bool   `json:"replace,omitempty" jsonschema_description:"Replace an existing comment on the cell instead of failing"`
+	Force   bool   `json:"force,omitempty" jsonschema_description:"Edit even when the sheet is protected"`
+	Backup  bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// AddCommentOutput reports the comment written.
+type AddCommentOutput struct {
+	Path     string `json:"path"`
+	Sheet    string `json:"sheet"`
+	Cell     string `json:"cell"`
+	Author   string `json:"author"`
+	Replaced bool   `json:"replaced"`
+	Save     string `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup   string `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// sheetComments returns sheet's comments in row-major order with their text
+// runs joined.
+func sheetComments(f *excelize.File, sheet string) ([]excelize.Comment, error) {
+	comments, err := f.GetComments(sheet)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		var b strings.Builder
+		b.WriteString(comments[i].Text)
+		for _, run := range comments[i].Paragraph {
+			b.WriteString(run.Text)
+		}
+		comments[i].Text = b.String()
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		ci, ri, _ := excelize.CellNameToCoordinates(comments[i].Cell)
+		cj, rj, _ := excelize.CellNameToCoordinates(comments[j].Cell)
+		if ri != rj {
+			return ri < rj
+		}
+		return ci < cj
+	})
+	return comments, nil
+}
+
+// commentSpan returns the A1 range bounding the commented cells, which
+// read_comments records as the cursor range.
+func commentSpan(comments []excelize.Comment) string {
+	x1, y1, x2, y2 := 0, 0, 0, 0
+	for _, c := range comments {
+		col, row, err := excelize.CellNameToCoordinates(c.Cell)
+		if err != nil {
+			continue
+		}
+		if x1 == 0 || col < x1 {
+			x1 = col
+		}
+		if y1 == 0 || row < y1 {
+			y1 = row
+		}
+		x2, y2 = max(x2, col), max(y2, row)
+	}
+	if x1 == 0 {
+		return "A1:A1"
+	}
+	left, _ := excelize.CoordinatesToCellName(x1, y1)
+	right, _ := excelize.CoordinatesToCellName(x2, y2)
+	return left + ":" + right
+}
+
+// RegisterCommentTools registers read_comments and the write-gated add_comment.
+func RegisterCommentTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	// read_comments
+	read := mcp.NewTool(
+		"read_comments",
+		mcp.WithDescription(fmt.Sprintf("List the cell comments (notes) on a sheet as {cell, author, text} in row‑major order. Reviewers often leave context there that cell values do not show. Texts longer than max_text_runes (default %d, at most %d) are cut and marked truncated. Pagination operates in comments (unit=rows, max_comments per page, default %d, at most %d); the cursor binds to path+content fingerprint and can be sent alone to resume. Modern threaded comments are not read. Errors: VALIDATION, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, READ_FAILED.", config.DefaultCommentTextRunes, maxCommentTextRunes, defaultCommentPage, maxCommentPage)),
+		mcp.WithInputSchema[ReadCommentsInput](),
+		mcp.WithOutputSchema[ReadCommentsOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(read, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ReadCommentsInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		pageSize := pagination.PageSize(in.MaxComments, 0, defaultCommentPage, maxCommentPage)
+		textRunes := in.MaxTextRunes
+		if textRunes <= 0 {
+			textRunes = config.DefaultCommentTextRunes
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+
+		var startOffset int
+		var parsedCur *pagination.Cursor
+		if curTok := strings.TrimSpace(in.Cursor); curTok != "" {
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
+			}
+			if pc.Pt != canonical {
+				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+			}
+			if pc.U != pagination.UnitRows || pc.Tr <= 0 {
+				return mcperr.FromText("CURSOR_INVALID: cursor was not issued by read_comments"), nil
+			}
+			sheet, textRunes, startOffset = pc.S, pc.Tr, pc.Off
+			pageSize = pagination.PageSize(in.MaxComments, pc.Ps, pageSize, maxCommentPage)
+			parsedCur = pc
+		}
+
+		out := ReadCommentsOutput{Path: canonical, Sheet: sheet, Comments: []CommentInfo{}}
+		reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
+			fileMT, fileFP := fileSnapshot(canonical)
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
+			}
+			actual, ok := resolveSheetName(f, sheet)
+			if !ok {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			out.Sheet = actual
+			comments, err := sheetComments(f, actual)
+			if err != nil {
+				return err
+			}
+			if startOffset > len(comments) {
+				startOffset = len(comments)
+			}
+			page := comments[startOffset:minInt(startOffset+pageSize, len(comments))]
+			for _, c := range page {
+				ci := CommentInfo{Cell: c.Cell, Author: c.Author, Text: c.Text}
+				if utf8.RuneCountInString(c.Text) > textRunes {
+					ci.Text, ci.Truncated = truncateText(c.Text, textRunes), true
+				}
+				out.Comments = append(out.Comments, ci)
+			}
+			out.Meta.Total = len(comments)
+			out.Meta.Returned = len(page)
+			out.Meta.Truncated = startOffset+len(page) < len(comments)
+			if out.Meta.Truncated {
+				next := pagination.Cursor{V: 1, Pt: canonical, S: actual, R: commentSpan(comments), U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(page)), Ps: pageSize, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: version, Tr: textRunes}
+				token, encErr := pagination.EncodeCursor(next)
+				if encErr != nil {
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
+				}
+				out.Meta.NextCursor = token
+			}
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+		}
+
+		runtime.CallStatsFrom(ctx).SetResult(out.Meta.Returned, out.Meta.Truncated)
+		summary := fmt.Sprintf("comments=%d returned=%d truncated=%v", out.Meta.Total, out.Meta.Returned, out.Meta.Truncated)
+		if out.Meta.NextCursor != "" {
+			summary += " nextCursor=" + out.Meta.NextCursor
+		}
+		lines := []string{summary}
+		for _, c := range out.Comments {
+			lines = append(lines, fmt.Sprintf("- %s [%s]: %s", c.Cell, c.Author, strings.ReplaceAll(c.Text, "\n", " ")))
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(read)
+
+	// add_comment
+	add := mcp.NewTool(
+		"add_comment",
+		mcp.WithDescription(fmt.Sprintf("Attach a comment (note) to a cell and save the workbook atomically, e.g. to flag a value for a reviewer. author defaults to '%s'. A cell that already has a comment is refused unless replace=true. Text is at most %d characters. Protected sheets are refused unless force=true. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, PERMISSION_DENIED, WRITE_FAILED.", defaultCommentAuthor, maxCommentLen)),
+		mcp.WithInputSchema[AddCommentInput](),
+		mcp.WithOutputSchema[AddCommentOutput](),
+		writeTool(false, true),
+	)
+	s.AddTool(add, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in AddCommentInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		col, row, cerr := excelize.CellNameToCoordinates(strings.TrimSpace(in.Cell))
+		if cerr != nil {
+			return mcperr.New(mcperr.Validation, "cell must be a single A1 reference such as B7"), nil
+		}
+		cell, _ := excelize.CoordinatesToCellName(col, row)
+		author := strings.TrimSpace(in.Author)
+		if author == "" {
+			author = defaultCommentAuthor
+		}
+		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, strings.TrimSpace(in.Path))
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		out := AddCommentOutput{Path: canonical, Cell: cell, Author: author}
+		mutated := false
+		// Comments add drawing and content-type parts shared by all sheets.
+		err := mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			sheet, ok := resolveSheetName(f, strings.TrimSpace(in.Sheet))
+			if !ok {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			out.Sheet = sheet
+			if err := checkSheetProtection(f, sheet, in.Force); err != nil {
+				return err
+			}
+			comments, err := f.GetComments(sheet)
+			if err != nil {
+				return err
+			}
+			for _, c := range comments {
+				if c.Cell == cell {
+					out.Replaced = true
+				}
+			}
+			if out.Replaced {
+				if !in.Replace {
+					return mcperr.Errorf(mcperr.Validation, "%s already has a comment; pass replace=true to overwrite it", cell)
+				}
+				mutated = true
+				if err := f.DeleteComment(sheet, cell); err != nil {
+					return err
+				}
+			}
+			mutated = true
+			if err := f.AddComment(sheet, excelize.Comment{Cell: cell, Author: author, Text: in.Text}); err != nil {
+				return err
+			}
+			if err := reg.auditWrite(ctx, audit.Record{Tool: "add_comment", Path: canonical, Sheet: sheet, Range: cell, Cells: 1}); err != nil {
+				return err
+			}
+			saved, err := save(canonical, in.Backup)
+			out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+			return err
+		})
+		if err != nil {
+			// A comment added or removed in memory but never saved must not
+			// reach later calls; dropping the handle reloads the untouched file.
+			if mutated {
+				err = discardFailedWrite(mgr, id, err)
+			}
+			return structureEditError(err), nil
+		}
+		summary := fmt.Sprintf("cell=%s sheet=%q author=%q replaced=%v", out.Cell, out.Sheet, out.Author, out.Replaced) + saveSummary(out.Save, out.Backup)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(add)
+}
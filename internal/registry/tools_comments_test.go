@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func createCommentsWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]any{"Region", "Amount"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]any{"North", 10}))
+	require.NoError(t, f.AddComment("Sheet1", excelize.Comment{Cell: "B2", Author: "Dana", Text: "Includes a one-off refund"}))
+	require.NoError(t, f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "Lee", Paragraph: []excelize.RichTextRun{
+		{Text: "Lee:", Font: &excelize.Font{Bold: true}},
+		{Text: " region codes follow the 2024 map"},
+	}}))
+	require.NoError(t, f.AddComment("Sheet1", excelize.Comment{Cell: "C2", Author: "Dana", Text: strings.Repeat("é", 40)}))
+	path := filepath.Join(t.TempDir(), "comments.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestReadComments_PagesAndTruncates(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createCommentsWorkbook(t)
+
+	res := callTool(t, srv, "read_comments", map[string]any{"path": path, "sheet": "sheet1", "max_comments": 2, "max_text_runes": 30})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadCommentsOutput)
+	require.Equal(t, "Sheet1", out.Sheet)
+	require.Equal(t, []CommentInfo{
+		{Cell: "A1", Author: "Lee", Text: "Lee: region codes follow the 2…", Truncated: true},
+		{Cell: "B2", Author: "Dana", Text: "Includes a one-off refund"},
+	}, out.Comments)
+	require.Equal(t, 3, out.Meta.Total)
+	require.True(t, out.Meta.Truncated)
+	require.Contains(t, resultText(t, res), "- B2 [Dana]: Includes a one-off refund")
+
+	// The cursor alone resumes with the same page size and text cap.
+	res = callTool(t, srv, "read_comments", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ReadCommentsOutput)
+	require.Equal(t, []CommentInfo{{Cell: "C2", Author: "Dana", Text: strings.Repeat("é", 30) + "…", Truncated: true}}, out.Comments)
+	require.False(t, out.Meta.Truncated)
+
+	res = callTool(t, srv, "read_comments", map[string]any{"path": path, "sheet": "Missing"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_SHEET")
+}
+
+func TestAddComment(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createCommentsWorkbook(t)
+
+	res := callTool(t, srv, "add_comment", map[string]any{"path": path, "sheet": "Sheet1", "cell": "a2", "text": "Check the region"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, AddCommentOutput{Path: res.StructuredContent.(AddCommentOutput).Path, Sheet: "Sheet1", Cell: "A2", Author: "mcpxcel", Save: "immediate"}, res.StructuredContent)
+
+	res = callTool(t, srv, "add_comment", map[string]any{"path": path, "sheet": "Sheet1", "cell": "B2", "text": "Refund confirmed", "author": "Ana"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "replace=true")
+
+	res = callTool(t, srv, "add_comment", map[string]any{"path": path, "sheet": "Sheet1", "cell": "B2", "text": "Refund confirmed", "author": "Ana", "replace": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.True(t, res.StructuredContent.(AddCommentOutput).Replaced)
+
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	comments, err := sheetComments(f, "Sheet1")
+	require.NoError(t, err)
+	require.Len(t, comments, 4)
+	require.Equal(t, "A2", comments[1].Cell)
+	require.Equal(t, "Check the region", comments[1].Text)
+	require.Equal(t, "Ana", comments[2].Author)
+	require.Equal(t, "Refund confirmed", comments[2].Text)
+
+	res = callTool(t, srv, "add_comment", map[string]any{"path": path, "sheet": "Sheet1", "cell": "A1:B2", "text": "x"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
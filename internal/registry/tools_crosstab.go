@@ -0,0 +1,416 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+const (
+	defaultCrosstabRowKeys = 20
+	defaultCrosstabColKeys = 12
+	// crosstabOther labels the row or column collecting keys past the caps.
+	crosstabOther = "(other)"
+)
+
+// CrosstabInput defines parameters for crosstab.
+type CrosstabInput struct {
+	Path            string     `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password        string     `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet           string     `json:"sheet" validate:"required" jsonschema_description:"Sheet to scan"`
+	RangeA1         string     `json:"range,omitempty" validate:"omitempty,a1orname" jsonschema_description:"Optional A1 range or defined name; omitted means the sheet's used range"`
+	Header          bool       `json:"header,omitempty" jsonschema_description:"Treat the first row of the range as a header and skip it (implied when any column is given by name)"`
+	RowDimension    ColumnRef  `json:"row_dimension" jsonschema_description:"Column whose values become matrix rows: 1‑based index within the range or header name"`
+	ColumnDimension ColumnRef  `json:"column_dimension" jsonschema_description:"Column whose values become matrix columns: 1‑based index within the range or header name"`
+	Measure         *ColumnRef `json:"measure,omitempty" jsonschema_description:"Numeric column to aggregate; required unless agg is count"`
+	Agg             string     `json:"agg,omitempty" validate:"omitempty,oneof=count sum avg min max" jsonschema_description:"Aggregation: count (rows, default), sum, avg, min, or max of measure"`
+	MaxRowKeys      int        `json:"max_row_keys,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Most frequent row keys to keep (default 20); the rest fold into (other)"`
+	MaxColKeys      int        `json:"max_col_keys,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Most frequent column keys to keep (default 12); the rest fold into (other)"`
+}
+
+// CrosstabOutput is a two-dimensional pivot. Values[i][j] aggregates the rows
+// with RowKeys[i] and ColumnKeys[j]; cells without observations are null
+// except under count.
+type CrosstabOutput struct {
+	Path               string       `json:"path"`
+	Sheet              string       `json:"sheet"`
+	RangeA1            string       `json:"range"`
+	RowDimension       string       `json:"rowDimension"`
+	ColumnDimension    string       `json:"columnDimension"`
+	Measure            string       `json:"measure,omitempty"`
+	Agg                string       `json:"agg"`
+	RowKeys            []string     `json:"rowKeys"`
+	ColumnKeys         []string     `json:"columnKeys"`
+	Values             [][]*float64 `json:"values"`
+	RowTotals          []*float64   `json:"rowTotals"`
+	ColumnTotals       []*float64   `json:"columnTotals"`
+	GrandTotal         *float64     `json:"grandTotal"`
+	OtherRow           bool         `json:"otherRow,omitempty" jsonschema_description:"The last row key is (other), holding row keys past max_row_keys"`
+	OtherColumn        bool         `json:"otherColumn,omitempty" jsonschema_description:"The last column key is (other), holding column keys past max_col_keys"`
+	DistinctRowKeys    int          `json:"distinctRowKeys"`
+	DistinctColumnKeys int          `json:"distinctColumnKeys"`
+	RowsScanned        int          `json:"rowsScanned"`
+	NonNumeric         int          `json:"nonNumeric,omitempty" jsonschema_description:"Rows skipped because the measure was blank or not a number"`
+	ScanTruncated      bool         `json:"scanTruncated,omitempty" jsonschema_description:"The range exceeded the per-operation cell limit; later rows were not scanned"`
+}
+
+// crossAcc accumulates one matrix cell or total.
+type crossAcc struct {
+	n             int
+	sum, min, max float64
+}
+
+func (a *crossAcc) add(v float64) {
+	if a.n == 0 || v < a.min {
+		a.min = v
+	}
+	if a.n == 0 || v > a.max {
+		a.max = v
+	}
+	a.n++
+	a.sum += v
+}
+
+func (a *crossAcc) merge(o *crossAcc) {
+	if o.n == 0 {
+		return
+	}
+	if a.n == 0 || o.min < a.min {
+		a.min = o.min
+	}
+	if a.n == 0 || o.max > a.max {
+		a.max = o.max
+	}
+	a.n += o.n
+	a.sum += o.sum
+}
+
+// value reports the aggregate, or nil when nothing was observed.
+func (a *crossAcc) value(agg string) *float64 {
+	var v float64
+	switch {
+	case agg == "count":
+		v = float64(a.n)
+	case a.n == 0:
+		return nil
+	case agg == "sum":
+		v = a.sum
+	case agg == "avg":
+		v = a.sum / float64(a.n)
+	case agg == "min":
+		v = a.min
+	default:
+		v = a.max
+	}
+	return &v
+}
+
+// RegisterCrosstabTools registers crosstab.
+func RegisterCrosstabTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"crosstab",
+		mcp.WithDescription(fmt.Sprintf("Pivot a range on two dimensions (e.g., region × month) in one streaming pass. Name row_dimension, column_dimension, and the optional measure by 1‑based index within the range or by header; agg is count (default, rows), sum, avg, min, or max. The most frequent max_row_keys/max_col_keys keys are kept (defaults %d and %d) and the rest fold into an (other) row/column; keys are listed in natural order (numbers numerically, then text). Returns values[row][col] with row totals, column totals, and a grand total; empty cells are null except under count. Blank dimension values group as (empty); rows whose measure is not a number are skipped and counted. max_row_keys × max_col_keys may not exceed %d. At most %d cells are scanned; scanTruncated reports when the range was larger. Errors: VALIDATION, INVALID_SHEET, LIMIT_EXCEEDED, ANALYSIS_FAILED.", defaultCrosstabRowKeys, defaultCrosstabColKeys, limits.MaxCrosstabCells, limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[CrosstabInput](),
+		mcp.WithOutputSchema[CrosstabOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in CrosstabInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		agg := in.Agg
+		if agg == "" {
+			agg = "count"
+		}
+		if in.RowDimension == (ColumnRef{}) || in.ColumnDimension == (ColumnRef{}) {
+			return mcperr.FromText("VALIDATION: row_dimension and column_dimension are required"), nil
+		}
+		if agg != "count" && in.Measure == nil {
+			return mcperr.FromText("VALIDATION: measure is required for agg " + agg), nil
+		}
+		maxRowKeys, maxColKeys := in.MaxRowKeys, in.MaxColKeys
+		if maxRowKeys <= 0 {
+			maxRowKeys = defaultCrosstabRowKeys
+		}
+		if maxColKeys <= 0 {
+			maxColKeys = defaultCrosstabColKeys
+		}
+		if cells := maxRowKeys * maxColKeys; cells > limits.MaxCrosstabCells {
+			return mcperr.New(mcperr.LimitExceeded, fmt.Sprintf("max_row_keys × max_col_keys = %d exceeds the crosstab cap of %d; lower max_row_keys or max_col_keys (extra keys fold into %s)", cells, limits.MaxCrosstabCells, crosstabOther)), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		refs := []ColumnRef{in.RowDimension, in.ColumnDimension}
+		if in.Measure != nil {
+			refs = append(refs, *in.Measure)
+		}
+		header := in.Header || columnRefsNeedHeader(refs)
+
+		out := CrosstabOutput{Path: canonical, Sheet: sheet, Agg: agg}
+		reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+			if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			rng := strings.TrimSpace(in.RangeA1)
+			if rng == "" {
+				rng, _ = scanUsedRange(f, sheet)
+			}
+			if rng == "" {
+				return mcperr.Errorf(mcperr.Validation, "sheet is empty")
+			}
+			x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+			}
+			out.RangeA1 = resolved
+			colCount := x2 - x1 + 1
+
+			// cols holds the 1-based range columns for refs; names resolve
+			// against the header row.
+			var cols []int
+			useCols := func(headerVals []string) error {
+				var err error
+				if cols, err = resolveColumnRefs(refs, headerVals, y1); err != nil {
+					return err
+				}
+				names := make([]string, len(cols))
+				for i, c := range cols {
+					if c > colCount {
+						return mcperr.Errorf(mcperr.Validation, "column %d outside range (%d columns)", c, colCount)
+					}
+					names[i] = fmt.Sprintf("column %d", c)
+					if c <= len(headerVals) && strings.TrimSpace(headerVals[c-1]) != "" {
+						names[i] = headerVals[c-1]
+					}
+				}
+				out.RowDimension, out.ColumnDimension = names[0], names[1]
+				if in.Measure != nil {
+					out.Measure = names[2]
+				}
+				return nil
+			}
+			if !header {
+				if err := useCols(nil); err != nil {
+					return err
+				}
+			}
+
+			cellOf := func(vals []string, c int) string {
+				if abs := x1 + c - 2; abs < len(vals) {
+					return strings.TrimSpace(vals[abs])
+				}
+				return ""
+			}
+			key := func(v string) string {
+				if v == "" {
+					return "(empty)"
+				}
+				return v
+			}
+			cells := map[string]map[string]*crossAcc{}
+			rowFreq, colFreq := map[string]int{}, map[string]int{}
+
+			rowsIter, rerr := f.Rows(sheet)
+			if rerr != nil {
+				return rerr
+			}
+			defer rowsIter.Close()
+			scanned, rowIdx := 0, 0
+			for rowsIter.Next() {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				rowIdx++
+				if rowIdx < y1 {
+					continue
+				}
+				if rowIdx > y2 {
+					break
+				}
+				vals, cerr := rowsIter.Columns()
+				if cerr != nil {
+					return cerr
+				}
+				if header && rowIdx == y1 {
+					if err := useCols(columnWindow(vals, x1, x2)); err != nil {
+						return err
+					}
+					continue
+				}
+				scanned += colCount
+				if scanned > limits.MaxCellsPerOp {
+					out.ScanTruncated = true
+					break
+				}
+				out.RowsScanned++
+				rk, ck := key(cellOf(vals, cols[0])), key(cellOf(vals, cols[1]))
+				rowFreq[rk]++
+				colFreq[ck]++
+				byCol := cells[rk]
+				if byCol == nil {
+					byCol = map[string]*crossAcc{}
+					cells[rk] = byCol
+				}
+				acc := byCol[ck]
+				if acc == nil {
+					acc = &crossAcc{}
+					byCol[ck] = acc
+				}
+				if agg == "count" {
+					acc.n++
+					continue
+				}
+				v, ok := parseNumber(cellOf(vals, cols[2]))
+				if !ok || math.IsInf(v, 0) || math.IsNaN(v) {
+					out.NonNumeric++
+					continue
+				}
+				acc.add(v)
+			}
+			if cols == nil {
+				return mcperr.Errorf(mcperr.Validation, "range has no header row to match column names")
+			}
+
+			out.DistinctRowKeys, out.DistinctColumnKeys = len(rowFreq), len(colFreq)
+			var rowIndex, colIndex map[string]int
+			out.RowKeys, rowIndex, out.OtherRow = crosstabKeys(rowFreq, maxRowKeys)
+			out.ColumnKeys, colIndex, out.OtherColumn = crosstabKeys(colFreq, maxColKeys)
+
+			matrix := make([][]crossAcc, len(out.RowKeys))
+			for i := range matrix {
+				matrix[i] = make([]crossAcc, len(out.ColumnKeys))
+			}
+			rowTotals := make([]crossAcc, len(out.RowKeys))
+			colTotals := make([]crossAcc, len(out.ColumnKeys))
+			var grand crossAcc
+			for rk, byCol := range cells {
+				ri := rowIndex[rk]
+				for ck, acc := range byCol {
+					ci := colIndex[ck]
+					matrix[ri][ci].merge(acc)
+					rowTotals[ri].merge(acc)
+					colTotals[ci].merge(acc)
+					grand.merge(acc)
+				}
+			}
+			out.Values = make([][]*float64, len(matrix))
+			for i := range matrix {
+				out.Values[i] = make([]*float64, len(matrix[i]))
+				for j := range matrix[i] {
+					out.Values[i][j] = matrix[i][j].value(agg)
+				}
+			}
+			out.RowTotals = make([]*float64, len(rowTotals))
+			for i := range rowTotals {
+				out.RowTotals[i] = rowTotals[i].value(agg)
+			}
+			out.ColumnTotals = make([]*float64, len(colTotals))
+			for j := range colTotals {
+				out.ColumnTotals[j] = colTotals[j].value(agg)
+			}
+			out.GrandTotal = grand.value(agg)
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.AnalysisFailed, "%v", err), nil
+		}
+		runtime.CallStatsFrom(ctx).SetResult(len(out.RowKeys)*len(out.ColumnKeys), out.ScanTruncated)
+
+		summary := fmt.Sprintf("crosstab: rows=%d cols=%d agg=%s distinctRows=%d distinctCols=%d scanned=%d truncated=%v", len(out.RowKeys), len(out.ColumnKeys), agg, out.DistinctRowKeys, out.DistinctColumnKeys, out.RowsScanned, out.ScanTruncated)
+		if out.NonNumeric > 0 {
+			summary += fmt.Sprintf(" nonNumeric=%d", out.NonNumeric)
+		}
+		table, used := renderMarkdownTable(crosstabGrid(out), markdownCellWidth(0), payloadBudget(limits.MaxPayloadBytes))
+		text := summary + "\n" + table
+		if used < len(out.RowKeys)+2 {
+			text += fmt.Sprintf("(%d of %d rows shown; see structured content)\n", used-1, len(out.RowKeys)+1)
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(text)}
+		return res, nil
+	}))
+	reg.Register(tool)
+}
+
+// crosstabKeys keeps the limit most frequent keys (ties in natural order),
+// lists them in natural order, and maps every key to its index; keys past the
+// limit map to a trailing (other) entry.
+func crosstabKeys(freq map[string]int, limit int) ([]string, map[string]int, bool) {
+	keys := make([]string, 0, len(freq))
+	for k := range freq {
+		keys = append(keys, k)
+	}
+	natural := func(a, b string) bool {
+		if c := compareCells(a, b); c != 0 {
+			return c < 0
+		}
+		return a < b
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if freq[keys[i]] != freq[keys[j]] {
+			return freq[keys[i]] > freq[keys[j]]
+		}
+		return natural(keys[i], keys[j])
+	})
+	rest := []string{}
+	if len(keys) > limit {
+		keys, rest = keys[:limit], keys[limit:]
+	}
+	sort.Slice(keys, func(i, j int) bool { return natural(keys[i], keys[j]) })
+	index := make(map[string]int, len(freq))
+	for i, k := range keys {
+		index[k] = i
+	}
+	if len(rest) == 0 {
+		return keys, index, false
+	}
+	for _, k := range rest {
+		index[k] = len(keys)
+	}
+	return append(keys, crosstabOther), index, true
+}
+
+// crosstabGrid lays out the matrix with headers and totals for rendering.
+func crosstabGrid(out CrosstabOutput) [][]string {
+	cell := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return compactNumber(*v)
+	}
+	head := append([]string{out.RowDimension + " \\ " + out.ColumnDimension}, out.ColumnKeys...)
+	grid := [][]string{append(head, "Total")}
+	for i, rk := range out.RowKeys {
+		row := []string{rk}
+		for _, v := range out.Values[i] {
+			row = append(row, cell(v))
+		}
+		grid = append(grid, append(row, cell(out.RowTotals[i])))
+	}
+	total := []string{"Total"}
+	for _, v := range out.ColumnTotals {
+		total = append(total, cell(v))
+	}
+	return append(grid, append(total, cell(out.GrandTotal)))
+}
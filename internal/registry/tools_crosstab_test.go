@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func createCrosstabWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	rows := [][]any{
+		{"Region", "Month", "Sales"},
+		{"North", 2, 10},
+		{"North", 1, 20},
+		{"South", 1, 5},
+		{"South", 1, "n/a"},
+		{"East", 10, 7},
+		{"West", 2, 3},
+		{"North", 10, 1},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "pivot.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestCrosstab(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createCrosstabWorkbook(t)
+	f := func(v float64) *float64 { return &v }
+
+	// Count pivot: month keys sort numerically (1, 2, 10).
+	res := callTool(t, srv, "crosstab", map[string]any{"path": path, "sheet": "Sheet1", "row_dimension": "region", "column_dimension": "Month"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out CrosstabOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, "Region", out.RowDimension)
+	require.Equal(t, "Month", out.ColumnDimension)
+	require.Equal(t, []string{"East", "North", "South", "West"}, out.RowKeys)
+	require.Equal(t, []string{"1", "2", "10"}, out.ColumnKeys)
+	require.Equal(t, []*float64{f(1), f(1), f(1)}, out.Values[1])
+	require.Equal(t, []*float64{f(3), f(2), f(2)}, out.ColumnTotals)
+	require.Equal(t, f(7), out.GrandTotal)
+	summary, table := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "crosstab: rows=4 cols=3 agg=count")
+	require.Contains(t, table, "| North | 1 | 1 | 1 | 3 |")
+	require.Contains(t, table, "| Total | 3 | 2 | 2 | 7 |")
+
+	// Sum with capped keys folds the least frequent into (other).
+	res = callTool(t, srv, "crosstab", map[string]any{"path": path, "sheet": "Sheet1", "row_dimension": 1, "column_dimension": 2, "measure": 3, "header": true, "agg": "sum", "max_row_keys": 2, "max_col_keys": 1})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = CrosstabOutput{}
+	decodeStructured(t, res, &out)
+	require.Equal(t, []string{"North", "South", crosstabOther}, out.RowKeys)
+	require.Equal(t, []string{"1", crosstabOther}, out.ColumnKeys)
+	require.True(t, out.OtherRow)
+	require.True(t, out.OtherColumn)
+	require.Equal(t, 1, out.NonNumeric)
+	require.Equal(t, [][]*float64{{f(20), f(11)}, {f(5), nil}, {nil, f(10)}}, out.Values)
+	require.Equal(t, []*float64{f(31), f(5), f(10)}, out.RowTotals)
+	require.Equal(t, f(46), out.GrandTotal)
+
+	res = callTool(t, srv, "crosstab", map[string]any{"path": path, "sheet": "Sheet1", "row_dimension": 1, "column_dimension": 2, "agg": "avg"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: measure is required")
+
+	res = callTool(t, srv, "crosstab", map[string]any{"path": path, "sheet": "Sheet1", "row_dimension": 1, "column_dimension": 2, "max_row_keys": 100, "max_col_keys": 100})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "LIMIT_EXCEEDED")
+}
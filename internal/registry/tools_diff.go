@@ -0,0 +1,635 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+// Row change kinds reported by workbook_diff.
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+// WorkbookDiffInput defines parameters for workbook_diff.
+type WorkbookDiffInput struct {
+	Path          string     `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute path of the before workbook (allow‑list enforced)"`
+	Password      string     `json:"password,omitempty" jsonschema_description:"Password for an encrypted before workbook; used only to open it, never stored or echoed"`
+	OtherPath     string     `json:"other_path,omitempty" validate:"omitempty,filepath_ext" jsonschema_description:"Path of the after workbook; omitted compares two sheets of path"`
+	OtherPassword string     `json:"other_password,omitempty" jsonschema_description:"Password for an encrypted after workbook"`
+	Sheet         string     `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Sheet of the before workbook"`
+	OtherSheet    string     `json:"other_sheet,omitempty" jsonschema_description:"Sheet of the after workbook (default: sheet)"`
+	RangeA1       string     `json:"range,omitempty" validate:"omitempty,a1orname" jsonschema_description:"A1 range or defined name compared on both sides; omitted means the union of both sheets' used ranges"`
+	Header        bool       `json:"header,omitempty" jsonschema_description:"Treat the first row of the range as a header on both sides: it names columns and is not compared (implied when key is a name)"`
+	Key           *ColumnRef `json:"key,omitempty" jsonschema_description:"Align rows by this key column (1‑based index within the range or header name) instead of by position"`
+	Epsilon       float64    `json:"epsilon,omitempty" validate:"omitempty,min=0" jsonschema_description:"Treat numeric cells differing by at most this much as unchanged"`
+	ValueMode     string     `json:"value_mode,omitempty" validate:"omitempty,oneof=raw formatted" jsonschema_description:"Compare stored values ('raw', default; number formats are ignored) or displayed text ('formatted')"`
+	MaxRows       int        `json:"max_rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max differing rows per page (unit=rows, default 100)"`
+	SnapshotCols  int        `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max range columns in each before/after snapshot (default 16)"`
+	Cursor        string     `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor (unit=rows) from a previous page; carries both sides and the diff options"`
+}
+
+// DiffRow is one added, removed, or changed row. Row is the before sheet's
+// row number, OtherRow the after sheet's.
+type DiffRow struct {
+	Change   string   `json:"change" jsonschema_description:"added, removed, or changed"`
+	Key      string   `json:"key,omitempty" jsonschema_description:"Key value when rows are aligned by key"`
+	Row      int      `json:"row,omitempty"`
+	OtherRow int      `json:"otherRow,omitempty"`
+	Columns  []string `json:"columns,omitempty" jsonschema_description:"Column letters (before sheet) whose values changed"`
+	Before   []string `json:"before,omitempty"`
+	After    []string `json:"after,omitempty"`
+}
+
+// DiffColumn counts changed cells in one column across the whole diff.
+type DiffColumn struct {
+	Column  string `json:"column"`
+	Header  string `json:"header,omitempty"`
+	Changed int    `json:"changed"`
+}
+
+// WorkbookDiffOutput reports one page of row differences between two sheets.
+type WorkbookDiffOutput struct {
+	Path       string       `json:"path"`
+	OtherPath  string       `json:"otherPath"`
+	Sheet      string       `json:"sheet"`
+	OtherSheet string       `json:"otherSheet"`
+	RangeA1    string       `json:"range"`
+	OtherRange string       `json:"otherRange"`
+	Key        string       `json:"key,omitempty" jsonschema_description:"Key column (before sheet letter); empty when rows align by position"`
+	Rows       []DiffRow    `json:"rows"`
+	Columns    []DiffColumn `json:"columns" jsonschema_description:"Changed-cell counts per column over all changed rows, not just this page"`
+	Stats      struct {
+		RowsBefore    int  `json:"rowsBefore"`
+		RowsAfter     int  `json:"rowsAfter"`
+		Added         int  `json:"added"`
+		Removed       int  `json:"removed"`
+		Changed       int  `json:"changed"`
+		Unchanged     int  `json:"unchanged"`
+		DuplicateKeys int  `json:"duplicateKeys,omitempty" jsonschema_description:"Rows skipped because their key repeated an earlier row on the same side"`
+		BlankKeys     int  `json:"blankKeys,omitempty" jsonschema_description:"Rows skipped because their key cell was empty"`
+		ScanTruncated bool `json:"scanTruncated" jsonschema_description:"A side exceeded the per-operation cell limit; its later rows were not compared"`
+	} `json:"stats"`
+	Meta PageMeta `json:"meta"`
+}
+
+// diffOptions are the alignment and comparison settings carried in a
+// workbook_diff cursor. keyA and keyB are 1-based columns within each side's
+// range; zero aligns rows by position.
+type diffOptions struct {
+	header     bool
+	keyA, keyB int
+	epsilon    float64
+	formatted  bool
+	snapCols   int
+}
+
+func (o diffOptions) String() string {
+	b := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return fmt.Sprintf("h=%d;ka=%d;kb=%d;f=%d;n=%d;e=%s", b(o.header), o.keyA, o.keyB, b(o.formatted), o.snapCols, strconv.FormatFloat(o.epsilon, 'g', -1, 64))
+}
+
+func parseDiffOptions(s string) (diffOptions, error) {
+	var o diffOptions
+	var h, f int
+	var eps string
+	if _, err := fmt.Sscanf(s, "h=%d;ka=%d;kb=%d;f=%d;n=%d;e=%s", &h, &o.keyA, &o.keyB, &f, &o.snapCols, &eps); err != nil || o.snapCols <= 0 || o.keyA < 0 || o.keyB < 0 {
+		return o, fmt.Errorf("invalid diff options %q", s)
+	}
+	e, err := strconv.ParseFloat(eps, 64)
+	if err != nil || e < 0 {
+		return o, fmt.Errorf("invalid diff options %q", s)
+	}
+	o.header, o.formatted, o.epsilon = h == 1, f == 1, e
+	return o, nil
+}
+
+// diffSide holds one side's range, read into memory within the cell budget.
+// Rows are keyed by their 0-based offset from the range's first row.
+type diffSide struct {
+	x1, y1, x2, y2 int
+	resolved       string
+	header         []string
+	rows           map[int][]string
+	order          []int
+	truncated      bool
+	version        int64
+	mt             int64
+	fp             string
+}
+
+func (s *diffSide) width() int {
+	if s.resolved == "" {
+		return 0
+	}
+	return s.x2 - s.x1 + 1
+}
+
+// cell returns the value in the 0-based range column i of row k.
+func (s *diffSide) cell(k, i int) string {
+	if row := s.rows[k]; i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// diffEntry is one differing row before snapshots are attached.
+type diffEntry struct {
+	change string
+	key    string
+	a, b   int // row offsets on each side; -1 when absent
+	cols   []int
+}
+
+// RegisterDiffTools registers workbook_diff.
+func RegisterDiffTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"workbook_diff",
+		mcp.WithDescription(fmt.Sprintf("Compare two sheets, in two workbooks (path and other_path) or in one (sheet and other_sheet), and list the rows that were added, removed, or changed. Rows align by position, or by a key column (key: 1‑based index within the range or header name) when given; blank and repeated keys are skipped and counted. Values are compared as stored (value_mode=raw, default), so formatting-only changes never count; epsilon ignores small numeric differences. Each row lists the changed column letters with bounded before/after snapshots (snapshot_cols); columns reports changed-cell counts per column over the whole diff. Pagination operates in rows (unit=rows) over the differing rows; the cursor binds to both paths and content fingerprints and the diff options, and can be sent with path alone to resume. Each side scans at most %d cells; stats.scanTruncated reports when a side was larger. Both workbooks count toward the open-workbook limit. Errors: VALIDATION, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, ANALYSIS_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[WorkbookDiffInput](),
+		mcp.WithOutputSchema[WorkbookDiffOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in WorkbookDiffInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		idA, pathA, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		maxRows := pagination.PageSize(in.MaxRows, 0, 100, 1000)
+		otherPath := strings.TrimSpace(in.OtherPath)
+		sheet, otherSheet := strings.TrimSpace(in.Sheet), strings.TrimSpace(in.OtherSheet)
+		rng := strings.TrimSpace(in.RangeA1)
+		opts := diffOptions{header: in.Header || (in.Key != nil && in.Key.Name != ""), epsilon: in.Epsilon, formatted: in.ValueMode == valueModeFormatted, snapCols: in.SnapshotCols}
+		if opts.snapCols <= 0 || opts.snapCols > 256 {
+			opts.snapCols = 16
+		}
+
+		var startOffset int
+		var parsedCur, otherCur *pagination.Cursor
+		if curTok := strings.TrimSpace(in.Cursor); curTok != "" {
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
+			}
+			if pc.Pt != pathA {
+				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+			}
+			if pc.U != pagination.UnitRows || pc.Op == "" || pc.Os == "" || pc.Df == "" {
+				return mcperr.FromText("CURSOR_INVALID: cursor was not issued by workbook_diff"), nil
+			}
+			curOpts, perr := parseDiffOptions(pc.Df)
+			if perr != nil {
+				return mcperr.FromText("CURSOR_INVALID: " + perr.Error()), nil
+			}
+			sheet, otherPath, otherSheet, rng, opts = pc.S, pc.Op, pc.Os, pc.R, curOpts
+			startOffset = pc.Off
+			maxRows = pagination.PageSize(in.MaxRows, pc.Ps, maxRows, 1000)
+			parsedCur = pc
+			// The other side's snapshot is checked like a cursor of its own.
+			otherCur = &pagination.Cursor{Mt: pc.Omt, Fp: pc.Ofp, Hid: pc.Ohid, Wbv: pc.Owbv}
+		} else {
+			if sheet == "" {
+				return mcperr.FromText("VALIDATION: sheet is required (or supply cursor)"), nil
+			}
+			if otherPath == "" && otherSheet == "" {
+				return mcperr.FromText("VALIDATION: give other_path, other_sheet, or both"), nil
+			}
+		}
+		if otherSheet == "" {
+			otherSheet = sheet
+		}
+		idB, pathB := idA, pathA
+		if otherPath != "" {
+			if idB, pathB, openErr = mgr.GetOrOpenWithOptions(ctx, otherPath, workbooks.OpenOptions{Password: in.OtherPassword}); openErr != nil {
+				return openFailed(openErr), nil
+			}
+		}
+		if pathA == pathB && sheet == otherSheet {
+			return mcperr.FromText("VALIDATION: both sides name the same sheet of the same workbook"), nil
+		}
+
+		out := WorkbookDiffOutput{Path: pathA, OtherPath: pathB, Sheet: sheet, OtherSheet: otherSheet}
+		reg.changes.observeWorkbook(ctx, mgr, idA, pathA)
+		if idB != idA {
+			reg.changes.observeWorkbook(ctx, mgr, idB, pathB)
+		}
+		// Sides are read one after the other so two sheet locks are never
+		// held at once, even when both sheets are in the same workbook.
+		readSide := func(id, path, sheet string, cur *pagination.Cursor) (*diffSide, error) {
+			var side *diffSide
+			err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
+				mt, fp := fileSnapshot(path)
+				if err := checkCursor(mgr, cur, id, version, mt, fp); err != nil {
+					return err
+				}
+				var err error
+				if side, err = readDiffSide(ctx, f, sheet, rng, opts, limits.MaxCellsPerOp); err != nil {
+					return err
+				}
+				side.version, side.mt, side.fp = version, mt, fp
+				return nil
+			})
+			return side, err
+		}
+		var entries []diffEntry
+		err := func() error {
+			if rng == "" {
+				usedA, err := diffUsedRange(mgr, idA, sheet)
+				if err != nil {
+					return err
+				}
+				usedB, err := diffUsedRange(mgr, idB, otherSheet)
+				if err != nil {
+					return err
+				}
+				if rng = unionRange(usedA, usedB); rng == "" {
+					return mcperr.Errorf(mcperr.Validation, "both sheets are empty")
+				}
+			}
+			a, err := readSide(idA, pathA, sheet, parsedCur)
+			if err != nil {
+				return err
+			}
+			b, err := readSide(idB, pathB, otherSheet, otherCur)
+			if err != nil {
+				return err
+			}
+			out.RangeA1, out.OtherRange = a.resolved, b.resolved
+			if parsedCur == nil && in.Key != nil {
+				if err := resolveDiffKey(*in.Key, a, b, &opts); err != nil {
+					return err
+				}
+			}
+			if opts.keyA > 0 {
+				out.Key, _ = excelize.ColumnNumberToName(a.x1 + opts.keyA - 1)
+			}
+			entries = computeDiff(a, b, opts, &out)
+
+			end := min(startOffset+maxRows, len(entries))
+			out.Rows = []DiffRow{}
+			for _, e := range entries[min(startOffset, end):end] {
+				row := DiffRow{Change: e.change, Key: e.key}
+				if e.a >= 0 {
+					row.Row = a.y1 + e.a
+					row.Before = columnWindow(a.rows[e.a], 1, opts.snapCols)
+				}
+				if e.b >= 0 {
+					row.OtherRow = b.y1 + e.b
+					row.After = columnWindow(b.rows[e.b], 1, opts.snapCols)
+				}
+				for _, i := range e.cols {
+					name, _ := excelize.ColumnNumberToName(a.x1 + i)
+					row.Columns = append(row.Columns, name)
+				}
+				out.Rows = append(out.Rows, row)
+			}
+			out.Meta.Total = len(entries)
+			out.Meta.Returned = len(out.Rows)
+			out.Meta.Pages = pageCount(len(entries), maxRows)
+			out.Meta.Truncated = startOffset+len(out.Rows) < len(entries)
+			if out.Meta.Truncated {
+				next := pagination.Cursor{V: 1, Pt: pathA, S: sheet, R: rng, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(out.Rows)), Ps: maxRows, Mt: a.mt, Fp: a.fp, Hid: idA, Wbv: a.version, Op: pathB, Os: otherSheet, Omt: b.mt, Ofp: b.fp, Ohid: idB, Owbv: b.version, Df: opts.String()}
+				token, encErr := pagination.EncodeCursor(next)
+				if encErr != nil {
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
+				}
+				out.Meta.NextCursor = token
+			}
+			return nil
+		}()
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.AnalysisFailed, "%v", err), nil
+		}
+
+		summary := fmt.Sprintf("added=%d removed=%d changed=%d unchanged=%d returned=%d truncated=%v", out.Stats.Added, out.Stats.Removed, out.Stats.Changed, out.Stats.Unchanged, out.Meta.Returned, out.Meta.Truncated)
+		if out.Stats.ScanTruncated {
+			summary += " scanTruncated=true"
+		}
+		if out.Meta.NextCursor != "" {
+			summary += " nextCursor=" + out.Meta.NextCursor
+		}
+		lines := []string{summary}
+		for _, r := range out.Rows {
+			key := ""
+			if r.Key != "" {
+				key = " key=" + r.Key
+			}
+			switch r.Change {
+			case diffAdded:
+				lines = append(lines, fmt.Sprintf("- added otherRow=%d%s: %s", r.OtherRow, key, compactRow(r.After)))
+			case diffRemoved:
+				lines = append(lines, fmt.Sprintf("- removed row=%d%s: %s", r.Row, key, compactRow(r.Before)))
+			default:
+				lines = append(lines, fmt.Sprintf("- changed row=%d otherRow=%d%s columns=%s", r.Row, r.OtherRow, key, strings.Join(r.Columns, ",")))
+			}
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(tool)
+}
+
+// diffUsedRange returns sheet's used range, or "" when it is empty.
+func diffUsedRange(mgr *workbooks.Manager, id, sheet string) (string, error) {
+	var used string
+	err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+		if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+			return mcperr.Errorf(mcperr.InvalidSheet, "sheet %q not found", sheet)
+		}
+		used, _ = scanUsedRange(f, sheet)
+		return nil
+	})
+	return used, err
+}
+
+// unionRange returns the smallest A1 range covering both ranges; an empty
+// range contributes nothing.
+func unionRange(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	ca, okA := rangeCoordinates(a)
+	cb, okB := rangeCoordinates(b)
+	if !okA || !okB {
+		return a
+	}
+	tl, _ := excelize.CoordinatesToCellName(min(ca[0], cb[0]), min(ca[1], cb[1]))
+	br, _ := excelize.CoordinatesToCellName(max(ca[2], cb[2]), max(ca[3], cb[3]))
+	return tl + ":" + br
+}
+
+// rangeCoordinates parses an A1:B2 range into x1, y1, x2, y2.
+func rangeCoordinates(ref string) ([4]int, bool) {
+	var c [4]int
+	from, to, ok := strings.Cut(ref, ":")
+	if !ok {
+		return c, false
+	}
+	var err1, err2 error
+	c[0], c[1], err1 = excelize.CellNameToCoordinates(from)
+	c[2], c[3], err2 = excelize.CellNameToCoordinates(to)
+	return c, err1 == nil && err2 == nil
+}
+
+// readDiffSide reads rng of sheet into memory, scanning at most budget
+// cells. The header row, when opts.header is set, names columns and is not
+// stored as data; blank rows are left out.
+func readDiffSide(ctx context.Context, f *excelize.File, sheet, rng string, opts diffOptions, budget int) (*diffSide, error) {
+	if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+		return nil, mcperr.Errorf(mcperr.InvalidSheet, "sheet %q not found", sheet)
+	}
+	x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
+	if perr != nil {
+		return nil, mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+	}
+	side := &diffSide{x1: x1, y1: y1, x2: x2, y2: y2, resolved: resolved, rows: map[int][]string{}}
+	var colOpts []excelize.Options
+	if !opts.formatted {
+		colOpts = append(colOpts, excelize.Options{RawCellValue: true})
+	}
+	rowsIter, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rowsIter.Close()
+	width := side.width()
+	cells, rowIdx := 0, 0
+	for rowsIter.Next() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		rowIdx++
+		if rowIdx < y1 {
+			continue
+		}
+		if rowIdx > y2 {
+			break
+		}
+		vals, cerr := rowsIter.Columns(colOpts...)
+		if cerr != nil {
+			return nil, cerr
+		}
+		window := columnWindow(vals, x1, x2)
+		if opts.header && rowIdx == y1 {
+			side.header = window
+			continue
+		}
+		cells += width
+		if cells > budget {
+			side.truncated = true
+			break
+		}
+		blank := true
+		for _, v := range window {
+			if strings.TrimSpace(v) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+		k := rowIdx - y1
+		side.rows[k] = window
+		side.order = append(side.order, k)
+	}
+	return side, nil
+}
+
+// resolveDiffKey records the key column of each side in opts. An index
+// applies to both ranges; a header name is looked up on each side, so
+// reordered columns still align.
+func resolveDiffKey(key ColumnRef, a, b *diffSide, opts *diffOptions) error {
+	resolve := func(s *diffSide, label string) (int, error) {
+		cols, err := resolveColumnRefs([]ColumnRef{key}, s.header, s.y1)
+		if err != nil {
+			var coded *mcperr.Error
+			if errors.As(err, &coded) {
+				return 0, mcperr.Errorf(coded.Code, "%s: %s", label, coded.Message)
+			}
+			return 0, err
+		}
+		if cols[0] > s.width() {
+			return 0, mcperr.Errorf(mcperr.Validation, "%s: key column %d outside range (%d columns)", label, cols[0], s.width())
+		}
+		return cols[0], nil
+	}
+	var err error
+	if opts.keyA, err = resolve(a, "before sheet"); err != nil {
+		return err
+	}
+	opts.keyB, err = resolve(b, "after sheet")
+	return err
+}
+
+// diffCellEqual compares two cell values, treating numbers within epsilon
+// as equal.
+func diffCellEqual(a, b string, epsilon float64) bool {
+	if a == b {
+		return true
+	}
+	if epsilon <= 0 {
+		return false
+	}
+	x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	return errA == nil && errB == nil && math.Abs(x-y) <= epsilon
+}
+
+// computeDiff aligns the sides' rows and returns the differing ones, filling
+// out's stats and per-column counts. Positional diffs are ordered by row;
+// keyed diffs list matched and added rows in the after sheet's order, then
+// removed rows in the before sheet's order.
+func computeDiff(a, b *diffSide, opts diffOptions, out *WorkbookDiffOutput) []diffEntry {
+	width := max(a.width(), b.width())
+	changedCols := func(ka, kb int) []int {
+		var cols []int
+		for i := 0; i < width; i++ {
+			if !diffCellEqual(a.cell(ka, i), b.cell(kb, i), opts.epsilon) {
+				cols = append(cols, i)
+			}
+		}
+		return cols
+	}
+	out.Stats.RowsBefore, out.Stats.RowsAfter = len(a.order), len(b.order)
+	out.Stats.ScanTruncated = a.truncated || b.truncated
+	var entries []diffEntry
+	add := func(e diffEntry) {
+		switch e.change {
+		case diffAdded:
+			out.Stats.Added++
+		case diffRemoved:
+			out.Stats.Removed++
+		case diffChanged:
+			out.Stats.Changed++
+		}
+		entries = append(entries, e)
+	}
+	compare := func(ka, kb int, key string) {
+		if cols := changedCols(ka, kb); len(cols) > 0 {
+			add(diffEntry{change: diffChanged, key: key, a: ka, b: kb, cols: cols})
+			return
+		}
+		out.Stats.Unchanged++
+	}
+
+	if opts.keyA == 0 {
+		ks := make([]int, 0, len(a.order)+len(b.order))
+		ks = append(append(ks, a.order...), b.order...)
+		sort.Ints(ks)
+		for i, k := range ks {
+			if i > 0 && ks[i-1] == k {
+				continue
+			}
+			_, inA := a.rows[k]
+			_, inB := b.rows[k]
+			switch {
+			case inA && inB:
+				compare(k, k, "")
+			case inA:
+				add(diffEntry{change: diffRemoved, a: k, b: -1})
+			default:
+				add(diffEntry{change: diffAdded, a: -1, b: k})
+			}
+		}
+	} else {
+		keyOf := func(s *diffSide, k, col int) string {
+			return strings.TrimSpace(s.cell(k, col-1))
+		}
+		// index maps each key to the first before row holding it.
+		index := map[string]int{}
+		for _, k := range a.order {
+			key := keyOf(a, k, opts.keyA)
+			if key == "" {
+				out.Stats.BlankKeys++
+				continue
+			}
+			if _, dup := index[key]; dup {
+				out.Stats.DuplicateKeys++
+				continue
+			}
+			index[key] = k
+		}
+		matched := map[int]bool{}
+		seen := map[string]bool{}
+		for _, k := range b.order {
+			key := keyOf(b, k, opts.keyB)
+			if key == "" {
+				out.Stats.BlankKeys++
+				continue
+			}
+			if seen[key] {
+				out.Stats.DuplicateKeys++
+				continue
+			}
+			seen[key] = true
+			if ka, ok := index[key]; ok {
+				matched[ka] = true
+				compare(ka, k, key)
+				continue
+			}
+			add(diffEntry{change: diffAdded, key: key, a: -1, b: k})
+		}
+		for _, k := range a.order {
+			key := keyOf(a, k, opts.keyA)
+			if key == "" || index[key] != k || matched[k] {
+				continue
+			}
+			add(diffEntry{change: diffRemoved, key: key, a: k, b: -1})
+		}
+	}
+
+	counts := map[int]int{}
+	for _, e := range entries {
+		for _, i := range e.cols {
+			counts[i]++
+		}
+	}
+	out.Columns = []DiffColumn{}
+	for i := 0; i < width; i++ {
+		if counts[i] == 0 {
+			continue
+		}
+		name, _ := excelize.ColumnNumberToName(a.x1 + i)
+		header := ""
+		if i < len(a.header) {
+			header = a.header[i]
+		}
+		if header == "" && i < len(b.header) {
+			header = b.header[i]
+		}
+		out.Columns = append(out.Columns, DiffColumn{Column: name, Header: header, Changed: counts[i]})
+	}
+	return entries
+}
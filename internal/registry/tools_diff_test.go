@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeDiffWorkbook saves rows to Sheet1 of name in dir, plus any extra
+// sheets given in more.
+func writeDiffWorkbook(t *testing.T, dir, name string, rows [][]any, more map[string][][]any) string {
+	t.Helper()
+	f := excelize.NewFile()
+	put := func(sheet string, rows [][]any) {
+		for i, r := range rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			require.NoError(t, f.SetSheetRow(sheet, cell, &r))
+		}
+	}
+	put("Sheet1", rows)
+	for sheet, rows := range more {
+		_, err := f.NewSheet(sheet)
+		require.NoError(t, err)
+		put(sheet, rows)
+	}
+	path := filepath.Join(dir, name)
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestWorkbookDiff(t *testing.T) {
+	srv, _ := newTestServer(t)
+	dir := t.TempDir()
+	before := writeDiffWorkbook(t, dir, "before.xlsx", [][]any{
+		{"ID", "Name", "Amount"},
+		{1, "Ann", 10},
+		{2, "Bob", 20},
+		{3, "Cid", 30},
+		{4, "Dee", 40},
+	}, nil)
+	afterRows := [][]any{
+		{"ID", "Name", "Amount"},
+		{2, "Bob", 25},
+		{1, "Ann", 10.0000001},
+		{4, "Dee", 40},
+		{5, "Eve", 50},
+	}
+	after := writeDiffWorkbook(t, dir, "after.xlsx", afterRows, nil)
+
+	// Keyed by header name: Bob changed, Eve added, Cid removed; Ann's
+	// difference is below epsilon.
+	args := map[string]any{"path": before, "other_path": after, "sheet": "Sheet1", "key": "id", "epsilon": 0.001, "max_rows": 2}
+	res := callTool(t, srv, "workbook_diff", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got WorkbookDiffOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, "A", got.Key)
+	require.Equal(t, 1, got.Stats.Added)
+	require.Equal(t, 1, got.Stats.Removed)
+	require.Equal(t, 1, got.Stats.Changed)
+	require.Equal(t, 2, got.Stats.Unchanged)
+	require.Equal(t, 3, got.Meta.Total)
+	require.Len(t, got.Rows, 2)
+	require.Equal(t, DiffRow{Change: "changed", Key: "2", Row: 3, OtherRow: 2, Columns: []string{"C"}, Before: []string{"2", "Bob", "20"}, After: []string{"2", "Bob", "25"}}, got.Rows[0])
+	require.Equal(t, "added", got.Rows[1].Change)
+	require.Equal(t, 5, got.Rows[1].OtherRow)
+	require.Equal(t, []DiffColumn{{Column: "C", Header: "Amount", Changed: 1}}, got.Columns)
+	require.NotEmpty(t, got.Meta.NextCursor)
+	require.Contains(t, resultText(t, res), "- changed row=3 otherRow=2 key=2 columns=C")
+
+	// The cursor carries the other side and the options.
+	res = callTool(t, srv, "workbook_diff", map[string]any{"path": before, "cursor": got.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var page2 WorkbookDiffOutput
+	decodeStructured(t, res, &page2)
+	require.Len(t, page2.Rows, 1)
+	require.Equal(t, DiffRow{Change: "removed", Key: "3", Row: 4, Before: []string{"3", "Cid", "30"}}, page2.Rows[0])
+	require.False(t, page2.Meta.Truncated)
+
+	// Positional alignment compares row by row, so every shifted row differs.
+	res = callTool(t, srv, "workbook_diff", map[string]any{"path": before, "other_path": after, "sheet": "Sheet1", "header": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var positional WorkbookDiffOutput
+	decodeStructured(t, res, &positional)
+	require.Empty(t, positional.Key)
+	require.Equal(t, 4, positional.Stats.Changed)
+	require.Zero(t, positional.Stats.Unchanged)
+	require.Equal(t, []DiffColumn{{Column: "A", Header: "ID", Changed: 4}, {Column: "B", Header: "Name", Changed: 4}, {Column: "C", Header: "Amount", Changed: 4}}, positional.Columns)
+
+	// Editing the other workbook invalidates the cursor.
+	afterRows[1][2] = 26
+	writeDiffWorkbook(t, dir, "after.xlsx", afterRows, nil)
+	res = callTool(t, srv, "workbook_diff", map[string]any{"path": before, "cursor": got.Meta.NextCursor})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+}
+
+func TestWorkbookDiff_SheetsOfOneWorkbook(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := writeDiffWorkbook(t, t.TempDir(), "book.xlsx", [][]any{
+		{"x", 1},
+		{"y", 2},
+	}, map[string][][]any{"Sheet2": {
+		{"x", 1},
+		{"y", 2},
+		{"z", 3, "extra"},
+	}})
+
+	res := callTool(t, srv, "workbook_diff", map[string]any{"path": path, "sheet": "Sheet1", "other_sheet": "Sheet2"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got WorkbookDiffOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, "A1:C3", got.RangeA1)
+	require.Equal(t, 2, got.Stats.Unchanged)
+	require.Equal(t, []DiffRow{{Change: "added", OtherRow: 3, After: []string{"z", "3", "extra"}}}, got.Rows)
+
+	res = callTool(t, srv, "workbook_diff", map[string]any{"path": path, "sheet": "Sheet1"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+	res = callTool(t, srv, "workbook_diff", map[string]any{"path": path, "sheet": "Sheet1", "other_sheet": "Nope"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_SHEET")
+}
@@ -0,0 +1,421 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// FindDuplicatesInput defines parameters for find_duplicates.
+type FindDuplicatesInput struct {
+	Path            string   `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password        string   `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet           string   `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Sheet to scan"`
+	RangeA1         string   `json:"range,omitempty" validate:"omitempty,a1orname" jsonschema_description:"Optional A1 range or defined name; omitted means the sheet's used range"`
+	KeyColumns      []int    `json:"key_columns,omitempty" validate:"omitempty,max=16,dive,min=1" jsonschema_description:"1‑based column indices within the range forming the composite key"`
+	KeyNames        []string `json:"key_names,omitempty" validate:"omitempty,max=16" jsonschema_description:"Header names forming the composite key (matched case‑insensitively in the range's first row; implies header=true)"`
+	Header          bool     `json:"header,omitempty" jsonschema_description:"Treat the first row of the range as a header and skip it"`
+	CaseInsensitive bool     `json:"case_insensitive,omitempty" jsonschema_description:"Compare key values ignoring case"`
+	Trim            bool     `json:"trim,omitempty" jsonschema_description:"Trim surrounding whitespace from key values before comparing"`
+	MaxGroups       int      `json:"max_groups,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max duplicate groups to report, earliest first (default 100)"`
+	MaxRows         int      `json:"max_rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max duplicate rows per page (unit=rows, default 200)"`
+	SnapshotCols    int      `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max range columns to include in each row snapshot (default 16)"`
+	Cursor          string   `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor (unit=rows) from a previous page; carries sheet, range, and key options"`
+}
+
+// DuplicateRow is one row of a duplicate group.
+type DuplicateRow struct {
+	Row      int      `json:"row"`
+	Snapshot []string `json:"snapshot"`
+}
+
+// DuplicateGroup lists rows sharing a key. A group split across pages
+// repeats on the next page with Continued set.
+type DuplicateGroup struct {
+	Group     int            `json:"group" jsonschema_description:"1‑based group number, ordered by first occurrence"`
+	Key       []string       `json:"key" jsonschema_description:"Key values from the group's first row on this page"`
+	Count     int            `json:"count" jsonschema_description:"Rows in the whole group"`
+	Continued bool           `json:"continued,omitempty"`
+	Rows      []DuplicateRow `json:"rows"`
+}
+
+// FindDuplicatesOutput reports one page of duplicate rows grouped by key.
+type FindDuplicatesOutput struct {
+	Path       string           `json:"path"`
+	Sheet      string           `json:"sheet"`
+	RangeA1    string           `json:"range"`
+	KeyColumns []int            `json:"keyColumns"`
+	Groups     []DuplicateGroup `json:"groups"`
+	Stats      struct {
+		RowsScanned     int  `json:"rowsScanned"`
+		BlankKeys       int  `json:"blankKeys" jsonschema_description:"Rows skipped because every key cell was empty"`
+		GroupsTotal     int  `json:"groupsTotal"`
+		GroupsTruncated bool `json:"groupsTruncated"`
+		ScanTruncated   bool `json:"scanTruncated" jsonschema_description:"The range exceeded the per-operation cell limit; later rows were not scanned"`
+	} `json:"stats"`
+	Meta PageMeta `json:"meta"`
+}
+
+// duplicateOptions are the key settings carried in a find_duplicates cursor.
+type duplicateOptions struct {
+	caseInsensitive, trim, header bool
+	maxGroups                     int
+}
+
+func (o duplicateOptions) String() string {
+	b := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return fmt.Sprintf("c=%d;t=%d;h=%d;g=%d", b(o.caseInsensitive), b(o.trim), b(o.header), o.maxGroups)
+}
+
+func parseDuplicateOptions(s string) (duplicateOptions, error) {
+	var o duplicateOptions
+	var c, t, h int
+	if _, err := fmt.Sscanf(s, "c=%d;t=%d;h=%d;g=%d", &c, &t, &h, &o.maxGroups); err != nil || o.maxGroups <= 0 {
+		return o, fmt.Errorf("invalid duplicate options %q", s)
+	}
+	o.caseInsensitive, o.trim, o.header = c == 1, t == 1, h == 1
+	return o, nil
+}
+
+// RegisterDuplicateTools registers find_duplicates.
+func RegisterDuplicateTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"find_duplicates",
+		mcp.WithDescription(fmt.Sprintf("List records that share a composite key so they can be reviewed or cleaned before statistics are trusted. Give key_columns (1‑based within the range) or key_names (header names; implies header=true); case_insensitive and trim normalize key values. Groups are ordered by first occurrence and capped by max_groups; each lists every row number with a bounded snapshot (snapshot_cols). Rows whose key cells are all empty are skipped. Pagination operates in rows (unit=rows) over the grouped duplicates; a group split across pages repeats with continued=true. The cursor binds to path+content fingerprint and the key options and can be sent alone to resume. At most %d cells are scanned; stats.scanTruncated reports when the range was larger. Errors: VALIDATION, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, ANALYSIS_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[FindDuplicatesInput](),
+		mcp.WithOutputSchema[FindDuplicatesOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in FindDuplicatesInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		maxRows := pagination.PageSize(in.MaxRows, 0, 200, 1000)
+		snapshotCols := in.SnapshotCols
+		if snapshotCols <= 0 || snapshotCols > 256 {
+			snapshotCols = 16
+		}
+		opts := duplicateOptions{caseInsensitive: in.CaseInsensitive, trim: in.Trim, header: in.Header || len(in.KeyNames) > 0, maxGroups: in.MaxGroups}
+		if opts.maxGroups <= 0 || opts.maxGroups > 1000 {
+			opts.maxGroups = 100
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		rng := strings.TrimSpace(in.RangeA1)
+		keyCols := in.KeyColumns
+
+		var startOffset int
+		var parsedCur *pagination.Cursor
+		if curTok := strings.TrimSpace(in.Cursor); curTok != "" {
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
+			}
+			if pc.Pt != canonical {
+				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+			}
+			if pc.U != pagination.UnitRows || pc.Dk == "" || len(pc.Cl) == 0 {
+				return mcperr.FromText("CURSOR_INVALID: cursor was not issued by find_duplicates"), nil
+			}
+			curOpts, perr := parseDuplicateOptions(pc.Dk)
+			if perr != nil {
+				return mcperr.FromText("CURSOR_INVALID: " + perr.Error()), nil
+			}
+			if len(keyCols) > 0 && computePredicateHash(opts.String(), keyCols) != pc.Ph {
+				return mcperr.FromText("CURSOR_INVALID: cursor parameters do not match current key columns/options"), nil
+			}
+			sheet, rng, keyCols, opts = pc.S, pc.R, pc.Cl, curOpts
+			startOffset = pc.Off
+			maxRows = pagination.PageSize(in.MaxRows, pc.Ps, maxRows, 1000)
+			parsedCur = pc
+		} else {
+			if sheet == "" {
+				return mcperr.FromText("VALIDATION: sheet is required (or supply cursor)"), nil
+			}
+			if len(keyCols) == 0 && len(in.KeyNames) == 0 {
+				return mcperr.FromText("VALIDATION: key_columns or key_names is required"), nil
+			}
+			if len(keyCols) > 0 && len(in.KeyNames) > 0 {
+				return mcperr.FromText("VALIDATION: use key_columns or key_names, not both"), nil
+			}
+		}
+
+		out := FindDuplicatesOutput{Path: canonical, Sheet: sheet}
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
+			fileMT, fileFP := fileSnapshot(canonical)
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
+			}
+			if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			if rng == "" {
+				rng, _ = scanUsedRange(f, sheet)
+			}
+			if rng == "" {
+				return mcperr.Errorf(mcperr.Validation, "sheet is empty")
+			}
+			x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+			}
+			out.RangeA1 = resolved
+			colCount := x2 - x1 + 1
+
+			// Pass 1: hash each row's normalized composite key.
+			type dupGroup struct {
+				first int
+				rows  []int
+			}
+			groups := map[uint64]*dupGroup{}
+			var keyAbs []int
+			resolveKeys := func(header []string) error {
+				if len(in.KeyNames) > 0 && len(keyCols) == 0 {
+					for _, name := range in.KeyNames {
+						found := 0
+						for i := 0; i < colCount && found == 0; i++ {
+							if i+x1-1 < len(header) && strings.EqualFold(strings.TrimSpace(header[i+x1-1]), strings.TrimSpace(name)) {
+								found = i + 1
+							}
+						}
+						if found == 0 {
+							return mcperr.Errorf(mcperr.Validation, "key name %q not found in header row %d", name, y1)
+						}
+						keyCols = append(keyCols, found)
+					}
+				}
+				for _, k := range keyCols {
+					if k < 1 || k > colCount {
+						return mcperr.Errorf(mcperr.Validation, "key column %d outside range (%d columns)", k, colCount)
+					}
+					keyAbs = append(keyAbs, x1+k-2)
+				}
+				return nil
+			}
+			if !opts.header {
+				if err := resolveKeys(nil); err != nil {
+					return err
+				}
+			}
+			normKey := func(vals []string) ([]string, bool) {
+				key := make([]string, len(keyAbs))
+				blank := true
+				for i, abs := range keyAbs {
+					if abs < len(vals) {
+						key[i] = vals[abs]
+					}
+					if opts.trim {
+						key[i] = strings.TrimSpace(key[i])
+					}
+					if opts.caseInsensitive {
+						key[i] = strings.ToLower(key[i])
+					}
+					if key[i] != "" {
+						blank = false
+					}
+				}
+				return key, blank
+			}
+
+			rowsIter, rerr := f.Rows(sheet)
+			if rerr != nil {
+				return rerr
+			}
+			cells := 0
+			rowIdx := 0
+			for rowsIter.Next() {
+				if ctx.Err() != nil {
+					_ = rowsIter.Close()
+					return ctx.Err()
+				}
+				rowIdx++
+				if rowIdx < y1 {
+					continue
+				}
+				if rowIdx > y2 {
+					break
+				}
+				vals, cerr := rowsIter.Columns()
+				if cerr != nil {
+					_ = rowsIter.Close()
+					return cerr
+				}
+				if opts.header && rowIdx == y1 {
+					if err := resolveKeys(vals); err != nil {
+						_ = rowsIter.Close()
+						return err
+					}
+					continue
+				}
+				cells += colCount
+				if cells > limits.MaxCellsPerOp {
+					out.Stats.ScanTruncated = true
+					break
+				}
+				out.Stats.RowsScanned++
+				key, blank := normKey(vals)
+				if blank {
+					out.Stats.BlankKeys++
+					continue
+				}
+				h := fnv.New64a()
+				for _, k := range key {
+					_, _ = h.Write([]byte(k))
+					_, _ = h.Write([]byte{0x1f})
+				}
+				sum := h.Sum64()
+				g, ok := groups[sum]
+				if !ok {
+					g = &dupGroup{first: rowIdx}
+					groups[sum] = g
+				}
+				g.rows = append(g.rows, rowIdx)
+			}
+			_ = rowsIter.Close()
+			if len(keyAbs) == 0 {
+				return mcperr.Errorf(mcperr.Validation, "range has no header row to match key_names")
+			}
+			out.KeyColumns = keyCols
+
+			dups := make([]*dupGroup, 0)
+			for _, g := range groups {
+				if len(g.rows) > 1 {
+					dups = append(dups, g)
+				}
+			}
+			sort.Slice(dups, func(i, j int) bool { return dups[i].first < dups[j].first })
+			out.Stats.GroupsTotal = len(dups)
+			if len(dups) > opts.maxGroups {
+				dups = dups[:opts.maxGroups]
+				out.Stats.GroupsTruncated = true
+			}
+
+			// Flatten rows in group order and select this page.
+			type pageRow struct {
+				group, row, count int
+				continued         bool
+			}
+			var page []pageRow
+			total := 0
+			for gi, g := range dups {
+				for ri, r := range g.rows {
+					if total >= startOffset && len(page) < maxRows {
+						page = append(page, pageRow{group: gi + 1, row: r, count: len(g.rows), continued: ri > 0 && len(page) == 0})
+					}
+					total++
+				}
+			}
+
+			// Pass 2: snapshot the rows on this page.
+			want := make(map[int][]string, len(page))
+			for _, p := range page {
+				want[p.row] = nil
+			}
+			snapEnd := x2
+			if x1+snapshotCols-1 < snapEnd {
+				snapEnd = x1 + snapshotCols - 1
+			}
+			keyVals := map[int][]string{}
+			if len(want) > 0 {
+				rowsIter, rerr = f.Rows(sheet)
+				if rerr != nil {
+					return rerr
+				}
+				defer rowsIter.Close()
+				last := 0
+				for r := range want {
+					if r > last {
+						last = r
+					}
+				}
+				for r := 1; rowsIter.Next() && r <= last; r++ {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					if _, ok := want[r]; !ok {
+						continue
+					}
+					vals, cerr := rowsIter.Columns()
+					if cerr != nil {
+						return cerr
+					}
+					want[r] = columnWindow(vals, x1, snapEnd)
+					keyVals[r] = make([]string, len(keyAbs))
+					for i, abs := range keyAbs {
+						if abs < len(vals) {
+							keyVals[r][i] = vals[abs]
+						}
+					}
+				}
+			}
+
+			out.Groups = []DuplicateGroup{}
+			for _, p := range page {
+				n := len(out.Groups)
+				if n == 0 || out.Groups[n-1].Group != p.group {
+					out.Groups = append(out.Groups, DuplicateGroup{Group: p.group, Key: keyVals[p.row], Count: p.count, Continued: p.continued})
+					n++
+				}
+				out.Groups[n-1].Rows = append(out.Groups[n-1].Rows, DuplicateRow{Row: p.row, Snapshot: want[p.row]})
+			}
+			out.Meta.Total = total
+			out.Meta.Returned = len(page)
+			out.Meta.Pages = pageCount(total, maxRows)
+			out.Meta.Truncated = startOffset+len(page) < total
+			if out.Meta.Truncated {
+				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: resolved, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(page)), Ps: maxRows, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: version, Ph: computePredicateHash(opts.String(), keyCols), Cl: keyCols, Dk: opts.String()}
+				token, encErr := pagination.EncodeCursor(next)
+				if encErr != nil {
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
+				}
+				out.Meta.NextCursor = token
+			}
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.AnalysisFailed, "%v", err), nil
+		}
+
+		summary := fmt.Sprintf("groups=%d duplicateRows=%d returned=%d truncated=%v scanned=%d", out.Stats.GroupsTotal, out.Meta.Total, out.Meta.Returned, out.Meta.Truncated, out.Stats.RowsScanned)
+		if out.Meta.NextCursor != "" {
+			summary += " nextCursor=" + out.Meta.NextCursor
+		}
+		lines := []string{summary}
+		for _, g := range out.Groups {
+			rows := make([]string, len(g.Rows))
+			for i, r := range g.Rows {
+				rows[i] = fmt.Sprint(r.Row)
+			}
+			lines = append(lines, fmt.Sprintf("- group %d key=%s count=%d rows=%s", g.Group, compactRow(g.Key), g.Count, strings.Join(rows, ",")))
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(tool)
+}
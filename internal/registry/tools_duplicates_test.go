@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func createDuplicatesWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	rows := [][]any{
+		{"Email", "Name", "Amount"},
+		{"a@x.com", "Ann", 10},
+		{"b@x.com", "Bob", 20},
+		{"A@X.com ", "Ann", 30},
+		{"c@x.com", "Cid", 40},
+		{"b@x.com", "Bob", 50},
+		{"", "", 60},
+		{"", "", 70},
+		{"a@x.com", "Ann", 80},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "dups.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestFindDuplicates(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createDuplicatesWorkbook(t)
+
+	// Exact keys: only b@x.com (rows 3, 6) and a@x.com (rows 2, 9) repeat.
+	res := callTool(t, srv, "find_duplicates", map[string]any{"path": path, "sheet": "Sheet1", "key_names": []string{"email"}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got FindDuplicatesOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, "A1:C9", got.RangeA1)
+	require.Equal(t, []int{1}, got.KeyColumns)
+	require.Equal(t, 8, got.Stats.RowsScanned)
+	require.Equal(t, 2, got.Stats.BlankKeys)
+	require.Len(t, got.Groups, 2)
+	require.Equal(t, []string{"a@x.com"}, got.Groups[0].Key)
+	require.Equal(t, []DuplicateRow{{Row: 2, Snapshot: []string{"a@x.com", "Ann", "10"}}, {Row: 9, Snapshot: []string{"a@x.com", "Ann", "80"}}}, got.Groups[0].Rows)
+	require.Equal(t, 3, got.Groups[1].Rows[0].Row)
+
+	// Normalized keys fold row 4 into the a@x.com group; page through two rows at a time.
+	args := map[string]any{"path": path, "sheet": "Sheet1", "key_columns": []int{1, 2}, "header": true, "case_insensitive": true, "trim": true, "max_rows": 2}
+	res = callTool(t, srv, "find_duplicates", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	got = FindDuplicatesOutput{}
+	decodeStructured(t, res, &got)
+	require.Equal(t, 5, got.Meta.Total)
+	require.Equal(t, 3, got.Meta.Pages)
+	require.Len(t, got.Groups, 1)
+	require.Equal(t, 3, got.Groups[0].Count)
+	require.Equal(t, []int{2, 4}, []int{got.Groups[0].Rows[0].Row, got.Groups[0].Rows[1].Row})
+	require.NotEmpty(t, got.Meta.NextCursor)
+
+	res = callTool(t, srv, "find_duplicates", map[string]any{"path": path, "cursor": got.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var page2 FindDuplicatesOutput
+	decodeStructured(t, res, &page2)
+	require.Len(t, page2.Groups, 2)
+	require.True(t, page2.Groups[0].Continued)
+	require.Equal(t, 9, page2.Groups[0].Rows[0].Row)
+	require.Equal(t, 2, page2.Groups[1].Group)
+
+	// A cursor cannot be reused with different key options.
+	args["cursor"] = got.Meta.NextCursor
+	args["case_insensitive"] = false
+	res = callTool(t, srv, "find_duplicates", args)
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+
+	res = callTool(t, srv, "find_duplicates", map[string]any{"path": path, "sheet": "Sheet1", "key_names": []string{"Phone"}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
@@ -0,0 +1,215 @@
+package registry
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportRangeCSVInput defines parameters for export_range_csv.
+type ExportRangeCSVInput struct {
+	Path       string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password   string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet      string `json:"sheet" validate:"required" jsonschema_description:"Sheet to export from"`
+	RangeA1    string `json:"range,omitempty" validate:"omitempty,a1orname" jsonschema_description:"Optional A1 range or defined name; omitted means the sheet's used range"`
+	Predicate  string `json:"predicate,omitempty" jsonschema_description:"Optional filter_data predicate ($N = absolute 1‑based column); only matching rows are exported"`
+	Header     bool   `json:"header,omitempty" jsonschema_description:"Treat the first row of the range as a header: always exported, never filtered"`
+	OutputPath string `json:"output_path" validate:"required" jsonschema_description:"Absolute .csv path inside an allow‑listed directory; the directory must exist"`
+	Overwrite  bool   `json:"overwrite,omitempty" jsonschema_description:"Replace output_path if it already exists"`
+}
+
+// ExportRangeCSVOutput reports the written file.
+type ExportRangeCSVOutput struct {
+	Path       string `json:"path"`
+	Sheet      string `json:"sheet"`
+	RangeA1    string `json:"range"`
+	Predicate  string `json:"predicate,omitempty"`
+	OutputPath string `json:"outputPath"`
+	Rows       int    `json:"rows" jsonschema_description:"CSV records written, including the header when header=true"`
+	Columns    int    `json:"columns"`
+	Bytes      int64  `json:"bytes"`
+}
+
+// RegisterExportTools registers export_range_csv (write-gated).
+func RegisterExportTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	export := mcp.NewTool(
+		"export_range_csv",
+		mcp.WithDescription(fmt.Sprintf("Write a range (default: the sheet's used range) to a new CSV file instead of returning the cells, optionally keeping only rows that match a filter_data predicate ($N are absolute columns; header=true always keeps the first row). Use to hand large slices to other systems without passing them through the conversation. Returns the written path, record count, columns, and byte size. output_path must end in .csv inside an allow‑listed directory that exists; existing files are refused unless overwrite=true. The range is capped at %d cells. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, LIMIT_EXCEEDED, PERMISSION_DENIED, WRITE_FAILED.", limits.MaxExportCells)),
+		mcp.WithInputSchema[ExportRangeCSVInput](),
+		mcp.WithOutputSchema[ExportRangeCSVOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(export, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ExportRangeCSVInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		outIn := strings.TrimSpace(in.OutputPath)
+		if !strings.EqualFold(filepath.Ext(outIn), ".csv") {
+			return mcperr.FromText("VALIDATION: output_path must end in .csv"), nil
+		}
+		outPath, verr := mgr.ValidateWritePath(outIn)
+		if verr != nil {
+			if errors.Is(verr, security.ErrNotFound) {
+				return mcperr.FromText("VALIDATION: output directory does not exist"), nil
+			}
+			if res := workbookAccessError(verr); res != nil {
+				return res, nil
+			}
+			return mcperr.FromText(fmt.Sprintf("PERMISSION_DENIED: output path not allowed: %v", verr)), nil
+		}
+		if fi, serr := os.Stat(filepath.Dir(outPath)); serr != nil || !fi.IsDir() {
+			return mcperr.FromText("VALIDATION: output directory does not exist"), nil
+		}
+		if _, serr := os.Stat(outPath); serr == nil && !in.Overwrite {
+			return mcperr.FromText("VALIDATION: output file already exists; set overwrite=true to replace it"), nil
+		}
+		var eval func([]string) bool
+		pred := strings.TrimSpace(in.Predicate)
+		if pred != "" {
+			var perr error
+			if eval, perr = compilePredicate(pred); perr != nil {
+				return mcperr.FromText("VALIDATION: invalid predicate; examples: $1 = \"foo\", $3 > 100, $2 contains \"bar\", $4 IS EMPTY"), nil
+			}
+		}
+
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		if canonical == outPath {
+			return mcperr.FromText("VALIDATION: output_path must differ from the source workbook"), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		out := ExportRangeCSVOutput{Path: canonical, Sheet: sheet, Predicate: pred, OutputPath: outPath}
+
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+			if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			rng := strings.TrimSpace(in.RangeA1)
+			if rng == "" {
+				rng, _ = scanUsedRange(f, sheet)
+			}
+			if rng == "" {
+				return mcperr.Errorf(mcperr.Validation, "sheet is empty; nothing to export")
+			}
+			x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+			}
+			out.RangeA1 = resolved
+			out.Columns = x2 - x1 + 1
+			if cells := out.Columns * (y2 - y1 + 1); cells > limits.MaxExportCells {
+				return mcperr.Errorf(mcperr.LimitExceeded, "range has %d cells, export max %d; export smaller ranges", cells, limits.MaxExportCells)
+			}
+			commit := func(records int) error {
+				rec := audit.Record{Tool: "export_range_csv", Path: outPath, Sheet: sheet, Range: resolved, Cells: records * out.Columns}
+				return reg.auditWrite(ctx, rec)
+			}
+			rows, written, werr := writeRangeCSV(ctx, f, sheet, outPath, x1, y1, x2, y2, eval, in.Header, commit)
+			out.Rows, out.Bytes = rows, written
+			return werr
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.WriteFailed, "%v", err), nil
+		}
+
+		summary := fmt.Sprintf("exported rows=%d cols=%d bytes=%d range=%s to %s", out.Rows, out.Columns, out.Bytes, out.RangeA1, out.OutputPath)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(export)
+}
+
+// writeRangeCSV streams rows y1..y2 (columns x1..x2) of sheet into a temporary
+// file next to outPath and, once commit accepts the record count, renames it
+// into place. When eval is set, rows it rejects are skipped; with header the
+// first row is always kept. It returns the records and bytes written.
+func writeRangeCSV(ctx context.Context, f *excelize.File, sheet, outPath string, x1, y1, x2, y2 int, eval func([]string) bool, header bool, commit func(records int) error) (int, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".tmp-*")
+	if err != nil {
+		return 0, 0, err
+	}
+	tmpName := tmp.Name()
+	fail := func(err error) (int, int64, error) {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return 0, 0, err
+	}
+
+	rowsIter, err := f.Rows(sheet)
+	if err != nil {
+		return fail(err)
+	}
+	defer func() { _ = rowsIter.Close() }()
+
+	w := csv.NewWriter(tmp)
+	record := make([]string, x2-x1+1)
+	records := 0
+	for r := 1; rowsIter.Next() && r <= y2; r++ {
+		if ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+		if r < y1 {
+			continue
+		}
+		vals, cerr := rowsIter.Columns()
+		if cerr != nil {
+			return fail(cerr)
+		}
+		if eval != nil && !(header && r == y1) && !eval(vals) {
+			continue
+		}
+		for c := x1; c <= x2; c++ {
+			record[c-x1] = ""
+			if c-1 < len(vals) {
+				record[c-x1] = vals[c-1]
+			}
+		}
+		if werr := w.Write(record); werr != nil {
+			return fail(werr)
+		}
+		records++
+	}
+	w.Flush()
+	if werr := w.Error(); werr != nil {
+		return fail(werr)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return 0, 0, err
+	}
+	fi, err := os.Stat(tmpName)
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return 0, 0, err
+	}
+	if err := commit(records); err != nil {
+		_ = os.Remove(tmpName)
+		return 0, 0, err
+	}
+	if err := os.Rename(tmpName, outPath); err != nil {
+		_ = os.Remove(tmpName)
+		return 0, 0, err
+	}
+	return records, fi.Size(), nil
+}
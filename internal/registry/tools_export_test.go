@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func createExportWorkbook(t *testing.T, dir string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	rows := [][]any{
+		{"Region", "Sales"},
+		{"East", 100},
+		{"West", 250},
+		{"North", 400},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	path := filepath.Join(dir, "export.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestExportRangeCSV(t *testing.T) {
+	srv, _ := newTestServer(t)
+	dir := t.TempDir()
+	path := createExportWorkbook(t, dir)
+	out := filepath.Join(dir, "out.csv")
+
+	res := callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "output_path": out})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got ExportRangeCSVOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, "A1:B4", got.RangeA1)
+	require.Equal(t, 4, got.Rows)
+	require.Equal(t, 2, got.Columns)
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "Region,Sales\nEast,100\nWest,250\nNorth,400\n", string(data))
+	require.Equal(t, int64(len(data)), got.Bytes)
+
+	// Existing files are refused without overwrite.
+	res = callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "output_path": out})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "overwrite=true")
+
+	res = callTool(t, srv, "export_range_csv", map[string]any{
+		"path": path, "sheet": "Sheet1", "output_path": out, "overwrite": true,
+		"predicate": "$2 > 200", "header": true,
+	})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &got)
+	require.Equal(t, 3, got.Rows)
+	data, err = os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "Region,Sales\nWest,250\nNorth,400\n", string(data))
+
+	res = callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "output_path": filepath.Join(dir, "out.txt")})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "must end in .csv")
+
+	res = callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "output_path": filepath.Join(dir, "missing", "out.csv")})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "output directory does not exist")
+}
+
+func TestExportRangeCSV_CellCap(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxExportCells = 4
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	RegisterExportTools(srv, New(), limits, mgr)
+
+	dir := t.TempDir()
+	path := createExportWorkbook(t, dir)
+	out := filepath.Join(dir, "capped.csv")
+	res := callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "output_path": out})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "LIMIT_EXCEEDED")
+	_, err := os.Stat(out)
+	require.True(t, os.IsNotExist(err))
+
+	res = callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B2", "output_path": out})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+}
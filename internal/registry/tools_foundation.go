@@ -2,6 +2,7 @@ package registry
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha1"
 	"encoding/csv"
@@ -15,10 +16,15 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/config"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/insights"
 	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/security"
 	"github.com/vinodismyname/mcpxcel/internal/workbooks"
 	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 	"github.com/vinodismyname/mcpxcel/pkg/pagination"
@@ -26,7 +32,54 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-var errCursorMtMismatch = errors.New("cursor mt mismatch")
+var errCursorFileChanged = errors.New("cursor file snapshot mismatch")
+
+// errNeedsWorkbookLock asks a write started under a sheet lock to retry under
+// the workbook lock because it would touch workbook-wide state.
+var errNeedsWorkbookLock = errors.New("write needs the workbook lock")
+
+// errCursorWritten rejects a cursor whose workbook was written through this
+// server after the cursor was issued.
+var errCursorWritten = errors.New("cursor workbook version superseded")
+
+// msgCursorStale is returned when a cursor's file snapshot no longer matches
+// because the file was edited on disk.
+const msgCursorStale = "CURSOR_INVALID: file changed since cursor was issued (edited on disk); restart pagination"
+
+// msgCursorWritten is returned when writes through this server (write_range,
+// insert_rows/delete_rows, sheet or format edits) changed the workbook after
+// the cursor was issued.
+const msgCursorWritten = "CURSOR_INVALID: workbook was modified by a write through this server since cursor was issued; restart pagination"
+
+// checkCursor rejects a resumed cursor whose workbook changed since it was
+// issued. The handle version catches writes, including ones not yet saved;
+// when a reload from disk moved the version instead, the file snapshot
+// decides, so a touched but unchanged file keeps the cursor valid. c may be
+// nil.
+func checkCursor(mgr *workbooks.Manager, c *pagination.Cursor, id string, version, mt int64, fp string) error {
+	if c == nil {
+		return nil
+	}
+	if !c.MatchesVersion(id, version) && !mgr.ReloadedSince(id, c.Wbv) {
+		return errCursorWritten
+	}
+	if !c.MatchesFile(mt, fp) {
+		return errCursorFileChanged
+	}
+	return nil
+}
+
+// cursorMismatch maps the errors checkCursor returns to CURSOR_INVALID
+// results, or returns nil for anything else.
+func cursorMismatch(err error) *mcp.CallToolResult {
+	switch {
+	case errors.Is(err, errCursorWritten):
+		return mcperr.FromText(msgCursorWritten)
+	case errors.Is(err, errCursorFileChanged):
+		return mcperr.FromText(msgCursorStale)
+	}
+	return nil
+}
 
 // --- Input / Output Schemas (typed for discovery) ---
 
@@ -36,12 +89,43 @@ type SheetInfo struct {
 	RowCount    int      `json:"rowCount" jsonschema_description:"Approximate row count"`
 	ColumnCount int      `json:"columnCount" jsonschema_description:"Approximate column count"`
 	Headers     []string `json:"headers,omitempty" jsonschema_description:"Header row when inferred"`
+	Hidden      bool     `json:"hidden,omitempty" jsonschema_description:"True when the sheet is hidden or very hidden"`
+	// Protected sheets refuse write tools unless force=true.
+	Protected  bool   `json:"protected,omitempty" jsonschema_description:"True when sheet protection is on; write tools refuse the sheet unless force=true"`
+	FrozenPane string `json:"frozenPane,omitempty" jsonschema_description:"Top-left cell of the scrollable pane when panes are frozen (e.g., B2 freezes row 1 and column A)"`
+	// MergedRegions counts merged-cell ranges; read_range expand_merged lists their covered cells.
+	MergedRegions int         `json:"mergedRegions" jsonschema_description:"Number of merged-cell regions"`
+	Tables        []TableInfo `json:"tables,omitempty" jsonschema_description:"Excel tables (ListObjects) on the sheet"`
+	// Scanned extents are populated only with accurate_counts=true.
+	ScannedRows       int  `json:"scannedRows,omitempty" jsonschema_description:"Last row holding a non-empty value (streaming scan)"`
+	ScannedColumns    int  `json:"scannedColumns,omitempty" jsonschema_description:"Last column holding a non-empty value (streaming scan)"`
+	ScanCapped        bool `json:"scanCapped,omitempty" jsonschema_description:"Scan stopped at the per-sheet cell cap; scanned extents are lower bounds"`
+	DimensionInflated bool `json:"dimensionInflated,omitempty" jsonschema_description:"Dimension-based counts exceed the scanned extents"`
+}
+
+// TableInfo names an Excel table and the range it covers.
+type TableInfo struct {
+	Name  string `json:"name"`
+	Range string `json:"range"`
+}
+
+// DefinedNameInfo describes a workbook defined name usable as a read_range target.
+type DefinedNameInfo struct {
+	Name     string `json:"name"`
+	RefersTo string `json:"refersTo"`
+	Scope    string `json:"scope" jsonschema_description:"Workbook or the sheet the name is local to"`
 }
 
+// maxListedNames caps defined names returned by list_structure.
+const maxListedNames = 100
+
 // ListStructureInput defines parameters for structure discovery.
 type ListStructureInput struct {
-	Path         string `json:"path" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+	Path         string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+	Password     string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
 	MetadataOnly bool   `json:"metadata_only,omitempty" jsonschema_description:"Return only metadata even for small sheets"`
+	// AccurateCounts streams each sheet to measure its real non-empty extent.
+	AccurateCounts bool `json:"accurate_counts,omitempty" jsonschema_description:"Stream each sheet (bounded per sheet) to report actual non-empty row/column extents"`
 }
 
 // ListStructureOutput summarizes workbook structure.
@@ -49,15 +133,32 @@ type ListStructureOutput struct {
 	Path         string      `json:"path"`
 	MetadataOnly bool        `json:"metadata_only"`
 	Sheets       []SheetInfo `json:"sheets"`
+	// DefinedNames is capped at maxListedNames; DefinedNamesTruncated reports the cut.
+	DefinedNames          []DefinedNameInfo `json:"definedNames,omitempty"`
+	DefinedNamesTotal     int               `json:"definedNamesTotal"`
+	DefinedNamesTruncated bool              `json:"definedNamesTruncated,omitempty"`
 }
 
 // PreviewSheetInput defines parameters for previewing a sheet.
 type PreviewSheetInput struct {
-	Path     string `json:"path" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
-	Sheet    string `json:"sheet" jsonschema_description:"Sheet name to preview"`
-	Rows     int    `json:"rows,omitempty" jsonschema_description:"Max rows to preview (bounded)"`
-	Encoding string `json:"encoding,omitempty" jsonschema_description:"Output encoding: json or csv"`
-	Cursor   string `json:"cursor,omitempty" jsonschema_description:"Opaque pagination cursor; takes precedence over sheet/rows"`
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet    string `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Sheet name to preview"`
+	Rows     int    `json:"rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max rows to preview (bounded)"`
+	Encoding string `json:"encoding,omitempty" validate:"omitempty,oneof=json csv markdown" jsonschema_description:"Output encoding: json, csv, or markdown"`
+	// CellWidth truncates markdown cells; ignored by other encodings.
+	CellWidth int    `json:"cell_width,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Markdown only: max characters per cell before truncation"`
+	StartCol  int    `json:"start_col,omitempty" validate:"omitempty,min=1,max=16384" jsonschema_description:"1-based first column of the window (default 1)"`
+	SkipRows  int    `json:"skip_rows,omitempty" validate:"min=0,max=1048575" jsonschema_description:"Rows to skip above the table (e.g., title banners); the preview starts at row skip_rows+1"`
+	HeaderRow int    `json:"header_row,omitempty" validate:"omitempty,min=1" jsonschema_description:"1-based row (<= skip_rows) emitted first on every page as the header"`
+	MaxCols   int    `json:"max_cols,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max columns per window; omitted means all columns"`
+	Cursor    string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque pagination cursor; takes precedence over sheet/rows"`
+	// ValueMode selects formatted, raw stored, or typed JSON values.
+	ValueMode string `json:"value_mode,omitempty" validate:"omitempty,oneof=formatted raw typed" jsonschema_description:"Cell values: formatted (as displayed), raw (stored value), or typed (JSON numbers/booleans/ISO-8601 dates)"`
+	// Summarize appends an inferred per-column schema of the previewed rows.
+	Summarize bool `json:"summarize,omitempty" jsonschema_description:"Append a one-line inferred schema (type, non-empty count, header) of the previewed rows"`
+	// IncludeHeader pins header_row (default 1) above every page.
+	IncludeHeader bool `json:"include_header,omitempty" jsonschema_description:"Repeat header_row (default row 1) at the top of every page; skip_rows defaults to header_row so it is not also returned as data"`
 }
 
 // PageMeta captures paging/truncation metadata.
@@ -66,62 +167,170 @@ type PageMeta struct {
 	Returned   int    `json:"returned"`
 	Truncated  bool   `json:"truncated"`
 	NextCursor string `json:"nextCursor,omitempty"`
+	// Estimated token counts of the text content in summary and full output
+	// modes (search_data/filter_data) so agents can pick a mode for the next page.
+	EstTokensSummary int `json:"estTokensSummary,omitempty"`
+	EstTokensFull    int `json:"estTokensFull,omitempty"`
+	// CellDetail marks read_range pages encoded as per-cell detail objects.
+	CellDetail bool `json:"cellDetail,omitempty"`
+	// ColumnsTruncated marks preview_sheet pages that omit columns outside the
+	// requested column window.
+	ColumnsTruncated bool `json:"columnsTruncated,omitempty"`
+	// PayloadCapped marks pages ended early by the payload byte budget rather
+	// than the row or cell limit.
+	PayloadCapped bool `json:"payloadCapped,omitempty"`
+	// Pages is the total page count at the current page size for tools that
+	// count every match (search_data/filter_data); jump with the page input.
+	Pages int `json:"pages,omitempty"`
+	// SortCapped marks sorted filter_data results with more matches than the
+	// sorted window holds; pages stop at the window.
+	SortCapped bool `json:"sortCapped,omitempty"`
+	// BytesReturned is the length of the text payload, and Limits the page
+	// size and payload cap it was built under, so clients can size later pages.
+	BytesReturned int         `json:"bytesReturned,omitempty"`
+	Limits        *PageLimits `json:"limits,omitempty"`
+}
+
+// PageLimits echoes the effective bounds of a page.
+type PageLimits struct {
+	Unit            pagination.Unit `json:"unit" jsonschema_description:"Unit of pageSize: rows or cells"`
+	PageSize        int             `json:"pageSize" jsonschema_description:"Effective page size after defaults, cursor, and global limits"`
+	MaxPayloadBytes int             `json:"maxPayloadBytes" jsonschema_description:"Global cap on the text payload of a page"`
 }
 
 // PreviewSheetOutput documents preview metadata.
 type PreviewSheetOutput struct {
-	Path     string   `json:"path"`
-	Sheet    string   `json:"sheet"`
-	Encoding string   `json:"encoding"`
-	Meta     PageMeta `json:"meta"`
+	Path     string `json:"path"`
+	Sheet    string `json:"sheet"`
+	Encoding string `json:"encoding"`
+	// Column window returned (1-based, inclusive) and the sheet width.
+	StartCol  int `json:"startCol,omitempty"`
+	EndCol    int `json:"endCol,omitempty"`
+	TotalCols int `json:"totalCols,omitempty"`
+	// Schema is the inferred per-column schema of the page (summarize=true),
+	// capped at maxSchemaCols columns.
+	Schema []PreviewColumn `json:"schema,omitempty"`
+	Meta   PageMeta        `json:"meta"`
+}
+
+// PreviewColumn describes one previewed column as inferred from the page.
+type PreviewColumn struct {
+	Column   string `json:"column"`
+	Header   string `json:"header,omitempty"`
+	Type     string `json:"type" jsonschema_description:"numeric, percent, date, boolean, text, mixed, or unknown"`
+	NonEmpty int    `json:"nonEmpty" jsonschema_description:"Non-empty data cells among the previewed rows"`
 }
 
 // ReadRangeInput defines parameters for reading a cell range.
 type ReadRangeInput struct {
-	Path     string `json:"path" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
-	Sheet    string `json:"sheet" jsonschema_description:"Sheet name"`
-	RangeA1  string `json:"range" jsonschema_description:"A1-style cell range (e.g., A1:D50)"`
-	MaxCells int    `json:"max_cells,omitempty" jsonschema_description:"Max cells to return (bounded)"`
-	Cursor   string `json:"cursor,omitempty" jsonschema_description:"Opaque pagination cursor; takes precedence over sheet/range/max_cells"`
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet    string `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Sheet name"`
+	RangeA1  string `json:"range" validate:"omitempty,a1orname" jsonschema_description:"A1-style cell range (e.g., A1:D50)"`
+	// Ranges reads several disjoint ranges in one call, paged in order.
+	Ranges   []string `json:"ranges,omitempty" validate:"omitempty,max=16,dive,a1orname" jsonschema_description:"Several A1 ranges or defined names read in order instead of range"`
+	MaxCells int      `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to return (bounded)"`
+	// HeaderRow names the sheet row whose values key encoding=records objects.
+	HeaderRow int    `json:"header_row,omitempty" validate:"omitempty,min=1,max=1048576" jsonschema_description:"Records or include_header only: 1-based sheet row holding the keys or header; defaults to the range's first row"`
+	Cursor    string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque pagination cursor; takes precedence over sheet/range/max_cells"`
+	// ExpandMerged reports merged-region membership in MergedCells. Covered
+	// cells read as their anchor's value either way.
+	ExpandMerged bool `json:"expand_merged,omitempty" jsonschema_description:"When true, list the cells covered by a merged region (other than its top-left anchor) in mergedCells; covered cells always read as the anchor's value"`
+	// CellDetail switches the text payload to {v, f, t} objects per cell.
+	CellDetail bool   `json:"cell_detail,omitempty" jsonschema_description:"When true, emit {v: value, f: formula, t: type} per cell; page size is divided by 3"`
+	Encoding   string `json:"encoding,omitempty" validate:"omitempty,oneof=json csv markdown records" jsonschema_description:"Output encoding: json, csv, markdown, or records"`
+	// CellWidth truncates markdown cells; ignored by other encodings.
+	CellWidth int `json:"cell_width,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Markdown only: max characters per cell before truncation"`
+	// ValueMode selects formatted, raw stored, or typed JSON values.
+	ValueMode string `json:"value_mode,omitempty" validate:"omitempty,oneof=formatted raw typed" jsonschema_description:"Cell values: formatted (as displayed), raw (stored value), or typed (JSON numbers/booleans/ISO-8601 dates)"`
+	// IncludeHeader pins the header row above every page's rows.
+	IncludeHeader bool `json:"include_header,omitempty" jsonschema_description:"json, csv, or markdown: emit the header row (header_row or the range's first row) above every page; data starts below it and counts alone toward max_cells and returned"`
 }
 
 // ReadRangeOutput documents range read metadata.
 type ReadRangeOutput struct {
-	Path    string   `json:"path"`
-	Sheet   string   `json:"sheet"`
-	RangeA1 string   `json:"range"`
-	Meta    PageMeta `json:"meta"`
+	Path     string `json:"path"`
+	Sheet    string `json:"sheet"`
+	RangeA1  string `json:"range"`
+	Encoding string `json:"encoding"`
+	// MergedCells lists cells in this page covered by a merged region, other
+	// than its anchor (only populated when expand_merged=true).
+	MergedCells []string `json:"mergedCells,omitempty"`
+	// Sections gives per-range counts for a multi-range read.
+	Sections []RangeSection `json:"sections,omitempty"`
+	// Keys lists the object keys of an encoding=records page, in column order.
+	Keys []string `json:"keys,omitempty"`
+	// Header is the row pinned above the page with include_header=true.
+	Header []string `json:"header,omitempty"`
+	Meta   PageMeta `json:"meta"`
 }
 
 // SearchDataInput defines parameters for searching values/patterns.
 type SearchDataInput struct {
-	Path         string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
-	Sheet        string `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Target sheet name (case‑insensitive)"`
-	Query        string `json:"query" validate:"required_without=Cursor,valid_regex" jsonschema_description:"Literal substring or pattern to find; set regex=true to treat as RE2 regex"`
-	Regex        bool   `json:"regex,omitempty" jsonschema_description:"If true, interpret query as Go RE2 regular expression; otherwise use literal substring match"`
-	Columns      []int  `json:"columns,omitempty" validate:"dive,min=1" jsonschema_description:"Optional 1‑based column indexes to restrict search scope"`
-	MaxResults   int    `json:"max_results,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max results per page (unit=rows); bounded by server limits"`
-	SnapshotCols int    `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max columns to include in each row snapshot; anchored to leftmost used column (bounded)"`
-	Cursor       string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque URL‑safe base64 cursor (unit=rows) bound to path+mtime and query hash; takes precedence for resume"`
+	Path         string            `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password     string            `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet        string            `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Target sheet name (case‑insensitive)"`
+	Query        string            `json:"query" validate:"required_without_all=Cursor RangeQuery,valid_regex" jsonschema_description:"Literal substring or pattern to find; set regex=true to treat as RE2 regex (at most 512 bytes; lookarounds and backreferences are not supported)"`
+	RangeQuery   *SearchRangeQuery `json:"range_query,omitempty" jsonschema_description:"Alternative to query: match cells whose number or date lies within inclusive bounds, e.g. {type: number, min: 1000, max: 2000} or {type: date, min: 2024-03-01, max: 2024-03-31}; omit min or max for an open-ended range"`
+	Regex        bool              `json:"regex,omitempty" jsonschema_description:"If true, interpret query as Go RE2 regular expression; otherwise use literal substring match"`
+	Columns      []int             `json:"columns,omitempty" validate:"dive,min=1" jsonschema_description:"Optional 1‑based column indexes to restrict search scope"`
+	MaxResults   int               `json:"max_results,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max results per page (unit=rows); bounded by server limits"`
+	Page         int               `json:"page,omitempty" validate:"omitempty,min=1" jsonschema_description:"1‑based page to jump to at the current page size (see meta.pages); with a cursor, jumps within the cursor's query. Each call rescans the sheet from the start"`
+	SnapshotCols int               `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max columns to include in each row snapshot; anchored to leftmost used column (bounded)"`
+	Cursor       string            `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque URL‑safe base64 cursor (unit=rows) bound to path+content fingerprint and query hash; takes precedence for resume"`
+	Output       string            `json:"output,omitempty" validate:"omitempty,oneof=summary full" jsonschema_description:"Text content mode: 'full' (summary + JSON results, default) or 'summary' (summary + up to 5 compact example rows); structured content always has all results"`
+	Scope        string            `json:"scope,omitempty" validate:"omitempty,oneof=values formulas comments" jsonschema_description:"What to search: values (default), formulas (formula text, e.g. Sheet3! or 1.07), or comments (cell comment text). Literal queries match whole values but substrings of formulas and comments, ignoring case there"`
 }
 
 // SearchMatch captures a single search hit with bounded row snapshot.
 type SearchMatch struct {
-	Cell     string   `json:"cell"`
-	Row      int      `json:"row"`
-	Column   int      `json:"column"`
+	Cell   string `json:"cell"`
+	Row    int    `json:"row"`
+	Column int    `json:"column"`
+	// Value is the cell value, the formula text with a leading '=', or the
+	// comment text, depending on Scope.
 	Value    string   `json:"value"`
+	Scope    string   `json:"scope" jsonschema_description:"Where the match was found: values, formulas, or comments"`
 	Snapshot []string `json:"snapshot,omitempty"`
 }
 
 // SearchDataOutput documents search metadata.
 type SearchDataOutput struct {
-	Path    string        `json:"path"`
-	Sheet   string        `json:"sheet"`
-	Query   string        `json:"query"`
-	Regex   bool          `json:"regex"`
-	Results []SearchMatch `json:"results"`
-	Meta    PageMeta      `json:"meta"`
+	Path  string `json:"path"`
+	Sheet string `json:"sheet"`
+	Query string `json:"query"`
+	Regex bool   `json:"regex"`
+	// RangeQuery echoes the normalized bounds when a range query was used.
+	RangeQuery *SearchRangeQuery `json:"rangeQuery,omitempty"`
+	Results    []SearchMatch     `json:"results"`
+	// Suggestions lists near misses when a literal value search found nothing.
+	Suggestions []SearchSuggestion `json:"suggestions,omitempty" jsonschema_description:"When a literal value query matched nothing: up to 5 distinct values that contain the query ignoring case or, for short queries, differ from it by a typo or two"`
+	Meta        PageMeta           `json:"meta"`
+}
+
+// GetLimitsInput is empty; get_limits takes no parameters.
+type GetLimitsInput struct{}
+
+// GetLimitsOutput reports the effective server guardrails.
+type GetLimitsOutput struct {
+	MaxConcurrentRequests   int      `json:"maxConcurrentRequests"`
+	MaxOpenWorkbooks        int      `json:"maxOpenWorkbooks"`
+	MaxPayloadBytes         int      `json:"maxPayloadBytes" jsonschema_description:"Approximate cap on one page of read_range/preview_sheet output"`
+	MaxCellsPerOp           int      `json:"maxCellsPerOp"`
+	PreviewRowLimit         int      `json:"previewRowLimit"`
+	MaxRowsPerEdit          int      `json:"maxRowsPerEdit"`
+	MaxExportCells          int      `json:"maxExportCells"`
+	MaxCrosstabCells        int      `json:"maxCrosstabCells" jsonschema_description:"Largest crosstab matrix (row keys × column keys)"`
+	MaxTextBytes            int      `json:"maxTextBytes" jsonschema_description:"Cap on the text content of list_structure, search_data, filter_data, detect_tables, and profile_schema; longer text ends with a truncation marker"`
+	MaxFileBytes            int64    `json:"maxFileBytes" jsonschema_description:"Largest workbook file that can be opened; 0 means unlimited"`
+	OperationTimeoutMs      int64    `json:"operationTimeoutMs"`
+	AcquireRequestTimeoutMs int64    `json:"acquireRequestTimeoutMs"`
+	CursorTTLMs             int64    `json:"cursorTtlMs" jsonschema_description:"Age after which pagination cursors fail with CURSOR_EXPIRED; 0 means cursors never expire"`
+	WritesEnabled           bool     `json:"writesEnabled"`
+	AllowedDirectories      []string `json:"allowedDirectories" jsonschema_description:"Allow-listed roots, read-only and read-write"`
+	WritableDirectories     []string `json:"writableDirectories"`
+	AllowedPatterns         []string `json:"allowedPatterns" jsonschema_description:"Allow-listed file patterns (** spans directories), read-only and read-write"`
+	WritablePatterns        []string `json:"writablePatterns"`
 }
 
 // RegisterFoundationTools defines core tool schemas and placeholder handlers.
@@ -131,26 +340,29 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 	// list_structure
 	listStructure := mcp.NewTool(
 		"list_structure",
-		mcp.WithDescription("Discover workbook structure without reading cell data. Lists sheets in index order with approximate row/column counts derived from the used range and a best‑effort header inference from the first row only (skipped when metadata_only=true). Use this first to ground subsequent steps (e.g., preview_sheet, read_range, search_data, filter_data) instead of streaming entire sheets. Returns no cell values and has no pagination; output includes sheets[] with name, rowCount, columnCount, and optional headers. Errors map to OPEN_FAILED, DISCOVERY_FAILED, or INVALID_HANDLE; access is restricted to configured allow‑list directories."),
+		mcp.WithDescription("Discover workbook structure without reading cell data. Lists sheets in index order with approximate row/column counts derived from the used range and a best‑effort header inference from the first row only (skipped when metadata_only=true). Use this first to ground subsequent steps (e.g., preview_sheet, read_range, search_data, filter_data) instead of streaming entire sheets. Returns no cell values and has no pagination; output includes sheets[] with name, rowCount, columnCount, optional headers, hidden, protected (write tools refuse protected sheets unless force=true), frozenPane (top‑left cell of the scrollable pane), mergedRegions, and tables[] (name, range), plus workbook definedNames[] (name, refersTo, scope; capped at 100 with definedNamesTruncated) that read_range accepts as range. rowCount/columnCount come from the stored dimension, which can be inflated by stray formatting; accurate_counts=true adds a bounded streaming scan (scannedRows, scannedColumns, scanCapped, dimensionInflated). Errors map to OPEN_FAILED, DISCOVERY_FAILED, or INVALID_HANDLE; access is restricted to configured allow‑list directories."),
 		mcp.WithString("path", mcp.Required(), mcp.Description("Canonical absolute file path to an Excel workbook (allow‑list enforced)")),
+		mcp.WithString("password", mcp.Description("Password for an encrypted workbook; used only to open it, never stored or echoed")),
 		mcp.WithBoolean("metadata_only", mcp.DefaultBool(false), mcp.Description("If true, return only metadata (sheet names, dimensions) and skip header inference")),
+		mcp.WithBoolean("accurate_counts", mcp.DefaultBool(false), mcp.Description(fmt.Sprintf("If true, stream each sheet (up to %d cells per sheet) to report scannedRows/scannedColumns next to the dimension-based counts; scanCapped marks sheets that hit the cap", limits.MaxCellsPerOp))),
 		mcp.WithOutputSchema[ListStructureOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(listStructure, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ListStructureInput) (*mcp.CallToolResult, error) {
-		p := strings.TrimSpace(in.Path)
-		if p == "" {
-			return mcperr.FromText("VALIDATION: path is required"), nil
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
 		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		p := strings.TrimSpace(in.Path)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
+			return openFailed(openErr), nil
 		}
 
 		var output ListStructureOutput
 		output.Path = canonical
 		output.MetadataOnly = in.MetadataOnly
 
-		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		err := mgr.WithRead(id, func(f *excelize.File, version int64) error {
 			// Respect cancellation before heavy work
 			if ctx.Err() != nil {
 				return ctx.Err()
@@ -171,84 +383,71 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			// Gather sheet names in index order
-			sheetMap := f.GetSheetMap()
-			idx := make([]int, 0, len(sheetMap))
-			for i := range sheetMap {
-				idx = append(idx, i)
-			}
-			sort.Ints(idx)
-
-			sheets := make([]SheetInfo, 0, len(idx))
-			for _, i := range idx {
-				if ctx.Err() != nil {
-					return ctx.Err()
-				}
-				name := sheetMap[i]
-				si := SheetInfo{Name: name}
-
-				if dim, derr := f.GetSheetDimension(name); derr == nil && dim != "" {
-					// dim like "A1:D50"; parse right cell for bounds
-					parts := strings.Split(dim, ":")
-					if len(parts) == 2 {
-						x1, y1, e1 := excelize.CellNameToCoordinates(parts[0])
-						x2, y2, e2 := excelize.CellNameToCoordinates(parts[1])
-						if e1 == nil && e2 == nil {
-							if x2 >= x1 {
-								si.ColumnCount = x2 - x1 + 1
-							}
-							if y2 >= y1 {
-								si.RowCount = y2 - y1 + 1
-							}
-						}
-					}
-				}
-
-				if !in.MetadataOnly {
-					// Infer header from first row via streaming iterator
-					rows, rerr := f.Rows(name)
-					if rerr == nil {
-						if rows.Next() {
-							if hdr, herr := rows.Columns(); herr == nil {
-								si.Headers = hdr
-							}
-						}
-						_ = rows.Close()
-					}
-				}
-
-				sheets = append(sheets, si)
+			if err := collectStructure(ctx, f, &output, in.MetadataOnly, in.AccurateCounts, limits.MaxCellsPerOp); err != nil {
+				return err
 			}
-			output.Sheets = sheets
+			reg.changes.observe(ctx, id, canonical, version)
 			return nil
 		})
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			return mcperr.FromText(fmt.Sprintf("DISCOVERY_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.DiscoveryFailed, "%v", err), nil
 		}
 
-		// Build a human-readable summary including sheet names and dimensions
-		var b strings.Builder
-		fmt.Fprintf(&b, "sheets=%d metadata_only=%v\n", len(output.Sheets), output.MetadataOnly)
+		// Build a human-readable summary including sheet names and dimensions,
+		// bounded by the text budget for workbooks with many sheets or names
+		b := newBudgetedWriter(limits)
+		fmt.Fprintf(b, "sheets=%d metadata_only=%v\n", len(output.Sheets), output.MetadataOnly)
 		for _, sh := range output.Sheets {
-			fmt.Fprintf(&b, "- %q rows=%d cols=%d", sh.Name, sh.RowCount, sh.ColumnCount)
+			fmt.Fprintf(b, "- %q rows=%d cols=%d", sh.Name, sh.RowCount, sh.ColumnCount)
 			if len(sh.Headers) > 0 {
 				// show up to first 8 headers to keep concise
 				max := len(sh.Headers)
 				if max > 8 {
 					max = 8
 				}
-				fmt.Fprintf(&b, " headers=%v", sh.Headers[:max])
+				fmt.Fprintf(b, " headers=%v", sh.Headers[:max])
 				if len(sh.Headers) > max {
 					b.WriteString("…")
 				}
 			}
-			b.WriteByte('\n')
+			if in.AccurateCounts {
+				fmt.Fprintf(b, " scanned=%dx%d", sh.ScannedRows, sh.ScannedColumns)
+				if sh.ScanCapped {
+					b.WriteString("(capped)")
+				}
+				if sh.DimensionInflated {
+					b.WriteString(" inflated")
+				}
+			}
+			if sh.Hidden {
+				b.WriteString(" hidden")
+			}
+			if sh.Protected {
+				b.WriteString(" protected")
+			}
+			if sh.FrozenPane != "" {
+				fmt.Fprintf(b, " frozen=%s", sh.FrozenPane)
+			}
+			if sh.MergedRegions > 0 {
+				fmt.Fprintf(b, " merged=%d", sh.MergedRegions)
+			}
+			for _, t := range sh.Tables {
+				fmt.Fprintf(b, " table=%s(%s)", t.Name, t.Range)
+			}
+			b.WriteString("\n")
+		}
+		if output.DefinedNamesTotal > 0 {
+			fmt.Fprintf(b, "definedNames=%d", output.DefinedNamesTotal)
+			if output.DefinedNamesTruncated {
+				fmt.Fprintf(b, " (first %d listed)", len(output.DefinedNames))
+			}
+			b.WriteString("\n")
+			for _, dn := range output.DefinedNames {
+				fmt.Fprintf(b, "- %s=%s scope=%s\n", dn.Name, dn.RefersTo, dn.Scope)
+			}
 		}
 		summary := b.String()
 
@@ -262,111 +461,197 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 	// preview_sheet
 	preview := mcp.NewTool(
 		"preview_sheet",
-		mcp.WithDescription("Stream a bounded preview of the first N rows to inspect headers and data types without loading the full sheet. When a cursor is provided it takes precedence over sheet/rows/encoding and resumes by row offset (unit=rows) bound to path and file mtime. Text content begins with a one‑line summary: 'total=<n> returned=<m> truncated=<bool> nextCursor=<token-or-empty>'; structured meta mirrors these fields. Use this to confirm structure before targeted reads/filters. Errors include VALIDATION, INVALID_SHEET, CURSOR_INVALID, and PREVIEW_FAILED; path access is allow‑listed."),
+		mcp.WithDescription("Stream a bounded preview of the first N rows to inspect headers and data types without loading the full sheet. When a cursor is provided it takes precedence over sheet/rows/encoding and resumes by row offset (unit=rows) bound to path and a file content fingerprint (a touch without edits keeps it valid). Text content begins with a one‑line summary: 'total=<n> returned=<m> truncated=<bool> nextCursor=<token-or-empty>'; structured meta mirrors these fields. encoding=markdown renders a GitHub table whose first returned row is the header, truncating cells at cell_width characters and ending the page early when the table would exceed the payload cap. skip_rows starts the preview below title/banner rows and header_row (≤ skip_rows) repeats that row first on every page; total and offsets then count only the rows after skip_rows. include_header=true does the same for row 1 (or header_row) without skip_rows, so resumed pages keep column context. For wide sheets pass start_col/max_cols to return a horizontal window: the summary adds 'cols=X..Y of N', meta.columnsTruncated flags omitted columns, and once all rows of a window are returned nextCursor advances to the next column window. Pages that would exceed the payload byte cap end at the last whole row that fits (meta.payloadCapped). value_mode=raw returns stored values (date serials, unformatted numbers, 1/0 booleans) and value_mode=typed emits JSON numbers, booleans, null, and ISO‑8601 dates for date‑formatted serials; cursors keep the mode. summarize=true appends one 'schema:' line after the data (and structured schema[]) giving each column's inferred type, non‑empty count, and header, computed from the previewed rows only: the header is header_row when given, otherwise the first row of a first page. Use this to confirm structure before targeted reads/filters. Errors include VALIDATION, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, and PREVIEW_FAILED; path access is allow‑listed."),
 		mcp.WithString("path", mcp.Required(), mcp.Description("Canonical absolute file path (allow‑list enforced)")),
+		mcp.WithString("password", mcp.Description("Password for an encrypted workbook; used only to open it, never stored or echoed")),
 		mcp.WithString("sheet", mcp.Required(), mcp.Description("Sheet name to preview (case‑insensitive)")),
 		mcp.WithNumber("rows", mcp.DefaultNumber(float64(limits.PreviewRowLimit)), mcp.Min(1), mcp.Max(1000), mcp.Description("Max rows per page (unit=rows); defaults to PreviewRowLimit")),
-		mcp.WithString("encoding", mcp.DefaultString("json"), mcp.Enum("json", "csv"), mcp.Description("Output text encoding: 'json' (array‑of‑rows) or 'csv'")),
-		mcp.WithString("cursor", mcp.Description("Opaque URL‑safe base64 cursor (unit=rows); takes precedence and binds to path+mtime")),
+		mcp.WithString("encoding", mcp.DefaultString("json"), mcp.Enum("json", "csv", "markdown"), mcp.Description("Output text encoding: 'json' (array‑of‑rows), 'csv', or 'markdown' (GitHub table; first returned row is the header)")),
+		mcp.WithNumber("cell_width", mcp.DefaultNumber(float64(config.DefaultMarkdownCellWidth)), mcp.Min(1), mcp.Max(maxMarkdownCellWidth), mcp.Description("Markdown only: truncate cells longer than this many characters")),
+		mcp.WithNumber("skip_rows", mcp.DefaultNumber(0), mcp.Min(0), mcp.Description("Rows to skip above the table (title/banner rows); the preview starts at row skip_rows+1")),
+		mcp.WithNumber("header_row", mcp.Min(1), mcp.Description("1‑based header row (must be ≤ skip_rows) emitted first on every page; not counted in returned")),
+		mcp.WithBoolean("include_header", mcp.DefaultBool(false), mcp.Description("Repeat header_row (default row 1) first on every page; skip_rows defaults to header_row so the header is not also returned as data")),
+		mcp.WithNumber("start_col", mcp.DefaultNumber(1), mcp.Min(1), mcp.Max(float64(excelize.MaxColumns)), mcp.Description("1‑based first column of the window")),
+		mcp.WithNumber("max_cols", mcp.Min(1), mcp.Max(maxPreviewCols), mcp.Description("Max columns per window for wide sheets; omitted returns all columns")),
+		mcp.WithString("cursor", mcp.Description("Opaque URL‑safe base64 cursor (unit=rows); takes precedence and binds to path+content fingerprint")),
+		mcp.WithString("value_mode", mcp.DefaultString(valueModeFormatted), mcp.Enum(valueModeFormatted, valueModeRaw, valueModeTyped), mcp.Description(valueModeDescription)),
+		mcp.WithBoolean("summarize", mcp.DefaultBool(false), mcp.Description(fmt.Sprintf("Append one 'schema:' line after the data with each column's inferred type, non‑empty count, and header (first %d columns, previewed rows only)", maxSchemaCols))),
 		mcp.WithOutputSchema[PreviewSheetOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(preview, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in PreviewSheetInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
 		p := strings.TrimSpace(in.Path)
 		sheet := strings.TrimSpace(in.Sheet)
 		curTok := strings.TrimSpace(in.Cursor)
-		if p == "" {
-			return mcperr.FromText("VALIDATION: path is required"), nil
-		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
-		}
-		rowsLimit := in.Rows
-		if rowsLimit <= 0 || rowsLimit > 1000 {
-			rowsLimit = limits.PreviewRowLimit
+			return openFailed(openErr), nil
 		}
-		enc := strings.ToLower(strings.TrimSpace(in.Encoding))
+		rowsLimit := pagination.PageSize(in.Rows, 0, limits.PreviewRowLimit, 1000)
+		enc := in.Encoding
 		if enc == "" {
 			enc = "json"
 		}
-		if enc != "json" && enc != "csv" {
-			return mcperr.FromText("VALIDATION: encoding must be 'json' or 'csv'"), nil
+		cellWidth := markdownCellWidth(in.CellWidth)
+		valueMode, _ := parseValueMode(in.ValueMode)
+		startCol := in.StartCol
+		if startCol == 0 {
+			startCol = 1
+		}
+		maxCols := in.MaxCols
+		skipRows, headerRow := in.SkipRows, in.HeaderRow
+		if in.IncludeHeader {
+			if headerRow == 0 {
+				headerRow = 1
+			}
+			skipRows = max(skipRows, headerRow)
+		}
+		if headerRow > skipRows {
+			return mcperr.New(mcperr.Validation, "header_row must be within the skipped rows (1..skip_rows) so it precedes the previewed rows"), nil
 		}
 
 		// Cursor precedence: when provided, override sheet/rows from token
 		var startOffset int
 		var parsedCur *pagination.Cursor
 		if curTok != "" {
-			pc, derr := pagination.DecodeCursor(curTok)
-			if derr != nil {
-				return mcperr.FromText("CURSOR_INVALID: failed to decode cursor; reopen workbook and restart pagination"), nil
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
 			}
 			if pc.Pt != canonical {
-				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+				return mcperr.New(mcperr.CursorInvalid, "cursor path does not match provided path"), nil
 			}
 			if pc.U != pagination.UnitRows {
-				return mcperr.FromText("CURSOR_INVALID: unit mismatch; preview_sheet expects rows"), nil
+				return mcperr.New(mcperr.CursorInvalid, "unit mismatch; preview_sheet expects rows"), nil
 			}
 			sheet = pc.S
 			startOffset = pc.Off
-			if pc.Ps > 0 && pc.Ps < rowsLimit {
-				rowsLimit = pc.Ps
+			rowsLimit = pagination.PageSize(in.Rows, pc.Ps, rowsLimit, 1000)
+			if pc.Enc != "" {
+				enc = pc.Enc
 			}
-			parsedCur = pc
-		} else {
-			if sheet == "" {
-				return mcperr.FromText("VALIDATION: sheet is required (or supply cursor)"), nil
+			if pc.Cw > 0 {
+				cellWidth = pc.Cw
+			}
+			if pc.Sc > 0 {
+				startCol = pc.Sc
 			}
+			maxCols = pc.Mc
+			skipRows, headerRow = pc.Sk, pc.Hr
+			valueMode, _ = parseValueMode(pc.Vm)
+			parsedCur = pc
 		}
 
 		meta := PageMeta{}
 		// Accumulate preview in selected encoding
 		var textOut string
+		var budgetCut bool
 		var sheetRange string
+		var totalCols, endCol int
 		var fileMT int64
-		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		var fileFP string
+		var wbVersion int64
+		var schema []PreviewColumn
+		var schemaRows int
+		reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
 			// Respect cancellation before heavy work
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			// Compute current file mtime under read lock for cursor emission
-			if fi, serr := os.Stat(canonical); serr == nil {
-				fileMT = fi.ModTime().Unix()
+			// Snapshot the file under the read lock for cursor emission and
+			// reject cursors issued against different contents
+			fileMT, fileFP = fileSnapshot(canonical)
+			wbVersion = version
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
+			}
+
+			// Total rows/columns from the dimension when available and capture range
+			// for cursor; excelize-written files may record only "A1", so fall back
+			// to a streaming scan of the used range.
+			dim, _ := f.GetSheetDimension(sheet)
+			if !strings.Contains(dim, ":") {
+				dim, _ = scanUsedRange(f, sheet)
 			}
-			// Validate cursor file mtime under read lock when resuming
-			if parsedCur != nil && parsedCur.Mt > 0 {
-				if parsedCur.Mt != fileMT {
-					return errCursorMtMismatch
+			if parts := strings.Split(dim, ":"); len(parts) == 2 {
+				_, y1, e1 := excelize.CellNameToCoordinates(parts[0])
+				x2, y2, e2 := excelize.CellNameToCoordinates(parts[1])
+				if e1 == nil && e2 == nil && y2 >= y1 {
+					meta.Total = y2 - y1 + 1
+					sheetRange = dim
+					totalCols = x2
 				}
 			}
-
-			// Total rows from dimension when available and capture range for cursor
-			if dim, derr := f.GetSheetDimension(sheet); derr == nil && dim != "" {
-				parts := strings.Split(dim, ":")
-				if len(parts) == 2 {
-					_, y1, e1 := excelize.CellNameToCoordinates(parts[0])
-					_, y2, e2 := excelize.CellNameToCoordinates(parts[1])
-					if e1 == nil && e2 == nil && y2 >= y1 {
-						meta.Total = y2 - y1 + 1
-						sheetRange = dim
-					}
+			// Total counts the rows after skip_rows
+			if skipRows > 0 && meta.Total > 0 {
+				if skipRows >= meta.Total {
+					return mcperr.Errorf(mcperr.Validation, "skip_rows %d leaves no rows to preview (sheet has %d rows)", skipRows, meta.Total)
 				}
+				meta.Total -= skipRows
 			}
 
+			// Resolve the column window against the sheet width
+			if totalCols > 0 && startCol > totalCols {
+				return mcperr.Errorf(mcperr.Validation, "start_col %d exceeds sheet width (%d columns)", startCol, totalCols)
+			}
+			endCol = totalCols
+			if maxCols > 0 && (endCol == 0 || startCol+maxCols-1 < endCol) {
+				endCol = startCol + maxCols - 1
+			}
+			meta.ColumnsTruncated = totalCols > 0 && (startCol > 1 || endCol < totalCols)
+
 			r, rerr := f.Rows(sheet)
 			if rerr != nil {
 				return rerr
 			}
 			defer r.Close()
 
-			// Skip rows up to startOffset when resuming
-			if startOffset > 0 {
+			// Rows are read in the requested value mode; typed mode also keeps
+			// each row's JSON values for the json encoding.
+			values := newValueReader(f, sheet, valueMode)
+			var colOpts []excelize.Options
+			if values.raw() {
+				colOpts = append(colOpts, excelize.Options{RawCellValue: true})
+			}
+			readRow := func(rowNum int) ([]string, []any, int, error) {
+				row, cerr := r.Columns(colOpts...)
+				if cerr != nil {
+					return nil, nil, 0, cerr
+				}
+				win := columnWindow(row, startCol, endCol)
+				if values.mode != valueModeTyped {
+					return win, nil, len(row), nil
+				}
+				typed := make([]any, len(win))
+				for i, v := range win {
+					cell, _ := excelize.CoordinatesToCellName(startCol+i, rowNum)
+					win[i], typed[i] = values.convert(cell, v)
+				}
+				return win, typed, len(row), nil
+			}
+
+			// Skip banner rows (skip_rows) plus startOffset when resuming, capturing
+			// the header row on the way past it
+			var header []string
+			var typedHeader []any
+			if toSkip := skipRows + startOffset; toSkip > 0 {
 				skipped := 0
-				for skipped < startOffset && r.Next() {
+				for skipped < toSkip && r.Next() {
 					if ctx.Err() != nil {
 						return ctx.Err()
 					}
 					skipped++
+					if skipped == headerRow {
+						var cerr error
+						header, typedHeader, _, cerr = readRow(skipped)
+						if cerr != nil {
+							return cerr
+						}
+					}
 				}
 				// If we reached end before skipping all, nothing left to return
 				if meta.Total > 0 && startOffset >= meta.Total {
@@ -381,103 +666,158 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				}
 			}
 
-			if enc == "json" {
-				// Build a JSON array of rows (array of arrays)
-				var buf bytes.Buffer
-				buf.WriteByte('[')
-				count := 0
-				first := true
-				for r.Next() {
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					if count >= rowsLimit {
-						break
-					}
-					row, cerr := r.Columns()
-					if cerr != nil {
-						return cerr
-					}
-					if !first {
-						buf.WriteByte(',')
+			// Collect the page (bounded by rowsLimit), keeping only the column window.
+			// A header_row is emitted first and is not counted in Returned.
+			grid := make([][]string, 0, rowsLimit+1)
+			var typedGrid [][]any
+			if headerRow > 0 {
+				if header == nil {
+					header, typedHeader = []string{}, []any{}
+				}
+				grid = append(grid, header)
+				typedGrid = append(typedGrid, typedHeader)
+			}
+			first := len(grid)
+			rowNum := skipRows + startOffset
+			for len(grid)-first < rowsLimit && r.Next() {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				rowNum++
+				row, typed, width, cerr := readRow(rowNum)
+				if cerr != nil {
+					return cerr
+				}
+				grid = append(grid, row)
+				typedGrid = append(typedGrid, typed)
+				runtime.CallStatsFrom(ctx).AddCells(width)
+			}
+			meta.Returned = len(grid) - first
+			if values.mode != valueModeTyped {
+				typedGrid = nil
+			}
+
+			// Rows that would push the page past the payload cap are left for
+			// the next page; the header and at least one data row are kept.
+			if enc != "markdown" {
+				full, _ := fitGrid(len(grid), func(r int) int { return len(grid[r]) }, func(r, c int) int {
+					switch {
+					case enc != "json":
+						return csvCellSize(grid[r][c])
+					case typedGrid != nil:
+						return jsonCellSize(typedGrid[r][c])
 					}
-					// serialize row as JSON array
-					b, merr := json.Marshal(row)
-					if merr != nil {
-						return merr
+					return jsonCellSize(grid[r][c])
+				}, enc == "json", payloadBudget(limits.MaxPayloadBytes))
+				if full < first+1 {
+					full = first + 1
+				}
+				if full < len(grid) {
+					grid = grid[:full]
+					if typedGrid != nil {
+						typedGrid = typedGrid[:full]
 					}
-					buf.Write(b)
-					count++
-					first = false
+					meta.Returned = full - first
+					budgetCut = true
 				}
-				buf.WriteByte(']')
-				textOut = buf.String()
-				meta.Returned = count
-			} else {
+			}
+
+			switch enc {
+			case "json":
+				var rows any = grid
+				if typedGrid != nil {
+					rows = typedGrid
+				}
+				b, merr := json.Marshal(rows)
+				if merr != nil {
+					return merr
+				}
+				textOut = string(b)
+			case "markdown":
+				// Rows that would push the table past the payload cap are left
+				// for the next page.
+				var used int
+				textOut, used = renderMarkdownTable(grid, cellWidth, payloadBudget(limits.MaxPayloadBytes))
+				meta.Returned = used - first
+				budgetCut = budgetCut || used < len(grid)
+			default:
 				var buf bytes.Buffer
 				w := csv.NewWriter(&buf)
-				count := 0
-				for r.Next() {
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					if count >= rowsLimit {
-						break
-					}
-					row, cerr := r.Columns()
-					if cerr != nil {
-						return cerr
-					}
-					if err := w.Write(row); err != nil {
-						return err
-					}
-					count++
-				}
-				w.Flush()
-				if err := w.Error(); err != nil {
-					return err
+				if werr := w.WriteAll(grid); werr != nil {
+					return werr
 				}
 				textOut = buf.String()
-				meta.Returned = count
 			}
 
-			// Compute truncation and cursor
-			meta.Truncated = meta.Total > 0 && (startOffset+meta.Returned) < meta.Total
+			if in.Summarize {
+				// Without header_row the sheet's first row heads a first page.
+				hasHeader := headerRow > 0 || (startOffset == 0 && first+meta.Returned > 0)
+				schema, schemaRows = previewSchema(grid[:first+meta.Returned], hasHeader, startCol)
+			}
+
+			// Compute truncation and cursor. Rows are paged first; once they are
+			// exhausted a column window advances to the next window from row 1.
+			next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: sheetRange, U: pagination.UnitRows, Ps: rowsLimit, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: wbVersion, Enc: enc, Cw: cellWidthFor(enc, cellWidth), Mc: maxCols, Sk: skipRows, Hr: headerRow, Vm: cursorValueMode(valueMode)}
+			meta.PayloadCapped = budgetCut
+			rowsRemain := budgetCut || (meta.Total > 0 && (startOffset+meta.Returned) < meta.Total)
+			switch {
+			case rowsRemain:
+				next.Off = pagination.NextOffset(startOffset, meta.Returned)
+				next.Sc = startCol
+				meta.Truncated = true
+			case maxCols > 0 && endCol < totalCols:
+				next.Sc = endCol + 1
+				meta.Truncated = true
+			}
 			if meta.Truncated {
-				// Build opaque next cursor with rows unit and bound mtime
-				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: sheetRange, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, meta.Returned), Ps: rowsLimit, Mt: fileMT}
 				token, _ := pagination.EncodeCursor(next)
 				meta.NextCursor = token
 			}
 			return nil
 		})
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if errors.Is(err, errCursorMtMismatch) {
-				return mcperr.FromText("CURSOR_INVALID: file changed since cursor was issued; restart pagination"), nil
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
 			}
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
-			}
-			return mcperr.FromText(fmt.Sprintf("PREVIEW_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.PreviewFailed, "%v", err), nil
 		}
 
+		runtime.CallStatsFrom(ctx).SetResult(meta.Returned, meta.Truncated)
 		out := PreviewSheetOutput{Path: canonical, Sheet: sheet, Encoding: enc, Meta: meta}
+		if totalCols > 0 {
+			out.StartCol, out.EndCol, out.TotalCols = startCol, endCol, totalCols
+		}
+		out.Schema = schema
 		// Text content carries a concise summary followed by the actual preview data
 		summary := fmt.Sprintf("total=%d returned=%d truncated=%v", out.Meta.Total, out.Meta.Returned, out.Meta.Truncated)
+		if totalCols > 0 {
+			summary += fmt.Sprintf(" cols=%d..%d of %d", startCol, endCol, totalCols)
+		}
+		if skipRows > 0 {
+			summary += fmt.Sprintf(" firstRow=%d", skipRows+startOffset+1)
+		}
+		if headerRow > 0 {
+			summary += fmt.Sprintf(" headerRow=%d", headerRow)
+		}
+		if valueMode != valueModeFormatted {
+			summary += " valueMode=" + valueMode
+		}
 		if out.Meta.Truncated {
 			// Surface nextCursor token for clients that ignore structured meta
 			summary = summary + " nextCursor=" + out.Meta.NextCursor
 		} else {
 			summary = summary + " nextCursor="
 		}
+		text := summary + "\n" + textOut
+		if in.Summarize {
+			text = strings.TrimSuffix(text, "\n") + "\n" + schemaLine(schema, schemaRows)
+		}
+		recordPayload(&out.Meta, text, pagination.UnitRows, rowsLimit, limits.MaxPayloadBytes)
 		res := mcp.NewToolResultStructured(out, "preview generated")
-		res.Content = []mcp.Content{mcp.NewTextContent(summary + "\n" + textOut)}
+		res.Content = []mcp.Content{mcp.NewTextContent(text)}
 		return res, nil
 	}))
 	reg.Register(preview)
@@ -485,221 +825,39 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 	// read_range
 	readRange := mcp.NewTool(
 		"read_range",
-		mcp.WithDescription("Return a bounded rectangular cell range with deterministic row‑major pagination (unit=cells). Provide an A1‑style range or a defined name; when a cursor is supplied it overrides sheet/range/max_cells and resumes at the exact cell offset bound to path and file mtime. Text output is a JSON array‑of‑arrays prefixed with a one‑line summary; structured meta includes total, returned, truncated, and nextCursor. Limits: max_cells and payload caps apply; named ranges must resolve. Errors: VALIDATION (bad range), INVALID_SHEET, CURSOR_INVALID, READ_FAILED."),
+		mcp.WithDescription(fmt.Sprintf("Return a bounded rectangular cell range with deterministic row‑major pagination (unit=cells). Provide an A1‑style range or a defined name; when a cursor is supplied it overrides sheet/range/max_cells and resumes at the exact cell offset bound to path and a file content fingerprint (a touch without edits keeps it valid). Text output is a JSON array‑of‑arrays prefixed with a one‑line summary; structured meta includes total, returned, truncated, and nextCursor. With cell_detail=true each cell becomes {v: value, f: formula (when present), t: empty|number|date|bool|error|string}; objects are about 3× larger, so the page size is divided by 3 and meta.cellDetail is set. encoding=csv emits CSV rows; encoding=markdown emits a GitHub table whose first returned row is the header (pipes escaped, cells cut at cell_width characters) and ends the page at a row boundary when the table would exceed the payload cap. encoding=records emits a JSON array of one object per data row keyed by the header row (the range's first row unless header_row is given; it is not repeated as data), with blank headers named col_<letter> and duplicates suffixed _2, _3; keys repeat in every row, so records cost roughly twice the tokens, the page size is halved and rounded to whole rows, and meta total counts data cells only. Cursors keep the encoding, and records cursors bind to a hash of the keys. include_header=true (json, csv, markdown) pins the range's first row, or header_row, above every page's data: structured header holds it, pages hold whole rows, and total/returned count only the data cells below it. Limits: max_cells and a payload byte cap apply, whichever is reached first; json/csv pages cut by the byte cap end at the last whole cell that fits (at least one cell) with meta.payloadCapped set, and nextCursor resumes from there. Named ranges must resolve. ranges=[...] reads up to %[1]d disjoint ranges in one call (json encoding only): the text payload is an array of {range, rows} sections, structured sections[] gives each range's offset, total, and returned counts, their combined cells may not exceed %[2]d, and pages continue across ranges in order with the cursor recording the range and offset to resume at. value_mode=raw returns stored values (date serials, unformatted numbers, 1/0 booleans); value_mode=typed emits JSON numbers, booleans, null for empty cells, and ISO‑8601 dates for date‑formatted serials (csv/markdown show the same text, not combinable with cell_detail); cursors keep the mode. Errors: VALIDATION (bad range), INVALID_SHEET, PAYLOAD_TOO_LARGE, CURSOR_INVALID, CURSOR_EXPIRED, READ_FAILED.", maxReadRanges, limits.MaxCellsPerOp)),
 		mcp.WithString("path", mcp.Required(), mcp.Description("Canonical absolute file path (allow‑list enforced)")),
+		mcp.WithString("password", mcp.Description("Password for an encrypted workbook; used only to open it, never stored or echoed")),
 		mcp.WithString("sheet", mcp.Required(), mcp.Description("Target sheet name (case‑insensitive)")),
-		mcp.WithString("range", mcp.Required(), mcp.Description("A1‑style range or defined name, e.g., 'A1:D50'")),
+		mcp.WithString("range", mcp.Description("A1‑style range or defined name, e.g., 'A1:D50'; required unless ranges or cursor is given")),
+		mcp.WithArray("ranges", mcp.WithStringItems(), mcp.MaxItems(maxReadRanges), mcp.Description("Several A1 ranges or defined names (e.g., ['A1:D1', 'AA100:AD105']) read in order in one call; use instead of range")),
 		mcp.WithNumber("max_cells", mcp.DefaultNumber(float64(limits.MaxCellsPerOp)), mcp.Min(1), mcp.Description("Max cells per page before truncation (unit=cells)")),
-		mcp.WithString("cursor", mcp.Description("Opaque URL‑safe base64 cursor (unit=cells); takes precedence and binds to path+mtime")),
+		mcp.WithString("cursor", mcp.Description("Opaque URL‑safe base64 cursor (unit=cells); takes precedence and binds to path+content fingerprint")),
+		mcp.WithBoolean("expand_merged", mcp.DefaultBool(false), mcp.Description("Report merged-region membership: list cells covered by a merged region (other than its anchor) in mergedCells. Covered cells read as the anchor's value with or without this flag")),
+		mcp.WithBoolean("cell_detail", mcp.DefaultBool(false), mcp.Description("Emit {v, f, t} objects (value, formula, inferred type) per cell instead of bare values; divides the page size by 3")),
+		mcp.WithString("encoding", mcp.DefaultString("json"), mcp.Enum("json", "csv", "markdown", "records"), mcp.Description("Output text encoding: 'json' (array‑of‑arrays), 'csv', 'markdown' (GitHub table; first returned row is the header), or 'records' (one object per data row keyed by header names; about 2× the tokens, so the page size is halved)")),
+		mcp.WithNumber("header_row", mcp.Min(1), mcp.Description("Records or include_header only: 1‑based sheet row holding the keys or header; defaults to the range's first row, and data starts below it")),
+		mcp.WithBoolean("include_header", mcp.DefaultBool(false), mcp.Description("json, csv, or markdown: emit the header row (header_row or the range's first row) above every page's rows; data starts below it, pages hold whole rows, and only data cells count toward max_cells and returned. Cursors keep the header")),
+		mcp.WithNumber("cell_width", mcp.DefaultNumber(float64(config.DefaultMarkdownCellWidth)), mcp.Min(1), mcp.Max(maxMarkdownCellWidth), mcp.Description("Markdown only: truncate cells longer than this many characters")),
+		mcp.WithString("value_mode", mcp.DefaultString(valueModeFormatted), mcp.Enum(valueModeFormatted, valueModeRaw, valueModeTyped), mcp.Description(valueModeDescription)),
 		mcp.WithOutputSchema[ReadRangeOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(readRange, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ReadRangeInput) (*mcp.CallToolResult, error) {
-		p := strings.TrimSpace(in.Path)
-		sheet := strings.TrimSpace(in.Sheet)
-		rng := strings.TrimSpace(in.RangeA1)
-		curTok := strings.TrimSpace(in.Cursor)
-		if p == "" {
-			return mcperr.FromText("VALIDATION: path is required"), nil
-		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
-		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
-		}
-		maxCells := in.MaxCells
-		if maxCells <= 0 || maxCells > limits.MaxCellsPerOp {
-			maxCells = limits.MaxCellsPerOp
-		}
-		// Cursor precedence: when provided, override sheet/range/maxCells from token
-		var startOffset int
-		var parsedCur *pagination.Cursor
-		if curTok != "" {
-			pc, derr := pagination.DecodeCursor(curTok)
-			if derr != nil {
-				return mcperr.FromText("CURSOR_INVALID: failed to decode cursor; reopen workbook and restart pagination"), nil
-			}
-			if pc.Pt != canonical {
-				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
-			}
-			if pc.U != pagination.UnitCells {
-				return mcperr.FromText("CURSOR_INVALID: unit mismatch; read_range expects cells"), nil
-			}
-			// Override inputs using cursor values
-			sheet = pc.S
-			rng = pc.R
-			startOffset = pc.Off
-			if pc.Ps > 0 && pc.Ps < maxCells {
-				maxCells = pc.Ps
-			}
-			parsedCur = pc
-		} else {
-			if sheet == "" || rng == "" {
-				return mcperr.FromText("VALIDATION: sheet and range are required (or supply cursor)"), nil
-			}
-		}
-
-		// We will build a JSON array-of-arrays payload in text form to keep memory bounded
-		var textOut string
-		var meta PageMeta
-		var outRange = rng
-
-		var fileMT int64
-		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
-			// Compute current file mtime under read lock for cursor emission
-			if fi, serr := os.Stat(canonical); serr == nil {
-				fileMT = fi.ModTime().Unix()
-			}
-			// Validate mtime snapshot if resuming from a cursor
-			if parsedCur != nil && parsedCur.Mt > 0 {
-				if parsedCur.Mt != fileMT {
-					return errCursorMtMismatch
-				}
-			}
-			// Resolve named range if needed
-			var x1, y1, x2, y2 int
-			var parseErr error
-			x1, y1, x2, y2, outRange, parseErr = resolveRange(f, sheet, rng)
-			if parseErr != nil {
-				return parseErr
-			}
-
-			// Explicitly validate that the target sheet exists; otherwise GetCellValue calls
-			// on a non-existent sheet would quietly return empty values without an error.
-			// Using GetSheetMap avoids mutating iterators and is safe under read lock.
-			{
-				exists := false
-				for _, name := range f.GetSheetMap() {
-					if strings.EqualFold(name, sheet) {
-						exists = true
-						break
-					}
-				}
-				if !exists {
-					return fmt.Errorf("sheet does not exist")
-				}
-			}
-
-			if x2 < x1 || y2 < y1 {
-				return fmt.Errorf("invalid range bounds after parse")
-			}
-
-			total := (x2 - x1 + 1) * (y2 - y1 + 1)
-			meta.Total = total
-
-			// Compute resume position from startOffset (cells) if provided
-			cols := x2 - x1 + 1
-			startRow := y1
-			startCol := x1
-			if startOffset > 0 {
-				startRow = y1 + (startOffset / cols)
-				startCol = x1 + (startOffset % cols)
-				if startCol > x2 {
-					startCol = x1
-					startRow++
-				}
-				if startRow > y2 {
-					// Nothing left to return
-					textOut = "[]"
-					meta.Returned = 0
-					meta.Truncated = false
-					return nil
-				}
-			}
-
-			// Iterate row-major from (startCol,startRow), but stop when we reach maxCells
-			// Build JSON array-of-arrays
-			var buf bytes.Buffer
-			buf.WriteByte('[')
-			writtenCells := 0
-			emittedRows := 0
-			stop := false
-			for row := startRow; row <= y2 && !stop; row++ {
-				if ctx.Err() != nil {
-					return ctx.Err()
-				}
-				// For each row, emit an array of columns
-				if emittedRows > 0 {
-					buf.WriteByte(',')
-				}
-				buf.WriteByte('[')
-				colsWritten := 0
-				cstart := x1
-				if row == startRow {
-					cstart = startCol
-				}
-				for col := cstart; col <= x2; col++ {
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					if writtenCells >= maxCells {
-						stop = true
-						break
-					}
-					if colsWritten > 0 {
-						buf.WriteByte(',')
-					}
-					cellName, _ := excelize.CoordinatesToCellName(col, row)
-					val, _ := f.GetCellValue(sheet, cellName)
-					b, _ := json.Marshal(val)
-					buf.Write(b)
-					colsWritten++
-					writtenCells++
-				}
-				buf.WriteByte(']')
-				emittedRows++
-			}
-			buf.WriteByte(']')
-			textOut = buf.String()
-			meta.Returned = writtenCells
-			meta.Truncated = (startOffset + writtenCells) < total
-			if meta.Truncated {
-				// Build opaque next cursor with bound mtime
-				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: outRange, U: pagination.UnitCells, Off: pagination.NextOffset(startOffset, writtenCells), Ps: maxCells, Mt: fileMT}
-				token, _ := pagination.EncodeCursor(next)
-				meta.NextCursor = token
-			}
-			return nil
-		})
-		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
-			}
-			if errors.Is(err, errCursorMtMismatch) {
-				return mcperr.FromText("CURSOR_INVALID: file changed since cursor was issued; restart pagination"), nil
-			}
-			// Map validation-ish errors
-			lower := strings.ToLower(err.Error())
-			if strings.Contains(lower, "invalid range") || strings.Contains(lower, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
-			}
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
-			}
-			return mcperr.FromText(fmt.Sprintf("READ_FAILED: %v", err)), nil
-		}
-
-		out := ReadRangeOutput{Path: canonical, Sheet: sheet, RangeA1: outRange, Meta: meta}
-		// Text payload starts with a concise meta summary followed by data
-		summary := fmt.Sprintf("total=%d returned=%d truncated=%v", out.Meta.Total, out.Meta.Returned, out.Meta.Truncated)
-		if out.Meta.Truncated {
-			summary = summary + " nextCursor=" + out.Meta.NextCursor
-		} else {
-			summary = summary + " nextCursor="
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
 		}
-		res := mcp.NewToolResultStructured(out, "range read complete")
-		res.Content = []mcp.Content{mcp.NewTextContent(summary + "\n" + textOut)}
-		return res, nil
+		return runReadRange(ctx, reg, limits, mgr, in)
 	}))
 	reg.Register(readRange)
 
 	// search_data
 	searchTool := mcp.NewTool(
 		"search_data",
-		mcp.WithDescription("Find literal values or regex matches in a sheet and return a bounded page of results with coordinates and a limited row snapshot. Use this to locate relevant rows without streaming entire sheets. Pagination operates in rows (unit=rows); when a cursor is provided it takes precedence over sheet/query/filters/max_results and binds to path+mtime and a query hash so resumes are deterministic. Optional 1‑based column filters restrict the search to specific columns. Snapshots are anchored to the leftmost used column and capped by snapshot_cols and sheet width. Errors include VALIDATION, INVALID_SHEET, CURSOR_INVALID, and SEARCH_FAILED."),
+		mcp.WithDescription("Find literal values or regex matches in a sheet and return a bounded page of results with coordinates and a limited row snapshot. Use this to locate relevant rows without streaming entire sheets. Pagination operates in rows (unit=rows); when a cursor is provided it takes precedence over sheet/query/filters/max_results and binds to path+content fingerprint and a query hash so resumes are deterministic. meta.pages gives the page count at the current page size; page=N jumps straight to a page (with a cursor, the cursor's parameters still bind), but every call rescans the sheet from the start, so a jump costs the same as a first page. Optional 1‑based column filters restrict the search to specific columns. scope='formulas' searches formula text instead of values (e.g. which cells reference Sheet3!, or where a hardcoded 1.07 appears) and scope='comments' searches cell comments; there a literal query matches anywhere in the text ignoring case, each match's value is the formula (with '=') or comment text, no snapshot is attached, and the cursor keeps the scope. Every match reports its scope. For numeric or date ranges pass range_query instead of query, e.g. {type: 'number', min: 1000, max: 2000} or {type: 'date', min: '2024-03-01', max: '2024-03-31'}: bounds are inclusive, either may be omitted for an open-ended range, and a date-only max covers that whole day. Cell values are parsed as numbers (numeric text included) or dates, so formatting does not hide matches; the normalized range is echoed as rangeQuery and kept by the cursor. Supplying both query and range_query is a VALIDATION error. A literal value query matches whole cell values exactly; when it matches nothing, the same scan collects up to 5 near misses (values containing the query ignoring case, or a close spelling of a short query, within the column filter) into suggestions, and the text explains the miss; regex, range, formula, and comment searches return no suggestions. Snapshots are anchored to the leftmost used column and capped by snapshot_cols and sheet width. Set output='summary' to keep text content to the stats line plus up to 5 compact examples (structured content still carries every result); meta reports estimated tokens for both modes. With regex=true the query is compiled as Go RE2 before the workbook is opened: patterns over 512 bytes, repeat counts over 1000, and PCRE‑only constructs (lookahead/lookbehind, backreferences, atomic groups, possessive quantifiers) fail with VALIDATION naming the problem. Errors include VALIDATION, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, and SEARCH_FAILED."),
 		mcp.WithInputSchema[SearchDataInput](),
 		mcp.WithOutputSchema[SearchDataOutput](),
+		readOnlyTool(true),
 	)
 	s.AddTool(searchTool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in SearchDataInput) (*mcp.CallToolResult, error) {
 		if msg := validation.ValidateStruct(in); msg != "" {
@@ -710,17 +868,31 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		query := strings.TrimSpace(in.Query)
 		curTok := strings.TrimSpace(in.Cursor)
 		regex := in.Regex
+		scope := in.Scope
 		if p == "" {
-			return mcperr.FromText("VALIDATION: path is required"), nil
+			return mcperr.New(mcperr.Validation, "path is required"), nil
+		}
+		var rangeQ *searchRange
+		if in.RangeQuery != nil {
+			switch {
+			case query != "":
+				return mcperr.New(mcperr.Validation, "use query or range_query, not both"), nil
+			case in.Regex:
+				return mcperr.New(mcperr.Validation, "regex applies to query; range_query needs no regex"), nil
+			case scope != "" && scope != searchScopeValues:
+				return mcperr.New(mcperr.Validation, "range_query searches cell values; drop scope or use scope=values"), nil
+			}
+			r, rerr := parseSearchRange(*in.RangeQuery)
+			if rerr != nil {
+				return mcperr.New(mcperr.Validation, rerr.Error()), nil
+			}
+			rangeQ = &r
 		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
-		}
-		maxResults := in.MaxResults
-		if maxResults <= 0 || maxResults > 1000 {
-			maxResults = 50
+			return openFailed(openErr), nil
 		}
+		maxResults := pagination.PageSize(in.MaxResults, 0, 50, 1000)
 		snapshotCols := in.SnapshotCols
 		if snapshotCols <= 0 || snapshotCols > 256 {
 			snapshotCols = 16
@@ -732,22 +904,24 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		var startOffset int
 		var parsedCur *pagination.Cursor
 		if curTok != "" {
-			pc, derr := pagination.DecodeCursor(curTok)
-			if derr != nil {
-				return mcperr.FromText("CURSOR_INVALID: failed to decode cursor; reopen workbook and restart pagination"), nil
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
 			}
 			if pc.Pt != canonical {
-				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+				return mcperr.New(mcperr.CursorInvalid, "cursor path does not match provided path"), nil
 			}
 			if pc.U != pagination.UnitRows {
-				return mcperr.FromText("CURSOR_INVALID: unit mismatch; search_data expects rows"), nil
+				return mcperr.New(mcperr.CursorInvalid, "unit mismatch; search_data expects rows"), nil
 			}
 			// When query/filters are provided alongside cursor, ensure they bind to the same parameters
-			if query != "" || len(in.Columns) > 0 || in.Regex {
-				qh := computeQueryHash(query, in.Regex, in.Columns)
+			if query != "" || len(in.Columns) > 0 || in.Regex || rangeQ != nil {
+				qh := computeQueryHash(query, in.Regex, in.Columns, cmp.Or(scope, pc.Sp), rangeText(rangeQ))
 				if pc.Qh != "" && pc.Qh != qh {
-					return mcperr.FromText("CURSOR_INVALID: cursor parameters do not match current query/filters"), nil
+					return mcperr.New(mcperr.CursorInvalid, "cursor parameters do not match current query/filters"), nil
 				}
+			} else if scope != "" && scope != cmp.Or(pc.Sp, searchScopeValues) {
+				return mcperr.New(mcperr.CursorInvalid, "cursor scope does not match scope"), nil
 			}
 			sheet = pc.S
 			// If query/regex/columns are not provided on resume, recover them from cursor when available
@@ -760,16 +934,33 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			if len(in.Columns) == 0 && len(pc.Cl) > 0 {
 				in.Columns = pc.Cl
 			}
-			startOffset = pc.Off
-			if pc.Ps > 0 && pc.Ps < maxResults {
-				maxResults = pc.Ps
+			if scope == "" {
+				scope = pc.Sp
 			}
+			if rangeQ == nil && pc.Rq != "" && query == "" {
+				r, rerr := parseSearchRangeText(pc.Rq)
+				if rerr != nil {
+					return mcperr.New(mcperr.CursorInvalid, "cursor range query is invalid; restart the search"), nil
+				}
+				rangeQ = &r
+			}
+			startOffset = pc.Off
+			maxResults = pagination.PageSize(in.MaxResults, pc.Ps, maxResults, 1000)
 			parsedCur = pc
 		} else {
-			if sheet == "" || query == "" {
-				return mcperr.FromText("VALIDATION: sheet and query are required (or supply cursor)"), nil
+			if sheet == "" || (query == "" && rangeQ == nil) {
+				return mcperr.New(mcperr.Validation, "sheet and query or range_query are required (or supply cursor)"), nil
 			}
 		}
+		// Page jumps replace the cursor offset; the query binding above still applies.
+		if in.Page > 0 {
+			startOffset = (in.Page - 1) * maxResults
+		}
+		pageNo := startOffset/maxResults + 1
+
+		if scope == "" {
+			scope = searchScopeValues
+		}
 
 		// Build column filter set from final in.Columns (possibly recovered from cursor)
 		if len(in.Columns) > 0 {
@@ -787,17 +978,20 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		output.Sheet = sheet
 		output.Query = query
 		output.Regex = regex
+		if rangeQ != nil {
+			output.RangeQuery = rangeQ.query()
+		}
 
 		var fileMT int64
-		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
-			// Compute current file mtime under read lock for cursor emission
-			if fi, serr := os.Stat(canonical); serr == nil {
-				fileMT = fi.ModTime().Unix()
-			}
-			if parsedCur != nil && parsedCur.Mt > 0 {
-				if parsedCur.Mt != fileMT {
-					return errCursorMtMismatch
-				}
+		var fileFP string
+		var wbVersion int64
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
+			// Snapshot the file under the read lock for cursor emission and
+			// reject cursors issued against different contents
+			fileMT, fileFP = fileSnapshot(canonical)
+			wbVersion = version
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
 			}
 
 			// Resolve used range for sheet and derive snapshot anchoring and bounds
@@ -823,13 +1017,20 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				}
 			}
 
-			// Execute search
+			// Execute search; formula and comment matches carry their text.
 			var matches []string
+			var texts map[string]string
+			var suggestions []SearchSuggestion
 			var sErr error
-			if regex {
+			switch {
+			case rangeQ != nil:
+				matches, sErr = searchSheetRange(ctx, f, sheet, *rangeQ)
+			case scope != searchScopeValues:
+				matches, texts, sErr = searchSheetText(ctx, f, sheet, query, regex, scope)
+			case regex:
 				matches, sErr = f.SearchSheet(sheet, query, true)
-			} else {
-				matches, sErr = f.SearchSheet(sheet, query)
+			default:
+				matches, suggestions, sErr = searchSheetLiteral(ctx, f, sheet, query, colFilter)
 			}
 			if sErr != nil {
 				return sErr
@@ -857,6 +1058,10 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			// Build results page
 			total := len(filtered)
 			output.Meta.Total = total
+			output.Meta.Pages = pageCount(total, maxResults)
+			if total == 0 {
+				output.Suggestions = suggestions
+			}
 			if startOffset > total {
 				startOffset = total
 			}
@@ -875,6 +1080,10 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				if e != nil {
 					continue
 				}
+				if scope != searchScopeValues {
+					results = append(results, SearchMatch{Cell: cell, Row: y, Column: x, Value: texts[cell], Scope: scope})
+					continue
+				}
 				val, _ := f.GetCellValue(sheet, cell)
 				// Snapshot anchored to left bound of used range
 				rowVals := make([]string, 0, maxCols)
@@ -886,7 +1095,7 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 					v, _ := f.GetCellValue(sheet, cn)
 					rowVals = append(rowVals, v)
 				}
-				results = append(results, SearchMatch{Cell: cell, Row: y, Column: x, Value: val, Snapshot: rowVals})
+				results = append(results, SearchMatch{Cell: cell, Row: y, Column: x, Value: val, Scope: scope, Snapshot: rowVals})
 			}
 			output.Results = results
 			output.Meta.Returned = len(results)
@@ -897,67 +1106,77 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				if parsedCur != nil && parsedCur.Qh != "" {
 					qh = parsedCur.Qh
 				} else {
-					qh = computeQueryHash(query, regex, in.Columns)
+					qh = computeQueryHash(query, regex, in.Columns, scope, rangeText(rangeQ))
+				}
+				if sheetRange == "" {
+					// excelize-written files may record only "A1" as the dimension.
+					sheetRange, _ = scanUsedRange(f, sheet)
+				}
+				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: sheetRange, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(results)), Ps: maxResults, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: wbVersion, Qh: qh, Q: query, Rg: regex, Cl: in.Columns}
+				if scope != searchScopeValues {
+					next.Sp = scope
+				}
+				if rangeQ != nil {
+					next.Rq = rangeQ.String()
 				}
-				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: sheetRange, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(results)), Ps: maxResults, Mt: fileMT, Qh: qh, Q: query, Rg: regex, Cl: in.Columns}
 				token, encErr := pagination.EncodeCursor(next)
 				if encErr != nil {
-					return fmt.Errorf("CURSOR_BUILD_FAILED: %v", encErr)
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
 				}
 				output.Meta.NextCursor = token
 			}
 			return nil
 		})
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
-			}
-			if errors.Is(err, errCursorMtMismatch) {
-				return mcperr.FromText("CURSOR_INVALID: file changed since cursor was issued; restart pagination"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
 			}
-			// Cursor build failure mapping
-			if strings.HasPrefix(err.Error(), "CURSOR_BUILD_FAILED:") {
-				return mcperr.FromText("CURSOR_BUILD_FAILED: failed to encode next page cursor; retry or narrow scope"), nil
-			}
-			return mcperr.FromText(fmt.Sprintf("SEARCH_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.SearchFailed, "%v", err), nil
 		}
 
+		runtime.CallStatsFrom(ctx).SetResult(output.Meta.Returned, output.Meta.Truncated)
 		// Human-friendly summary
-		summary := fmt.Sprintf("matches=%d returned=%d truncated=%v", output.Meta.Total, output.Meta.Returned, output.Meta.Truncated)
+		summary := fmt.Sprintf("matches=%d returned=%d truncated=%v page=%d/%d", output.Meta.Total, output.Meta.Returned, output.Meta.Truncated, pageNo, output.Meta.Pages)
 		if output.Meta.Truncated && output.Meta.NextCursor != "" {
 			// Surface nextCursor in summary for clients that ignore structured meta
 			summary = summary + " nextCursor=" + output.Meta.NextCursor
 		}
-		res := mcp.NewToolResultStructured(output, summary)
-		// Attach a human-readable summary line followed by JSON results text
-		if b, jerr := json.Marshal(output.Results); jerr == nil {
-			var sb strings.Builder
-			sb.WriteString(summary)
-			sb.WriteByte('\n')
-			sb.Write(b)
-			res.Content = []mcp.Content{mcp.NewTextContent(sb.String())}
-		} else {
-			res.Content = []mcp.Content{mcp.NewTextContent(summary)}
+		if output.Meta.Total == 0 && rangeQ == nil && !regex && scope == searchScopeValues {
+			summary += "\n" + searchMissHint(output.Suggestions)
+		}
+		examples := make([]string, 0, minInt(len(output.Results), maxSummaryExamples))
+		for _, m := range output.Results {
+			if len(examples) == maxSummaryExamples {
+				break
+			}
+			examples = append(examples, fmt.Sprintf("- %s: %s", m.Cell, compactRow(m.Snapshot)))
 		}
+		textOut := budgetText(limits, buildPageText(summary, output.Results, examples, in.Output, &output.Meta))
+		recordPayload(&output.Meta, textOut, pagination.UnitRows, maxResults, limits.MaxPayloadBytes)
+		res := mcp.NewToolResultStructured(output, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(textOut)}
 		return res, nil
 	}))
 	reg.Register(searchTool)
 
 	// filter_data
 	type FilterDataInput struct {
-		Path         string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
-		Sheet        string `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Target sheet name (case‑insensitive)"`
-		Predicate    string `json:"predicate" validate:"required_without=Cursor" jsonschema_description:"Boolean predicate using $N (1‑based) column refs with operators (=, !=, >, <, >=, <=, contains) and AND/OR/NOT; parentheses supported"`
-		Columns      []int  `json:"columns,omitempty" validate:"dive,min=1" jsonschema_description:"Optional 1‑based column indexes echoed into the cursor provenance for deterministic resume"`
-		MaxRows      int    `json:"max_rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max rows per page (unit=rows); bounded by server limits"`
-		SnapshotCols int    `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max columns to include in each row snapshot; anchored to leftmost used column (bounded)"`
-		Cursor       string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque URL‑safe base64 cursor (unit=rows) bound to path+mtime and predicate hash; takes precedence for resume"`
+		Path          string      `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+		Password      string      `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+		Sheet         string      `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Target sheet name (case‑insensitive)"`
+		Predicate     string      `json:"predicate" validate:"required_without=Cursor" jsonschema_description:"Boolean predicate using $N (1‑based) column refs with operators (=, !=, >, <, >=, <=, contains), $N IS EMPTY / $N IS NOT EMPTY (whitespace‑only cells count as empty), and AND/OR/NOT; parentheses supported"`
+		Columns       []int       `json:"columns,omitempty" validate:"dive,min=1" jsonschema_description:"Optional 1‑based column indexes echoed into the cursor provenance for deterministic resume"`
+		MaxRows       int         `json:"max_rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max rows per page (unit=rows); bounded by server limits"`
+		Page          int         `json:"page,omitempty" validate:"omitempty,min=1" jsonschema_description:"1‑based page to jump to at the current page size (see meta.pages); with a cursor, jumps within the cursor's predicate. Each call rescans the sheet from the start"`
+		SnapshotCols  int         `json:"snapshot_cols,omitempty" validate:"omitempty,min=1,max=256" jsonschema_description:"Max columns to include in each row snapshot; anchored to leftmost used column (bounded); ignored when return_columns is set"`
+		ReturnColumns []ColumnRef `json:"return_columns,omitempty" validate:"omitempty,max=256" jsonschema_description:"Columns to include in each row snapshot, in this order: 1‑based indices (as in $N) or header names matched case‑insensitively in the first used row; missing cells are empty strings. Presentation only: not part of the predicate hash, but carried by the cursor"`
+		SortColumn    *ColumnRef  `json:"sort_column,omitempty" jsonschema_description:"Order matches by this column (1‑based index or header name) before paging; numbers sort numerically before text, empty cells last. Only the first max_rows × 10 sorted rows are reachable"`
+		SortOrder     string      `json:"sort_order,omitempty" validate:"omitempty,oneof=asc desc" jsonschema_description:"Sort direction for sort_column: 'asc' (default) or 'desc'"`
+		Cursor        string      `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque URL‑safe base64 cursor (unit=rows) bound to path+content fingerprint, predicate hash, and sort; takes precedence for resume"`
+		Output        string      `json:"output,omitempty" validate:"omitempty,oneof=summary full" jsonschema_description:"Text content mode: 'full' (summary + JSON results, default) or 'summary' (summary + up to 5 compact example rows); structured content always has all results"`
 	}
 
 	type FilteredRow struct {
@@ -966,18 +1185,20 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 	}
 
 	type FilterDataOutput struct {
-		Path      string        `json:"path"`
-		Sheet     string        `json:"sheet"`
-		Predicate string        `json:"predicate"`
-		Results   []FilteredRow `json:"results"`
-		Meta      PageMeta      `json:"meta"`
+		Path          string        `json:"path"`
+		Sheet         string        `json:"sheet"`
+		Predicate     string        `json:"predicate"`
+		ReturnColumns []int         `json:"returnColumns,omitempty" jsonschema_description:"1‑based sheet columns of each snapshot, in order, when return_columns was given"`
+		Results       []FilteredRow `json:"results"`
+		Meta          PageMeta      `json:"meta"`
 	}
 
 	filterTool := mcp.NewTool(
 		"filter_data",
-		mcp.WithDescription("Filter rows using a boolean predicate with $N column references and comparison/boolean operators, and return a bounded page with snapshots. Use when column positions are known and you need structured selection (e.g., $1 contains 'foo' AND $3 > 100). Pagination operates in rows (unit=rows); a cursor takes precedence and binds to path+mtime and a predicate hash so resumes are deterministic. Column indices referenced by $N are 1‑based. Snapshots are anchored to the leftmost used column and capped by snapshot_cols. Errors include VALIDATION (predicate/inputs), INVALID_SHEET, CURSOR_INVALID, and FILTER_FAILED."),
+		mcp.WithDescription(fmt.Sprintf("Filter rows using a boolean predicate with $N column references, comparison operators (=, !=, >, <, >=, <=, contains), emptiness checks ($N IS EMPTY, $N IS NOT EMPTY; whitespace‑only cells count as empty), and AND/OR/NOT, and return a bounded page with snapshots. Use when column positions are known and you need structured selection (e.g., $1 contains 'foo' AND $3 > 100). Pagination operates in rows (unit=rows); a cursor takes precedence and binds to path+content fingerprint and a predicate hash so resumes are deterministic. meta.pages gives the page count at the current page size; page=N jumps straight to a page (with a cursor, the cursor's parameters still bind), but every call rescans the sheet from the start, so a jump costs the same as a first page. Column indices referenced by $N are 1‑based. Snapshots are anchored to the leftmost used column and capped by snapshot_cols; return_columns instead projects exactly the listed columns (1‑based indices or header names) in the order given, and the cursor carries them so resumed pages render identically. Without sort_column rows stream in sheet order; with sort_column (and sort_order asc|desc) the best max_rows × %[1]d matches are kept in a bounded top‑K buffer and paged in sorted order, meta.sortCapped marks results with more matches than that, and pages past the cap return LIMIT_EXCEEDED. Set output='summary' to keep text content to the stats line plus up to 5 compact examples (structured content still carries every result); meta reports estimated tokens for both modes. Errors include VALIDATION (predicate/inputs), INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, LIMIT_EXCEEDED, and FILTER_FAILED.", maxSortedPages)),
 		mcp.WithInputSchema[FilterDataInput](),
 		mcp.WithOutputSchema[FilterDataOutput](),
+		readOnlyTool(true),
 	)
 
 	s.AddTool(filterTool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in FilterDataInput) (*mcp.CallToolResult, error) {
@@ -989,40 +1210,70 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		pred := strings.TrimSpace(in.Predicate)
 		curTok := strings.TrimSpace(in.Cursor)
 		if p == "" {
-			return mcperr.FromText("VALIDATION: path is required"), nil
+			return mcperr.New(mcperr.Validation, "path is required"), nil
 		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
-		}
-		maxRows := in.MaxRows
-		if maxRows <= 0 || maxRows > 1000 {
-			maxRows = 200
+			return openFailed(openErr), nil
 		}
+		maxRows := pagination.PageSize(in.MaxRows, 0, 200, 1000)
 		snapshotCols := in.SnapshotCols
 		if snapshotCols <= 0 || snapshotCols > 256 {
 			snapshotCols = 16
 		}
 
+		// Index-only projections resolve now; header names resolve against the
+		// first used row during the scan.
+		var returnCols []int
+		if len(in.ReturnColumns) > 0 && !columnRefsNeedHeader(in.ReturnColumns) {
+			cols, rerr := resolveColumnRefs(in.ReturnColumns, nil, 0)
+			if rerr != nil {
+				return classifyError(rerr), nil
+			}
+			returnCols = cols
+		}
+		var order *filterSort
+		if in.SortColumn != nil {
+			order = &filterSort{col: *in.SortColumn, desc: in.SortOrder == "desc"}
+		} else if in.SortOrder != "" {
+			return mcperr.New(mcperr.Validation, "sort_order requires sort_column"), nil
+		}
+
 		// Cursor precedence and binding validation
 		var startOffset int
 		var parsedCur *pagination.Cursor
 		if curTok != "" {
-			pc, derr := pagination.DecodeCursor(curTok)
-			if derr != nil {
-				return mcperr.FromText("CURSOR_INVALID: failed to decode cursor; reopen workbook and restart pagination"), nil
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
 			}
 			if pc.Pt != canonical {
-				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+				return mcperr.New(mcperr.CursorInvalid, "cursor path does not match provided path"), nil
 			}
 			if pc.U != pagination.UnitRows {
-				return mcperr.FromText("CURSOR_INVALID: unit mismatch; filter_data expects rows"), nil
+				return mcperr.New(mcperr.CursorInvalid, "unit mismatch; filter_data expects rows"), nil
+			}
+			// return_columns is presentation only, so a new projection may
+			// replace the cursor's without invalidating it.
+			if len(in.ReturnColumns) == 0 && len(pc.Rc) > 0 {
+				returnCols = pc.Rc
+			}
+			// Sorting changes which rows a page holds, so it binds like the predicate.
+			if order != nil && order.String() != pc.Ob {
+				return mcperr.New(mcperr.CursorInvalid, "cursor parameters do not match current sort"), nil
+			}
+			if pc.Ob != "" {
+				cs, serr := parseFilterSort(pc.Ob)
+				if serr != nil {
+					return mcperr.New(mcperr.CursorInvalid, serr.Error()), nil
+				}
+				order = &cs
 			}
 			// When predicate/columns are provided alongside cursor, ensure they bind to same parameters
 			if pred != "" || len(in.Columns) > 0 {
-				ph := computePredicateHash(pred, in.Columns)
+				ph := filterPredicateHash(pred, in.Columns)
 				if pc.Ph != "" && pc.Ph != ph {
-					return mcperr.FromText("CURSOR_INVALID: cursor parameters do not match current predicate/columns"), nil
+					return mcperr.New(mcperr.CursorInvalid, "cursor parameters do not match current predicate/columns"), nil
 				}
 			}
 			sheet = pc.S
@@ -1033,20 +1284,33 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				in.Columns = pc.Cl
 			}
 			startOffset = pc.Off
-			if pc.Ps > 0 && pc.Ps < maxRows {
-				maxRows = pc.Ps
-			}
+			maxRows = pagination.PageSize(in.MaxRows, pc.Ps, maxRows, 1000)
 			parsedCur = pc
 		} else {
 			if sheet == "" || pred == "" {
-				return mcperr.FromText("VALIDATION: sheet and predicate are required (or supply cursor)"), nil
+				return mcperr.New(mcperr.Validation, "sheet and predicate are required (or supply cursor)"), nil
+			}
+		}
+		// Page jumps replace the cursor offset; the predicate binding above still applies.
+		if in.Page > 0 {
+			startOffset = (in.Page - 1) * maxRows
+		}
+		pageNo := startOffset/maxRows + 1
+		sortCap := maxRows * maxSortedPages
+		var sortCol int
+		if order != nil {
+			if startOffset >= sortCap {
+				return mcperr.New(mcperr.LimitExceeded, fmt.Sprintf("sorted results are capped at %d rows (max_rows × %d pages); narrow the predicate or drop sort_column", sortCap, maxSortedPages)), nil
+			}
+			if order.col.Name == "" {
+				sortCol = order.col.Index
 			}
 		}
 
 		// Compile predicate to evaluator
 		eval, perr := compilePredicate(pred)
 		if perr != nil {
-			return mcperr.FromText("VALIDATION: invalid predicate; examples: $1 = \"foo\", $3 > 100, $2 contains \"bar\", ($1 = \"x\" AND $4 >= 0.5) OR NOT $5 = \"y\""), nil
+			return mcperr.New(mcperr.Validation, "invalid predicate; examples: $1 = \"foo\", $3 > 100, $2 contains \"bar\", $4 IS NOT EMPTY, ($1 = \"x\" AND $4 >= 0.5) OR NOT $5 = \"y\""), nil
 		}
 
 		var output FilterDataOutput
@@ -1055,15 +1319,15 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		output.Predicate = pred
 
 		var fileMT int64
-		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
-			// Compute current file mtime under read lock for cursor emission
-			if fi, serr := os.Stat(canonical); serr == nil {
-				fileMT = fi.ModTime().Unix()
-			}
-			if parsedCur != nil && parsedCur.Mt > 0 {
-				if parsedCur.Mt != fileMT {
-					return errCursorMtMismatch
-				}
+		var fileFP string
+		var wbVersion int64
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
+			// Snapshot the file under the read lock for cursor emission and
+			// reject cursors issued against different contents
+			fileMT, fileFP = fileSnapshot(canonical)
+			wbVersion = version
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
 			}
 			// Resolve used range and snapshot bounds
 			sheetRange := ""
@@ -1095,7 +1359,35 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			total := 0
 			returned := 0
 			rowIdx := 0
+			scanned := 0
 			results := make([]FilteredRow, 0, maxRows)
+			var top *topRows
+			if order != nil {
+				top = newTopRows(sortCap, order.desc)
+			}
+			// snapshot projects return_columns in order, or else the
+			// [xLeft,xRight] window.
+			snapshot := func(rowVals []string) []string {
+				if returnCols != nil {
+					snap := make([]string, len(returnCols))
+					for i, c := range returnCols {
+						if c <= len(rowVals) {
+							snap[i] = rowVals[c-1]
+						}
+					}
+					return snap
+				}
+				snap := make([]string, 0, xRight-xLeft+1)
+				for c := xLeft; c <= xRight; c++ {
+					absCol := c - 1
+					if absCol >= 0 && absCol < len(rowVals) {
+						snap = append(snap, rowVals[absCol])
+					} else {
+						snap = append(snap, "")
+					}
+				}
+				return snap
+			}
 
 			for rowsIter.Next() {
 				if ctx.Err() != nil {
@@ -1112,93 +1404,121 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				if cerr != nil {
 					return cerr
 				}
+				if returnCols == nil && len(in.ReturnColumns) > 0 {
+					cols, rerr := resolveColumnRefs(in.ReturnColumns, rowVals, rowIdx)
+					if rerr != nil {
+						return rerr
+					}
+					returnCols = cols
+				}
+				if order != nil && sortCol == 0 {
+					cols, rerr := resolveColumnRefs([]ColumnRef{order.col}, rowVals, rowIdx)
+					if rerr != nil {
+						return rerr
+					}
+					sortCol = cols[0]
+				}
+				scanned += len(rowVals)
 				ok := eval(rowVals)
 				if ok {
 					total++
-					if total > startOffset && returned < maxRows {
-						// Build snapshot across [xLeft,xRight]
-						snap := make([]string, 0, xRight-xLeft+1)
-						for c := xLeft; c <= xRight; c++ {
-							if ctx.Err() != nil {
-								return ctx.Err()
-							}
-							absCol := c - 1
-							if absCol >= 0 && absCol < len(rowVals) {
-								snap = append(snap, rowVals[absCol])
-							} else {
-								snap = append(snap, "")
-							}
+					switch {
+					case top != nil:
+						r := sortedRow{row: rowIdx}
+						if sortCol <= len(rowVals) {
+							r.key = rowVals[sortCol-1]
+						}
+						if top.accepts(r) {
+							r.snapshot = snapshot(rowVals)
+							top.offer(r)
 						}
-						results = append(results, FilteredRow{Row: rowIdx, Snapshot: snap})
+					case total > startOffset && returned < maxRows:
+						results = append(results, FilteredRow{Row: rowIdx, Snapshot: snapshot(rowVals)})
 						returned++
 					}
 				}
 			}
+			reachable := total
+			if top != nil {
+				kept := top.sorted()
+				for i := startOffset; i < len(kept) && returned < maxRows; i++ {
+					results = append(results, FilteredRow{Row: kept[i].row, Snapshot: kept[i].snapshot})
+					returned++
+				}
+				output.Meta.SortCapped = total > sortCap
+				reachable = minInt(total, sortCap)
+			}
 
+			runtime.CallStatsFrom(ctx).AddCells(scanned)
+			output.ReturnColumns = returnCols
 			output.Results = results
 			output.Meta.Total = total
+			output.Meta.Pages = pageCount(reachable, maxRows)
 			output.Meta.Returned = returned
 			output.Meta.Truncated = (startOffset + returned) < total
-			if output.Meta.Truncated {
+			if next := pagination.NextOffset(startOffset, returned); output.Meta.Truncated && next < reachable {
 				ph := ""
 				if parsedCur != nil && parsedCur.Ph != "" {
 					ph = parsedCur.Ph
 				} else {
-					ph = computePredicateHash(pred, in.Columns)
+					ph = filterPredicateHash(pred, in.Columns)
 				}
-				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: sheetRange, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, returned), Ps: maxRows, Mt: fileMT, Ph: ph, P: pred, Cl: in.Columns}
-				token, encErr := pagination.EncodeCursor(next)
+				if sheetRange == "" {
+					// excelize-written files may record only "A1" as the dimension.
+					sheetRange, _ = scanUsedRange(f, sheet)
+				}
+				nc := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: sheetRange, U: pagination.UnitRows, Off: next, Ps: maxRows, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: wbVersion, Ph: ph, P: pred, Cl: in.Columns, Rc: returnCols}
+				if order != nil {
+					nc.Ob = order.String()
+				}
+				token, encErr := pagination.EncodeCursor(nc)
 				if encErr != nil {
-					return fmt.Errorf("CURSOR_BUILD_FAILED: %v", encErr)
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
 				}
 				output.Meta.NextCursor = token
 			}
 			return nil
 		})
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
-			}
-			if errors.Is(err, errCursorMtMismatch) {
-				return mcperr.FromText("CURSOR_INVALID: file changed since cursor was issued; restart pagination"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
 			}
-			if strings.HasPrefix(err.Error(), "CURSOR_BUILD_FAILED:") {
-				return mcperr.FromText("CURSOR_BUILD_FAILED: failed to encode next page cursor; retry or narrow scope"), nil
-			}
-			return mcperr.FromText(fmt.Sprintf("FILTER_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.FilterFailed, "%v", err), nil
 		}
 
+		runtime.CallStatsFrom(ctx).SetResult(output.Meta.Returned, output.Meta.Truncated)
 		// Attach human-readable summary and JSON results (like search_data)
-		summary := fmt.Sprintf("matches=%d returned=%d truncated=%v", output.Meta.Total, output.Meta.Returned, output.Meta.Truncated)
+		summary := fmt.Sprintf("matches=%d returned=%d truncated=%v page=%d/%d", output.Meta.Total, output.Meta.Returned, output.Meta.Truncated, pageNo, output.Meta.Pages)
 		if output.Meta.Truncated && output.Meta.NextCursor != "" {
 			summary = summary + " nextCursor=" + output.Meta.NextCursor
 		}
-		res := mcp.NewToolResultStructured(output, summary)
-		if b, jerr := json.Marshal(output.Results); jerr == nil {
-			var sb strings.Builder
-			sb.WriteString(summary)
-			sb.WriteByte('\n')
-			sb.Write(b)
-			res.Content = []mcp.Content{mcp.NewTextContent(sb.String())}
-		} else {
-			res.Content = []mcp.Content{mcp.NewTextContent(summary)}
+		examples := make([]string, 0, minInt(len(output.Results), maxSummaryExamples))
+		for _, r := range output.Results {
+			if len(examples) == maxSummaryExamples {
+				break
+			}
+			examples = append(examples, fmt.Sprintf("- row %d: %s", r.Row, compactRow(r.Snapshot)))
 		}
+		textOut := budgetText(limits, buildPageText(summary, output.Results, examples, in.Output, &output.Meta))
+		recordPayload(&output.Meta, textOut, pagination.UnitRows, maxRows, limits.MaxPayloadBytes)
+		res := mcp.NewToolResultStructured(output, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(textOut)}
 		return res, nil
 	}))
 	reg.Register(filterTool)
 
 	// write_range
 	type WriteRangeInput struct {
-		Path    string     `json:"path" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
-		Sheet   string     `json:"sheet" jsonschema_description:"Target sheet name"`
-		RangeA1 string     `json:"range" jsonschema_description:"Target A1 range (e.g., B2:D10)"`
-		Values  [][]string `json:"values" jsonschema_description:"2D array of values matching the range dimensions"`
+		Path     string     `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+		Password string     `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+		Sheet    string     `json:"sheet" validate:"required" jsonschema_description:"Target sheet name"`
+		RangeA1  string     `json:"range" validate:"required,a1orname" jsonschema_description:"Target A1 range (e.g., B2:D10)"`
+		Values   [][]string `json:"values" validate:"required,min=1" jsonschema_description:"2D array of values matching the range dimensions"`
+		Force    bool       `json:"force,omitempty" jsonschema_description:"Write even when the sheet is protected"`
+		Backup   bool       `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
 	}
 	type WriteRangeOutput struct {
 		Path         string `json:"path"`
@@ -1206,38 +1526,42 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		RangeA1      string `json:"range"`
 		CellsUpdated int    `json:"cellsUpdated"`
 		Idempotent   bool   `json:"idempotent"`
+		Save         string `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+		Backup       string `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
 	}
 
 	writeRange := mcp.NewTool(
 		"write_range",
-		mcp.WithDescription("Write a bounded block of values to a range using a transactional stream writer"),
+		mcp.WithDescription("Write a bounded block of values to a range; other cells on the sheet are left unchanged. Protected sheets (list_structure protected=true) are refused with PERMISSION_DENIED unless force=true."),
 		mcp.WithInputSchema[WriteRangeInput](),
 		mcp.WithOutputSchema[WriteRangeOutput](),
+		writeTool(true, false),
 	)
 	s.AddTool(writeRange, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in WriteRangeInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
 		p := strings.TrimSpace(in.Path)
 		sheet := strings.TrimSpace(in.Sheet)
 		rng := strings.TrimSpace(in.RangeA1)
-		if p == "" || sheet == "" || rng == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, and range are required"), nil
-		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
-		}
-		if len(in.Values) == 0 {
-			return mcperr.FromText("VALIDATION: values must be a non-empty 2D array"), nil
+			return openFailed(openErr), nil
 		}
 
 		var updated int
-		err := mgr.WithWrite(id, func(f *excelize.File) error {
+		var saved workbooks.SaveResult
+		// writeCells sets each cell in place; a stream writer would rewrite the
+		// whole sheet and is tracked in a workbook-wide map excelize reads
+		// without locking. Under a sheet lock it refuses ranges holding formulas,
+		// since replacing one edits the workbook-wide calc chain.
+		writeCells := func(f *excelize.File, save workbooks.SaveFunc, workbookLocked bool) error {
 			// Respect cancellation before heavy work
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			// Respect cancellation before heavy work
-			if ctx.Err() != nil {
-				return ctx.Err()
+			if err := checkSheetProtection(f, sheet, in.Force); err != nil {
+				return err
 			}
 			// Resolve range and verify dimensions match values
 			x1, y1, x2, y2, resolvedRange, perr := resolveRange(f, sheet, rng)
@@ -1248,7 +1572,7 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			rows := y2 - y1 + 1
 			cols := x2 - x1 + 1
 			if rows <= 0 || cols <= 0 {
-				return fmt.Errorf("invalid range bounds")
+				return fmt.Errorf("%w bounds", mcperr.ErrInvalidRange)
 			}
 			if len(in.Values) != rows {
 				return fmt.Errorf("values row count (%d) does not match range rows (%d)", len(in.Values), rows)
@@ -1260,73 +1584,74 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			}
 			cells := rows * cols
 			if cells > limits.MaxCellsPerOp {
-				return fmt.Errorf("payload exceeds max cells per operation: %d > %d", cells, limits.MaxCellsPerOp)
+				return mcperr.Errorf(mcperr.PayloadTooLarge, "range has %d cells, max %d per operation; reduce range size or split into batches", cells, limits.MaxCellsPerOp)
+			}
+			if !workbookLocked {
+				for r := y1; r <= y2; r++ {
+					for c := x1; c <= x2; c++ {
+						cell, _ := excelize.CoordinatesToCellName(c, r)
+						if fx, _ := f.GetCellFormula(sheet, cell); fx != "" {
+							return errNeedsWorkbookLock
+						}
+					}
+				}
 			}
 
-			sw, err := f.NewStreamWriter(sheet)
-			if err != nil {
-				return err
-			}
-			// Write each row in ascending order
 			for r := 0; r < rows; r++ {
 				if ctx.Err() != nil {
 					return ctx.Err()
 				}
-				startCell, _ := excelize.CoordinatesToCellName(x1, y1+r)
-				// Convert []string to []interface{}
-				rowVals := make([]interface{}, cols)
 				for c := 0; c < cols; c++ {
-					if ctx.Err() != nil {
-						return ctx.Err()
+					cell, _ := excelize.CoordinatesToCellName(x1+c, y1+r)
+					if err := f.SetCellStr(sheet, cell, in.Values[r][c]); err != nil {
+						return err
 					}
-					rowVals[c] = in.Values[r][c]
-				}
-				if err := sw.SetRow(startCell, rowVals); err != nil {
-					return err
 				}
 			}
-			if err := sw.Flush(); err != nil {
+			if err := reg.auditWrite(ctx, audit.Record{Tool: "write_range", Path: canonical, Sheet: sheet, Range: rng, Cells: cells, ContentHash: audit.HashValues(in.Values)}); err != nil {
 				return err
 			}
 			// Persist changes to disk
-			if err := f.Save(); err != nil {
+			var err error
+			if saved, err = save(canonical, in.Backup); err != nil {
 				return err
 			}
 			updated = cells
 			return nil
+		}
+		err := mgr.WithSheetWrite(id, sheet, func(f *excelize.File, save workbooks.SaveFunc) error {
+			return writeCells(f, save, false)
 		})
+		if errors.Is(err, errNeedsWorkbookLock) {
+			err = mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+				return writeCells(f, save, true)
+			})
+		}
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
-			}
-			lower := strings.ToLower(err.Error())
-			if strings.Contains(lower, "invalid range") || strings.Contains(lower, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
-			}
-			if strings.Contains(lower, "payload exceeds") {
-				return mcperr.FromText("PAYLOAD_TOO_LARGE: reduce range size or split into batches"), nil
-			}
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
+			err = discardUnaudited(mgr, id, err)
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			return mcperr.FromText(fmt.Sprintf("WRITE_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.WriteFailed, "%v", err), nil
 		}
 
-		out := WriteRangeOutput{Path: canonical, Sheet: sheet, RangeA1: rng, CellsUpdated: updated, Idempotent: false}
-		summary := fmt.Sprintf("updated=%d nonIdempotent=true", updated)
+		out := WriteRangeOutput{Path: canonical, Sheet: sheet, RangeA1: rng, CellsUpdated: updated, Idempotent: false, Save: saveMode(saved.Deferred), Backup: saved.Backup}
+		summary := fmt.Sprintf("updated=%d nonIdempotent=true", updated) + saveSummary(out.Save, out.Backup)
 		return mcp.NewToolResultStructured(out, summary), nil
 	}))
 	reg.Register(writeRange)
 
 	// apply_formula
 	type ApplyFormulaInput struct {
-		Path    string `json:"path" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
-		Sheet   string `json:"sheet" jsonschema_description:"Target sheet name"`
-		RangeA1 string `json:"range" jsonschema_description:"Target A1 range to apply the formula"`
-		Formula string `json:"formula" jsonschema_description:"Formula string (e.g., =SUM(A1:B1))"`
+		Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+		Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+		Sheet    string `json:"sheet" validate:"required" jsonschema_description:"Target sheet name"`
+		RangeA1  string `json:"range" validate:"required,a1orname" jsonschema_description:"Target A1 range to apply the formula"`
+		Formula  string `json:"formula" validate:"required" jsonschema_description:"Formula string (e.g., =SUM(A1:B1))"`
+		// Autofill defaults to true; a pointer distinguishes omission from false.
+		Autofill *bool `json:"autofill,omitempty" jsonschema_description:"Shift relative references per target cell like Excel fill (default true); false writes the identical formula to every cell"`
+		Force    bool  `json:"force,omitempty" jsonschema_description:"Write even when the sheet is protected"`
+		Backup   bool  `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
 	}
 	type ApplyFormulaOutput struct {
 		Path       string `json:"path"`
@@ -1334,29 +1659,42 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		RangeA1    string `json:"range"`
 		CellsSet   int    `json:"cellsSet"`
 		Idempotent bool   `json:"idempotent"`
+		Save       string `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+		Backup     string `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
 	}
 
 	applyFormula := mcp.NewTool(
 		"apply_formula",
-		mcp.WithDescription("Apply a formula to each cell in the given range"),
+		mcp.WithDescription("Apply a formula to each cell in the given range. The formula is written as entered in the range's top‑left cell; with autofill=true (default) relative references are shifted for every other cell the way Excel's fill handle does ($‑anchored columns/rows stay fixed, references to other sheets and text inside string literals are left unchanged, and references pushed off the grid become #REF!). Set autofill=false to write the identical formula everywhere. Cached values are not recalculated; call recalculate_workbook afterwards to refresh them. Protected sheets are refused with PERMISSION_DENIED unless force=true."),
 		mcp.WithInputSchema[ApplyFormulaInput](),
 		mcp.WithOutputSchema[ApplyFormulaOutput](),
+		writeTool(true, false),
 	)
 	s.AddTool(applyFormula, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ApplyFormulaInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
 		p := strings.TrimSpace(in.Path)
 		sheet := strings.TrimSpace(in.Sheet)
 		rng := strings.TrimSpace(in.RangeA1)
 		formula := strings.TrimSpace(in.Formula)
-		if p == "" || sheet == "" || rng == "" || formula == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, range, and formula are required"), nil
+		if formula == "" {
+			return mcperr.New(mcperr.Validation, "formula must not be blank"), nil
 		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
+			return openFailed(openErr), nil
 		}
 
 		var cellsSet int
-		err := mgr.WithWrite(id, func(f *excelize.File) error {
+		var saved workbooks.SaveResult
+		// Setting a non-empty formula only touches the sheet; excelize edits
+		// the workbook-wide calc chain when a formula is cleared, which the
+		// required-formula check above rules out.
+		err := mgr.WithSheetWrite(id, sheet, func(f *excelize.File, save workbooks.SaveFunc) error {
+			if err := checkSheetProtection(f, sheet, in.Force); err != nil {
+				return err
+			}
 			x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
 			if perr != nil {
 				return perr
@@ -1366,9 +1704,11 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			cols := x2 - x1 + 1
 			cells := rows * cols
 			if cells > limits.MaxCellsPerOp {
-				return fmt.Errorf("payload exceeds max cells per operation: %d > %d", cells, limits.MaxCellsPerOp)
+				return mcperr.Errorf(mcperr.PayloadTooLarge, "range has %d cells, max %d per operation; reduce range size or split into batches", cells, limits.MaxCellsPerOp)
 			}
-			// Apply formula per cell; Excel interprets relative references appropriately
+			autofill := in.Autofill == nil || *in.Autofill
+			// Apply formula per cell, translating relative references from the
+			// top-left anchor when autofill is enabled.
 			for r := y1; r <= y2; r++ {
 				if ctx.Err() != nil {
 					return ctx.Err()
@@ -1378,39 +1718,33 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 						return ctx.Err()
 					}
 					cell, _ := excelize.CoordinatesToCellName(c, r)
-					if err := f.SetCellFormula(sheet, cell, formula); err != nil {
+					cellFormula := formula
+					if autofill {
+						cellFormula = translateFormula(formula, sheet, c-x1, r-y1)
+					}
+					if err := f.SetCellFormula(sheet, cell, cellFormula); err != nil {
 						return err
 					}
 					cellsSet++
 				}
 			}
-			if err := f.Save(); err != nil {
+			if err := reg.auditWrite(ctx, audit.Record{Tool: "apply_formula", Path: canonical, Sheet: sheet, Range: rng, Cells: cellsSet, ContentHash: audit.HashValues([][]string{{formula}})}); err != nil {
 				return err
 			}
-			return nil
+			var err error
+			saved, err = save(canonical, in.Backup)
+			return err
 		})
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
-			}
-			lower := strings.ToLower(err.Error())
-			if strings.Contains(lower, "invalid range") || strings.Contains(lower, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
-			}
-			if strings.Contains(lower, "exceeds max cells") {
-				return mcperr.FromText("PAYLOAD_TOO_LARGE: reduce range size or split into batches"), nil
+			err = discardUnaudited(mgr, id, err)
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if mcperr.IsInvalidSheet(err) {
-				return mcperr.FromText("INVALID_SHEET: sheet not found"), nil
-			}
-			return mcperr.FromText(fmt.Sprintf("APPLY_FORMULA_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.ApplyFormulaFailed, "%v", err), nil
 		}
 
-		out := ApplyFormulaOutput{Path: canonical, Sheet: sheet, RangeA1: rng, CellsSet: cellsSet, Idempotent: false}
-		summary := fmt.Sprintf("formulas_applied=%d nonIdempotent=true", cellsSet)
+		out := ApplyFormulaOutput{Path: canonical, Sheet: sheet, RangeA1: rng, CellsSet: cellsSet, Idempotent: false, Save: saveMode(saved.Deferred), Backup: saved.Backup}
+		summary := fmt.Sprintf("formulas_applied=%d nonIdempotent=true", cellsSet) + saveSummary(out.Save, out.Backup)
 		return mcp.NewToolResultStructured(out, summary), nil
 	}))
 	reg.Register(applyFormula)
@@ -1421,20 +1755,30 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 	// compute_statistics
 	type ComputeStatisticsInput struct {
 		Path          string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Absolute or allowed path to an Excel workbook"`
+		Password      string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
 		Sheet         string `json:"sheet" validate:"required" jsonschema_description:"Sheet name"`
 		RangeA1       string `json:"range" validate:"required,a1orname" jsonschema_description:"A1-style range or defined name to analyze"`
 		ColumnIndices []int  `json:"columns,omitempty" validate:"dive,min=1" jsonschema_description:"1-based column indexes within the range; omitted means all"`
 		GroupByIndex  int    `json:"group_by_index,omitempty" validate:"omitempty,min=1" jsonschema_description:"Optional 1-based column index within the range to group by"`
 		MaxCells      int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells to process (bounded)"`
+		// TreatBlankAsZero counts blank cells as numeric zeros in count, sum,
+		// average, min, and max.
+		TreatBlankAsZero bool `json:"treat_blank_as_zero,omitempty" jsonschema_description:"Count blank cells as 0 in count/sum/average/min/max instead of skipping them"`
 	}
 
 	type ColumnStats struct {
+		// Count is the number of numeric observations the reducers used.
 		Count         int     `json:"count"`
+		Blank         int     `json:"blank" jsonschema_description:"Empty cells (counted as zeros when treat_blank_as_zero is set)"`
+		NonNumeric    int     `json:"nonNumeric" jsonschema_description:"Non-empty cells that are not numbers, including dates; excluded from sum and average"`
 		DistinctCount int     `json:"distinct"`
 		Sum           float64 `json:"sum"`
 		Average       float64 `json:"average"`
 		Min           float64 `json:"min"`
 		Max           float64 `json:"max"`
+		// Dates summarizes values recognized as dates, which the numeric
+		// reducers above skip.
+		Dates *DateStats `json:"dates,omitempty"`
 	}
 
 	type ComputeStatisticsOutput struct {
@@ -1453,26 +1797,31 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 
 	computeStats := mcp.NewTool(
 		"compute_statistics",
-		mcp.WithDescription("Compute per-column summary statistics with optional group-by using streaming analysis"),
+		mcp.WithDescription(fmt.Sprintf("Compute per-column summary statistics with optional group-by using streaming analysis. Numeric values feed count/sum/average/min/max; dates (date-formatted serials or text such as 2024-03-15 or 3/15/2024) are summarized separately under dates: earliest, latest, spanDays, and counts per month, or per year when there are more than %d months. Each column reports blank and nonNumeric counts; blanks are skipped unless treat_blank_as_zero is set. The summary lists lowNumericCoverage columns where under half the cells are numeric.", maxDatePeriods)),
 		mcp.WithInputSchema[ComputeStatisticsInput](),
 		mcp.WithOutputSchema[ComputeStatisticsOutput](),
+		readOnlyTool(true),
 	)
 
-	// Helper to parse string to float64; returns value and ok flag
-	parseNumber := func(s string) (float64, bool) {
-		if s == "" {
-			return 0, false
-		}
-		// Try plain float
-		if v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64); err == nil {
-			return v, true
+	// Reducer update for a single observation
+	observeNumber := func(st *ColumnStats, f float64) {
+		st.Count++
+		st.Sum += f
+		if st.Count == 1 {
+			st.Min = f
+			st.Max = f
+		} else {
+			st.Min = math.Min(st.Min, f)
+			st.Max = math.Max(st.Max, f)
 		}
-		return 0, false
+		st.Average = st.Sum / float64(st.Count)
 	}
-
-	// Reducer update for a single observation
-	updateStats := func(st *ColumnStats, val string, distinct map[string]struct{}) {
+	updateStats := func(st *ColumnStats, val string, distinct map[string]struct{}, cell string, dates *statDateParser, blankAsZero bool) {
 		if val == "" {
+			st.Blank++
+			if blankAsZero {
+				observeNumber(st, 0)
+			}
 			return
 		}
 		// Distinct tracking by raw string
@@ -1481,16 +1830,22 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			st.DistinctCount = len(distinct)
 		}
 		if f, ok := parseNumber(val); ok {
-			st.Count++
-			st.Sum += f
-			if st.Count == 1 {
-				st.Min = f
-				st.Max = f
-			} else {
-				st.Min = math.Min(st.Min, f)
-				st.Max = math.Max(st.Max, f)
+			observeNumber(st, f)
+			return
+		}
+		st.NonNumeric++
+		if t, ok := dates.parse(cell, val); ok {
+			if st.Dates == nil {
+				st.Dates = &DateStats{}
+			}
+			st.Dates.observe(t)
+		}
+	}
+	finishDates := func(cols []ColumnStats) {
+		for i := range cols {
+			if cols[i].Dates != nil {
+				cols[i].Dates.finish()
 			}
-			st.Average = st.Sum / float64(st.Count)
 		}
 	}
 
@@ -1502,11 +1857,11 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		sheet := strings.TrimSpace(in.Sheet)
 		rng := strings.TrimSpace(in.RangeA1)
 		if p == "" || sheet == "" || rng == "" {
-			return mcperr.FromText("VALIDATION: path, sheet, and range are required"), nil
+			return mcperr.New(mcperr.Validation, "path, sheet, and range are required"), nil
 		}
-		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, p)
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
 		if openErr != nil {
-			return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", openErr)), nil
+			return openFailed(openErr), nil
 		}
 		maxCells := in.MaxCells
 		if maxCells <= 0 || maxCells > limits.MaxCellsPerOp {
@@ -1514,12 +1869,13 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		}
 
 		var out ComputeStatisticsOutput
+		var indices []int
 		out.Path = canonical
 		out.Sheet = sheet
 		out.RangeA1 = rng
 		out.Meta.MaxCells = maxCells
 
-		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
 			// Resolve range coordinates and normalized textual range
 			x1, y1, x2, y2, normalizedRange, perr := resolveRange(f, sheet, rng)
 			if perr != nil {
@@ -1530,7 +1886,7 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 
 			// Determine which columns to include (1-based within range)
 			colCount := x2 - x1 + 1
-			indices := in.ColumnIndices
+			indices = in.ColumnIndices
 			if len(indices) == 0 {
 				indices = make([]int, colCount)
 				for i := 0; i < colCount; i++ {
@@ -1556,6 +1912,7 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 				return rerr
 			}
 			defer rowsIter.Close()
+			dates := newStatDateParser(f, sheet)
 
 			processed := 0
 			rowIdx := 0
@@ -1609,7 +1966,7 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 					// Initialize group reducers lazily
 					if _, ok := groupStats[gkey]; !ok {
 						if len(groupStats) >= maxGroups {
-							return fmt.Errorf("too many groups: %d (max %d); narrow group_by or range", len(groupStats)+1, maxGroups)
+							return mcperr.Errorf(mcperr.LimitExceeded, "too many groups: %d (max %d); narrow group_by or reduce range", len(groupStats)+1, maxGroups)
 						}
 						groupStats[gkey] = make([]ColumnStats, len(indices))
 						set := make([]map[string]struct{}, len(indices))
@@ -1630,13 +1987,14 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 					if absCol >= 0 && absCol < len(rowVals) {
 						cell = rowVals[absCol]
 					}
+					name, _ := excelize.CoordinatesToCellName(absCol+1, rowIdx)
 					if groupBy > 0 {
 						arr := groupStats[gkey]
 						sets := groupDistinctSets[gkey]
-						updateStats(&arr[i], cell, sets[i])
+						updateStats(&arr[i], cell, sets[i], name, dates, in.TreatBlankAsZero)
 						groupStats[gkey] = arr
 					} else {
-						updateStats(&out.Columns[i], cell, distinctSets[i])
+						updateStats(&out.Columns[i], cell, distinctSets[i], name, dates, in.TreatBlankAsZero)
 					}
 				}
 
@@ -1648,28 +2006,23 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 			}
 
 			out.Meta.ProcessedCells = processed
+			finishDates(out.Columns)
+			for _, cols := range groupStats {
+				finishDates(cols)
+			}
 			if groupBy > 0 {
 				out.Groups = groupStats
 			}
 			return nil
 		})
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit"), nil
-			}
-			if errors.Is(err, workbooks.ErrHandleNotFound) {
-				return mcperr.FromText("INVALID_HANDLE: workbook handle not found or expired"), nil
-			}
-			lower := strings.ToLower(err.Error())
-			if strings.Contains(lower, "invalid range") || strings.Contains(lower, "coordinates") {
-				return mcperr.FromText("VALIDATION: invalid range; use A1:D50 or a defined name"), nil
+			if res := classifyError(err); res != nil {
+				return res, nil
 			}
-			if strings.Contains(lower, "too many groups") {
-				return mcperr.FromText("LIMIT_EXCEEDED: too many groups for available budget; narrow group_by or reduce range"), nil
-			}
-			return mcperr.FromText(fmt.Sprintf("STATISTICS_FAILED: %v", err)), nil
+			return mcperr.Wrapf(mcperr.StatisticsFailed, "%v", err), nil
 		}
 
+		runtime.CallStatsFrom(ctx).Record(out.Meta.ProcessedCells, len(out.Columns)+len(out.Groups), out.Meta.Truncated)
 		// Build concise summary string
 		var summary string
 		if len(out.Groups) > 0 {
@@ -1684,9 +2037,458 @@ func RegisterFoundationTools(s *server.MCPServer, reg *Registry, limits runtime.
 		} else {
 			summary = fmt.Sprintf("stats: cols=%d processed=%d truncated=%v", len(out.Columns), out.Meta.ProcessedCells, out.Meta.Truncated)
 		}
+		// Flag columns where under half the cells fed the numeric reducers,
+		// pooling groups, so their averages are not over-trusted.
+		numeric := make([]int, len(indices))
+		seen := make([]int, len(indices))
+		tally := func(cols []ColumnStats) {
+			for i, c := range cols {
+				numeric[i] += c.Count
+				seen[i] += c.Count + c.NonNumeric
+				if !in.TreatBlankAsZero {
+					seen[i] += c.Blank
+				}
+			}
+		}
+		tally(out.Columns)
+		for _, cols := range out.Groups {
+			tally(cols)
+		}
+		var low []int
+		for i := range indices {
+			if seen[i] > 0 && 2*numeric[i] < seen[i] {
+				low = append(low, indices[i])
+			}
+		}
+		if len(low) > 0 {
+			summary += fmt.Sprintf(" lowNumericCoverage=%v", low)
+		}
 		return mcp.NewToolResultStructured(out, summary), nil
 	}))
 	reg.Register(computeStats)
+
+	// get_limits
+	getLimits := mcp.NewTool(
+		"get_limits",
+		mcp.WithDescription("Report the server's effective guardrails: concurrency caps, max cells per operation, preview row limit, payload bytes per page, rows per edit, export cells, crosstab matrix cells, max file size, timeouts, whether write tools are enabled, and the allow‑listed directories (paths only). Read‑only and cheap; call it before planning large reads to choose page sizes and ranges that will not be truncated."),
+		mcp.WithInputSchema[GetLimitsInput](),
+		mcp.WithOutputSchema[GetLimitsOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(getLimits, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in GetLimitsInput) (*mcp.CallToolResult, error) {
+		reg.mu.RLock()
+		filter, allow := reg.writeFilter, reg.allowList
+		reg.mu.RUnlock()
+		out := GetLimitsOutput{
+			MaxConcurrentRequests:   limits.MaxConcurrentRequests,
+			MaxOpenWorkbooks:        limits.MaxOpenWorkbooks,
+			MaxPayloadBytes:         limits.MaxPayloadBytes,
+			MaxCellsPerOp:           limits.MaxCellsPerOp,
+			PreviewRowLimit:         limits.PreviewRowLimit,
+			MaxRowsPerEdit:          limits.MaxRowsPerEdit,
+			MaxExportCells:          limits.MaxExportCells,
+			MaxCrosstabCells:        limits.MaxCrosstabCells,
+			MaxTextBytes:            limits.TextBudgetBytes(),
+			MaxFileBytes:            limits.MaxFileBytes,
+			OperationTimeoutMs:      limits.OperationTimeout.Milliseconds(),
+			AcquireRequestTimeoutMs: limits.AcquireRequestTimeout.Milliseconds(),
+			CursorTTLMs:             limits.CursorTTL.Milliseconds(),
+			// Without a filter nothing is hidden.
+			WritesEnabled:       filter == nil || filter.WritesEnabled(),
+			AllowedDirectories:  []string{},
+			WritableDirectories: []string{},
+			AllowedPatterns:     []string{},
+			WritablePatterns:    []string{},
+		}
+		if allow != nil {
+			out.AllowedDirectories = append(out.AllowedDirectories, allow.AllowedDirectories()...)
+			out.WritableDirectories = append(out.WritableDirectories, allow.WritableDirectories()...)
+			out.AllowedPatterns = append(out.AllowedPatterns, allow.AllowedPatterns()...)
+			out.WritablePatterns = append(out.WritablePatterns, allow.WritablePatterns()...)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "maxCellsPerOp=%d previewRowLimit=%d maxPayloadBytes=%d maxRowsPerEdit=%d maxExportCells=%d maxCrosstabCells=%d maxTextBytes=%d maxFileBytes=%d", out.MaxCellsPerOp, out.PreviewRowLimit, out.MaxPayloadBytes, out.MaxRowsPerEdit, out.MaxExportCells, out.MaxCrosstabCells, out.MaxTextBytes, out.MaxFileBytes)
+		fmt.Fprintf(&b, "\ntimeoutMs=%d acquireTimeoutMs=%d cursorTtlMs=%d maxConcurrentRequests=%d maxOpenWorkbooks=%d writesEnabled=%t", out.OperationTimeoutMs, out.AcquireRequestTimeoutMs, out.CursorTTLMs, out.MaxConcurrentRequests, out.MaxOpenWorkbooks, out.WritesEnabled)
+		fmt.Fprintf(&b, "\nallowedDirs=%v writableDirs=%v", out.AllowedDirectories, out.WritableDirectories)
+		if len(out.AllowedPatterns) > 0 {
+			fmt.Fprintf(&b, "\nallowedPatterns=%v writablePatterns=%v", out.AllowedPatterns, out.WritablePatterns)
+		}
+		return mcp.NewToolResultStructured(out, b.String()), nil
+	}))
+	reg.Register(getLimits)
+}
+
+// runReadRange serves read_range and tools that resolve their target to a
+// range first (read_table).
+func runReadRange(ctx context.Context, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager, in ReadRangeInput) (*mcp.CallToolResult, error) {
+	p := strings.TrimSpace(in.Path)
+	sheet := strings.TrimSpace(in.Sheet)
+	rng := strings.TrimSpace(in.RangeA1)
+	curTok := strings.TrimSpace(in.Cursor)
+	expandMerged := in.ExpandMerged
+	detailMode := in.CellDetail
+	includeHeader := in.IncludeHeader
+	enc := strings.ToLower(strings.TrimSpace(in.Encoding))
+	if enc == "" {
+		enc = "json"
+	}
+	cellWidth := markdownCellWidth(in.CellWidth)
+	valueMode, ok := parseValueMode(in.ValueMode)
+	if !ok {
+		return mcperr.New(mcperr.Validation, "value_mode must be 'formatted', 'raw', or 'typed'"), nil
+	}
+	id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, p, workbooks.OpenOptions{Password: in.Password})
+	if openErr != nil {
+		return openFailed(openErr), nil
+	}
+	maxCells := pagination.PageSize(in.MaxCells, 0, limits.MaxCellsPerOp, limits.MaxCellsPerOp)
+	// resumedSize marks a page size taken from the cursor, which already
+	// carries the encoding reductions below.
+	resumedSize := false
+	// Cursor precedence: when provided, override sheet/range/maxCells from token
+	var startOffset int
+	var parsedCur *pagination.Cursor
+	if curTok != "" {
+		pc, cres := decodeCursor(curTok, limits.CursorTTL)
+		if cres != nil {
+			return cres, nil
+		}
+		if pc.Pt != canonical {
+			return mcperr.New(mcperr.CursorInvalid, "cursor path does not match provided path"), nil
+		}
+		if pc.U != pagination.UnitCells {
+			return mcperr.New(mcperr.CursorInvalid, "unit mismatch; read_range expects cells"), nil
+		}
+		// Override inputs using cursor values
+		sheet = pc.S
+		rng = pc.R
+		in.Ranges = pc.Rs
+		startOffset = pc.Off
+		if in.MaxCells < 1 || in.MaxCells > limits.MaxCellsPerOp {
+			maxCells = pagination.PageSize(0, pc.Ps, maxCells, limits.MaxCellsPerOp)
+			resumedSize = maxCells == pc.Ps
+		}
+		expandMerged = pc.Em
+		detailMode = pc.Cd
+		if pc.Enc != "" {
+			enc = pc.Enc
+		}
+		if pc.Cw > 0 {
+			cellWidth = pc.Cw
+		}
+		in.HeaderRow = pc.Hr
+		includeHeader = pc.Ih
+		valueMode, _ = parseValueMode(pc.Vm)
+		parsedCur = pc
+	} else {
+		if len(in.Ranges) > 0 && rng != "" {
+			return mcperr.New(mcperr.Validation, "use range or ranges, not both"), nil
+		}
+		if len(in.Ranges) > 0 && enc != "json" {
+			return mcperr.New(mcperr.Validation, "ranges requires encoding 'json'"), nil
+		}
+		if sheet == "" || (rng == "" && len(in.Ranges) == 0) {
+			return mcperr.New(mcperr.Validation, "sheet and range are required (or supply cursor)"), nil
+		}
+		if detailMode && enc != "json" {
+			return mcperr.New(mcperr.Validation, "cell_detail requires encoding 'json'"), nil
+		}
+		if detailMode && valueMode == valueModeTyped {
+			return mcperr.New(mcperr.Validation, "cell_detail already reports types; use value_mode 'formatted' or 'raw'"), nil
+		}
+		if includeHeader && (enc == "records" || detailMode || len(in.Ranges) > 0) {
+			return mcperr.New(mcperr.Validation, "include_header applies to a single range with encoding 'json', 'csv', or 'markdown' (records already carry keys; cell_detail is not supported)"), nil
+		}
+		if in.HeaderRow > 0 && enc != "records" && !includeHeader {
+			return mcperr.New(mcperr.Validation, "header_row requires encoding 'records' or include_header=true"), nil
+		}
+	}
+	if !resumedSize {
+		if enc == "records" {
+			maxCells = max(maxCells/recordsFactor, 1)
+		}
+		// Detail objects are roughly three times the size of bare values; a
+		// resumed page reuses the already-reduced size from the cursor.
+		if detailMode {
+			maxCells = maxCells / cellDetailFactor
+			if maxCells < 1 {
+				maxCells = 1
+			}
+		}
+	}
+
+	if len(in.Ranges) > 0 {
+		q := multiRangeRead{id: id, canonical: canonical, sheet: sheet, ranges: in.Ranges, startOffset: startOffset, maxCells: maxCells, expandMerged: expandMerged, detailMode: detailMode, valueMode: valueMode, cursor: parsedCur}
+		if parsedCur != nil {
+			if parsedCur.Ri < 0 || parsedCur.Ri >= len(parsedCur.Rs) {
+				return mcperr.New(mcperr.CursorInvalid, "cursor range index out of bounds"), nil
+			}
+			q.startRange = parsedCur.Ri
+		}
+		return reg.readMultiRange(ctx, mgr, limits.MaxPayloadBytes, limits.MaxCellsPerOp, q)
+	}
+
+	// Cells are collected per row (bounded by maxCells) and encoded once the page is known
+	var textOut string
+	var meta PageMeta
+	var outRange = rng
+	var mergedCells []string
+	var keys []string
+	var headerRow int
+	var header []string
+	var typedHeader []any
+
+	var fileMT int64
+	var fileFP string
+	var wbVersion int64
+	reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+	err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, version int64) error {
+		// Snapshot the file under the read lock for cursor emission and
+		// reject cursors issued against different contents
+		fileMT, fileFP = fileSnapshot(canonical)
+		wbVersion = version
+		if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+			return err
+		}
+		// Resolve named range if needed
+		var x1, y1, x2, y2 int
+		var parseErr error
+		x1, y1, x2, y2, outRange, parseErr = resolveRange(f, sheet, rng)
+		if parseErr != nil {
+			return parseErr
+		}
+
+		// Explicitly validate that the target sheet exists; otherwise GetCellValue calls
+		// on a non-existent sheet would quietly return empty values without an error.
+		if !sheetExists(f, sheet) {
+			return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+		}
+
+		if x2 < x1 || y2 < y1 {
+			return fmt.Errorf("%w bounds after parse", mcperr.ErrInvalidRange)
+		}
+
+		// Records take their keys from the header row; data starts below it
+		// and pages hold whole rows.
+		if enc == "records" {
+			headerRow = in.HeaderRow
+			if headerRow == 0 {
+				headerRow = y1
+			}
+			if headerRow > y2 {
+				return mcperr.Errorf(mcperr.Validation, "header_row %d is below the range %s", headerRow, outRange)
+			}
+			header := make([]string, 0, x2-x1+1)
+			for col := x1; col <= x2; col++ {
+				cellName, _ := excelize.CoordinatesToCellName(col, headerRow)
+				v, _ := f.GetCellValue(sheet, cellName)
+				header = append(header, v)
+			}
+			keys = recordKeys(header, x1)
+			if parsedCur != nil && parsedCur.Hh != "" && parsedCur.Hh != recordKeysHash(keys) {
+				return mcperr.Errorf(mcperr.CursorInvalid, "header row keys changed since the cursor was issued")
+			}
+			if headerRow >= y1 {
+				y1 = headerRow + 1
+			}
+			cols := x2 - x1 + 1
+			maxCells = max(maxCells-maxCells%cols, cols)
+		}
+		// A pinned header is read on every page and emitted above the data
+		// outside the cell budget; pages hold whole rows so the columns stay
+		// aligned under it.
+		if includeHeader {
+			headerRow = in.HeaderRow
+			if headerRow == 0 {
+				headerRow = y1
+			}
+			if headerRow > y2 {
+				return mcperr.Errorf(mcperr.Validation, "header_row %d is below the range %s", headerRow, outRange)
+			}
+			cols := x2 - x1 + 1
+			hw, herr := readCellWindow(ctx, f, sheet, x1, headerRow, x2, headerRow, 0, cols, nil, nil, newValueReader(f, sheet, valueMode))
+			if herr != nil {
+				return herr
+			}
+			header = make([]string, cols)
+			copy(header, hw.grid[0])
+			if hw.typed != nil {
+				typedHeader = hw.typed[0]
+			}
+			if headerRow >= y1 {
+				y1 = headerRow + 1
+			}
+			maxCells = max(maxCells-maxCells%cols, cols)
+		}
+
+		total := (x2 - x1 + 1) * (y2 - y1 + 1)
+		meta.Total = total
+		meta.CellDetail = detailMode
+		var details *cellDetailReader
+		if detailMode {
+			details = newCellDetailReader(f, sheet)
+		}
+
+		// Consult merged regions once per call; anchors are resolved against the
+		// whole sheet so a region spanning a page boundary fills identically on
+		// every page.
+		var merges []mergedRegion
+		if expandMerged {
+			var merr error
+			merges, merr = mergedRegionsInRange(f, sheet, x1, y1, x2, y2)
+			if merr != nil {
+				return merr
+			}
+		}
+
+		// Iterate row-major from startOffset, but stop when we reach maxCells.
+		values := newValueReader(f, sheet, valueMode)
+		win, werr := readCellWindow(ctx, f, sheet, x1, y1, x2, y2, startOffset, maxCells, merges, details, values)
+		if werr != nil {
+			return werr
+		}
+		grid := win.grid
+
+		// Whichever bound hits first ends the page: maxCells above, or the
+		// payload cap here. At least one cell is kept so the cursor advances.
+		budget := payloadBudget(limits.MaxPayloadBytes)
+		if header != nil && budget > 0 && enc != "markdown" {
+			budget -= pinnedHeaderSize(enc, header, typedHeader)
+		}
+		// wholeRows drops a partial trailing row under a pinned header unless
+		// nothing else fits.
+		wholeRows := func(full, partial int) int {
+			if header != nil && full > 0 {
+				return cellsBefore(grid, full)
+			}
+			return cellsBefore(grid, full) + partial
+		}
+		keep := win.cells
+		switch enc {
+		case "records":
+			var used int
+			textOut, used = renderRecords(keys, grid, win.typed, budget)
+			keep = cellsBefore(grid, used)
+		case "markdown":
+			var used int
+			if header != nil {
+				// The pinned header heads the table; only data rows count.
+				textOut, used = renderMarkdownTable(append([][]string{header}, grid...), cellWidth, budget)
+				used--
+			} else {
+				textOut, used = renderMarkdownTable(grid, cellWidth, budget)
+			}
+			if used < len(grid) {
+				// End the page after the last emitted row.
+				keep = 0
+				for _, r := range grid[:used] {
+					keep += len(r)
+				}
+			}
+		case "csv":
+			full, partial := fitGrid(len(grid), func(r int) int { return len(grid[r]) }, func(r, c int) int { return csvCellSize(grid[r][c]) }, false, budget)
+			keep = wholeRows(full, partial)
+		default:
+			size := func(r, c int) int { return jsonCellSize(grid[r][c]) }
+			switch {
+			case details != nil:
+				size = func(r, c int) int { return jsonCellSize(win.details[r][c]) }
+			case win.typed != nil:
+				size = func(r, c int) int { return jsonCellSize(win.typed[r][c]) }
+			}
+			full, partial := fitGrid(len(grid), func(r int) int { return len(grid[r]) }, size, true, budget)
+			keep = wholeRows(full, partial)
+		}
+		if keep < win.cells {
+			if keep < 1 {
+				keep = 1
+			}
+			meta.PayloadCapped = true
+			win.trim(keep)
+			grid = win.grid
+		}
+		mergedCells = win.merged
+		writtenCells := win.cells
+
+		switch enc {
+		case "markdown", "records":
+			// Rendered above.
+		case "csv":
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			if header != nil {
+				grid = append([][]string{header}, grid...)
+			}
+			if werr := w.WriteAll(grid); werr != nil {
+				return werr
+			}
+			textOut = buf.String()
+		default:
+			var rows any = grid
+			switch {
+			case details != nil:
+				rows = win.details
+			case win.typed != nil:
+				rows = win.typed
+				if header != nil {
+					rows = append([][]any{typedHeader}, win.typed...)
+				}
+			case header != nil:
+				rows = append([][]string{header}, grid...)
+			}
+			b, _ := json.Marshal(rows)
+			textOut = string(b)
+		}
+		meta.Returned = writtenCells
+		meta.Truncated = (startOffset + writtenCells) < total
+		runtime.CallStatsFrom(ctx).AddCells(writtenCells)
+		if meta.Truncated {
+			// Build opaque next cursor bound to the file snapshot
+			next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: outRange, U: pagination.UnitCells, Off: pagination.NextOffset(startOffset, writtenCells), Ps: maxCells, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: wbVersion, Em: expandMerged, Cd: detailMode, Enc: enc, Cw: cellWidthFor(enc, cellWidth), Vm: cursorValueMode(valueMode)}
+			if enc == "records" {
+				next.Hr, next.Hh = headerRow, recordKeysHash(keys)
+			}
+			if includeHeader {
+				next.Hr, next.Ih = headerRow, true
+			}
+			token, _ := pagination.EncodeCursor(next)
+			meta.NextCursor = token
+		}
+		return nil
+	})
+	if err != nil {
+		if res := classifyError(err); res != nil {
+			return res, nil
+		}
+		if res := cursorMismatch(err); res != nil {
+			return res, nil
+		}
+		return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+	}
+
+	runtime.CallStatsFrom(ctx).SetResult(meta.Returned, meta.Truncated)
+	out := ReadRangeOutput{Path: canonical, Sheet: sheet, RangeA1: outRange, Encoding: enc, MergedCells: mergedCells, Keys: keys, Header: header, Meta: meta}
+	// Text payload starts with a concise meta summary followed by data
+	summary := fmt.Sprintf("total=%d returned=%d truncated=%v", out.Meta.Total, out.Meta.Returned, out.Meta.Truncated)
+	if out.Meta.CellDetail {
+		summary += " cellDetail=true"
+	}
+	if valueMode != valueModeFormatted {
+		summary += " valueMode=" + valueMode
+	}
+	if header != nil {
+		summary += fmt.Sprintf(" headerRow=%d", headerRow)
+	}
+	if out.Meta.Truncated {
+		summary = summary + " nextCursor=" + out.Meta.NextCursor
+	} else {
+		summary = summary + " nextCursor="
+	}
+	text := summary + "\n" + textOut
+	recordPayload(&out.Meta, text, pagination.UnitCells, maxCells, limits.MaxPayloadBytes)
+	res := mcp.NewToolResultStructured(out, "range read complete")
+	res.Content = []mcp.Content{mcp.NewTextContent(text)}
+	return res, nil
 }
 
 // resolveRange parses an A1-style range or resolves a named range into coordinates.
@@ -1699,7 +2501,7 @@ func resolveRange(f *excelize.File, sheet, input string) (int, int, int, int, st
 		if len(parts) == 2 {
 			s := strings.Trim(parts[0], "'")
 			if s != "" && !strings.EqualFold(s, sheet) {
-				return 0, 0, 0, 0, "", fmt.Errorf("invalid range: sheet mismatch")
+				return 0, 0, 0, 0, "", fmt.Errorf("%w: sheet mismatch", mcperr.ErrInvalidRange)
 			}
 			in = parts[1]
 		}
@@ -1708,12 +2510,12 @@ func resolveRange(f *excelize.File, sheet, input string) (int, int, int, int, st
 	if strings.Contains(in, ":") {
 		parts := strings.Split(in, ":")
 		if len(parts) != 2 {
-			return 0, 0, 0, 0, "", fmt.Errorf("invalid range: %s", input)
+			return 0, 0, 0, 0, "", fmt.Errorf("%w: %s", mcperr.ErrInvalidRange, input)
 		}
 		x1, y1, err1 := excelize.CellNameToCoordinates(parts[0])
 		x2, y2, err2 := excelize.CellNameToCoordinates(parts[1])
 		if err1 != nil || err2 != nil {
-			return 0, 0, 0, 0, "", fmt.Errorf("invalid range coordinates")
+			return 0, 0, 0, 0, "", fmt.Errorf("%w coordinates", mcperr.ErrInvalidRange)
 		}
 		if x2 < x1 {
 			x1, x2 = x2, x1
@@ -1745,6 +2547,10 @@ func resolveRange(f *excelize.File, sheet, input string) (int, int, int, int, st
 					refers = parts[1]
 				}
 			}
+			// A single-cell name refers to a one-cell range.
+			if !strings.Contains(refers, ":") {
+				refers += ":" + refers
+			}
 			// Now parse the range part after optional sheet qualifier
 			if strings.Contains(refers, ":") {
 				p := strings.Split(refers, ":")
@@ -1774,6 +2580,187 @@ func resolveRange(f *excelize.File, sheet, input string) (int, int, int, int, st
 	return 0, 0, 0, 0, "", fmt.Errorf("invalid or unsupported range: %s", input)
 }
 
+// maxSummaryExamples bounds the example rows emitted in output=summary mode.
+const maxSummaryExamples = 5
+
+// buildPageText renders the text content for a result page. Full mode appends
+// the JSON results after the summary line; summary mode appends only the
+// compact examples. Token estimates for both forms are stored on meta.
+func buildPageText(summary string, results any, examples []string, mode string, meta *PageMeta) string {
+	full := summary
+	if b, err := json.Marshal(results); err == nil {
+		full = summary + "\n" + string(b)
+	}
+	compact := summary
+	if len(examples) > 0 {
+		compact = summary + "\n" + strings.Join(examples, "\n")
+	}
+	meta.EstTokensFull = estimateTokens(full)
+	meta.EstTokensSummary = estimateTokens(compact)
+	if strings.EqualFold(strings.TrimSpace(mode), "summary") {
+		return compact
+	}
+	return full
+}
+
+// pageCount returns how many pages of size pageSize cover total items.
+func pageCount(total, pageSize int) int {
+	if pageSize <= 0 {
+		return 0
+	}
+	return (total + pageSize - 1) / pageSize
+}
+
+// estimateTokens approximates LLM tokens using the common ~4 bytes/token heuristic.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// compactRow joins a row snapshot for one-line display, trimming trailing
+// empties and capping the rendered length.
+func compactRow(cells []string) string {
+	end := len(cells)
+	for end > 0 && strings.TrimSpace(cells[end-1]) == "" {
+		end--
+	}
+	return truncateText(strings.Join(cells[:end], " | "), 160)
+}
+
+// mergedRegion is a merged cell block with its anchor (top-left) value.
+type mergedRegion struct {
+	x1, y1, x2, y2 int
+	value          string
+}
+
+// mergedRegionsInRange returns the merged regions on sheet that intersect the
+// given bounds. Regions are kept whole (not clipped) so the anchor value is
+// available even when the anchor itself lies outside the requested range.
+func mergedRegionsInRange(f *excelize.File, sheet string, x1, y1, x2, y2 int) ([]mergedRegion, error) {
+	mcs, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]mergedRegion, 0, len(mcs))
+	for _, mc := range mcs {
+		ax, ay, e1 := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		bx, by, e2 := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if e1 != nil || e2 != nil {
+			continue
+		}
+		if bx < x1 || ax > x2 || by < y1 || ay > y2 {
+			continue
+		}
+		out = append(out, mergedRegion{x1: ax, y1: ay, x2: bx, y2: by, value: mc.GetCellValue()})
+	}
+	return out, nil
+}
+
+// findMergedRegion reports the merged region covering (col,row), if any.
+func findMergedRegion(regions []mergedRegion, col, row int) (mergedRegion, bool) {
+	for _, r := range regions {
+		if col >= r.x1 && col <= r.x2 && row >= r.y1 && row <= r.y2 {
+			return r, true
+		}
+	}
+	return mergedRegion{}, false
+}
+
+// openFailed maps a GetOrOpenByPath error to a tool error result: BUSY_RESOURCE
+// when every open-workbook slot is in active use, PASSWORD_* for encrypted
+// workbooks, OPEN_FAILED otherwise.
+// decodeCursor decodes a pagination token and enforces the cursor TTL,
+// returning the tool error result when the token is unusable.
+func decodeCursor(tok string, ttl time.Duration) (*pagination.Cursor, *mcp.CallToolResult) {
+	pc, err := pagination.DecodeCursorWithTTL(tok, ttl, time.Now())
+	if errors.Is(err, pagination.ErrCursorExpired) {
+		return nil, mcperr.New(mcperr.CursorExpired, fmt.Sprintf("cursor is older than %s; restart pagination without a cursor", ttl))
+	}
+	if err != nil {
+		return nil, mcperr.New(mcperr.CursorInvalid, "failed to decode cursor; reopen workbook and restart pagination")
+	}
+	return pc, nil
+}
+
+// fileSnapshot returns the mtime (unix seconds) and content fingerprint that
+// cursors bind to; either is zero when the file cannot be read.
+func fileSnapshot(path string) (int64, string) {
+	var mt int64
+	if fi, err := os.Stat(path); err == nil {
+		mt = fi.ModTime().Unix()
+	}
+	fp, _ := pagination.Fingerprint(path)
+	return mt, fp
+}
+
+func openFailed(err error) *mcp.CallToolResult {
+	if errors.Is(err, workbooks.ErrWorkbooksBusy) {
+		return mcperr.New(mcperr.BusyResource, "all open workbooks are in use; retry shortly")
+	}
+	if res := workbookAccessError(err); res != nil {
+		return res
+	}
+	return mcperr.Wrapf(mcperr.OpenFailed, "%v", err)
+}
+
+// workbookAccessError maps handle lifecycle errors shared by all tools
+// (missing, stale, password-protected, read-only, oversized, write-denied, or
+// unaudited workbooks) and returns nil for others.
+func workbookAccessError(err error) *mcp.CallToolResult {
+	var roErr *security.ReadOnlyRootError
+	switch {
+	case errors.Is(err, workbooks.ErrHandleNotFound):
+		return mcperr.New(mcperr.InvalidHandle, "workbook handle not found or expired")
+	case errors.Is(err, workbooks.ErrStaleWorkbook):
+		return mcperr.New(mcperr.StaleWorkbook, "workbook changed on disk since it was opened; retry")
+	case errors.Is(err, workbooks.ErrPasswordRequired):
+		return mcperr.New(mcperr.PasswordRequired, "workbook is password-protected; supply password")
+	case errors.Is(err, workbooks.ErrPasswordInvalid):
+		return mcperr.New(mcperr.PasswordInvalid, "workbook password is not correct")
+	case errors.Is(err, workbooks.ErrReadOnlyWorkbook):
+		return mcperr.New(mcperr.UnsupportedFormat, "CSV files are read-only; write to an .xlsx copy instead")
+	case errors.Is(err, workbooks.ErrFileTooLarge), errors.Is(err, security.ErrFileTooLarge):
+		return mcperr.Wrapf(mcperr.FileTooLarge, "%v", err)
+	case errors.As(err, &roErr):
+		return mcperr.Wrapf(mcperr.PermissionDenied, "allow-list root %s is read-only; write to a MCPXCEL_ALLOWED_DIRS_RW directory", roErr.Root)
+	case errors.Is(err, workbooks.ErrWriteNotAllowed):
+		return mcperr.New(mcperr.PermissionDenied, "workbook path is not writable")
+	case errors.Is(err, audit.ErrAuditFailed):
+		return mcperr.New(mcperr.AuditFailed, "audit log unavailable; the write was not applied")
+	case errors.Is(err, workbooks.ErrFlushFailed):
+		return mcperr.Wrapf(mcperr.WriteFailed, "%v; the changes remain pending", err)
+	}
+	return nil
+}
+
+// classifyError maps failures mcperr.Classify recognizes (timeouts, missing
+// sheets, bad ranges, coded errors, and the workbook access errors above) to
+// error results. It returns nil for anything else so callers can apply their
+// operation's fallback code.
+func classifyError(err error) *mcp.CallToolResult {
+	if res := workbookAccessError(err); res != nil {
+		return res
+	}
+	var coded *mcperr.Error
+	if errors.As(err, &coded) {
+		return mcperr.New(coded.Code, coded.Message)
+	}
+	switch code := mcperr.Classify(err); code {
+	case "":
+		return nil
+	case mcperr.Timeout:
+		return mcperr.New(mcperr.Timeout, "operation exceeded configured time limit")
+	case mcperr.InvalidSheet:
+		return mcperr.New(mcperr.InvalidSheet, "sheet not found")
+	case mcperr.Validation:
+		if mcperr.IsInvalidRange(err) {
+			return mcperr.New(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+		}
+		return mcperr.Wrapf(mcperr.Validation, "%v", err)
+	default:
+		return mcperr.Wrapf(code, "%v", err)
+	}
+}
+
 // errorsIsHandleNotFound reports whether the error is from the workbooks package
 // indicating a missing handle. We compare by string to avoid importing internal error vars.
 // Removed helper in favor of errors.Is with workbooks.ErrHandleNotFound
@@ -1781,9 +2768,11 @@ func resolveRange(f *excelize.File, sheet, input string) (int, int, int, int, st
 // legacy cursor emission has been removed. Only opaque cursors are supported.
 
 // computeQueryHash returns a short, deterministic hex hash that binds search parameters
-// (query string, regex flag, and restricted columns). This is embedded in pagination
-// cursors (qh) so resuming pages can be validated against the same parameters.
-func computeQueryHash(query string, regex bool, columns []int) string {
+// (query string, regex flag, restricted columns, scope, and range query). This is embedded
+// in pagination cursors (qh) so resuming pages can be validated against the same parameters.
+// The default values scope and an absent range query hash as before either existed, so
+// older cursors stay valid.
+func computeQueryHash(query string, regex bool, columns []int, scope, rangeQuery string) string {
 	// Normalize inputs
 	q := strings.TrimSpace(query)
 	// Copy and sort columns for stable representation
@@ -1809,10 +2798,91 @@ func computeQueryHash(query string, regex bool, columns []int) string {
 		}
 		b.WriteString(strconv.Itoa(c))
 	}
+	if scope != "" && scope != searchScopeValues {
+		b.WriteString("|")
+		b.WriteString(scope)
+	}
+	if rangeQuery != "" {
+		b.WriteString("|range=")
+		b.WriteString(rangeQuery)
+	}
 	sum := sha1.Sum([]byte(b.String()))
 	return hex.EncodeToString(sum[:])
 }
 
+// search_data scopes: cell values, formula text, or comment text.
+const (
+	searchScopeValues   = "values"
+	searchScopeFormulas = "formulas"
+	searchScopeComments = "comments"
+)
+
+// searchSheetText finds the cells of sheet whose formula or comment text
+// (per scope) matches query, in row-major order, and returns each match's
+// text. A literal query matches case-insensitively anywhere in the text.
+// Formulas are read cell by cell over the used range, which also counts
+// toward the call's processed cells.
+func searchSheetText(ctx context.Context, f *excelize.File, sheet, query string, regex bool, scope string) ([]string, map[string]string, error) {
+	match := func(s string) bool { return strings.Contains(strings.ToLower(s), strings.ToLower(query)) }
+	if regex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, nil, mcperr.Errorf(mcperr.Validation, "invalid regex: %v", err)
+		}
+		match = re.MatchString
+	}
+	var cells []string
+	texts := make(map[string]string)
+	if scope == searchScopeComments {
+		comments, err := sheetComments(f, sheet)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range comments {
+			if match(c.Text) {
+				cells = append(cells, c.Cell)
+				texts[c.Cell] = c.Text
+			}
+		}
+		return cells, texts, nil
+	}
+	x2, y2 := formulaExtent(f, sheet)
+	for row := 1; row <= y2; row++ {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		for col := 1; col <= x2; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			formula, err := f.GetCellFormula(sheet, cell)
+			if err != nil {
+				return nil, nil, err
+			}
+			if formula != "" && match(formula) {
+				cells = append(cells, cell)
+				texts[cell] = "=" + formula
+			}
+		}
+	}
+	runtime.CallStatsFrom(ctx).AddCells(x2 * y2)
+	return cells, texts, nil
+}
+
+// formulaExtent returns the last column and row that may hold a formula: the
+// larger of the recorded sheet dimension and the scanned used range, since a
+// formula without a cached value reads as an empty cell.
+func formulaExtent(f *excelize.File, sheet string) (int, int) {
+	var x2, y2 int
+	if dim, err := f.GetSheetDimension(sheet); err == nil && dim != "" {
+		parts := strings.Split(dim, ":")
+		x2, y2, _ = excelize.CellNameToCoordinates(parts[len(parts)-1])
+	}
+	if used, _ := scanUsedRange(f, sheet); used != "" {
+		ux, uy, _ := excelize.CellNameToCoordinates(strings.TrimPrefix(used, "A1:"))
+		x2, y2 = max(x2, ux), max(y2, uy)
+	}
+	return x2, y2
+}
+
 // computePredicateHash returns a deterministic hash binding predicate expression and column scope.
 func computePredicateHash(predicate string, columns []int) string {
 	// Normalize predicate by trimming redundant whitespace sequences to a single space
@@ -1840,385 +2910,203 @@ func computePredicateHash(predicate string, columns []int) string {
 	return hex.EncodeToString(sum[:])
 }
 
-// Predicate parsing and evaluation
-// Grammar (subset):
-//   expr := orExpr
-//   orExpr := andExpr { OR andExpr }
-//   andExpr := unaryExpr { AND unaryExpr }
-//   unaryExpr := [NOT] primary
-//   primary := comparison | '(' expr ')'
-//   comparison := value ( = | != | > | < | >= | <= | CONTAINS ) value
-//   value := $N | number | string
-// Columns referenced with $N are 1-based absolute column indices.
-
-type tokenKind int
-
-const (
-	tkEOF tokenKind = iota
-	tkLParen
-	tkRParen
-	tkAnd
-	tkOr
-	tkNot
-	tkOp // comparison op or 'contains'
-	tkCol
-	tkString
-	tkNumber
-)
-
-type token struct {
-	kind tokenKind
-	val  string
-}
-
-// compilePredicate compiles a predicate string into an evaluator function.
-func compilePredicate(src string) (func([]string) bool, error) {
-	toks, err := tokenizePredicate(src)
-	if err != nil {
-		return nil, err
+// collectStructure fills out's sheets and defined names from f. With
+// accurateCounts each sheet is also streamed, up to maxScanCells cells.
+func collectStructure(ctx context.Context, f *excelize.File, out *ListStructureOutput, metadataOnly, accurateCounts bool, maxScanCells int) error {
+	// Gather sheet names in index order
+	sheetMap := f.GetSheetMap()
+	idx := make([]int, 0, len(sheetMap))
+	for i := range sheetMap {
+		idx = append(idx, i)
 	}
-	rpn, err := toRPN(toks)
-	if err != nil {
-		return nil, err
-	}
-	return func(row []string) bool {
-		ok, _ := evalRPN(rpn, row)
-		return ok
-	}, nil
-}
-
-func tokenizePredicate(s string) ([]token, error) {
-	var toks []token
-	i := 0
-	for i < len(s) {
-		ch := s[i]
-		// whitespace
-		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' {
-			i++
-			continue
-		}
-		// parentheses
-		if ch == '(' {
-			toks = append(toks, token{kind: tkLParen, val: "("})
-			i++
-			continue
-		}
-		if ch == ')' {
-			toks = append(toks, token{kind: tkRParen, val: ")"})
-			i++
-			continue
-		}
-		// operators: >= <= != == = > <
-		if ch == '>' || ch == '<' || ch == '!' || ch == '=' {
-			if i+1 < len(s) {
-				pair := s[i : i+2]
-				switch pair {
-				case ">=", "<=", "!=", "==":
-					toks = append(toks, token{kind: tkOp, val: pair})
-					i += 2
-					continue
+	sort.Ints(idx)
+
+	sheets := make([]SheetInfo, 0, len(idx))
+	for _, i := range idx {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := sheetMap[i]
+		si := SheetInfo{Name: name}
+
+		if dim, derr := f.GetSheetDimension(name); derr == nil && dim != "" {
+			// dim like "A1:D50"; parse right cell for bounds
+			parts := strings.Split(dim, ":")
+			if len(parts) == 2 {
+				x1, y1, e1 := excelize.CellNameToCoordinates(parts[0])
+				x2, y2, e2 := excelize.CellNameToCoordinates(parts[1])
+				if e1 == nil && e2 == nil {
+					if x2 >= x1 {
+						si.ColumnCount = x2 - x1 + 1
+					}
+					if y2 >= y1 {
+						si.RowCount = y2 - y1 + 1
+					}
 				}
 			}
-			// single-char ops
-			toks = append(toks, token{kind: tkOp, val: string(ch)})
-			i++
-			continue
 		}
-		// column ref: $N
-		if ch == '$' {
-			j := i + 1
-			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
-				j++
-			}
-			if j == i+1 {
-				return nil, fmt.Errorf("invalid column reference at %d", i)
-			}
-			toks = append(toks, token{kind: tkCol, val: s[i:j]})
-			i = j
-			continue
+
+		if visible, verr := f.GetSheetVisible(name); verr == nil {
+			si.Hidden = !visible
 		}
-		// string literal '...' or "..."
-		if ch == '\'' || ch == '"' {
-			quote := ch
-			j := i + 1
-			var b strings.Builder
-			for j < len(s) {
-				if s[j] == '\\' && j+1 < len(s) {
-					b.WriteByte(s[j+1])
-					j += 2
-					continue
-				}
-				if s[j] == quote {
-					break
-				}
-				b.WriteByte(s[j])
-				j++
-			}
-			if j >= len(s) || s[j] != quote {
-				return nil, fmt.Errorf("unterminated string literal")
+		si.Protected = sheetProtected(f, name)
+		si.FrozenPane = frozenPane(f, name)
+		if merges, merr := f.GetMergeCells(name); merr == nil {
+			si.MergedRegions = len(merges)
+		}
+		if tables, terr := f.GetTables(name); terr == nil {
+			for _, t := range tables {
+				si.Tables = append(si.Tables, TableInfo{Name: t.Name, Range: t.Range})
 			}
-			toks = append(toks, token{kind: tkString, val: b.String()})
-			i = j + 1
-			continue
 		}
-		// identifier: AND OR NOT CONTAINS (case-insensitive)
-		if isAlpha(ch) {
-			j := i + 1
-			for j < len(s) && (isAlphaNum(s[j]) || s[j] == '_') {
-				j++
-			}
-			word := strings.ToUpper(s[i:j])
-			switch word {
-			case "AND":
-				toks = append(toks, token{kind: tkAnd, val: word})
-			case "OR":
-				toks = append(toks, token{kind: tkOr, val: word})
-			case "NOT":
-				toks = append(toks, token{kind: tkNot, val: word})
-			case "CONTAINS":
-				toks = append(toks, token{kind: tkOp, val: "contains"})
-			default:
-				// number? fallthrough
-				// treat as bareword string value
-				toks = append(toks, token{kind: tkString, val: s[i:j]})
+
+		if accurateCounts {
+			rowsExt, colsExt, capped, serr := scanNonEmptyExtent(ctx, f, name, maxScanCells)
+			if serr != nil {
+				return serr
 			}
-			i = j
-			continue
+			si.ScannedRows, si.ScannedColumns, si.ScanCapped = rowsExt, colsExt, capped
+			si.DimensionInflated = !capped && (si.RowCount > rowsExt || si.ColumnCount > colsExt)
 		}
-		// number literal (digits, optional dot, optional commas)
-		if (ch >= '0' && ch <= '9') || ch == '-' || ch == '+' {
-			j := i + 1
-			for j < len(s) {
-				c := s[j]
-				if (c >= '0' && c <= '9') || c == '.' || c == ',' {
-					j++
-					continue
+
+		if !metadataOnly {
+			// Infer header from first row via streaming iterator
+			rows, rerr := f.Rows(name)
+			if rerr == nil {
+				if rows.Next() {
+					if hdr, herr := rows.Columns(); herr == nil {
+						si.Headers = hdr
+					}
 				}
-				break
+				_ = rows.Close()
 			}
-			toks = append(toks, token{kind: tkNumber, val: s[i:j]})
-			i = j
-			continue
 		}
-		return nil, fmt.Errorf("unexpected character %q at %d", ch, i)
+
+		sheets = append(sheets, si)
+	}
+	out.Sheets = sheets
+
+	names := f.GetDefinedName()
+	out.DefinedNamesTotal = len(names)
+	if len(names) > maxListedNames {
+		names = names[:maxListedNames]
+		out.DefinedNamesTruncated = true
+	}
+	for _, dn := range names {
+		out.DefinedNames = append(out.DefinedNames, DefinedNameInfo{Name: dn.Name, RefersTo: dn.RefersTo, Scope: dn.Scope})
 	}
-	return toks, nil
+	return nil
 }
 
-func isAlpha(b byte) bool    { return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') }
-func isAlphaNum(b byte) bool { return isAlpha(b) || (b >= '0' && b <= '9') }
-
-func precedence(t token) int {
-	switch t.kind {
-	case tkNot:
-		return 3
-	case tkOp:
-		return 2
-	case tkAnd:
-		return 1
-	case tkOr:
-		return 0
-	default:
-		return -1
+// parseNumber parses a displayed cell value as a number, ignoring thousands
+// separators.
+func parseNumber(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64); err == nil {
+		return v, true
 	}
+	return 0, false
 }
 
-func toRPN(toks []token) ([]token, error) {
-	var out []token
-	var ops []token
-	for i := 0; i < len(toks); i++ {
-		t := toks[i]
-		switch t.kind {
-		case tkCol, tkString, tkNumber:
-			out = append(out, t)
-		case tkNot, tkAnd, tkOr, tkOp:
-			for len(ops) > 0 {
-				top := ops[len(ops)-1]
-				if top.kind == tkLParen {
-					break
-				}
-				if precedence(top) >= precedence(t) {
-					out = append(out, top)
-					ops = ops[:len(ops)-1]
-					continue
-				}
-				break
-			}
-			ops = append(ops, t)
-		case tkLParen:
-			ops = append(ops, t)
-		case tkRParen:
-			found := false
-			for len(ops) > 0 {
-				top := ops[len(ops)-1]
-				ops = ops[:len(ops)-1]
-				if top.kind == tkLParen {
-					found = true
-					break
-				}
-				out = append(out, top)
-			}
-			if !found {
-				return nil, fmt.Errorf("mismatched parentheses")
-			}
-		default:
-			return nil, fmt.Errorf("unexpected token in expression")
-		}
+// maxPreviewCols bounds preview_sheet's max_cols window.
+const maxPreviewCols = 1000
+
+// columnWindow returns the cells of row within the 1-based inclusive column
+// window [startCol, endCol]; endCol 0 means through the end of the row.
+func columnWindow(row []string, startCol, endCol int) []string {
+	if startCol <= 1 && endCol == 0 {
+		return row
 	}
-	for i := len(ops) - 1; i >= 0; i-- {
-		if ops[i].kind == tkLParen || ops[i].kind == tkRParen {
-			return nil, fmt.Errorf("mismatched parentheses")
-		}
-		out = append(out, ops[i])
+	if startCol > len(row) {
+		return []string{}
 	}
-	return out, nil
+	if endCol == 0 || endCol > len(row) {
+		endCol = len(row)
+	}
+	return row[startCol-1 : endCol]
 }
 
-// evalRPN evaluates the predicate in RPN form for a given row of cell strings.
-func evalRPN(rpn []token, row []string) (bool, error) {
-	// helper to fetch string for a token value
-	getString := func(t token) (string, error) {
-		switch t.kind {
-		case tkString:
-			return t.val, nil
-		case tkNumber:
-			return t.val, nil
-		case tkCol:
-			// t.val like "$12"
-			num := strings.TrimPrefix(t.val, "$")
-			idx, err := strconv.Atoi(num)
-			if err != nil || idx <= 0 {
-				return "", fmt.Errorf("invalid column index")
-			}
-			pos := idx - 1
-			if pos >= 0 && pos < len(row) {
-				return row[pos], nil
-			}
-			return "", nil
-		default:
-			return "", fmt.Errorf("unexpected token for value")
-		}
+// maxSchemaCols caps the columns preview_sheet's summarize line describes.
+const maxSchemaCols = 20
+
+// previewSchema infers the schema of a preview page from its rows only and
+// returns it with the number of data rows examined. When hasHeader is set the
+// first row supplies the headers and is not counted as data. startCol is the
+// 1-based column of the rows' first cell.
+func previewSchema(grid [][]string, hasHeader bool, startCol int) ([]PreviewColumn, int) {
+	var header []string
+	data := grid
+	if hasHeader && len(grid) > 0 {
+		header, data = grid[0], grid[1:]
+	}
+	width := len(header)
+	for _, row := range data {
+		width = max(width, len(row))
+	}
+	width = min(width, maxSchemaCols)
+	cols := make([]PreviewColumn, width)
+	values := make([]string, len(data))
+	for c := range cols {
+		for r, row := range data {
+			values[r] = cellAt(row, c)
+		}
+		name, _ := excelize.ColumnNumberToName(startCol + c)
+		typ, nonEmpty := insights.InferType(values)
+		cols[c] = PreviewColumn{Column: name, Header: strings.TrimSpace(cellAt(header, c)), Type: typ, NonEmpty: nonEmpty}
 	}
+	return cols, len(data)
+}
 
-	// helper parse number
-	parseNum := func(s string) (float64, bool) {
-		s = strings.ReplaceAll(s, ",", "")
-		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
-			return v, true
+// schemaLine renders a preview schema over rows data rows as one line, e.g.
+// schema: A "Region" text 10/10; B "Amount" integer 9/10.
+func schemaLine(cols []PreviewColumn, rows int) string {
+	if len(cols) == 0 {
+		return "schema: (no columns)"
+	}
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		parts[i] = c.Column
+		if c.Header != "" {
+			parts[i] += " " + strconv.Quote(truncateText(c.Header, 40))
 		}
-		return 0, false
+		parts[i] += fmt.Sprintf(" %s %d/%d", c.Type, c.NonEmpty, rows)
 	}
+	return "schema: " + strings.Join(parts, "; ")
+}
 
-	var st []bool
-	for _, t := range rpn {
-		switch t.kind {
-		case tkString, tkNumber, tkCol:
-			// push placeholder on a temp value stack by encoding string onto a marker
-			// We'll encode as pushing a boolean marker onto st along with a special op in a parallel stack is overkill.
-			// Instead, treat operands by pushing onto an auxiliary stack of tokens.
-			// For simplicity: maintain a stack of tokens representing intermediate values.
-			// We'll implement a small inner stack here.
-			// Defer: we change approach below to use a value token stack.
-		}
-	}
-	// Re-implement using a token stack for values:
-	var valStack []token
-	for _, t := range rpn {
-		switch t.kind {
-		case tkString, tkNumber, tkCol:
-			valStack = append(valStack, t)
-		case tkNot:
-			if len(st) == 0 {
-				// Evaluate the next value token into boolean then apply not
-				if len(valStack) == 0 {
-					return false, fmt.Errorf("invalid NOT operand")
-				}
-				v := valStack[len(valStack)-1]
-				valStack = valStack[:len(valStack)-1]
-				// non-empty string or non-zero number considered truthy
-				vs, _ := getString(v)
-				b := false
-				if vs != "" {
-					b = true
-				}
-				st = append(st, !b)
-			} else {
-				b := st[len(st)-1]
-				st = st[:len(st)-1]
-				st = append(st, !b)
-			}
-		case tkAnd, tkOr:
-			// ensure two booleans on stack; if missing, try evaluate from valStack
-			for len(st) < 2 {
-				if len(valStack) == 0 {
-					return false, fmt.Errorf("invalid boolean operands")
-				}
-				v := valStack[len(valStack)-1]
-				valStack = valStack[:len(valStack)-1]
-				vs, _ := getString(v)
-				st = append(st, vs != "")
-			}
-			b2 := st[len(st)-1]
-			b1 := st[len(st)-2]
-			st = st[:len(st)-2]
-			if t.kind == tkAnd {
-				st = append(st, b1 && b2)
-			} else {
-				st = append(st, b1 || b2)
-			}
-		case tkOp:
-			// binary comparison: need two value tokens
-			if len(valStack) < 2 {
-				return false, fmt.Errorf("invalid comparison operands")
-			}
-			r := valStack[len(valStack)-1]
-			l := valStack[len(valStack)-2]
-			valStack = valStack[:len(valStack)-2]
-			ls, _ := getString(l)
-			rs, _ := getString(r)
-			var res bool
-			switch strings.ToLower(t.val) {
-			case "contains":
-				res = strings.Contains(strings.ToLower(ls), strings.ToLower(rs))
-			case ">", ">=", "<", "<=":
-				ln, lok := parseNum(ls)
-				rn, rok := parseNum(rs)
-				if lok && rok {
-					switch t.val {
-					case ">":
-						res = ln > rn
-					case ">=":
-						res = ln >= rn
-					case "<":
-						res = ln < rn
-					case "<=":
-						res = ln <= rn
-					}
-				} else {
-					res = false
-				}
-			case "=", "==":
-				res = ls == rs
-			case "!=":
-				res = ls != rs
-			default:
-				return false, fmt.Errorf("unsupported operator %q", t.val)
-			}
-			st = append(st, res)
-		default:
-			return false, fmt.Errorf("unexpected token during evaluation")
-		}
+// scanNonEmptyExtent streams sheet and returns the last row and column that hold
+// a non-empty value. It stops after examining maxCells cells and reports capped.
+func scanNonEmptyExtent(ctx context.Context, f *excelize.File, sheet string, maxCells int) (int, int, bool, error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return 0, 0, false, err
 	}
-	if len(st) != 1 {
-		// If booleans not consolidated, try reducing from remaining valStack
-		for len(st) > 1 {
-			b := st[len(st)-1]
-			st = st[:len(st)-1]
-			st[len(st)-1] = st[len(st)-1] && b
+	defer func() { _ = rows.Close() }()
+	lastRow, lastCol, examined, rowNum := 0, 0, 0, 0
+	for rows.Next() {
+		if ctx.Err() != nil {
+			return 0, 0, false, ctx.Err()
 		}
-		if len(st) != 1 {
-			return false, fmt.Errorf("invalid expression result")
+		rowNum++
+		cols, cerr := rows.Columns()
+		if cerr != nil {
+			return 0, 0, false, cerr
+		}
+		for i, v := range cols {
+			if examined >= maxCells {
+				return lastRow, lastCol, true, nil
+			}
+			examined++
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			lastRow = rowNum
+			if i+1 > lastCol {
+				lastCol = i + 1
+			}
 		}
 	}
-	return st[0], nil
+	return lastRow, lastCol, false, nil
 }
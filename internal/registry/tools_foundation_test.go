@@ -0,0 +1,1540 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/config"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/xuri/excelize/v2"
+)
+
+func createMergedWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]string{"Region", "Q1", "Q2"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]string{"North", "10", "20"}))
+	require.NoError(t, f.SetSheetRow(sh, "B3", &[]string{"30", "40"}))
+	// A2:A3 carries "North" for both rows
+	require.NoError(t, f.MergeCell(sh, "A2", "A3"))
+	path := filepath.Join(t.TempDir(), "merged.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestReadRange_ExpandMergedAcrossPages(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createMergedWorkbook(t)
+
+	// Page 1 ends at B2 past the merge anchor A2; page 2 covers A3 inside the region.
+	res := callTool(t, srv, "read_range", map[string]any{
+		"path": path, "sheet": "Sheet1", "range": "A1:C3", "max_cells": 5, "expand_merged": true,
+	})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.True(t, out.Meta.Truncated)
+	require.Empty(t, out.MergedCells)
+	_, body := splitSummary(t, resultText(t, res))
+	var page1 [][]string
+	require.NoError(t, json.Unmarshal([]byte(body), &page1))
+	require.Equal(t, [][]string{{"Region", "Q1", "Q2"}, {"North", "10"}}, page1)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ReadRangeOutput)
+	require.False(t, out.Meta.Truncated)
+	require.Equal(t, []string{"A3"}, out.MergedCells)
+	_, body = splitSummary(t, resultText(t, res))
+	var page2 [][]string
+	require.NoError(t, json.Unmarshal([]byte(body), &page2))
+	require.Equal(t, [][]string{{"20"}, {"North", "30", "40"}}, page2)
+}
+
+func TestReadRange_ExpandMergedOnlyReportsMembership(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createMergedWorkbook(t)
+
+	// Covered cells read as the anchor value either way; the flag only adds mergedCells.
+	for _, mode := range []string{"formatted", "raw"} {
+		read := func(expand bool) (ReadRangeOutput, string) {
+			t.Helper()
+			res := callTool(t, srv, "read_range", map[string]any{
+				"path": path, "sheet": "Sheet1", "range": "A1:C3", "value_mode": mode, "expand_merged": expand,
+			})
+			require.False(t, res.IsError, "%s", resultText(t, res))
+			_, body := splitSummary(t, resultText(t, res))
+			return res.StructuredContent.(ReadRangeOutput), body
+		}
+		off, offBody := read(false)
+		on, onBody := read(true)
+		require.Equal(t, offBody, onBody, mode)
+		var rows [][]string
+		require.NoError(t, json.Unmarshal([]byte(offBody), &rows))
+		require.Equal(t, "North", rows[2][0], mode)
+		require.Empty(t, off.MergedCells, mode)
+		require.Equal(t, []string{"A3"}, on.MergedCells, mode)
+	}
+}
+
+func TestReadRange_MultipleRanges(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 6)
+	type section struct {
+		Range string     `json:"range"`
+		Rows  [][]string `json:"rows"`
+	}
+
+	// Page 1 takes all of A1:B1 and the first cell of A6:B7.
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "ranges": []string{"A1:B1", "A6:B7"}, "max_cells": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, "A1:B1,A6:B7", out.RangeA1)
+	require.Equal(t, 6, out.Meta.Total)
+	require.Equal(t, 3, out.Meta.Returned)
+	require.True(t, out.Meta.Truncated)
+	require.Equal(t, []RangeSection{{Range: "A1:B1", Total: 2, Returned: 2}, {Range: "A6:B7", Total: 4, Returned: 1}}, out.Sections)
+	_, body := splitSummary(t, resultText(t, res))
+	var page []section
+	require.NoError(t, json.Unmarshal([]byte(body), &page))
+	require.Equal(t, []section{{"A1:B1", [][]string{{"Region", "Amount"}}}, {"A6:B7", [][]string{{"North"}}}}, page)
+
+	// The cursor resumes inside the second range.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ReadRangeOutput)
+	require.False(t, out.Meta.Truncated)
+	require.Equal(t, []RangeSection{{Range: "A6:B7", Offset: 1, Total: 4, Returned: 3}}, out.Sections)
+	_, body = splitSummary(t, resultText(t, res))
+	require.NoError(t, json.Unmarshal([]byte(body), &page))
+	require.Equal(t, []section{{"A6:B7", [][]string{{"40"}, {"North", "50"}}}}, page)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B1", "ranges": []string{"A6:B7"}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "ranges": []string{"A1:B1"}, "encoding": "csv"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: ranges requires encoding 'json'")
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "ranges": []string{"A1:B1", "A1:XFD1048576"}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PAYLOAD_TOO_LARGE")
+}
+
+func TestReadRange_RecordsEncoding(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Sales report"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]string{"Region", "Amount", "", "amount", "Amount"}))
+	for i := 0; i < 4; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i+3)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]any{"North", i, "x", i * 2, i * 3}))
+	}
+	path := filepath.Join(t.TempDir(), "records.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	// max_cells 14 halves to 7 and rounds down to one 5-cell row.
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A2:E6", "encoding": "records", "max_cells": 14})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, []string{"Region", "Amount", "col_C", "amount", "Amount_2"}, out.Keys)
+	require.Equal(t, 20, out.Meta.Total)
+	require.Equal(t, 5, out.Meta.Returned)
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, `[{"Region":"North","Amount":"0","col_C":"x","amount":"0","Amount_2":"0"}]`, body)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, "Amount_2", out.Keys[4])
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, `[{"Region":"North","Amount":"1","col_C":"x","amount":"2","Amount_2":"3"}]`, body)
+
+	// An explicit header row above the range keys every row of it.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A5:B6", "encoding": "records", "header_row": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, `[{"Region":"North","Amount":"2"},{"Region":"North","Amount":"3"}]`, body)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A2:B3", "header_row": 2})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: header_row requires encoding 'records'")
+}
+
+// createTypedWorkbook writes a header row and one data row of date, time,
+// number, boolean, blank, inline-string, and shared-string cells. The stream
+// writer stores strings inline; H2 is added on reopen as a shared string.
+func createTypedWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	require.NoError(t, err)
+	stampFmt := "yyyy-mm-dd hh:mm"
+	stampStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &stampFmt})
+	require.NoError(t, err)
+	timeStyle, err := f.NewStyle(&excelize.Style{NumFmt: 21})
+	require.NoError(t, err)
+	amountStyle, err := f.NewStyle(&excelize.Style{NumFmt: 4})
+	require.NoError(t, err)
+	sw, err := f.NewStreamWriter("Sheet1")
+	require.NoError(t, err)
+	require.NoError(t, sw.SetRow("A1", []any{"Label", "Date", "Stamp", "Time", "Amount", "Flag", "Blank", "Note"}))
+	require.NoError(t, sw.SetRow("A2", []any{
+		"Widget",
+		excelize.Cell{StyleID: dateStyle, Value: 45292},
+		excelize.Cell{StyleID: stampStyle, Value: 45292.5},
+		excelize.Cell{StyleID: timeStyle, Value: 0.75},
+		excelize.Cell{StyleID: amountStyle, Value: 1234.5},
+		true,
+	}))
+	require.NoError(t, sw.Flush())
+	path := filepath.Join(t.TempDir(), "typed.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	f, err = excelize.OpenFile(path)
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellValue("Sheet1", "H2", "shared"))
+	require.NoError(t, f.Save())
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestReadRange_ValueModes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createTypedWorkbook(t)
+
+	for _, tc := range []struct{ mode, want string }{
+		{"formatted", `[["Widget","01-01-24","2024-01-01 12:00","18:00:00","1,234.50","TRUE","","shared"]]`},
+		{"raw", `[["Widget","45292","45292.5","0.75","1234.5","1","","shared"]]`},
+		{"typed", `[["Widget","2024-01-01","2024-01-01T12:00:00","18:00:00",1234.5,true,null,"shared"]]`},
+	} {
+		res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A2:H2", "value_mode": tc.mode})
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		_, body := splitSummary(t, resultText(t, res))
+		require.Equal(t, tc.want, body, tc.mode)
+	}
+
+	// csv shows the typed text; records carry typed values.
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "B2:F2", "value_mode": "typed", "encoding": "csv"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, "2024-01-01,2024-01-01T12:00:00,18:00:00,1234.5,true\n", body)
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C2", "value_mode": "typed", "encoding": "records"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, `[{"Label":"Widget","Date":"2024-01-01","Stamp":"2024-01-01T12:00:00"}]`, body)
+
+	// The cursor keeps the mode.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "D2:F2", "value_mode": "typed", "max_cells": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	summary, _ := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "valueMode=typed")
+	out := res.StructuredContent.(ReadRangeOutput)
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, `[[true]]`, body)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A2:B2", "value_mode": "typed", "cell_detail": true})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A2:B2", "value_mode": "iso"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: value_mode must be")
+}
+
+func TestPreviewSheet_ValueModes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createTypedWorkbook(t)
+
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "value_mode": "raw"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, `[["Label","Date","Stamp","Time","Amount","Flag","Blank","Note"],["Widget","45292","45292.5","0.75","1234.5","1","","shared"]]`, body)
+
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "value_mode": "typed", "skip_rows": 1, "header_row": 1, "start_col": 2, "max_cols": 6})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	summary, body := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "valueMode=typed")
+	require.Equal(t, `[["Date","Stamp","Time","Amount","Flag","Blank"],["2024-01-01","2024-01-01T12:00:00","18:00:00",1234.5,true,null]]`, body)
+
+	// Resuming into the next column window stays typed.
+	out := res.StructuredContent.(PreviewSheetOutput)
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, `[["Note"],["shared"]]`, body)
+}
+
+func TestReadRange_DefaultDoesNotFlagMerged(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createMergedWorkbook(t)
+
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A3:A3"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.Empty(t, out.MergedCells)
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, `[["North"]]`, body)
+}
+
+func TestReadRange_CellDetailAcrossPages(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetCellValue(sh, "A1", "Item"))
+	require.NoError(t, f.SetCellValue(sh, "B1", 4.5))
+	require.NoError(t, f.SetCellValue(sh, "C1", true))
+	require.NoError(t, f.SetCellFloat(sh, "A2", 45292, -1, 64))
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle(sh, "A2", "A2", dateStyle))
+	require.NoError(t, f.SetCellFormula(sh, "B2", "B1*2"))
+	path := filepath.Join(t.TempDir(), "detail.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	// max_cells 9 shrinks to 3 detail cells: row 1 on page 1, row 2 on page 2.
+	res := callTool(t, srv, "read_range", map[string]any{
+		"path": path, "sheet": sh, "range": "A1:C2", "max_cells": 9, "cell_detail": true,
+	})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.True(t, out.Meta.CellDetail)
+	require.Equal(t, 3, out.Meta.Returned)
+	summary, body := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "cellDetail=true")
+	var page1 [][]cellDetail
+	require.NoError(t, json.Unmarshal([]byte(body), &page1))
+	require.Equal(t, [][]cellDetail{{{V: "Item", T: "string"}, {V: "4.5", T: "number"}, {V: "TRUE", T: "bool"}}}, page1)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ReadRangeOutput)
+	require.True(t, out.Meta.CellDetail)
+	require.False(t, out.Meta.Truncated)
+	_, body = splitSummary(t, resultText(t, res))
+	var page2 [][]cellDetail
+	require.NoError(t, json.Unmarshal([]byte(body), &page2))
+	require.Len(t, page2, 1)
+	require.Equal(t, "date", page2[0][0].T)
+	require.Equal(t, "B1*2", page2[0][1].F)
+	require.Equal(t, cellDetail{T: "empty"}, page2[0][2])
+}
+
+func createSalesWorkbook(t *testing.T, n int) string {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Region", "Amount"}))
+	for i := 0; i < n; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]any{"North", i * 10}))
+	}
+	path := filepath.Join(t.TempDir(), "sales.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestFilterData_SummaryOutputMode(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 8)
+
+	full := callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'"})
+	require.False(t, full.IsError, "%s", resultText(t, full))
+	summ := callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "output": "summary"})
+	require.False(t, summ.IsError, "%s", resultText(t, summ))
+
+	fullText := resultText(t, full)
+	summText := resultText(t, summ)
+	_, body := splitSummary(t, summText)
+	lines := strings.Split(body, "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, "- row 2: North | 0", lines[0])
+	require.Less(t, len(summText), len(fullText))
+
+	// Structured content keeps every row and meta carries both estimates.
+	var out struct {
+		Results []any    `json:"results"`
+		Meta    PageMeta `json:"meta"`
+	}
+	decodeStructured(t, summ, &out)
+	require.Len(t, out.Results, 8)
+	require.Positive(t, out.Meta.EstTokensSummary)
+	require.Greater(t, out.Meta.EstTokensFull, out.Meta.EstTokensSummary)
+	require.Equal(t, estimateTokens(fullText), out.Meta.EstTokensFull)
+	require.Equal(t, estimateTokens(summText), out.Meta.EstTokensSummary)
+}
+
+func TestFilterAndSearch_PageJump(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 25)
+	type page struct {
+		Results []struct {
+			Row  int    `json:"row"`
+			Cell string `json:"cell"`
+		} `json:"results"`
+		Meta PageMeta `json:"meta"`
+	}
+
+	res := callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "max_rows": 10, "page": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "page=3/3")
+	var out page
+	decodeStructured(t, res, &out)
+	require.Equal(t, 3, out.Meta.Pages)
+	require.Len(t, out.Results, 5)
+	require.Equal(t, 22, out.Results[0].Row)
+	require.False(t, out.Meta.Truncated)
+
+	// A jump with a cursor keeps the cursor's predicate binding.
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "max_rows": 10})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.NotEmpty(t, out.Meta.NextCursor)
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "predicate": "$2 > 5", "page": 3})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "page": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, 22, out.Results[0].Row)
+
+	res = callTool(t, srv, "search_data", map[string]any{"path": path, "sheet": "Sheet1", "query": "North", "max_results": 10, "page": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, 3, out.Meta.Pages)
+	require.Equal(t, "A12", out.Results[0].Cell)
+	require.NotEmpty(t, out.Meta.NextCursor)
+}
+
+func TestPreviewSheet_Summarize(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 4)
+
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "rows": 3, "summarize": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	lines := strings.Split(resultText(t, res), "\n")
+	require.Equal(t, `schema: A "Region" text 2/2; B "Amount" numeric 2/2`, lines[len(lines)-1])
+	out := res.StructuredContent.(PreviewSheetOutput)
+	require.Equal(t, []PreviewColumn{{Column: "A", Header: "Region", Type: "text", NonEmpty: 2}, {Column: "B", Header: "Amount", Type: "numeric", NonEmpty: 2}}, out.Schema)
+
+	// Later pages have no header row of their own; all rows count as data.
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "summarize": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.True(t, strings.HasSuffix(resultText(t, res), `schema: A text 2/2; B numeric 2/2`))
+
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.NotContains(t, resultText(t, res), "schema:")
+	require.Empty(t, res.StructuredContent.(PreviewSheetOutput).Schema)
+}
+
+func TestPagination_ResumedPageSize(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 25)
+	type page struct {
+		Results []struct {
+			Row int `json:"row"`
+		} `json:"results"`
+		Meta PageMeta `json:"meta"`
+	}
+	call := func(tool string, args map[string]any) page {
+		args["path"] = path
+		res := callTool(t, srv, tool, args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		var out page
+		decodeStructured(t, res, &out)
+		return out
+	}
+
+	// The caller's size wins on resume, whether smaller or larger than the
+	// cursor's; omitting it keeps the cursor's.
+	out := call("filter_data", map[string]any{"sheet": "Sheet1", "predicate": "$1 = 'North'", "max_rows": 5})
+	out = call("filter_data", map[string]any{"cursor": out.Meta.NextCursor, "max_rows": 2})
+	require.Len(t, out.Results, 2)
+	require.Equal(t, 7, out.Results[0].Row)
+	out = call("filter_data", map[string]any{"cursor": out.Meta.NextCursor})
+	require.Len(t, out.Results, 2)
+	out = call("filter_data", map[string]any{"cursor": out.Meta.NextCursor, "max_rows": 20})
+	require.Len(t, out.Results, 16)
+	require.Empty(t, out.Meta.NextCursor)
+
+	out = call("search_data", map[string]any{"sheet": "Sheet1", "query": "North", "max_results": 5})
+	out = call("search_data", map[string]any{"cursor": out.Meta.NextCursor, "max_results": 15})
+	require.Len(t, out.Results, 15)
+
+	out = call("read_range", map[string]any{"sheet": "Sheet1", "range": "A1:B26", "max_cells": 8})
+	require.Equal(t, 8, out.Meta.Returned)
+	out = call("read_range", map[string]any{"cursor": out.Meta.NextCursor, "max_cells": 4})
+	require.Equal(t, 4, out.Meta.Returned)
+	out = call("read_range", map[string]any{"cursor": out.Meta.NextCursor})
+	require.Equal(t, 4, out.Meta.Returned)
+
+	out = call("preview_sheet", map[string]any{"sheet": "Sheet1", "rows": 3})
+	out = call("preview_sheet", map[string]any{"cursor": out.Meta.NextCursor, "rows": 6})
+	require.Equal(t, 6, out.Meta.Returned)
+}
+
+func TestFilterData_ReturnColumns(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 5)
+	type page struct {
+		ReturnColumns []int `json:"returnColumns"`
+		Results       []struct {
+			Row      int      `json:"row"`
+			Snapshot []string `json:"snapshot"`
+		} `json:"results"`
+		Meta PageMeta `json:"meta"`
+	}
+
+	// Names and indices mix; order is preserved and cells past the row are empty.
+	res := callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "max_rows": 2, "return_columns": []any{"amount", 5, "Region"}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out page
+	decodeStructured(t, res, &out)
+	require.Equal(t, []int{2, 5, 1}, out.ReturnColumns)
+	require.Equal(t, []string{"0", "", "North"}, out.Results[0].Snapshot)
+	require.NotEmpty(t, out.Meta.NextCursor)
+
+	// The cursor carries the projection.
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, 4, out.Results[0].Row)
+	require.Equal(t, []string{"20", "", "North"}, out.Results[0].Snapshot)
+
+	// return_columns is not bound by the predicate hash, so it can change on resume.
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "predicate": "$1 = 'North'", "return_columns": []any{1}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, []string{"North"}, out.Results[0].Snapshot)
+
+	// The predicate binds by its normalized form, so spacing, keyword case,
+	// and quoting may differ on resume; a different predicate may not.
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "max_rows": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	cursor := out.Meta.NextCursor
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": cursor, "predicate": `($1="North")`})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": cursor, "predicate": "$1 = 'South'"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "return_columns": []any{"Missing"}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), `VALIDATION: column name "Missing" not found in header row 1`)
+}
+
+func TestFilterData_EmptinessPredicates(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Name", "Email", "Phone"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]any{"Ann", "ann@example.com", "555"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A3", &[]any{"Bob", "   ", "556"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A4", &[]any{"Cy", nil, nil}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A5", &[]any{"Di", "di@example.com"}))
+	path := filepath.Join(t.TempDir(), "contacts.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	type page struct {
+		Results []struct {
+			Row      int      `json:"row"`
+			Snapshot []string `json:"snapshot"`
+		} `json:"results"`
+	}
+	filter := func(args map[string]any) page {
+		t.Helper()
+		args["path"], args["sheet"] = path, "Sheet1"
+		res := callTool(t, srv, "filter_data", args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		var out page
+		decodeStructured(t, res, &out)
+		return out
+	}
+	rows := func(out page) []int {
+		got := make([]int, 0, len(out.Results))
+		for _, r := range out.Results {
+			got = append(got, r.Row)
+		}
+		return got
+	}
+
+	// Whitespace-only cells are empty, unlike $2 = "".
+	require.Equal(t, []int{3, 4}, rows(filter(map[string]any{"predicate": "$2 IS EMPTY"})))
+	require.Equal(t, []int{4}, rows(filter(map[string]any{"predicate": `$2 = ""`})))
+	require.Equal(t, []int{1, 2, 5}, rows(filter(map[string]any{"predicate": "$2 is not empty"})))
+	require.Equal(t, []int{3, 4, 5}, rows(filter(map[string]any{"predicate": "$2 IS EMPTY OR $3 IS EMPTY"})))
+	require.Equal(t, []int{3}, rows(filter(map[string]any{"predicate": "$2 IS EMPTY AND $3 IS NOT EMPTY"})))
+	require.Equal(t, []int{4}, rows(filter(map[string]any{"predicate": "NOT ($2 IS NOT EMPTY OR $3 IS NOT EMPTY)"})))
+
+	// Projections may include the blank columns being tested.
+	out := filter(map[string]any{"predicate": "$3 IS EMPTY AND $1 != 'Name'", "return_columns": []any{"Name", "Phone"}})
+	require.Equal(t, []int{4, 5}, rows(out))
+	require.Equal(t, []string{"Cy", ""}, out.Results[0].Snapshot)
+	require.Equal(t, []string{"Di", ""}, out.Results[1].Snapshot)
+}
+
+func TestFilterData_SortColumn(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 25)
+	type page struct {
+		Results []struct {
+			Row      int      `json:"row"`
+			Snapshot []string `json:"snapshot"`
+		} `json:"results"`
+		Meta PageMeta `json:"meta"`
+	}
+
+	args := map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "max_rows": 3, "sort_column": "Amount", "sort_order": "desc"}
+	res := callTool(t, srv, "filter_data", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out page
+	decodeStructured(t, res, &out)
+	require.Equal(t, []int{26, 25, 24}, []int{out.Results[0].Row, out.Results[1].Row, out.Results[2].Row})
+	require.Equal(t, 25, out.Meta.Total)
+	require.False(t, out.Meta.SortCapped)
+
+	// The cursor keeps the sort; a different sort does not bind.
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var next page
+	decodeStructured(t, res, &next)
+	require.Equal(t, 23, next.Results[0].Row)
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "sort_column": 2})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+
+	// At most max_rows × 10 sorted rows are reachable.
+	args["max_rows"] = 2
+	args["page"] = 10
+	res = callTool(t, srv, "filter_data", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var last page
+	decodeStructured(t, res, &last)
+	require.True(t, last.Meta.SortCapped)
+	require.Equal(t, 10, last.Meta.Pages)
+	require.True(t, last.Meta.Truncated)
+	require.Empty(t, last.Meta.NextCursor)
+	require.Equal(t, 8, last.Results[0].Row)
+	args["page"] = 11
+	res = callTool(t, srv, "filter_data", args)
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "LIMIT_EXCEEDED")
+
+	res = callTool(t, srv, "filter_data", map[string]any{"path": path, "sheet": "Sheet1", "predicate": "$1 = 'North'", "sort_order": "desc"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: sort_order requires sort_column")
+}
+
+func TestSearchData_SummaryOutputRejectsUnknownMode(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 2)
+
+	res := callTool(t, srv, "search_data", map[string]any{"path": path, "sheet": "Sheet1", "query": "North", "output": "brief"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+
+	res = callTool(t, srv, "search_data", map[string]any{"path": path, "sheet": "Sheet1", "query": "North", "output": "summary"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, "- A2: North | 0\n- A3: North | 10", body)
+}
+
+func TestSearchData_FormulaAndCommentScopes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	_, err := f.NewSheet("Sheet3")
+	require.NoError(t, err)
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]any{"Price", 10, 20, "sheet3 notes"}))
+	require.NoError(t, f.SetCellFormula("Sheet1", "B2", "Sheet3!A1*2"))
+	require.NoError(t, f.SetCellFormula("Sheet1", "C2", "B1*1.07"))
+	require.NoError(t, f.SetCellFormula("Sheet1", "D3", "SUM(SHEET3!A1:A9)"))
+	require.NoError(t, f.AddComment("Sheet1", excelize.Comment{Cell: "C1", Author: "qa", Text: "Check the 1.07 uplift"}))
+	path := filepath.Join(t.TempDir(), "audit.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	search := func(args map[string]any) SearchDataOutput {
+		t.Helper()
+		res := callTool(t, srv, "search_data", args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		var out SearchDataOutput
+		decodeStructured(t, res, &out)
+		return out
+	}
+
+	// Formula text matches ignore case; values that merely mention Sheet3 do not match.
+	out := search(map[string]any{"path": path, "sheet": "Sheet1", "query": "sheet3!", "scope": "formulas"})
+	require.Equal(t, []SearchMatch{
+		{Cell: "B2", Row: 2, Column: 2, Value: "=Sheet3!A1*2", Scope: "formulas"},
+		{Cell: "D3", Row: 3, Column: 4, Value: "=SUM(SHEET3!A1:A9)", Scope: "formulas"},
+	}, out.Results)
+
+	out = search(map[string]any{"path": path, "sheet": "Sheet1", "query": `1\.07$`, "regex": true, "scope": "formulas"})
+	require.Len(t, out.Results, 1)
+	require.Equal(t, "C2", out.Results[0].Cell)
+
+	out = search(map[string]any{"path": path, "sheet": "Sheet1", "query": "1.07", "scope": "comments"})
+	require.Equal(t, []SearchMatch{{Cell: "C1", Row: 1, Column: 3, Value: "Check the 1.07 uplift", Scope: "comments"}}, out.Results)
+
+	out = search(map[string]any{"path": path, "sheet": "Sheet1", "query": "Price"})
+	require.Equal(t, "values", out.Results[0].Scope)
+	require.NotEmpty(t, out.Results[0].Snapshot)
+
+	// The cursor keeps the scope and rejects a different one.
+	out = search(map[string]any{"path": path, "sheet": "Sheet1", "query": "sheet3", "scope": "formulas", "max_results": 1})
+	require.NotEmpty(t, out.Meta.NextCursor)
+	next := search(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Equal(t, "D3", next.Results[0].Cell)
+	require.Equal(t, "formulas", next.Results[0].Scope)
+	res := callTool(t, srv, "search_data", map[string]any{"path": path, "cursor": out.Meta.NextCursor, "scope": "comments"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+	res = callTool(t, srv, "search_data", map[string]any{"path": path, "sheet": "Sheet1", "query": "x", "scope": "styles"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: scope must be one of")
+}
+
+func TestPreviewSheet_ColumnWindowsAdvanceAfterRows(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	for r := 1; r <= 3; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		row := make([]string, 5)
+		for c := range row {
+			row[c] = fmt.Sprintf("r%dc%d", r, c+1)
+		}
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	path := filepath.Join(t.TempDir(), "wide.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	page := func(args map[string]any) (PreviewSheetOutput, string, [][]string) {
+		t.Helper()
+		res := callTool(t, srv, "preview_sheet", args)
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		summary, body := splitSummary(t, resultText(t, res))
+		var rows [][]string
+		require.NoError(t, json.Unmarshal([]byte(body), &rows))
+		return res.StructuredContent.(PreviewSheetOutput), summary, rows
+	}
+
+	out, summary, rows := page(map[string]any{"path": path, "sheet": "Sheet1", "rows": 2, "max_cols": 2})
+	require.Contains(t, summary, "cols=1..2 of 5")
+	require.True(t, out.Meta.ColumnsTruncated)
+	require.True(t, out.Meta.Truncated)
+	require.Equal(t, [][]string{{"r1c1", "r1c2"}, {"r2c1", "r2c2"}}, rows)
+
+	out, _, rows = page(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Equal(t, [][]string{{"r3c1", "r3c2"}}, rows)
+	require.True(t, out.Meta.Truncated, "columns 3..5 remain")
+
+	out, summary, rows = page(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Contains(t, summary, "cols=3..4 of 5")
+	require.Equal(t, [][]string{{"r1c3", "r1c4"}, {"r2c3", "r2c4"}}, rows)
+
+	out, _, _ = page(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	out, summary, rows = page(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Contains(t, summary, "cols=5..5 of 5")
+	require.Equal(t, [][]string{{"r1c5"}, {"r2c5"}}, rows)
+
+	out, _, rows = page(map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.Equal(t, [][]string{{"r3c5"}}, rows)
+	require.False(t, out.Meta.Truncated)
+	require.Empty(t, out.Meta.NextCursor)
+
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "start_col": 9})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: start_col 9 exceeds sheet width")
+}
+
+func TestPreviewSheet_SkipRowsWithHeaderResumesFromCursor(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "Quarterly Report"))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A3", &[]string{"Region", "Units"}))
+	for r := 4; r <= 8; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]string{fmt.Sprintf("R%d", r), fmt.Sprint(r * 10)}))
+	}
+	path := filepath.Join(t.TempDir(), "banner.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	header := []string{"Region", "Units"}
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "rows": 2, "skip_rows": 3, "header_row": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(PreviewSheetOutput)
+	require.Equal(t, 5, out.Meta.Total)
+	require.Equal(t, 2, out.Meta.Returned)
+	summary, body := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "firstRow=4 headerRow=3")
+	var rows [][]string
+	require.NoError(t, json.Unmarshal([]byte(body), &rows))
+	require.Equal(t, [][]string{header, {"R4", "40"}, {"R5", "50"}}, rows)
+
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(PreviewSheetOutput)
+	summary, body = splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "firstRow=6")
+	require.NoError(t, json.Unmarshal([]byte(body), &rows))
+	require.Equal(t, [][]string{header, {"R6", "60"}, {"R7", "70"}}, rows)
+
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(PreviewSheetOutput)
+	require.False(t, out.Meta.Truncated)
+	_, body = splitSummary(t, resultText(t, res))
+	require.NoError(t, json.Unmarshal([]byte(body), &rows))
+	require.Equal(t, [][]string{header, {"R8", "80"}}, rows)
+
+	for _, args := range []map[string]any{
+		{"path": path, "sheet": "Sheet1", "skip_rows": 3, "header_row": 4},
+		{"path": path, "sheet": "Sheet1", "skip_rows": 20},
+		{"path": path, "sheet": "Sheet1", "skip_rows": -1},
+	} {
+		res = callTool(t, srv, "preview_sheet", args)
+		require.True(t, res.IsError, "%v", args)
+		require.Contains(t, resultText(t, res), "VALIDATION")
+	}
+}
+
+func TestListStructure_NamesTablesMergesHidden(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Region", "Units"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]string{"North", "10"}))
+	require.NoError(t, f.AddTable("Sheet1", &excelize.Table{Range: "A1:B2", Name: "Sales"}))
+	require.NoError(t, f.MergeCell("Sheet1", "D1", "E1"))
+	require.NoError(t, f.SetDefinedName(&excelize.DefinedName{Name: "Units", RefersTo: "Sheet1!$B$2"}))
+	_, err := f.NewSheet("Lookup")
+	require.NoError(t, err)
+	require.NoError(t, f.SetSheetVisible("Lookup", false))
+	path := filepath.Join(t.TempDir(), "meta.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path, "metadata_only": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out ListStructureOutput
+	decodeStructured(t, res, &out)
+	require.Len(t, out.Sheets, 2)
+	require.Equal(t, []TableInfo{{Name: "Sales", Range: "A1:B2"}}, out.Sheets[0].Tables)
+	require.Equal(t, 1, out.Sheets[0].MergedRegions)
+	require.False(t, out.Sheets[0].Hidden)
+	require.True(t, out.Sheets[1].Hidden)
+	require.Equal(t, 1, out.DefinedNamesTotal)
+	require.Equal(t, []DefinedNameInfo{{Name: "Units", RefersTo: "Sheet1!$B$2", Scope: "Workbook"}}, out.DefinedNames)
+	require.False(t, out.DefinedNamesTruncated)
+	require.Contains(t, resultText(t, res), "table=Sales(A1:B2)")
+}
+
+// createProtectedWorkbook writes Sheet1 with panes frozen at B2, a protected
+// "Locked" sheet, and a hidden "Archive" sheet.
+func createProtectedWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Region", "Units"}))
+	require.NoError(t, f.SetPanes("Sheet1", &excelize.Panes{Freeze: true, XSplit: 1, YSplit: 1, TopLeftCell: "B2", ActivePane: "bottomRight"}))
+	for _, name := range []string{"Locked", "Archive"} {
+		_, err := f.NewSheet(name)
+		require.NoError(t, err)
+		require.NoError(t, f.SetSheetRow(name, "A1", &[]string{"Region", "Units"}))
+	}
+	require.NoError(t, f.ProtectSheet("Locked", &excelize.SheetProtectionOptions{Password: "secret"}))
+	require.NoError(t, f.SetSheetVisible("Archive", false))
+	path := filepath.Join(t.TempDir(), "protected.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestListStructure_ProtectionAndPanes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createProtectedWorkbook(t)
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path, "metadata_only": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out ListStructureOutput
+	decodeStructured(t, res, &out)
+	require.Len(t, out.Sheets, 3)
+	require.Equal(t, "B2", out.Sheets[0].FrozenPane)
+	require.False(t, out.Sheets[0].Protected)
+	require.True(t, out.Sheets[1].Protected)
+	require.False(t, out.Sheets[1].Hidden)
+	require.True(t, out.Sheets[2].Hidden)
+	require.False(t, out.Sheets[2].Protected)
+	text := resultText(t, res)
+	require.Contains(t, text, `"Sheet1" rows=0 cols=0 frozen=B2`)
+	require.Contains(t, text, `"Locked" rows=0 cols=0 protected`)
+	require.Contains(t, text, `"Archive" rows=0 cols=0 hidden`)
+}
+
+func TestWriteTools_RefuseProtectedSheet(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createProtectedWorkbook(t)
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+	}{
+		{"write_range", map[string]any{"path": path, "sheet": "Locked", "range": "A2:B2", "values": [][]string{{"North", "10"}}}},
+		{"apply_formula", map[string]any{"path": path, "sheet": "Locked", "range": "C2:C2", "formula": "=B2*2"}},
+	} {
+		res := callTool(t, srv, tc.tool, tc.args)
+		require.True(t, res.IsError, tc.tool)
+		require.Contains(t, resultText(t, res), `PERMISSION_DENIED: sheet "Locked" is protected`)
+
+		tc.args["force"] = true
+		res = callTool(t, srv, tc.tool, tc.args)
+		require.False(t, res.IsError, "%s: %s", tc.tool, resultText(t, res))
+	}
+
+	// Unprotected sheets, hidden or not, are written without force.
+	res := callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Archive", "range": "A2:B2", "values": [][]string{{"South", "5"}}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+}
+
+func TestListStructure_AccurateCounts(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"Region", "Units"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]string{"North", "10"}))
+	require.NoError(t, f.SetSheetDimension("Sheet1", "A1:Z500"))
+	_, err := f.NewSheet("Stray")
+	require.NoError(t, err)
+	// 120x100 populated cells exceed the default 10,000-cell scan cap.
+	row := make([]int, 100)
+	for r := 1; r <= 120; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, f.SetSheetRow("Stray", cell, &row))
+	}
+	path := filepath.Join(t.TempDir(), "counts.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path, "metadata_only": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out ListStructureOutput
+	decodeStructured(t, res, &out)
+	require.Zero(t, out.Sheets[0].ScannedRows, "scan is skipped by default")
+
+	res = callTool(t, srv, "list_structure", map[string]any{"path": path, "metadata_only": true, "accurate_counts": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = ListStructureOutput{}
+	decodeStructured(t, res, &out)
+	sh := out.Sheets[0]
+	require.Equal(t, 500, sh.RowCount)
+	require.Equal(t, 26, sh.ColumnCount)
+	require.Equal(t, 2, sh.ScannedRows)
+	require.Equal(t, 2, sh.ScannedColumns)
+	require.False(t, sh.ScanCapped)
+	require.True(t, sh.DimensionInflated)
+	require.Contains(t, resultText(t, res), "scanned=2x2 inflated")
+
+	stray := out.Sheets[1]
+	require.True(t, stray.ScanCapped)
+	require.False(t, stray.DimensionInflated, "capped scans are not compared")
+	require.Equal(t, 100, stray.ScannedRows, "scan stops after 100 full rows")
+	require.Equal(t, 100, stray.ScannedColumns)
+	require.Contains(t, resultText(t, res), "(capped)")
+}
+
+func TestReadRange_StaleWorkbook(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "stale.xlsx")
+	write := func(v string, mtime time.Time) {
+		f := excelize.NewFile()
+		require.NoError(t, f.SetCellValue("Sheet1", "A1", v))
+		require.NoError(t, f.SaveAs(path))
+		require.NoError(t, f.Close())
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	read := func() *mcp.CallToolResult {
+		return callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1"})
+	}
+	write("old", time.Now())
+	res := read()
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	// Default policy: the changed file is reopened transparently.
+	write("new", time.Now().Add(2*time.Second))
+	res = read()
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "new")
+
+	mgr.SetStalePolicy(workbooks.StaleError)
+	write("newer", time.Now().Add(4*time.Second))
+	res = read()
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "STALE_WORKBOOK")
+	res = read()
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "newer")
+}
+
+func TestCSVWorkbook_ReadOnlyTools(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(path, []byte("region,units\nNorth,10\nSouth,20\n"), 0o644))
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var ls ListStructureOutput
+	decodeStructured(t, res, &ls)
+	require.Len(t, ls.Sheets, 1)
+	require.Equal(t, "orders", ls.Sheets[0].Name)
+	require.Equal(t, 3, ls.Sheets[0].RowCount)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "orders", "range": "A1:B3"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "South")
+
+	res = callTool(t, srv, "compute_statistics", map[string]any{"path": path, "sheet": "orders", "range": "B2:B3"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	res = callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "orders", "range": "A4:B4", "values": [][]string{{"East", "5"}}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "UNSUPPORTED_FORMAT")
+}
+
+func TestWriteRange_ReadOnlyRootDenied(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	ro, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	rw, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	sec, err := security.NewManagerWithModes([]string{ro}, []string{rw}, nil)
+	require.NoError(t, err)
+	mgr.SetPathValidator(sec)
+
+	path := filepath.Join(ro, "book.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	res = callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "values": [][]string{{"x"}}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PERMISSION_DENIED")
+	require.Contains(t, resultText(t, res), ro)
+
+	res = callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "output_path": filepath.Join(ro, "out.csv")})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PERMISSION_DENIED")
+
+	res = callTool(t, srv, "export_range_csv", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "output_path": filepath.Join(rw, "out.csv")})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+}
+
+func TestListStructure_FileTooLarge(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	sec, err := security.NewManager([]string{dir}, nil)
+	require.NoError(t, err)
+	mgr.SetPathValidator(sec)
+
+	path := filepath.Join(dir, "book.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	sec.SetMaxFileBytes(16)
+
+	res := callTool(t, srv, "list_structure", map[string]any{"path": path})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "FILE_TOO_LARGE")
+	require.Zero(t, mgr.Count())
+}
+
+func TestWriteTools_AuditLog(t *testing.T) {
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	limits := runtime.NewLimits(8, 8)
+	RegisterFoundationTools(srv, reg, limits, mgr)
+	RegisterStructureTools(srv, reg, limits, mgr)
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.jsonl")
+	auditLog, err := audit.Open(logPath, true)
+	require.NoError(t, err)
+	reg.SetAuditLogger(auditLog)
+
+	path := filepath.Join(dir, "book.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "old"))
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	values := [][]string{{"new", "1"}}
+	res := callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B1", "values": values})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	res = callTool(t, srv, "add_sheet", map[string]any{"path": path, "name": "Extra"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var rec audit.Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	require.Equal(t, "write_range", rec.Tool)
+	require.Equal(t, "Sheet1", rec.Sheet)
+	require.Equal(t, "A1:B1", rec.Range)
+	require.Equal(t, 2, rec.Cells)
+	require.Equal(t, audit.HashValues(values), rec.ContentHash)
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &rec))
+	require.Equal(t, "add_sheet", rec.Tool)
+	require.Equal(t, "Extra", rec.Sheet)
+
+	// Strict mode: an unwritable audit log blocks the write and discards the
+	// in-memory edit.
+	require.NoError(t, auditLog.Close())
+	res = callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "values": [][]string{{"unaudited"}}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "AUDIT_FAILED")
+	require.Zero(t, mgr.Count())
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "new")
+	require.NotContains(t, resultText(t, res), "unaudited")
+}
+
+func TestWriteTools_FailedWriteKeepsDeferredChanges(t *testing.T) {
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	mgr.SetSaveDelay(time.Hour)
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	limits := runtime.NewLimits(8, 8)
+	RegisterFoundationTools(srv, reg, limits, mgr)
+	RegisterWorkbookTools(srv, reg, limits, mgr)
+
+	dir := t.TempDir()
+	auditLog, err := audit.Open(filepath.Join(dir, "audit.jsonl"), true)
+	require.NoError(t, err)
+	reg.SetAuditLogger(auditLog)
+	path := filepath.Join(dir, "book.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "values": [][]string{{"first"}}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "save=deferred")
+
+	// Discarding the failed write's edits would lose the deferred one, so
+	// the handle stays open and the error says so.
+	require.NoError(t, auditLog.Close())
+	res = callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "B1:B1", "values": [][]string{{"second"}}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "AUDIT_FAILED")
+	require.Contains(t, resultText(t, res), "earlier deferred changes")
+	require.Equal(t, 1, mgr.Count())
+
+	res = callTool(t, srv, "flush_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	disk, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer func() { _ = disk.Close() }()
+	v, err := disk.GetCellValue("Sheet1", "A1")
+	require.NoError(t, err)
+	require.Equal(t, "first", v)
+}
+
+func TestWriteRange_LeavesOtherCells(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "keep"))
+	require.NoError(t, f.SetCellValue("Sheet1", "C5", 7))
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "B2:B3", "values": [][]string{{"x"}, {"y"}}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	saved, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer saved.Close()
+	for cell, want := range map[string]string{"A1": "keep", "B2": "x", "B3": "y", "C5": "7"} {
+		got, err := saved.GetCellValue("Sheet1", cell)
+		require.NoError(t, err)
+		require.Equal(t, want, got, cell)
+	}
+}
+
+// TestWriteRange_ConcurrentFormulaCells overwrites formula cells on two sheets
+// while formulas are applied next to them. Replacing a formula edits the
+// workbook-wide calc chain, so run with -race to check those writes are
+// serialized.
+func TestWriteRange_ConcurrentFormulaCells(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "formulas.xlsx")
+	sheets := []string{"Sheet1", "Sheet2"}
+	f := excelize.NewFile()
+	_, err := f.NewSheet("Sheet2")
+	require.NoError(t, err)
+	const n = 20
+	for _, sheet := range sheets {
+		for row := 1; row <= n; row++ {
+			require.NoError(t, f.SetCellFormula(sheet, fmt.Sprintf("A%d", row), "B1+1"))
+		}
+	}
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	id, _, err := mgr.GetOrOpenWithOptions(context.Background(), path, workbooks.OpenOptions{})
+	require.NoError(t, err)
+
+	call := func(name string, args map[string]any) mcp.JSONRPCMessage {
+		msg, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": name, "arguments": args},
+		})
+		return srv.HandleMessage(context.Background(), msg)
+	}
+	var wg sync.WaitGroup
+	resps := make(chan mcp.JSONRPCMessage, 2*len(sheets)*n)
+	for _, sheet := range sheets {
+		for row := 1; row <= n; row++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				resps <- call("write_range", map[string]any{"path": path, "sheet": sheet, "range": fmt.Sprintf("A%d:A%d", row, row), "values": [][]string{{"v"}}})
+			}()
+			go func() {
+				defer wg.Done()
+				resps <- call("apply_formula", map[string]any{"path": path, "sheet": sheet, "range": fmt.Sprintf("C%d:C%d", row, row), "formula": "=A1"})
+			}()
+		}
+	}
+	wg.Wait()
+	close(resps)
+	for resp := range resps {
+		rpc, ok := resp.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", resp)
+		res := rpc.Result.(mcp.CallToolResult)
+		require.False(t, res.IsError, "%s", resultText(t, &res))
+	}
+
+	require.NoError(t, mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+		for _, sheet := range sheets {
+			for row := 1; row <= n; row++ {
+				cell := fmt.Sprintf("A%d", row)
+				fx, err := f.GetCellFormula(sheet, cell)
+				require.NoError(t, err)
+				require.Empty(t, fx, "%s!%s", sheet, cell)
+				v, err := f.GetCellValue(sheet, cell)
+				require.NoError(t, err)
+				require.Equal(t, "v", v, "%s!%s", sheet, cell)
+			}
+		}
+		return nil
+	}))
+}
+
+func TestGetLimits(t *testing.T) {
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	limits := runtime.NewLimits(3, 5)
+	limits.MaxCellsPerOp = 1234
+	RegisterFoundationTools(srv, reg, limits, mgr)
+
+	ro, rw := t.TempDir(), t.TempDir()
+	sec, err := security.NewManagerWithModes([]string{ro}, []string{rw}, nil)
+	require.NoError(t, err)
+	reg.SetAllowList(sec)
+	reg.SetWriteFilter(&WriteToolFilter{})
+
+	res := callTool(t, srv, "get_limits", map[string]any{})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out GetLimitsOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, 3, out.MaxConcurrentRequests)
+	require.Equal(t, 5, out.MaxOpenWorkbooks)
+	require.Equal(t, 1234, out.MaxCellsPerOp)
+	require.Equal(t, limits.OperationTimeout.Milliseconds(), out.OperationTimeoutMs)
+	require.False(t, out.WritesEnabled)
+	require.Len(t, out.AllowedDirectories, 2)
+	require.Equal(t, sec.WritableDirectories(), out.WritableDirectories)
+	require.Empty(t, out.AllowedPatterns)
+	require.Contains(t, resultText(t, res), "maxCellsPerOp=1234")
+}
+
+func TestPreviewSheet_ExpiredCursor(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{"h"}))
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	old := time.Now().Add(-config.DefaultCursorTTL - time.Minute)
+	tok, err := pagination.EncodeCursor(pagination.Cursor{Pt: path, S: "Sheet1", R: "A1:A1", U: pagination.UnitRows, Ps: 10, Iat: old.Unix()})
+	require.NoError(t, err)
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "cursor": tok})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_EXPIRED")
+}
+
+func TestReadRange_CursorSurvivesTouchButNotEdit(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	issued := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write := func(v string, mtime time.Time) {
+		f := excelize.NewFile()
+		require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]string{v, "b", "c", "d"}))
+		require.NoError(t, f.SaveAs(path))
+		require.NoError(t, f.Close())
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	write("a", issued)
+
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:D1", "max_cells": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out ReadRangeOutput
+	decodeStructured(t, res, &out)
+	require.NotEmpty(t, out.Meta.NextCursor)
+
+	// A touch (new mtime, same bytes) keeps the cursor usable.
+	later := issued.Add(30 * time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "c")
+
+	// Changed contents are rejected even when the mtime is restored.
+	write("z", issued)
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "file changed since cursor was issued")
+}
+
+func TestCursor_RejectedAfterInProcessWrite(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	path := createSalesWorkbook(t, 10)
+	res := callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B11", "max_cells": 4})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out ReadRangeOutput
+	decodeStructured(t, res, &out)
+	require.NotEmpty(t, out.Meta.NextCursor)
+
+	res = callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "D1:D1", "values": [][]string{{"note"}}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), msgCursorWritten)
+
+	// Deferred saves leave the file untouched; the version still catches them.
+	mgr.SetSaveDelay(time.Hour)
+	path = createSalesWorkbook(t, 10)
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "rows": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var prev PreviewSheetOutput
+	decodeStructured(t, res, &prev)
+	require.NotEmpty(t, prev.Meta.NextCursor)
+	res = callTool(t, srv, "insert_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 2, "count": 1})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "save=deferred")
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": prev.Meta.NextCursor})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), msgCursorWritten)
+}
+
+func TestPreviewSheet_StructuredError(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 2)
+
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Missing"})
+	require.True(t, res.IsError)
+	require.True(t, strings.HasPrefix(resultText(t, res), "INVALID_SHEET: sheet not found | nextSteps: "))
+	var out mcperr.ErrorOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, "INVALID_SHEET", out.Code)
+	require.Equal(t, "sheet not found", out.Message)
+	require.True(t, out.Retryable)
+	require.NotEmpty(t, out.NextSteps)
+}
+
+func TestComputeStatistics_DateColumns(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]any{"Ordered", "Shipped", "Units", "Region"}))
+	rows := [][]any{
+		{time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), "2024-01-20", 10, "North"},
+		{time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), "2024-02-02", 20, "North"},
+		{time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), "3/12/2024", 30, "South"},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "dates.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	type stats struct {
+		Count int        `json:"count"`
+		Sum   float64    `json:"sum"`
+		Dates *DateStats `json:"dates"`
+	}
+	var out struct {
+		Columns []stats            `json:"columns"`
+		Groups  map[string][]stats `json:"groups"`
+	}
+	res := callTool(t, srv, "compute_statistics", map[string]any{"path": path, "sheet": sh, "range": "A2:C4"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Len(t, out.Columns, 3)
+	require.Equal(t, 0, out.Columns[0].Count)
+	require.Equal(t, &DateStats{
+		Count: 3, Earliest: "2024-01-15", Latest: "2024-03-10", SpanDays: 55, Granularity: "month",
+		Periods: []DatePeriod{{"2024-01", 2}, {"2024-03", 1}},
+	}, out.Columns[0].Dates)
+	require.Equal(t, &DateStats{
+		Count: 3, Earliest: "2024-01-20", Latest: "2024-03-12", SpanDays: 52, Granularity: "month",
+		Periods: []DatePeriod{{"2024-01", 1}, {"2024-02", 1}, {"2024-03", 1}},
+	}, out.Columns[1].Dates)
+	require.Nil(t, out.Columns[2].Dates)
+	require.Equal(t, 60.0, out.Columns[2].Sum)
+
+	res = callTool(t, srv, "compute_statistics", map[string]any{"path": path, "sheet": sh, "range": "A2:D4", "columns": []int{1}, "group_by_index": 4})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, "2024-01-31", out.Groups["North"][0].Dates.Latest)
+	require.Equal(t, []DatePeriod{{"2024-03", 1}}, out.Groups["South"][0].Dates.Periods)
+}
+
+func TestDateStats_FallsBackToYears(t *testing.T) {
+	var d DateStats
+	start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	for m := 0; m <= maxDatePeriods; m++ {
+		d.observe(start.AddDate(0, m, 0))
+	}
+	d.finish()
+	require.Equal(t, "year", d.Granularity)
+	require.Len(t, d.Periods, 11)
+	require.Equal(t, DatePeriod{Period: "2010", Count: 12}, d.Periods[0])
+	require.Equal(t, "2020-01-01", d.Latest)
+}
+
+func TestComputeStatistics_BlankCoverage(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	rows := [][]any{
+		{10, "n/a"},
+		{nil, "x"},
+		{20, 5},
+		{nil, nil},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow(sh, cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "blanks.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	type stats struct {
+		Count      int     `json:"count"`
+		Blank      int     `json:"blank"`
+		NonNumeric int     `json:"nonNumeric"`
+		Average    float64 `json:"average"`
+		Min        float64 `json:"min"`
+	}
+	var out struct {
+		Columns []stats `json:"columns"`
+	}
+	res := callTool(t, srv, "compute_statistics", map[string]any{"path": path, "sheet": sh, "range": "A1:B4"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, stats{Count: 2, Blank: 2, Average: 15, Min: 10}, out.Columns[0])
+	require.Equal(t, stats{Count: 1, Blank: 1, NonNumeric: 2, Average: 5, Min: 5}, out.Columns[1])
+	summary, _ := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "lowNumericCoverage=[2]")
+
+	res = callTool(t, srv, "compute_statistics", map[string]any{"path": path, "sheet": sh, "range": "A1:B4", "treat_blank_as_zero": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, stats{Count: 4, Blank: 2, Average: 7.5}, out.Columns[0])
+	require.Equal(t, stats{Count: 2, Blank: 1, NonNumeric: 2, Average: 2.5}, out.Columns[1])
+	summary, _ = splitSummary(t, resultText(t, res))
+	require.NotContains(t, summary, "lowNumericCoverage")
+}
+
+func TestIncludeHeader(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createSalesWorkbook(t, 5)
+	header := []string{"Region", "Amount"}
+
+	// preview_sheet pins row 1 and starts the data below it on every page.
+	res := callTool(t, srv, "preview_sheet", map[string]any{"path": path, "sheet": "Sheet1", "rows": 2, "include_header": true, "encoding": "csv"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(PreviewSheetOutput)
+	require.Equal(t, 5, out.Meta.Total)
+	require.Equal(t, 2, out.Meta.Returned)
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, "Region,Amount\nNorth,0\nNorth,10\n", body)
+	res = callTool(t, srv, "preview_sheet", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, "Region,Amount\nNorth,20\nNorth,30\n", body)
+
+	// read_range pins the range's first row; only data cells count.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B6", "include_header": true, "max_cells": 5})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	rr := res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, header, rr.Header)
+	require.Equal(t, 10, rr.Meta.Total)
+	require.Equal(t, 4, rr.Meta.Returned)
+	summary, body := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "headerRow=1")
+	require.Equal(t, `[["Region","Amount"],["North","0"],["North","10"]]`, body)
+
+	// Resumed pages repeat the header, in csv too when the cursor says so.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "cursor": rr.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	rr = res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, 4, rr.Meta.Returned)
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, `[["Region","Amount"],["North","20"],["North","30"]]`, body)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A4:B6", "header_row": 1, "include_header": true, "encoding": "csv"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.Equal(t, "Region,Amount\nNorth,20\nNorth,30\nNorth,40\n", body)
+
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B6", "include_header": true, "encoding": "records"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: include_header applies")
+}
@@ -0,0 +1,340 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+const (
+	defaultHistogramBins    = 10
+	defaultHistogramMaxBins = 50
+)
+
+// HistogramInput defines parameters for histogram. At most one of bins,
+// bin_width, and edges selects the bin strategy; bins=10 is the default.
+type HistogramInput struct {
+	Path     string    `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string    `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet    string    `json:"sheet" validate:"required" jsonschema_description:"Sheet to scan"`
+	RangeA1  string    `json:"range,omitempty" validate:"omitempty,a1orname" jsonschema_description:"Optional A1 range or defined name; omitted means the sheet's used range"`
+	Column   ColumnRef `json:"column" jsonschema_description:"1‑based column index within the range, or a header name matched case‑insensitively in the range's first row (implies header=true)"`
+	Header   bool      `json:"header,omitempty" jsonschema_description:"Treat the first row of the range as a header and skip it"`
+	Bins     int       `json:"bins,omitempty" validate:"omitempty,min=1" jsonschema_description:"Fixed bin count spanning min..max (default 10)"`
+	BinWidth float64   `json:"bin_width,omitempty" validate:"omitempty,gt=0" jsonschema_description:"Fixed bin width; bins start at min (default: the data minimum rounded down to a multiple of the width)"`
+	Edges    []float64 `json:"edges,omitempty" validate:"omitempty,min=2" jsonschema_description:"Explicit strictly increasing bin edges; values outside go to underflow/overflow"`
+	Min      *float64  `json:"min,omitempty" jsonschema_description:"Lower bound for bins/bin_width; smaller values count as underflow"`
+	Max      *float64  `json:"max,omitempty" jsonschema_description:"Upper bound for bins/bin_width; larger values count as overflow"`
+	MaxBins  int       `json:"max_bins,omitempty" validate:"omitempty,min=1,max=500" jsonschema_description:"Max bins to report (default 50); bin_width bins past the cap count as overflow"`
+}
+
+// HistogramBin counts values in [lower, upper); the last bin includes upper.
+type HistogramBin struct {
+	Lower   float64 `json:"lower"`
+	Upper   float64 `json:"upper"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent" jsonschema_description:"Share of numeric values, 0–100"`
+}
+
+// HistogramOutput reports binned counts for one numeric column.
+type HistogramOutput struct {
+	Path       string         `json:"path"`
+	Sheet      string         `json:"sheet"`
+	RangeA1    string         `json:"range"`
+	Column     int            `json:"column" jsonschema_description:"1‑based column index within the range"`
+	Header     string         `json:"header,omitempty"`
+	Bins       []HistogramBin `json:"bins"`
+	Underflow  int            `json:"underflow"`
+	Overflow   int            `json:"overflow"`
+	Numeric    int            `json:"numeric"`
+	NonNumeric int            `json:"nonNumeric" jsonschema_description:"Non-empty cells that are not numbers; not binned"`
+	Blank      int            `json:"blank"`
+	Min        float64        `json:"min"`
+	Max        float64        `json:"max"`
+	// BinsCapped reports that bin_width needed more than max_bins bins.
+	BinsCapped    bool `json:"binsCapped,omitempty" jsonschema_description:"bin_width needed more than max_bins bins; the rest count as overflow"`
+	ScanTruncated bool `json:"scanTruncated,omitempty" jsonschema_description:"The range exceeded the per-operation cell limit; later rows were not scanned"`
+}
+
+// RegisterHistogramTools registers histogram.
+func RegisterHistogramTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"histogram",
+		mcp.WithDescription(fmt.Sprintf("Bin one numeric column into counts to answer distribution-shape questions (skew, bimodality, outliers). Name the column by 1‑based index within the range or by header. Choose one strategy: bins (fixed count over min..max, default 10), bin_width (fixed width), or edges (explicit boundaries); min/max bound the first two. Bins are [lower, upper) with the last including its upper edge; values outside land in underflow/overflow. Percentages are of numeric values; non-numeric and blank cells are counted separately. max_bins caps the bins (default %d). At most %d rows are scanned in one pass; scanTruncated reports when the range was larger. Errors: VALIDATION, INVALID_SHEET, ANALYSIS_FAILED.", defaultHistogramMaxBins, limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[HistogramInput](),
+		mcp.WithOutputSchema[HistogramOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in HistogramInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if in.Column.Index == 0 && in.Column.Name == "" {
+			return mcperr.FromText("VALIDATION: column is required"), nil
+		}
+		maxBins := in.MaxBins
+		if maxBins <= 0 {
+			maxBins = defaultHistogramMaxBins
+		}
+		if msg := checkHistogramBins(in, maxBins); msg != "" {
+			return mcperr.FromText("VALIDATION: " + msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		header := in.Header || in.Column.Name != ""
+
+		out := HistogramOutput{Path: canonical, Sheet: sheet, Bins: []HistogramBin{}}
+		reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+			if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			rng := strings.TrimSpace(in.RangeA1)
+			if rng == "" {
+				rng, _ = scanUsedRange(f, sheet)
+			}
+			if rng == "" {
+				return mcperr.Errorf(mcperr.Validation, "sheet is empty")
+			}
+			x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+			}
+			out.RangeA1 = resolved
+			colCount := x2 - x1 + 1
+			col := in.Column.Index
+
+			rowsIter, rerr := f.Rows(sheet)
+			if rerr != nil {
+				return rerr
+			}
+			defer rowsIter.Close()
+			var values []float64
+			rowIdx := 0
+			for rowsIter.Next() {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				rowIdx++
+				if rowIdx < y1 {
+					continue
+				}
+				if rowIdx > y2 {
+					break
+				}
+				vals, cerr := rowsIter.Columns()
+				if cerr != nil {
+					return cerr
+				}
+				if header && rowIdx == y1 {
+					cols, rerr := resolveColumnRefs([]ColumnRef{in.Column}, columnWindow(vals, x1, x2), y1)
+					if rerr != nil {
+						return rerr
+					}
+					col = cols[0]
+					if col <= colCount && x1+col-2 < len(vals) {
+						out.Header = vals[x1+col-2]
+					}
+					continue
+				}
+				if col < 1 || col > colCount {
+					return mcperr.Errorf(mcperr.Validation, "column %d outside range (%d columns)", col, colCount)
+				}
+				if out.Numeric+out.NonNumeric+out.Blank >= limits.MaxCellsPerOp {
+					out.ScanTruncated = true
+					break
+				}
+				val := ""
+				if x1+col-2 < len(vals) {
+					val = strings.TrimSpace(vals[x1+col-2])
+				}
+				if val == "" {
+					out.Blank++
+					continue
+				}
+				v, ok := parseNumber(val)
+				if !ok || math.IsInf(v, 0) || math.IsNaN(v) {
+					out.NonNumeric++
+					continue
+				}
+				out.Numeric++
+				values = append(values, v)
+			}
+			if header && rowIdx < y1 {
+				return mcperr.Errorf(mcperr.Validation, "range has no header row to match column")
+			}
+			// Rows past the last stored row are blank too.
+			if !out.ScanTruncated && rowIdx < y2 {
+				out.Blank += y2 - max(rowIdx, y1-1)
+			}
+			if col < 1 || col > colCount {
+				return mcperr.Errorf(mcperr.Validation, "column %d outside range (%d columns)", col, colCount)
+			}
+			out.Column = col
+			binValues(&out, values, in, maxBins)
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.AnalysisFailed, "%v", err), nil
+		}
+		runtime.CallStatsFrom(ctx).SetResult(len(out.Bins), out.ScanTruncated)
+
+		summary := fmt.Sprintf("histogram: column=%d bins=%d numeric=%d nonNumeric=%d blank=%d underflow=%d overflow=%d truncated=%v", out.Column, len(out.Bins), out.Numeric, out.NonNumeric, out.Blank, out.Underflow, out.Overflow, out.ScanTruncated)
+		lines := []string{summary}
+		if len(out.Bins) > 0 {
+			if out.Underflow > 0 {
+				lines = append(lines, fmt.Sprintf("< %s → %d", compactNumber(out.Bins[0].Lower), out.Underflow))
+			}
+			for i, b := range out.Bins {
+				closing := ")"
+				if i == len(out.Bins)-1 {
+					closing = "]"
+				}
+				lines = append(lines, fmt.Sprintf("[%s, %s%s → %d (%s%%)", compactNumber(b.Lower), compactNumber(b.Upper), closing, b.Count, compactNumber(b.Percent)))
+			}
+			if out.Overflow > 0 {
+				lines = append(lines, fmt.Sprintf("> %s → %d", compactNumber(out.Bins[len(out.Bins)-1].Upper), out.Overflow))
+			}
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(tool)
+}
+
+// checkHistogramBins validates the bin strategy against maxBins and returns a
+// message, or "" when the inputs are usable.
+func checkHistogramBins(in HistogramInput, maxBins int) string {
+	strategies := 0
+	for _, set := range []bool{in.Bins > 0, in.BinWidth > 0, len(in.Edges) > 0} {
+		if set {
+			strategies++
+		}
+	}
+	if strategies > 1 {
+		return "use one of bins, bin_width, or edges"
+	}
+	if in.Bins > maxBins {
+		return fmt.Sprintf("bins %d exceeds max_bins %d", in.Bins, maxBins)
+	}
+	if len(in.Edges) > 0 {
+		if len(in.Edges)-1 > maxBins {
+			return fmt.Sprintf("edges define %d bins; max_bins is %d", len(in.Edges)-1, maxBins)
+		}
+		if in.Min != nil || in.Max != nil {
+			return "min and max apply to bins and bin_width; edges already bound the bins"
+		}
+		for i := 1; i < len(in.Edges); i++ {
+			if !(in.Edges[i] > in.Edges[i-1]) {
+				return "edges must be strictly increasing"
+			}
+		}
+	}
+	if in.Min != nil && in.Max != nil && *in.Max < *in.Min {
+		return "max must not be less than min"
+	}
+	return ""
+}
+
+// binValues fills out's bins, underflow, overflow, and min/max from values.
+func binValues(out *HistogramOutput, values []float64, in HistogramInput, maxBins int) {
+	if len(values) == 0 {
+		return
+	}
+	out.Min, out.Max = values[0], values[0]
+	for _, v := range values {
+		out.Min = math.Min(out.Min, v)
+		out.Max = math.Max(out.Max, v)
+	}
+
+	edges := in.Edges
+	if len(edges) == 0 {
+		lo, hi := out.Min, out.Max
+		if in.Min != nil {
+			lo = *in.Min
+		}
+		if in.Max != nil {
+			hi = *in.Max
+		}
+		if in.BinWidth > 0 {
+			if in.Min == nil {
+				lo = math.Floor(lo/in.BinWidth) * in.BinWidth
+			}
+			n := int(math.Floor((hi-lo)/in.BinWidth)) + 1
+			if in.Max != nil && lo+float64(n-1)*in.BinWidth >= hi && n > 1 {
+				n-- // an explicit max is the closing edge, not the start of a bin
+			}
+			if n > maxBins {
+				n, out.BinsCapped = maxBins, true
+			}
+			edges = make([]float64, n+1)
+			for i := range edges {
+				edges[i] = lo + float64(i)*in.BinWidth
+			}
+			if in.Max != nil && !out.BinsCapped {
+				edges[n] = hi
+			}
+		} else {
+			n := in.Bins
+			if n <= 0 {
+				n = min(defaultHistogramBins, maxBins)
+			}
+			if hi <= lo {
+				n = 1
+			}
+			edges = make([]float64, n+1)
+			for i := range edges {
+				edges[i] = lo + (hi-lo)*float64(i)/float64(n)
+			}
+			edges[n] = hi
+		}
+	}
+
+	last := len(edges) - 1
+	counts := make([]int, last)
+	for _, v := range values {
+		switch {
+		case v < edges[0]:
+			out.Underflow++
+		case v > edges[last] || (v == edges[last] && out.BinsCapped):
+			out.Overflow++
+		case v == edges[last]:
+			counts[last-1]++
+		default:
+			// The first edge strictly above v closes v's bin.
+			counts[sort.Search(last, func(i int) bool { return edges[i+1] > v })]++
+		}
+	}
+	out.Bins = make([]HistogramBin, last)
+	for i := range out.Bins {
+		pct := float64(counts[i]) * 100 / float64(len(values))
+		out.Bins[i] = HistogramBin{Lower: edges[i], Upper: edges[i+1], Count: counts[i], Percent: math.Round(pct*100) / 100}
+	}
+}
+
+// compactNumber formats v compactly for text renderings.
+func compactNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', 10, 64)
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func createHistogramWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	rows := [][]any{
+		{"Region", "Revenue"},
+		{"North", 1},
+		{"North", 2},
+		{"South", 5},
+		{"South", "n/a"},
+		{"East", nil},
+		{"East", 9},
+		{"West", 10},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "hist.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestHistogram(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createHistogramWorkbook(t)
+
+	// Fixed count over the data range: the maximum lands in the closed last bin.
+	res := callTool(t, srv, "histogram", map[string]any{"path": path, "sheet": "Sheet1", "column": "revenue", "bins": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out HistogramOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, "A1:B8", out.RangeA1)
+	require.Equal(t, 2, out.Column)
+	require.Equal(t, "Revenue", out.Header)
+	require.Equal(t, 5, out.Numeric)
+	require.Equal(t, 1, out.NonNumeric)
+	require.Equal(t, 1, out.Blank)
+	require.Equal(t, 1.0, out.Min)
+	require.Equal(t, 10.0, out.Max)
+	require.Equal(t, []HistogramBin{
+		{Lower: 1, Upper: 4, Count: 2, Percent: 40},
+		{Lower: 4, Upper: 7, Count: 1, Percent: 20},
+		{Lower: 7, Upper: 10, Count: 2, Percent: 40},
+	}, out.Bins)
+	summary, body := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "bins=3 numeric=5 nonNumeric=1 blank=1")
+	require.Equal(t, "[1, 4) → 2 (40%)\n[4, 7) → 1 (20%)\n[7, 10] → 2 (40%)", body)
+
+	// Explicit edges put values outside into underflow/overflow.
+	res = callTool(t, srv, "histogram", map[string]any{"path": path, "sheet": "Sheet1", "range": "B2:B8", "column": 1, "edges": []float64{2, 5, 9}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = HistogramOutput{}
+	decodeStructured(t, res, &out)
+	require.Equal(t, 1, out.Underflow)
+	require.Equal(t, 1, out.Overflow)
+	require.Equal(t, []int{1, 2}, []int{out.Bins[0].Count, out.Bins[1].Count})
+	_, body = splitSummary(t, resultText(t, res))
+	require.True(t, strings.HasPrefix(body, "< 2 → 1\n"), body)
+	require.True(t, strings.HasSuffix(body, "\n> 9 → 1"), body)
+
+	// Fixed width starts at a multiple of the width; the cap spills into overflow.
+	res = callTool(t, srv, "histogram", map[string]any{"path": path, "sheet": "Sheet1", "column": "Revenue", "bin_width": 2, "max_bins": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = HistogramOutput{}
+	decodeStructured(t, res, &out)
+	require.True(t, out.BinsCapped)
+	require.Len(t, out.Bins, 3)
+	require.Equal(t, 0.0, out.Bins[0].Lower)
+	require.Equal(t, 6.0, out.Bins[2].Upper)
+	require.Equal(t, 2, out.Overflow)
+
+	for _, args := range []map[string]any{
+		{"bins": 3, "bin_width": 2},
+		{"edges": []float64{3, 1}},
+		{"bins": 60},
+		{"edges": []float64{1, 2}, "min": 0},
+	} {
+		args["path"], args["sheet"], args["column"] = path, "Sheet1", 2
+		res = callTool(t, srv, "histogram", args)
+		require.True(t, res.IsError, "%v", args)
+		require.Contains(t, resultText(t, res), "VALIDATION")
+	}
+	res = callTool(t, srv, "histogram", map[string]any{"path": path, "sheet": "Sheet1", "column": "Profit"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "not found in header row 1")
+}
@@ -0,0 +1,349 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+// maxMissedKeys caps the sample of unmatched base keys reported.
+const maxMissedKeys = 10
+
+// ColumnLookupInput defines parameters for column_lookup.
+type ColumnLookupInput struct {
+	Path              string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password          string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet             string `json:"sheet" validate:"required_without=Cursor" jsonschema_description:"Sheet holding the base range"`
+	RangeA1           string `json:"range" validate:"required_without=Cursor,omitempty,a1orname" jsonschema_description:"Base A1 range or defined name whose rows are returned with the looked-up value appended"`
+	KeyColumn         int    `json:"key_column" validate:"required_without=Cursor,omitempty,min=1" jsonschema_description:"1‑based column within the base range holding the join key"`
+	LookupSheet       string `json:"lookup_sheet,omitempty" jsonschema_description:"Sheet holding the lookup range (default: sheet)"`
+	LookupRange       string `json:"lookup_range" validate:"required_without=Cursor,omitempty,a1orname" jsonschema_description:"Lookup A1 range or defined name (the mapping table)"`
+	LookupKeyColumn   int    `json:"lookup_key_column" validate:"required_without=Cursor,omitempty,min=1" jsonschema_description:"1‑based column within the lookup range holding the key"`
+	LookupValueColumn int    `json:"lookup_value_column" validate:"required_without=Cursor,omitempty,min=1" jsonschema_description:"1‑based column within the lookup range holding the value to append"`
+	Header            bool   `json:"header,omitempty" jsonschema_description:"Treat the first row of both ranges as headers: skip them as data and name the joined column after the lookup value header"`
+	CaseInsensitive   bool   `json:"case_insensitive,omitempty" jsonschema_description:"Compare keys ignoring case"`
+	Trim              bool   `json:"trim,omitempty" jsonschema_description:"Trim surrounding whitespace from keys before comparing"`
+	MaxRows           int    `json:"max_rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max base rows per page (unit=rows, default 200); also bounded by the per-operation cell limit"`
+	Cursor            string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor (unit=rows) from a previous page; carries both ranges, key columns, and options"`
+}
+
+// LookupRow is one base row with the joined value as its last cell.
+type LookupRow struct {
+	Row     int      `json:"row"`
+	Values  []string `json:"values"`
+	Matched bool     `json:"matched"`
+}
+
+// ColumnLookupOutput returns one page of joined base rows.
+type ColumnLookupOutput struct {
+	Path        string      `json:"path"`
+	Sheet       string      `json:"sheet"`
+	RangeA1     string      `json:"range"`
+	LookupSheet string      `json:"lookupSheet"`
+	LookupRange string      `json:"lookupRange"`
+	Header      []string    `json:"header,omitempty" jsonschema_description:"Base header followed by the lookup value header (header=true)"`
+	Rows        []LookupRow `json:"rows"`
+	Stats       struct {
+		Rows       int      `json:"rows" jsonschema_description:"Base data rows in the range"`
+		Matched    int      `json:"matched"`
+		Misses     int      `json:"misses" jsonschema_description:"Base rows whose non-empty key is absent from the lookup range"`
+		BlankKeys  int      `json:"blankKeys" jsonschema_description:"Base rows with an empty key; never matched"`
+		MissedKeys []string `json:"missedKeys,omitempty" jsonschema_description:"First distinct missed keys, up to 10"`
+		LookupKeys int      `json:"lookupKeys" jsonschema_description:"Distinct keys in the lookup range"`
+		Collisions int      `json:"collisions" jsonschema_description:"Lookup rows whose key repeats an earlier row; the first value wins"`
+	} `json:"stats"`
+	Meta PageMeta `json:"meta"`
+}
+
+// lookupOptions are the key settings carried in a column_lookup cursor.
+type lookupOptions struct {
+	caseInsensitive, trim, header bool
+}
+
+func (o lookupOptions) String() string {
+	b := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return fmt.Sprintf("c=%d;t=%d;h=%d", b(o.caseInsensitive), b(o.trim), b(o.header))
+}
+
+func parseLookupOptions(s string) (lookupOptions, error) {
+	var o lookupOptions
+	var c, t, h int
+	if _, err := fmt.Sscanf(s, "c=%d;t=%d;h=%d", &c, &t, &h); err != nil {
+		return o, fmt.Errorf("invalid lookup options %q", s)
+	}
+	o.caseInsensitive, o.trim, o.header = c == 1, t == 1, h == 1
+	return o, nil
+}
+
+// normalize applies the trim and case options to a key.
+func (o lookupOptions) normalize(key string) string {
+	if o.trim {
+		key = strings.TrimSpace(key)
+	}
+	if o.caseInsensitive {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// lookupSpec is everything a column_lookup cursor binds to besides the base
+// sheet and range.
+type lookupSpec struct {
+	sheet, rng string
+	cols       []int // base key, lookup key, lookup value
+	opts       lookupOptions
+}
+
+func (l lookupSpec) hash() string {
+	return computePredicateHash(fmt.Sprintf("%s|%s|%v|%s", l.sheet, l.rng, l.cols, l.opts), nil)
+}
+
+// RegisterLookupTools registers column_lookup.
+func RegisterLookupTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"column_lookup",
+		mcp.WithDescription(fmt.Sprintf("Join a value from a lookup table onto the rows of a base range, like VLOOKUP with exact match, without reading both ranges into the conversation. Give the base range and its 1‑based key_column, and a lookup_range (on lookup_sheet, default the same sheet) with lookup_key_column and lookup_value_column. The lookup range is streamed into a map of at most %[1]d distinct keys (more fails with LIMIT_EXCEEDED; narrow lookup_range), then the base range is streamed and each row is returned with the matched value appended (empty on a miss). Duplicate lookup keys keep the first value and are counted as collisions; misses, blank keys, and a sample of missed keys are reported for the whole base range. header=true skips the first row of both ranges and names the joined column after the lookup value header; case_insensitive and trim normalize keys on both sides. Pagination operates in base rows (unit=rows, max_rows per page and at most %[1]d cells); the cursor binds to path+content fingerprint and both ranges and can be sent alone to resume. Errors: VALIDATION, INVALID_SHEET, LIMIT_EXCEEDED, CURSOR_INVALID, CURSOR_EXPIRED, ANALYSIS_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[ColumnLookupInput](),
+		mcp.WithOutputSchema[ColumnLookupOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ColumnLookupInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		maxRows := pagination.PageSize(in.MaxRows, 0, 200, 1000)
+		sheet := strings.TrimSpace(in.Sheet)
+		rng := strings.TrimSpace(in.RangeA1)
+		spec := lookupSpec{
+			sheet: strings.TrimSpace(in.LookupSheet),
+			rng:   strings.TrimSpace(in.LookupRange),
+			cols:  []int{in.KeyColumn, in.LookupKeyColumn, in.LookupValueColumn},
+			opts:  lookupOptions{caseInsensitive: in.CaseInsensitive, trim: in.Trim, header: in.Header},
+		}
+		if spec.sheet == "" {
+			spec.sheet = sheet
+		}
+
+		var startOffset int
+		var parsedCur *pagination.Cursor
+		if curTok := strings.TrimSpace(in.Cursor); curTok != "" {
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
+			}
+			if pc.Pt != canonical {
+				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+			}
+			if pc.U != pagination.UnitRows || pc.Lr == "" || len(pc.Cl) != 3 {
+				return mcperr.FromText("CURSOR_INVALID: cursor was not issued by column_lookup"), nil
+			}
+			curOpts, perr := parseLookupOptions(pc.Dk)
+			if perr != nil {
+				return mcperr.FromText("CURSOR_INVALID: " + perr.Error()), nil
+			}
+			curSpec := lookupSpec{sheet: pc.Os, rng: pc.Lr, cols: pc.Cl, opts: curOpts}
+			if spec.rng != "" && spec.hash() != curSpec.hash() {
+				return mcperr.FromText("CURSOR_INVALID: cursor parameters do not match the current lookup"), nil
+			}
+			sheet, rng, spec = pc.S, pc.R, curSpec
+			startOffset = pc.Off
+			maxRows = pagination.PageSize(in.MaxRows, pc.Ps, maxRows, 1000)
+			parsedCur = pc
+		}
+
+		out := ColumnLookupOutput{Path: canonical, Sheet: sheet, LookupSheet: spec.sheet}
+		// The lookup table may live on another sheet, so hold the workbook.
+		err := mgr.WithRead(id, func(f *excelize.File, version int64) error {
+			fileMT, fileFP := fileSnapshot(canonical)
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
+			}
+			for _, sh := range []string{sheet, spec.sheet} {
+				if !sheetExists(f, sh) {
+					return mcperr.Errorf(mcperr.InvalidSheet, "sheet %q not found", sh)
+				}
+			}
+			bx1, by1, bx2, by2, baseResolved, perr := resolveRange(f, sheet, rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+			}
+			lx1, ly1, lx2, ly2, lookupResolved, perr := resolveRange(f, spec.sheet, spec.rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid lookup_range; use A1:D50 or a defined name")
+			}
+			out.RangeA1, out.LookupRange = baseResolved, lookupResolved
+			baseCols, lookupCols := bx2-bx1+1, lx2-lx1+1
+			if spec.cols[0] > baseCols {
+				return mcperr.Errorf(mcperr.Validation, "key_column %d outside range (%d columns)", spec.cols[0], baseCols)
+			}
+			for _, c := range spec.cols[1:] {
+				if c > lookupCols {
+					return mcperr.Errorf(mcperr.Validation, "lookup column %d outside lookup_range (%d columns)", c, lookupCols)
+				}
+			}
+
+			// Pass 1: stream the lookup table into a bounded map.
+			values := map[string]string{}
+			var valueHeader string
+			keyAbs, valAbs := lx1+spec.cols[1]-2, lx1+spec.cols[2]-2
+			err := streamRangeRows(ctx, f, spec.sheet, ly1, ly2, func(row int, vals []string) error {
+				if spec.opts.header && row == ly1 {
+					valueHeader = cellAt(vals, valAbs)
+					return nil
+				}
+				key := spec.opts.normalize(cellAt(vals, keyAbs))
+				if key == "" {
+					return nil
+				}
+				if _, ok := values[key]; ok {
+					out.Stats.Collisions++
+					return nil
+				}
+				if len(values) >= limits.MaxCellsPerOp {
+					return mcperr.Errorf(mcperr.LimitExceeded, "lookup_range holds more than %d distinct keys; narrow lookup_range or split the lookup by key prefix", limits.MaxCellsPerOp)
+				}
+				values[key] = cellAt(vals, valAbs)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			out.Stats.LookupKeys = len(values)
+
+			// Pass 2: stream the base range, joining every row for the stats
+			// and keeping the rows of this page.
+			pageRows := min(maxRows, max(limits.MaxCellsPerOp/(baseCols+1), 1))
+			baseKeyAbs := bx1 + spec.cols[0] - 2
+			missed := map[string]struct{}{}
+			out.Rows = []LookupRow{}
+			err = streamRangeRows(ctx, f, sheet, by1, by2, func(row int, vals []string) error {
+				if spec.opts.header && row == by1 {
+					out.Header = append(columnWindow(padRow(vals, bx2), bx1, bx2), valueHeader)
+					return nil
+				}
+				idx := out.Stats.Rows
+				out.Stats.Rows++
+				raw := cellAt(vals, baseKeyAbs)
+				key := spec.opts.normalize(raw)
+				v, ok := values[key]
+				switch {
+				case key == "":
+					out.Stats.BlankKeys++
+					ok = false
+				case ok:
+					out.Stats.Matched++
+				default:
+					out.Stats.Misses++
+					if _, seen := missed[key]; !seen && len(out.Stats.MissedKeys) < maxMissedKeys {
+						missed[key] = struct{}{}
+						out.Stats.MissedKeys = append(out.Stats.MissedKeys, raw)
+					}
+				}
+				if idx >= startOffset && len(out.Rows) < pageRows {
+					cells := append(columnWindow(padRow(vals, bx2), bx1, bx2), v)
+					out.Rows = append(out.Rows, LookupRow{Row: row, Values: cells, Matched: ok})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			out.Meta.Total = out.Stats.Rows
+			out.Meta.Returned = len(out.Rows)
+			out.Meta.Pages = pageCount(out.Meta.Total, pageRows)
+			out.Meta.Truncated = startOffset+len(out.Rows) < out.Meta.Total
+			runtime.CallStatsFrom(ctx).AddCells(len(out.Rows) * (baseCols + 1))
+			if out.Meta.Truncated {
+				next := pagination.Cursor{V: 1, Pt: canonical, S: sheet, R: baseResolved, U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(out.Rows)), Ps: maxRows, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: version, Os: spec.sheet, Lr: lookupResolved, Cl: spec.cols, Dk: spec.opts.String()}
+				token, encErr := pagination.EncodeCursor(next)
+				if encErr != nil {
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
+				}
+				out.Meta.NextCursor = token
+			}
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.AnalysisFailed, "%v", err), nil
+		}
+
+		runtime.CallStatsFrom(ctx).SetResult(out.Meta.Returned, out.Meta.Truncated)
+		summary := fmt.Sprintf("rows=%d matched=%d misses=%d blankKeys=%d lookupKeys=%d collisions=%d returned=%d truncated=%v", out.Stats.Rows, out.Stats.Matched, out.Stats.Misses, out.Stats.BlankKeys, out.Stats.LookupKeys, out.Stats.Collisions, out.Meta.Returned, out.Meta.Truncated)
+		if out.Meta.NextCursor != "" {
+			summary += " nextCursor=" + out.Meta.NextCursor
+		}
+		grid := make([][]string, 0, len(out.Rows)+1)
+		if out.Header != nil {
+			grid = append(grid, out.Header)
+		}
+		for _, r := range out.Rows {
+			grid = append(grid, r.Values)
+		}
+		body, _ := json.Marshal(grid)
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(summary + "\n" + string(body))}
+		return res, nil
+	}))
+	reg.Register(tool)
+}
+
+// streamRangeRows calls visit with the 1-based row number and cell values of
+// each sheet row from y1 through y2.
+func streamRangeRows(ctx context.Context, f *excelize.File, sheet string, y1, y2 int, visit func(row int, vals []string) error) error {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for row := 1; rows.Next() && row <= y2; row++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if row < y1 {
+			continue
+		}
+		vals, cerr := rows.Columns()
+		if cerr != nil {
+			return cerr
+		}
+		if err := visit(row, vals); err != nil {
+			return err
+		}
+	}
+	return rows.Error()
+}
+
+// padRow extends vals with empty cells through 1-based column end.
+func padRow(vals []string, end int) []string {
+	for len(vals) < end {
+		vals = append(vals, "")
+	}
+	return vals
+}
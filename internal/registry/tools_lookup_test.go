@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+func createLookupWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	orders := [][]any{
+		{"Order", "SKU", "Qty"},
+		{1001, "A-1", 2},
+		{1002, "b-2 ", 1},
+		{1003, "Z-9", 5},
+		{1004, "", 3},
+		{1005, "A-1", 4},
+	}
+	for i, r := range orders {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &r))
+	}
+	_, err := f.NewSheet("Products")
+	require.NoError(t, err)
+	products := [][]any{
+		{"SKU", "Name", "Price"},
+		{"A-1", "Anvil", 10},
+		{"B-2", "Bolt", 2},
+		{"A-1", "Axe", 30},
+	}
+	for i, r := range products {
+		cell, _ := excelize.CoordinatesToCellName(2, i+1)
+		require.NoError(t, f.SetSheetRow("Products", cell, &r))
+	}
+	path := filepath.Join(t.TempDir(), "lookup.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestColumnLookup(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createLookupWorkbook(t)
+
+	args := map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C6", "key_column": 2, "lookup_sheet": "Products", "lookup_range": "B1:D4", "lookup_key_column": 1, "lookup_value_column": 2, "header": true}
+	res := callTool(t, srv, "column_lookup", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got ColumnLookupOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, "Products", got.LookupSheet)
+	require.Equal(t, []string{"Order", "SKU", "Qty", "Name"}, got.Header)
+	require.Equal(t, 5, got.Stats.Rows)
+	require.Equal(t, 2, got.Stats.Matched)
+	require.Equal(t, 2, got.Stats.Misses)
+	require.Equal(t, 1, got.Stats.BlankKeys)
+	require.Equal(t, []string{"b-2 ", "Z-9"}, got.Stats.MissedKeys)
+	require.Equal(t, 2, got.Stats.LookupKeys)
+	require.Equal(t, 1, got.Stats.Collisions)
+	require.Len(t, got.Rows, 5)
+	// The first A-1 row in the lookup table wins.
+	require.Equal(t, LookupRow{Row: 2, Values: []string{"1001", "A-1", "2", "Anvil"}, Matched: true}, got.Rows[0])
+	require.Equal(t, LookupRow{Row: 4, Values: []string{"1003", "Z-9", "5", ""}}, got.Rows[2])
+	summary, _ := splitSummary(t, resultText(t, res))
+	require.Contains(t, summary, "matched=2 misses=2 blankKeys=1 lookupKeys=2 collisions=1")
+
+	// Normalized keys match "b-2 " to B-2; page through two rows at a time.
+	args["case_insensitive"] = true
+	args["trim"] = true
+	args["max_rows"] = 2
+	res = callTool(t, srv, "column_lookup", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	got = ColumnLookupOutput{}
+	decodeStructured(t, res, &got)
+	require.Equal(t, 3, got.Stats.Matched)
+	require.Equal(t, 3, got.Meta.Pages)
+	require.Len(t, got.Rows, 2)
+	require.Equal(t, "Bolt", got.Rows[1].Values[3])
+	require.NotEmpty(t, got.Meta.NextCursor)
+
+	res = callTool(t, srv, "column_lookup", map[string]any{"path": path, "cursor": got.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var page2 ColumnLookupOutput
+	decodeStructured(t, res, &page2)
+	require.Equal(t, []int{4, 5}, []int{page2.Rows[0].Row, page2.Rows[1].Row})
+	require.Equal(t, "B1:D4", page2.LookupRange)
+
+	// A cursor cannot be reused with different key options.
+	args["cursor"] = got.Meta.NextCursor
+	args["trim"] = false
+	res = callTool(t, srv, "column_lookup", args)
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "CURSOR_INVALID")
+
+	res = callTool(t, srv, "column_lookup", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C6", "key_column": 2, "lookup_range": "B1:D4", "lookup_key_column": 1, "lookup_value_column": 4})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
+
+func TestColumnLookup_MapLimit(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxCellsPerOp = 1
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	RegisterLookupTools(srv, New(), limits, mgr)
+	path := createLookupWorkbook(t)
+
+	res := callTool(t, srv, "column_lookup", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C6", "key_column": 2, "lookup_sheet": "Products", "lookup_range": "B2:D4", "lookup_key_column": 1, "lookup_value_column": 2})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "LIMIT_EXCEEDED")
+	require.Contains(t, resultText(t, res), "narrow lookup_range")
+}
@@ -0,0 +1,518 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/pagination"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+)
+
+// mergeSourceColumn heads the column naming each merged row's sheet.
+const mergeSourceColumn = "source_sheet"
+
+// maxHeaderDiffs caps the differing columns listed per sheet in a header
+// mismatch error.
+const maxHeaderDiffs = 10
+
+// MergeSheetsInput defines parameters for merge_sheets.
+type MergeSheetsInput struct {
+	Path         string   `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password     string   `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheets       []string `json:"sheets,omitempty" validate:"omitempty,max=100" jsonschema_description:"Sheets to append, in this order"`
+	SheetPattern string   `json:"sheet_pattern,omitempty" jsonschema_description:"Glob over sheet names instead of sheets (e.g. 2024-*; * ? and [...] as in path.Match), taken in workbook order"`
+	HeaderRow    int      `json:"header_row,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"1‑based header row on every sheet (default 1); rows above it are skipped"`
+	MaxRows      int      `json:"max_rows,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"Max merged rows per page (unit=rows, default 200)"`
+	Cursor       string   `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor (unit=rows) from a previous page; carries the sheets and header row"`
+}
+
+// CreateMergedSheetInput defines parameters for create_merged_sheet.
+type CreateMergedSheetInput struct {
+	Path         string   `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Sheets       []string `json:"sheets,omitempty" validate:"omitempty,max=100" jsonschema_description:"Sheets to append, in this order"`
+	SheetPattern string   `json:"sheet_pattern,omitempty" jsonschema_description:"Glob over sheet names instead of sheets (e.g. 2024-*), taken in workbook order"`
+	HeaderRow    int      `json:"header_row,omitempty" validate:"omitempty,min=1,max=1000" jsonschema_description:"1‑based header row on every sheet (default 1); rows above it are skipped"`
+	Target       string   `json:"target" validate:"required" jsonschema_description:"Name of the new sheet receiving the merged rows (max 31 chars; no : \\ / ? * [ ])"`
+	Backup       bool     `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// MergeSource counts the rows one sheet contributed.
+type MergeSource struct {
+	Sheet string `json:"sheet"`
+	Rows  int    `json:"rows"`
+}
+
+// MergeSheetsOutput returns one page of the concatenated rows.
+type MergeSheetsOutput struct {
+	Path           string        `json:"path"`
+	Header         []string      `json:"header" jsonschema_description:"source_sheet followed by the first sheet's header"`
+	Rows           [][]string    `json:"rows" jsonschema_description:"Merged rows; the first cell names the source sheet"`
+	Sources        []MergeSource `json:"sources"`
+	Truncated      bool          `json:"truncated" jsonschema_description:"The merged rows exceeded the per-operation cell limit; merging stopped at truncatedSheet/truncatedRow"`
+	TruncatedSheet string        `json:"truncatedSheet,omitempty"`
+	TruncatedRow   int           `json:"truncatedRow,omitempty" jsonschema_description:"First row of truncatedSheet left out"`
+	Meta           PageMeta      `json:"meta"`
+}
+
+// CreateMergedSheetOutput reports a materialized merge.
+type CreateMergedSheetOutput struct {
+	Path        string        `json:"path"`
+	Target      string        `json:"target"`
+	Sources     []MergeSource `json:"sources"`
+	RowsWritten int           `json:"rowsWritten" jsonschema_description:"Data rows written below the header"`
+	Columns     int           `json:"columns" jsonschema_description:"Columns written, including source_sheet"`
+	Sheets      []string      `json:"sheets"`
+	Save        string        `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup      string        `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// mergeResult describes a merge pass: the shared header, per-sheet row
+// counts, and where the cell budget stopped it.
+type mergeResult struct {
+	header         []string
+	sources        []MergeSource
+	truncatedSheet string
+	truncatedRow   int
+}
+
+// RegisterMergeTools registers merge_sheets and create_merged_sheet.
+func RegisterMergeTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	mergeSheets := mcp.NewTool(
+		"merge_sheets",
+		mcp.WithDescription(fmt.Sprintf("Append the rows of identically shaped sheets (e.g., one per month) into one consolidated view without modifying the workbook. Pick sheets by list or by sheet_pattern (glob, workbook order). Every sheet's header_row (default 1) must match the first sheet's, ignoring case, repeated spaces, and trailing empty columns; otherwise VALIDATION lists the differing columns. Rows below the header are returned with a leading source_sheet column; blank rows are skipped. Pagination operates in rows (unit=rows); the cursor binds to path+content fingerprint and the sheet list and can be sent alone to resume. At most %d cells (including source_sheet) are merged; truncated, truncatedSheet, and truncatedRow report where merging stopped. Errors: VALIDATION, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, ANALYSIS_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[MergeSheetsInput](),
+		mcp.WithOutputSchema[MergeSheetsOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(mergeSheets, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in MergeSheetsInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		maxRows := pagination.PageSize(in.MaxRows, 0, 200, 1000)
+		headerRow := in.HeaderRow
+		if headerRow <= 0 {
+			headerRow = 1
+		}
+		var sheets []string
+		var startOffset int
+		var parsedCur *pagination.Cursor
+		if curTok := strings.TrimSpace(in.Cursor); curTok != "" {
+			pc, cres := decodeCursor(curTok, limits.CursorTTL)
+			if cres != nil {
+				return cres, nil
+			}
+			if pc.Pt != canonical {
+				return mcperr.FromText("CURSOR_INVALID: cursor path does not match provided path"), nil
+			}
+			if pc.U != pagination.UnitRows || len(pc.Ss) == 0 || pc.Hr <= 0 {
+				return mcperr.FromText("CURSOR_INVALID: cursor was not issued by merge_sheets"), nil
+			}
+			sheets, headerRow, startOffset = pc.Ss, pc.Hr, pc.Off
+			maxRows = pagination.PageSize(in.MaxRows, pc.Ps, maxRows, 1000)
+			parsedCur = pc
+		} else if msg := validateMergeSelection(in.Sheets, in.SheetPattern); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+
+		out := MergeSheetsOutput{Path: canonical, Rows: [][]string{}}
+		reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+		err := mgr.WithRead(id, func(f *excelize.File, version int64) error {
+			fileMT, fileFP := fileSnapshot(canonical)
+			if err := checkCursor(mgr, parsedCur, id, version, fileMT, fileFP); err != nil {
+				return err
+			}
+			if parsedCur == nil {
+				var err error
+				if sheets, err = selectMergeSheets(f, in.Sheets, in.SheetPattern); err != nil {
+					return err
+				}
+			}
+			total := 0
+			res, err := mergeSheetRows(ctx, f, sheets, headerRow, limits.MaxCellsPerOp, false, func(sheet string, _ int, vals []string) {
+				if total >= startOffset && len(out.Rows) < maxRows {
+					out.Rows = append(out.Rows, append([]string{sheet}, vals...))
+				}
+				total++
+			})
+			if err != nil {
+				return err
+			}
+			out.Header = append([]string{mergeSourceColumn}, res.header...)
+			out.Sources = res.sources
+			out.Truncated = res.truncatedSheet != ""
+			out.TruncatedSheet, out.TruncatedRow = res.truncatedSheet, res.truncatedRow
+			out.Meta.Total = total
+			out.Meta.Returned = len(out.Rows)
+			out.Meta.Pages = pageCount(total, maxRows)
+			out.Meta.Truncated = startOffset+len(out.Rows) < total
+			if out.Meta.Truncated {
+				lastCol, _ := excelize.ColumnNumberToName(len(res.header))
+				next := pagination.Cursor{V: 1, Pt: canonical, S: sheets[0], R: fmt.Sprintf("A%d:%s%d", headerRow, lastCol, headerRow), U: pagination.UnitRows, Off: pagination.NextOffset(startOffset, len(out.Rows)), Ps: maxRows, Mt: fileMT, Fp: fileFP, Hid: id, Wbv: version, Hr: headerRow, Ss: sheets}
+				token, encErr := pagination.EncodeCursor(next)
+				if encErr != nil {
+					return mcperr.Errorf(mcperr.CursorBuildFailed, "failed to encode next page cursor (%v); retry or narrow scope", encErr)
+				}
+				out.Meta.NextCursor = token
+			}
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			if res := cursorMismatch(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.AnalysisFailed, "%v", err), nil
+		}
+
+		summary := fmt.Sprintf("sheets=%d rows=%d returned=%d truncated=%v", len(out.Sources), out.Meta.Total, out.Meta.Returned, out.Meta.Truncated)
+		if out.Truncated {
+			summary += fmt.Sprintf(" cellLimitAt=%s!%d", out.TruncatedSheet, out.TruncatedRow)
+		}
+		if out.Meta.NextCursor != "" {
+			summary += " nextCursor=" + out.Meta.NextCursor
+		}
+		lines := []string{summary, compactRow(out.Header)}
+		for _, r := range out.Rows {
+			lines = append(lines, compactRow(r))
+		}
+		res := mcp.NewToolResultStructured(out, summary)
+		res.Content = []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}
+		return res, nil
+	}))
+	reg.Register(mergeSheets)
+
+	createMerged := mcp.NewTool(
+		"create_merged_sheet",
+		mcp.WithDescription(fmt.Sprintf("Materialize merge_sheets into a new sheet of the same workbook and save atomically: a header row (source_sheet plus the shared header) followed by every non-blank row of the selected sheets. Sheets, sheet_pattern, header_row, and header matching work as in merge_sheets. Values are copied as stored (numbers, booleans, and dates keep their type; formulas and styles are not copied). The target name follows Excel rules and must not exist. Merges larger than %d cells are refused with LIMIT_EXCEEDED rather than written partially. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, LIMIT_EXCEEDED, WRITE_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[CreateMergedSheetInput](),
+		mcp.WithOutputSchema[CreateMergedSheetOutput](),
+		writeTool(false, false),
+	)
+	s.AddTool(createMerged, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in CreateMergedSheetInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		if msg := validateMergeSelection(in.Sheets, in.SheetPattern); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		target := strings.TrimSpace(in.Target)
+		if msg := validateSheetName(target); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		headerRow := in.HeaderRow
+		if headerRow <= 0 {
+			headerRow = 1
+		}
+		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, strings.TrimSpace(in.Path))
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		out := CreateMergedSheetOutput{Path: canonical, Target: target}
+		err := mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+			if _, taken := resolveSheetName(f, target); taken {
+				return mcperr.Errorf(mcperr.Validation, "sheet %q already exists", target)
+			}
+			sheets, err := selectMergeSheets(f, in.Sheets, in.SheetPattern)
+			if err != nil {
+				return err
+			}
+			// Collect every row before touching the workbook so a failed
+			// merge leaves it unchanged.
+			type sourceRow struct {
+				sheet string
+				row   int
+				vals  []string
+			}
+			var rows []sourceRow
+			res, err := mergeSheetRows(ctx, f, sheets, headerRow, limits.MaxCellsPerOp, true, func(sheet string, row int, vals []string) {
+				rows = append(rows, sourceRow{sheet: sheet, row: row, vals: vals})
+			})
+			if err != nil {
+				return err
+			}
+			if res.truncatedSheet != "" {
+				return mcperr.Errorf(mcperr.LimitExceeded, "merged rows exceed %d cells (stopped at %s row %d); merge fewer sheets", limits.MaxCellsPerOp, res.truncatedSheet, res.truncatedRow)
+			}
+			if _, err := f.NewSheet(target); err != nil {
+				return err
+			}
+			header := append([]any{mergeSourceColumn}, stringsToAny(res.header)...)
+			if err := f.SetSheetRow(target, "A1", &header); err != nil {
+				return err
+			}
+			readers := map[string]*cellDetailReader{}
+			date1904 := false
+			if props, perr := f.GetWorkbookProps(); perr == nil && props.Date1904 != nil {
+				date1904 = *props.Date1904
+			}
+			for i, r := range rows {
+				rd := readers[r.sheet]
+				if rd == nil {
+					rd = newCellDetailReader(f, r.sheet)
+					readers[r.sheet] = rd
+				}
+				vals := make([]any, 0, len(r.vals)+1)
+				vals = append(vals, r.sheet)
+				for c, v := range r.vals {
+					cell, _ := excelize.CoordinatesToCellName(c+1, r.row)
+					vals = append(vals, mergedCellValue(rd, cell, v, date1904))
+				}
+				cell, _ := excelize.CoordinatesToCellName(1, i+2)
+				if err := f.SetSheetRow(target, cell, &vals); err != nil {
+					return err
+				}
+			}
+			out.Sources, out.RowsWritten, out.Columns = res.sources, len(rows), len(header)
+			lastCell, _ := excelize.CoordinatesToCellName(len(header), len(rows)+1)
+			if err := reg.auditWrite(ctx, audit.Record{Tool: "create_merged_sheet", Path: canonical, Sheet: target, Range: "A1:" + lastCell, Cells: len(header) * (len(rows) + 1)}); err != nil {
+				return err
+			}
+			saved, err := save(canonical, in.Backup)
+			if err != nil {
+				return err
+			}
+			out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+			out.Sheets = f.GetSheetList()
+			return nil
+		})
+		if err != nil {
+			err = discardUnaudited(mgr, id, err)
+			return structureEditError(err), nil
+		}
+		summary := fmt.Sprintf("target=%q sources=%d rows=%d columns=%d", out.Target, len(out.Sources), out.RowsWritten, out.Columns) + saveSummary(out.Save, out.Backup)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(createMerged)
+}
+
+// validateMergeSelection checks that exactly one of sheets and pattern is
+// given, returning a VALIDATION message or "".
+func validateMergeSelection(sheets []string, pattern string) string {
+	switch hasPattern := strings.TrimSpace(pattern) != ""; {
+	case len(sheets) == 0 && !hasPattern:
+		return "VALIDATION: sheets or sheet_pattern is required"
+	case len(sheets) > 0 && hasPattern:
+		return "VALIDATION: use sheets or sheet_pattern, not both"
+	}
+	if _, err := path.Match(strings.TrimSpace(pattern), ""); err != nil {
+		return "VALIDATION: invalid sheet_pattern: " + err.Error()
+	}
+	return ""
+}
+
+// selectMergeSheets resolves the listed sheets (case-insensitively, keeping
+// their order) or the sheets matching pattern in workbook order.
+func selectMergeSheets(f *excelize.File, sheets []string, pattern string) ([]string, error) {
+	var out []string
+	if pattern = strings.TrimSpace(pattern); pattern != "" {
+		for _, sh := range f.GetSheetList() {
+			if ok, _ := path.Match(pattern, sh); ok {
+				out = append(out, sh)
+			}
+		}
+		if len(out) == 0 {
+			return nil, mcperr.Errorf(mcperr.Validation, "no sheet matches %q", pattern)
+		}
+		return out, nil
+	}
+	seen := map[string]bool{}
+	for _, name := range sheets {
+		sh, ok := resolveSheetName(f, strings.TrimSpace(name))
+		if !ok {
+			return nil, mcperr.Errorf(mcperr.InvalidSheet, "sheet %q not found", name)
+		}
+		if seen[sh] {
+			return nil, mcperr.Errorf(mcperr.Validation, "sheet %q listed twice", sh)
+		}
+		seen[sh] = true
+		out = append(out, sh)
+	}
+	return out, nil
+}
+
+// mergeSheetRows checks that every sheet's header row matches the first
+// sheet's, then streams the non-blank rows below it, cut to the header width,
+// to visit in sheet order. Each row costs its width plus the source column
+// against budget; the first row past it is reported in the result and
+// merging stops. raw reads stored values instead of formatted ones.
+func mergeSheetRows(ctx context.Context, f *excelize.File, sheets []string, headerRow, budget int, raw bool, visit func(sheet string, row int, vals []string)) (mergeResult, error) {
+	var colOpts []excelize.Options
+	if raw {
+		colOpts = append(colOpts, excelize.Options{RawCellValue: true})
+	}
+	headers := make([][]string, len(sheets))
+	for i, sh := range sheets {
+		h, err := readSheetRow(f, sh, headerRow)
+		if err != nil {
+			return mergeResult{}, err
+		}
+		headers[i] = trimTrailingBlank(h)
+	}
+	res := mergeResult{header: headers[0]}
+	if len(res.header) == 0 {
+		return res, mcperr.Errorf(mcperr.Validation, "sheet %q has an empty header row %d", sheets[0], headerRow)
+	}
+	if msg := headerMismatch(sheets, headers); msg != "" {
+		return res, mcperr.Errorf(mcperr.Validation, "%s", msg)
+	}
+
+	width := len(res.header)
+	cells := 0
+	for _, sh := range sheets {
+		src := MergeSource{Sheet: sh}
+		rowsIter, err := f.Rows(sh)
+		if err != nil {
+			return res, err
+		}
+		rowIdx := 0
+		for rowsIter.Next() {
+			if ctx.Err() != nil {
+				_ = rowsIter.Close()
+				return res, ctx.Err()
+			}
+			rowIdx++
+			if rowIdx <= headerRow {
+				continue
+			}
+			vals, cerr := rowsIter.Columns(colOpts...)
+			if cerr != nil {
+				_ = rowsIter.Close()
+				return res, cerr
+			}
+			vals = columnWindow(vals, 1, width)
+			if len(trimTrailingBlank(vals)) == 0 {
+				continue
+			}
+			cells += width + 1
+			if cells > budget {
+				res.truncatedSheet, res.truncatedRow = sh, rowIdx
+				break
+			}
+			row := make([]string, width)
+			copy(row, vals)
+			visit(sh, rowIdx, row)
+			src.Rows++
+		}
+		_ = rowsIter.Close()
+		res.sources = append(res.sources, src)
+		if res.truncatedSheet != "" {
+			break
+		}
+	}
+	return res, nil
+}
+
+// readSheetRow returns the formatted cells of one 1-based row.
+func readSheetRow(f *excelize.File, sheet string, row int) ([]string, error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	for n := 1; rows.Next(); n++ {
+		if n == row {
+			return rows.Columns()
+		}
+	}
+	return nil, nil
+}
+
+// trimTrailingBlank drops trailing cells that are empty or whitespace.
+func trimTrailingBlank(cells []string) []string {
+	end := len(cells)
+	for end > 0 && strings.TrimSpace(cells[end-1]) == "" {
+		end--
+	}
+	return cells[:end]
+}
+
+// normalizeHeader folds case and collapses whitespace so headers that differ
+// only cosmetically still match.
+func normalizeHeader(h string) string {
+	return strings.ToLower(strings.Join(strings.Fields(h), " "))
+}
+
+// headerMismatch compares each sheet's header with the first sheet's and
+// describes the differing columns, or returns "" when all match.
+func headerMismatch(sheets []string, headers [][]string) string {
+	var parts []string
+	for i := 1; i < len(sheets); i++ {
+		var diffs []string
+		n := max(len(headers[0]), len(headers[i]))
+		for c := 0; c < n; c++ {
+			want, got := cellAt(headers[0], c), cellAt(headers[i], c)
+			if normalizeHeader(want) == normalizeHeader(got) {
+				continue
+			}
+			if len(diffs) == maxHeaderDiffs {
+				diffs = append(diffs, "…")
+				break
+			}
+			col, _ := excelize.ColumnNumberToName(c + 1)
+			diffs = append(diffs, fmt.Sprintf("%s (%q vs %q)", col, want, got))
+		}
+		if len(diffs) > 0 {
+			parts = append(parts, fmt.Sprintf("sheet %q columns %s", sheets[i], strings.Join(diffs, ", ")))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("headers differ from sheet %q: %s", sheets[0], strings.Join(parts, "; "))
+}
+
+func cellAt(cells []string, i int) string {
+	if i < len(cells) {
+		return cells[i]
+	}
+	return ""
+}
+
+func stringsToAny(vals []string) []any {
+	out := make([]any, len(vals))
+	for i, v := range vals {
+		out[i] = v
+	}
+	return out
+}
+
+// mergedCellValue converts the stored value raw of a source cell to the
+// value written to the merged sheet, keeping numbers, booleans, and dates
+// typed. Empty cells become nil so they stay empty.
+func mergedCellValue(rd *cellDetailReader, cell, raw string, date1904 bool) any {
+	if raw == "" {
+		return nil
+	}
+	switch rd.inferType(cell, raw) {
+	case "number":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case "bool":
+		return raw == "1" || strings.EqualFold(raw, "TRUE")
+	case "date":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			if t, terr := excelize.ExcelDateToTime(n, date1904); terr == nil {
+				return t
+			}
+		}
+	}
+	return raw
+}
@@ -0,0 +1,138 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+// writeMonthlyWorkbook saves a Total-only Sheet1 followed by 2024-01 and
+// 2024-02 with a Code/Amount header; 2024-02 spells its header differently
+// and has a blank row.
+func writeMonthlyWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "Total"))
+	for _, sh := range []struct {
+		name string
+		rows [][]any
+	}{
+		{"2024-01", [][]any{{"Code", "Amount"}, {"007", 10}, {"A2", 20}}},
+		{"2024-02", [][]any{{" code ", "AMOUNT", ""}, {"B1", 30}, {nil, nil}, {"B3", 40}}},
+	} {
+		_, err := f.NewSheet(sh.name)
+		require.NoError(t, err)
+		for i, r := range sh.rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			require.NoError(t, f.SetSheetRow(sh.name, cell, &r))
+		}
+	}
+	path := filepath.Join(t.TempDir(), "monthly.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestMergeSheets(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := writeMonthlyWorkbook(t)
+
+	res := callTool(t, srv, "merge_sheets", map[string]any{"path": path, "sheet_pattern": "2024-*", "max_rows": 3})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got MergeSheetsOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, []string{"source_sheet", "Code", "Amount"}, got.Header)
+	require.Equal(t, [][]string{{"2024-01", "007", "10"}, {"2024-01", "A2", "20"}, {"2024-02", "B1", "30"}}, got.Rows)
+	require.Equal(t, []MergeSource{{Sheet: "2024-01", Rows: 2}, {Sheet: "2024-02", Rows: 2}}, got.Sources)
+	require.False(t, got.Truncated)
+	require.Equal(t, 4, got.Meta.Total)
+	require.NotEmpty(t, got.Meta.NextCursor)
+
+	res = callTool(t, srv, "merge_sheets", map[string]any{"path": path, "cursor": got.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var page2 MergeSheetsOutput
+	decodeStructured(t, res, &page2)
+	require.Equal(t, [][]string{{"2024-02", "B3", "40"}}, page2.Rows)
+	require.Empty(t, page2.Meta.NextCursor)
+
+	// An explicit list keeps its order.
+	res = callTool(t, srv, "merge_sheets", map[string]any{"path": path, "sheets": []string{"2024-02", "2024-01"}})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var listed MergeSheetsOutput
+	decodeStructured(t, res, &listed)
+	require.Equal(t, "B1", listed.Rows[0][1])
+
+	// Sheet1's header does not match.
+	res = callTool(t, srv, "merge_sheets", map[string]any{"path": path, "sheets": []string{"2024-01", "Sheet1"}})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+	require.Contains(t, resultText(t, res), `A ("Code" vs "Total")`)
+	require.Contains(t, resultText(t, res), `B ("Amount" vs "")`)
+
+	res = callTool(t, srv, "merge_sheets", map[string]any{"path": path, "sheets": []string{"2024-01"}, "sheet_pattern": "2024-*"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
+
+func TestMergeSheets_CellLimit(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	limits.MaxCellsPerOp = 9
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	RegisterMergeTools(srv, New(), limits, mgr)
+	path := writeMonthlyWorkbook(t)
+
+	res := callTool(t, srv, "merge_sheets", map[string]any{"path": path, "sheet_pattern": "2024-*"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got MergeSheetsOutput
+	decodeStructured(t, res, &got)
+	require.Len(t, got.Rows, 3)
+	require.True(t, got.Truncated)
+	require.Equal(t, "2024-02", got.TruncatedSheet)
+	require.Equal(t, 4, got.TruncatedRow)
+
+	res = callTool(t, srv, "create_merged_sheet", map[string]any{"path": path, "sheet_pattern": "2024-*", "target": "All"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "LIMIT_EXCEEDED")
+}
+
+func TestCreateMergedSheet(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := writeMonthlyWorkbook(t)
+
+	res := callTool(t, srv, "create_merged_sheet", map[string]any{"path": path, "sheet_pattern": "2024-*", "target": "All"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var got CreateMergedSheetOutput
+	decodeStructured(t, res, &got)
+	require.Equal(t, 4, got.RowsWritten)
+	require.Equal(t, 3, got.Columns)
+	require.Contains(t, got.Sheets, "All")
+
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	rows, err := f.GetRows("All")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"source_sheet", "Code", "Amount"},
+		{"2024-01", "007", "10"},
+		{"2024-01", "A2", "20"},
+		{"2024-02", "B1", "30"},
+		{"2024-02", "B3", "40"},
+	}, rows)
+	typ, err := f.GetCellType("All", "C2")
+	require.NoError(t, err)
+	require.NotEqual(t, excelize.CellTypeSharedString, typ)
+
+	res = callTool(t, srv, "create_merged_sheet", map[string]any{"path": path, "sheet_pattern": "2024-*", "target": "all"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "already exists")
+}
@@ -0,0 +1,323 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxNamedRanges caps the defined names returned by the named range tools.
+const maxNamedRanges = 1000
+
+// maxDefinedNameLen is Excel's limit on defined name length.
+const maxDefinedNameLen = 255
+
+// workbookScope is the scope reported for names visible in every sheet.
+const workbookScope = "Workbook"
+
+// ListNamedRangesInput defines parameters for list_named_ranges.
+type ListNamedRangesInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+}
+
+// CreateNamedRangeInput defines parameters for create_named_range.
+type CreateNamedRangeInput struct {
+	Path    string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Name    string `json:"name" validate:"required" jsonschema_description:"New name (Excel rules: starts with a letter, underscore, or backslash; letters, digits, _ . \\ only; not a cell reference; max 255 chars)"`
+	Sheet   string `json:"sheet" validate:"required" jsonschema_description:"Sheet the name refers to"`
+	RangeA1 string `json:"range" validate:"required" jsonschema_description:"A1 cell or range the name refers to, e.g. A1:F5000"`
+	Scope   string `json:"scope,omitempty" jsonschema_description:"Sheet the name is local to; omit (or 'Workbook') for a workbook‑wide name"`
+	Backup  bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// DeleteNamedRangeInput defines parameters for delete_named_range.
+type DeleteNamedRangeInput struct {
+	Path   string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Name   string `json:"name" validate:"required" jsonschema_description:"Defined name to delete (case‑insensitive)"`
+	Scope  string `json:"scope,omitempty" jsonschema_description:"Sheet the name is local to; omit (or 'Workbook') for a workbook‑wide name"`
+	Backup bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// NamedRangesOutput lists a workbook's defined names; Name is the name a
+// create or delete acted on.
+type NamedRangesOutput struct {
+	Path      string            `json:"path"`
+	Name      string            `json:"name,omitempty"`
+	Names     []DefinedNameInfo `json:"names"`
+	Total     int               `json:"total"`
+	Truncated bool              `json:"truncated,omitempty"`
+	Save      string            `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup    string            `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// RegisterNameTools registers list_named_ranges and the write-gated
+// create_named_range and delete_named_range.
+func RegisterNameTools(s *server.MCPServer, reg *Registry, mgr *workbooks.Manager) {
+	// list_named_ranges
+	list := mcp.NewTool(
+		"list_named_ranges",
+		mcp.WithDescription(fmt.Sprintf("List the workbook's defined names with what they refer to (e.g., 'Sheet1!$A$1:$F$5000') and their scope ('Workbook' or the sheet a name is local to). Any name referring to a range can be passed as the range of read_range and other range tools. At most %d names are returned; truncated marks a cut list. Errors: VALIDATION, READ_FAILED.", maxNamedRanges)),
+		mcp.WithInputSchema[ListNamedRangesInput](),
+		mcp.WithOutputSchema[NamedRangesOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(list, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ListNamedRangesInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		out := NamedRangesOutput{Path: canonical}
+		err := mgr.WithRead(id, func(f *excelize.File, version int64) error {
+			listNames(f, &out)
+			reg.changes.observe(ctx, id, canonical, version)
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+		}
+		runtime.CallStatsFrom(ctx).SetResult(len(out.Names), out.Truncated)
+		return mcp.NewToolResultStructured(out, namesSummary(out)), nil
+	}))
+	reg.Register(list)
+
+	// create_named_range
+	create := mcp.NewTool(
+		"create_named_range",
+		mcp.WithDescription("Define a name for a cell or range (e.g., 'SalesTable' for Data!A1:F5000) and save the workbook atomically, so later calls can pass the name instead of coordinates. Names follow Excel rules: they start with a letter, underscore, or backslash, contain only letters, digits, underscores, periods, and backslashes, are at most 255 characters, and cannot look like a cell reference (A1, R1C1) or be 'R' or 'C'. A name already defined in the same scope (case‑insensitive) is refused. scope makes the name local to a sheet; it is workbook‑wide by default. Output includes the updated name list. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, WRITE_FAILED."),
+		mcp.WithInputSchema[CreateNamedRangeInput](),
+		mcp.WithOutputSchema[NamedRangesOutput](),
+		writeTool(false, false),
+	)
+	s.AddTool(create, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in CreateNamedRangeInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		name := strings.TrimSpace(in.Name)
+		if msg := validateDefinedName(name); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		return runNameEdit(ctx, reg, mgr, "create_named_range", in.Path, name, in.Backup, func(f *excelize.File) (string, error) {
+			sheet, ok := resolveSheetName(f, strings.TrimSpace(in.Sheet))
+			if !ok {
+				return "", mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			scope, err := nameScope(f, in.Scope)
+			if err != nil {
+				return "", err
+			}
+			if _, found := findDefinedName(f, name, scope); found {
+				return "", mcperr.Errorf(mcperr.Validation, "name %q already exists in scope %s", name, scope)
+			}
+			ref := strings.TrimSpace(in.RangeA1)
+			if !strings.Contains(ref, ":") {
+				ref += ":" + ref
+			}
+			x1, y1, x2, y2, _, err := resolveRange(f, sheet, ref)
+			if err != nil {
+				return "", mcperr.Errorf(mcperr.Validation, "invalid range %q; use a cell like B2 or a range like A1:F500", in.RangeA1)
+			}
+			dn := excelize.DefinedName{Name: name, RefersTo: absoluteRef(sheet, x1, y1, x2, y2)}
+			if scope != workbookScope {
+				dn.Scope = scope
+			}
+			return sheet, f.SetDefinedName(&dn)
+		})
+	}))
+	reg.Register(create)
+
+	// delete_named_range
+	del := mcp.NewTool(
+		"delete_named_range",
+		mcp.WithDescription("Delete a defined name and save the workbook atomically. The cells it referred to are unchanged, but formulas that use the name show #NAME? in Excel. Names are matched case‑insensitively within scope (workbook‑wide by default). Output includes the updated name list. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, WRITE_FAILED."),
+		mcp.WithInputSchema[DeleteNamedRangeInput](),
+		mcp.WithOutputSchema[NamedRangesOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(del, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in DeleteNamedRangeInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		name := strings.TrimSpace(in.Name)
+		return runNameEdit(ctx, reg, mgr, "delete_named_range", in.Path, name, in.Backup, func(f *excelize.File) (string, error) {
+			scope, err := nameScope(f, in.Scope)
+			if err != nil {
+				return "", err
+			}
+			dn, found := findDefinedName(f, name, scope)
+			if !found {
+				return "", mcperr.Errorf(mcperr.Validation, "name %q not found in scope %s", name, scope)
+			}
+			sheet := ""
+			if scope != workbookScope {
+				sheet = scope
+			}
+			return sheet, f.DeleteDefinedName(&excelize.DefinedName{Name: dn.Name, Scope: sheet})
+		})
+	}))
+	reg.Register(del)
+}
+
+// runNameEdit applies edit under the workbook write lock, audits it as tool
+// against the sheet edit returns, saves atomically, and reports the
+// resulting name list.
+func runNameEdit(ctx context.Context, reg *Registry, mgr *workbooks.Manager, tool, path, name string, backup bool, edit func(*excelize.File) (string, error)) (*mcp.CallToolResult, error) {
+	id, canonical, openErr := mgr.GetOrOpenByPath(ctx, strings.TrimSpace(path))
+	if openErr != nil {
+		return openFailed(openErr), nil
+	}
+	out := NamedRangesOutput{Path: canonical, Name: name}
+	err := mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		sheet, err := edit(f)
+		if err != nil {
+			return err
+		}
+		if err := reg.auditWrite(ctx, audit.Record{Tool: tool, Path: canonical, Sheet: sheet, Range: name}); err != nil {
+			return err
+		}
+		saved, err := save(canonical, backup)
+		if err != nil {
+			return err
+		}
+		out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+		listNames(f, &out)
+		return nil
+	})
+	if err != nil {
+		err = discardUnaudited(mgr, id, err)
+		return structureEditError(err), nil
+	}
+	return mcp.NewToolResultStructured(out, fmt.Sprintf("name=%q ", name)+namesSummary(out)+saveSummary(out.Save, out.Backup)), nil
+}
+
+// listNames fills out with f's defined names, capped at maxNamedRanges.
+func listNames(f *excelize.File, out *NamedRangesOutput) {
+	names := f.GetDefinedName()
+	out.Total = len(names)
+	if len(names) > maxNamedRanges {
+		names = names[:maxNamedRanges]
+		out.Truncated = true
+	}
+	out.Names = make([]DefinedNameInfo, 0, len(names))
+	for _, dn := range names {
+		out.Names = append(out.Names, DefinedNameInfo{Name: dn.Name, RefersTo: dn.RefersTo, Scope: dn.Scope})
+	}
+}
+
+// namesSummary renders the name list as "names=N" followed by name=refersTo
+// pairs, sheet-local names qualified by their scope.
+func namesSummary(out NamedRangesOutput) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "names=%d", out.Total)
+	if out.Truncated {
+		fmt.Fprintf(&b, " (first %d listed)", len(out.Names))
+	}
+	for _, dn := range out.Names {
+		b.WriteString(" ")
+		if dn.Scope != workbookScope {
+			b.WriteString(dn.Scope + "/")
+		}
+		b.WriteString(dn.Name + "=" + dn.RefersTo)
+	}
+	return b.String()
+}
+
+// nameScope resolves a scope input to a sheet name, or workbookScope when it
+// is empty or "Workbook".
+func nameScope(f *excelize.File, scope string) (string, error) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" || strings.EqualFold(scope, workbookScope) {
+		return workbookScope, nil
+	}
+	sheet, ok := resolveSheetName(f, scope)
+	if !ok {
+		return "", mcperr.Errorf(mcperr.InvalidSheet, "scope sheet %q not found", scope)
+	}
+	return sheet, nil
+}
+
+// findDefinedName returns the name defined in scope matching name
+// case-insensitively, as Excel compares names.
+func findDefinedName(f *excelize.File, name, scope string) (excelize.DefinedName, bool) {
+	for _, dn := range f.GetDefinedName() {
+		if strings.EqualFold(dn.Name, name) && dn.Scope == scope {
+			return dn, true
+		}
+	}
+	return excelize.DefinedName{}, false
+}
+
+// absoluteRef renders a sheet-qualified absolute reference such as
+// 'My Sheet'!$A$1:$F$10, or Sheet1!$B$2 for a single cell.
+func absoluteRef(sheet string, x1, y1, x2, y2 int) string {
+	abs := func(x, y int) string {
+		col, _ := excelize.ColumnNumberToName(x)
+		return fmt.Sprintf("$%s$%d", col, y)
+	}
+	ref := abs(x1, y1)
+	if x1 != x2 || y1 != y2 {
+		ref += ":" + abs(x2, y2)
+	}
+	return quoteSheet(sheet) + "!" + ref
+}
+
+// quoteSheet quotes a sheet name for use in a reference when it contains
+// anything but letters, digits, underscores, and periods.
+func quoteSheet(sheet string) string {
+	for _, r := range sheet {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			return "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+		}
+	}
+	return sheet
+}
+
+// r1c1Name matches names Excel would read as R1C1 references (R, C, R2,
+// C3, R2C3).
+var r1c1Name = regexp.MustCompile(`(?i)^(r[0-9]*c?[0-9]*|c[0-9]*)$`)
+
+// validateDefinedName checks Excel's defined name rules and returns a
+// VALIDATION message, or "" when the name is acceptable.
+func validateDefinedName(name string) string {
+	if name == "" {
+		return "VALIDATION: name is required"
+	}
+	if utf8.RuneCountInString(name) > maxDefinedNameLen {
+		return fmt.Sprintf("VALIDATION: name exceeds %d characters", maxDefinedNameLen)
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), r == '_', r == '\\':
+		case i > 0 && (unicode.IsDigit(r) || r == '.'):
+		case i == 0:
+			return "VALIDATION: name must start with a letter, underscore, or backslash"
+		default:
+			return "VALIDATION: name may contain only letters, digits, underscores, periods, and backslashes"
+		}
+	}
+	if _, _, err := excelize.CellNameToCoordinates(name); err == nil || r1c1Name.MatchString(name) {
+		return "VALIDATION: name cannot look like a cell reference"
+	}
+	return ""
+}
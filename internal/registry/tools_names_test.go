@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNamedRanges_CreateListReadDelete(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]any{"Region", "Amount"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]any{"North", 10}))
+	_, err := f.NewSheet("Q1 Data")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "names.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "create_named_range", map[string]any{"path": path, "name": "Sales", "sheet": "sheet1", "range": "B2:A1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(NamedRangesOutput)
+	require.Equal(t, "Sales", out.Name)
+	require.Equal(t, []DefinedNameInfo{{Name: "Sales", RefersTo: "Sheet1!$A$1:$B$2", Scope: "Workbook"}}, out.Names)
+
+	// Sheet-local single-cell names quote the sheet; the same name in another
+	// scope is allowed.
+	res = callTool(t, srv, "create_named_range", map[string]any{"path": path, "name": "sales", "sheet": "Q1 Data", "range": "C3", "scope": "Q1 Data"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, `name="sales" names=2 Sales=Sheet1!$A$1:$B$2 Q1 Data/sales='Q1 Data'!$C$3`, resultText(t, res))
+
+	res = callTool(t, srv, "list_named_ranges", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(NamedRangesOutput)
+	require.Equal(t, 2, out.Total)
+	require.Equal(t, DefinedNameInfo{Name: "sales", RefersTo: "'Q1 Data'!$C$3", Scope: "Q1 Data"}, out.Names[1])
+
+	// The new name is usable as a range right away.
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "Sales"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "North")
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"name": "SALES", "sheet": "Sheet1", "range": "A1"}, "already exists"},
+		{map[string]any{"name": "AB12", "sheet": "Sheet1", "range": "A1"}, "cell reference"},
+		{map[string]any{"name": "r2c3", "sheet": "Sheet1", "range": "A1"}, "cell reference"},
+		{map[string]any{"name": "1st", "sheet": "Sheet1", "range": "A1"}, "must start with"},
+		{map[string]any{"name": "Net Sales", "sheet": "Sheet1", "range": "A1"}, "only letters"},
+		{map[string]any{"name": "Other", "sheet": "Missing", "range": "A1"}, "INVALID_SHEET"},
+		{map[string]any{"name": "Other", "sheet": "Sheet1", "range": "A1", "scope": "Missing"}, "INVALID_SHEET"},
+	} {
+		tc.args["path"] = path
+		res = callTool(t, srv, "create_named_range", tc.args)
+		require.True(t, res.IsError, "%v", tc.args)
+		require.Contains(t, resultText(t, res), tc.want)
+	}
+
+	res = callTool(t, srv, "delete_named_range", map[string]any{"path": path, "name": "SALES"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(NamedRangesOutput)
+	require.Equal(t, []DefinedNameInfo{{Name: "sales", RefersTo: "'Q1 Data'!$C$3", Scope: "Q1 Data"}}, out.Names)
+
+	res = callTool(t, srv, "delete_named_range", map[string]any{"path": path, "name": "Sales"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "not found in scope Workbook")
+
+	res = callTool(t, srv, "delete_named_range", map[string]any{"path": path, "name": "sales", "scope": "q1 data"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Empty(t, res.StructuredContent.(NamedRangesOutput).Names)
+}
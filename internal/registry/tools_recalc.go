@@ -0,0 +1,345 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxRecalcFailures caps the per-cell failures echoed back to the client.
+const maxRecalcFailures = 20
+
+// RecalculateInput defines parameters for recalculate_workbook.
+type RecalculateInput struct {
+	Path    string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Sheet   string `json:"sheet" validate:"required" jsonschema_description:"Sheet whose formula cells are recalculated"`
+	RangeA1 string `json:"range,omitempty" validate:"omitempty,a1orname" jsonschema_description:"Optional A1 range or defined name; omitted means the sheet's used range"`
+	Backup  bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// RecalcFailure records a formula cell whose value could not be computed.
+type RecalcFailure struct {
+	Cell  string `json:"cell"`
+	Error string `json:"error"`
+}
+
+// RecalculateOutput reports how many formula cells were refreshed.
+type RecalculateOutput struct {
+	Path         string          `json:"path"`
+	Sheet        string          `json:"sheet"`
+	RangeA1      string          `json:"range"`
+	FormulaCells int             `json:"formulaCells"`
+	Recalculated int             `json:"recalculated" jsonschema_description:"Formula cells whose numeric result was stored as the cached value"`
+	Cleared      int             `json:"cleared" jsonschema_description:"Formula cells with text, boolean, or error results whose stale cached value was cleared"`
+	Failed       int             `json:"failed"`
+	Failures     []RecalcFailure `json:"failures,omitempty" jsonschema_description:"First failing cells (bounded); their cached values are left unchanged"`
+	Save         string          `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup       string          `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// formulaCell is a formula captured before any cached value is rewritten.
+type formulaCell struct {
+	cell    string
+	formula string
+	col     int
+	row     int
+}
+
+// RegisterRecalcTools registers recalculate_workbook (write-gated).
+func RegisterRecalcTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	recalc := mcp.NewTool(
+		"recalculate_workbook",
+		mcp.WithDescription(fmt.Sprintf("Recompute formula cells in a range (or the sheet's used range when range is omitted) with excelize's calculation engine, store numeric results as cached values, and save the workbook atomically so read_range returns fresh numbers after write_range or apply_formula. Text, boolean, and error results cannot be cached by excelize; their stale values are cleared (reported as cleared) and the workbook is flagged for full recalculation when Excel opens it. The range is capped at %d cells, as are the cells outside it belonging to shared formulas anchored in it, which the rewrite must preserve. Excelize does not implement every Excel function (volatile, dynamic‑array, external‑link, and some statistical/financial functions are unsupported); such cells are reported in failures and keep their previous cached value. Shared formulas in the sheet are rewritten as equivalent per‑cell formulas. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, PAYLOAD_TOO_LARGE, WRITE_FAILED.", limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[RecalculateInput](),
+		mcp.WithOutputSchema[RecalculateOutput](),
+		writeTool(false, true),
+	)
+	s.AddTool(recalc, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in RecalculateInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenByPath(ctx, strings.TrimSpace(in.Path))
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+
+		out := RecalculateOutput{Path: canonical, Sheet: sheet}
+		err := mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			used, _ := scanUsedRange(f, sheet)
+			rng := strings.TrimSpace(in.RangeA1)
+			if rng == "" {
+				rng = used
+			}
+			if rng == "" {
+				// Empty sheet: nothing to recalculate and nothing to save.
+				return nil
+			}
+			x1, y1, x2, y2, resolved, perr := resolveRange(f, sheet, rng)
+			if perr != nil {
+				return mcperr.Errorf(mcperr.Validation, "invalid range; use A1:D50 or a defined name")
+			}
+			out.RangeA1 = resolved
+			if cells := (x2 - x1 + 1) * (y2 - y1 + 1); cells > limits.MaxCellsPerOp {
+				return mcperr.Errorf(mcperr.PayloadTooLarge, "range has %d cells, max %d; recalculate smaller ranges", cells, limits.MaxCellsPerOp)
+			}
+
+			// Snapshot the formulas a rewrite can reach first: rewriting the
+			// anchor of a shared formula drops the formula from all cells in
+			// its group, which extends right and down from the anchor and may
+			// leave the target range. Only groups anchored in the range can be
+			// reached, and their cells outside it are bounded like the range.
+			areas := []cellArea{{x1, y1, x2, y2}}
+			groups, gerr := sharedFormulaGroups(ctx, f, sheet, areas[0])
+			if gerr != nil {
+				return gerr
+			}
+			if extra := areaCellsOutside(groups, areas[0]); extra > limits.MaxCellsPerOp {
+				return mcperr.Errorf(mcperr.PayloadTooLarge, "shared formulas anchored in %s cover %d cells outside it, max %d; recalculate a range without their anchor cells", resolved, extra, limits.MaxCellsPerOp)
+			}
+			all, serr := snapshotFormulas(ctx, f, sheet, append(areas, groups...))
+			if serr != nil {
+				return serr
+			}
+			var targets []formulaCell
+			for _, fc := range all {
+				if fc.col >= x1 && fc.col <= x2 && fc.row >= y1 && fc.row <= y2 {
+					targets = append(targets, fc)
+				}
+			}
+			out.FormulaCells = len(targets)
+			if len(targets) == 0 {
+				return nil
+			}
+
+			// Compute every value before writing so results reflect the
+			// workbook as it was, independent of iteration order.
+			values := make([]string, len(targets))
+			ok := make([]bool, len(targets))
+			for i, fc := range targets {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				v, cerr := f.CalcCellValue(sheet, fc.cell, excelize.Options{RawCellValue: true})
+				switch {
+				case cerr == nil:
+					values[i], ok[i] = v, true
+				case strings.HasPrefix(cerr.Error(), "#"):
+					// Formula errors such as #DIV/0! are legitimate results.
+					values[i], ok[i] = cerr.Error(), true
+				default:
+					out.Failed++
+					if len(out.Failures) < maxRecalcFailures {
+						out.Failures = append(out.Failures, RecalcFailure{Cell: fc.cell, Error: cerr.Error()})
+					}
+				}
+			}
+			if out.Failed == len(targets) {
+				return nil
+			}
+
+			for i, fc := range targets {
+				if !ok[i] {
+					continue
+				}
+				// Excelize setters drop the cell formula (restored below) and
+				// re-adding it marks the cached value as a formula string, so
+				// only numeric results survive as cached values.
+				if n, perr := strconv.ParseFloat(values[i], 64); perr == nil {
+					if werr := f.SetCellFloat(sheet, fc.cell, n, -1, 64); werr != nil {
+						return werr
+					}
+					out.Recalculated++
+					continue
+				}
+				if werr := f.SetCellValue(sheet, fc.cell, nil); werr != nil {
+					return werr
+				}
+				out.Cleared++
+			}
+			// Restore any formula dropped by a shared-group rewrite, including
+			// the targets whose cached value was just replaced.
+			for _, fc := range all {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if cur, _ := f.GetCellFormula(sheet, fc.cell); cur != fc.formula {
+					if ferr := f.SetCellFormula(sheet, fc.cell, fc.formula); ferr != nil {
+						return ferr
+					}
+				}
+			}
+			// Ask Excel to recompute everything on open, covering the
+			// results excelize could not cache.
+			fullCalc := true
+			if cerr := f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &fullCalc}); cerr != nil {
+				return cerr
+			}
+			written := make([][]string, 0, out.Recalculated+out.Cleared)
+			for i, fc := range targets {
+				if ok[i] {
+					written = append(written, []string{fc.cell, values[i]})
+				}
+			}
+			rec := audit.Record{Tool: "recalculate_workbook", Path: canonical, Sheet: sheet, Range: out.RangeA1, Cells: len(written), ContentHash: audit.HashValues(written)}
+			if aerr := reg.auditWrite(ctx, rec); aerr != nil {
+				return aerr
+			}
+			saved, err := save(canonical, in.Backup)
+			out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+			return err
+		})
+		if err != nil {
+			err = discardUnaudited(mgr, id, err)
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return structureEditError(err), nil
+		}
+
+		summary := fmt.Sprintf("formulas=%d recalculated=%d cleared=%d failed=%d range=%s", out.FormulaCells, out.Recalculated, out.Cleared, out.Failed, out.RangeA1) + saveSummary(out.Save, out.Backup)
+		if out.Failed > 0 {
+			summary += "; failed cells keep their previous cached value"
+		}
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(recalc)
+}
+
+// cellArea is an inclusive block of cells by 1-based column and row.
+type cellArea struct {
+	x1, y1, x2, y2 int
+}
+
+func (a cellArea) contains(col, row int) bool {
+	return col >= a.x1 && col <= a.x2 && row >= a.y1 && row <= a.y2
+}
+
+// sharedFormulaGroups returns the ref areas of the shared-formula groups whose
+// anchor cell lies in within. excelize has no getter for a formula's type or
+// ref, so the worksheet part is scanned for anchors (<f t="shared" ref="...">)
+// after Rows flushes the in-memory sheet to it.
+func sharedFormulaGroups(ctx context.Context, f *excelize.File, sheet string, within cellArea) ([]cellArea, error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	part, ok := worksheetPart(f, sheet)
+	if !ok {
+		return nil, nil
+	}
+	attr := func(se xml.StartElement, name string) string {
+		for _, a := range se.Attr {
+			if a.Name.Local == name {
+				return a.Value
+			}
+		}
+		return ""
+	}
+	var groups []cellArea
+	var cell string
+	dec := xml.NewDecoder(bytes.NewReader(pkgPart(f, part)))
+	for n := 0; ; n++ {
+		if n%4096 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return groups, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "c":
+			cell = attr(se, "r")
+		case "f":
+			ref := attr(se, "ref")
+			if attr(se, "t") != "shared" || ref == "" {
+				continue
+			}
+			col, row, cerr := excelize.CellNameToCoordinates(cell)
+			if cerr != nil || !within.contains(col, row) {
+				continue
+			}
+			from, to, _ := strings.Cut(ref, ":")
+			if to == "" {
+				to = from
+			}
+			gx1, gy1, e1 := excelize.CellNameToCoordinates(from)
+			gx2, gy2, e2 := excelize.CellNameToCoordinates(to)
+			if e1 == nil && e2 == nil {
+				groups = append(groups, cellArea{gx1, gy1, gx2, gy2})
+			}
+		}
+	}
+}
+
+// areaCellsOutside counts the cells of areas that lie outside base.
+func areaCellsOutside(areas []cellArea, base cellArea) int {
+	n := 0
+	for _, a := range areas {
+		total := (a.x2 - a.x1 + 1) * (a.y2 - a.y1 + 1)
+		ox := min(a.x2, base.x2) - max(a.x1, base.x1) + 1
+		oy := min(a.y2, base.y2) - max(a.y1, base.y1) + 1
+		if ox > 0 && oy > 0 {
+			total -= ox * oy
+		}
+		n += total
+	}
+	return n
+}
+
+// snapshotFormulas returns the formula cells within areas, each cell once, in
+// area then row-major order.
+func snapshotFormulas(ctx context.Context, f *excelize.File, sheet string, areas []cellArea) ([]formulaCell, error) {
+	var cells []formulaCell
+	for i, a := range areas {
+		for r := a.y1; r <= a.y2; r++ {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			for c := a.x1; c <= a.x2; c++ {
+				if seen := slices.ContainsFunc(areas[:i], func(p cellArea) bool { return p.contains(c, r) }); seen {
+					continue
+				}
+				name, _ := excelize.CoordinatesToCellName(c, r)
+				formula, ferr := f.GetCellFormula(sheet, name)
+				if ferr != nil {
+					return nil, ferr
+				}
+				if formula != "" {
+					cells = append(cells, formulaCell{cell: name, formula: formula, col: c, row: r})
+				}
+			}
+		}
+	}
+	return cells, nil
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestRecalculateWorkbook_RefreshesCachedValues(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	for r := 1; r <= 3; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, f.SetCellInt("Sheet1", cell, int64(r)))
+	}
+	require.NoError(t, f.SetCellFormula("Sheet1", "D1", "=NOSUCHFUNC(A1)"))
+	path := filepath.Join(t.TempDir(), "recalc.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "apply_formula", map[string]any{"path": path, "sheet": "Sheet1", "range": "B1:B3", "formula": "=A1*2"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	res = callTool(t, srv, "apply_formula", map[string]any{"path": path, "sheet": "Sheet1", "range": "C1:C1", "formula": "=1/0"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+
+	res = callTool(t, srv, "recalculate_workbook", map[string]any{"path": path, "sheet": "Sheet1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out RecalculateOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, "A1:D3", out.RangeA1)
+	require.Equal(t, 5, out.FormulaCells)
+	require.Equal(t, 3, out.Recalculated)
+	require.Equal(t, 1, out.Cleared)
+	require.Equal(t, 1, out.Failed)
+	require.Len(t, out.Failures, 1)
+	require.Equal(t, "D1", out.Failures[0].Cell)
+
+	g, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer g.Close()
+	for r, want := range []string{"2", "4", "6"} {
+		cell, _ := excelize.CoordinatesToCellName(2, r+1)
+		got, err := g.GetCellValue("Sheet1", cell)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		formula, err := g.GetCellFormula("Sheet1", cell)
+		require.NoError(t, err)
+		require.NotEmpty(t, formula, "formula must survive recalculation")
+	}
+	got, err := g.GetCellValue("Sheet1", "C1")
+	require.NoError(t, err)
+	require.Empty(t, got, "non-numeric results clear the stale cached value")
+	formula, err := g.GetCellFormula("Sheet1", "D1")
+	require.NoError(t, err)
+	require.Equal(t, "=NOSUCHFUNC(A1)", formula)
+}
+
+func TestRecalculateWorkbook_RangeKeepsSharedFormulaGroup(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	for r := 1; r <= 4; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		require.NoError(t, f.SetCellInt("Sheet1", cell, int64(r)))
+	}
+	shared, ref := excelize.STCellFormulaTypeShared, "B1:B4"
+	require.NoError(t, f.SetCellFormula("Sheet1", "B1", "A1+100", excelize.FormulaOpts{Type: &shared, Ref: &ref}))
+	path := filepath.Join(t.TempDir(), "shared.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "recalculate_workbook", map[string]any{"path": path, "sheet": "Sheet1", "range": "B1:B2"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out RecalculateOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, 2, out.Recalculated)
+
+	g, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer g.Close()
+	got, err := g.GetCellValue("Sheet1", "B2")
+	require.NoError(t, err)
+	require.Equal(t, "102", got)
+	for _, cell := range []string{"B3", "B4"} {
+		formula, err := g.GetCellFormula("Sheet1", cell)
+		require.NoError(t, err)
+		require.NotEmpty(t, formula, "%s lost its shared formula", cell)
+	}
+}
+
+func TestRecalculateWorkbook_Validation(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", 1))
+	path := filepath.Join(t.TempDir(), "v.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "recalculate_workbook", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:Z1000"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PAYLOAD_TOO_LARGE")
+
+	// A small range on a large sheet only snapshots the shared-formula
+	// groups anchored in it, and those groups are bounded too.
+	f = excelize.NewFile()
+	require.NoError(t, f.SetCellFormula("Sheet1", "A1", "1+1"))
+	require.NoError(t, f.SetCellFormula("Sheet1", "Y500", "2+2"))
+	require.NoError(t, f.SetCellValue("Sheet1", "Z500", 1))
+	shared, ref := excelize.STCellFormulaTypeShared, "C1:C20000"
+	require.NoError(t, f.SetCellFormula("Sheet1", "C1", "Z500*2", excelize.FormulaOpts{Type: &shared, Ref: &ref}))
+	wide := filepath.Join(t.TempDir(), "wide.xlsx")
+	require.NoError(t, f.SaveAs(wide))
+	require.NoError(t, f.Close())
+	res = callTool(t, srv, "recalculate_workbook", map[string]any{"path": wide, "sheet": "Sheet1", "range": "A1:B2"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out RecalculateOutput
+	decodeStructured(t, res, &out)
+	require.Equal(t, 1, out.Recalculated)
+	res = callTool(t, srv, "recalculate_workbook", map[string]any{"path": wide, "sheet": "Sheet1", "range": "C1:C2"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PAYLOAD_TOO_LARGE")
+	require.Contains(t, resultText(t, res), "cover 19998 cells outside it")
+	res = callTool(t, srv, "recalculate_workbook", map[string]any{"path": wide, "sheet": "Sheet1", "range": "C2:C3"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	decodeStructured(t, res, &out)
+	require.Equal(t, 2, out.Recalculated)
+
+	res = callTool(t, srv, "recalculate_workbook", map[string]any{"path": path, "sheet": "Nope"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_SHEET")
+}
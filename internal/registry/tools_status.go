@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/version"
+)
+
+// ServerStatusInput is empty; server_status takes no parameters.
+type ServerStatusInput struct{}
+
+// ServerStatusOutput reports lifecycle state and load.
+type ServerStatusOutput struct {
+	State            string `json:"state" jsonschema_description:"starting, ready, draining, or stopped"`
+	UptimeSeconds    int64  `json:"uptimeSeconds"`
+	OpenWorkbooks    int    `json:"openWorkbooks"`
+	InFlightRequests int64  `json:"inFlightRequests"`
+}
+
+// ServerInfoInput is empty; get_server_info takes no parameters.
+type ServerInfoInput struct{}
+
+// ServerInfoOutput identifies the server build and its enabled features. It
+// holds nothing that changes while the server runs.
+type ServerInfoOutput struct {
+	Version       string   `json:"version"`
+	GoVersion     string   `json:"goVersion"`
+	Commit        string   `json:"commit,omitempty" jsonschema_description:"Source revision of the build, when known"`
+	BuildDate     string   `json:"buildDate,omitempty" jsonschema_description:"Build or commit date, when known"`
+	WritesEnabled bool     `json:"writesEnabled"`
+	Transports    []string `json:"transports" jsonschema_description:"How the server is reached: stdio or http"`
+	Tools         int      `json:"tools" jsonschema_description:"Registered tools, including write tools hidden while writes are disabled"`
+}
+
+// RegisterStatusTools registers the read-only server_status and
+// get_server_info tools.
+func RegisterStatusTools(s *server.MCPServer, reg *Registry, ctrl *runtime.Controller, mgr *workbooks.Manager) {
+	status := mcp.NewTool(
+		"server_status",
+		mcp.WithDescription("Report server health: lifecycle state (starting, ready, draining, stopped), uptime, open workbook count, and in‑flight tool calls (including this one). Read‑only and cheap; remains callable while the server drains so supervisors can watch shutdown progress."),
+		mcp.WithInputSchema[ServerStatusInput](),
+		mcp.WithOutputSchema[ServerStatusOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(status, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ServerStatusInput) (*mcp.CallToolResult, error) {
+		lc := ctrl.Lifecycle()
+		out := ServerStatusOutput{
+			State:            lc.State().String(),
+			UptimeSeconds:    int64(time.Since(lc.StartedAt()).Seconds()),
+			OpenWorkbooks:    mgr.Count(),
+			InFlightRequests: ctrl.InFlight(),
+		}
+		summary := fmt.Sprintf("state=%s uptime=%ds openWorkbooks=%d inFlight=%d", out.State, out.UptimeSeconds, out.OpenWorkbooks, out.InFlightRequests)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(status)
+
+	info := mcp.NewTool(
+		"get_server_info",
+		mcp.WithDescription("Identify the server build: version, Go version, commit and build date (when known), whether write tools are enabled, the transports in use, and how many tools are registered. Read‑only and constant for the life of the process; quote it in bug reports."),
+		mcp.WithInputSchema[ServerInfoInput](),
+		mcp.WithOutputSchema[ServerInfoOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(info, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ServerInfoInput) (*mcp.CallToolResult, error) {
+		tools, _ := reg.Tools(ctx)
+		reg.mu.RLock()
+		filter := reg.writeFilter
+		transports := append([]string{}, reg.transports...)
+		reg.mu.RUnlock()
+		out := ServerInfoOutput{
+			Version:   version.Version(),
+			GoVersion: goruntime.Version(),
+			Commit:    version.Commit(),
+			BuildDate: version.Date(),
+			// Without a filter nothing is hidden.
+			WritesEnabled: filter == nil || filter.WritesEnabled(),
+			Transports:    transports,
+			Tools:         len(tools),
+		}
+		summary := fmt.Sprintf("version=%s go=%s writes=%t transports=%s tools=%d", out.Version, out.GoVersion, out.WritesEnabled, strings.Join(out.Transports, ","), out.Tools)
+		if out.Commit != "" {
+			summary += " commit=" + out.Commit
+		}
+		if out.BuildDate != "" {
+			summary += " built=" + out.BuildDate
+		}
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(info)
+}
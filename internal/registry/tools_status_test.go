@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"context"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/version"
+)
+
+func TestGetServerInfo(t *testing.T) {
+	limits := runtime.NewLimits(8, 8)
+	mgr := workbooks.NewManager(0, 0, nil, nil)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	srv := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	reg := New()
+	reg.SetWriteFilter(&WriteToolFilter{})
+	reg.SetTransports("stdio")
+	RegisterWorkbookTools(srv, reg, limits, mgr)
+	RegisterStatusTools(srv, reg, runtime.NewController(limits), mgr)
+
+	res := callTool(t, srv, "get_server_info", map[string]any{})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out ServerInfoOutput
+	decodeStructured(t, res, &out)
+	tools, err := reg.Tools(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, ServerInfoOutput{
+		Version:       version.Version(),
+		GoVersion:     goruntime.Version(),
+		Commit:        version.Commit(),
+		BuildDate:     version.Date(),
+		WritesEnabled: false,
+		Transports:    []string{"stdio"},
+		Tools:         len(tools),
+	}, out)
+	require.Contains(t, resultText(t, res), "writes=false transports=stdio")
+}
@@ -0,0 +1,422 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// RowEditInput defines parameters shared by insert_rows and delete_rows.
+type RowEditInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Sheet    string `json:"sheet" validate:"required" jsonschema_description:"Target sheet name"`
+	StartRow int    `json:"start_row" validate:"min=1" jsonschema_description:"1‑based row where the edit begins"`
+	Count    int    `json:"count" validate:"min=1" jsonschema_description:"Number of rows to insert or delete (bounded by server limits)"`
+	Force    bool   `json:"force,omitempty" jsonschema_description:"Edit even when the sheet is protected"`
+	Backup   bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// RowEditOutput reports the effect of a structural row edit.
+type RowEditOutput struct {
+	Path        string `json:"path"`
+	Sheet       string `json:"sheet"`
+	StartRow    int    `json:"startRow"`
+	Count       int    `json:"count"`
+	RowsDeleted int    `json:"rowsDeleted,omitempty"`
+	RowsShifted int    `json:"rowsShifted"`
+	Save        string `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup      string `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// AddSheetInput defines parameters for add_sheet.
+type AddSheetInput struct {
+	Path   string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Name   string `json:"name" validate:"required" jsonschema_description:"New sheet name (max 31 chars; no : \\ / ? * [ ])"`
+	Index  *int   `json:"index,omitempty" validate:"omitempty,min=0" jsonschema_description:"Optional 0‑based position from 0 to the current sheet count (which appends); appends when omitted"`
+	Backup bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// RenameSheetInput defines parameters for rename_sheet.
+type RenameSheetInput struct {
+	Path    string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Sheet   string `json:"sheet" validate:"required" jsonschema_description:"Existing sheet name"`
+	NewName string `json:"new_name" validate:"required" jsonschema_description:"New sheet name (max 31 chars; no : \\ / ? * [ ])"`
+	Backup  bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// DeleteSheetInput defines parameters for delete_sheet.
+type DeleteSheetInput struct {
+	Path   string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Sheet  string `json:"sheet" validate:"required" jsonschema_description:"Sheet to delete; the last remaining sheet cannot be deleted"`
+	Backup bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// CopySheetInput defines parameters for copy_sheet.
+type CopySheetInput struct {
+	Path   string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Source string `json:"source" validate:"required" jsonschema_description:"Existing sheet to copy"`
+	Target string `json:"target" validate:"required" jsonschema_description:"Name for the new copy (max 31 chars; no : \\ / ? * [ ])"`
+	Backup bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// SheetEditOutput reports the sheet affected by a sheet management tool and
+// the workbook's sheet list after the edit.
+type SheetEditOutput struct {
+	Path   string   `json:"path"`
+	Sheet  string   `json:"sheet"`
+	Sheets []string `json:"sheets"`
+	Save   string   `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup string   `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// RegisterStructureTools registers structural edit tools (write-gated).
+func RegisterStructureTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	maxRows := limits.MaxRowsPerEdit
+	if maxRows <= 0 {
+		maxRows = 1
+	}
+
+	// insert_rows
+	insertRows := mcp.NewTool(
+		"insert_rows",
+		mcp.WithDescription(fmt.Sprintf("Insert count blank rows before start_row and save the workbook atomically. Existing rows at or below start_row shift down; excelize adjusts formulas, merged ranges, and defined names that reference shifted cells. Pagination cursors issued before the edit become invalid (CURSOR_INVALID) because the file changes. count is capped at %d. Protected sheets are refused unless force=true. Write tool: hidden unless writes are enabled. Errors: VALIDATION, LIMIT_EXCEEDED, INVALID_SHEET, PERMISSION_DENIED, WRITE_FAILED.", maxRows)),
+		mcp.WithInputSchema[RowEditInput](),
+		mcp.WithOutputSchema[RowEditOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(insertRows, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in RowEditInput) (*mcp.CallToolResult, error) {
+		return runRowEdit(ctx, reg, mgr, maxRows, in, false)
+	}))
+	reg.Register(insertRows)
+
+	// delete_rows
+	deleteRows := mcp.NewTool(
+		"delete_rows",
+		mcp.WithDescription(fmt.Sprintf("Delete count rows starting at start_row and save the workbook atomically. Rows below the deleted block shift up; excelize adjusts formulas, merged ranges, and defined names that reference shifted cells (references into deleted rows may become invalid). Pagination cursors issued before the edit become invalid (CURSOR_INVALID) because the file changes. Deleting past the used range is a no‑op. count is capped at %d. Protected sheets are refused unless force=true. Write tool: hidden unless writes are enabled. Errors: VALIDATION, LIMIT_EXCEEDED, INVALID_SHEET, PERMISSION_DENIED, WRITE_FAILED.", maxRows)),
+		mcp.WithInputSchema[RowEditInput](),
+		mcp.WithOutputSchema[RowEditOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(deleteRows, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in RowEditInput) (*mcp.CallToolResult, error) {
+		return runRowEdit(ctx, reg, mgr, maxRows, in, true)
+	}))
+	reg.Register(deleteRows)
+
+	// add_sheet
+	addSheet := mcp.NewTool(
+		"add_sheet",
+		mcp.WithDescription("Add an empty worksheet, optionally at a 0‑based position (0 to the current sheet count, which appends; larger values are refused), and save the workbook atomically. Names follow Excel rules: 1–31 characters, none of : \\ / ? * [ ], no leading/trailing apostrophe, not 'History', and unique (case‑insensitive). Output includes the updated sheet list. Write tool: hidden unless writes are enabled. Errors: VALIDATION, WRITE_FAILED."),
+		mcp.WithInputSchema[AddSheetInput](),
+		mcp.WithOutputSchema[SheetEditOutput](),
+		writeTool(false, false),
+	)
+	s.AddTool(addSheet, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in AddSheetInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		name := strings.TrimSpace(in.Name)
+		if msg := validateSheetName(name); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		return runSheetEdit(ctx, reg, mgr, "add_sheet", in.Path, name, in.Backup, func(f *excelize.File) error {
+			if _, taken := resolveSheetName(f, name); taken {
+				return mcperr.Errorf(mcperr.Validation, "sheet %q already exists", name)
+			}
+			if n := len(f.GetSheetList()); in.Index != nil && *in.Index > n {
+				return mcperr.Errorf(mcperr.Validation, "index %d is out of range; use 0 to %d (%d appends)", *in.Index, n, n)
+			}
+			if _, err := f.NewSheet(name); err != nil {
+				return err
+			}
+			if in.Index != nil {
+				list := f.GetSheetList()
+				if *in.Index < len(list)-1 {
+					return f.MoveSheet(name, list[*in.Index])
+				}
+			}
+			return nil
+		})
+	}))
+	reg.Register(addSheet)
+
+	// rename_sheet
+	renameSheet := mcp.NewTool(
+		"rename_sheet",
+		mcp.WithDescription("Rename a worksheet and save the workbook atomically. Defined names that reference the old name are updated; cell formulas in other sheets are not rewritten and may break. The new name follows Excel rules (1–31 characters, none of : \\ / ? * [ ], no leading/trailing apostrophe, unique). Output includes the updated sheet list; cursors issued before the edit become invalid. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, WRITE_FAILED."),
+		mcp.WithInputSchema[RenameSheetInput](),
+		mcp.WithOutputSchema[SheetEditOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(renameSheet, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in RenameSheetInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		oldName := strings.TrimSpace(in.Sheet)
+		newName := strings.TrimSpace(in.NewName)
+		if msg := validateSheetName(newName); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		return runSheetEdit(ctx, reg, mgr, "rename_sheet", in.Path, newName, in.Backup, func(f *excelize.File) error {
+			actual, ok := resolveSheetName(f, oldName)
+			if !ok {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			// Allow case-only renames of the same sheet.
+			if _, taken := resolveSheetName(f, newName); taken && !strings.EqualFold(actual, newName) {
+				return mcperr.Errorf(mcperr.Validation, "sheet %q already exists", newName)
+			}
+			return f.SetSheetName(actual, newName)
+		})
+	}))
+	reg.Register(renameSheet)
+
+	// delete_sheet
+	deleteSheet := mcp.NewTool(
+		"delete_sheet",
+		mcp.WithDescription("Delete a worksheet and save the workbook atomically. Refuses to delete the last remaining sheet. Formulas elsewhere that referenced the deleted sheet are not rewritten and may produce #REF! in Excel. Output includes the updated sheet list; cursors issued before the edit become invalid. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, WRITE_FAILED."),
+		mcp.WithInputSchema[DeleteSheetInput](),
+		mcp.WithOutputSchema[SheetEditOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(deleteSheet, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in DeleteSheetInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		name := strings.TrimSpace(in.Sheet)
+		return runSheetEdit(ctx, reg, mgr, "delete_sheet", in.Path, name, in.Backup, func(f *excelize.File) error {
+			actual, ok := resolveSheetName(f, name)
+			if !ok {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			if f.SheetCount <= 1 {
+				return mcperr.Errorf(mcperr.Validation, "cannot delete the last remaining sheet")
+			}
+			return f.DeleteSheet(actual)
+		})
+	}))
+	reg.Register(deleteSheet)
+
+	// copy_sheet
+	copySheet := mcp.NewTool(
+		"copy_sheet",
+		mcp.WithDescription("Duplicate a worksheet's cells, styles, and merges into a new sheet appended to the workbook, then save atomically. Tables, charts, and pictures are not copied (excelize limitation). The target name follows Excel rules (1–31 characters, none of : \\ / ? * [ ], no leading/trailing apostrophe, unique). Output includes the updated sheet list. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, WRITE_FAILED."),
+		mcp.WithInputSchema[CopySheetInput](),
+		mcp.WithOutputSchema[SheetEditOutput](),
+		writeTool(false, false),
+	)
+	s.AddTool(copySheet, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in CopySheetInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		src := strings.TrimSpace(in.Source)
+		dst := strings.TrimSpace(in.Target)
+		if msg := validateSheetName(dst); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		return runSheetEdit(ctx, reg, mgr, "copy_sheet", in.Path, dst, in.Backup, func(f *excelize.File) error {
+			from, err := f.GetSheetIndex(src)
+			if err != nil || from < 0 {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			if _, taken := resolveSheetName(f, dst); taken {
+				return mcperr.Errorf(mcperr.Validation, "sheet %q already exists", dst)
+			}
+			to, err := f.NewSheet(dst)
+			if err != nil {
+				return err
+			}
+			return f.CopySheet(from, to)
+		})
+	}))
+	reg.Register(copySheet)
+}
+
+// runSheetEdit applies edit under the workbook write lock, audits it as tool,
+// saves atomically (after a backup when requested or configured), and
+// reports the resulting sheet list.
+func runSheetEdit(ctx context.Context, reg *Registry, mgr *workbooks.Manager, tool, path, sheet string, backup bool, edit func(*excelize.File) error) (*mcp.CallToolResult, error) {
+	id, canonical, openErr := mgr.GetOrOpenByPath(ctx, strings.TrimSpace(path))
+	if openErr != nil {
+		return openFailed(openErr), nil
+	}
+	out := SheetEditOutput{Path: canonical, Sheet: sheet}
+	err := mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := edit(f); err != nil {
+			return err
+		}
+		if err := reg.auditWrite(ctx, audit.Record{Tool: tool, Path: canonical, Sheet: sheet}); err != nil {
+			return err
+		}
+		saved, err := save(canonical, backup)
+		if err != nil {
+			return err
+		}
+		out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+		out.Sheets = f.GetSheetList()
+		return nil
+	})
+	if err != nil {
+		err = discardUnaudited(mgr, id, err)
+		return structureEditError(err), nil
+	}
+	summary := fmt.Sprintf("sheet=%q sheets=%d %v", out.Sheet, len(out.Sheets), out.Sheets) + saveSummary(out.Save, out.Backup)
+	return mcp.NewToolResultStructured(out, summary), nil
+}
+
+// validateSheetName checks Excel's worksheet naming rules and returns a
+// VALIDATION message, or "" when the name is acceptable.
+func validateSheetName(name string) string {
+	switch {
+	case name == "":
+		return "VALIDATION: sheet name is required"
+	case utf8.RuneCountInString(name) > excelize.MaxSheetNameLength:
+		return fmt.Sprintf("VALIDATION: sheet name exceeds %d characters", excelize.MaxSheetNameLength)
+	case strings.ContainsAny(name, ":\\/?*[]"):
+		return "VALIDATION: sheet name cannot contain any of : \\ / ? * [ ]"
+	case strings.HasPrefix(name, "'") || strings.HasSuffix(name, "'"):
+		return "VALIDATION: sheet name cannot begin or end with an apostrophe"
+	case strings.EqualFold(name, "History"):
+		return "VALIDATION: 'History' is a reserved sheet name"
+	}
+	return ""
+}
+
+// resolveSheetName returns the stored name of the sheet matching name
+// case-insensitively.
+func resolveSheetName(f *excelize.File, name string) (string, bool) {
+	for _, sh := range f.GetSheetList() {
+		if strings.EqualFold(sh, name) {
+			return sh, true
+		}
+	}
+	return "", false
+}
+
+// runRowEdit validates inputs and performs an insert or delete under the workbook write lock.
+func runRowEdit(ctx context.Context, reg *Registry, mgr *workbooks.Manager, maxRows int, in RowEditInput, del bool) (*mcp.CallToolResult, error) {
+	if msg := validation.ValidateStruct(in); msg != "" {
+		return mcperr.FromText(msg), nil
+	}
+	if in.Count > maxRows {
+		return mcperr.FromText(fmt.Sprintf("LIMIT_EXCEEDED: count %d exceeds max rows per edit (%d); split into smaller edits", in.Count, maxRows)), nil
+	}
+	if in.StartRow+in.Count-1 > excelize.TotalRows {
+		return mcperr.FromText(fmt.Sprintf("VALIDATION: start_row+count exceeds the sheet row limit (%d)", excelize.TotalRows)), nil
+	}
+	id, canonical, openErr := mgr.GetOrOpenByPath(ctx, strings.TrimSpace(in.Path))
+	if openErr != nil {
+		return openFailed(openErr), nil
+	}
+	sheet := strings.TrimSpace(in.Sheet)
+
+	out := RowEditOutput{Path: canonical, Sheet: sheet, StartRow: in.StartRow, Count: in.Count}
+	err := mgr.WithWrite(id, func(f *excelize.File, save workbooks.SaveFunc) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if idx, serr := f.GetSheetIndex(sheet); serr != nil || idx < 0 {
+			return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+		}
+		if err := checkSheetProtection(f, sheet, in.Force); err != nil {
+			return err
+		}
+		lastRow := usedLastRow(f, sheet)
+		if del {
+			end := in.StartRow + in.Count - 1
+			if in.StartRow <= lastRow {
+				out.RowsDeleted = minInt(end, lastRow) - in.StartRow + 1
+				if end < lastRow {
+					out.RowsShifted = lastRow - end
+				}
+			}
+			if out.RowsDeleted == 0 {
+				// Nothing in the used range to remove; avoid touching the file.
+				return nil
+			}
+			for i := 0; i < out.RowsDeleted; i++ {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if rerr := f.RemoveRow(sheet, in.StartRow); rerr != nil {
+					return rerr
+				}
+			}
+		} else {
+			if in.StartRow <= lastRow {
+				out.RowsShifted = lastRow - in.StartRow + 1
+			}
+			if ierr := f.InsertRows(sheet, in.StartRow, in.Count); ierr != nil {
+				return ierr
+			}
+		}
+		rec := audit.Record{Tool: "insert_rows", Path: canonical, Sheet: sheet, Range: fmt.Sprintf("%d:%d", in.StartRow, in.StartRow+in.Count-1)}
+		if del {
+			rec.Tool = "delete_rows"
+		}
+		if aerr := reg.auditWrite(ctx, rec); aerr != nil {
+			return aerr
+		}
+		saved, err := save(canonical, in.Backup)
+		out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+		return err
+	})
+	if err != nil {
+		err = discardUnaudited(mgr, id, err)
+		return structureEditError(err), nil
+	}
+
+	var summary string
+	if del {
+		summary = fmt.Sprintf("deleted=%d shifted=%d startRow=%d; cursors issued before this edit are invalid", out.RowsDeleted, out.RowsShifted, out.StartRow)
+	} else {
+		summary = fmt.Sprintf("inserted=%d shifted=%d startRow=%d; cursors issued before this edit are invalid", out.Count, out.RowsShifted, out.StartRow)
+	}
+	return mcp.NewToolResultStructured(out, summary+saveSummary(out.Save, out.Backup)), nil
+}
+
+// structureEditError maps errors from structural edits to tool error results.
+func structureEditError(err error) *mcp.CallToolResult {
+	if res := classifyError(err); res != nil {
+		return res
+	}
+	return mcperr.Wrapf(mcperr.WriteFailed, "%v", err)
+}
+
+// usedLastRow returns the last used row of a sheet, preferring the stored
+// dimension and falling back to a streaming scan when it is missing.
+func usedLastRow(f *excelize.File, sheet string) int {
+	dim, _ := f.GetSheetDimension(sheet)
+	if !strings.Contains(dim, ":") {
+		dim, _ = scanUsedRange(f, sheet)
+	}
+	parts := strings.Split(dim, ":")
+	if len(parts) != 2 {
+		return 0
+	}
+	_, y, err := excelize.CellNameToCoordinates(parts[1])
+	if err != nil {
+		return 0
+	}
+	return y
+}
+
+// minInt returns the smaller of two ints.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
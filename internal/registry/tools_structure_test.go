@@ -0,0 +1,186 @@
+package registry
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createNumberedWorkbook writes rows 1..n where column A holds "r<i>".
+func createNumberedWorkbook(t *testing.T, n int) string {
+	t.Helper()
+	f := excelize.NewFile()
+	for i := 1; i <= n; i++ {
+		cell, _ := excelize.CoordinatesToCellName(1, i)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &[]any{fmt.Sprintf("r%d", i)}))
+	}
+	path := filepath.Join(t.TempDir(), "rows.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func columnA(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	out := make([]string, 0, len(rows))
+	for _, r := range rows {
+		if len(r) > 0 {
+			out = append(out, r[0])
+		} else {
+			out = append(out, "")
+		}
+	}
+	return out
+}
+
+func TestDeleteRows_TopMiddlePastUsedRange(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createNumberedWorkbook(t, 6)
+
+	// Top: remove r1
+	res := callTool(t, srv, "delete_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 1, "count": 1})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(RowEditOutput)
+	require.Equal(t, 1, out.RowsDeleted)
+	require.Equal(t, 5, out.RowsShifted)
+	require.Equal(t, []string{"r2", "r3", "r4", "r5", "r6"}, columnA(t, path))
+
+	// Middle: remove r3,r4 (now rows 2-3)
+	res = callTool(t, srv, "delete_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 2, "count": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(RowEditOutput)
+	require.Equal(t, 2, out.RowsDeleted)
+	require.Equal(t, 2, out.RowsShifted)
+	require.Equal(t, []string{"r2", "r5", "r6"}, columnA(t, path))
+
+	// Past the used range: no-op
+	res = callTool(t, srv, "delete_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 10, "count": 5})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(RowEditOutput)
+	require.Zero(t, out.RowsDeleted)
+	require.Zero(t, out.RowsShifted)
+	require.Equal(t, []string{"r2", "r5", "r6"}, columnA(t, path))
+}
+
+func TestInsertRows_ShiftsAndSaves(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createNumberedWorkbook(t, 3)
+
+	res := callTool(t, srv, "insert_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 2, "count": 2})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(RowEditOutput)
+	require.Equal(t, 2, out.RowsShifted)
+	require.Equal(t, []string{"r1", "", "", "r2", "r3"}, columnA(t, path))
+}
+
+func TestRowEdit_ProtectedSheetNeedsForce(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createProtectedWorkbook(t)
+
+	res := callTool(t, srv, "insert_rows", map[string]any{"path": path, "sheet": "Locked", "start_row": 1, "count": 1})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PERMISSION_DENIED")
+
+	res = callTool(t, srv, "insert_rows", map[string]any{"path": path, "sheet": "Locked", "start_row": 1, "count": 1, "force": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, 1, res.StructuredContent.(RowEditOutput).RowsShifted)
+}
+
+func TestRowEdit_Validation(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createNumberedWorkbook(t, 3)
+
+	res := callTool(t, srv, "insert_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 0, "count": 1})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+
+	res = callTool(t, srv, "delete_rows", map[string]any{"path": path, "sheet": "Sheet1", "start_row": 1, "count": 1_000_000})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "LIMIT_EXCEEDED")
+
+	res = callTool(t, srv, "delete_rows", map[string]any{"path": path, "sheet": "Nope", "start_row": 1, "count": 1})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_SHEET")
+}
+
+func TestSheetTools_AddRenameCopyDelete(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createNumberedWorkbook(t, 2)
+
+	res := callTool(t, srv, "add_sheet", map[string]any{"path": path, "name": "Summary", "index": 0})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []string{"Summary", "Sheet1"}, res.StructuredContent.(SheetEditOutput).Sheets)
+
+	res = callTool(t, srv, "rename_sheet", map[string]any{"path": path, "sheet": "sheet1", "new_name": "Data"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []string{"Summary", "Data"}, res.StructuredContent.(SheetEditOutput).Sheets)
+
+	res = callTool(t, srv, "copy_sheet", map[string]any{"path": path, "source": "Data", "target": "Data Copy"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []string{"Summary", "Data", "Data Copy"}, res.StructuredContent.(SheetEditOutput).Sheets)
+
+	res = callTool(t, srv, "delete_sheet", map[string]any{"path": path, "sheet": "Summary"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []string{"Data", "Data Copy"}, res.StructuredContent.(SheetEditOutput).Sheets)
+
+	// Persisted to disk, including copied cell values.
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.Equal(t, []string{"Data", "Data Copy"}, f.GetSheetList())
+	v, err := f.GetCellValue("Data Copy", "A2")
+	require.NoError(t, err)
+	require.Equal(t, "r2", v)
+}
+
+func TestAddSheet_IndexBounds(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createNumberedWorkbook(t, 2)
+
+	// The sheet count itself appends; anything past it is refused unsaved.
+	res := callTool(t, srv, "add_sheet", map[string]any{"path": path, "name": "Tail", "index": 1})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []string{"Sheet1", "Tail"}, res.StructuredContent.(SheetEditOutput).Sheets)
+
+	res = callTool(t, srv, "add_sheet", map[string]any{"path": path, "name": "Far", "index": 3})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: index 3 is out of range; use 0 to 2 (2 appends)")
+
+	res = callTool(t, srv, "add_sheet", map[string]any{"path": path, "name": "Middle", "index": 1})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []string{"Sheet1", "Middle", "Tail"}, res.StructuredContent.(SheetEditOutput).Sheets)
+}
+
+func TestSheetTools_Validation(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createNumberedWorkbook(t, 1)
+
+	cases := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"add_sheet", map[string]any{"name": "this-name-is-way-longer-than-31-chars"}, "VALIDATION: sheet name exceeds 31"},
+		{"add_sheet", map[string]any{"name": "Q1/Q2"}, "VALIDATION: sheet name cannot contain"},
+		{"add_sheet", map[string]any{"name": "'quoted'"}, "VALIDATION: sheet name cannot begin or end"},
+		{"add_sheet", map[string]any{"name": "SHEET1"}, "VALIDATION: sheet \"SHEET1\" already exists"},
+		{"add_sheet", map[string]any{"name": "Late", "index": 5}, "VALIDATION: index 5 is out of range; use 0 to 1 (1 appends)"},
+		{"rename_sheet", map[string]any{"sheet": "Missing", "new_name": "X"}, "INVALID_SHEET"},
+		{"copy_sheet", map[string]any{"source": "Sheet1", "target": "a[b]"}, "VALIDATION: sheet name cannot contain"},
+		{"delete_sheet", map[string]any{"sheet": "Sheet1"}, "VALIDATION: cannot delete the last remaining sheet"},
+	}
+	for _, tc := range cases {
+		tc.args["path"] = path
+		res := callTool(t, srv, tc.tool, tc.args)
+		require.True(t, res.IsError, tc.tool)
+		require.Contains(t, resultText(t, res), tc.want, tc.tool)
+	}
+}
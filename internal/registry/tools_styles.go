@@ -0,0 +1,364 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxStyleCells caps read_styles: every cell costs a style lookup and a
+// record several times larger than a bare value.
+const maxStyleCells = 500
+
+// ReadStylesInput defines parameters for read_styles.
+type ReadStylesInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet    string `json:"sheet" validate:"required" jsonschema_description:"Sheet name (case‑insensitive)"`
+	RangeA1  string `json:"range" validate:"required,a1orname" jsonschema_description:"A1 range or defined name; cells past the cap are not returned"`
+}
+
+// CellStyle is the formatting of one cell. Fields at their defaults (no
+// fill, regular font, General format, unmerged) are omitted.
+type CellStyle struct {
+	Cell      string `json:"cell"`
+	Fill      string `json:"fill,omitempty" jsonschema_description:"Fill color as RGB hex (e.g., FF0000); empty when the cell has no fill"`
+	FontColor string `json:"fontColor,omitempty" jsonschema_description:"Font color as RGB hex; omitted for the default black"`
+	Bold      bool   `json:"bold,omitempty"`
+	Italic    bool   `json:"italic,omitempty"`
+	NumFmt    string `json:"numFmt,omitempty" jsonschema_description:"Number format code (e.g., #,##0.00 or yyyy-mm-dd); omitted for General"`
+	Merged    string `json:"merged,omitempty" jsonschema_description:"Merged region containing the cell (e.g., A1:C1)"`
+}
+
+// ReadStylesMeta reports how much of the range was returned.
+type ReadStylesMeta struct {
+	Total     int  `json:"total"`
+	Returned  int  `json:"returned"`
+	Truncated bool `json:"truncated"`
+	MaxCells  int  `json:"maxCells" jsonschema_description:"Per-call cell cap; request later cells as a separate range"`
+}
+
+// ReadStylesOutput lists per-cell formatting in row-major order.
+type ReadStylesOutput struct {
+	Path    string         `json:"path"`
+	Sheet   string         `json:"sheet"`
+	RangeA1 string         `json:"range"`
+	Cells   []CellStyle    `json:"cells"`
+	Meta    ReadStylesMeta `json:"meta"`
+}
+
+// maxNumFmtLen is Excel's limit on a number format code.
+const maxNumFmtLen = 255
+
+// FormatRangeInput defines parameters for format_range.
+type FormatRangeInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet    string `json:"sheet" validate:"required" jsonschema_description:"Target sheet name"`
+	RangeA1  string `json:"range" validate:"required,a1orname" jsonschema_description:"A1 range or defined name to format"`
+	NumFmt   string `json:"num_format,omitempty" validate:"omitempty,max=255" jsonschema_description:"Excel number format code, e.g. '$#,##0.00', '0.0%', or 'yyyy-mm-dd'"`
+	Bold     *bool  `json:"bold,omitempty" jsonschema_description:"Set (true) or clear (false) bold; omitted leaves the font unchanged"`
+	Fill     string `json:"fill,omitempty" jsonschema_description:"Solid fill color as RGB hex (e.g., FFFF00 or #FFFF00); 'none' removes the fill"`
+	Force    bool   `json:"force,omitempty" jsonschema_description:"Format even when the sheet is protected"`
+	Backup   bool   `json:"backup,omitempty" jsonschema_description:"Copy the workbook to a timestamped backup before saving (see restore_backup); always done when the server has a backup directory"`
+}
+
+// FormatRangeOutput reports the formatted range.
+type FormatRangeOutput struct {
+	Path           string `json:"path"`
+	Sheet          string `json:"sheet"`
+	RangeA1        string `json:"range"`
+	CellsFormatted int    `json:"cellsFormatted"`
+	Save           string `json:"save,omitempty" jsonschema_description:"immediate when written to disk before returning, deferred when batched into a later flush (see flush_workbook)"`
+	Backup         string `json:"backup,omitempty" jsonschema_description:"Backup of the workbook taken before this save; pass it to restore_backup to undo the write"`
+}
+
+// formatChange is the formatting format_range applies on top of each cell's
+// existing style.
+type formatChange struct {
+	numFmt string
+	bold   *bool
+	fill   string // RGB hex, "none", or "" for unchanged
+}
+
+// parseFillColor normalizes a fill input to RGB hex or "none".
+func parseFillColor(v string) (string, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "#")
+	if strings.EqualFold(v, "none") {
+		return "none", true
+	}
+	if len(v) != 6 {
+		return "", false
+	}
+	for _, c := range v {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", false
+		}
+	}
+	return strings.ToUpper(v), true
+}
+
+// apply returns st with the change applied.
+func (c formatChange) apply(st *excelize.Style) *excelize.Style {
+	if c.numFmt != "" {
+		fmtCode := c.numFmt
+		st.CustomNumFmt = &fmtCode
+	}
+	if c.bold != nil {
+		if st.Font == nil {
+			st.Font = &excelize.Font{}
+		}
+		st.Font.Bold = *c.bold
+	}
+	switch c.fill {
+	case "":
+	case "none":
+		st.Fill = excelize.Fill{}
+	default:
+		st.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{c.fill}}
+	}
+	return st
+}
+
+// builtinNumFmts maps built-in number format IDs to their format codes
+// (ECMA-376 §18.8.30); excelize does not export its table.
+var builtinNumFmts = map[int]string{
+	1: "0", 2: "0.00", 3: "#,##0", 4: "#,##0.00", 9: "0%", 10: "0.00%",
+	11: "0.00E+00", 12: "# ?/?", 13: "# ??/??", 14: "mm-dd-yy", 15: "d-mmm-yy",
+	16: "d-mmm", 17: "mmm-yy", 18: "h:mm AM/PM", 19: "h:mm:ss AM/PM", 20: "h:mm",
+	21: "h:mm:ss", 22: "m/d/yy h:mm", 37: "#,##0 ;(#,##0)", 38: "#,##0 ;[Red](#,##0)",
+	39: "#,##0.00;(#,##0.00)", 40: "#,##0.00;[Red](#,##0.00)", 45: "mm:ss",
+	46: "[h]:mm:ss", 47: "mm:ss.0", 48: "##0.0E+0", 49: "@",
+}
+
+// styleReader resolves style IDs to CellStyle fields, caching each ID.
+type styleReader struct {
+	f     *excelize.File
+	cache map[int]CellStyle
+}
+
+func (r *styleReader) style(id int) CellStyle {
+	if cs, ok := r.cache[id]; ok {
+		return cs
+	}
+	var cs CellStyle
+	if st, err := r.f.GetStyle(id); err == nil {
+		if st.Fill.Pattern != 0 || st.Fill.Type == "gradient" {
+			if len(st.Fill.Color) > 0 {
+				cs.Fill = strings.ToUpper(st.Fill.Color[0])
+			}
+		}
+		if st.Font != nil {
+			cs.Bold, cs.Italic = st.Font.Bold, st.Font.Italic
+			// Indexed color 0 is indistinguishable from no color, so only an
+			// explicit RGB, theme, or non-zero index is resolved.
+			if st.Font.Color != "" || st.Font.ColorTheme != nil || st.Font.ColorIndexed != 0 {
+				c := strings.ToUpper(r.f.GetBaseColor(st.Font.Color, st.Font.ColorIndexed, st.Font.ColorTheme))
+				if c != "000000" {
+					cs.FontColor = c
+				}
+			}
+		}
+		switch {
+		case st.CustomNumFmt != nil:
+			cs.NumFmt = *st.CustomNumFmt
+		default:
+			cs.NumFmt = builtinNumFmts[st.NumFmt]
+		}
+	}
+	r.cache[id] = cs
+	return cs
+}
+
+// RegisterStyleTools registers read_styles and the write-gated format_range.
+func RegisterStyleTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	tool := mcp.NewTool(
+		"read_styles",
+		mcp.WithDescription(fmt.Sprintf("Return the formatting of each cell in a small range: fill color, font color, bold, italic, number format code, and the merged region the cell belongs to. Formatting often carries meaning values miss (highlighted problem rows, bold subtotals); combine with read_range or filter_data to act on it. Text output is a one‑line summary followed by a JSON array of records keyed by cell reference, in row‑major order; fields at their defaults (no fill, regular font, General format, unmerged) are omitted. At most %d cells are returned per call; meta.truncated marks a cut range, so request later cells as a separate range. Errors: VALIDATION, INVALID_SHEET, READ_FAILED.", maxStyleCells)),
+		mcp.WithInputSchema[ReadStylesInput](),
+		mcp.WithOutputSchema[ReadStylesOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(tool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ReadStylesInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		out := ReadStylesOutput{Path: canonical, Sheet: sheet, Cells: []CellStyle{}, Meta: ReadStylesMeta{MaxCells: maxStyleCells}}
+		reg.changes.observeWorkbook(ctx, mgr, id, canonical)
+		err := mgr.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+			if !sheetExists(f, sheet) {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			x1, y1, x2, y2, a1, perr := resolveRange(f, sheet, strings.TrimSpace(in.RangeA1))
+			if perr != nil {
+				return perr
+			}
+			out.RangeA1 = a1
+			out.Meta.Total = (x2 - x1 + 1) * (y2 - y1 + 1)
+			merges, merr := mergedRegionsInRange(f, sheet, x1, y1, x2, y2)
+			if merr != nil {
+				return merr
+			}
+			styles := &styleReader{f: f, cache: make(map[int]CellStyle)}
+			for row := y1; row <= y2 && len(out.Cells) < maxStyleCells; row++ {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				for col := x1; col <= x2 && len(out.Cells) < maxStyleCells; col++ {
+					cell, _ := excelize.CoordinatesToCellName(col, row)
+					var cs CellStyle
+					if sid, serr := f.GetCellStyle(sheet, cell); serr == nil && sid != 0 {
+						cs = styles.style(sid)
+					}
+					cs.Cell = cell
+					if mr, ok := findMergedRegion(merges, col, row); ok {
+						start, _ := excelize.CoordinatesToCellName(mr.x1, mr.y1)
+						end, _ := excelize.CoordinatesToCellName(mr.x2, mr.y2)
+						cs.Merged = start + ":" + end
+					}
+					out.Cells = append(out.Cells, cs)
+				}
+			}
+			out.Meta.Returned = len(out.Cells)
+			out.Meta.Truncated = out.Meta.Returned < out.Meta.Total
+			runtime.CallStatsFrom(ctx).AddCells(out.Meta.Returned)
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+		}
+
+		runtime.CallStatsFrom(ctx).SetResult(out.Meta.Returned, out.Meta.Truncated)
+		body, _ := json.Marshal(out.Cells)
+		summary := fmt.Sprintf("total=%d returned=%d truncated=%v maxCells=%d", out.Meta.Total, out.Meta.Returned, out.Meta.Truncated, maxStyleCells)
+		res := mcp.NewToolResultStructured(out, "styles read")
+		res.Content = []mcp.Content{mcp.NewTextContent(summary + "\n" + string(body))}
+		return res, nil
+	}))
+	reg.Register(tool)
+
+	// format_range
+	format := mcp.NewTool(
+		"format_range",
+		mcp.WithDescription(fmt.Sprintf("Apply a number format (e.g., '$#,##0.00', '0.0%%', 'yyyy-mm-dd') and optionally bold and a solid fill color to every cell of a range, keeping each cell's other formatting (borders, fonts, alignment). Use after writing computed columns so values display as currency, percentages, or dates. Returns cellsFormatted. At least one of num_format, bold, or fill is required; num_format is at most %d characters. The range is capped at %d cells. Protected sheets are refused unless force=true. The workbook is saved atomically; on failure the file is left unchanged. Write tool: hidden unless writes are enabled. Errors: VALIDATION, INVALID_SHEET, PAYLOAD_TOO_LARGE, PERMISSION_DENIED, WRITE_FAILED.", maxNumFmtLen, limits.MaxCellsPerOp)),
+		mcp.WithInputSchema[FormatRangeInput](),
+		mcp.WithOutputSchema[FormatRangeOutput](),
+		writeTool(false, true),
+	)
+	s.AddTool(format, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in FormatRangeInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		change := formatChange{numFmt: in.NumFmt, bold: in.Bold}
+		if strings.TrimSpace(in.Fill) != "" {
+			fill, ok := parseFillColor(in.Fill)
+			if !ok {
+				return mcperr.New(mcperr.Validation, "fill must be a 6-digit RGB hex color or 'none'"), nil
+			}
+			change.fill = fill
+		}
+		if change.numFmt == "" && change.bold == nil && change.fill == "" {
+			return mcperr.New(mcperr.Validation, "provide at least one of num_format, bold, or fill"), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		sheet := strings.TrimSpace(in.Sheet)
+		out := FormatRangeOutput{Path: canonical, Sheet: sheet}
+		var mutated bool
+		// Styles only touch the target sheet and the lock-guarded style table,
+		// so other sheets stay readable while the range is reformatted.
+		err := mgr.WithSheetWrite(id, sheet, func(f *excelize.File, save workbooks.SaveFunc) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !sheetExists(f, sheet) {
+				return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+			}
+			if err := checkSheetProtection(f, sheet, in.Force); err != nil {
+				return err
+			}
+			x1, y1, x2, y2, a1, perr := resolveRange(f, sheet, strings.TrimSpace(in.RangeA1))
+			if perr != nil {
+				return perr
+			}
+			out.RangeA1 = a1
+			cells := (x2 - x1 + 1) * (y2 - y1 + 1)
+			if cells > limits.MaxCellsPerOp {
+				return mcperr.Errorf(mcperr.PayloadTooLarge, "range has %d cells, max %d per operation; reduce range size or split into batches", cells, limits.MaxCellsPerOp)
+			}
+			// Each distinct existing style maps to one new style, so cells keep
+			// their other formatting and the style table grows by at most the
+			// number of distinct styles in the range.
+			restyled := make(map[int]int)
+			for row := y1; row <= y2; row++ {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				for col := x1; col <= x2; col++ {
+					cell, _ := excelize.CoordinatesToCellName(col, row)
+					old, serr := f.GetCellStyle(sheet, cell)
+					if serr != nil {
+						return serr
+					}
+					next, seen := restyled[old]
+					if !seen {
+						st, gerr := f.GetStyle(old)
+						if gerr != nil {
+							return gerr
+						}
+						if next, gerr = f.NewStyle(change.apply(st)); gerr != nil {
+							return gerr
+						}
+						restyled[old] = next
+					}
+					mutated = true
+					if err := f.SetCellStyle(sheet, cell, cell, next); err != nil {
+						return err
+					}
+				}
+			}
+			out.CellsFormatted = cells
+			bold := ""
+			if change.bold != nil {
+				bold = fmt.Sprint(*change.bold)
+			}
+			if err := reg.auditWrite(ctx, audit.Record{Tool: "format_range", Path: canonical, Sheet: sheet, Range: a1, Cells: cells, ContentHash: audit.HashValues([][]string{{change.numFmt, bold, change.fill}})}); err != nil {
+				return err
+			}
+			saved, err := save(canonical, in.Backup)
+			out.Save, out.Backup = saveMode(saved.Deferred), saved.Backup
+			return err
+		})
+		if err != nil {
+			// Styles applied in memory but never saved must not reach later
+			// calls; dropping the handle reloads the untouched file.
+			if mutated {
+				err = discardFailedWrite(mgr, id, err)
+			}
+			return structureEditError(err), nil
+		}
+		summary := fmt.Sprintf("formatted=%d range=%s", out.CellsFormatted, out.RangeA1) + saveSummary(out.Save, out.Backup)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(format)
+}
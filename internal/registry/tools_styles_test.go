@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestReadStyles_FillFontFormatMerged(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]any{"Region", "Amount", "Note"}))
+	require.NoError(t, f.SetSheetRow(sh, "A2", &[]any{"North", 1200.5, "check"}))
+	require.NoError(t, f.SetSheetRow(sh, "A3", &[]any{"Total", 1200.5}))
+	red, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FF0000"}}})
+	require.NoError(t, err)
+	subtotal, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Italic: true, Color: "0000FF"}, NumFmt: 4})
+	require.NoError(t, err)
+	custom := "yyyy-mm-dd"
+	dated, err := f.NewStyle(&excelize.Style{CustomNumFmt: &custom})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle(sh, "A2", "C2", red))
+	require.NoError(t, f.SetCellStyle(sh, "B3", "B3", subtotal))
+	require.NoError(t, f.SetCellStyle(sh, "C1", "C1", dated))
+	require.NoError(t, f.MergeCell(sh, "B3", "C3"))
+	path := filepath.Join(t.TempDir(), "styles.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "read_styles", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C3"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadStylesOutput)
+	require.Equal(t, ReadStylesMeta{Total: 9, Returned: 9, MaxCells: maxStyleCells}, out.Meta)
+	require.Equal(t, []CellStyle{
+		{Cell: "A1"},
+		{Cell: "B1"},
+		{Cell: "C1", NumFmt: "yyyy-mm-dd"},
+		{Cell: "A2", Fill: "FF0000"},
+		{Cell: "B2", Fill: "FF0000"},
+		{Cell: "C2", Fill: "FF0000"},
+		{Cell: "A3"},
+		{Cell: "B3", FontColor: "0000FF", Bold: true, Italic: true, NumFmt: "#,##0.00", Merged: "B3:C3"},
+		{Cell: "C3", Merged: "B3:C3"},
+	}, out.Cells)
+	summary, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, "total=9 returned=9 truncated=false maxCells=500", summary)
+	var records []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(body), &records))
+	require.Equal(t, map[string]any{"cell": "A2", "fill": "FF0000"}, records[3])
+
+	// Ranges past the cap are cut row-major.
+	res = callTool(t, srv, "read_styles", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:J60"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ReadStylesOutput)
+	require.Equal(t, ReadStylesMeta{Total: 600, Returned: maxStyleCells, Truncated: true, MaxCells: maxStyleCells}, out.Meta)
+	require.Equal(t, "J50", out.Cells[maxStyleCells-1].Cell)
+
+	res = callTool(t, srv, "read_styles", map[string]any{"path": path, "sheet": "Missing", "range": "A1:B2"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_SHEET")
+}
+
+func TestFormatRange_AppliesFormatKeepingStyles(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	sh := "Sheet1"
+	require.NoError(t, f.SetSheetRow(sh, "A1", &[]any{0.125, 0.5, 1234.5}))
+	italic, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Italic: true}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle(sh, "B1", "B1", italic))
+	path := filepath.Join(t.TempDir(), "format.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "format_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B1", "num_format": "0.0%", "bold": true, "fill": "#ffff00"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(FormatRangeOutput)
+	require.Equal(t, 2, out.CellsFormatted)
+	require.Equal(t, "A1:B1", out.RangeA1)
+
+	res = callTool(t, srv, "read_styles", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:C1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, []CellStyle{
+		{Cell: "A1", Fill: "FFFF00", Bold: true, NumFmt: "0.0%"},
+		{Cell: "B1", Fill: "FFFF00", Bold: true, Italic: true, NumFmt: "0.0%"},
+		{Cell: "C1"},
+	}, res.StructuredContent.(ReadStylesOutput).Cells)
+	res = callTool(t, srv, "read_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:B1"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body := splitSummary(t, resultText(t, res))
+	require.Equal(t, `[["12.5%","50.0%"]]`, body)
+
+	// The change was saved to disk.
+	saved, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer saved.Close()
+	v, err := saved.GetCellValue(sh, "A1")
+	require.NoError(t, err)
+	require.Equal(t, "12.5%", v)
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"path": path, "sheet": "Sheet1", "range": "A1"}, "VALIDATION: provide at least one"},
+		{map[string]any{"path": path, "sheet": "Sheet1", "range": "A1", "fill": "red"}, "VALIDATION: fill must be"},
+		{map[string]any{"path": path, "sheet": "Sheet1", "range": "A1", "num_format": strings.Repeat("0", maxNumFmtLen+1)}, "VALIDATION"},
+		{map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:Z1000", "bold": true}, "PAYLOAD_TOO_LARGE"},
+		{map[string]any{"path": path, "sheet": "Nope", "range": "A1", "bold": true}, "INVALID_SHEET"},
+	} {
+		res = callTool(t, srv, "format_range", tc.args)
+		require.True(t, res.IsError, "%v", tc.args)
+		require.Contains(t, resultText(t, res), tc.want)
+	}
+}
@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxListedTables caps the tables returned by list_tables.
+const maxListedTables = 200
+
+// ListTablesInput defines parameters for list_tables.
+type ListTablesInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Sheet    string `json:"sheet,omitempty" jsonschema_description:"Only list tables on this sheet; all sheets when omitted"`
+}
+
+// TableDetail describes an Excel table (ListObject).
+type TableDetail struct {
+	Name      string   `json:"name"`
+	Sheet     string   `json:"sheet"`
+	Range     string   `json:"range" jsonschema_description:"Full table range including header and totals rows"`
+	DataRange string   `json:"dataRange,omitempty" jsonschema_description:"Rows between the header and totals rows; empty when the table has no data rows"`
+	Columns   []string `json:"columns"`
+	Style     string   `json:"style,omitempty"`
+	HeaderRow bool     `json:"headerRow"`
+	TotalsRow bool     `json:"totalsRow"`
+}
+
+// ListTablesOutput lists Excel tables in sheet order.
+type ListTablesOutput struct {
+	Path      string        `json:"path"`
+	Tables    []TableDetail `json:"tables"`
+	Total     int           `json:"total"`
+	Truncated bool          `json:"truncated,omitempty"`
+}
+
+// ReadTableInput defines parameters for read_table; paging options are
+// passed through to read_range.
+type ReadTableInput struct {
+	Path       string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password   string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+	Table      string `json:"table,omitempty" jsonschema_description:"Table name (case‑insensitive); required unless cursor is given"`
+	DataOnly   bool   `json:"data_only,omitempty" jsonschema_description:"Read only the data rows, leaving out the header row"`
+	MaxCells   int    `json:"max_cells,omitempty" validate:"omitempty,min=1" jsonschema_description:"Max cells per page before truncation (unit=cells)"`
+	Cursor     string `json:"cursor,omitempty" validate:"omitempty,cursor" jsonschema_description:"Opaque cursor from a previous read_table or read_range page; takes precedence over table"`
+	Encoding   string `json:"encoding,omitempty" validate:"omitempty,oneof=json csv markdown records" jsonschema_description:"Output encoding: json (default), csv, markdown, or records (objects keyed by the header row)"`
+	CellDetail bool   `json:"cell_detail,omitempty" jsonschema_description:"Emit {v, f, t} objects per cell; divides the page size by 3"`
+	CellWidth  int    `json:"cell_width,omitempty" validate:"omitempty,min=1" jsonschema_description:"Markdown only: truncate cells longer than this many characters"`
+	ValueMode  string `json:"value_mode,omitempty" jsonschema_description:"Cell values: formatted (default), raw (stored value), or typed (JSON numbers/booleans/ISO-8601 dates)"`
+}
+
+// tablePart is the subset of a table part (xl/tables/tableN.xml) that
+// excelize's Table does not expose.
+type tablePart struct {
+	Name           string `xml:"name,attr"`
+	HeaderRowCount *int   `xml:"headerRowCount,attr"`
+	TotalsRowCount int    `xml:"totalsRowCount,attr"`
+	Columns        []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"tableColumns>tableColumn"`
+}
+
+// tableParts decodes every table part in f keyed by lower-cased table name.
+func tableParts(f *excelize.File) map[string]tablePart {
+	parts := make(map[string]tablePart)
+	f.Pkg.Range(func(k, v any) bool {
+		name, _ := k.(string)
+		data, _ := v.([]byte)
+		if !strings.HasPrefix(name, "xl/tables/") || !strings.HasSuffix(name, ".xml") {
+			return true
+		}
+		var tp tablePart
+		if err := xml.Unmarshal(data, &tp); err == nil && tp.Name != "" {
+			parts[strings.ToLower(tp.Name)] = tp
+		}
+		return true
+	})
+	return parts
+}
+
+// collectTables lists the tables on sheets in order, calling visit for each
+// until it returns false.
+func collectTables(f *excelize.File, sheets []string, visit func(TableDetail) bool) error {
+	parts := tableParts(f)
+	for _, sheet := range sheets {
+		tables, err := f.GetTables(sheet)
+		if err != nil {
+			return err
+		}
+		for _, t := range tables {
+			td := TableDetail{Name: t.Name, Sheet: sheet, Range: strings.ReplaceAll(t.Range, "$", ""), Style: t.StyleName, Columns: []string{}}
+			tp := parts[strings.ToLower(t.Name)]
+			td.HeaderRow = tp.HeaderRowCount == nil || *tp.HeaderRowCount > 0
+			td.TotalsRow = tp.TotalsRowCount > 0
+			for _, c := range tp.Columns {
+				td.Columns = append(td.Columns, c.Name)
+			}
+			td.DataRange = tableDataRange(td)
+			if !visit(td) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// tableDataRange returns the rows of td between its header and totals rows,
+// or "" when there are none.
+func tableDataRange(td TableDetail) string {
+	parts := strings.Split(td.Range, ":")
+	if len(parts) != 2 {
+		return ""
+	}
+	x1, y1, err1 := excelize.CellNameToCoordinates(parts[0])
+	x2, y2, err2 := excelize.CellNameToCoordinates(parts[1])
+	if err1 != nil || err2 != nil {
+		return ""
+	}
+	if td.HeaderRow {
+		y1++
+	}
+	if td.TotalsRow {
+		y2--
+	}
+	if y2 < y1 {
+		return ""
+	}
+	left, _ := excelize.CoordinatesToCellName(x1, y1)
+	right, _ := excelize.CoordinatesToCellName(x2, y2)
+	return left + ":" + right
+}
+
+// RegisterTableTools registers list_tables and read_table.
+func RegisterTableTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	// list_tables
+	list := mcp.NewTool(
+		"list_tables",
+		mcp.WithDescription(fmt.Sprintf("List the Excel tables (ListObjects) defined in the workbook, in sheet order: name, sheet, full range, data range (between the header and totals rows), column names, table style, and whether header and totals rows are shown. Tables carry exact structure that detect_tables can only approximate; pass a name to read_table to page through its rows. sheet limits the listing to one sheet. At most %d tables are returned; truncated marks a cut list. Errors: VALIDATION, INVALID_SHEET, READ_FAILED.", maxListedTables)),
+		mcp.WithInputSchema[ListTablesInput](),
+		mcp.WithOutputSchema[ListTablesOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(list, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ListTablesInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		out := ListTablesOutput{Path: canonical, Tables: []TableDetail{}}
+		err := mgr.WithRead(id, func(f *excelize.File, version int64) error {
+			sheets := f.GetSheetList()
+			if name := strings.TrimSpace(in.Sheet); name != "" {
+				sheet, ok := resolveSheetName(f, name)
+				if !ok {
+					return mcperr.Errorf(mcperr.InvalidSheet, "sheet not found")
+				}
+				sheets = []string{sheet}
+			}
+			if err := collectTables(f, sheets, func(td TableDetail) bool {
+				out.Total++
+				if len(out.Tables) < maxListedTables {
+					out.Tables = append(out.Tables, td)
+				}
+				return true
+			}); err != nil {
+				return err
+			}
+			out.Truncated = out.Total > len(out.Tables)
+			reg.changes.observe(ctx, id, canonical, version)
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+		}
+		runtime.CallStatsFrom(ctx).SetResult(len(out.Tables), out.Truncated)
+		var b strings.Builder
+		fmt.Fprintf(&b, "tables=%d", out.Total)
+		if out.Truncated {
+			fmt.Fprintf(&b, " (first %d listed)", len(out.Tables))
+		}
+		for _, t := range out.Tables {
+			fmt.Fprintf(&b, " %s=%s!%s", t.Name, t.Sheet, t.Range)
+		}
+		return mcp.NewToolResultStructured(out, b.String()), nil
+	}))
+	reg.Register(list)
+
+	// read_table
+	read := mcp.NewTool(
+		"read_table",
+		mcp.WithDescription("Read an Excel table by name: resolves the table to its sheet and range (header row plus data rows; the totals row is left out) and returns it exactly as read_range would, with the same pagination, encodings, and meta. data_only=true skips the header row; encoding=records keys each data row by the header. Continue with nextCursor (read_table or read_range accept it). Call list_tables for table names. Errors: VALIDATION, TABLE_NOT_FOUND, INVALID_SHEET, CURSOR_INVALID, CURSOR_EXPIRED, READ_FAILED."),
+		mcp.WithInputSchema[ReadTableInput](),
+		mcp.WithOutputSchema[ReadRangeOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(read, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ReadTableInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		rr := ReadRangeInput{
+			Path:       in.Path,
+			Password:   in.Password,
+			MaxCells:   in.MaxCells,
+			Cursor:     strings.TrimSpace(in.Cursor),
+			CellDetail: in.CellDetail,
+			Encoding:   in.Encoding,
+			CellWidth:  in.CellWidth,
+			ValueMode:  in.ValueMode,
+		}
+		if rr.Cursor != "" {
+			return runReadRange(ctx, reg, limits, mgr, rr)
+		}
+		name := strings.TrimSpace(in.Table)
+		if name == "" {
+			return mcperr.New(mcperr.Validation, "table is required unless cursor is given"), nil
+		}
+		id, _, openErr := mgr.GetOrOpenWithOptions(ctx, strings.TrimSpace(in.Path), workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		var table TableDetail
+		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+			found := false
+			if err := collectTables(f, f.GetSheetList(), func(td TableDetail) bool {
+				if strings.EqualFold(td.Name, name) {
+					table, found = td, true
+				}
+				return !found
+			}); err != nil {
+				return err
+			}
+			if !found {
+				return mcperr.Errorf(mcperr.TableNotFound, "table %q not found", name)
+			}
+			return nil
+		})
+		if err != nil {
+			if res := classifyError(err); res != nil {
+				return res, nil
+			}
+			return mcperr.Wrapf(mcperr.ReadFailed, "%v", err), nil
+		}
+		rr.Sheet = table.Sheet
+		rr.RangeA1 = table.Range
+		if table.TotalsRow || in.DataOnly {
+			dataRange := tableDataRange(table)
+			if dataRange == "" {
+				return mcperr.Wrapf(mcperr.Validation, "table %q has no data rows", table.Name), nil
+			}
+			if !in.DataOnly && table.HeaderRow {
+				start, _, _ := strings.Cut(table.Range, ":")
+				_, end, _ := strings.Cut(dataRange, ":")
+				dataRange = start + ":" + end
+			}
+			rr.RangeA1 = dataRange
+		}
+		return runReadRange(ctx, reg, limits, mgr, rr)
+	}))
+	reg.Register(read)
+}
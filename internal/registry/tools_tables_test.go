@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func createTableWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]any{"title"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "B3", &[]any{"Region", "Amount"}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "B4", &[]any{"North", 10}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "B5", &[]any{"South", 20}))
+	require.NoError(t, f.SetSheetRow("Sheet1", "B6", &[]any{"East", 30}))
+	require.NoError(t, f.AddTable("Sheet1", &excelize.Table{Range: "B3:C6", Name: "Sales", StyleName: "TableStyleMedium2"}))
+	_, err := f.NewSheet("Other")
+	require.NoError(t, err)
+	require.NoError(t, f.SetSheetRow("Other", "A1", &[]any{"Key"}))
+	require.NoError(t, f.AddTable("Other", &excelize.Table{Range: "A1:A2", Name: "Keys"}))
+	path := filepath.Join(t.TempDir(), "tables.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestListTables(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createTableWorkbook(t)
+
+	res := callTool(t, srv, "list_tables", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ListTablesOutput)
+	require.Equal(t, 2, out.Total)
+	require.Equal(t, TableDetail{
+		Name: "Sales", Sheet: "Sheet1", Range: "B3:C6", DataRange: "B4:C6",
+		Columns: []string{"Region", "Amount"}, Style: "TableStyleMedium2", HeaderRow: true,
+	}, out.Tables[0])
+	require.Equal(t, "tables=2 Sales=Sheet1!B3:C6 Keys=Other!A1:A2", resultText(t, res))
+
+	res = callTool(t, srv, "list_tables", map[string]any{"path": path, "sheet": "other"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = res.StructuredContent.(ListTablesOutput)
+	require.Len(t, out.Tables, 1)
+	require.Equal(t, "Keys", out.Tables[0].Name)
+
+	res = callTool(t, srv, "list_tables", map[string]any{"path": path, "sheet": "Missing"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_SHEET")
+}
+
+func TestReadTable_DelegatesToReadRange(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := createTableWorkbook(t)
+
+	res := callTool(t, srv, "read_table", map[string]any{"path": path, "table": "sales", "max_cells": 4})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out := res.StructuredContent.(ReadRangeOutput)
+	require.Equal(t, "Sheet1", out.Sheet)
+	require.Equal(t, "B3:C6", out.RangeA1)
+	require.True(t, out.Meta.Truncated)
+	_, body := splitSummary(t, resultText(t, res))
+	require.JSONEq(t, `[["Region","Amount"],["North","10"]]`, body)
+
+	// The cursor resumes through read_table like read_range.
+	res = callTool(t, srv, "read_table", map[string]any{"path": path, "cursor": out.Meta.NextCursor})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.JSONEq(t, `[["South","20"],["East","30"]]`, body)
+
+	res = callTool(t, srv, "read_table", map[string]any{"path": path, "table": "Sales", "data_only": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, "B4:C6", res.StructuredContent.(ReadRangeOutput).RangeA1)
+
+	res = callTool(t, srv, "read_table", map[string]any{"path": path, "table": "Sales", "encoding": "records"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	_, body = splitSummary(t, resultText(t, res))
+	require.JSONEq(t, `[{"Region":"North","Amount":"10"},{"Region":"South","Amount":"20"},{"Region":"East","Amount":"30"}]`, body)
+
+	res = callTool(t, srv, "read_table", map[string]any{"path": path, "table": "Nope"})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "TABLE_NOT_FOUND")
+
+	res = callTool(t, srv, "read_table", map[string]any{"path": path})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION")
+}
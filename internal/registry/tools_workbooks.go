@@ -0,0 +1,305 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
+	"github.com/vinodismyname/mcpxcel/pkg/validation"
+	"github.com/xuri/excelize/v2"
+)
+
+// OpenWorkbookInput defines parameters for open_workbook.
+type OpenWorkbookInput struct {
+	Path     string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Canonical absolute workbook path (allow‑list enforced)"`
+	Password string `json:"password,omitempty" jsonschema_description:"Password for an encrypted workbook; used only to open it, never stored or echoed"`
+}
+
+// OpenWorkbookOutput describes the handle backing a workbook path.
+type OpenWorkbookOutput struct {
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+	Sheets      int    `json:"sheets"`
+	TTLSeconds  int64  `json:"ttlSeconds" jsonschema_description:"Seconds until the handle is evicted if left idle"`
+	ExpiresAt   string `json:"expiresAt"`
+	AlreadyOpen bool   `json:"alreadyOpen" jsonschema_description:"The workbook was already cached; its TTL was refreshed"`
+}
+
+// CloseWorkbookInput identifies a workbook to release by path or handle ID.
+type CloseWorkbookInput struct {
+	Path string `json:"path,omitempty" validate:"required_without=ID,omitempty,filepath_ext" jsonschema_description:"Workbook path to release"`
+	ID   string `json:"id,omitempty" jsonschema_description:"Handle ID from open_workbook or list_open_workbooks"`
+}
+
+// CloseWorkbookOutput reports the released handle.
+type CloseWorkbookOutput struct {
+	ID   string `json:"id"`
+	Path string `json:"path,omitempty"`
+}
+
+// FlushWorkbookInput identifies a workbook whose deferred saves to write.
+type FlushWorkbookInput struct {
+	Path string `json:"path,omitempty" validate:"required_without=ID,omitempty,filepath_ext" jsonschema_description:"Workbook path to flush"`
+	ID   string `json:"id,omitempty" jsonschema_description:"Handle ID from open_workbook or list_open_workbooks"`
+}
+
+// FlushWorkbookOutput reports whether pending changes were written.
+type FlushWorkbookOutput struct {
+	ID           string `json:"id"`
+	Path         string `json:"path,omitempty"`
+	Flushed      bool   `json:"flushed" jsonschema_description:"Deferred changes were pending and are now on disk"`
+	SaveDelayMS  int64  `json:"saveDelayMs" jsonschema_description:"Configured write batching delay; 0 means every write saves immediately"`
+	BatchedSaves bool   `json:"batchedSaves"`
+}
+
+// RestoreBackupInput identifies a workbook and the backup to copy over it.
+type RestoreBackupInput struct {
+	Path   string `json:"path" validate:"required,filepath_ext" jsonschema_description:"Workbook to restore (allow‑list enforced; must be writable)"`
+	Backup string `json:"backup,omitempty" validate:"omitempty,filepath_ext" jsonschema_description:"Backup path reported by a write tool; omit to restore the newest backup"`
+}
+
+// RestoreBackupOutput reports the restored workbook and its source backup.
+type RestoreBackupOutput struct {
+	Path         string `json:"path"`
+	RestoredFrom string `json:"restoredFrom"`
+}
+
+// ListOpenWorkbooksInput is empty; list_open_workbooks takes no parameters.
+type ListOpenWorkbooksInput struct{}
+
+// OpenWorkbookInfo describes one cached workbook handle.
+type OpenWorkbookInfo struct {
+	ID         string `json:"id"`
+	Path       string `json:"path"`
+	LoadedAt   string `json:"loadedAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	LastAccess string `json:"lastAccess"`
+	Version    int64  `json:"version" jsonschema_description:"Mutation counter; bumps on each write or on reload after an external change"`
+	Pending    bool   `json:"pending,omitempty" jsonschema_description:"Deferred writes not yet saved to disk"`
+}
+
+// ListOpenWorkbooksOutput lists cached handles and the open-workbook capacity.
+type ListOpenWorkbooksOutput struct {
+	Workbooks []OpenWorkbookInfo `json:"workbooks"`
+	Count     int                `json:"count"`
+	Capacity  int                `json:"capacity" jsonschema_description:"Configured MaxOpenWorkbooks"`
+}
+
+// RegisterWorkbookTools registers open_workbook, close_workbook,
+// flush_workbook, and list_open_workbooks for explicit handle lifecycle
+// control, and restore_backup for undoing writes from a backup.
+func RegisterWorkbookTools(s *server.MCPServer, reg *Registry, limits runtime.Limits, mgr *workbooks.Manager) {
+	open := mcp.NewTool(
+		"open_workbook",
+		mcp.WithDescription(fmt.Sprintf("Open a workbook (or refresh the TTL of an already cached one) and return its handle id, sheet count, and idle TTL. Optional: every tool opens workbooks by path on demand; use this to warm the cache or check that a path is readable. Encrypted workbooks need password; once open, other tools read the cached handle without it until it is evicted or the file changes. At most %d workbooks stay open; idle ones are evicted least‑recently‑used first. Errors: VALIDATION, OPEN_FAILED, BUSY_RESOURCE, PASSWORD_REQUIRED, PASSWORD_INVALID.", limits.MaxOpenWorkbooks)),
+		mcp.WithInputSchema[OpenWorkbookInput](),
+		mcp.WithOutputSchema[OpenWorkbookOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(open, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in OpenWorkbookInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		path := strings.TrimSpace(in.Path)
+		out := OpenWorkbookOutput{}
+		if canonical, err := mgr.Canonicalize(path); err == nil {
+			_, out.AlreadyOpen = mgr.LookupPath(canonical)
+		}
+		id, canonical, openErr := mgr.GetOrOpenWithOptions(ctx, path, workbooks.OpenOptions{Password: in.Password})
+		if openErr != nil {
+			return openFailed(openErr), nil
+		}
+		err := mgr.WithRead(id, func(f *excelize.File, _ int64) error {
+			out.Sheets = len(f.GetSheetList())
+			return nil
+		})
+		if err != nil {
+			return lifecycleError(err), nil
+		}
+		out.ID, out.Path = id, canonical
+		if info, ok := mgr.Info(id); ok {
+			out.ExpiresAt = formatHandleTime(info.ExpiresAt)
+			out.TTLSeconds = int64(time.Until(info.ExpiresAt).Round(time.Second).Seconds())
+		}
+		summary := fmt.Sprintf("id=%s sheets=%d ttl=%ds alreadyOpen=%t path=%s", out.ID, out.Sheets, out.TTLSeconds, out.AlreadyOpen, out.Path)
+		return mcp.NewToolResultStructured(out, summary), nil
+	}))
+	reg.Register(open)
+
+	closeTool := mcp.NewTool(
+		"close_workbook",
+		mcp.WithDescription("Release a cached workbook immediately by path or handle id, freeing its open‑workbook slot. Unsaved state is never lost: writes deferred by batched saves are written first, and when that fails the workbook stays open. Later calls reopen the file on demand. Errors: VALIDATION, INVALID_HANDLE (nothing open for that path or id), WRITE_FAILED."),
+		mcp.WithInputSchema[CloseWorkbookInput](),
+		mcp.WithOutputSchema[CloseWorkbookOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(closeTool, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in CloseWorkbookInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, res := resolveHandleRef(mgr, in.ID, in.Path)
+		if res != nil {
+			return res, nil
+		}
+		info, _ := mgr.Info(id)
+		if err := mgr.CloseHandle(ctx, id); err != nil {
+			return lifecycleError(err), nil
+		}
+		out := CloseWorkbookOutput{ID: id, Path: info.Path}
+		return mcp.NewToolResultStructured(out, fmt.Sprintf("closed id=%s path=%s", out.ID, out.Path)), nil
+	}))
+	reg.Register(closeTool)
+
+	flush := mcp.NewTool(
+		"flush_workbook",
+		mcp.WithDescription("Write a workbook's batched changes to disk now, by path or handle id. When the server runs with a save delay (--save-delay), write tools report save=deferred and the workbook is saved once writes pause for that long, when it is closed or evicted, or when this tool is called. With the default save‑per‑call behavior nothing is ever pending and flushed=false. Errors: VALIDATION, INVALID_HANDLE (nothing open for that path or id), WRITE_FAILED (the changes remain pending)."),
+		mcp.WithInputSchema[FlushWorkbookInput](),
+		mcp.WithOutputSchema[FlushWorkbookOutput](),
+		writeTool(false, true),
+	)
+	s.AddTool(flush, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in FlushWorkbookInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		id, res := resolveHandleRef(mgr, in.ID, in.Path)
+		if res != nil {
+			return res, nil
+		}
+		info, _ := mgr.Info(id)
+		flushed, err := mgr.Flush(id)
+		if err != nil {
+			return lifecycleError(err), nil
+		}
+		delay := mgr.SaveDelay()
+		out := FlushWorkbookOutput{ID: id, Path: info.Path, Flushed: flushed, SaveDelayMS: delay.Milliseconds(), BatchedSaves: delay > 0}
+		return mcp.NewToolResultStructured(out, fmt.Sprintf("flushed=%t id=%s path=%s", out.Flushed, out.ID, out.Path)), nil
+	}))
+	reg.Register(flush)
+
+	restore := mcp.NewTool(
+		"restore_backup",
+		mcp.WithDescription("Copy a backup taken by a write tool back over its workbook. Write tools take a timestamped backup before saving when called with backup=true (stored in .mcpxcel-backups beside the workbook) or always when the server has a backup directory (--backup-dir), and report its path as backup. Omit backup to restore the newest one. The workbook must be writable and the backup readable under the allow‑list, so a server backup directory must lie inside an allowed directory to restore from it; only backups of the same workbook are accepted. Unsaved batched changes to the workbook are discarded. Errors: VALIDATION (not a backup of this workbook, or none exist), PERMISSION_DENIED, AUDIT_FAILED, WRITE_FAILED."),
+		mcp.WithInputSchema[RestoreBackupInput](),
+		mcp.WithOutputSchema[RestoreBackupOutput](),
+		writeTool(true, false),
+	)
+	s.AddTool(restore, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in RestoreBackupInput) (*mcp.CallToolResult, error) {
+		if msg := validation.ValidateStruct(in); msg != "" {
+			return mcperr.FromText(msg), nil
+		}
+		canonical, src, err := mgr.RestoreBackup(strings.TrimSpace(in.Path), strings.TrimSpace(in.Backup), func(path, backup string) error {
+			return reg.auditWrite(ctx, audit.Record{Tool: "restore_backup", Path: path, Source: backup})
+		})
+		if err != nil {
+			return restoreError(err), nil
+		}
+		out := RestoreBackupOutput{Path: canonical, RestoredFrom: src}
+		return mcp.NewToolResultStructured(out, fmt.Sprintf("restored path=%s from=%s", out.Path, out.RestoredFrom)), nil
+	}))
+	reg.Register(restore)
+
+	list := mcp.NewTool(
+		"list_open_workbooks",
+		mcp.WithDescription("List workbooks the server currently holds open: handle id, canonical path, loaded/expires/last‑access timestamps (RFC 3339, UTC), and version counters, plus the open‑workbook capacity. Read‑only; does not refresh TTLs."),
+		mcp.WithInputSchema[ListOpenWorkbooksInput](),
+		mcp.WithOutputSchema[ListOpenWorkbooksOutput](),
+		readOnlyTool(true),
+	)
+	s.AddTool(list, mcp.NewTypedToolHandler(func(ctx context.Context, req mcp.CallToolRequest, in ListOpenWorkbooksInput) (*mcp.CallToolResult, error) {
+		handles := mgr.List()
+		out := ListOpenWorkbooksOutput{Workbooks: make([]OpenWorkbookInfo, 0, len(handles)), Count: len(handles), Capacity: limits.MaxOpenWorkbooks}
+		var b strings.Builder
+		fmt.Fprintf(&b, "open=%d capacity=%d", out.Count, out.Capacity)
+		for _, h := range handles {
+			out.Workbooks = append(out.Workbooks, OpenWorkbookInfo{
+				ID:         h.ID,
+				Path:       h.Path,
+				LoadedAt:   formatHandleTime(h.LoadedAt),
+				ExpiresAt:  formatHandleTime(h.ExpiresAt),
+				LastAccess: formatHandleTime(h.LastAccess),
+				Version:    h.Version,
+				Pending:    h.Pending,
+			})
+			fmt.Fprintf(&b, "\n- %s %s version=%d expires=%s", h.ID, h.Path, h.Version, formatHandleTime(h.ExpiresAt))
+			if h.Pending {
+				b.WriteString(" pending=true")
+			}
+		}
+		return mcp.NewToolResultStructured(out, b.String()), nil
+	}))
+	reg.Register(list)
+}
+
+// resolveHandleRef returns the handle ID given directly or open for path,
+// or an error result when neither names an open workbook.
+func resolveHandleRef(mgr *workbooks.Manager, id, path string) (string, *mcp.CallToolResult) {
+	if id = strings.TrimSpace(id); id != "" {
+		return id, nil
+	}
+	canonical, err := mgr.Canonicalize(strings.TrimSpace(path))
+	if err != nil {
+		return "", mcperr.FromText(fmt.Sprintf("VALIDATION: %v", err))
+	}
+	id, ok := mgr.LookupPath(canonical)
+	if !ok {
+		return "", mcperr.FromText("INVALID_HANDLE: no open workbook for path")
+	}
+	return id, nil
+}
+
+// saveMode names how a write tool's save happened, for its output.
+func saveMode(deferred bool) string {
+	if deferred {
+		return "deferred"
+	}
+	return "immediate"
+}
+
+// saveSummary is the summary suffix flagging a deferred save and any backup
+// taken before it; plain immediate saves keep the summary unchanged.
+func saveSummary(mode, backup string) string {
+	var s string
+	if mode == "deferred" {
+		s = " save=deferred"
+	}
+	if backup != "" {
+		s += " backup=" + backup
+	}
+	return s
+}
+
+// restoreError maps RestoreBackup errors.
+func restoreError(err error) *mcp.CallToolResult {
+	switch {
+	case errors.Is(err, workbooks.ErrNotABackup):
+		return mcperr.New(mcperr.Validation, "backup is not a backup of this workbook")
+	case errors.Is(err, workbooks.ErrNoBackups):
+		return mcperr.New(mcperr.Validation, "workbook has no backups")
+	case errors.Is(err, workbooks.ErrBackupNotAllowed):
+		return mcperr.Wrapf(mcperr.PermissionDenied, "%v", err)
+	}
+	if res := workbookAccessError(err); res != nil {
+		return res
+	}
+	return mcperr.Wrapf(mcperr.WriteFailed, "%v", err)
+}
+
+// lifecycleError maps Manager errors from the lifecycle tools.
+func lifecycleError(err error) *mcp.CallToolResult {
+	if res := workbookAccessError(err); res != nil {
+		return res
+	}
+	return mcperr.FromText(fmt.Sprintf("OPEN_FAILED: %v", err))
+}
+
+func formatHandleTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
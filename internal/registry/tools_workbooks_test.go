@@ -0,0 +1,208 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWorkbookLifecycleTools(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	f := excelize.NewFile()
+	_, err := f.NewSheet("Data")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "life.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+
+	res := callTool(t, srv, "open_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var opened OpenWorkbookOutput
+	decodeStructured(t, res, &opened)
+	require.NotEmpty(t, opened.ID)
+	require.Equal(t, 2, opened.Sheets)
+	require.False(t, opened.AlreadyOpen)
+	require.Positive(t, opened.TTLSeconds)
+
+	res = callTool(t, srv, "open_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var again OpenWorkbookOutput
+	decodeStructured(t, res, &again)
+	require.Equal(t, opened.ID, again.ID)
+	require.True(t, again.AlreadyOpen)
+
+	res = callTool(t, srv, "list_open_workbooks", map[string]any{})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var listed ListOpenWorkbooksOutput
+	decodeStructured(t, res, &listed)
+	require.Equal(t, 1, listed.Count)
+	require.Equal(t, opened.ID, listed.Workbooks[0].ID)
+	require.Equal(t, opened.Path, listed.Workbooks[0].Path)
+	require.NotEmpty(t, listed.Workbooks[0].ExpiresAt)
+
+	res = callTool(t, srv, "close_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Equal(t, 0, mgr.Count())
+
+	res = callTool(t, srv, "close_workbook", map[string]any{"id": opened.ID})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_HANDLE")
+
+	res = callTool(t, srv, "close_workbook", map[string]any{})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "path or id is required")
+}
+
+func TestPasswordProtectedWorkbook(t *testing.T) {
+	srv, _ := newTestServer(t)
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "classified"))
+	path := filepath.Join(t.TempDir(), "secret.xlsx")
+	require.NoError(t, f.SaveAs(path, excelize.Options{Password: "s3cret"}))
+	require.NoError(t, f.Close())
+
+	args := map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1"}
+	res := callTool(t, srv, "read_range", args)
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PASSWORD_REQUIRED")
+
+	args["password"] = "wrong"
+	res = callTool(t, srv, "read_range", args)
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PASSWORD_INVALID")
+	require.NotContains(t, resultText(t, res), "wrong")
+
+	args["password"] = "s3cret"
+	res = callTool(t, srv, "read_range", args)
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "classified")
+	require.NotContains(t, resultText(t, res), "s3cret")
+
+	// Once the handle is gone the password is needed again.
+	res = callTool(t, srv, "close_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	delete(args, "password")
+	res = callTool(t, srv, "read_range", args)
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "PASSWORD_REQUIRED")
+}
+
+func TestBatchedSavesAndFlushWorkbook(t *testing.T) {
+	srv, mgr := newTestServer(t)
+	mgr.SetSaveDelay(time.Hour)
+	path := filepath.Join(t.TempDir(), "batched.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	diskA1 := func() string {
+		f, err := excelize.OpenFile(path)
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+		v, err := f.GetCellValue("Sheet1", "A1")
+		require.NoError(t, err)
+		return v
+	}
+
+	for _, v := range []string{"first", "second"} {
+		res := callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "values": [][]string{{v}}})
+		require.False(t, res.IsError, "%s", resultText(t, res))
+		require.Contains(t, resultText(t, res), "save=deferred")
+		var written struct {
+			Save string `json:"save"`
+		}
+		decodeStructured(t, res, &written)
+		require.Equal(t, "deferred", written.Save)
+	}
+	require.Empty(t, diskA1())
+
+	res := callTool(t, srv, "list_open_workbooks", map[string]any{})
+	var listed ListOpenWorkbooksOutput
+	decodeStructured(t, res, &listed)
+	require.True(t, listed.Workbooks[0].Pending)
+
+	res = callTool(t, srv, "flush_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var out FlushWorkbookOutput
+	decodeStructured(t, res, &out)
+	require.True(t, out.Flushed)
+	require.True(t, out.BatchedSaves)
+	require.Equal(t, int64(time.Hour/time.Millisecond), out.SaveDelayMS)
+	require.Equal(t, "second", diskA1())
+
+	res = callTool(t, srv, "flush_workbook", map[string]any{"id": out.ID})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	out = FlushWorkbookOutput{}
+	decodeStructured(t, res, &out)
+	require.False(t, out.Flushed)
+
+	// close_workbook writes what is still pending.
+	res = callTool(t, srv, "format_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "bold": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.Contains(t, resultText(t, res), "save=deferred")
+	res = callTool(t, srv, "close_workbook", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	res = callTool(t, srv, "flush_workbook", map[string]any{"path": path})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "INVALID_HANDLE")
+}
+
+func TestWriteBackupAndRestoreBackup(t *testing.T) {
+	srv, _ := newTestServer(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "undo.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "original"))
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	cellA1 := func(p string) string {
+		t.Helper()
+		f, err := excelize.OpenFile(p)
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+		v, err := f.GetCellValue("Sheet1", "A1")
+		require.NoError(t, err)
+		return v
+	}
+
+	res := callTool(t, srv, "restore_backup", map[string]any{"path": path})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: workbook has no backups")
+
+	res = callTool(t, srv, "write_range", map[string]any{"path": path, "sheet": "Sheet1", "range": "A1:A1", "values": [][]string{{"edited"}}, "backup": true})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var written struct {
+		Backup string `json:"backup"`
+	}
+	decodeStructured(t, res, &written)
+	require.Equal(t, filepath.Join(dir, workbooks.LocalBackupDir), filepath.Dir(written.Backup))
+	require.Contains(t, resultText(t, res), "backup="+written.Backup)
+	require.Equal(t, "original", cellA1(written.Backup))
+	// Without backup=true (and no backup directory) nothing is copied.
+	res = callTool(t, srv, "add_sheet", map[string]any{"path": path, "name": "Extra"})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var sheets SheetEditOutput
+	decodeStructured(t, res, &sheets)
+	require.Empty(t, sheets.Backup)
+
+	res = callTool(t, srv, "restore_backup", map[string]any{"path": path, "backup": written.Backup})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	var restored RestoreBackupOutput
+	decodeStructured(t, res, &restored)
+	require.Equal(t, RestoreBackupOutput{Path: path, RestoredFrom: written.Backup}, restored)
+	require.Equal(t, "original", cellA1(path))
+
+	// Later reads see the restored file, not the cached edit.
+	res = callTool(t, srv, "list_structure", map[string]any{"path": path})
+	require.False(t, res.IsError, "%s", resultText(t, res))
+	require.NotContains(t, resultText(t, res), "Extra")
+
+	other := filepath.Join(dir, "other.xlsx")
+	require.NoError(t, excelize.NewFile().SaveAs(other))
+	res = callTool(t, srv, "restore_backup", map[string]any{"path": path, "backup": other})
+	require.True(t, res.IsError)
+	require.Contains(t, resultText(t, res), "VALIDATION: backup is not a backup of this workbook")
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFoundationInputValidation checks that each foundation tool rejects bad
+// inputs through its validate tags before opening the workbook.
+func TestFoundationInputValidation(t *testing.T) {
+	srv, _ := newTestServer(t)
+	path := "/tmp/book.xlsx"
+
+	cases := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"list_structure", map[string]any{}, "VALIDATION: path is required"},
+		{"list_structure", map[string]any{"path": "/tmp/notes.txt"}, "VALIDATION: path must be an Excel file"},
+
+		{"preview_sheet", map[string]any{"path": path}, "VALIDATION: sheet is required (or supply cursor)"},
+		{"preview_sheet", map[string]any{"path": path, "sheet": "S", "rows": 5000}, "VALIDATION: rows must satisfy max=1000"},
+		{"preview_sheet", map[string]any{"path": path, "sheet": "S", "encoding": "xml"}, "VALIDATION: encoding must be one of: json csv markdown"},
+		{"preview_sheet", map[string]any{"path": path, "sheet": "S", "start_col": 20000}, "VALIDATION: start_col must satisfy max=16384"},
+		{"preview_sheet", map[string]any{"path": path, "sheet": "S", "skip_rows": -1}, "VALIDATION: skip_rows must satisfy min=0"},
+		{"preview_sheet", map[string]any{"path": path, "sheet": "S", "value_mode": "iso"}, "VALIDATION: value_mode must be one of"},
+		{"preview_sheet", map[string]any{"path": path, "cursor": "not a cursor"}, "CURSOR_INVALID"},
+
+		{"read_range", map[string]any{"path": path, "range": "A1:B2"}, "VALIDATION: sheet is required (or supply cursor)"},
+		{"read_range", map[string]any{"path": path, "sheet": "S", "range": "A1:B2:C3"}, "VALIDATION: invalid range"},
+		{"read_range", map[string]any{"path": path, "sheet": "S", "ranges": []string{"A1:B2", ""}}, "VALIDATION: invalid range"},
+		{"read_range", map[string]any{"path": path, "sheet": "S", "ranges": make([]string, 17)}, "VALIDATION: ranges must have at most 16 entries"},
+		{"read_range", map[string]any{"path": path, "sheet": "S", "range": "A1:B2", "max_cells": -5}, "VALIDATION: max_cells must satisfy min=1"},
+		{"read_range", map[string]any{"path": path, "sheet": "S", "range": "A1:B2", "encoding": "xml"}, "VALIDATION: encoding must be one of: json csv markdown records"},
+
+		{"search_data", map[string]any{"path": path, "sheet": "S"}, "VALIDATION: query is required (or supply cursor or range_query)"},
+		{"search_data", map[string]any{"path": path, "sheet": "S", "query": `total(?=\s*\d)`, "regex": true}, "lookahead assertions"},
+		{"search_data", map[string]any{"path": path, "sheet": "S", "query": `(\w+) \1`, "regex": true}, "backreferences"},
+		{"filter_data", map[string]any{"path": path, "sheet": "S"}, "VALIDATION: predicate is required (or supply cursor)"},
+		{"filter_data", map[string]any{"path": path, "sheet": "S", "predicate": "$1 > 1", "sort_order": "up"}, "VALIDATION: sort_order must be one of"},
+
+		{"write_range", map[string]any{"path": path, "sheet": "S", "values": [][]string{{"a"}}}, "VALIDATION: range is required"},
+		{"write_range", map[string]any{"path": path, "sheet": "S", "range": "A1"}, "VALIDATION: values is required"},
+		{"write_range", map[string]any{"path": path, "sheet": "S", "range": "A1", "values": [][]string{}}, "VALIDATION: values must have at least 1 entries"},
+		{"apply_formula", map[string]any{"path": path, "sheet": "S", "range": "A1"}, "VALIDATION: formula is required"},
+		{"apply_formula", map[string]any{"path": path, "sheet": "S", "range": "A1", "formula": "  "}, "VALIDATION: formula must not be blank"},
+	}
+	for _, tc := range cases {
+		res := callTool(t, srv, tc.tool, tc.args)
+		require.True(t, res.IsError, "%s %v", tc.tool, tc.args)
+		require.Contains(t, resultText(t, res), tc.want, "%s %v", tc.tool, tc.args)
+	}
+}
@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Value modes accepted by read_range and preview_sheet.
+const (
+	// valueModeFormatted returns values as Excel displays them (default).
+	valueModeFormatted = "formatted"
+	// valueModeRaw returns the stored value: date serials, unformatted numbers,
+	// 1/0 booleans, and resolved shared or inline strings.
+	valueModeRaw = "raw"
+	// valueModeTyped emits JSON numbers, booleans, null for empty cells, and
+	// ISO-8601 strings for date-formatted serials.
+	valueModeTyped = "typed"
+)
+
+// valueModeDescription documents the value_mode parameter on both read tools.
+const valueModeDescription = "Cell values: 'formatted' (as Excel displays them, default), 'raw' (stored value: date serials, unformatted numbers, 1/0 booleans), or 'typed' (JSON numbers and booleans, null for empty cells, ISO‑8601 strings for date‑formatted serials; csv/markdown show the same text)"
+
+// parseValueMode normalizes a value_mode input; empty means formatted.
+func parseValueMode(m string) (string, bool) {
+	m = strings.ToLower(strings.TrimSpace(m))
+	switch m {
+	case "":
+		return valueModeFormatted, true
+	case valueModeFormatted, valueModeRaw, valueModeTyped:
+		return m, true
+	}
+	return "", false
+}
+
+// cursorValueMode returns the mode to record in a cursor: the formatted
+// default is left out.
+func cursorValueMode(m string) string {
+	if m == valueModeFormatted {
+		return ""
+	}
+	return m
+}
+
+// valueReader reads cells of one sheet in a value mode. In typed mode it also
+// yields the JSON value; its text form (used by csv and markdown) is the raw
+// number, true/false, or the ISO-8601 date.
+type valueReader struct {
+	f        *excelize.File
+	sheet    string
+	mode     string
+	types    *cellDetailReader
+	date1904 bool
+}
+
+func newValueReader(f *excelize.File, sheet, mode string) *valueReader {
+	if mode == "" {
+		mode = valueModeFormatted
+	}
+	r := &valueReader{f: f, sheet: sheet, mode: mode}
+	if mode == valueModeTyped {
+		r.types = newCellDetailReader(f, sheet)
+		if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+			r.date1904 = *props.Date1904
+		}
+	}
+	return r
+}
+
+// raw reports whether values should be read without number formatting.
+func (r *valueReader) raw() bool {
+	return r.mode != valueModeFormatted
+}
+
+// read returns cell's text and, in typed mode, its JSON value.
+func (r *valueReader) read(cell string) (string, any) {
+	if !r.raw() {
+		v, _ := r.f.GetCellValue(r.sheet, cell)
+		return v, nil
+	}
+	v, _ := r.f.GetCellValue(r.sheet, cell, excelize.Options{RawCellValue: true})
+	return r.convert(cell, v)
+}
+
+// convert types a stored value read elsewhere (e.g. from a row iterator with
+// RawCellValue set). Outside typed mode it returns raw unchanged.
+func (r *valueReader) convert(cell, raw string) (string, any) {
+	if r.mode != valueModeTyped {
+		return raw, nil
+	}
+	switch r.types.inferType(cell, raw) {
+	case "empty":
+		return "", nil
+	case "number":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+			return raw, n
+		}
+	case "bool":
+		b := raw == "1" || raw == "TRUE"
+		return strconv.FormatBool(b), b
+	case "date":
+		// Cells stored as ISO dates (t="d") are already text.
+		if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+			if t, terr := excelize.ExcelDateToTime(serial, r.date1904); terr == nil {
+				iso := isoDate(serial, t)
+				return iso, iso
+			}
+		}
+	}
+	return raw, raw
+}
+
+// isoDate formats a date serial as an ISO-8601 date, time, or date-time,
+// depending on whether it has a date part, a time part, or both.
+func isoDate(serial float64, t interface{ Format(string) string }) string {
+	switch {
+	case serial < 1:
+		return t.Format("15:04:05")
+	case serial == math.Trunc(serial):
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CallStats accumulates per-call counters that handlers report for the
+// tool-call log line. The middleware stores one in each call's context; all
+// methods are safe on a nil receiver so handlers never need to check.
+type CallStats struct {
+	start time.Time
+
+	mu             sync.Mutex
+	processedCells int
+	returned       int
+	truncated      bool
+}
+
+// CallSummary is a snapshot of a finished call's stats.
+type CallSummary struct {
+	Duration       time.Duration
+	ProcessedCells int
+	Returned       int
+	Truncated      bool
+}
+
+// CallObserver receives every tool call the middleware handled, including
+// calls it rejected, with the handler's result or error.
+type CallObserver func(tool string, sum CallSummary, res *mcp.CallToolResult, err error)
+
+type callStatsKey struct{}
+
+// NewCallStats returns stats for a call starting now.
+func NewCallStats() *CallStats {
+	return &CallStats{start: time.Now()}
+}
+
+// WithCallStats returns ctx carrying s.
+func WithCallStats(ctx context.Context, s *CallStats) context.Context {
+	return context.WithValue(ctx, callStatsKey{}, s)
+}
+
+// CallStatsFrom returns the stats stored in ctx, or nil outside a tool call.
+func CallStatsFrom(ctx context.Context) *CallStats {
+	s, _ := ctx.Value(callStatsKey{}).(*CallStats)
+	return s
+}
+
+// AddCells adds n to the processed cell count.
+func (s *CallStats) AddCells(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.processedCells += n
+	s.mu.Unlock()
+}
+
+// SetResult records how many items (rows, cells, matches, groups) the call
+// returned and whether the output was truncated.
+func (s *CallStats) SetResult(returned int, truncated bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.returned = returned
+	s.truncated = truncated
+	s.mu.Unlock()
+}
+
+// Record is shorthand for AddCells followed by SetResult.
+func (s *CallStats) Record(cells, returned int, truncated bool) {
+	s.AddCells(cells)
+	s.SetResult(returned, truncated)
+}
+
+// Summary snapshots the stats with the time elapsed since the call started.
+func (s *CallStats) Summary() CallSummary {
+	if s == nil {
+		return CallSummary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return CallSummary{
+		Duration:       time.Since(s.start),
+		ProcessedCells: s.processedCells,
+		Returned:       s.returned,
+		Truncated:      s.truncated,
+	}
+}
@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyEnv returns a copy of l with limits overridden from the environment:
+// MCPXCEL_MAX_EXPORT_CELLS sets MaxExportCells, MCPXCEL_MAX_CROSSTAB_CELLS sets
+// MaxCrosstabCells, MCPXCEL_MAX_FILE_BYTES sets MaxFileBytes,
+// MCPXCEL_TEXT_BUDGET_PCT (1-100) sets TextBudgetPercent, and
+// MCPXCEL_CURSOR_TTL (a Go duration such as "30m"; "0"
+// disables expiry) sets CursorTTL. Unset variables keep the current value;
+// malformed or out-of-range values are an error.
+func (l Limits) ApplyEnv() (Limits, error) {
+	if err := envInt("MCPXCEL_MAX_EXPORT_CELLS", &l.MaxExportCells); err != nil {
+		return l, err
+	}
+	if err := envInt("MCPXCEL_MAX_CROSSTAB_CELLS", &l.MaxCrosstabCells); err != nil {
+		return l, err
+	}
+	if err := envInt64("MCPXCEL_MAX_FILE_BYTES", &l.MaxFileBytes); err != nil {
+		return l, err
+	}
+	if err := envInt("MCPXCEL_TEXT_BUDGET_PCT", &l.TextBudgetPercent); err != nil {
+		return l, err
+	}
+	if l.TextBudgetPercent > 100 {
+		return l, fmt.Errorf("runtime: MCPXCEL_TEXT_BUDGET_PCT must be at most 100, got %d", l.TextBudgetPercent)
+	}
+	if err := envDuration("MCPXCEL_CURSOR_TTL", &l.CursorTTL); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+func envInt(name string, dst *int) error {
+	var n int64
+	if err := envInt64(name, &n); err != nil || n == 0 {
+		return err
+	}
+	*dst = int(n)
+	return nil
+}
+
+// envInt64 leaves dst untouched when the variable is unset.
+func envInt64(name string, dst *int64) error {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("runtime: %s must be a positive integer, got %q", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+// envDuration leaves dst untouched when the variable is unset; zero is allowed.
+func envDuration(name string, dst *time.Duration) error {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return fmt.Errorf("runtime: %s must be a non-negative duration such as 30m, got %q", name, v)
+	}
+	*dst = d
+	return nil
+}
@@ -0,0 +1,150 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is the server lifecycle phase used for health and readiness signals.
+type State int32
+
+const (
+	StateStarting State = iota
+	StateReady
+	StateDraining
+	StateStopped
+)
+
+// String returns the lowercase state name.
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// ParseState converts a state name produced by String back to a State.
+func ParseState(name string) (State, error) {
+	for s := StateStarting; s <= StateStopped; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return StateStarting, fmt.Errorf("runtime: unknown state %q", name)
+}
+
+// Lifecycle tracks the server state machine (starting → ready → draining →
+// stopped). Transitions only move forward; observers run synchronously on each
+// successful transition.
+type Lifecycle struct {
+	state     atomic.Int32
+	startedAt time.Time
+
+	mu        sync.Mutex
+	observers []func(from, to State)
+}
+
+// NewLifecycle constructs a Lifecycle in the starting state.
+func NewLifecycle(now time.Time) *Lifecycle {
+	return &Lifecycle{startedAt: now}
+}
+
+// State returns the current lifecycle state.
+func (l *Lifecycle) State() State {
+	return State(l.state.Load())
+}
+
+// StartedAt returns when the lifecycle was created.
+func (l *Lifecycle) StartedAt() time.Time {
+	return l.startedAt
+}
+
+// OnTransition registers an observer invoked after each state change.
+func (l *Lifecycle) OnTransition(fn func(from, to State)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.observers = append(l.observers, fn)
+}
+
+// Transition moves to the target state when it is ahead of the current one and
+// reports whether a change occurred. Backward or repeated transitions are ignored.
+func (l *Lifecycle) Transition(to State) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	from := State(l.state.Load())
+	if to <= from {
+		return false
+	}
+	l.state.Store(int32(to))
+	for _, fn := range l.observers {
+		fn(from, to)
+	}
+	return true
+}
+
+// StatusFile is the on-disk health record written on each transition and read
+// by the --healthcheck probe.
+type StatusFile struct {
+	State     string `json:"state"`
+	PID       int    `json:"pid"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// WriteStatusFile records the state atomically (temp file + rename) at path.
+func WriteStatusFile(path string, st State, now time.Time) error {
+	b, err := json.Marshal(StatusFile{State: st.String(), PID: os.Getpid(), UpdatedAt: now.Unix()})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ErrNotReady indicates the probed server is not in the ready state.
+var ErrNotReady = errors.New("runtime: server not ready")
+
+// CheckStatusFile reads the status file at path and returns nil only when the
+// recorded state is ready.
+func CheckStatusFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var sf StatusFile
+	if err := json.Unmarshal(b, &sf); err != nil {
+		return fmt.Errorf("runtime: malformed status file: %w", err)
+	}
+	st, err := ParseState(sf.State)
+	if err != nil {
+		return err
+	}
+	if st != StateReady {
+		return fmt.Errorf("%w: state=%s", ErrNotReady, st)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle_ForwardOnlyTransitions(t *testing.T) {
+	lc := NewLifecycle(time.Now())
+	require.Equal(t, StateStarting, lc.State())
+
+	var seen []string
+	lc.OnTransition(func(from, to State) { seen = append(seen, from.String()+"->"+to.String()) })
+
+	require.True(t, lc.Transition(StateReady))
+	require.False(t, lc.Transition(StateReady), "repeat transition is ignored")
+	require.True(t, lc.Transition(StateDraining))
+	require.False(t, lc.Transition(StateReady), "cannot go back to ready")
+	require.True(t, lc.Transition(StateStopped))
+
+	require.Equal(t, []string{"starting->ready", "ready->draining", "draining->stopped"}, seen)
+}
+
+func TestStatusFile_ReadyAndNotReady(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+	require.Error(t, CheckStatusFile(path), "missing file is unhealthy")
+
+	require.NoError(t, WriteStatusFile(path, StateReady, time.Now()))
+	require.NoError(t, CheckStatusFile(path))
+
+	require.NoError(t, WriteStatusFile(path, StateDraining, time.Now()))
+	err := CheckStatusFile(path)
+	require.True(t, errors.Is(err, ErrNotReady))
+}
@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -12,7 +13,8 @@ import (
 // Middleware enforces runtime limits for tool calls using the Controller.
 // It bounds global concurrency and applies an operation timeout to each call.
 type Middleware struct {
-	ctrl *Controller
+	ctrl     *Controller
+	observer CallObserver
 }
 
 // NewMiddleware constructs a Middleware bound to the provided Controller.
@@ -20,10 +22,36 @@ func NewMiddleware(ctrl *Controller) *Middleware {
 	return &Middleware{ctrl: ctrl}
 }
 
+// SetCallObserver registers fn to receive each call's stats and outcome once
+// the call finishes. Call it before the server starts handling requests.
+func (m *Middleware) SetCallObserver(fn CallObserver) {
+	m.observer = fn
+}
+
+// drainExemptTools may still run while draining; they are read-only and let
+// supervisors observe shutdown progress.
+var drainExemptTools = map[string]struct{}{
+	"server_status": {},
+}
+
 // ToolMiddleware implements mcp-go's tool handler middleware interface.
-// It acquires a request slot, applies a timeout, and guarantees release.
+// It rejects calls once the server is draining, acquires a request slot,
+// applies a timeout, and guarantees release. Each call's context carries a
+// CallStats reported to the observer when the call returns.
 func (m *Middleware) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (res *mcp.CallToolResult, err error) {
+		stats := NewCallStats()
+		ctx = WithCallStats(ctx, stats)
+		if m.observer != nil {
+			defer func() { m.observer(req.Params.Name, stats.Summary(), res, err) }()
+		}
+
+		if st := m.ctrl.lifecycle.State(); st >= StateDraining {
+			if _, ok := drainExemptTools[req.Params.Name]; !ok {
+				return mcperr.New(mcperr.ShuttingDown, fmt.Sprintf("server is %s and not accepting new tool calls", st)), nil
+			}
+		}
+
 		// Attempt to acquire request capacity with a bounded wait.
 		acquireCtx := ctx
 		if m.ctrl.limits.AcquireRequestTimeout > 0 {
@@ -33,11 +61,18 @@ func (m *Middleware) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHand
 		}
 
 		if err := m.ctrl.AcquireRequest(acquireCtx); err != nil {
-			// Return a tool-level error so the client can self-correct/retry.
-			msg := fmt.Sprintf("%s: concurrent request limit reached (max=%d). Please retry shortly.", mcperr.BusyResource, m.ctrl.limits.MaxConcurrentRequests)
-			return mcperr.FromText(msg), nil
+			// Return a tool-level error with the queue depth and an estimated
+			// wait so clients back off instead of retrying in lockstep.
+			queued := m.ctrl.Waiting() + 1
+			wait := m.ctrl.EstimatedWait(queued)
+			msg := fmt.Sprintf("concurrent request limit reached (max=%d, inFlight=%d, queued=%d); retry in about %dms", m.ctrl.limits.MaxConcurrentRequests, m.ctrl.InFlight(), queued, wait.Milliseconds())
+			return mcperr.NewRetryAfter(mcperr.BusyResource, msg, wait), nil
 		}
 		defer m.ctrl.ReleaseRequest()
+		m.ctrl.inFlight.Add(1)
+		defer m.ctrl.inFlight.Add(-1)
+		start := time.Now()
+		defer func() { m.ctrl.RecordLatency(time.Since(start)) }()
 
 		callCtx := ctx
 		cancel := func() {}
@@ -48,7 +83,7 @@ func (m *Middleware) ToolMiddleware(next server.ToolHandlerFunc) server.ToolHand
 		defer cancel()
 
 		// Delegate to the next handler.
-		res, err := next(callCtx, req)
+		res, err = next(callCtx, req)
 
 		// If the underlying handler surfaced a context deadline or cancellation,
 		// prefer a tool-level TIMEOUT error with consistent guidance.
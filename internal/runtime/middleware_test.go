@@ -8,6 +8,7 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/pkg/mcperr"
 )
 
 func TestMiddleware_AllowsWhenCapacity(t *testing.T) {
@@ -52,6 +53,46 @@ func TestMiddleware_BusyWhenSaturated(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, res)
 	require.True(t, res.IsError)
+	out := res.StructuredContent.(mcperr.ErrorOutput)
+	require.Equal(t, "BUSY_RESOURCE", out.Code)
+	require.Equal(t, defaultCallLatency.Milliseconds(), out.RetryAfterMs)
+	require.Contains(t, out.Message, "queued=1")
+	require.Zero(t, ctrl.Waiting())
+}
+
+func TestMiddleware_BusyHintTracksLatencyAndQueue(t *testing.T) {
+	limits := NewLimits(1, 1)
+	limits.AcquireRequestTimeout = 200 * time.Millisecond
+	ctrl := NewController(limits)
+	ctrl.RecordLatency(300 * time.Millisecond)
+	ctrl.RecordLatency(500 * time.Millisecond)
+	mw := NewMiddleware(ctrl)
+
+	// Hold the only slot in a running call, then queue two more behind it.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	next := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-release
+		return mcp.NewToolResultText("ok"), nil
+	}
+	wrapped := mw.ToolMiddleware(server.ToolHandlerFunc(next))
+	go func() { _, _ = wrapped(context.Background(), mcp.CallToolRequest{}) }()
+	<-started
+	queuedCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = ctrl.AcquireRequest(queuedCtx) }()
+	require.Eventually(t, func() bool { return ctrl.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	res, err := wrapped(context.Background(), mcp.CallToolRequest{})
+	close(release)
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	out := res.StructuredContent.(mcperr.ErrorOutput)
+	// Two requests queued for one slot: two rounds of the 400ms average.
+	require.Equal(t, int64(800), out.RetryAfterMs)
+	require.Contains(t, out.Message, "inFlight=1, queued=2")
+	require.Contains(t, res.Content[0].(mcp.TextContent).Text, "retry in about 800ms")
 }
 
 func TestMiddleware_TimeoutApplied(t *testing.T) {
@@ -75,3 +116,70 @@ func TestMiddleware_TimeoutApplied(t *testing.T) {
 	require.NotNil(t, res)
 	require.True(t, res.IsError)
 }
+
+func TestMiddleware_ShuttingDownWhileDraining(t *testing.T) {
+	ctrl := NewController(NewLimits(1, 1))
+	ctrl.Lifecycle().Transition(StateReady)
+	ctrl.Lifecycle().Transition(StateDraining)
+	mw := NewMiddleware(ctrl)
+
+	calls := 0
+	next := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText("ok"), nil
+	}
+	wrapped := mw.ToolMiddleware(server.ToolHandlerFunc(next))
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "read_range"
+	res, err := wrapped(context.Background(), req)
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	require.Contains(t, res.Content[0].(mcp.TextContent).Text, "SHUTTING_DOWN")
+	require.Zero(t, calls, "handler must not run while draining")
+
+	// server_status stays available for supervisors.
+	req.Params.Name = "server_status"
+	res, err = wrapped(context.Background(), req)
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+	require.Equal(t, 1, calls)
+}
+
+func TestMiddleware_ReportsCallStats(t *testing.T) {
+	ctrl := NewController(NewLimits(1, 1))
+	mw := NewMiddleware(ctrl)
+	var (
+		gotTool string
+		gotSum  CallSummary
+		gotRes  *mcp.CallToolResult
+	)
+	mw.SetCallObserver(func(tool string, sum CallSummary, res *mcp.CallToolResult, err error) {
+		gotTool, gotSum, gotRes = tool, sum, res
+	})
+
+	next := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		stats := CallStatsFrom(ctx)
+		require.NotNil(t, stats)
+		stats.AddCells(40)
+		stats.Record(10, 7, true)
+		return mcp.NewToolResultText("ok"), nil
+	}
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "read_range"
+	res, err := mw.ToolMiddleware(server.ToolHandlerFunc(next))(context.Background(), req)
+	require.NoError(t, err)
+	require.Same(t, res, gotRes)
+	require.Equal(t, "read_range", gotTool)
+	require.Equal(t, 50, gotSum.ProcessedCells)
+	require.Equal(t, 7, gotSum.Returned)
+	require.True(t, gotSum.Truncated)
+
+	// Rejected calls are reported too, and stats are nil-safe outside a call.
+	ctrl.Lifecycle().Transition(StateDraining)
+	res, err = mw.ToolMiddleware(server.ToolHandlerFunc(next))(context.Background(), req)
+	require.NoError(t, err)
+	require.True(t, gotRes.IsError)
+	require.Same(t, res, gotRes)
+	CallStatsFrom(context.Background()).Record(1, 1, false)
+}
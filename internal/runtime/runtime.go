@@ -2,6 +2,8 @@ package runtime
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vinodismyname/mcpxcel/config"
@@ -18,10 +20,23 @@ type Limits struct {
 	MaxPayloadBytes int
 	MaxCellsPerOp   int
 	PreviewRowLimit int
+	MaxRowsPerEdit  int
+	MaxExportCells  int
+	// MaxCrosstabCells caps crosstab row keys × column keys
+	MaxCrosstabCells int
+	// TextBudgetPercent is the share of MaxPayloadBytes a tool's text
+	// content may use (see TextBudgetBytes)
+	TextBudgetPercent int
+
+	// File size bound enforced before a workbook is opened
+	MaxFileBytes int64
 
 	// Timeouts
 	OperationTimeout      time.Duration
 	AcquireRequestTimeout time.Duration
+
+	// CursorTTL bounds pagination cursor age; zero disables expiry
+	CursorTTL time.Duration
 }
 
 // NewLimits initializes Limits with sensible fallbacks when values are unset.
@@ -39,16 +54,90 @@ func NewLimits(maxConcurrentRequests, maxOpenWorkbooks int) Limits {
 		MaxPayloadBytes:       config.DefaultMaxPayloadBytes,
 		MaxCellsPerOp:         config.DefaultMaxCellsPerOp,
 		PreviewRowLimit:       config.DefaultPreviewRowLimit,
+		MaxRowsPerEdit:        config.DefaultMaxRowsPerEdit,
+		MaxExportCells:        config.DefaultMaxExportCells,
+		MaxCrosstabCells:      config.DefaultMaxCrosstabCells,
+		TextBudgetPercent:     config.DefaultTextBudgetPercent,
+		MaxFileBytes:          config.DefaultMaxFileBytes,
 		OperationTimeout:      config.DefaultOperationTimeout,
 		AcquireRequestTimeout: config.DefaultAcquireRequestTimeout,
+		CursorTTL:             config.DefaultCursorTTL,
 	}
 }
 
+// TextBudgetBytes returns the most bytes a tool's text content may use:
+// TextBudgetPercent of MaxPayloadBytes, with an unset percent meaning the
+// default. It returns 0 (no budget) when MaxPayloadBytes is unset.
+func (l Limits) TextBudgetBytes() int {
+	pct := l.TextBudgetPercent
+	if pct <= 0 || pct > 100 {
+		pct = config.DefaultTextBudgetPercent
+	}
+	if l.MaxPayloadBytes <= 0 {
+		return 0
+	}
+	return l.MaxPayloadBytes * pct / 100
+}
+
 // Controller coordinates runtime semaphores for request and workbook guardrails.
 type Controller struct {
 	limits            Limits
 	requestSemaphore  *semaphore.Weighted
 	workbookSemaphore *semaphore.Weighted
+	lifecycle         *Lifecycle
+	inFlight          atomic.Int64
+	waiting           atomic.Int64
+	requestsHeld      atomic.Int64
+	workbooksHeld     atomic.Int64
+	latency           latencyRing
+}
+
+// latencyWindow is how many recent call durations feed the wait estimate
+// returned with BUSY_RESOURCE.
+const latencyWindow = 32
+
+// defaultCallLatency stands in for the average call duration until a call
+// has finished.
+const defaultCallLatency = 100 * time.Millisecond
+
+// latencyRing keeps the most recent call durations.
+type latencyRing struct {
+	mu      sync.Mutex
+	samples [latencyWindow]time.Duration
+	next, n int
+}
+
+func (r *latencyRing) add(d time.Duration) {
+	r.mu.Lock()
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % latencyWindow
+	if r.n < latencyWindow {
+		r.n++
+	}
+	r.mu.Unlock()
+}
+
+// mean returns the average of the recorded durations, or zero when empty.
+func (r *latencyRing) mean() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range r.samples[:r.n] {
+		sum += d
+	}
+	return sum / time.Duration(r.n)
+}
+
+// Stats is a point-in-time view of the Controller's permits.
+type Stats struct {
+	InFlight         int64 // tool calls executing
+	RequestPermits   int64 // request slots held
+	RequestCapacity  int
+	WorkbookPermits  int64 // open-workbook slots held
+	WorkbookCapacity int
 }
 
 // NewController constructs a Controller backed by weighted semaphores.
@@ -57,26 +146,85 @@ func NewController(limits Limits) *Controller {
 		limits:            limits,
 		requestSemaphore:  semaphore.NewWeighted(int64(limits.MaxConcurrentRequests)),
 		workbookSemaphore: semaphore.NewWeighted(int64(limits.MaxOpenWorkbooks)),
+		lifecycle:         NewLifecycle(time.Now()),
 	}
 }
 
-// AcquireRequest reserves capacity for an incoming request.
+// Lifecycle exposes the server state machine driven by bootstrap and shutdown.
+func (c *Controller) Lifecycle() *Lifecycle {
+	return c.lifecycle
+}
+
+// InFlight reports the number of tool calls currently executing.
+func (c *Controller) InFlight() int64 {
+	return c.inFlight.Load()
+}
+
+// Stats reports in-flight calls and the request and workbook permits held.
+func (c *Controller) Stats() Stats {
+	return Stats{
+		InFlight:         c.inFlight.Load(),
+		RequestPermits:   c.requestsHeld.Load(),
+		RequestCapacity:  c.limits.MaxConcurrentRequests,
+		WorkbookPermits:  c.workbooksHeld.Load(),
+		WorkbookCapacity: c.limits.MaxOpenWorkbooks,
+	}
+}
+
+// Waiting reports the number of requests waiting for a request slot.
+func (c *Controller) Waiting() int64 {
+	return c.waiting.Load()
+}
+
+// RecordLatency adds a finished call's duration to the rolling average behind
+// EstimatedWait.
+func (c *Controller) RecordLatency(d time.Duration) {
+	c.latency.add(d)
+}
+
+// EstimatedWait approximates how long a request with queued requests ahead of
+// it (itself included) waits for a slot: one average call duration for each
+// round of MaxConcurrentRequests calls that must finish first.
+func (c *Controller) EstimatedWait(queued int64) time.Duration {
+	avg := c.latency.mean()
+	if avg <= 0 {
+		avg = defaultCallLatency
+	}
+	slots := int64(max(c.limits.MaxConcurrentRequests, 1))
+	rounds := max((queued+slots-1)/slots, 1)
+	return avg * time.Duration(rounds)
+}
+
+// AcquireRequest reserves capacity for an incoming request, counting it as
+// waiting until a slot frees up or ctx ends.
 func (c *Controller) AcquireRequest(ctx context.Context) error {
-	return c.requestSemaphore.Acquire(ctx, 1)
+	c.waiting.Add(1)
+	defer c.waiting.Add(-1)
+	if err := c.requestSemaphore.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	c.requestsHeld.Add(1)
+	return nil
 }
 
 // ReleaseRequest frees previously-acquired request capacity.
 func (c *Controller) ReleaseRequest() {
+	c.requestsHeld.Add(-1)
 	c.requestSemaphore.Release(1)
 }
 
 // AcquireWorkbook reserves an open workbook slot.
 func (c *Controller) AcquireWorkbook(ctx context.Context) error {
-	return c.workbookSemaphore.Acquire(ctx, 1)
+	if err := c.workbookSemaphore.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	c.workbooksHeld.Add(1)
+	return nil
 }
 
 // ReleaseWorkbook frees an open workbook slot.
 func (c *Controller) ReleaseWorkbook() {
+	c.workbooksHeld.Add(-1)
 	c.workbookSemaphore.Release(1)
 }
 
@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -14,8 +15,81 @@ func TestControllerAcquireRelease(t *testing.T) {
 	require.Equal(t, limits, controller.LimitsSnapshot())
 
 	require.NoError(t, controller.AcquireRequest(context.Background()))
+	require.Equal(t, int64(1), controller.Stats().RequestPermits)
 	controller.ReleaseRequest()
 
 	require.NoError(t, controller.AcquireWorkbook(context.Background()))
+	require.Equal(t, Stats{WorkbookPermits: 1, RequestCapacity: 1, WorkbookCapacity: 1}, controller.Stats())
 	controller.ReleaseWorkbook()
+	require.Equal(t, int64(0), controller.Stats().WorkbookPermits)
+
+	// A failed acquire holds no permit.
+	require.NoError(t, controller.AcquireRequest(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, controller.AcquireRequest(ctx))
+	require.Equal(t, int64(1), controller.Stats().RequestPermits)
+	controller.ReleaseRequest()
+}
+
+func TestLimitsApplyEnv(t *testing.T) {
+	base := NewLimits(1, 1)
+	t.Setenv("MCPXCEL_MAX_EXPORT_CELLS", "")
+	t.Setenv("MCPXCEL_MAX_CROSSTAB_CELLS", "")
+	t.Setenv("MCPXCEL_MAX_FILE_BYTES", "")
+	t.Setenv("MCPXCEL_CURSOR_TTL", "")
+	t.Setenv("MCPXCEL_TEXT_BUDGET_PCT", "")
+	l, err := base.ApplyEnv()
+	require.NoError(t, err)
+	require.Equal(t, base, l)
+
+	t.Setenv("MCPXCEL_MAX_EXPORT_CELLS", "5000")
+	l, err = base.ApplyEnv()
+	require.NoError(t, err)
+	require.Equal(t, 5000, l.MaxExportCells)
+
+	t.Setenv("MCPXCEL_MAX_EXPORT_CELLS", "-1")
+	_, err = base.ApplyEnv()
+	require.Error(t, err)
+
+	t.Setenv("MCPXCEL_MAX_EXPORT_CELLS", "")
+	t.Setenv("MCPXCEL_MAX_CROSSTAB_CELLS", "400")
+	l, err = base.ApplyEnv()
+	require.NoError(t, err)
+	require.Equal(t, 400, l.MaxCrosstabCells)
+
+	t.Setenv("MCPXCEL_MAX_CROSSTAB_CELLS", "")
+	t.Setenv("MCPXCEL_MAX_FILE_BYTES", "5368709120")
+	l, err = base.ApplyEnv()
+	require.NoError(t, err)
+	require.Equal(t, int64(5<<30), l.MaxFileBytes)
+
+	t.Setenv("MCPXCEL_MAX_FILE_BYTES", "big")
+	_, err = base.ApplyEnv()
+	require.Error(t, err)
+
+	t.Setenv("MCPXCEL_MAX_FILE_BYTES", "")
+	t.Setenv("MCPXCEL_CURSOR_TTL", "0")
+	l, err = base.ApplyEnv()
+	require.NoError(t, err)
+	require.Zero(t, l.CursorTTL)
+
+	t.Setenv("MCPXCEL_CURSOR_TTL", "2h")
+	l, err = base.ApplyEnv()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Hour, l.CursorTTL)
+
+	t.Setenv("MCPXCEL_CURSOR_TTL", "-5m")
+	_, err = base.ApplyEnv()
+	require.Error(t, err)
+
+	t.Setenv("MCPXCEL_CURSOR_TTL", "")
+	require.Equal(t, base.MaxPayloadBytes/2, base.TextBudgetBytes())
+	t.Setenv("MCPXCEL_TEXT_BUDGET_PCT", "25")
+	l, err = base.ApplyEnv()
+	require.NoError(t, err)
+	require.Equal(t, base.MaxPayloadBytes/4, l.TextBudgetBytes())
+	t.Setenv("MCPXCEL_TEXT_BUDGET_PCT", "150")
+	_, err = base.ApplyEnv()
+	require.Error(t, err)
 }
@@ -0,0 +1,114 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isPattern reports whether an allow-list entry is a file pattern rather than
+// a directory: it contains a glob metacharacter.
+func isPattern(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
+
+// ParsePattern canonicalizes an allow-list file pattern such as
+// /data/exports/**/report_*.xlsx. Segments follow filepath.Match syntax,
+// except that a segment of exactly ** matches zero or more directories. The
+// directories before the first glob segment must exist and are resolved
+// through symlinks, like directory roots, so the pattern is matched against
+// resolved real paths.
+func ParsePattern(p string) (string, error) {
+	abs, err := filepath.Abs(strings.TrimSpace(p))
+	if err != nil {
+		return "", fmt.Errorf("security: resolve abs for %q: %w", p, err)
+	}
+	segs := splitPath(abs)
+	first := -1
+	for i, seg := range segs {
+		if !isPattern(seg) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if seg != "**" && strings.Contains(seg, "**") {
+			return "", fmt.Errorf("security: invalid pattern %q: ** must be a whole path segment", p)
+		}
+		if _, err := filepath.Match(seg, ""); err != nil {
+			return "", fmt.Errorf("security: invalid pattern %q: %w", p, err)
+		}
+	}
+	if first < 0 {
+		return "", fmt.Errorf("security: allow-list entry is not a pattern: %q", p)
+	}
+	base := joinPath(segs[:first])
+	real, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", fmt.Errorf("security: eval symlinks for %q: %w", base, err)
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return "", fmt.Errorf("security: stat %q: %w", real, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("security: pattern base is not a directory: %q", real)
+	}
+	return filepath.Join(append([]string{real}, segs[first:]...)...), nil
+}
+
+// PatternBase returns the directory part of a canonical pattern before its
+// first glob segment; every path the pattern matches lies below it.
+func PatternBase(pattern string) string {
+	segs := splitPath(pattern)
+	for i, seg := range segs {
+		if isPattern(seg) {
+			return joinPath(segs[:i])
+		}
+	}
+	return filepath.Dir(pattern)
+}
+
+// matchPattern reports whether the resolved absolute path matches the
+// canonical pattern.
+func matchPattern(pattern, path string) bool {
+	return matchSegments(splitPath(pattern), splitPath(filepath.Clean(path)))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// splitPath splits an absolute path into its segments; the first segment is
+// the volume name (empty on Unix).
+func splitPath(p string) []string {
+	return strings.Split(p, string(filepath.Separator))
+}
+
+// joinPath reverses splitPath for a leading run of segments.
+func joinPath(segs []string) string {
+	j := strings.Join(segs, string(filepath.Separator))
+	if j == filepath.VolumeName(j) {
+		// A bare volume (or "" on Unix) is the filesystem root.
+		j += string(filepath.Separator)
+	}
+	return j
+}
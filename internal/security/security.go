@@ -5,15 +5,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // Manager enforces filesystem allow-list and path validation guardrails.
 // It resolves and stores canonical absolute directory paths and validates
 // that requested file paths are within these roots and have supported extensions.
+// Allow-list entries containing glob metacharacters are file patterns instead
+// of roots (see ParsePattern). Each rule is either read-only or read-write;
+// only read-write rules accept writes. Denied directories override every
+// allow rule: nothing under them is accepted.
 type Manager struct {
-	allowedDirs []string
-	allowedExts map[string]struct{}
+	allowedDirs     []string
+	deniedDirs      []string
+	writable        map[string]bool
+	patterns        []string
+	patternWritable map[string]bool
+	allowedExts     map[string]struct{}
+	maxFileBytes    int64
 }
 
 // ErrNotAllowed indicates the requested path is outside the allow-list roots.
@@ -25,12 +35,40 @@ var ErrUnsupportedExtension = errors.New("security: unsupported file extension")
 // ErrNotFound indicates the requested file does not exist or is not accessible.
 var ErrNotFound = errors.New("security: file not found")
 
+// ErrFileTooLarge indicates the file exceeds the configured maximum size.
+var ErrFileTooLarge = errors.New("security: file exceeds maximum size")
+
+// ErrReadOnlyRoot indicates a write into an allow-list root or pattern
+// configured as read-only. ValidateWritePath returns it as a *ReadOnlyRootError.
+var ErrReadOnlyRoot = errors.New("security: allow-list root is read-only")
+
+// ReadOnlyRootError names the read-only root or pattern that refused a write.
+type ReadOnlyRootError struct {
+	Root string
+}
+
+func (e *ReadOnlyRootError) Error() string {
+	return fmt.Sprintf("security: allow-list root %s is read-only", e.Root)
+}
+
+// Is reports ErrReadOnlyRoot so callers can match with errors.Is.
+func (e *ReadOnlyRootError) Is(target error) bool {
+	return target == ErrReadOnlyRoot
+}
+
 // NewManager constructs a security manager given an allow-list of directories
 // and a list of allowed file extensions (case-insensitive, with leading dot).
-// Directories are canonicalized (absolute + EvalSymlinks) and validated.
+// Directories are canonicalized (absolute + EvalSymlinks) and validated. All
+// directories are read-write; use NewManagerWithModes for read-only roots.
 func NewManager(allowDirs []string, allowedExtensions []string) (*Manager, error) {
+	return NewManagerWithModes(nil, allowDirs, allowedExtensions)
+}
+
+// NewManagerWithModes constructs a security manager with separate read-only
+// and read-write allow-lists. A directory listed in both is read-only.
+func NewManagerWithModes(readOnlyDirs, readWriteDirs []string, allowedExtensions []string) (*Manager, error) {
 	if len(allowedExtensions) == 0 {
-		allowedExtensions = []string{".xlsx", ".xlsm", ".xltx", ".xltm"}
+		allowedExtensions = []string{".xlsx", ".xlsm", ".xltx", ".xltm", ".csv"}
 	}
 
 	exts := make(map[string]struct{}, len(allowedExtensions))
@@ -42,66 +80,203 @@ func NewManager(allowDirs []string, allowedExtensions []string) (*Manager, error
 		exts[e] = struct{}{}
 	}
 
-	canonical := make([]string, 0, len(allowDirs))
-	for _, d := range allowDirs {
+	m := &Manager{writable: make(map[string]bool), patternWritable: make(map[string]bool), allowedExts: exts}
+	if err := m.addRoots(readWriteDirs, true); err != nil {
+		return nil, err
+	}
+	if err := m.addRoots(readOnlyDirs, false); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// addRoots canonicalizes dirs and records them with the given write mode.
+// Read-only registration downgrades a rule already registered as read-write.
+func (m *Manager) addRoots(dirs []string, writable bool) error {
+	for _, d := range dirs {
 		d = strings.TrimSpace(d)
 		if d == "" { // skip empties
 			continue
 		}
-		abs, err := filepath.Abs(d)
-		if err != nil {
-			return nil, fmt.Errorf("security: resolve abs for %q: %w", d, err)
+		if isPattern(d) {
+			if err := m.addPattern(d, writable); err != nil {
+				return err
+			}
+			continue
 		}
-		// EvalSymlinks so that symlinked roots cannot be used to escape later.
-		real, err := filepath.EvalSymlinks(abs)
+		real, err := canonicalDir(d)
 		if err != nil {
-			return nil, fmt.Errorf("security: eval symlinks for %q: %w", abs, err)
+			return err
+		}
+		if _, seen := m.writable[real]; !seen {
+			m.allowedDirs = append(m.allowedDirs, real)
+			m.writable[real] = writable
+			continue
+		}
+		m.writable[real] = m.writable[real] && writable
+	}
+	return nil
+}
+
+// AddDeniedDirectories excludes dirs and everything below them, even where
+// an allow-list root or pattern covers them: deny wins. Entries are
+// canonicalized like allow-list roots and must exist.
+func (m *Manager) AddDeniedDirectories(dirs []string) error {
+	for _, d := range dirs {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
 		}
-		info, err := os.Stat(real)
+		real, err := canonicalDir(d)
 		if err != nil {
-			return nil, fmt.Errorf("security: stat %q: %w", real, err)
+			return err
 		}
-		if !info.IsDir() {
-			return nil, fmt.Errorf("security: allow-list entry is not a directory: %q", real)
+		if !slices.Contains(m.deniedDirs, real) {
+			m.deniedDirs = append(m.deniedDirs, real)
 		}
-		// Normalize with a trailing separator removed for consistent prefix checks.
-		canonical = append(canonical, filepath.Clean(real))
 	}
+	return nil
+}
 
-	return &Manager{allowedDirs: canonical, allowedExts: exts}, nil
+// canonicalDir resolves d to a clean absolute path with symlinks evaluated
+// and checks that it is a directory.
+func canonicalDir(d string) (string, error) {
+	abs, err := filepath.Abs(d)
+	if err != nil {
+		return "", fmt.Errorf("security: resolve abs for %q: %w", d, err)
+	}
+	// EvalSymlinks so that symlinked roots cannot be used to escape later.
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("security: eval symlinks for %q: %w", abs, err)
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return "", fmt.Errorf("security: stat %q: %w", real, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("security: allow-list or deny-list entry is not a directory: %q", real)
+	}
+	// Normalize with a trailing separator removed for consistent prefix checks.
+	return filepath.Clean(real), nil
 }
 
-// NewManagerFromEnv constructs a Manager from environment variable
-// MCPXCEL_ALLOWED_DIRS as a path list separated by os.PathListSeparator.
-// If the variable is empty, an empty allow-list is used (deny-by-default).
+// addPattern canonicalizes a file pattern and records it with the given write
+// mode.
+func (m *Manager) addPattern(p string, writable bool) error {
+	canon, err := ParsePattern(p)
+	if err != nil {
+		return err
+	}
+	if _, seen := m.patternWritable[canon]; !seen {
+		m.patterns = append(m.patterns, canon)
+		m.patternWritable[canon] = writable
+		return nil
+	}
+	m.patternWritable[canon] = m.patternWritable[canon] && writable
+	return nil
+}
+
+// NewManagerFromEnv constructs a Manager from the path lists (separated by
+// os.PathListSeparator) in MCPXCEL_ALLOWED_DIRS_RO (read-only rules) and
+// MCPXCEL_ALLOWED_DIRS_RW (read-write rules); entries are directories or
+// file patterns. MCPXCEL_ALLOWED_DIRS is kept
+// for compatibility and adds read-write roots. If all are empty, an empty
+// allow-list is used (deny-by-default). MCPXCEL_DENIED_DIRS lists
+// directories excluded from all of them.
 func NewManagerFromEnv() (*Manager, error) {
-	list := os.Getenv("MCPXCEL_ALLOWED_DIRS")
-	var dirs []string
-	if list != "" {
-		dirs = filepath.SplitList(list)
+	ro := envDirs("MCPXCEL_ALLOWED_DIRS_RO")
+	rw := append(envDirs("MCPXCEL_ALLOWED_DIRS_RW"), envDirs("MCPXCEL_ALLOWED_DIRS")...)
+	m, err := NewManagerWithModes(ro, rw, nil)
+	if err != nil {
+		return nil, err
 	}
-	return NewManager(dirs, nil)
+	if err := m.AddDeniedDirectories(envDirs("MCPXCEL_DENIED_DIRS")); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func envDirs(name string) []string {
+	list := os.Getenv(name)
+	if list == "" {
+		return nil
+	}
+	return filepath.SplitList(list)
 }
 
-// AllowedDirectories returns the canonical allow-list roots.
+// AllowedDirectories returns the canonical allow-list directory roots,
+// read-only and read-write. Patterns are reported by AllowedPatterns.
 func (m *Manager) AllowedDirectories() []string {
 	out := make([]string, len(m.allowedDirs))
 	copy(out, m.allowedDirs)
 	return out
 }
 
+// WritableDirectories returns the canonical read-write allow-list roots.
+func (m *Manager) WritableDirectories() []string {
+	var out []string
+	for _, d := range m.allowedDirs {
+		if m.writable[d] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// DeniedDirectories returns the canonical denied directories.
+func (m *Manager) DeniedDirectories() []string {
+	out := make([]string, len(m.deniedDirs))
+	copy(out, m.deniedDirs)
+	return out
+}
+
+// AllowedPatterns returns the canonical allow-list file patterns, read-only
+// and read-write.
+func (m *Manager) AllowedPatterns() []string {
+	out := make([]string, len(m.patterns))
+	copy(out, m.patterns)
+	return out
+}
+
+// WritablePatterns returns the canonical read-write allow-list file patterns.
+func (m *Manager) WritablePatterns() []string {
+	var out []string
+	for _, p := range m.patterns {
+		if m.patternWritable[p] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// SetMaxFileBytes sets the largest file ValidateOpenPath accepts; n <= 0
+// disables the check.
+func (m *Manager) SetMaxFileBytes(n int64) {
+	m.maxFileBytes = n
+}
+
+// MaxFileBytes returns the configured file size limit (0 when unlimited).
+func (m *Manager) MaxFileBytes() int64 {
+	return m.maxFileBytes
+}
+
 // ValidateConfig returns an error when no allow-list entries are configured.
 // This supports fail-safe startup where file operations should be disabled
 // until explicit directories are provided by the operator.
 func (m *Manager) ValidateConfig() error {
-	if len(m.allowedDirs) == 0 {
+	if len(m.allowedDirs) == 0 && len(m.patterns) == 0 {
 		return errors.New("security: no allowed directories configured")
 	}
 	return nil
 }
 
 // ValidateOpenPath ensures the input path refers to an existing file with an
-// allowed extension inside one of the configured allow-list directories.
+// allowed extension inside one of the configured allow-list directories, or
+// matching one of the allow-list patterns, and no larger than the configured
+// maximum size (ErrFileTooLarge otherwise). Directories are checked first;
+// both checks use the path after resolving symlinks. Paths under a denied
+// directory fail with ErrNotAllowed even when a rule admits them.
 // It returns the canonical absolute path suitable for opening.
 func (m *Manager) ValidateOpenPath(input string) (string, error) {
 	if input == "" {
@@ -138,22 +313,110 @@ func (m *Manager) ValidateOpenPath(input string) (string, error) {
 		return "", ErrNotAllowed
 	}
 
-	// Check containment: real path must be within one of the allow-list roots.
-	for _, root := range m.allowedDirs {
-		// filepath.Rel returns a path starting with ".." when outside.
-		rel, err := filepath.Rel(root, real)
-		if err != nil {
-			continue
+	if _, _, ok := m.ruleFor(real); !ok {
+		return "", ErrNotAllowed
+	}
+	if m.maxFileBytes > 0 && info.Size() > m.maxFileBytes {
+		return "", fmt.Errorf("%w: %d bytes, limit %d", ErrFileTooLarge, info.Size(), m.maxFileBytes)
+	}
+	return real, nil
+}
+
+// ValidateWritePath checks an output path that may not exist yet: the
+// extension must be allowed and the parent directory must exist (after
+// resolving symlinks) inside a read-write allow-list root, or the resolved
+// path must match a read-write pattern. An existing target must be a regular
+// file, not a directory or symlink. Paths under a read-only root or pattern
+// fail with a *ReadOnlyRootError naming it, and paths under a denied directory
+// with ErrNotAllowed. It returns the canonical absolute path to write.
+func (m *Manager) ValidateWritePath(input string) (string, error) {
+	if input == "" {
+		return "", ErrNotAllowed
+	}
+	ext := strings.ToLower(filepath.Ext(input))
+	if _, ok := m.allowedExts[ext]; !ok {
+		return "", ErrUnsupportedExtension
+	}
+	abs, err := filepath.Abs(input)
+	if err != nil {
+		return "", fmt.Errorf("security: abs path: %w", err)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(abs))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("security: eval symlinks: %w", err)
+	}
+	target := filepath.Join(dir, filepath.Base(abs))
+	if info, err := os.Lstat(target); err == nil {
+		if !info.Mode().IsRegular() {
+			return "", ErrNotAllowed
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("security: stat: %w", err)
+	}
+	rule, writable, ok := m.ruleFor(target)
+	if !ok {
+		return "", ErrNotAllowed
+	}
+	if !writable {
+		return "", &ReadOnlyRootError{Root: rule}
+	}
+	return target, nil
+}
+
+// ruleFor returns the allow-list rule admitting the resolved path and whether
+// it accepts writes. Directory roots are tried first; when only patterns
+// match, any read-only match makes the path read-only. Denied directories
+// override both.
+func (m *Manager) ruleFor(real string) (string, bool, bool) {
+	for _, d := range m.deniedDirs {
+		if within(d, real) {
+			return "", false, false
 		}
-		if rel == "." || rel == "" {
-			// exact root match but file is not dir; continue to next root
+	}
+	if root, ok := m.rootFor(real); ok {
+		return root, m.writable[root], true
+	}
+	match := ""
+	for _, p := range m.patterns {
+		if !matchPattern(p, real) {
 			continue
 		}
-		// Normalize separators and check for escape attempts.
-		if !strings.HasPrefix(rel, "..") && !strings.HasPrefix(filepath.Clean(rel), "..") {
-			// Contained within root.
-			return real, nil
+		if !m.patternWritable[p] {
+			return p, false, true
+		}
+		if match == "" {
+			match = p
+		}
+	}
+	return match, match != "", match != ""
+}
+
+// rootFor returns the innermost allow-list root that strictly contains the
+// resolved path, so a nested root's mode overrides its parent's.
+func (m *Manager) rootFor(real string) (string, bool) {
+	best := ""
+	for _, root := range m.allowedDirs {
+		if within(root, real) && len(root) > len(best) {
+			best = root
 		}
 	}
-	return "", ErrNotAllowed
+	return best, best != ""
+}
+
+// within reports whether real lies strictly below dir.
+func within(dir, real string) bool {
+	// filepath.Rel returns a path starting with ".." when outside.
+	rel, err := filepath.Rel(dir, real)
+	if err != nil {
+		return false
+	}
+	if rel == "." || rel == "" {
+		// exact root match but file is not dir
+		return false
+	}
+	// Normalize separators and check for escape attempts.
+	return !strings.HasPrefix(rel, "..") && !strings.HasPrefix(filepath.Clean(rel), "..")
 }
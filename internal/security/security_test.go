@@ -1,6 +1,7 @@
 package security
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -114,3 +115,327 @@ func TestValidateOpenPath_UnsupportedExt(t *testing.T) {
 		t.Fatalf("expected unsupported extension error")
 	}
 }
+
+func TestValidateOpenPath_CSVAllowedByDefault(t *testing.T) {
+	root := mustTempDir(t)
+	fp := filepath.Join(root, "data.csv")
+	if err := os.WriteFile(fp, []byte("a,b\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	m, err := NewManager([]string{root}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if _, err := m.ValidateOpenPath(fp); err != nil {
+		t.Fatalf("expected csv to be allowed: %v", err)
+	}
+}
+
+func TestValidateWritePath(t *testing.T) {
+	root := mustTempDir(t)
+	outside := mustTempDir(t)
+	m, err := NewManager([]string{root}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+
+	want := filepath.Join(root, "out.csv")
+	got, err := m.ValidateWritePath(want)
+	if err != nil {
+		t.Fatalf("new file inside root: %v", err)
+	}
+	if got != want {
+		t.Fatalf("canonical = %q, want %q", got, want)
+	}
+	if _, err := m.ValidateWritePath(filepath.Join(outside, "out.csv")); err != ErrNotAllowed {
+		t.Fatalf("outside root: err = %v, want ErrNotAllowed", err)
+	}
+	if _, err := m.ValidateWritePath(filepath.Join(root, "out.txt")); err != ErrUnsupportedExtension {
+		t.Fatalf("bad extension: err = %v, want ErrUnsupportedExtension", err)
+	}
+	if _, err := m.ValidateWritePath(filepath.Join(root, "missing", "out.csv")); err != ErrNotFound {
+		t.Fatalf("missing parent: err = %v, want ErrNotFound", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink creation requires elevated privileges on Windows")
+	}
+	link := filepath.Join(root, "link.csv")
+	if err := os.Symlink(filepath.Join(outside, "target.csv"), link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if _, err := m.ValidateWritePath(link); err != ErrNotAllowed {
+		t.Fatalf("symlinked target: err = %v, want ErrNotAllowed", err)
+	}
+}
+
+func TestReadOnlyAndReadWriteRoots(t *testing.T) {
+	ro := mustTempDir(t)
+	rw := mustTempDir(t)
+	nested := filepath.Join(ro, "out")
+	if err := os.Mkdir(nested, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	existing := filepath.Join(ro, "book.xlsx")
+	if err := os.WriteFile(existing, []byte("test"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	m, err := NewManagerWithModes([]string{ro}, []string{rw, nested}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if got := m.WritableDirectories(); len(got) != 2 {
+		t.Fatalf("writable dirs = %v, want 2 entries", got)
+	}
+
+	if _, err := m.ValidateOpenPath(existing); err != nil {
+		t.Fatalf("read under read-only root: %v", err)
+	}
+	_, err = m.ValidateWritePath(existing)
+	var roErr *ReadOnlyRootError
+	if !errors.As(err, &roErr) || !errors.Is(err, ErrReadOnlyRoot) {
+		t.Fatalf("write under read-only root: err = %v, want ReadOnlyRootError", err)
+	}
+	if roErr.Root != ro {
+		t.Fatalf("read-only root = %q, want %q", roErr.Root, ro)
+	}
+	if _, err := m.ValidateWritePath(filepath.Join(rw, "out.csv")); err != nil {
+		t.Fatalf("write under read-write root: %v", err)
+	}
+	// The innermost root decides: a read-write directory nested in a
+	// read-only root accepts writes.
+	if _, err := m.ValidateWritePath(filepath.Join(nested, "out.csv")); err != nil {
+		t.Fatalf("write under nested read-write root: %v", err)
+	}
+
+	both, err := NewManagerWithModes([]string{rw}, []string{rw}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if _, err := both.ValidateWritePath(filepath.Join(rw, "out.csv")); !errors.Is(err, ErrReadOnlyRoot) {
+		t.Fatalf("root listed as both: err = %v, want ErrReadOnlyRoot", err)
+	}
+}
+
+func TestNewManagerFromEnv_Modes(t *testing.T) {
+	ro := mustTempDir(t)
+	rw := mustTempDir(t)
+	legacy := mustTempDir(t)
+	t.Setenv("MCPXCEL_ALLOWED_DIRS_RO", ro)
+	t.Setenv("MCPXCEL_ALLOWED_DIRS_RW", rw)
+	t.Setenv("MCPXCEL_ALLOWED_DIRS", legacy)
+	m, err := NewManagerFromEnv()
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if got := len(m.AllowedDirectories()); got != 3 {
+		t.Fatalf("allowed dirs len = %d, want 3", got)
+	}
+	writable := m.WritableDirectories()
+	if len(writable) != 2 || writable[0] != rw || writable[1] != legacy {
+		t.Fatalf("writable dirs = %v, want [%s %s]", writable, rw, legacy)
+	}
+}
+
+func TestValidateOpenPath_MaxFileBytes(t *testing.T) {
+	root := mustTempDir(t)
+	fpath := filepath.Join(root, "huge.xlsx")
+	f, err := os.Create(fpath)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// Sparse file: the size is reported without allocating the blocks.
+	if err := f.Truncate(2 << 30); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	m, err := NewManager([]string{root}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if _, err := m.ValidateOpenPath(fpath); err != nil {
+		t.Fatalf("no limit: %v", err)
+	}
+	m.SetMaxFileBytes(100 << 20)
+	if _, err := m.ValidateOpenPath(fpath); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("over limit: err = %v, want ErrFileTooLarge", err)
+	}
+	m.SetMaxFileBytes(2 << 30)
+	if _, err := m.ValidateOpenPath(fpath); err != nil {
+		t.Fatalf("at limit: %v", err)
+	}
+}
+
+func TestPatternRules(t *testing.T) {
+	root := mustTempDir(t)
+	exports := filepath.Join(root, "exports")
+	deep := filepath.Join(exports, "2024", "q1")
+	hr := filepath.Join(root, "hr")
+	for _, d := range []string{deep, hr} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	write := func(p string) string {
+		if err := os.WriteFile(p, []byte("test"), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		return p
+	}
+	top := write(filepath.Join(exports, "report_top.xlsx"))
+	nested := write(filepath.Join(deep, "report_q1.xlsx"))
+	other := write(filepath.Join(deep, "salaries.xlsx"))
+	secret := write(filepath.Join(hr, "report_hr.xlsx"))
+
+	m, err := NewManager([]string{filepath.Join(root, "exports", "**", "report_*.xlsx")}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if err := m.ValidateConfig(); err != nil {
+		t.Fatalf("validate config: %v", err)
+	}
+	if got := m.AllowedDirectories(); len(got) != 0 {
+		t.Fatalf("allowed dirs = %v, want none", got)
+	}
+	if got := m.AllowedPatterns(); len(got) != 1 {
+		t.Fatalf("allowed patterns = %v, want 1 entry", got)
+	}
+	for _, p := range []string{top, nested} {
+		if _, err := m.ValidateOpenPath(p); err != nil {
+			t.Fatalf("matching path %s: %v", p, err)
+		}
+	}
+	for _, p := range []string{other, secret} {
+		if _, err := m.ValidateOpenPath(p); !errors.Is(err, ErrNotAllowed) {
+			t.Fatalf("non-matching path %s: err = %v, want ErrNotAllowed", p, err)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		// A matching name that links outside the pattern is judged by its target.
+		link := filepath.Join(deep, "report_link.xlsx")
+		if err := os.Symlink(secret, link); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+		if _, err := m.ValidateOpenPath(link); !errors.Is(err, ErrNotAllowed) {
+			t.Fatalf("symlink escape: err = %v, want ErrNotAllowed", err)
+		}
+	}
+
+	// Writes need a read-write pattern; read-only patterns name themselves.
+	if _, err := m.ValidateWritePath(filepath.Join(deep, "report_new.xlsx")); err != nil {
+		t.Fatalf("write matching read-write pattern: %v", err)
+	}
+	ro, err := NewManagerWithModes([]string{filepath.Join(exports, "*.xlsx")}, nil, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	_, err = ro.ValidateWritePath(top)
+	var roErr *ReadOnlyRootError
+	if !errors.As(err, &roErr) || roErr.Root != filepath.Join(exports, "*.xlsx") {
+		t.Fatalf("write matching read-only pattern: err = %v, want ReadOnlyRootError", err)
+	}
+	if got := ro.WritablePatterns(); len(got) != 0 {
+		t.Fatalf("writable patterns = %v, want none", got)
+	}
+
+	for _, bad := range []string{filepath.Join(root, "x**", "*.xlsx"), filepath.Join(root, "[", "*.xlsx"), filepath.Join(root, "missing", "*.xlsx")} {
+		if _, err := NewManager([]string{bad}, nil); err == nil {
+			t.Fatalf("pattern %q: expected error", bad)
+		}
+	}
+}
+
+func TestDeniedDirectories(t *testing.T) {
+	root := mustTempDir(t)
+	restricted := filepath.Join(root, "restricted")
+	inner := filepath.Join(restricted, "public")
+	if err := os.MkdirAll(inner, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	write := func(p string) string {
+		if err := os.WriteFile(p, []byte("test"), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		return p
+	}
+	allowed := write(filepath.Join(root, "open.xlsx"))
+	hidden := write(filepath.Join(restricted, "payroll.xlsx"))
+	nested := write(filepath.Join(inner, "summary.xlsx"))
+
+	// Denied directory inside an allowed root.
+	m, err := NewManager([]string{root}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if err := m.AddDeniedDirectories([]string{restricted}); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if _, err := m.ValidateOpenPath(allowed); err != nil {
+		t.Fatalf("allowed path: %v", err)
+	}
+	if _, err := m.ValidateOpenPath(hidden); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("denied path: err = %v, want ErrNotAllowed", err)
+	}
+	if _, err := m.ValidateWritePath(filepath.Join(restricted, "out.csv")); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("denied write: err = %v, want ErrNotAllowed", err)
+	}
+
+	// Allowed root inside a denied directory: deny still wins.
+	m, err = NewManager([]string{root, inner}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if err := m.AddDeniedDirectories([]string{restricted}); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if _, err := m.ValidateOpenPath(nested); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("allowed root under denied dir: err = %v, want ErrNotAllowed", err)
+	}
+
+	// Patterns are overridden too, and symlinks are judged by their target.
+	m, err = NewManager([]string{filepath.Join(root, "**", "*.xlsx")}, nil)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if err := m.AddDeniedDirectories([]string{restricted}); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if _, err := m.ValidateOpenPath(nested); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("pattern under denied dir: err = %v, want ErrNotAllowed", err)
+	}
+	if runtime.GOOS != "windows" {
+		link := filepath.Join(root, "alias.xlsx")
+		if err := os.Symlink(hidden, link); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+		if _, err := m.ValidateOpenPath(link); !errors.Is(err, ErrNotAllowed) {
+			t.Fatalf("symlink into denied dir: err = %v, want ErrNotAllowed", err)
+		}
+	}
+
+	if err := m.AddDeniedDirectories([]string{filepath.Join(root, "missing")}); err == nil {
+		t.Fatal("missing denied dir: expected error")
+	}
+}
+
+func TestNewManagerFromEnv_Denied(t *testing.T) {
+	root := mustTempDir(t)
+	denied := filepath.Join(root, "restricted")
+	if err := os.Mkdir(denied, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Setenv("MCPXCEL_ALLOWED_DIRS_RW", root)
+	t.Setenv("MCPXCEL_DENIED_DIRS", denied)
+	m, err := NewManagerFromEnv()
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if got := m.DeniedDirectories(); len(got) != 1 || got[0] != denied {
+		t.Fatalf("denied dirs = %v, want [%s]", got, denied)
+	}
+}
@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+// WorkbookCounter reports how many workbooks are open; workbooks.Manager
+// satisfies it.
+type WorkbookCounter interface {
+	Count() int
+}
+
+// AllowList exposes the configured allow-list roots and file patterns.
+type AllowList interface {
+	AllowedDirectories() []string
+	AllowedPatterns() []string
+}
+
+// Health is the /healthz response body.
+type Health struct {
+	Status           string   `json:"status"` // ok or unavailable
+	State            string   `json:"state"`
+	Version          string   `json:"version"`
+	UptimeSeconds    int64    `json:"uptimeSeconds"`
+	OpenWorkbooks    int      `json:"openWorkbooks"`
+	InFlightRequests int64    `json:"inFlightRequests"`
+	RequestPermits   int64    `json:"requestPermits"`
+	RequestCapacity  int      `json:"requestCapacity"`
+	AllowedDirs      int      `json:"allowedDirectories"`
+	AllowedPatterns  int      `json:"allowedPatterns"`
+	Problems         []string `json:"problems,omitempty"`
+}
+
+// NewHealthHandler serves liveness and readiness as JSON. It answers 200
+// when the server is ready and 503 while starting or draining, when the
+// allow-list is empty, or when there is no workbook manager.
+func NewHealthHandler(version string, ctrl *runtime.Controller, mgr WorkbookCounter, allow AllowList) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lc := ctrl.Lifecycle()
+		stats := ctrl.Stats()
+		h := Health{
+			State:            lc.State().String(),
+			Version:          version,
+			UptimeSeconds:    int64(time.Since(lc.StartedAt()).Seconds()),
+			InFlightRequests: stats.InFlight,
+			RequestPermits:   stats.RequestPermits,
+			RequestCapacity:  stats.RequestCapacity,
+		}
+		if lc.State() != runtime.StateReady {
+			h.Problems = append(h.Problems, "server is "+h.State)
+		}
+		if mgr != nil {
+			h.OpenWorkbooks = mgr.Count()
+		} else {
+			h.Problems = append(h.Problems, "workbook manager not started")
+		}
+		if allow != nil {
+			h.AllowedDirs = len(allow.AllowedDirectories())
+			h.AllowedPatterns = len(allow.AllowedPatterns())
+		}
+		if h.AllowedDirs == 0 && h.AllowedPatterns == 0 {
+			h.Problems = append(h.Problems, "allow-list is empty")
+		}
+
+		code := http.StatusOK
+		h.Status = "ok"
+		if len(h.Problems) > 0 {
+			code = http.StatusServiceUnavailable
+			h.Status = "unavailable"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		if r.Method != http.MethodHead {
+			_ = json.NewEncoder(w).Encode(h)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+type fakeCounter int
+
+func (c fakeCounter) Count() int { return int(c) }
+
+type fakeAllow []string
+
+func (a fakeAllow) AllowedDirectories() []string { return a }
+
+func (a fakeAllow) AllowedPatterns() []string { return nil }
+
+func getHealth(t *testing.T, h http.Handler) (int, Health) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var body Health
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestHealthHandler(t *testing.T) {
+	ctrl := runtime.NewController(runtime.NewLimits(4, 2))
+
+	// Not ready until the lifecycle says so.
+	code, body := getHealth(t, NewHealthHandler("1.2.3", ctrl, fakeCounter(3), fakeAllow{"/data"}))
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, []string{"server is starting"}, body.Problems)
+
+	ctrl.Lifecycle().Transition(runtime.StateReady)
+	require.NoError(t, ctrl.AcquireRequest(t.Context()))
+	code, body = getHealth(t, NewHealthHandler("1.2.3", ctrl, fakeCounter(3), fakeAllow{"/data"}))
+	ctrl.ReleaseRequest()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok", body.Status)
+	require.Equal(t, "ready", body.State)
+	require.Equal(t, "1.2.3", body.Version)
+	require.Equal(t, 3, body.OpenWorkbooks)
+	require.Equal(t, int64(1), body.RequestPermits)
+	require.Equal(t, 4, body.RequestCapacity)
+	require.Equal(t, 1, body.AllowedDirs)
+	require.Empty(t, body.Problems)
+
+	code, body = getHealth(t, NewHealthHandler("1.2.3", ctrl, nil, fakeAllow{}))
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "unavailable", body.Status)
+	require.ElementsMatch(t, []string{"workbook manager not started", "allow-list is empty"}, body.Problems)
+}
@@ -3,11 +3,14 @@ package telemetry
 import (
 	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/rs/zerolog"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
 )
 
 // Hooks implements mcp-go server lifecycle callbacks for basic telemetry and logging.
-// It is intentionally minimal; metrics backends can be added later under this package.
+// Tool call metrics live in metrics.go (Metrics, ToolCallObserver).
 type Hooks struct {
 	logger zerolog.Logger
 }
@@ -47,6 +50,30 @@ func (h *Hooks) OnToolCall(sessionID, toolName string, duration time.Duration, e
 	evt.Msg("tool call completed")
 }
 
+// LogToolCall emits one structured line per tool call with its duration,
+// processed cells, returned items, truncation, and outcome code. It matches
+// runtime.CallObserver.
+func (h *Hooks) LogToolCall(tool string, sum runtime.CallSummary, res *mcp.CallToolResult, err error) {
+	code := ResultCode(res)
+	switch {
+	case err != nil:
+		code = ErrorCode(err.Error())
+	case res == nil:
+		code = CodeInternal
+	}
+	evt := h.logger.Info()
+	if code != CodeOK {
+		evt = h.logger.Warn()
+	}
+	evt.Str("tool", tool).
+		Int64("duration_ms", sum.Duration.Milliseconds()).
+		Int("processed_cells", sum.ProcessedCells).
+		Int("returned", sum.Returned).
+		Bool("truncated", sum.Truncated).
+		Str("code", code).
+		Msg("tool call")
+}
+
 // OnResourceRead logs resource reads and their outcomes.
 func (h *Hooks) OnResourceRead(sessionID, uri string, duration time.Duration, err error) {
 	evt := h.logger.Info().Str("session_id", sessionID).Str("uri", uri).Dur("duration", duration)
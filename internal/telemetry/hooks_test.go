@@ -0,0 +1,31 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vinodismyname/mcpxcel/internal/runtime"
+)
+
+func TestLogToolCall(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHooks(zerolog.New(&buf))
+	sum := runtime.CallSummary{Duration: 1500 * time.Millisecond, ProcessedCells: 1200, Returned: 100, Truncated: true}
+	h.LogToolCall("read_range", sum, mcp.NewToolResultError("LIMIT_EXCEEDED: too many cells"), nil)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "warn", line["level"])
+	require.Equal(t, "read_range", line["tool"])
+	require.EqualValues(t, 1500, line["duration_ms"])
+	require.EqualValues(t, 1200, line["processed_cells"])
+	require.EqualValues(t, 100, line["returned"])
+	require.Equal(t, true, line["truncated"])
+	require.Equal(t, "LIMIT_EXCEEDED", line["code"])
+}
@@ -0,0 +1,177 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Outcome codes recorded alongside the "CODE: message" error codes.
+const (
+	// CodeOK labels tool calls that returned a non-error result.
+	CodeOK = "OK"
+	// CodeInternal labels protocol-level failures and error text without a code.
+	CodeInternal = "INTERNAL"
+	// ToolUnknown labels calls naming a tool the server does not register.
+	ToolUnknown = "unknown"
+)
+
+// Metrics records tool call outcomes. PromMetrics exports them; tests can
+// supply a fake to assert what the hooks observed.
+type Metrics interface {
+	ObserveToolCall(tool, code string, duration time.Duration)
+}
+
+// PromMetrics keeps per-tool call counters and latency histograms, labeled by
+// tool name and outcome code, in its own Prometheus registry.
+type PromMetrics struct {
+	registry *prometheus.Registry
+	calls    *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// NewPromMetrics constructs the tool metrics plus the standard Go runtime and
+// process collectors.
+func NewPromMetrics() *PromMetrics {
+	m := &PromMetrics{
+		registry: prometheus.NewRegistry(),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mcpxcel",
+			Name:      "tool_calls_total",
+			Help:      "Tool calls by tool name and outcome code (OK or the CODE of a \"CODE: message\" error).",
+		}, []string{"tool", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "mcpxcel",
+			Name:      "tool_call_duration_seconds",
+			Help:      "Tool call latency by tool name and outcome code.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"tool", "code"}),
+	}
+	m.registry.MustRegister(m.calls, m.latency,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// ObserveToolCall counts the call and records its latency.
+func (m *PromMetrics) ObserveToolCall(tool, code string, duration time.Duration) {
+	m.calls.WithLabelValues(tool, code).Inc()
+	m.latency.WithLabelValues(tool, code).Observe(duration.Seconds())
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *PromMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ToolCallObserver times tool calls between mcp-go's before and after hooks
+// and reports each one to a Metrics sink.
+type ToolCallObserver struct {
+	metrics Metrics
+	known   func(tool string) bool
+	now     func() time.Time
+
+	mu      sync.Mutex
+	started map[string]time.Time // keyed by session and request id
+}
+
+// NewToolCallObserver returns an observer reporting to m. Tool names for
+// which known returns false are reported as ToolUnknown, so clients cannot
+// grow the label set by calling made-up tools.
+func NewToolCallObserver(m Metrics, known func(tool string) bool) *ToolCallObserver {
+	return &ToolCallObserver{metrics: m, known: known, now: time.Now, started: map[string]time.Time{}}
+}
+
+// Attach adds the BeforeCallTool, AfterCallTool, and OnError hooks that feed
+// the observer.
+func (o *ToolCallObserver) Attach(hooks *server.Hooks) {
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, req *mcp.CallToolRequest) {
+		o.mu.Lock()
+		o.started[callKey(ctx, id)] = o.now()
+		o.mu.Unlock()
+	})
+	hooks.AddAfterCallTool(func(ctx context.Context, id any, req *mcp.CallToolRequest, res *mcp.CallToolResult) {
+		o.finish(ctx, id, req.Params.Name, ResultCode(res))
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		if method != mcp.MethodToolsCall {
+			return
+		}
+		name := ""
+		if req, ok := message.(*mcp.CallToolRequest); ok {
+			name = req.Params.Name
+		}
+		code := CodeInternal
+		if err != nil {
+			code = ErrorCode(err.Error())
+		}
+		o.finish(ctx, id, name, code)
+	})
+}
+
+// finish reports the call identified by ctx and id. Calls rejected before
+// the before hook ran (malformed requests) are reported with zero latency.
+func (o *ToolCallObserver) finish(ctx context.Context, id any, tool, code string) {
+	key := callKey(ctx, id)
+	o.mu.Lock()
+	start, ok := o.started[key]
+	delete(o.started, key)
+	o.mu.Unlock()
+	var d time.Duration
+	if ok {
+		d = o.now().Sub(start)
+	}
+	if tool == "" || !o.known(tool) {
+		tool = ToolUnknown
+	}
+	o.metrics.ObserveToolCall(tool, code, d)
+}
+
+// callKey identifies an in-flight call; request ids are only unique within a
+// session, so HTTP calls are keyed by both.
+func callKey(ctx context.Context, id any) string {
+	session := ""
+	if cs := server.ClientSessionFromContext(ctx); cs != nil {
+		session = cs.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", session, id)
+}
+
+// ResultCode returns CodeOK for a successful result and the error code of an
+// error result.
+func ResultCode(res *mcp.CallToolResult) string {
+	if res == nil || !res.IsError {
+		return CodeOK
+	}
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			return ErrorCode(tc.Text)
+		}
+	}
+	return CodeInternal
+}
+
+// ErrorCode extracts CODE from "CODE: message" text. Text that does not start
+// with an upper-case code yields CodeInternal, keeping label cardinality bounded.
+func ErrorCode(text string) string {
+	code, _, ok := strings.Cut(strings.TrimSpace(text), ":")
+	if !ok || code == "" || len(code) > 40 {
+		return CodeInternal
+	}
+	for _, r := range code {
+		if (r < 'A' || r > 'Z') && r != '_' && (r < '0' || r > '9') {
+			return CodeInternal
+		}
+	}
+	return code
+}
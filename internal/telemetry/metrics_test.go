@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+)
+
+type observed struct {
+	tool, code string
+	duration   time.Duration
+}
+
+type fakeMetrics struct{ calls []observed }
+
+func (m *fakeMetrics) ObserveToolCall(tool, code string, d time.Duration) {
+	m.calls = append(m.calls, observed{tool, code, d})
+}
+
+func TestToolCallObserver(t *testing.T) {
+	fm := &fakeMetrics{}
+	obs := NewToolCallObserver(fm, func(tool string) bool { return tool == "read_range" })
+	clock := time.Unix(0, 0)
+	obs.now = func() time.Time { return clock }
+	hooks := &server.Hooks{}
+	obs.Attach(hooks)
+
+	ctx := context.Background()
+	req := &mcp.CallToolRequest{}
+	req.Params.Name = "read_range"
+	call := func(id any, res *mcp.CallToolResult, err error, d time.Duration) {
+		for _, h := range hooks.OnBeforeCallTool {
+			h(ctx, id, req)
+		}
+		clock = clock.Add(d)
+		if err != nil {
+			for _, h := range hooks.OnError {
+				h(ctx, id, mcp.MethodToolsCall, req, err)
+			}
+			return
+		}
+		for _, h := range hooks.OnAfterCallTool {
+			h(ctx, id, req, res)
+		}
+	}
+
+	call(1, mcp.NewToolResultText("ok"), nil, 30*time.Millisecond)
+	call(2, mcp.NewToolResultError("INVALID_SHEET: sheet not found | nextSteps: x"), nil, 5*time.Millisecond)
+	call(3, nil, errors.New("panic recovered in read_range tool handler"), time.Millisecond)
+	// Calls to tools the server does not register share one label.
+	req = &mcp.CallToolRequest{}
+	req.Params.Name = "made_up_tool_1234"
+	call(5, nil, errors.New("tool 'made_up_tool_1234' not found"), time.Millisecond)
+	// Errors from other methods are not tool calls.
+	for _, h := range hooks.OnError {
+		h(ctx, 4, mcp.MethodResourcesRead, nil, errors.New("boom"))
+	}
+
+	require.Equal(t, []observed{
+		{"read_range", CodeOK, 30 * time.Millisecond},
+		{"read_range", "INVALID_SHEET", 5 * time.Millisecond},
+		{"read_range", CodeInternal, time.Millisecond},
+		{ToolUnknown, CodeInternal, time.Millisecond},
+	}, fm.calls)
+	require.Empty(t, obs.started)
+}
+
+func TestErrorCode(t *testing.T) {
+	require.Equal(t, "VALIDATION", ErrorCode("VALIDATION: bad input"))
+	require.Equal(t, "BUSY_RESOURCE", ErrorCode("  BUSY_RESOURCE: concurrent request limit reached"))
+	require.Equal(t, CodeInternal, ErrorCode("open /tmp/x: no such file"))
+	require.Equal(t, CodeInternal, ErrorCode("no code here"))
+	require.Equal(t, CodeOK, ResultCode(mcp.NewToolResultText("fine")))
+}
+
+func TestPromMetricsHandler(t *testing.T) {
+	m := NewPromMetrics()
+	m.ObserveToolCall("preview_sheet", CodeOK, 20*time.Millisecond)
+	m.ObserveToolCall("preview_sheet", "TIMEOUT", 2*time.Second)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	require.Contains(t, body, `mcpxcel_tool_calls_total{code="OK",tool="preview_sheet"} 1`)
+	require.Contains(t, body, `mcpxcel_tool_calls_total{code="TIMEOUT",tool="preview_sheet"} 1`)
+	require.Contains(t, body, `mcpxcel_tool_call_duration_seconds_bucket{code="TIMEOUT",tool="preview_sheet",le="2.5"} 1`)
+}
@@ -0,0 +1,235 @@
+package workbooks
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBackupKeep is how many backups of each workbook are kept when no
+// count is configured.
+const DefaultBackupKeep = 10
+
+// LocalBackupDir is the directory, beside the workbook, that receives
+// backups requested per call when no backup directory is configured.
+const LocalBackupDir = ".mcpxcel-backups"
+
+// backupStamp formats backup timestamps so names sort chronologically.
+const backupStamp = "20060102T150405.000000000Z"
+
+// ErrBackupFailed wraps failures copying a workbook before a save; the save
+// is not attempted.
+var ErrBackupFailed = errors.New("workbooks: backup before save failed")
+
+// ErrNotABackup indicates a file offered for restore is not a backup of the
+// target workbook.
+var ErrNotABackup = errors.New("workbooks: not a backup of this workbook")
+
+// ErrBackupNotAllowed indicates a file offered for restore lies outside the
+// directories the path validator allows reading.
+var ErrBackupNotAllowed = errors.New("workbooks: backup path not allowed")
+
+// ErrNoBackups indicates a workbook has no backups to restore.
+var ErrNoBackups = errors.New("workbooks: no backups found")
+
+// SetBackup enables write-ahead backups: before every save the workbook's
+// file is copied into dir, keeping the newest keep backups of each workbook
+// (keep <= 0 uses DefaultBackupKeep). An empty dir disables automatic
+// backups; a save can still request one, which then goes to LocalBackupDir
+// beside the workbook.
+func (m *Manager) SetBackup(dir string, keep int) {
+	if keep <= 0 {
+		keep = DefaultBackupKeep
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backupDir, m.backupKeep = dir, keep
+}
+
+// BackupDir returns the configured backup directory; empty means backups
+// are taken only when a save requests one.
+func (m *Manager) BackupDir() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.backupDir
+}
+
+// backupPolicy returns where path's backups go and how many are kept.
+func (m *Manager) backupPolicy(path string) (dir string, keep int) {
+	m.mu.RLock()
+	dir, keep = m.backupDir, m.backupKeep
+	m.mu.RUnlock()
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(path), LocalBackupDir)
+	}
+	if keep <= 0 {
+		keep = DefaultBackupKeep
+	}
+	return dir, keep
+}
+
+// backup copies path into its backup directory when backups are configured
+// or requested, pruning older backups of the same workbook, and returns the
+// backup's path ("" when none was taken).
+func (m *Manager) backup(path string, requested bool) (string, error) {
+	if !requested && m.BackupDir() == "" {
+		return "", nil
+	}
+	dir, keep := m.backupPolicy(path)
+	name, err := backupFile(path, dir, keep, m.clock())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrBackupFailed, err)
+	}
+	return name, nil
+}
+
+// backupPrefix is the name prefix shared by every backup of path: the file's
+// stem and a short hash of its full path, so same-named workbooks from
+// different directories do not mix in a shared backup directory.
+func backupPrefix(path string) string {
+	sum := sha1.Sum([]byte(path))
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "." + hex.EncodeToString(sum[:4]) + "."
+}
+
+// backupFile copies path into dir as <stem>.<hash>.<timestamp><ext> and
+// removes all but the newest keep backups of path.
+func backupFile(path, dir string, keep int, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, backupPrefix(path)+now.UTC().Format(backupStamp)+filepath.Ext(path))
+	if err := copyFileAtomic(path, name); err != nil {
+		return "", err
+	}
+	backups, err := listBackups(path, dir)
+	if err != nil {
+		return "", err
+	}
+	for _, old := range backups[min(keep, len(backups)):] {
+		if old != name {
+			_ = os.Remove(old)
+		}
+	}
+	return name, nil
+}
+
+// listBackups returns path's backups in dir, newest first.
+func listBackups(path, dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := backupPrefix(path), filepath.Ext(path)
+	var out []string
+	for _, e := range entries {
+		if n := e.Name(); !e.IsDir() && strings.HasPrefix(n, prefix) && strings.HasSuffix(n, ext) {
+			out = append(out, filepath.Join(dir, n))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(out)))
+	return out, nil
+}
+
+// Backups returns the backups of the workbook at canonical path, newest
+// first, from the directory its saves back up to.
+func (m *Manager) Backups(path string) ([]string, error) {
+	dir, _ := m.backupPolicy(path)
+	return listBackups(path, dir)
+}
+
+// RestoreBackup copies backup over the workbook at path; an empty backup
+// restores the newest one. The workbook must be writable and the backup
+// readable under the path validator, and the backup must be one of the
+// workbook's own. before, when non-nil, runs once both paths are resolved
+// and aborts the restore by returning an error. An open handle is dropped
+// without saving its pending changes, so the next access loads the restored
+// file. It returns the canonical workbook and backup paths.
+func (m *Manager) RestoreBackup(path, backup string, before func(path, backup string) error) (string, string, error) {
+	canonical, err := m.ValidateWritePath(path)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrWriteNotAllowed, err)
+	}
+	if strings.TrimSpace(backup) == "" {
+		backups, err := m.Backups(canonical)
+		if err != nil {
+			return "", "", err
+		}
+		if len(backups) == 0 {
+			return "", "", ErrNoBackups
+		}
+		backup = backups[0]
+	}
+	src, err := m.Canonicalize(backup)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrBackupNotAllowed, err)
+	}
+	name := filepath.Base(src)
+	if !strings.HasPrefix(name, backupPrefix(canonical)) || filepath.Ext(name) != filepath.Ext(canonical) {
+		return "", "", ErrNotABackup
+	}
+	if before != nil {
+		if err := before(canonical, src); err != nil {
+			return "", "", err
+		}
+	}
+	if id, ok := m.LookupPath(canonical); ok {
+		if err := m.Discard(id); err != nil && !errors.Is(err, ErrHandleNotFound) {
+			return "", "", err
+		}
+	}
+	if err := copyFileAtomic(src, canonical); err != nil {
+		return "", "", err
+	}
+	return canonical, src, nil
+}
+
+// copyFileAtomic copies src to dst through a temporary file in dst's
+// directory, so dst is never left partially written. An existing dst keeps
+// its file mode.
+func copyFileAtomic(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := io.Copy(tmp, in); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if fi, err := os.Stat(dst); err == nil {
+		_ = os.Chmod(tmpName, fi.Mode().Perm())
+	} else if fi, err := os.Stat(src); err == nil {
+		_ = os.Chmod(tmpName, fi.Mode().Perm())
+	}
+	if err := os.Rename(tmpName, dst); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
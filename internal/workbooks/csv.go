@@ -0,0 +1,137 @@
+package workbooks
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	// ErrReadOnlyWorkbook indicates a write against a CSV-backed handle.
+	ErrReadOnlyWorkbook = errors.New("workbooks: CSV-backed workbooks are read-only")
+	// ErrFileTooLarge indicates the file exceeds the configured size cap.
+	ErrFileTooLarge = errors.New("workbooks: file exceeds the configured size limit")
+)
+
+// isCSVPath reports whether path names a CSV file.
+func isCSVPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// loadCSV streams a CSV file into an in-memory workbook holding one sheet
+// named after the file. Files larger than maxBytes are rejected before parsing.
+// Numeric fields become numbers (fields with leading zeros stay text) and
+// empty fields are left blank.
+func loadCSV(path string, maxBytes int64) (*excelize.File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = fh.Close() }()
+	if fi, err := fh.Stat(); err != nil {
+		return nil, err
+	} else if maxBytes > 0 && fi.Size() > maxBytes {
+		return nil, fmt.Errorf("%w: %d bytes, max %d", ErrFileTooLarge, fi.Size(), maxBytes)
+	}
+
+	f := excelize.NewFile()
+	sheet := csvSheetName(path)
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	r := csv.NewReader(bufio.NewReader(fh))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.ReuseRecord = true
+	rows, cols := 0, 0
+	for row := 1; ; row++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("workbooks: parse csv: %w", err)
+		}
+		if row == 1 && len(rec) > 0 {
+			rec[0] = strings.TrimPrefix(rec[0], "\ufeff")
+		}
+		vals := make([]any, len(rec))
+		for i, v := range rec {
+			vals[i] = csvValue(v)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := sw.SetRow(cell, vals); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		rows = row
+		if len(rec) > cols {
+			cols = len(rec)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	// Record the used range so dimension-based tools size the sheet correctly.
+	if rows > 0 && cols > 0 {
+		last, _ := excelize.CoordinatesToCellName(cols, rows)
+		if err := f.SetSheetDimension(sheet, "A1:"+last); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// csvValue types a CSV field: nil for empty, float64 for plain numbers, and
+// the original text otherwise so identifiers such as "007" keep their zeros.
+func csvValue(v string) any {
+	if v == "" {
+		return nil
+	}
+	t := strings.TrimSpace(v)
+	if t != v || (len(t) > 1 && t[0] == '0' && t[1] != '.') {
+		return v
+	}
+	if n, err := strconv.ParseFloat(t, 64); err == nil && !strings.ContainsAny(t, "xXpPnN_") {
+		return n
+	}
+	return v
+}
+
+// csvSheetName derives a valid Excel sheet name from the file's base name.
+func csvSheetName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	base = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, base)
+	base = strings.Trim(base, "' ")
+	if utf8.RuneCountInString(base) > 31 {
+		base = string([]rune(base)[:31])
+	}
+	if base == "" {
+		return "Sheet1"
+	}
+	return base
+}
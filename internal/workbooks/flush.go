@@ -0,0 +1,220 @@
+package workbooks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SaveFunc persists the workbook a write callback changed to path. The file
+// on disk is first copied to a backup when backups are configured (see
+// SetBackup) or backup is set; a failed backup aborts the save.
+type SaveFunc func(path string, backup bool) (SaveResult, error)
+
+// SaveResult describes how a SaveFunc persisted a workbook.
+type SaveResult struct {
+	// Deferred reports that the save was batched into a later flush
+	// instead of being written before returning.
+	Deferred bool
+	// Backup is the copy of the file taken before the save, or "" when
+	// none was taken. Writes batched into one flush share the backup the
+	// first of them took.
+	Backup string
+}
+
+// ErrFlushFailed wraps failures writing deferred changes to disk; the
+// changes stay pending and the next flush retries them.
+var ErrFlushFailed = errors.New("workbooks: saving deferred changes failed")
+
+// ErrPendingChanges is returned by DiscardUnlessPending when the workbook
+// holds deferred changes that discarding would lose.
+var ErrPendingChanges = errors.New("workbooks: deferred changes not yet saved")
+
+// SetSaveDelay enables write batching: saves requested by write callbacks
+// only mark the workbook dirty, and it is written once no further save has
+// been requested for d. Dirty workbooks are also written by Flush,
+// CloseHandle, Close, and eviction. While changes are pending the workbook
+// is not reloaded when its file changes on disk; the pending save
+// overwrites the external change. d <= 0 saves on every call (the default).
+func (m *Manager) SetSaveDelay(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveDelay = d
+}
+
+// SaveDelay returns the write batching delay; zero means saves are immediate.
+func (m *Manager) SaveDelay() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.saveDelay
+}
+
+// SetFlushErrorHook installs a callback invoked when a background flush (the
+// batching timer or TTL eviction) fails to write a workbook's pending
+// changes, receiving the canonical path and the error.
+func (m *Manager) SetFlushErrorHook(fn func(path string, err error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onFlushError = fn
+}
+
+// Flush writes id's deferred changes to disk now and reports whether any
+// were pending. It waits for in-flight reads and writes of the workbook.
+func (m *Manager) Flush(id string) (bool, error) {
+	m.mu.RLock()
+	h, ok := m.handles[id]
+	m.mu.RUnlock()
+	if !ok {
+		return false, ErrHandleNotFound
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false, ErrHandleNotFound
+	}
+	return h.flushLocked()
+}
+
+// Discard closes id without writing its deferred changes. Callers use it to
+// drop in-memory edits that must not reach disk; changes deferred by earlier
+// calls are lost with them.
+func (m *Manager) Discard(id string) error {
+	return m.closeHandle(id, dropPending)
+}
+
+// DiscardUnlessPending is Discard for dropping the in-memory edits of a
+// single failed write. When earlier writes deferred changes that are not yet
+// on disk, it keeps the handle open so those changes survive, and returns
+// ErrPendingChanges; the failed write's edits then stay in memory with them.
+func (m *Manager) DiscardUnlessPending(id string) error {
+	return m.closeHandle(id, keepPending)
+}
+
+// saveFunc returns the SaveFunc for a write to h's file f. exclusive reports
+// whether the caller holds h.mu exclusively; otherwise it holds it shared
+// and an immediate save trades up for the duration of the write.
+func (m *Manager) saveFunc(h *Handle, f *excelize.File, delay time.Duration, exclusive bool) SaveFunc {
+	return func(path string, backup bool) (SaveResult, error) {
+		if delay > 0 {
+			return m.deferSave(h, path, delay, backup)
+		}
+		if !exclusive {
+			// The sheet lock stays held, which keeps the lock order.
+			h.mu.RUnlock()
+			h.mu.Lock()
+			defer func() {
+				h.mu.Unlock()
+				h.mu.RLock()
+			}()
+			if h.closed || h.File != f {
+				return SaveResult{}, ErrHandleNotFound
+			}
+		}
+		// Changes already pending were backed up when first deferred.
+		res := SaveResult{Backup: h.pendingBackupPath()}
+		if res.Backup == "" {
+			name, err := m.backup(path, backup)
+			if err != nil {
+				return SaveResult{}, err
+			}
+			res.Backup = name
+		}
+		if err := SaveAtomic(f, path); err != nil {
+			return SaveResult{}, err
+		}
+		h.restamp()
+		// The whole workbook was written, including anything pending.
+		h.clearPending()
+		return res, nil
+	}
+}
+
+// deferSave marks h dirty and restarts its flush timer. The first deferred
+// save of a batch takes the backup, while the file still holds the state
+// before the batch.
+func (m *Manager) deferSave(h *Handle, path string, delay time.Duration, backup bool) (SaveResult, error) {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+	res := SaveResult{Deferred: true, Backup: h.pendingBackup}
+	if res.Backup == "" {
+		name, err := m.backup(path, backup)
+		if err != nil {
+			return SaveResult{}, err
+		}
+		if name != "" {
+			h.pendingBackup, res.Backup = name, name
+		}
+	}
+	h.dirty = true
+	h.pendingPath = path
+	if h.flushTimer == nil {
+		h.flushTimer = time.AfterFunc(delay, func() { m.flushDeferred(h) })
+		return res, nil
+	}
+	h.flushTimer.Reset(delay)
+	return res, nil
+}
+
+// flushDeferred is the flush timer's callback.
+func (m *Manager) flushDeferred(h *Handle) {
+	m.mu.RLock()
+	hook := m.onFlushError
+	m.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	if _, err := h.flushLocked(); err != nil && hook != nil {
+		hook(h.path, err)
+	}
+}
+
+// flushLocked writes pending changes and reports whether there were any; the
+// caller holds h.mu exclusively, so no write can mark h dirty meanwhile.
+func (h *Handle) flushLocked() (bool, error) {
+	h.flushMu.Lock()
+	dirty, path := h.dirty, h.pendingPath
+	h.flushMu.Unlock()
+	if !dirty {
+		return false, nil
+	}
+	if err := SaveAtomic(h.File, path); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrFlushFailed, err)
+	}
+	h.restamp()
+	h.clearPending()
+	return true, nil
+}
+
+// clearPending marks h clean and stops its flush timer. A timer already
+// firing finds nothing to write.
+func (h *Handle) clearPending() {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+	h.dirty = false
+	h.pendingPath = ""
+	h.pendingBackup = ""
+	if h.flushTimer != nil {
+		h.flushTimer.Stop()
+	}
+}
+
+// pendingBackupPath returns the backup taken for h's pending changes.
+func (h *Handle) pendingBackupPath() string {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+	return h.pendingBackup
+}
+
+// pending reports whether h has deferred changes not yet on disk.
+func (h *Handle) pending() bool {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+	return h.dirty
+}
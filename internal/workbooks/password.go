@@ -0,0 +1,83 @@
+package workbooks
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/vinodismyname/mcpxcel/config"
+	"github.com/xuri/excelize/v2"
+)
+
+// OpenOptions carries per-request settings for opening a workbook. Options
+// are used only while opening and are never stored on the handle.
+type OpenOptions struct {
+	// Password decrypts an encrypted workbook. It is ignored for files that
+	// are not encrypted so a plain workbook is never encrypted on save.
+	Password string
+}
+
+var (
+	// ErrPasswordRequired indicates an encrypted workbook was opened without a password.
+	ErrPasswordRequired = errors.New("workbooks: workbook is password-protected; a password is required")
+	// ErrPasswordInvalid indicates the supplied password did not decrypt the workbook.
+	ErrPasswordInvalid = errors.New("workbooks: the supplied workbook password is not correct")
+)
+
+// oleMagic prefixes OLE compound files, the container Excel uses for
+// encrypted workbooks; plain .xlsx files are zip archives.
+var oleMagic = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+// isEncrypted reports whether the file at path is an encrypted workbook.
+func isEncrypted(path string) (bool, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = fh.Close() }()
+	head := make([]byte, len(oleMagic))
+	if _, err := io.ReadFull(fh, head); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return bytes.Equal(head, oleMagic), nil
+}
+
+// fileTraits describes how a workbook file was loaded.
+type fileTraits struct {
+	encrypted bool
+	readOnly  bool
+}
+
+// openWorkbookFile loads path: CSV files through the read-only adapter, and
+// Excel files with excelize, decrypting them when encrypted. Password failures
+// map to ErrPasswordRequired and ErrPasswordInvalid.
+func openWorkbookFile(path string, opts OpenOptions) (*excelize.File, fileTraits, error) {
+	if isCSVPath(path) {
+		f, err := loadCSV(path, config.DefaultMaxCSVBytes)
+		return f, fileTraits{readOnly: true}, err
+	}
+	encrypted, err := isEncrypted(path)
+	if err != nil {
+		return nil, fileTraits{}, err
+	}
+	if !encrypted {
+		f, err := excelize.OpenFile(path)
+		return f, fileTraits{}, err
+	}
+	traits := fileTraits{encrypted: true}
+	if opts.Password == "" {
+		return nil, traits, ErrPasswordRequired
+	}
+	f, err := excelize.OpenFile(path, excelize.Options{Password: opts.Password})
+	if err != nil {
+		if errors.Is(err, excelize.ErrWorkbookPassword) || errors.Is(err, excelize.ErrWorkbookFileFormat) {
+			return nil, traits, ErrPasswordInvalid
+		}
+		return nil, traits, err
+	}
+	return f, traits, nil
+}
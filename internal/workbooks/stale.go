@@ -0,0 +1,176 @@
+package workbooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// StalePolicy selects how the manager reacts when a cached workbook's file has
+// changed on disk since it was opened.
+type StalePolicy string
+
+const (
+	// StaleReopen transparently reloads the workbook and bumps its version.
+	StaleReopen StalePolicy = "reopen"
+	// StaleError closes the cached handle and fails the call with
+	// ErrStaleWorkbook; the next call opens the current file.
+	StaleError StalePolicy = "error"
+)
+
+// ErrStaleWorkbook indicates the workbook file changed on disk under the
+// StaleError policy.
+var ErrStaleWorkbook = errors.New("workbooks: workbook changed on disk since it was opened")
+
+// ParseStalePolicy parses "reopen" or "error"; empty selects StaleReopen.
+func ParseStalePolicy(s string) (StalePolicy, error) {
+	switch p := StalePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return StaleReopen, nil
+	case StaleReopen, StaleError:
+		return p, nil
+	default:
+		return "", fmt.Errorf("workbooks: unknown stale policy %q (use reopen or error)", s)
+	}
+}
+
+// SetStalePolicy installs the policy applied when a cached file changes on disk.
+func (m *Manager) SetStalePolicy(p StalePolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stalePolicy = p
+}
+
+// SetReopenHook installs a callback invoked after a stale handle is reloaded,
+// receiving the canonical path and the manager's total reopen count.
+func (m *Manager) SetReopenHook(fn func(path string, reopens int64)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onReopen = fn
+}
+
+// Reopens returns how many times stale handles have been reloaded.
+func (m *Manager) Reopens() int64 {
+	return m.reopens.Load()
+}
+
+// fileStamp identifies a file revision by modification time and size.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statStamp(path string) (fileStamp, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+func (s fileStamp) equal(o fileStamp) bool {
+	return s.size == o.size && s.modTime.Equal(o.modTime)
+}
+
+// stale reports whether the handle's file no longer matches its stamp.
+func (h *Handle) stale() bool {
+	if h.path == "" {
+		return false
+	}
+	cur, err := statStamp(h.path)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return err != nil || !cur.equal(h.stamp)
+}
+
+// ensureFresh compares the handle's recorded stamp with the file on disk and
+// applies the stale policy when they differ. Handles without a path (adopted
+// files) are never stale.
+func (m *Manager) ensureFresh(id string, h *Handle) error {
+	// Deferred changes win over the file on disk; reloading would drop them.
+	if h.path == "" || h.pending() {
+		return nil
+	}
+	cur, statErr := statStamp(h.path)
+	h.mu.RLock()
+	fresh := statErr == nil && cur.equal(h.stamp)
+	h.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	m.mu.RLock()
+	policy, hook := m.stalePolicy, m.onReopen
+	m.mu.RUnlock()
+	if policy == StaleError {
+		_ = m.CloseHandle(context.Background(), id)
+		if statErr != nil {
+			return statErr
+		}
+		return ErrStaleWorkbook
+	}
+	reopened, err := m.reload(h)
+	if err != nil {
+		// Never keep serving a workbook that no longer matches the file.
+		_ = m.CloseHandle(context.Background(), id)
+		return err
+	}
+	if reopened {
+		n := m.reopens.Add(1)
+		if hook != nil {
+			hook(h.path, n)
+		}
+	}
+	return nil
+}
+
+// reload reopens the handle's file under its write lock. It reports false when
+// a concurrent caller already reloaded the current revision.
+func (m *Manager) reload(h *Handle) (bool, error) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return false, ErrHandleNotFound
+	}
+	cur, err := statStamp(h.path)
+	if err != nil {
+		h.mu.Unlock()
+		return false, err
+	}
+	if cur.equal(h.stamp) || h.pending() {
+		h.mu.Unlock()
+		return false, nil
+	}
+	if h.encrypted {
+		// The password is not retained; the caller must reopen with it.
+		h.mu.Unlock()
+		return false, ErrPasswordRequired
+	}
+	// Re-validate: the new revision may have grown past the size limit.
+	m.mu.RLock()
+	v := m.validator
+	m.mu.RUnlock()
+	if v != nil {
+		if _, err := v.ValidateOpenPath(h.path); err != nil {
+			h.mu.Unlock()
+			return false, err
+		}
+	}
+	f, _, err := openWorkbookFile(h.path, OpenOptions{})
+	if err != nil {
+		h.mu.Unlock()
+		return false, err
+	}
+	old := h.File
+	h.File = f
+	h.stamp = cur
+	h.LoadedAt = m.clock()
+	// Cursors minted against the previous contents must not resume.
+	h.reloadVersion.Store(h.version.Add(1))
+	h.mu.Unlock()
+	_ = old.Close()
+	return true, nil
+}
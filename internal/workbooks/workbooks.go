@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,17 +18,57 @@ import (
 )
 
 // Handle represents an in-memory workbook reference paired with metadata for TTL eviction.
+//
+// Locking: mu is the workbook lock. Structural operations (WithWrite: sheet
+// add/delete/rename, defined names, recalculation) and saves hold it
+// exclusively; every other access holds it shared. Sheet-scoped access
+// additionally holds that sheet's lock from sheets, so a write to one sheet
+// does not block reads or writes of another. Sheet locks are always taken
+// before mu, several at once only in ascending key order, and never while
+// mu is held; keeping this order is what rules out deadlock. The Manager's
+// lock may be taken while mu is held, so the Manager never waits for mu
+// while holding its own lock (evictLRU only tries it).
 type Handle struct {
 	ID        string
 	File      *excelize.File
 	LoadedAt  time.Time
 	ExpiresAt time.Time
-	mu        sync.RWMutex
+	// ttlMu guards ExpiresAt, which every lookup refreshes, so that a held
+	// sheet lock does not stall lookups behind the workbook lock.
+	ttlMu sync.Mutex
+	mu    sync.RWMutex
+	// sheetsMu guards sheets, the per-sheet locks keyed by lower-cased sheet
+	// name and created on first use.
+	sheetsMu sync.Mutex
+	sheets   map[string]*sync.RWMutex
 	// version increments after each successful write to provide
 	// cursor stability under concurrent mutations.
-	version int64
+	version atomic.Int64
+	// reloadVersion is the version the latest reload from disk produced.
+	reloadVersion atomic.Int64
+	// flushMu guards the write batching state: dirty marks changes not yet
+	// written to pendingPath, pendingBackup is the backup taken before
+	// them, and flushTimer fires the debounced save. Writers set it holding
+	// mu shared or exclusively; flushes clear it holding mu exclusively.
+	flushMu       sync.Mutex
+	dirty         bool
+	pendingPath   string
+	pendingBackup string
+	flushTimer    *time.Timer
 	// canonical absolute path for this workbook
 	path string
+	// stamp records the file revision the workbook was loaded from.
+	stamp fileStamp
+	// lastAccess holds the UnixNano time of the latest lookup, for LRU eviction.
+	lastAccess atomic.Int64
+	// encrypted marks workbooks opened with a password. The password itself
+	// is not kept, so such handles cannot be reloaded without the caller.
+	encrypted bool
+	// readOnly marks CSV-backed workbooks, which WithWrite rejects.
+	readOnly bool
+	// closed is set under mu once File has been closed by eviction or
+	// CloseHandle; lock holders must not touch File afterwards.
+	closed bool
 }
 
 // WorkbookGate coordinates capacity for open workbook handles (backed by runtime.Controller).
@@ -46,6 +89,14 @@ type Manager struct {
 	stopCh       chan struct{}
 	cleanupWG    sync.WaitGroup
 	validator    PathValidator
+	stalePolicy  StalePolicy
+	acquireWait  time.Duration
+	onReopen     func(path string, reopens int64)
+	reopens      atomic.Int64
+	saveDelay    time.Duration
+	onFlushError func(path string, err error)
+	backupDir    string
+	backupKeep   int
 }
 
 // NewManager constructs a lifecycle manager with TTL-bearing handle cache.
@@ -69,6 +120,8 @@ func NewManager(ttl, cleanupEvery time.Duration, gate WorkbookGate, clock func()
 		clock:        clock,
 		gate:         gate,
 		stopCh:       make(chan struct{}),
+		stalePolicy:  StaleReopen,
+		acquireWait:  config.DefaultWorkbookAcquireWait,
 	}
 }
 
@@ -99,7 +152,8 @@ func (m *Manager) Start() {
 	}()
 }
 
-// Close stops background cleanup and closes all open handles.
+// Close stops background cleanup and closes all open handles, writing any
+// deferred changes first. It returns the flush failures, if any.
 func (m *Manager) Close(ctx context.Context) error {
 	// Stop the cleanup loop
 	close(m.stopCh)
@@ -111,20 +165,34 @@ func (m *Manager) Close(ctx context.Context) error {
 		return ctx.Err()
 	}
 
-	// Close any remaining handles
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	// Close any remaining handles. The manager lock is not held while
+	// waiting on a handle, since lock holders may take it briefly.
+	m.mu.RLock()
+	handles := make(map[string]*Handle, len(m.handles))
 	for id, h := range m.handles {
+		handles[id] = h
+	}
+	m.mu.RUnlock()
+	var errs []error
+	for id, h := range handles {
 		// block until we can close; best-effort cleanup
 		h.mu.Lock()
-		_ = h.File.Close()
+		wasClosed := h.closed
+		h.closed = true
+		if !wasClosed {
+			if _, err := h.flushLocked(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", h.path, err))
+			}
+			h.clearPending()
+			_ = h.File.Close()
+		}
 		h.mu.Unlock()
-		delete(m.handles, id)
-		if m.gate != nil {
+		m.forget(id, h)
+		if !wasClosed && m.gate != nil {
 			m.gate.ReleaseWorkbook()
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // NewHandle initializes a Handle wrapper for an excelize workbook instance.
@@ -139,20 +207,35 @@ func (m *Manager) NewHandle(id string, file *excelize.File, ttl time.Duration) (
 		ttl = m.ttl
 	}
 	loadedAt := m.clock()
-	return &Handle{
+	h := &Handle{
 		ID:        id,
 		File:      file,
 		LoadedAt:  loadedAt,
 		ExpiresAt: loadedAt.Add(ttl),
-	}, nil
+	}
+	h.lastAccess.Store(loadedAt.UnixNano())
+	return h, nil
 }
 
 // ErrHandleNotFound indicates an unknown or expired handle ID.
 var ErrHandleNotFound = errors.New("workbooks: handle not found")
 
+// ErrWorkbooksBusy indicates every open-workbook slot is held by a handle that
+// is actively being read or written, so none could be evicted.
+var ErrWorkbooksBusy = errors.New("workbooks: all open workbook slots are in use")
+
+// ErrWriteNotAllowed indicates the path validator refused to authorize writing
+// the workbook's file; returned errors wrap it and the validator's reason.
+var ErrWriteNotAllowed = errors.New("workbooks: write not allowed")
+
 // Open opens a workbook from the given path, registers a TTL-bearing handle, and returns its ID.
 // The manager enforces open-workbook capacity via the gate when provided.
 func (m *Manager) Open(ctx context.Context, path string) (string, error) {
+	return m.OpenWithOptions(ctx, path, OpenOptions{})
+}
+
+// OpenWithOptions is Open with per-request options such as a decryption password.
+func (m *Manager) OpenWithOptions(ctx context.Context, path string, opts OpenOptions) (string, error) {
 	if err := m.acquire(ctx); err != nil {
 		return "", err
 	}
@@ -162,6 +245,8 @@ func (m *Manager) Open(ctx context.Context, path string) (string, error) {
 	switch ext {
 	case ".xlsx", ".xlsm", ".xltx", ".xltm":
 		// allowed Excel formats
+	case ".csv":
+		// loaded read-only into a single-sheet in-memory workbook
 	default:
 		m.release()
 		return "", fmt.Errorf("workbooks: unsupported format: %s", ext)
@@ -182,7 +267,13 @@ func (m *Manager) Open(ctx context.Context, path string) (string, error) {
 		}
 	}
 
-	f, err := excelize.OpenFile(path)
+	// Stamp before reading so a change during the open is detected later.
+	stamp, err := statStamp(path)
+	if err != nil {
+		m.release()
+		return "", err
+	}
+	f, traits, err := openWorkbookFile(path, opts)
 	if err != nil {
 		m.release()
 		return "", err
@@ -195,6 +286,9 @@ func (m *Manager) Open(ctx context.Context, path string) (string, error) {
 		return "", err
 	}
 	h.path = path
+	h.stamp = stamp
+	h.encrypted = traits.encrypted
+	h.readOnly = traits.readOnly
 
 	m.mu.Lock()
 	m.handles[id] = h
@@ -224,75 +318,274 @@ func (m *Manager) Adopt(ctx context.Context, f *excelize.File) (string, error) {
 	return id, nil
 }
 
-// Get returns the handle when present and refreshes its TTL.
+// Get returns the handle when present and refreshes its TTL. A handle whose
+// file changed on disk is reloaded first, or dropped under StaleError.
 func (m *Manager) Get(id string) (*Handle, bool) {
+	h, err := m.lookup(id)
+	return h, err == nil
+}
+
+// lookup resolves id, applies the stale policy, and refreshes the TTL.
+func (m *Manager) lookup(id string) (*Handle, error) {
 	m.mu.RLock()
 	h, ok := m.handles[id]
 	m.mu.RUnlock()
 	if !ok {
-		return nil, false
+		return nil, ErrHandleNotFound
+	}
+	if err := m.ensureFresh(id, h); err != nil {
+		return nil, err
 	}
 	// Refresh TTL on access (idle timeout semantics)
 	now := m.clock()
-	h.mu.Lock()
+	h.lastAccess.Store(now.UnixNano())
+	h.ttlMu.Lock()
 	h.ExpiresAt = now.Add(m.ttl)
-	h.mu.Unlock()
-	return h, true
+	h.ttlMu.Unlock()
+	return h, nil
 }
 
-// WithRead obtains a shared read lock for the handle and executes fn.
+// WithRead obtains shared locks on the whole workbook and executes fn: every
+// sheet's read lock and then the workbook lock. Prefer WithSheetRead when
+// only one sheet is read.
 func (m *Manager) WithRead(id string, fn func(*excelize.File, int64) error) error {
-	h, ok := m.Get(id)
-	if !ok {
-		return ErrHandleNotFound
+	h, err := m.lookup(id)
+	if err != nil {
+		return err
 	}
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	var locks []*sync.RWMutex
+	for {
+		h.mu.RLock()
+		if h.closed {
+			h.mu.RUnlock()
+			return ErrHandleNotFound
+		}
+		keys := sheetKeys(h.File.GetSheetList())
+		h.mu.RUnlock()
+
+		locks = h.sheetLocks(keys)
+		for _, l := range locks {
+			l.RLock()
+		}
+		h.mu.RLock()
+		// A sheet added or renamed while unlocked would be unguarded; retry.
+		if !h.closed && slices.Equal(keys, sheetKeys(h.File.GetSheetList())) {
+			break
+		}
+		closed := h.closed
+		h.mu.RUnlock()
+		for _, l := range locks {
+			l.RUnlock()
+		}
+		if closed {
+			return ErrHandleNotFound
+		}
+	}
+	defer func() {
+		h.mu.RUnlock()
+		for _, l := range locks {
+			l.RUnlock()
+		}
+	}()
 	// Pass a snapshot of the workbook version under the read lock so
 	// callers can validate pagination cursors atomically with the read.
-	return fn(h.File, h.version)
+	return fn(h.File, h.version.Load())
 }
 
-// WithWrite obtains an exclusive write lock for the handle and executes fn.
-func (m *Manager) WithWrite(id string, fn func(*excelize.File) error) error {
-	h, ok := m.Get(id)
-	if !ok {
+// WithSheetRead executes fn holding sheet's read lock and a shared workbook
+// lock, so it runs alongside writes to other sheets.
+func (m *Manager) WithSheetRead(id, sheet string, fn func(*excelize.File, int64) error) error {
+	h, err := m.lookup(id)
+	if err != nil {
+		return err
+	}
+	sl := h.sheetLocks([]string{sheetKey(sheet)})[0]
+	sl.RLock()
+	defer sl.RUnlock()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
 		return ErrHandleNotFound
 	}
+	return fn(h.File, h.version.Load())
+}
+
+// WithSheetWrite executes fn holding sheet's exclusive lock and a shared
+// workbook lock, so reads and writes of other sheets proceed meanwhile. fn
+// must confine its changes to sheet and persist them with save. An immediate
+// save holds the workbook lock exclusively while the workbook is written, so
+// it first waits for in-flight access to other sheets; a deferred one only
+// marks the workbook dirty. The path is authorized for writing before any
+// lock is taken.
+func (m *Manager) WithSheetWrite(id, sheet string, fn func(f *excelize.File, save SaveFunc) error) error {
+	h, err := m.lookup(id)
+	if err != nil {
+		return err
+	}
+	if h.readOnly {
+		return ErrReadOnlyWorkbook
+	}
+	if err := m.authorizeWrite(h.path); err != nil {
+		return err
+	}
+	delay := m.SaveDelay()
+	sl := h.sheetLocks([]string{sheetKey(sheet)})[0]
+	sl.Lock()
+	defer sl.Unlock()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return ErrHandleNotFound
+	}
+	f := h.File
+	if err := fn(f, m.saveFunc(h, f, delay, false)); err != nil {
+		return err
+	}
+	h.version.Add(1)
+	return nil
+}
+
+// WithWrite obtains an exclusive write lock for the handle and executes fn,
+// which persists its changes with save. The workbook's path is authorized
+// for writing before the lock is taken.
+func (m *Manager) WithWrite(id string, fn func(f *excelize.File, save SaveFunc) error) error {
+	h, err := m.lookup(id)
+	if err != nil {
+		return err
+	}
+	if h.readOnly {
+		return ErrReadOnlyWorkbook
+	}
+	if err := m.authorizeWrite(h.path); err != nil {
+		return err
+	}
+	delay := m.SaveDelay()
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if err := fn(h.File); err != nil {
+	if h.closed {
+		return ErrHandleNotFound
+	}
+	if err := fn(h.File, m.saveFunc(h, h.File, delay, true)); err != nil {
 		return err
 	}
 	// Successful write: bump workbook version so cursors embedding a
 	// prior snapshot can be detected as stale.
-	h.version++
+	h.version.Add(1)
 	return nil
 }
 
-// CloseHandle closes and removes a handle by ID, releasing capacity via the gate.
-func (m *Manager) CloseHandle(ctx context.Context, id string) error {
-	m.mu.Lock()
-	h, ok := m.handles[id]
-	if ok {
-		delete(m.handles, id)
-		if h.path != "" {
-			delete(m.byPath, h.path)
+// restamp records the file revision after our own save so it is not seen
+// as stale; the caller holds h.mu exclusively.
+func (h *Handle) restamp() {
+	if h.path != "" {
+		if stamp, serr := statStamp(h.path); serr == nil {
+			h.stamp = stamp
 		}
 	}
-	m.mu.Unlock()
+}
+
+// sheetKey maps a sheet name to its lock key; Excel sheet names are
+// case-insensitive.
+func sheetKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// sheetKeys returns the sorted lock keys for names, which fixes the order
+// WithRead takes them in.
+func sheetKeys(names []string) []string {
+	keys := make([]string, len(names))
+	for i, n := range names {
+		keys[i] = sheetKey(n)
+	}
+	sort.Strings(keys)
+	return slices.Compact(keys)
+}
+
+// sheetLocks returns the locks for keys, creating missing ones.
+func (h *Handle) sheetLocks(keys []string) []*sync.RWMutex {
+	h.sheetsMu.Lock()
+	defer h.sheetsMu.Unlock()
+	if h.sheets == nil {
+		h.sheets = make(map[string]*sync.RWMutex)
+	}
+	locks := make([]*sync.RWMutex, len(keys))
+	for i, k := range keys {
+		l, ok := h.sheets[k]
+		if !ok {
+			l = &sync.RWMutex{}
+			h.sheets[k] = l
+		}
+		locks[i] = l
+	}
+	return locks
+}
+
+// CloseHandle closes and removes a handle by ID, releasing capacity via the
+// gate. Deferred changes are written first; when that fails the handle stays
+// open and the error wraps ErrFlushFailed.
+func (m *Manager) CloseHandle(ctx context.Context, id string) error {
+	return m.closeHandle(id, flushPending)
+}
+
+// pendingOnClose says what closeHandle does with a handle's deferred changes.
+type pendingOnClose int
+
+const (
+	flushPending pendingOnClose = iota // write them, keeping the handle on failure
+	dropPending                        // lose them
+	keepPending                        // keep the handle open instead
+)
+
+func (m *Manager) closeHandle(id string, pending pendingOnClose) error {
+	m.mu.RLock()
+	h, ok := m.handles[id]
+	m.mu.RUnlock()
 	if !ok {
 		return ErrHandleNotFound
 	}
 	// Ensure no other readers/writers are inside the workbook.
 	h.mu.Lock()
+	if h.closed {
+		// A concurrent eviction closed it and owns the slot release.
+		h.mu.Unlock()
+		return nil
+	}
+	switch pending {
+	case flushPending:
+		if _, err := h.flushLocked(); err != nil {
+			h.mu.Unlock()
+			return err
+		}
+	case keepPending:
+		if h.pending() {
+			h.mu.Unlock()
+			return ErrPendingChanges
+		}
+	}
+	h.clearPending()
+	h.closed = true
 	err := h.File.Close()
 	h.mu.Unlock()
+	m.forget(id, h)
 	m.release()
 	return err
 }
 
-// EvictExpired scans for expired handles and closes them.
+// forget removes a closed handle from the cache maps unless its ID or path
+// has already been taken over.
+func (m *Manager) forget(id string, h *Handle) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.handles[id] == h {
+		delete(m.handles, id)
+	}
+	if h.path != "" && m.byPath[h.path] == id {
+		delete(m.byPath, h.path)
+	}
+}
+
+// EvictExpired scans for expired handles and closes them. A handle whose
+// deferred changes cannot be written stays open until a later sweep.
 func (m *Manager) EvictExpired() {
 	now := m.clock()
 	var expired []*Handle
@@ -300,11 +593,9 @@ func (m *Manager) EvictExpired() {
 	var expiredPaths []string
 
 	m.mu.RLock()
+	hook := m.onFlushError
 	for id, h := range m.handles {
-		h.mu.RLock()
-		isExpired := now.After(h.ExpiresAt)
-		h.mu.RUnlock()
-		if isExpired {
+		if h.Expired(now) {
 			expired = append(expired, h)
 			expiredIDs = append(expiredIDs, id)
 			expiredPaths = append(expiredPaths, h.path)
@@ -320,6 +611,20 @@ func (m *Manager) EvictExpired() {
 	for i, h := range expired {
 		// block until safe to close
 		h.mu.Lock()
+		if h.closed {
+			// Already evicted to free a slot.
+			h.mu.Unlock()
+			continue
+		}
+		if _, err := h.flushLocked(); err != nil {
+			h.mu.Unlock()
+			if hook != nil {
+				hook(h.path, err)
+			}
+			continue
+		}
+		h.clearPending()
+		h.closed = true
 		_ = h.File.Close()
 		h.mu.Unlock()
 
@@ -340,11 +645,72 @@ func (m *Manager) Count() int {
 	return len(m.handles)
 }
 
+// acquire reserves an open-workbook slot. When none frees up within the
+// acquire wait, it evicts the least-recently-used idle handle and retries,
+// failing with ErrWorkbooksBusy only when every open handle is in use.
 func (m *Manager) acquire(ctx context.Context) error {
 	if m.gate == nil {
 		return nil
 	}
-	return m.gate.AcquireWorkbook(ctx)
+	for {
+		wctx, cancel := context.WithTimeout(ctx, m.acquireWait)
+		err := m.gate.AcquireWorkbook(wctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !m.evictLRU() {
+			return ErrWorkbooksBusy
+		}
+	}
+}
+
+// evictLRU closes the least-recently-accessed handle that no caller currently
+// holds a read or write lock on, writing its deferred changes first. It
+// reports whether a handle was evicted.
+func (m *Manager) evictLRU() bool {
+	m.mu.Lock()
+	candidates := make([]*Handle, 0, len(m.handles))
+	for _, h := range m.handles {
+		candidates = append(candidates, h)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastAccess.Load() < candidates[j].lastAccess.Load()
+	})
+
+	for _, h := range candidates {
+		// TryLock fails while readers or a writer hold the handle; it never
+		// blocks, so taking it under m.mu cannot deadlock.
+		if !h.mu.TryLock() {
+			continue
+		}
+		if h.closed {
+			h.mu.Unlock()
+			continue
+		}
+		// Pending changes are written under m.mu; this only happens when
+		// every slot is taken, and a failed write keeps the handle open.
+		if _, err := h.flushLocked(); err != nil {
+			h.mu.Unlock()
+			continue
+		}
+		h.clearPending()
+		h.closed = true
+		delete(m.handles, h.ID)
+		if h.path != "" && m.byPath[h.path] == h.ID {
+			delete(m.byPath, h.path)
+		}
+		m.mu.Unlock()
+		_ = h.File.Close()
+		h.mu.Unlock()
+		m.release()
+		return true
+	}
+	m.mu.Unlock()
+	return false
 }
 
 func (m *Manager) release() {
@@ -354,16 +720,33 @@ func (m *Manager) release() {
 	m.gate.ReleaseWorkbook()
 }
 
+// ReloadedSince reports whether handle id was reloaded from disk after it
+// was at version, so that later version changes are not all writes through
+// this manager. An unknown handle counts as reloaded. It only takes the
+// manager lock briefly, so callbacks holding a workbook lock may call it.
+func (m *Manager) ReloadedSince(id string, version int64) bool {
+	m.mu.RLock()
+	h, ok := m.handles[id]
+	m.mu.RUnlock()
+	if !ok {
+		return true
+	}
+	return h.reloadVersion.Load() > version
+}
+
 // VersionOf returns the current mutation version for a handle.
 // It acquires a read lock to snapshot the value safely.
 func (m *Manager) VersionOf(id string) (int64, error) {
-	h, ok := m.Get(id)
-	if !ok {
-		return 0, ErrHandleNotFound
+	h, err := m.lookup(id)
+	if err != nil {
+		return 0, err
 	}
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	return h.version, nil
+	if h.closed {
+		return 0, ErrHandleNotFound
+	}
+	return h.version.Load(), nil
 }
 
 // Close releases the underlying excelize file resources for a single handle.
@@ -382,8 +765,8 @@ func (h *Handle) Close(ctx context.Context) error {
 
 // Expired reports whether the handle has reached its TTL.
 func (h *Handle) Expired(now time.Time) bool {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.ttlMu.Lock()
+	defer h.ttlMu.Unlock()
 
 	return now.After(h.ExpiresAt)
 }
@@ -394,38 +777,181 @@ type PathValidator interface {
 	ValidateOpenPath(path string) (string, error)
 }
 
+// WritePathValidator is implemented by path validators that can authorize
+// output files which may not exist yet.
+type WritePathValidator interface {
+	ValidateWritePath(path string) (string, error)
+}
+
+// ValidateWritePath authorizes an output path via the configured validator
+// when it implements WritePathValidator, returning the canonical path. Without
+// a validator it falls back to filepath.Abs; a validator that cannot check
+// write paths denies them.
+func (m *Manager) ValidateWritePath(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("workbooks: empty path")
+	}
+	m.mu.RLock()
+	v := m.validator
+	m.mu.RUnlock()
+	if v == nil {
+		return filepath.Abs(path)
+	}
+	wv, ok := v.(WritePathValidator)
+	if !ok {
+		return "", fmt.Errorf("workbooks: path validator cannot authorize output files")
+	}
+	return wv.ValidateWritePath(path)
+}
+
+// authorizeWrite checks an open workbook's path against the validator's write
+// rules. Adopted handles and validators without write support are not checked.
+func (m *Manager) authorizeWrite(path string) error {
+	if path == "" {
+		return nil
+	}
+	m.mu.RLock()
+	wv, ok := m.validator.(WritePathValidator)
+	m.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	if _, err := wv.ValidateWritePath(path); err != nil {
+		return fmt.Errorf("%w: %w", ErrWriteNotAllowed, err)
+	}
+	return nil
+}
+
 // GetOrOpenByPath returns the handle ID for a canonicalized path, opening it if
 // necessary. It uses the configured PathValidator to canonicalize/authorize the
 // path when available. The returned path is the canonical absolute path used as
 // the cache key.
 func (m *Manager) GetOrOpenByPath(ctx context.Context, path string) (id string, canonical string, err error) {
-	if strings.TrimSpace(path) == "" {
-		return "", "", fmt.Errorf("workbooks: empty path")
-	}
-	// Canonicalize/authorize
-	if m.validator != nil {
-		canonical, err = m.validator.ValidateOpenPath(path)
-		if err != nil {
-			return "", "", err
-		}
-	} else {
-		if abs, aerr := filepath.Abs(path); aerr == nil {
-			canonical = abs
-		} else {
-			canonical = path
-		}
+	return m.GetOrOpenWithOptions(ctx, path, OpenOptions{})
+}
+
+// GetOrOpenWithOptions is GetOrOpenByPath with options applied when the
+// workbook has to be opened. A cached handle is reused without checking
+// opts.Password; an encrypted handle whose file changed is reopened with it.
+func (m *Manager) GetOrOpenWithOptions(ctx context.Context, path string, opts OpenOptions) (id string, canonical string, err error) {
+	canonical, err = m.Canonicalize(path)
+	if err != nil {
+		return "", "", err
 	}
 	// Fast-path: existing handle for path
 	m.mu.RLock()
 	if hid, ok := m.byPath[canonical]; ok {
+		h := m.handles[hid]
+		policy := m.stalePolicy
+		m.mu.RUnlock()
+		if h == nil {
+			return hid, canonical, nil
+		}
+		// Count resolution as access so the handle about to be used is
+		// not the next LRU victim.
+		h.lastAccess.Store(m.clock().UnixNano())
+		if !(h.encrypted && opts.Password != "" && policy == StaleReopen && h.stale()) {
+			return hid, canonical, nil
+		}
+		// Encrypted handles cannot reload themselves; use this request's
+		// password to open the current file instead.
+		_ = m.CloseHandle(ctx, hid)
+	} else {
 		m.mu.RUnlock()
-		return hid, canonical, nil
 	}
-	m.mu.RUnlock()
 	// Need to open a new handle
-	hid, err := m.Open(ctx, canonical)
+	hid, err := m.OpenWithOptions(ctx, canonical, opts)
 	if err != nil {
 		return "", "", err
 	}
 	return hid, canonical, nil
 }
+
+// Canonicalize authorizes path with the configured PathValidator and returns
+// the canonical absolute path used as the cache key. Without a validator it
+// falls back to filepath.Abs.
+func (m *Manager) Canonicalize(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("workbooks: empty path")
+	}
+	m.mu.RLock()
+	v := m.validator
+	m.mu.RUnlock()
+	if v != nil {
+		return v.ValidateOpenPath(path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs, nil
+	}
+	return path, nil
+}
+
+// LookupPath returns the ID of the open handle for a canonical path.
+func (m *Manager) LookupPath(canonical string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.byPath[canonical]
+	return id, ok
+}
+
+// HandleInfo is a point-in-time description of an open handle.
+type HandleInfo struct {
+	ID         string
+	Path       string
+	LoadedAt   time.Time
+	ExpiresAt  time.Time
+	LastAccess time.Time
+	Version    int64
+	// Pending reports deferred changes not yet written to disk.
+	Pending bool
+}
+
+// Info describes an open handle without refreshing its TTL.
+func (m *Manager) Info(id string) (HandleInfo, bool) {
+	m.mu.RLock()
+	h, ok := m.handles[id]
+	m.mu.RUnlock()
+	if !ok {
+		return HandleInfo{}, false
+	}
+	return h.info(), true
+}
+
+// List describes every open handle, ordered by path and then ID. Handles
+// adopted without a path sort first.
+func (m *Manager) List() []HandleInfo {
+	m.mu.RLock()
+	hs := make([]*Handle, 0, len(m.handles))
+	for _, h := range m.handles {
+		hs = append(hs, h)
+	}
+	m.mu.RUnlock()
+	out := make([]HandleInfo, 0, len(hs))
+	for _, h := range hs {
+		out = append(out, h.info())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (h *Handle) info() HandleInfo {
+	h.ttlMu.Lock()
+	expires := h.ExpiresAt
+	h.ttlMu.Unlock()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return HandleInfo{
+		ID:         h.ID,
+		Path:       h.path,
+		LoadedAt:   h.LoadedAt,
+		ExpiresAt:  expires,
+		LastAccess: time.Unix(0, h.lastAccess.Load()),
+		Version:    h.version.Load(),
+		Pending:    h.pending(),
+	}
+}
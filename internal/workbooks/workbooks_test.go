@@ -3,6 +3,8 @@ package workbooks
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -107,7 +109,7 @@ func TestReadWriteLocking(t *testing.T) {
 		// Wait until both readers have acquired before attempting write
 		r1Acq.Wait()
 		r2Acq.Wait()
-		err := m.WithWrite(id, func(*excelize.File) error {
+		err := m.WithWrite(id, func(*excelize.File, SaveFunc) error {
 			wAcq.Done()
 			return nil
 		})
@@ -168,6 +170,146 @@ func TestOpen_PathValidatorDenied_ReleasesGate(t *testing.T) {
 	require.Equal(t, int64(1), gate.releases.Load())
 }
 
+// openTwoSheetWorkbook saves a workbook with Sheet1 and Sheet2 and opens it.
+func openTwoSheetWorkbook(t *testing.T, m *Manager) (string, string) {
+	t.Helper()
+	f := excelize.NewFile()
+	_, err := f.NewSheet("Sheet2")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "two.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	id, err := m.Open(context.Background(), path)
+	require.NoError(t, err)
+	return id, path
+}
+
+func TestSheetWriteBlocksOnlyItsSheet(t *testing.T) {
+	m := NewManager(time.Second, time.Second, nil, time.Now)
+	id, path := openTwoSheetWorkbook(t, m)
+
+	holding, release := make(chan struct{}), make(chan struct{})
+	writeDone := make(chan error, 1)
+	go func() {
+		writeDone <- m.WithSheetWrite(id, "Sheet1", func(f *excelize.File, save SaveFunc) error {
+			if err := f.SetCellValue("Sheet1", "A1", "one"); err != nil {
+				return err
+			}
+			close(holding)
+			<-release
+			_, err := save(path, false)
+			return err
+		})
+	}()
+	<-holding
+
+	// Other sheets stay readable and writable; saving (not done here) would
+	// wait for the Sheet1 writer, and its save persists this edit too.
+	require.NoError(t, m.WithSheetRead(id, "Sheet2", func(*excelize.File, int64) error { return nil }))
+	require.NoError(t, m.WithSheetWrite(id, "Sheet2", func(f *excelize.File, _ SaveFunc) error {
+		return f.SetCellValue("Sheet2", "A1", "two")
+	}))
+
+	// The written sheet (matched case-insensitively) and whole-workbook
+	// readers wait for the writer.
+	blocked := make(chan string, 2)
+	go func() {
+		_ = m.WithSheetRead(id, "SHEET1", func(f *excelize.File, _ int64) error {
+			v, _ := f.GetCellValue("Sheet1", "A1")
+			blocked <- "sheet:" + v
+			return nil
+		})
+	}()
+	go func() {
+		_ = m.WithRead(id, func(f *excelize.File, _ int64) error {
+			v, _ := f.GetCellValue("Sheet1", "A1")
+			blocked <- "workbook:" + v
+			return nil
+		})
+	}()
+	select {
+	case got := <-blocked:
+		t.Fatalf("reader %q ran while Sheet1 was being written", got)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-writeDone)
+	require.ElementsMatch(t, []string{"sheet:one", "workbook:one"}, []string{<-blocked, <-blocked})
+
+	v, err := m.VersionOf(id)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), v)
+	saved, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer saved.Close()
+	for sheet, want := range map[string]string{"Sheet1": "one", "Sheet2": "two"} {
+		got, err := saved.GetCellValue(sheet, "A1")
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+}
+
+// TestSheetLocksNoDeadlock mixes every access mode on one handle; the lock
+// order (sheet locks ascending, then the workbook lock) must keep it live.
+func TestSheetLocksNoDeadlock(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute, nil, time.Now)
+	id, path := openTwoSheetWorkbook(t, m)
+	sheets := []string{"Sheet1", "Sheet2"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 400)
+	for i := 0; i < 100; i++ {
+		sheet := sheets[i%2]
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			errs <- m.WithRead(id, func(f *excelize.File, _ int64) error {
+				_, err := f.GetRows("Sheet1")
+				return err
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- m.WithSheetRead(id, sheet, func(f *excelize.File, _ int64) error {
+				_, err := f.GetCellValue(sheet, "A1")
+				return err
+			})
+		}()
+		go func(n int) {
+			defer wg.Done()
+			errs <- m.WithSheetWrite(id, sheet, func(f *excelize.File, save SaveFunc) error {
+				if err := f.SetCellValue(sheet, "A1", n); err != nil {
+					return err
+				}
+				if n%10 == 0 {
+					_, err := save(path, false)
+					return err
+				}
+				return nil
+			})
+		}(i)
+		go func() {
+			defer wg.Done()
+			errs <- m.WithWrite(id, func(f *excelize.File, _ SaveFunc) error {
+				_ = f.GetSheetList()
+				return nil
+			})
+		}()
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("sheet and workbook locks deadlocked")
+	}
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
 func TestWorkbookVersionIncrementsOnWrite(t *testing.T) {
 	m := NewManager(time.Second, time.Second, nil, time.Now)
 	id, err := m.Adopt(context.Background(), excelize.NewFile())
@@ -179,7 +321,7 @@ func TestWorkbookVersionIncrementsOnWrite(t *testing.T) {
 	require.NoError(t, err)
 
 	// Perform a write (no-op save) and expect version to bump
-	err = m.WithWrite(id, func(f *excelize.File) error { return nil })
+	err = m.WithWrite(id, func(f *excelize.File, _ SaveFunc) error { return nil })
 	require.NoError(t, err)
 
 	err = m.WithRead(id, func(_ *excelize.File, ver int64) error { v1 = ver; return nil })
@@ -189,3 +331,541 @@ func TestWorkbookVersionIncrementsOnWrite(t *testing.T) {
 	// but after one write, version should be >= 1.
 	require.GreaterOrEqual(t, v1, int64(1))
 }
+
+func TestSaveAtomic_ReplacesFileWithoutLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "atomic.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "before"))
+	require.NoError(t, f.SaveAs(path))
+
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "after"))
+	require.NoError(t, SaveAtomic(f, path))
+	require.NoError(t, f.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary file should be renamed into place")
+
+	g, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer g.Close()
+	v, err := g.GetCellValue("Sheet1", "A1")
+	require.NoError(t, err)
+	require.Equal(t, "after", v)
+}
+
+// rewriteCell replaces the workbook at path with one holding v in Sheet1!A1
+// and pushes the mtime forward so the change is visible regardless of
+// filesystem timestamp granularity.
+func rewriteCell(t *testing.T, path, v string) {
+	t.Helper()
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", v))
+	require.NoError(t, f.SaveAs(path))
+	require.NoError(t, f.Close())
+	later := time.Now().Add(2 * time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func readA1(m *Manager, id string) (string, int64, error) {
+	var v string
+	var ver int64
+	err := m.WithRead(id, func(f *excelize.File, version int64) error {
+		var gerr error
+		v, gerr = f.GetCellValue("Sheet1", "A1")
+		ver = version
+		return gerr
+	})
+	return v, ver, err
+}
+
+func TestStaleHandle_ReopensWithFreshData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stale.xlsx")
+	rewriteCell(t, path, "before")
+	m := NewManager(time.Minute, time.Minute, nil, time.Now)
+	var hooked []int64
+	m.SetReopenHook(func(_ string, n int64) { hooked = append(hooked, n) })
+
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	v, ver0, err := readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, "before", v)
+
+	rewriteCell(t, path, "after")
+	id2, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	require.Equal(t, id, id2, "handle is reloaded in place")
+	v, ver1, err := readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, "after", v)
+	require.Greater(t, ver1, ver0, "reload invalidates earlier cursors")
+	require.Equal(t, int64(1), m.Reopens())
+	require.Equal(t, []int64{1}, hooked)
+
+	// Unchanged file: no further reloads.
+	_, _, err = readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), m.Reopens())
+}
+
+func TestStaleHandle_OwnWritesAreNotStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "own.xlsx")
+	rewriteCell(t, path, "before")
+	m := NewManager(time.Minute, time.Minute, nil, time.Now)
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+
+	require.NoError(t, m.WithWrite(id, func(f *excelize.File, save SaveFunc) error {
+		if err := f.SetCellValue("Sheet1", "A1", "mine"); err != nil {
+			return err
+		}
+		_, err := save(path, false)
+		return err
+	}))
+	v, _, err := readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, "mine", v)
+	require.Zero(t, m.Reopens())
+}
+
+func TestStaleHandle_ErrorPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "strict.xlsx")
+	rewriteCell(t, path, "before")
+	gate := &fakeGate{}
+	m := NewManager(time.Minute, time.Minute, gate, time.Now)
+	m.SetStalePolicy(StaleError)
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+
+	rewriteCell(t, path, "after")
+	_, _, err = readA1(m, id)
+	require.ErrorIs(t, err, ErrStaleWorkbook)
+	require.Equal(t, 0, m.Count(), "stale handle is dropped")
+	require.Equal(t, int64(1), gate.releases.Load())
+
+	// A retry opens the current file.
+	id, _, err = m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	v, _, err := readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, "after", v)
+}
+
+func TestParseStalePolicy(t *testing.T) {
+	p, err := ParseStalePolicy("")
+	require.NoError(t, err)
+	require.Equal(t, StaleReopen, p)
+	p, err = ParseStalePolicy(" Error ")
+	require.NoError(t, err)
+	require.Equal(t, StaleError, p)
+	_, err = ParseStalePolicy("ignore")
+	require.Error(t, err)
+}
+
+// capGate is a WorkbookGate with a fixed number of slots.
+type capGate struct{ slots chan struct{} }
+
+func newCapGate(n int) *capGate { return &capGate{slots: make(chan struct{}, n)} }
+
+func (g *capGate) AcquireWorkbook(ctx context.Context) error {
+	select {
+	case g.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+func (g *capGate) ReleaseWorkbook() { <-g.slots }
+
+func newWorkbookFiles(t *testing.T, names ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(names))
+	for i, n := range names {
+		paths[i] = filepath.Join(dir, n+".xlsx")
+		rewriteCell(t, paths[i], n)
+	}
+	return paths
+}
+
+func TestOpen_EvictsLeastRecentlyUsedIdleHandle(t *testing.T) {
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	clock := func() time.Time { return time.Unix(0, now.Add(int64(time.Millisecond))) }
+	m := NewManager(time.Minute, time.Minute, newCapGate(2), clock)
+	m.acquireWait = 10 * time.Millisecond
+	paths := newWorkbookFiles(t, "a", "b", "c")
+
+	idA, _, err := m.GetOrOpenByPath(context.Background(), paths[0])
+	require.NoError(t, err)
+	idB, _, err := m.GetOrOpenByPath(context.Background(), paths[1])
+	require.NoError(t, err)
+	// Touch a so b becomes the least recently used.
+	_, _, err = readA1(m, idA)
+	require.NoError(t, err)
+
+	idC, _, err := m.GetOrOpenByPath(context.Background(), paths[2])
+	require.NoError(t, err)
+	require.Equal(t, 2, m.Count())
+	_, ok := m.Get(idB)
+	require.False(t, ok, "least recently used handle is evicted")
+	v, _, err := readA1(m, idA)
+	require.NoError(t, err)
+	require.Equal(t, "a", v)
+	v, _, err = readA1(m, idC)
+	require.NoError(t, err)
+	require.Equal(t, "c", v)
+
+	// The evicted path reopens on demand, evicting the next LRU handle (a).
+	idB2, _, err := m.GetOrOpenByPath(context.Background(), paths[1])
+	require.NoError(t, err)
+	require.NotEqual(t, idB, idB2)
+	_, ok = m.Get(idA)
+	require.False(t, ok)
+	require.Equal(t, 2, m.Count())
+}
+
+func TestOpen_AllHandlesBusy(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute, newCapGate(2), time.Now)
+	m.acquireWait = 10 * time.Millisecond
+	paths := newWorkbookFiles(t, "a", "b", "c")
+
+	release := make(chan struct{})
+	var held, done sync.WaitGroup
+	for _, p := range paths[:2] {
+		id, _, err := m.GetOrOpenByPath(context.Background(), p)
+		require.NoError(t, err)
+		held.Add(1)
+		done.Add(1)
+		go func(id string) {
+			defer done.Done()
+			_ = m.WithRead(id, func(*excelize.File, int64) error {
+				held.Done()
+				<-release
+				return nil
+			})
+		}(id)
+	}
+	held.Wait()
+
+	_, _, err := m.GetOrOpenByPath(context.Background(), paths[2])
+	require.ErrorIs(t, err, ErrWorkbooksBusy)
+	require.Equal(t, 2, m.Count())
+
+	close(release)
+	done.Wait()
+	_, _, err = m.GetOrOpenByPath(context.Background(), paths[2])
+	require.NoError(t, err, "idle handles become evictable once readers finish")
+}
+
+func TestListAndLookupPath(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute, nil, time.Now)
+	paths := newWorkbookFiles(t, "b", "a")
+	idB, canonB, err := m.GetOrOpenByPath(context.Background(), paths[0])
+	require.NoError(t, err)
+	idA, _, err := m.GetOrOpenByPath(context.Background(), paths[1])
+	require.NoError(t, err)
+	require.NoError(t, m.WithWrite(idA, func(*excelize.File, SaveFunc) error { return nil }))
+
+	got, ok := m.LookupPath(canonB)
+	require.True(t, ok)
+	require.Equal(t, idB, got)
+
+	list := m.List()
+	require.Len(t, list, 2)
+	require.Equal(t, idA, list[0].ID, "ordered by path")
+	require.Equal(t, int64(1), list[0].Version)
+	require.Equal(t, idB, list[1].ID)
+	require.True(t, list[1].ExpiresAt.After(list[1].LoadedAt))
+
+	require.NoError(t, m.CloseHandle(context.Background(), idB))
+	_, ok = m.LookupPath(canonB)
+	require.False(t, ok)
+	_, ok = m.Info(idB)
+	require.False(t, ok)
+}
+
+func TestOpenEncryptedWorkbook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.xlsx")
+	f := excelize.NewFile()
+	require.NoError(t, f.SetCellValue("Sheet1", "A1", "classified"))
+	require.NoError(t, f.SaveAs(path, excelize.Options{Password: "s3cret"}))
+	require.NoError(t, f.Close())
+
+	gate := &fakeGate{}
+	m := NewManager(time.Minute, time.Minute, gate, time.Now)
+	_, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.ErrorIs(t, err, ErrPasswordRequired)
+	_, _, err = m.GetOrOpenWithOptions(context.Background(), path, OpenOptions{Password: "wrong"})
+	require.ErrorIs(t, err, ErrPasswordInvalid)
+	require.Equal(t, gate.acquires.Load(), gate.releases.Load(), "failed opens release their slot")
+
+	id, _, err := m.GetOrOpenWithOptions(context.Background(), path, OpenOptions{Password: "s3cret"})
+	require.NoError(t, err)
+	v, _, err := readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, "classified", v)
+
+	// Saves keep the file encrypted.
+	require.NoError(t, m.WithWrite(id, func(f *excelize.File, save SaveFunc) error {
+		_, err := save(path, false)
+		return err
+	}))
+	_, err = excelize.OpenFile(path)
+	require.Error(t, err)
+
+	// An external change cannot be reloaded without the password.
+	later := time.Now().Add(2 * time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	_, _, err = readA1(m, id)
+	require.ErrorIs(t, err, ErrPasswordRequired)
+	require.Equal(t, 0, m.Count())
+
+	// A cached handle is reused without the password until it goes away.
+	id, _, err = m.GetOrOpenWithOptions(context.Background(), path, OpenOptions{Password: "s3cret"})
+	require.NoError(t, err)
+	id2, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	require.Equal(t, id, id2)
+}
+
+func TestLoadCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales: q1.csv")
+	body := "\ufeffregion,units,code,note\nNorth,10,007,\"a, b\"\nSouth,2.5,,\n"
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+
+	f, err := loadCSV(path, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	require.Equal(t, []string{"sales_ q1"}, f.GetSheetList())
+	rows, err := f.GetRows("sales_ q1")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"region", "units", "code", "note"}, {"North", "10", "007", "a, b"}, {"South", "2.5"}}, rows)
+	typ, err := f.GetCellType("sales_ q1", "B2")
+	require.NoError(t, err)
+	require.NotEqual(t, excelize.CellTypeInlineString, typ, "numeric fields are stored as numbers")
+
+	_, err = loadCSV(path, 8)
+	require.ErrorIs(t, err, ErrFileTooLarge)
+}
+
+func TestCSVHandleIsReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	require.NoError(t, os.WriteFile(path, []byte("a\n1\n"), 0o644))
+	m := NewManager(time.Minute, time.Minute, nil, time.Now)
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	require.NoError(t, m.WithRead(id, func(f *excelize.File, _ int64) error {
+		v, err := f.GetCellValue("data", "A2")
+		require.Equal(t, "1", v)
+		return err
+	}))
+	err = m.WithWrite(id, func(*excelize.File, SaveFunc) error { return nil })
+	require.ErrorIs(t, err, ErrReadOnlyWorkbook)
+}
+
+// diskA1 reads Sheet1!A1 straight from the file, bypassing the manager.
+func diskA1(t *testing.T, path string) string {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	v, err := f.GetCellValue("Sheet1", "A1")
+	require.NoError(t, err)
+	return v
+}
+
+func setA1(m *Manager, id, path, v string) (bool, error) {
+	var deferred bool
+	err := m.WithWrite(id, func(f *excelize.File, save SaveFunc) error {
+		if err := f.SetCellValue("Sheet1", "A1", v); err != nil {
+			return err
+		}
+		res, err := save(path, false)
+		deferred = res.Deferred
+		return err
+	})
+	return deferred, err
+}
+
+func TestDeferredSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batched.xlsx")
+	rewriteCell(t, path, "disk")
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	clock := func() time.Time { return time.Unix(0, now.Load()) }
+	gate := &fakeGate{}
+	m := NewManager(time.Minute, time.Minute, gate, clock)
+	m.SetSaveDelay(time.Hour)
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+
+	deferred, err := setA1(m, id, path, "one")
+	require.NoError(t, err)
+	require.True(t, deferred)
+	deferred, err = setA1(m, id, path, "two")
+	require.NoError(t, err)
+	require.True(t, deferred)
+	require.Equal(t, "disk", diskA1(t, path))
+	info, _ := m.Info(id)
+	require.True(t, info.Pending)
+
+	// Pending writes win over an external change instead of being reloaded.
+	later := time.Now().Add(4 * time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	v, _, err := readA1(m, id)
+	require.NoError(t, err)
+	require.Equal(t, "two", v)
+	require.Zero(t, m.Reopens())
+
+	flushed, err := m.Flush(id)
+	require.NoError(t, err)
+	require.True(t, flushed)
+	require.Equal(t, "two", diskA1(t, path))
+	flushed, err = m.Flush(id)
+	require.NoError(t, err)
+	require.False(t, flushed)
+
+	// Close and TTL eviction write pending changes; Discard drops them.
+	_, err = setA1(m, id, path, "closed")
+	require.NoError(t, err)
+	require.NoError(t, m.CloseHandle(context.Background(), id))
+	require.Equal(t, "closed", diskA1(t, path))
+
+	id, _, err = m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	_, err = setA1(m, id, path, "evicted")
+	require.NoError(t, err)
+	now.Add(int64(2 * time.Minute))
+	m.EvictExpired()
+	require.Zero(t, m.Count())
+	require.Equal(t, "evicted", diskA1(t, path))
+
+	id, _, err = m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	_, err = setA1(m, id, path, "dropped")
+	require.NoError(t, err)
+	// DiscardUnlessPending keeps what earlier writes deferred.
+	require.ErrorIs(t, m.DiscardUnlessPending(id), ErrPendingChanges)
+	require.Equal(t, 1, m.Count())
+	require.NoError(t, m.Discard(id))
+	require.Equal(t, "evicted", diskA1(t, path))
+	require.Equal(t, gate.acquires.Load(), gate.releases.Load())
+}
+
+func TestDeferredSaveTimer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timer.xlsx")
+	rewriteCell(t, path, "disk")
+	m := NewManager(time.Minute, time.Minute, nil, time.Now)
+	t.Cleanup(func() { _ = m.Close(context.Background()) })
+	m.SetSaveDelay(20 * time.Millisecond)
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+
+	_, err = setA1(m, id, path, "quiet")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		info, _ := m.Info(id)
+		return !info.Pending
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "quiet", diskA1(t, path))
+
+	// Save-per-call is the default and clears nothing pending.
+	m.SetSaveDelay(0)
+	deferred, err := setA1(m, id, path, "now")
+	require.NoError(t, err)
+	require.False(t, deferred)
+	require.Equal(t, "now", diskA1(t, path))
+}
+
+func TestWriteAheadBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "book.xlsx")
+	rewriteCell(t, path, "disk")
+	var now atomic.Int64
+	now.Store(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC).UnixNano())
+	clock := func() time.Time { return time.Unix(0, now.Add(int64(time.Second))) }
+	m := NewManager(time.Minute, time.Minute, nil, clock)
+	id, _, err := m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	write := func(v string, backup bool) SaveResult {
+		t.Helper()
+		var res SaveResult
+		err := m.WithWrite(id, func(f *excelize.File, save SaveFunc) error {
+			if err := f.SetCellValue("Sheet1", "A1", v); err != nil {
+				return err
+			}
+			var err error
+			res, err = save(path, backup)
+			return err
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	// Off by default; a requested backup lands beside the workbook.
+	require.Empty(t, write("plain", false).Backup)
+	res := write("one", true)
+	require.Equal(t, filepath.Join(dir, LocalBackupDir), filepath.Dir(res.Backup))
+	require.Equal(t, "plain", diskA1(t, res.Backup))
+	require.Equal(t, "one", diskA1(t, path))
+
+	// A configured directory backs up every save and keeps the newest two.
+	backups := t.TempDir()
+	m.SetBackup(backups, 2)
+	for _, v := range []string{"two", "three", "four"} {
+		require.Equal(t, backups, filepath.Dir(write(v, false).Backup))
+	}
+	list, err := m.Backups(path)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "three", diskA1(t, list[0]))
+	require.Equal(t, "two", diskA1(t, list[1]))
+
+	// A batch of deferred writes shares the backup taken by its first write.
+	m.SetSaveDelay(time.Hour)
+	first, second := write("five", false), write("six", false)
+	require.True(t, first.Deferred)
+	require.Equal(t, first.Backup, second.Backup)
+	require.Equal(t, "four", diskA1(t, first.Backup))
+	flushed, err := m.Flush(id)
+	require.NoError(t, err)
+	require.True(t, flushed)
+	require.NotEqual(t, first.Backup, write("seven", false).Backup)
+	_, err = m.Flush(id)
+	require.NoError(t, err)
+	m.SetSaveDelay(0)
+
+	// Restore takes the newest backup by default and drops the open handle.
+	canonical, src, err := m.RestoreBackup(path, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, path, canonical)
+	require.Equal(t, "six", diskA1(t, src))
+	require.Equal(t, "six", diskA1(t, path))
+	require.Zero(t, m.Count())
+
+	list, err = m.Backups(path)
+	require.NoError(t, err)
+	_, _, err = m.RestoreBackup(path, list[1], func(string, string) error { return fmt.Errorf("vetoed") })
+	require.EqualError(t, err, "vetoed")
+	require.Equal(t, "six", diskA1(t, path))
+	other := filepath.Join(dir, "other.xlsx")
+	rewriteCell(t, other, "other")
+	_, _, err = m.RestoreBackup(path, other, nil)
+	require.ErrorIs(t, err, ErrNotABackup)
+	_, _, err = m.RestoreBackup(other, "", nil)
+	require.ErrorIs(t, err, ErrNoBackups)
+
+	// A backup that cannot be written aborts the save.
+	id, _, err = m.GetOrOpenByPath(context.Background(), path)
+	require.NoError(t, err)
+	m.SetBackup(other, 0)
+	err = m.WithWrite(id, func(f *excelize.File, save SaveFunc) error {
+		_, err := save(path, false)
+		return err
+	})
+	require.ErrorIs(t, err, ErrBackupFailed)
+	require.Equal(t, "six", diskA1(t, path))
+}
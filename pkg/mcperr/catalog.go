@@ -3,6 +3,7 @@ package mcperr
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -17,6 +18,11 @@ const (
 	InvalidSheet      Code = "INVALID_SHEET"
 	CursorInvalid     Code = "CURSOR_INVALID"
 	CursorBuildFailed Code = "CURSOR_BUILD_FAILED"
+	CursorExpired     Code = "CURSOR_EXPIRED"
+	StaleWorkbook     Code = "STALE_WORKBOOK"
+	PasswordRequired  Code = "PASSWORD_REQUIRED"
+	PasswordInvalid   Code = "PASSWORD_INVALID"
+	TableNotFound     Code = "TABLE_NOT_FOUND"
 
 	// Resource & Limits
 	BusyResource    Code = "BUSY_RESOURCE"
@@ -24,6 +30,7 @@ const (
 	LimitExceeded   Code = "LIMIT_EXCEEDED"
 	PayloadTooLarge Code = "PAYLOAD_TOO_LARGE"
 	FileTooLarge    Code = "FILE_TOO_LARGE"
+	ShuttingDown    Code = "SHUTTING_DOWN"
 
 	// IO & Formats
 	OpenFailed         Code = "OPEN_FAILED"
@@ -36,14 +43,17 @@ const (
 	FilterFailed       Code = "FILTER_FAILED"
 
 	// Analysis/Insights
-	PlanningFailed  Code = "PLANNING_FAILED"
-	DetectionFailed Code = "DETECTION_FAILED"
-	AnalysisFailed  Code = "ANALYSIS_FAILED"
+	PlanningFailed   Code = "PLANNING_FAILED"
+	DetectionFailed  Code = "DETECTION_FAILED"
+	AnalysisFailed   Code = "ANALYSIS_FAILED"
+	ProfilingFailed  Code = "PROFILING_FAILED"
+	StatisticsFailed Code = "STATISTICS_FAILED"
 
 	// Integrity
 	CorruptWorkbook   Code = "CORRUPT_WORKBOOK"
 	UnsupportedFormat Code = "UNSUPPORTED_FORMAT"
 	PermissionDenied  Code = "PERMISSION_DENIED"
+	AuditFailed       Code = "AUDIT_FAILED"
 )
 
 // Entry documents a code's standard message, retry semantics, and next steps.
@@ -60,13 +70,19 @@ var catalog = map[Code]Entry{
 	InvalidHandle:     {Code: InvalidHandle, Message: "workbook handle not found or expired", Retryable: true, NextSteps: []string{"Reopen the workbook via path and retry"}},
 	InvalidSheet:      {Code: InvalidSheet, Message: "sheet not found", Retryable: true, NextSteps: []string{"Call list_structure to verify sheet names", "Check case and spacing"}},
 	CursorInvalid:     {Code: CursorInvalid, Message: "cursor is invalid for current context", Retryable: true, NextSteps: []string{"Restart pagination from the first page", "Avoid edits between pages or reissue query"}},
+	CursorExpired:     {Code: CursorExpired, Message: "cursor has expired", Retryable: true, NextSteps: []string{"Restart pagination from the first page without a cursor", "Finish paging soon after the first call"}},
 	CursorBuildFailed: {Code: CursorBuildFailed, Message: "failed to encode next page cursor", Retryable: true, NextSteps: []string{"Retry or narrow scope (smaller pages)"}},
+	PasswordRequired:  {Code: PasswordRequired, Message: "workbook is password-protected", Retryable: true, NextSteps: []string{"Retry with the password input set", "Passwords are not remembered; resend it when the workbook is reopened"}},
+	PasswordInvalid:   {Code: PasswordInvalid, Message: "workbook password is not correct", Retryable: true, NextSteps: []string{"Check the password and retry"}},
+	TableNotFound:     {Code: TableNotFound, Message: "table not found", Retryable: true, NextSteps: []string{"Call list_tables to verify table names", "Use read_range or detect_tables for data not defined as an Excel table"}},
+	StaleWorkbook:     {Code: StaleWorkbook, Message: "workbook changed on disk since it was opened", Retryable: true, NextSteps: []string{"Retry to read the current file", "Restart pagination; earlier pages reflect the old contents"}},
 
 	BusyResource:    {Code: BusyResource, Message: "concurrent request limit reached", Retryable: true, NextSteps: []string{"Retry after a short delay"}},
 	Timeout:         {Code: Timeout, Message: "operation exceeded configured time limit", Retryable: true, NextSteps: []string{"Narrow scope (rows/cells) or increase timeout", "Prefer cursor-first pagination"}},
 	LimitExceeded:   {Code: LimitExceeded, Message: "operation exceeded configured limits", Retryable: true, NextSteps: []string{"Narrow range, reduce groups, or lower page size"}},
 	PayloadTooLarge: {Code: PayloadTooLarge, Message: "payload exceeds configured size", Retryable: true, NextSteps: []string{"Reduce range size or split into batches"}},
 	FileTooLarge:    {Code: FileTooLarge, Message: "file exceeds configured size", Retryable: false, NextSteps: []string{"Use a smaller workbook or increase the limit"}},
+	ShuttingDown:    {Code: ShuttingDown, Message: "server is shutting down", Retryable: true, NextSteps: []string{"Retry after the server restarts or against another instance"}},
 
 	OpenFailed:         {Code: OpenFailed, Message: "failed to open workbook", Retryable: true, NextSteps: []string{"Verify path, permissions, and format"}},
 	DiscoveryFailed:    {Code: DiscoveryFailed, Message: "failed to discover structure", Retryable: true, NextSteps: []string{"Retry or open the workbook and inspect"}},
@@ -77,36 +93,64 @@ var catalog = map[Code]Entry{
 	SearchFailed:       {Code: SearchFailed, Message: "search execution failed", Retryable: true, NextSteps: []string{"Simplify query or disable regex", "Reduce snapshot_cols"}},
 	FilterFailed:       {Code: FilterFailed, Message: "filter execution failed", Retryable: true, NextSteps: []string{"Simplify predicate or reduce snapshot_cols"}},
 
-	PlanningFailed:  {Code: PlanningFailed, Message: "planning failed", Retryable: true, NextSteps: []string{"Retry with a simpler objective or provide hints"}},
-	DetectionFailed: {Code: DetectionFailed, Message: "table detection failed", Retryable: true, NextSteps: []string{"Specify an approximate range or reduce scan bounds"}},
-	AnalysisFailed:  {Code: AnalysisFailed, Message: "analysis failed", Retryable: true, NextSteps: []string{"Verify range and indices", "Reduce max_cells or top_n"}},
+	PlanningFailed:   {Code: PlanningFailed, Message: "planning failed", Retryable: true, NextSteps: []string{"Retry with a simpler objective or provide hints"}},
+	DetectionFailed:  {Code: DetectionFailed, Message: "table detection failed", Retryable: true, NextSteps: []string{"Specify an approximate range or reduce scan bounds"}},
+	AnalysisFailed:   {Code: AnalysisFailed, Message: "analysis failed", Retryable: true, NextSteps: []string{"Verify range and indices", "Reduce max_cells or top_n"}},
+	ProfilingFailed:  {Code: ProfilingFailed, Message: "schema profiling failed", Retryable: true, NextSteps: []string{"Verify the range or reduce max_sample_rows"}},
+	StatisticsFailed: {Code: StatisticsFailed, Message: "statistics computation failed", Retryable: true, NextSteps: []string{"Verify range and columns", "Reduce max_cells"}},
 
 	CorruptWorkbook:   {Code: CorruptWorkbook, Message: "workbook appears corrupt or unreadable", Retryable: false, NextSteps: []string{"Open in Excel and re-save or repair", "Provide a clean copy"}},
 	UnsupportedFormat: {Code: UnsupportedFormat, Message: "unsupported workbook format", Retryable: false, NextSteps: []string{"Convert to .xlsx and retry"}},
 	PermissionDenied:  {Code: PermissionDenied, Message: "insufficient permissions to access path", Retryable: false, NextSteps: []string{"Adjust permissions or choose an allowed directory"}},
+	AuditFailed:       {Code: AuditFailed, Message: "write could not be audited and was not applied", Retryable: true, NextSteps: []string{"Ask the operator to check the audit log file", "Retry once auditing is restored"}},
 }
 
-// normalize builds a standard error string including next steps for MCP clients that
-// surface only a message string. Format: "CODE: message" followed by a guidance tail.
-func normalize(code Code, msg string) string {
+// ErrorOutput is the structured content attached to every error result, so
+// clients can branch on code and retryable without parsing the text. Tools can
+// advertise it with mcp.WithOutputSchema[mcperr.ErrorOutput]().
+type ErrorOutput struct {
+	Code      string   `json:"code" jsonschema_description:"Canonical error code, e.g. VALIDATION or INVALID_SHEET"`
+	Message   string   `json:"message" jsonschema_description:"Human-readable detail"`
+	Retryable bool     `json:"retryable" jsonschema_description:"Whether retrying (possibly with corrected inputs) can succeed"`
+	NextSteps []string `json:"next_steps,omitempty" jsonschema_description:"Suggested recovery steps"`
+	// RetryAfterMs is set on BUSY_RESOURCE errors.
+	RetryAfterMs int64 `json:"retry_after_ms,omitempty" jsonschema_description:"Suggested delay in milliseconds before retrying"`
+}
+
+// build resolves the catalog entry for code and returns the structured error
+// with msg (or the catalog message when msg is empty).
+func build(code Code, msg string) ErrorOutput {
 	base := strings.TrimSpace(msg)
 	e, ok := catalog[code]
 	if !ok {
 		// Unknown code; preserve as-is
-		if base == "" {
-			return string(code)
-		}
-		return fmt.Sprintf("%s: %s", string(code), base)
+		return ErrorOutput{Code: string(code), Message: base}
 	}
 	if base == "" {
 		base = e.Message
 	}
-	// Append compact nextSteps guidance inline to aid clients lacking structured fields.
+	return ErrorOutput{Code: string(e.Code), Message: base, Retryable: e.Retryable, NextSteps: e.NextSteps}
+}
+
+// Text renders the error in the "CODE: message" format followed by a compact
+// nextSteps tail, for MCP clients that surface only a message string.
+func (o ErrorOutput) Text() string {
+	if o.Message == "" {
+		return o.Code
+	}
 	guidance := ""
-	if len(e.NextSteps) > 0 {
-		guidance = " | nextSteps: " + strings.Join(e.NextSteps, "; ")
+	if len(o.NextSteps) > 0 {
+		guidance = " | nextSteps: " + strings.Join(o.NextSteps, "; ")
 	}
-	return fmt.Sprintf("%s: %s%s", e.Code, base, guidance)
+	return fmt.Sprintf("%s: %s%s", o.Code, o.Message, guidance)
+}
+
+// result returns an error tool result carrying both the text and the
+// structured form of o.
+func result(o ErrorOutput) *mcp.CallToolResult {
+	res := mcp.NewToolResultError(o.Text())
+	res.StructuredContent = o
+	return res
 }
 
 // FromText parses a "CODE: message" string, enriches it with catalog guidance,
@@ -114,37 +158,38 @@ func normalize(code Code, msg string) string {
 func FromText(text string) *mcp.CallToolResult {
 	t := strings.TrimSpace(text)
 	if t == "" {
-		return mcp.NewToolResultError(normalize(Validation, ""))
+		return result(build(Validation, ""))
 	}
 	parts := strings.SplitN(t, ":", 2)
-	if len(parts) == 0 {
-		return mcp.NewToolResultError(normalize(Validation, t))
-	}
 	code := Code(strings.TrimSpace(parts[0]))
 	msg := ""
 	if len(parts) > 1 {
 		msg = strings.TrimSpace(parts[1])
 	}
-	return mcp.NewToolResultError(normalize(code, msg))
+	return result(build(code, msg))
 }
 
 // New returns an MCP error result for a given code and optional message override.
 func New(code Code, message string) *mcp.CallToolResult {
-	return mcp.NewToolResultError(normalize(code, message))
+	return result(build(code, message))
+}
+
+// NewRetryAfter returns an MCP error result for code that suggests waiting
+// retryAfter before retrying.
+func NewRetryAfter(code Code, message string, retryAfter time.Duration) *mcp.CallToolResult {
+	o := build(code, message)
+	o.RetryAfterMs = max(retryAfter.Milliseconds(), 1)
+	return result(o)
 }
 
 // Wrapf formats details and returns an MCP error result for the code.
 func Wrapf(code Code, format string, args ...any) *mcp.CallToolResult {
-	return mcp.NewToolResultError(normalize(code, fmt.Sprintf(format, args...)))
+	return result(build(code, fmt.Sprintf(format, args...)))
 }
 
 // Helpers for common mappings
 
-// IsInvalidSheet returns true if an error matches common excelize "sheet does not exist" messages.
+// IsInvalidSheet reports whether err means the named sheet does not exist.
 func IsInvalidSheet(err error) bool {
-	if err == nil {
-		return false
-	}
-	low := strings.ToLower(err.Error())
-	return strings.Contains(low, "doesn't exist") || strings.Contains(low, "does not exist")
+	return Classify(err) == InvalidSheet
 }
@@ -0,0 +1,40 @@
+package mcperr
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func text(t *testing.T, res *mcp.CallToolResult) string {
+	t.Helper()
+	require.True(t, res.IsError)
+	tc, ok := res.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return tc.Text
+}
+
+func TestFromText_TextAndStructured(t *testing.T) {
+	res := FromText("VALIDATION: range is required")
+	require.Equal(t, "VALIDATION: range is required | nextSteps: Correct the inputs per schema and retry; See examples in tool description", text(t, res))
+	require.Equal(t, ErrorOutput{
+		Code:      "VALIDATION",
+		Message:   "range is required",
+		Retryable: true,
+		NextSteps: []string{"Correct the inputs per schema and retry", "See examples in tool description"},
+	}, res.StructuredContent)
+
+	// The catalog message fills an empty override.
+	res = New(FileTooLarge, "")
+	require.Equal(t, "FILE_TOO_LARGE: file exceeds configured size | nextSteps: Use a smaller workbook or increase the limit", text(t, res))
+	require.False(t, res.StructuredContent.(ErrorOutput).Retryable)
+
+	// Unknown codes keep their text and carry no guidance.
+	res = FromText("CUSTOM_CODE: something odd")
+	require.Equal(t, "CUSTOM_CODE: something odd", text(t, res))
+	require.Equal(t, ErrorOutput{Code: "CUSTOM_CODE", Message: "something odd"}, res.StructuredContent)
+
+	res = Wrapf(ReadFailed, "row %d", 7)
+	require.Equal(t, "row 7", res.StructuredContent.(ErrorOutput).Message)
+}
@@ -0,0 +1,142 @@
+package mcperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrInvalidRange marks A1 range parse failures. Wrap it so the message keeps
+// its detail: fmt.Errorf("%w: %s", mcperr.ErrInvalidRange, input).
+var ErrInvalidRange = errors.New("invalid range")
+
+// Error is an error that already knows its code. Handlers return it from
+// inside workbook callbacks; its text keeps the "CODE: message" convention.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Errorf returns an *Error with a formatted message.
+func Errorf(code Code, format string, args ...any) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// excelizeCodes maps excelize's exported sentinel errors.
+var excelizeCodes = []struct {
+	err  error
+	code Code
+}{
+	{excelize.ErrCoordinates, Validation},
+	{excelize.ErrColumnNumber, Validation},
+	{excelize.ErrMaxRows, Validation},
+	{excelize.ErrParameterInvalid, Validation},
+	{excelize.ErrParameterRequired, Validation},
+	{excelize.ErrCellCharsLength, Validation},
+	{excelize.ErrSheetNameBlank, Validation},
+	{excelize.ErrSheetNameInvalid, Validation},
+	{excelize.ErrSheetNameLength, Validation},
+	{excelize.ErrSheetNameSingleQuote, Validation},
+	{excelize.ErrExistsSheet, Validation},
+	{excelize.ErrDefinedNameScope, Validation},
+	{excelize.ErrSheetIdx, InvalidSheet},
+	{excelize.ErrWorkbookFileFormat, UnsupportedFormat},
+	{excelize.ErrWorkbookPassword, PasswordInvalid},
+}
+
+// excelizeRangeMessages are fragments of excelize's unexported cell and range
+// errors, which it builds with fmt.Errorf and does not wrap. They and the
+// not-a-worksheet suffix are the only text matched here; tests exercise them
+// against live excelize calls so an upgrade that rewords them fails loudly.
+var excelizeRangeMessages = []string{
+	"cannot convert cell ",
+	"invalid cell reference ",
+	"invalid cell name ",
+	"invalid column name ",
+	"invalid row number ",
+}
+
+// Classify maps err to its canonical code: codes carried by *Error, context
+// deadlines, excelize sentinel errors, and the security, workbooks, and audit
+// package errors. It returns "" for errors it does not recognize so callers
+// can apply an operation-specific fallback such as READ_FAILED.
+func Classify(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Timeout
+	}
+	var noSheet excelize.ErrSheetNotExist
+	if errors.As(err, &noSheet) {
+		return InvalidSheet
+	}
+	if IsInvalidRange(err) {
+		return Validation
+	}
+	for _, m := range excelizeCodes {
+		if errors.Is(err, m.err) {
+			return m.code
+		}
+	}
+
+	var roErr *security.ReadOnlyRootError
+	switch {
+	case errors.Is(err, workbooks.ErrHandleNotFound):
+		return InvalidHandle
+	case errors.Is(err, workbooks.ErrStaleWorkbook):
+		return StaleWorkbook
+	case errors.Is(err, workbooks.ErrPasswordRequired):
+		return PasswordRequired
+	case errors.Is(err, workbooks.ErrPasswordInvalid):
+		return PasswordInvalid
+	case errors.Is(err, workbooks.ErrWorkbooksBusy):
+		return BusyResource
+	case errors.Is(err, workbooks.ErrFileTooLarge), errors.Is(err, security.ErrFileTooLarge):
+		return FileTooLarge
+	case errors.Is(err, workbooks.ErrReadOnlyWorkbook), errors.Is(err, security.ErrUnsupportedExtension):
+		return UnsupportedFormat
+	case errors.Is(err, security.ErrNotAllowed), errors.As(err, &roErr), errors.Is(err, workbooks.ErrWriteNotAllowed):
+		return PermissionDenied
+	case errors.Is(err, security.ErrNotFound):
+		return OpenFailed
+	case errors.Is(err, audit.ErrAuditFailed):
+		return AuditFailed
+	}
+	if strings.HasSuffix(err.Error(), " is not a worksheet") {
+		return InvalidSheet
+	}
+	return ""
+}
+
+// IsInvalidRange reports whether err is an A1 range or cell reference that
+// could not be parsed, from this module's parsers or from excelize.
+func IsInvalidRange(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrInvalidRange) || errors.Is(err, excelize.ErrCoordinates) || errors.Is(err, excelize.ErrColumnNumber) || errors.Is(err, excelize.ErrMaxRows) {
+		return true
+	}
+	msg := err.Error()
+	for _, p := range excelizeRangeMessages {
+		if strings.Contains(msg, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package mcperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/vinodismyname/mcpxcel/internal/audit"
+	"github.com/vinodismyname/mcpxcel/internal/security"
+	"github.com/vinodismyname/mcpxcel/internal/workbooks"
+)
+
+// TestClassify_Excelize drives real excelize calls rather than constructing
+// its errors, so an upgrade that changes what excelize returns fails here.
+func TestClassify_Excelize(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	_, err := f.GetCellValue("Missing", "A1")
+	require.Error(t, err)
+	require.Equal(t, InvalidSheet, Classify(err))
+	require.Equal(t, InvalidSheet, Classify(fmt.Errorf("read: %w", err)))
+
+	_, err = f.GetCellValue("Sheet1", "ZZ")
+	require.Error(t, err)
+	require.Equal(t, Validation, Classify(err))
+	require.True(t, IsInvalidRange(err))
+
+	_, _, err = excelize.CellNameToCoordinates("A0")
+	require.Error(t, err)
+	require.True(t, IsInvalidRange(err))
+
+	_, err = excelize.CoordinatesToCellName(0, 0)
+	require.Error(t, err)
+	require.Equal(t, Validation, Classify(err))
+	require.True(t, IsInvalidRange(err))
+
+	_, err = excelize.ColumnNameToNumber("1A")
+	require.Error(t, err)
+	require.True(t, IsInvalidRange(err))
+
+	_, err = excelize.ColumnNumberToName(0)
+	require.Error(t, err)
+	require.Equal(t, Validation, Classify(err))
+
+	_, err = f.NewSheet("bad[name")
+	require.Error(t, err)
+	require.Equal(t, Validation, Classify(err))
+	require.False(t, IsInvalidRange(err))
+
+	err = f.SetSheetName("Sheet1", "")
+	require.Error(t, err)
+	require.Equal(t, Validation, Classify(err))
+
+	require.NoError(t, f.AddChartSheet("Chart1", &excelize.Chart{
+		Type:   excelize.Col,
+		Series: []excelize.ChartSeries{{Name: "Sheet1!$A$1", Values: "Sheet1!$B$1:$B$2"}},
+	}))
+	err = f.SetCellValue("Chart1", "A1", true)
+	require.Error(t, err)
+	require.Equal(t, InvalidSheet, Classify(err))
+
+	for _, sentinel := range []error{excelize.ErrSheetIdx, excelize.ErrWorkbookFileFormat, excelize.ErrWorkbookPassword} {
+		require.NotEmpty(t, Classify(sentinel), sentinel.Error())
+	}
+}
+
+func TestClassify_ModuleErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		want Code
+	}{
+		{workbooks.ErrHandleNotFound, InvalidHandle},
+		{workbooks.ErrStaleWorkbook, StaleWorkbook},
+		{workbooks.ErrPasswordRequired, PasswordRequired},
+		{workbooks.ErrPasswordInvalid, PasswordInvalid},
+		{workbooks.ErrWorkbooksBusy, BusyResource},
+		{workbooks.ErrFileTooLarge, FileTooLarge},
+		{workbooks.ErrReadOnlyWorkbook, UnsupportedFormat},
+		{workbooks.ErrWriteNotAllowed, PermissionDenied},
+		{security.ErrFileTooLarge, FileTooLarge},
+		{security.ErrUnsupportedExtension, UnsupportedFormat},
+		{security.ErrNotAllowed, PermissionDenied},
+		{security.ErrNotFound, OpenFailed},
+		{&security.ReadOnlyRootError{Root: "/data"}, PermissionDenied},
+		{audit.ErrAuditFailed, AuditFailed},
+		{context.DeadlineExceeded, Timeout},
+		{context.Canceled, Timeout},
+		{Errorf(LimitExceeded, "too many groups: %d", 9), LimitExceeded},
+		{fmt.Errorf("%w: A1:", ErrInvalidRange), Validation},
+		{errors.New("disk on fire"), ""},
+		{nil, ""},
+	}
+	for _, tc := range cases {
+		name := "nil"
+		if tc.err != nil {
+			name = tc.err.Error()
+		}
+		require.Equal(t, tc.want, Classify(tc.err), name)
+		if tc.err != nil {
+			require.Equal(t, tc.want, Classify(fmt.Errorf("wrapped: %w", tc.err)), name)
+		}
+	}
+}
+
+func TestErrorf_Text(t *testing.T) {
+	err := Errorf(PayloadTooLarge, "range has %d cells", 12)
+	require.Equal(t, "PAYLOAD_TOO_LARGE: range has 12 cells", err.Error())
+}
@@ -29,9 +29,36 @@ const (
 //   - off: offset in unit from the start of the range/results
 //   - ps:  page size in the chosen unit
 //   - mt:  file modification time snapshot (unix seconds)
+//   - fp:  optional content fingerprint (see Fingerprint); preferred over mt
 //   - iat: issued-at timestamp (unix seconds)
 //   - qh:  optional query hash (search)
 //   - ph:  optional predicate hash (filter)
+//   - em:  optional expand-merged flag (read_range)
+//   - cd:  optional cell-detail flag (read_range)
+//   - enc: optional text encoding (preview_sheet, read_range)
+//   - cw:  optional markdown cell width (preview_sheet, read_range)
+//   - sc:  optional 1-based first column of the window (preview_sheet, detect_tables)
+//   - mc:  optional column window width (preview_sheet, detect_tables)
+//   - sk:  optional rows skipped above the data; off counts from row sk+1 (preview_sheet)
+//   - hr:  optional header row repeated on each page (preview_sheet) or keying records (read_range)
+//   - dk:  optional key normalization and group cap (find_duplicates)
+//   - rc:  optional projected snapshot columns (filter_data)
+//   - ob:  optional sort column and direction (filter_data)
+//   - rs:  optional resolved ranges of a multi-range read (read_range); r is rs[ri]
+//   - ri:  optional index into rs where off applies (read_range)
+//   - hh:  optional hash of the record keys (read_range encoding=records)
+//   - vm:  optional value mode, raw or typed (preview_sheet/read_range)
+//   - tr:  optional comment text rune cap (read_comments)
+//   - hid: optional workbook handle the page was read from
+//   - wbv: optional workbook version of that handle; writes through the server bump it
+//   - op:  optional second workbook path compared against pt (workbook_diff)
+//   - os:  optional second sheet compared against s (workbook_diff)
+//   - omt, ofp, ohid, owbv: the second workbook's mt, fp, hid, and wbv (workbook_diff)
+//   - df:  optional diff alignment and comparison options (workbook_diff)
+//   - ss:  optional merged sheets in order (merge_sheets)
+//   - lr:  optional lookup range (column_lookup)
+//   - sp:  optional search scope other than values (search_data)
+//   - rq:  optional range query in canonical form, e.g. number:1000..2000 (search_data)
 type Cursor struct {
 	V   int    `json:"v"`
 	Pt  string `json:"pt"`
@@ -41,16 +68,55 @@ type Cursor struct {
 	Off int    `json:"off"`
 	Ps  int    `json:"ps"`
 	Mt  int64  `json:"mt"`
+	Fp  string `json:"fp,omitempty"`
 	Iat int64  `json:"iat"`
 	Qh  string `json:"qh,omitempty"`
 	Ph  string `json:"ph,omitempty"`
 	// Optional: carry original search/filter parameters to enable cursor-only resume
-	Q  string `json:"q,omitempty"`  // original query for search_data
-	Rg bool   `json:"rg,omitempty"` // regex flag for search_data
-	Cl []int  `json:"cl,omitempty"` // columns filter for search_data
-	P  string `json:"p,omitempty"`  // original predicate expression for filter_data
+	Q    string   `json:"q,omitempty"`    // original query for search_data
+	Rg   bool     `json:"rg,omitempty"`   // regex flag for search_data
+	Cl   []int    `json:"cl,omitempty"`   // columns filter for search_data
+	P    string   `json:"p,omitempty"`    // original predicate expression for filter_data
+	Em   bool     `json:"em,omitempty"`   // expand merged cells for read_range
+	Cd   bool     `json:"cd,omitempty"`   // cell-detail encoding for read_range
+	Enc  string   `json:"enc,omitempty"`  // text encoding for preview_sheet/read_range
+	Cw   int      `json:"cw,omitempty"`   // markdown cell width for preview_sheet/read_range
+	Sc   int      `json:"sc,omitempty"`   // column window start for preview_sheet/detect_tables
+	Mc   int      `json:"mc,omitempty"`   // column window width for preview_sheet/detect_tables
+	Sk   int      `json:"sk,omitempty"`   // rows skipped before the preview window
+	Hr   int      `json:"hr,omitempty"`   // preview header row, or records/pinned header row for read_range
+	Dk   string   `json:"dk,omitempty"`   // key options for find_duplicates/column_lookup
+	Rc   []int    `json:"rc,omitempty"`   // snapshot columns for filter_data
+	Ob   string   `json:"ob,omitempty"`   // sort spec for filter_data
+	Rs   []string `json:"rs,omitempty"`   // ranges of a multi-range read_range
+	Ri   int      `json:"ri,omitempty"`   // index into Rs the offset applies to
+	Hh   string   `json:"hh,omitempty"`   // record keys hash for read_range
+	Ih   bool     `json:"ih,omitempty"`   // header row pinned above every read_range page
+	Vm   string   `json:"vm,omitempty"`   // value mode for preview_sheet/read_range
+	Tr   int      `json:"tr,omitempty"`   // comment text rune cap for read_comments
+	Hid  string   `json:"hid,omitempty"`  // workbook handle ID Wbv belongs to
+	Wbv  int64    `json:"wbv,omitempty"`  // workbook version when the page was read
+	Op   string   `json:"op,omitempty"`   // second workbook path for workbook_diff
+	Os   string   `json:"os,omitempty"`   // second sheet for workbook_diff, lookup sheet for column_lookup
+	Omt  int64    `json:"omt,omitempty"`  // second workbook mtime snapshot
+	Ofp  string   `json:"ofp,omitempty"`  // second workbook content fingerprint
+	Ohid string   `json:"ohid,omitempty"` // second workbook handle ID
+	Owbv int64    `json:"owbv,omitempty"` // second workbook version
+	Df   string   `json:"df,omitempty"`   // diff options for workbook_diff
+	Ss   []string `json:"ss,omitempty"`   // merged sheets for merge_sheets
+	Lr   string   `json:"lr,omitempty"`   // lookup range for column_lookup
+	Sp   string   `json:"sp,omitempty"`   // search scope for search_data
+	Rq   string   `json:"rq,omitempty"`   // range query for search_data
 }
 
+// ErrCursorExpired indicates a cursor was issued longer ago than the allowed TTL.
+var ErrCursorExpired = errors.New("cursor: expired")
+
+// MaxClockSkew is how far in the future a cursor's issued-at time may lie
+// before the cursor is rejected, tolerating small clock differences between
+// server restarts or replicas.
+const MaxClockSkew = time.Minute
+
 // EncodeCursor serializes and encodes the cursor as URL-safe base64 (without padding).
 func EncodeCursor(c Cursor) (string, error) {
 	if err := validate(&c); err != nil {
@@ -84,6 +150,25 @@ func DecodeCursor(token string) (*Cursor, error) {
 	return &c, nil
 }
 
+// DecodeCursorWithTTL decodes token like DecodeCursor and additionally rejects
+// cursors issued more than ttl before now with ErrCursorExpired. A ttl <= 0
+// disables the age check. Cursors issued up to MaxClockSkew after now are
+// accepted; later ones are invalid.
+func DecodeCursorWithTTL(token string, ttl time.Duration, now time.Time) (*Cursor, error) {
+	c, err := DecodeCursor(token)
+	if err != nil || ttl <= 0 {
+		return c, err
+	}
+	iat := time.Unix(c.Iat, 0)
+	if iat.After(now.Add(MaxClockSkew)) {
+		return nil, errors.New("cursor: iat is in the future")
+	}
+	if now.Sub(iat) > ttl {
+		return nil, fmt.Errorf("%w: issued %s ago, ttl %s", ErrCursorExpired, now.Sub(iat).Truncate(time.Second), ttl)
+	}
+	return c, nil
+}
+
 // validate performs structural checks and defaulting.
 func validate(c *Cursor) error {
 	if c.V <= 0 {
@@ -119,6 +204,40 @@ func validate(c *Cursor) error {
 	return nil
 }
 
+// MatchesFile reports whether the file snapshot (mtime and fingerprint) still
+// matches the one the cursor was issued against. When both carry a
+// fingerprint it decides, so a touched but unchanged file still matches;
+// otherwise a recorded mtime must be equal.
+func (c *Cursor) MatchesFile(mt int64, fp string) bool {
+	if c.Fp != "" && fp != "" {
+		return c.Fp == fp
+	}
+	return c.Mt <= 0 || c.Mt == mt
+}
+
+// MatchesVersion reports whether the workbook is still at the version the
+// cursor was issued against. Versions only compare within one handle:
+// cursors without one, or issued against a handle since closed and
+// reopened as id, match any version and rely on MatchesFile.
+func (c *Cursor) MatchesVersion(id string, version int64) bool {
+	return c.Hid == "" || c.Hid != id || c.Wbv == version
+}
+
+// PageSize resolves the page size of a call. A requested size within
+// [1, limit] always wins, on a first call and on a resumed one alike, so
+// callers can shrink or grow pages mid-walk. Otherwise the cursor's page size
+// (0 when there is no cursor) is reused if within bounds, and def applies
+// when neither is usable.
+func PageSize(requested, cursorPs, def, limit int) int {
+	switch {
+	case requested >= 1 && requested <= limit:
+		return requested
+	case cursorPs >= 1 && cursorPs <= limit:
+		return cursorPs
+	}
+	return def
+}
+
 // NextOffset computes the next offset after returning n units.
 func NextOffset(curr, n int) int {
 	if curr < 0 {
@@ -1,9 +1,14 @@
 package pagination
 
 import (
+	"bytes"
 	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncodeDecodeCursor_RoundTrip(t *testing.T) {
@@ -55,6 +60,51 @@ func TestDecodeCursor_Invalid(t *testing.T) {
 	}
 }
 
+func TestDecodeCursorWithTTL(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tokAt := func(iat time.Time) string {
+		tok, err := EncodeCursor(Cursor{Pt: "/p", S: "S", R: "A1:B2", U: UnitRows, Ps: 10, Iat: iat.Unix()})
+		if err != nil {
+			t.Fatalf("EncodeCursor error: %v", err)
+		}
+		return tok
+	}
+	ttl := 30 * time.Minute
+	cases := []struct {
+		name    string
+		iat     time.Time
+		ttl     time.Duration
+		expired bool
+		invalid bool
+	}{
+		{name: "fresh", iat: now.Add(-time.Minute), ttl: ttl},
+		{name: "exactly ttl", iat: now.Add(-ttl), ttl: ttl},
+		{name: "one second past ttl", iat: now.Add(-ttl - time.Second), ttl: ttl, expired: true},
+		{name: "ttl disabled", iat: now.Add(-48 * time.Hour), ttl: 0},
+		{name: "future within skew", iat: now.Add(MaxClockSkew), ttl: ttl},
+		{name: "future beyond skew", iat: now.Add(MaxClockSkew + time.Second), ttl: ttl, invalid: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := DecodeCursorWithTTL(tokAt(tc.iat), tc.ttl, now)
+			switch {
+			case tc.expired:
+				if !errors.Is(err, ErrCursorExpired) {
+					t.Fatalf("expected ErrCursorExpired, got %v", err)
+				}
+			case tc.invalid:
+				if err == nil || errors.Is(err, ErrCursorExpired) {
+					t.Fatalf("expected invalid-cursor error, got %v", err)
+				}
+			default:
+				if err != nil || c == nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
 func FuzzDecodeCursor(f *testing.F) {
 	seeds := []string{
 		"", "abc", mustB64(`{"v":1}`), mustB64(`{"pt":"x"}`),
@@ -71,3 +121,100 @@ func FuzzDecodeCursor(f *testing.F) {
 func mustB64(s string) string {
 	return base64.RawURLEncoding.EncodeToString([]byte(s))
 }
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	fp := func(p string) string {
+		s, err := Fingerprint(p)
+		if err != nil {
+			t.Fatalf("Fingerprint error: %v", err)
+		}
+		return s
+	}
+
+	// Zip packages: only the head and tail windows are hashed.
+	big := append([]byte("PK\x03\x04"), bytes.Repeat([]byte{'x'}, 4*fingerprintWindow)...)
+	p := write("a.xlsx", big)
+	base := fp(p)
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(p, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := fp(p); got != base {
+		t.Fatalf("touch changed fingerprint: %s vs %s", got, base)
+	}
+	tail := bytes.Clone(big)
+	tail[len(tail)-1] = 'y'
+	if got := fp(write("a.xlsx", tail)); got == base {
+		t.Fatal("tail change not detected")
+	}
+	if got := fp(write("a.xlsx", append(big, 'x'))); got == base {
+		t.Fatal("size change not detected")
+	}
+
+	// Other files are hashed in full, so a same-size middle edit is detected.
+	csv := bytes.Repeat([]byte("a,b\n"), fingerprintWindow)
+	p = write("a.csv", csv)
+	base = fp(p)
+	mid := bytes.Clone(csv)
+	mid[len(mid)/2] = 'z'
+	if got := fp(write("a.csv", mid)); got == base {
+		t.Fatal("middle change in non-zip file not detected")
+	}
+}
+
+func TestCursorMatchesFile(t *testing.T) {
+	c := Cursor{Mt: 100, Fp: "abc"}
+	if !c.MatchesFile(200, "abc") {
+		t.Fatal("fingerprint match should win over mtime difference")
+	}
+	if c.MatchesFile(100, "def") {
+		t.Fatal("fingerprint mismatch should fail even when mtime matches")
+	}
+	legacy := Cursor{Mt: 100}
+	if !legacy.MatchesFile(100, "abc") || legacy.MatchesFile(101, "abc") {
+		t.Fatal("cursors without a fingerprint compare mtime")
+	}
+}
+
+func TestCursorMatchesVersion(t *testing.T) {
+	c := Cursor{Hid: "h1", Wbv: 3}
+	if !c.MatchesVersion("h1", 3) || c.MatchesVersion("h1", 4) {
+		t.Fatal("versions compare within the issuing handle")
+	}
+	if !c.MatchesVersion("h2", 0) {
+		t.Fatal("a reopened handle leaves the decision to the file snapshot")
+	}
+	if legacy := (Cursor{}); !legacy.MatchesVersion("h1", 9) {
+		t.Fatal("cursors without a version match any version")
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	cases := []struct {
+		name                     string
+		requested, cursorPs, def int
+		want                     int
+	}{
+		{"first call uses request", 20, 0, 50, 20},
+		{"first call default", 0, 0, 50, 50},
+		{"first call out of bounds", 5000, 0, 50, 50},
+		{"resume shrink", 10, 40, 50, 10},
+		{"resume grow", 80, 40, 50, 80},
+		{"resume omitted keeps cursor", 0, 40, 50, 40},
+		{"resume out of bounds keeps cursor", 5000, 40, 50, 40},
+		{"cursor out of bounds falls back", 0, 5000, 50, 50},
+	}
+	for _, tc := range cases {
+		if got := PageSize(tc.requested, tc.cursorPs, tc.def, 1000); got != tc.want {
+			t.Errorf("%s: PageSize(%d, %d, %d, 1000) = %d, want %d", tc.name, tc.requested, tc.cursorPs, tc.def, got, tc.want)
+		}
+	}
+}
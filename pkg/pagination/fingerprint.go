@@ -0,0 +1,62 @@
+package pagination
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+// fingerprintWindow is how many bytes from each end of a zip file Fingerprint
+// hashes.
+const fingerprintWindow = 64 << 10
+
+// zipMagic starts every xlsx/xlsm/xltx/xltm package.
+var zipMagic = []byte("PK\x03\x04")
+
+// Fingerprint returns a cheap content fingerprint of the file at path: its
+// size plus an FNV-1a hash of the first and last 64 KB. The tail of a zip
+// package holds the central directory with a CRC-32 of every part, so any
+// content change alters it while a touch that only moves the mtime does not.
+// Other files (CSV) have no such summary and are hashed in full.
+func Fingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := fi.Size()
+	h := fnv.New64a()
+	head := make([]byte, fingerprintWindow)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+	h.Write(head)
+	switch {
+	case int64(n) == size:
+		// Whole file already hashed.
+	case bytes.HasPrefix(head, zipMagic):
+		off := size - fingerprintWindow
+		if off < int64(n) {
+			off = int64(n)
+		}
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	default:
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%x-%016x", size, h.Sum64()), nil
+}
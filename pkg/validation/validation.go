@@ -2,8 +2,11 @@ package validation
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
+	"regexp/syntax"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -19,14 +22,22 @@ var (
 func Validator() *validator.Validate {
 	if v == nil {
 		v = validator.New()
-		// Custom: Excel file path must have supported extension
+		// Report fields by their JSON names so messages match tool schemas.
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				return f.Name
+			}
+			return name
+		})
+		// Custom: Excel (or read-only CSV) file path must have supported extension
 		_ = v.RegisterValidation("filepath_ext", func(fl validator.FieldLevel) bool {
 			s := strings.TrimSpace(fl.Field().String())
 			if s == "" {
 				return false
 			}
 			s = strings.ToLower(s)
-			return strings.HasSuffix(s, ".xlsx") || strings.HasSuffix(s, ".xlsm") || strings.HasSuffix(s, ".xltx") || strings.HasSuffix(s, ".xltm")
+			return strings.HasSuffix(s, ".xlsx") || strings.HasSuffix(s, ".xlsm") || strings.HasSuffix(s, ".xltx") || strings.HasSuffix(s, ".xltm") || strings.HasSuffix(s, ".csv")
 		})
 		// Custom: A1-style range or a plausible defined name
 		_ = v.RegisterValidation("a1orname", func(fl validator.FieldLevel) bool {
@@ -34,17 +45,25 @@ func Validator() *validator.Validate {
 			if s == "" {
 				return false
 			}
-			// A1:A1 style
+			// Optional sheet qualifier (Sheet1!A1:B2 or 'My Sheet'!A1:B2)
+			if i := strings.LastIndex(s, "!"); i >= 0 {
+				if strings.Trim(s[:i], "'") == "" {
+					return false
+				}
+				s = s[i+1:]
+			}
+			// A1:A1 style, with optional $ anchors
 			if strings.Contains(s, ":") {
 				parts := strings.Split(s, ":")
 				if len(parts) != 2 {
 					return false
 				}
-				a1 := regexp.MustCompile(`^[A-Za-z]+[0-9]+$`)
+				a1 := regexp.MustCompile(`^\$?[A-Za-z]+\$?[0-9]+$`)
 				return a1.MatchString(parts[0]) && a1.MatchString(parts[1])
 			}
-			// Named range heuristic: letters, numbers, underscore, dot, space
-			nameRe := regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_\. ]{0,63}$`)
+			// Named range heuristic: Excel name characters (letters, digits,
+			// underscore, dot, backslash) plus space, up to Excel's 255 limit
+			nameRe := regexp.MustCompile(`^[\p{L}_\\][\p{L}\p{N}_\.\\ ]{0,254}$`)
 			return nameRe.MatchString(s)
 		})
 		// Custom: cursor must be decodable via pagination.DecodeCursor
@@ -62,20 +81,15 @@ func Validator() *validator.Validate {
 			}
 			return true
 		})
-		// Custom: valid_regex – only enforced if a sibling boolean field named "Regex" is true
+		// Custom: valid_regex – only enforced if a sibling boolean field named "Regex" is true.
+		// Empty values pass; pair with required/required_without for presence.
 		_ = v.RegisterValidation("valid_regex", func(fl validator.FieldLevel) bool {
-			// Only enforce when parent has Regex=true
 			parent := fl.Parent()
 			if parent.IsValid() {
 				rf := parent.FieldByName("Regex")
-				if rf.IsValid() && rf.Kind().String() == "bool" && rf.Bool() {
-					s := fl.Field().String()
-					if s == "" {
-						return false
-					}
-					// Defer actual compile check to handler if needed; basic sanity
-					// Accept anything non-empty; runtime will compile and return detailed error
-					return true
+				if rf.IsValid() && rf.Kind() == reflect.Bool && rf.Bool() {
+					s := strings.TrimSpace(fl.Field().String())
+					return s == "" || RegexError(s) == ""
 				}
 			}
 			return true
@@ -96,25 +110,41 @@ func ValidateStruct(s any) string {
 				return fmt.Sprintf("VALIDATION: %s is required", field)
 			case "required_without":
 				// Common pattern: sheet/query/predicate required unless cursor provided
-				if field == "sheet" {
-					return "VALIDATION: sheet is required (or supply cursor)"
-				}
-				if field == "query" {
-					return "VALIDATION: query is required (or supply cursor)"
+				if fe.Param() == "Cursor" {
+					return fmt.Sprintf("VALIDATION: %s is required (or supply cursor)", field)
 				}
-				if field == "predicate" {
-					return "VALIDATION: predicate is required (or supply cursor)"
+				if field == "path" && fe.Param() == "ID" {
+					return "VALIDATION: path or id is required"
 				}
 				return fmt.Sprintf("VALIDATION: %s is required", field)
+			case "required_without_all":
+				// e.g. query required unless cursor or range_query is supplied
+				alts := strings.Fields(fe.Param())
+				for i, a := range alts {
+					alts[i] = snakeCase(a)
+				}
+				return fmt.Sprintf("VALIDATION: %s is required (or supply %s)", field, strings.Join(alts, " or "))
 			case "filepath_ext":
-				return "VALIDATION: path must be an Excel file (.xlsx, .xlsm, .xltx, .xltm)"
+				return "VALIDATION: path must be an Excel file (.xlsx, .xlsm, .xltx, .xltm) or a .csv file"
 			case "a1orname":
 				return "VALIDATION: invalid range; use A1:D50 or a defined name"
 			case "cursor":
 				return "CURSOR_INVALID: failed to decode cursor; reopen workbook and restart pagination"
 			case "valid_regex":
+				if msg := RegexError(strings.TrimSpace(fmt.Sprint(fe.Value()))); msg != "" {
+					return msg
+				}
 				return "VALIDATION: invalid regex; examples: 'foo.*' or '^\\d{4}$'"
+			case "oneof":
+				return fmt.Sprintf("VALIDATION: %s must be one of: %s", field, fe.Param())
 			case "min", "max", "gte", "lte":
+				if k := fe.Kind(); k == reflect.Slice || k == reflect.Array || k == reflect.Map {
+					bound := "at least"
+					if fe.Tag() == "max" || fe.Tag() == "lte" {
+						bound = "at most"
+					}
+					return fmt.Sprintf("VALIDATION: %s must have %s %s entries", field, bound, fe.Param())
+				}
 				return fmt.Sprintf("VALIDATION: %s must satisfy %s=%s", field, fe.Tag(), fe.Param())
 			}
 			// Fallback generic
@@ -124,3 +154,65 @@ func ValidateStruct(s any) string {
 	}
 	return ""
 }
+
+// MaxRegexLength caps the length in bytes of regex query patterns.
+const MaxRegexLength = 512
+
+// regexHints explains RE2 parse errors users commonly hit, notably Perl/PCRE
+// constructs pasted from other engines. A hint applies when the error code
+// matches and, if set, the pattern contains the construct.
+var regexHints = []struct {
+	code syntax.ErrorCode
+	re   *regexp.Regexp
+	hint string
+}{
+	{syntax.ErrInvalidPerlOp, regexp.MustCompile(`\(\?[=!]`), "lookahead assertions (?=...) and (?!...) are not supported; match the text directly or search twice"},
+	{syntax.ErrInvalidNamedCapture, regexp.MustCompile(`\(\?<[=!]`), "lookbehind assertions (?<=...) and (?<!...) are not supported; match the text directly or search twice"},
+	{syntax.ErrInvalidPerlOp, regexp.MustCompile(`\(\?>`), "atomic groups (?>...) are not supported; RE2 never backtracks, so a plain group (?:...) behaves the same"},
+	{syntax.ErrInvalidEscape, regexp.MustCompile(`\\[1-9]|\\k<`), "backreferences such as \\1 are not supported"},
+	{syntax.ErrInvalidRepeatOp, regexp.MustCompile(`[*+?}]\+`), "possessive quantifiers such as a*+ are not supported; drop the trailing +"},
+	{syntax.ErrInvalidRepeatSize, nil, "repeat counts are capped at 1000, including nested repeats multiplied together; simplify the pattern"},
+	{syntax.ErrLarge, nil, "the compiled pattern is too large; simplify nested repeats"},
+	{syntax.ErrNestingDepth, nil, "groups or repeats are nested too deeply; simplify the pattern"},
+}
+
+// RegexError compiles pattern as a Go RE2 regular expression and returns a
+// VALIDATION message with the compiler's error, a hint for common PCRE
+// constructs, and example syntax; it returns "" when the pattern is usable.
+// Patterns over MaxRegexLength bytes and repetitions RE2 refuses as too large
+// are rejected the same way.
+func RegexError(pattern string) string {
+	if len(pattern) > MaxRegexLength {
+		return fmt.Sprintf("VALIDATION: regex is %d bytes; the limit is %d", len(pattern), MaxRegexLength)
+	}
+	_, err := regexp.Compile(pattern)
+	if err == nil {
+		return ""
+	}
+	msg := "VALIDATION: invalid regex: " + err.Error()
+	var se *syntax.Error
+	if errors.As(err, &se) {
+		for _, h := range regexHints {
+			if h.code == se.Code && (h.re == nil || h.re.MatchString(pattern)) {
+				msg += "; " + h.hint
+				break
+			}
+		}
+	}
+	return msg + " (Go RE2 syntax; examples: 'foo.*', '^\\d{4}$', '(?i)total')"
+}
+
+// snakeCase converts a Go field name such as RangeQuery to its JSON form.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateStruct_A1OrName(t *testing.T) {
+	type input struct {
+		Range string `json:"range" validate:"required,a1orname"`
+	}
+	cases := []struct {
+		rng string
+		ok  bool
+	}{
+		{"A1:D50", true},
+		{"$A$1:$D$50", true},
+		{"Sheet1!A1:B2", true},
+		{"'My Sheet'!$A1:B$2", true},
+		{"Totals", true},
+		{"A1", true},
+		{"A1:B2:C3", false},
+		{"!A1:B2", false},
+		{"1abc", false},
+	}
+	for _, tc := range cases {
+		msg := ValidateStruct(input{Range: tc.rng})
+		if tc.ok && msg != "" {
+			t.Fatalf("%q: unexpected error %q", tc.rng, msg)
+		}
+		if !tc.ok && !strings.HasPrefix(msg, "VALIDATION: invalid range") {
+			t.Fatalf("%q: got %q, want invalid range", tc.rng, msg)
+		}
+	}
+}
+
+func TestValidateStruct_Messages(t *testing.T) {
+	type input struct {
+		Path    string   `json:"path" validate:"required"`
+		Sheet   string   `json:"sheet" validate:"required_without=Cursor"`
+		Values  []string `json:"values" validate:"omitempty,min=2"`
+		MaxRows int      `json:"max_rows" validate:"omitempty,max=10"`
+		Cursor  string   `json:"cursor"`
+	}
+	cases := []struct {
+		in   input
+		want string
+	}{
+		{input{Sheet: "S"}, "VALIDATION: path is required"},
+		{input{Path: "p"}, "VALIDATION: sheet is required (or supply cursor)"},
+		{input{Path: "p", Cursor: "c"}, ""},
+		{input{Path: "p", Sheet: "S", Values: []string{"a"}}, "VALIDATION: values must have at least 2 entries"},
+		{input{Path: "p", Sheet: "S", MaxRows: 11}, "VALIDATION: max_rows must satisfy max=10"},
+	}
+	for _, tc := range cases {
+		if got := ValidateStruct(tc.in); got != tc.want {
+			t.Fatalf("%+v: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRegexError(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    string // substring of the message; "" means valid
+	}{
+		{`^\d{4}-\d{2}$`, ""},
+		{`(?i)total|sum`, ""},
+		{`(?P<year>\d{4})`, ""},
+		{`price(?=\d)`, "lookahead assertions"},
+		{`(?!draft)report`, "lookahead assertions"},
+		{`(?<=\$)\d+`, "lookbehind assertions"},
+		{`(?<!-)\d+`, "lookbehind assertions"},
+		{`(a)\1`, "backreferences"},
+		{`(?>ab|a)c`, "atomic groups"},
+		{`a++`, "possessive quantifiers"},
+		{`a{1001}`, "repeat counts are capped at 1000"},
+		{`((a{100}){100}){100}`, "repeat counts are capped at 1000"},
+		{`[z-a]`, "invalid character class range"},
+		{strings.Repeat("a", MaxRegexLength+1), "the limit is 512"},
+	}
+	for _, tc := range cases {
+		got := RegexError(tc.pattern)
+		if tc.want == "" {
+			if got != "" {
+				t.Fatalf("%q: unexpected error %q", tc.pattern, got)
+			}
+			continue
+		}
+		if !strings.HasPrefix(got, "VALIDATION: ") || !strings.Contains(got, tc.want) {
+			t.Fatalf("%q: got %q, want it to mention %q", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestValidateStruct_ValidRegex(t *testing.T) {
+	type input struct {
+		Query string `json:"query" validate:"valid_regex"`
+		Regex bool   `json:"regex"`
+	}
+	if msg := ValidateStruct(input{Query: "foo(?=bar)"}); msg != "" {
+		t.Fatalf("literal query rejected: %q", msg)
+	}
+	msg := ValidateStruct(input{Query: "foo(?=bar)", Regex: true})
+	if !strings.Contains(msg, "invalid or unsupported Perl syntax") || !strings.Contains(msg, "lookahead") {
+		t.Fatalf("got %q, want the compiler error and a lookahead hint", msg)
+	}
+}
@@ -2,7 +2,13 @@ package version
 
 import "runtime/debug"
 
-var version = "dev"
+// Build metadata injected with -ldflags, e.g.
+// -X github.com/vinodismyname/mcpxcel/pkg/version.commit=$(git rev-parse --short HEAD).
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
 
 // Version returns the build string embedded via -ldflags when available.
 func Version() string {
@@ -18,3 +24,49 @@ func Set(v string) {
 		version = v
 	}
 }
+
+// Commit returns the source revision the binary was built from: the value
+// injected via -ldflags, else the VCS revision Go stamped into the build,
+// else "".
+func Commit() string {
+	if commit != "" {
+		return commit
+	}
+	return buildSetting("vcs.revision")
+}
+
+// SetCommit assigns the commit when ldflags are not provided.
+func SetCommit(c string) {
+	if c != "" {
+		commit = c
+	}
+}
+
+// Date returns the build or commit date: the value injected via -ldflags,
+// else the VCS commit time Go stamped into the build, else "".
+func Date() string {
+	if date != "" {
+		return date
+	}
+	return buildSetting("vcs.time")
+}
+
+// SetDate assigns the build date when ldflags are not provided.
+func SetDate(d string) {
+	if d != "" {
+		date = d
+	}
+}
+
+func buildSetting(key string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
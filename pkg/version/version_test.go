@@ -0,0 +1,17 @@
+package version
+
+import "testing"
+
+func TestSetters(t *testing.T) {
+	defer func(c, d string) { commit, date = c, d }(commit, date)
+	SetCommit("abc1234")
+	SetDate("2024-05-01T10:00:00Z")
+	SetCommit("")
+	SetDate("")
+	if got := Commit(); got != "abc1234" {
+		t.Fatalf("Commit() = %q, want abc1234", got)
+	}
+	if got := Date(); got != "2024-05-01T10:00:00Z" {
+		t.Fatalf("Date() = %q, want 2024-05-01T10:00:00Z", got)
+	}
+}